@@ -1,19 +1,28 @@
 "use client";
 
-import { useEffect } from 'react';
+import { useEffect, useRef } from 'react';
 import { useRouter, useSearchParams } from 'next/navigation';
+import axios from 'axios';
 
 export default function AuthSuccess() {
   const router = useRouter();
   const searchParams = useSearchParams();
-  const token = searchParams.get('token');
+  const code = searchParams.get('code');
+  const exchanged = useRef(false);
 
   useEffect(() => {
-    if (token) {
-      localStorage.setItem('token', token);
-      router.push('/dashboard');
-    }
-  }, [token, router]);
+    // Codes are single-use, so never exchange twice (e.g. effects re-running in dev)
+    if (!code || exchanged.current) return;
+    exchanged.current = true;
+
+    // Trade the one-time login code for a session token
+    axios.post('http://localhost:8080/api/auth/exchange', { code })
+      .then((response) => {
+        localStorage.setItem('token', response.data.token);
+        router.push('/dashboard');
+      })
+      .catch(() => router.push('/'));
+  }, [code, router]);
 
   return (
     <div className="min-h-screen flex items-center justify-center bg-gray-50">
@@ -12,6 +12,8 @@ interface Playlist {
     image_url: string;
     is_public: boolean;
     service_type: string;
+    owner_name?: string;
+    followed?: boolean;
 }
 
 interface PlaylistsProps {
@@ -124,6 +126,7 @@ export default function Playlists({ service, isConnected }: PlaylistsProps) {
                                     </h4>
                                     <p className="text-sm text-gray-500 mt-1">
                                         {playlist.track_count} tracks
+                                        {playlist.followed && playlist.owner_name && ` · by ${playlist.owner_name}`}
                                     </p>
                                     {playlist.description && (
                                         <p className="text-xs text-gray-400 mt-1 truncate">
@@ -139,6 +142,11 @@ export default function Playlists({ service, isConnected }: PlaylistsProps) {
                                         >
                                             {playlist.is_public ? 'Public' : 'Private'}
                                         </span>
+                                        {playlist.followed && (
+                                            <span className="ml-2 inline-flex items-center px-2 py-1 rounded-full text-xs font-medium bg-blue-100 text-blue-800">
+                                                Followed
+                                            </span>
+                                        )}
                                     </div>
                                 </div>
                             </div>
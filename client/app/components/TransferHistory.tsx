@@ -13,6 +13,7 @@ interface Transfer {
     tracks_total: number;
     tracks_matched: number;
     tracks_failed: number;
+    tracks_skipped?: number;
     CreatedAt: string;
     UpdatedAt: string;
 }
@@ -27,6 +28,7 @@ interface TransferTrack {
     target_artist: string;
     status: string;
     match_confidence: number;
+    flagged?: boolean;
 }
 
 export default function TransferHistory() {
@@ -78,6 +80,20 @@ export default function TransferHistory() {
         }
     };
 
+    const rerunTransfer = async (transferId: number) => {
+        try {
+            setError('');
+            const token = localStorage.getItem('token');
+            await axios.post(`http://localhost:8080/api/transfers/${transferId}/rerun`, {}, {
+                headers: { Authorization: `Bearer ${token}` }
+            });
+            fetchTransfers();
+        } catch (error: any) {
+            console.error('Failed to re-run transfer:', error);
+            setError(error.response?.data?.error || 'Failed to re-run transfer');
+        }
+    };
+
     useEffect(() => {
         fetchTransfers();
     }, []);
@@ -89,9 +105,16 @@ export default function TransferHistory() {
             case 'completed_with_errors':
                 return 'bg-yellow-100 text-yellow-800';
             case 'failed':
+            case 'provider_unavailable':
+            case 'quota_exceeded':
                 return 'bg-red-100 text-red-800';
             case 'processing':
+            case 'creating_playlist':
+            case 'matching':
+            case 'adding_tracks':
                 return 'bg-blue-100 text-blue-800';
+            case 'paused':
+                return 'bg-purple-100 text-purple-800';
             case 'pending':
                 return 'bg-gray-100 text-gray-800';
             default:
@@ -126,6 +149,13 @@ export default function TransferHistory() {
             'completed_with_errors': 'Completed with errors',
             'failed': 'Failed',
             'processing': 'Processing',
+            'creating_playlist': 'Creating playlist',
+            'matching': 'Matching tracks',
+            'adding_tracks': 'Adding tracks',
+            'paused': 'Paused until tomorrow',
+            'quota_exceeded': 'Quota exceeded',
+            'provider_unavailable': 'Provider unavailable',
+            'cancelled': 'Cancelled',
             'pending': 'Pending'
         };
         return statusMap[status] || status;
@@ -181,6 +211,9 @@ export default function TransferHistory() {
                                         {transfer.tracks_failed > 0 && (
                                             <span className="text-red-600"> ({transfer.tracks_failed} failed)</span>
                                         )}
+                                        {!!transfer.tracks_skipped && (
+                                            <span className="text-gray-500"> ({transfer.tracks_skipped} skipped)</span>
+                                        )}
                                     </p>
                                 </div>
                                 <div className="flex flex-col items-end space-y-2 ml-4">
@@ -190,6 +223,15 @@ export default function TransferHistory() {
                                     <span className="text-xs text-gray-500 text-right">
                                         {formatDate(transfer.CreatedAt)}
                                     </span>
+                                    <button
+                                        onClick={(e) => {
+                                            e.stopPropagation();
+                                            rerunTransfer(transfer.ID);
+                                        }}
+                                        className="text-xs text-blue-600 hover:text-blue-800"
+                                    >
+                                        Run again
+                                    </button>
                                 </div>
                             </div>
 
@@ -209,6 +251,7 @@ export default function TransferHistory() {
                                                         key={track.ID || `track-${index}`}
                                                         className={`flex justify-between items-center py-2 px-3 rounded ${track.status === 'matched' ? 'bg-green-50 border border-green-200' :
                                                             track.status === 'not_found' ? 'bg-red-50 border border-red-200' :
+                                                                track.status === 'skipped_by_rule' ? 'bg-gray-50 border border-gray-200' :
                                                                 'bg-yellow-50 border border-yellow-200'
                                                             }`}
                                                     >
@@ -233,6 +276,8 @@ export default function TransferHistory() {
                                                                         {track.target_artist || 'Unknown artist'}
                                                                     </div>
                                                                 </>
+                                                            ) : track.status === 'skipped_by_rule' ? (
+                                                                <div className="text-gray-500 text-sm">Skipped</div>
                                                             ) : (
                                                                 <div className="text-red-600 text-sm">Not found</div>
                                                             )}
@@ -242,6 +287,8 @@ export default function TransferHistory() {
                                                             {track.status === 'matched' && `Match: ${Math.round(track.match_confidence * 100)}%`}
                                                             {track.status === 'not_found' && 'No match'}
                                                             {track.status === 'error' && 'Error'}
+                                                            {track.status === 'skipped_by_rule' && 'Skip rule'}
+                                                            {track.flagged && ' · Flagged'}
                                                         </div>
                                                     </div>
                                                 ))}
@@ -252,7 +299,8 @@ export default function TransferHistory() {
                                                 <p>
                                                     <span className="font-medium">Summary:</span> {transferTracks.filter(t => t.status === 'matched').length} matched,
                                                     {' '}{transferTracks.filter(t => t.status === 'not_found').length} not found,
-                                                    {' '}{transferTracks.filter(t => t.status === 'error').length} errors
+                                                    {' '}{transferTracks.filter(t => t.status === 'error').length} errors,
+                                                    {' '}{transferTracks.filter(t => t.status === 'skipped_by_rule').length} skipped
                                                 </p>
                                             </div>
                                         </>
@@ -23,6 +23,7 @@ export default function TransferModal({ isOpen, onClose, connectedServices }: Tr
     const [targetService, setTargetService] = useState('');
     const [sourcePlaylist, setSourcePlaylist] = useState('');
     const [targetPlaylistName, setTargetPlaylistName] = useState('');
+    const [highPriority, setHighPriority] = useState(false);
     const [playlists, setPlaylists] = useState<Playlist[]>([]);
     const [loading, setLoading] = useState(false);
     const [playlistsLoading, setPlaylistsLoading] = useState(false);
@@ -63,6 +64,7 @@ export default function TransferModal({ isOpen, onClose, connectedServices }: Tr
 
             setSourcePlaylist('');
             setTargetPlaylistName('');
+            setHighPriority(false);
             setError('');
         } else {
             setSourceService('');
@@ -74,6 +76,20 @@ export default function TransferModal({ isOpen, onClose, connectedServices }: Tr
         }
     }, [isOpen, connectedServices]);
 
+    const startTransfer = async (splitAcrossDays: boolean) => {
+        const token = localStorage.getItem('token');
+        return axios.post('http://localhost:8080/api/transfers', {
+            source_service: sourceService,
+            source_playlist_id: sourcePlaylist,
+            target_service: targetService,
+            target_playlist_name: targetPlaylistName,
+            split_across_days: splitAcrossDays,
+            priority: highPriority ? 'high' : 'normal',
+        }, {
+            headers: { Authorization: `Bearer ${token}` }
+        });
+    };
+
     const handleSubmit = async (e: React.FormEvent) => {
         e.preventDefault();
         if (!sourceService || !targetService || !sourcePlaylist) {
@@ -84,16 +100,18 @@ export default function TransferModal({ isOpen, onClose, connectedServices }: Tr
         try {
             setLoading(true);
             setError('');
-            const token = localStorage.getItem('token');
 
-            const response = await axios.post('http://localhost:8080/api/transfers', {
-                source_service: sourceService,
-                source_playlist_id: sourcePlaylist,
-                target_service: targetService,
-                target_playlist_name: targetPlaylistName,
-            }, {
-                headers: { Authorization: `Bearer ${token}` }
-            });
+            let response;
+            try {
+                response = await startTransfer(false);
+            } catch (err: any) {
+                // Too large for today's YouTube quota: offer to spread it over several days
+                const estimate = err.response?.status === 409 ? err.response.data?.estimate : null;
+                if (!estimate || !confirm(`This playlist needs about ${estimate.days_needed} days of YouTube quota. Transfer it across several days?`)) {
+                    throw err;
+                }
+                response = await startTransfer(true);
+            }
 
             alert(`Transfer started! Transfer ID: ${response.data.transfer_id}`);
             onClose();
@@ -178,14 +196,12 @@ export default function TransferModal({ isOpen, onClose, connectedServices }: Tr
                             required
                         >
                             <option value="">Select target service</option>
-                            {connectedServices
-                                .filter(service => service !== sourceService)
-                                .map(service => (
-                                    <option key={service} value={service}>
-                                        {service === 'spotify' ? 'Spotify' : 'YouTube Music'}
-                                    </option>
-                                ))
-                            }
+                            {connectedServices.map(service => (
+                                <option key={service} value={service}>
+                                    {service === 'spotify' ? 'Spotify' : 'YouTube Music'}
+                                    {service === sourceService ? ' (copy)' : ''}
+                                </option>
+                            ))}
                         </select>
                     </div>
 
@@ -203,6 +219,16 @@ export default function TransferModal({ isOpen, onClose, connectedServices }: Tr
                         />
                     </div>
 
+                    {/* Priority */}
+                    <label className="flex items-center space-x-2 text-sm text-gray-700">
+                        <input
+                            type="checkbox"
+                            checked={highPriority}
+                            onChange={(e) => setHighPriority(e.target.checked)}
+                        />
+                        <span>High priority (run before background syncs)</span>
+                    </label>
+
                     {error && (
                         <div className="bg-red-100 border border-red-400 text-red-700 px-4 py-3 rounded">
                             {error}
@@ -1,7 +1,8 @@
 import type { NextConfig } from "next";
 
 const nextConfig: NextConfig = {
-  /* config options here */
+  // NEXT_OUTPUT=export builds a static bundle the Go server can embed
+  output: process.env.NEXT_OUTPUT === "export" ? "export" : undefined,
 };
 
 export default nextConfig;
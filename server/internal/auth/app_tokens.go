@@ -0,0 +1,198 @@
+package auth
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log"
+	"os"
+	"strings"
+	"sync"
+	"time"
+
+	"server/internal/config"
+
+	"golang.org/x/oauth2"
+	"golang.org/x/oauth2/clientcredentials"
+)
+
+// ErrNoAppCredentials means no usable app-level credential is configured for a service
+var ErrNoAppCredentials = errors.New("no app credentials available")
+
+// appCredential is one pooled client-credentials app or API key
+type appCredential struct {
+	name            string // non-secret label for status output
+	source          oauth2.TokenSource
+	apiKey          string
+	exhaustedUntil  time.Time
+	uses            int64
+	quotaExhaustion int64
+}
+
+// AppTokenManager pools app-level credentials for reading public data:
+// Spotify client-credentials tokens and YouTube Data API keys. Credentials
+// are used round-robin, and a credential that ran out of quota is rested.
+type AppTokenManager struct {
+	spotify []*appCredential
+	youtube []*appCredential
+	next    map[string]int
+	mu      sync.Mutex
+}
+
+// NewAppTokenManager reads SPOTIFY_APP_CREDENTIALS ("id:secret,...") and
+// YOUTUBE_API_KEYS, falling back to the OAuth client and YOUTUBE_API_KEY
+func NewAppTokenManager() *AppTokenManager {
+	m := &AppTokenManager{next: make(map[string]int)}
+
+	pairs := config.List("SPOTIFY_APP_CREDENTIALS", nil)
+	if len(pairs) == 0 && os.Getenv("SPOTIFY_CLIENT_ID") != "" {
+		pairs = []string{os.Getenv("SPOTIFY_CLIENT_ID") + ":" + os.Getenv("SPOTIFY_CLIENT_SECRET")}
+	}
+	for i, pair := range pairs {
+		id, secret, ok := strings.Cut(pair, ":")
+		if !ok || id == "" || secret == "" {
+			log.Printf("Ignoring malformed Spotify app credential #%d", i+1)
+			continue
+		}
+		cfg := &clientcredentials.Config{
+			ClientID:     id,
+			ClientSecret: secret,
+			TokenURL:     "https://accounts.spotify.com/api/token",
+		}
+		// The token source caches each token and fetches a new one once it expires
+		m.spotify = append(m.spotify, &appCredential{
+			name:   fmt.Sprintf("spotify-%d", i+1),
+			source: cfg.TokenSource(context.Background()),
+		})
+	}
+
+	keys := config.List("YOUTUBE_API_KEYS", nil)
+	if len(keys) == 0 && os.Getenv("YOUTUBE_API_KEY") != "" {
+		keys = []string{os.Getenv("YOUTUBE_API_KEY")}
+	}
+	for i, key := range keys {
+		m.youtube = append(m.youtube, &appCredential{name: fmt.Sprintf("youtube-%d", i+1), apiKey: key})
+	}
+
+	return m
+}
+
+// Configured reports whether any app credential exists for the service
+func (m *AppTokenManager) Configured(service string) bool {
+	return len(m.pool(service)) > 0
+}
+
+// SpotifyToken returns a client-credentials access token from the next available app
+func (m *AppTokenManager) SpotifyToken() (string, error) {
+	var lastErr error = ErrNoAppCredentials
+	for range m.spotify {
+		cred := m.pick("spotify")
+		if cred == nil {
+			break
+		}
+
+		token, err := cred.source.Token()
+		if err != nil {
+			log.Printf("Failed to get Spotify app token from %s: %v", cred.name, err)
+			lastErr = err
+			m.rest(cred, time.Minute)
+			continue
+		}
+		return token.AccessToken, nil
+	}
+	return "", lastErr
+}
+
+// YouTubeKey returns the next YouTube API key that has quota left
+func (m *AppTokenManager) YouTubeKey() (string, error) {
+	cred := m.pick("youtube")
+	if cred == nil {
+		return "", ErrNoAppCredentials
+	}
+	return cred.apiKey, nil
+}
+
+// ReportYouTubeQuotaExceeded rests a key until the daily quota resets (midnight Pacific time)
+func (m *AppTokenManager) ReportYouTubeQuotaExceeded(key string) {
+	for _, cred := range m.youtube {
+		if cred.apiKey == key {
+			m.rest(cred, untilPacificMidnight(time.Now()))
+			m.mu.Lock()
+			cred.quotaExhaustion++
+			m.mu.Unlock()
+			log.Printf("YouTube app key %s exhausted its quota", cred.name)
+		}
+	}
+}
+
+// Status describes the pool without exposing secrets
+func (m *AppTokenManager) Status() map[string]interface{} {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	describe := func(pool []*appCredential) []map[string]interface{} {
+		out := make([]map[string]interface{}, 0, len(pool))
+		for _, cred := range pool {
+			entry := map[string]interface{}{
+				"name":              cred.name,
+				"uses":              cred.uses,
+				"quota_exhaustions": cred.quotaExhaustion,
+				"available":         time.Now().After(cred.exhaustedUntil),
+			}
+			if !cred.exhaustedUntil.IsZero() {
+				entry["resting_until"] = cred.exhaustedUntil
+			}
+			out = append(out, entry)
+		}
+		return out
+	}
+
+	return map[string]interface{}{
+		"spotify": describe(m.spotify),
+		"youtube": describe(m.youtube),
+	}
+}
+
+func (m *AppTokenManager) pool(service string) []*appCredential {
+	switch service {
+	case "spotify":
+		return m.spotify
+	case "youtube":
+		return m.youtube
+	}
+	return nil
+}
+
+// pick returns the next credential round-robin, skipping rested ones
+func (m *AppTokenManager) pick(service string) *appCredential {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	pool := m.pool(service)
+	now := time.Now()
+	for i := 0; i < len(pool); i++ {
+		cred := pool[(m.next[service]+i)%len(pool)]
+		if now.After(cred.exhaustedUntil) {
+			m.next[service] = (m.next[service] + i + 1) % len(pool)
+			cred.uses++
+			return cred
+		}
+	}
+	return nil
+}
+
+func (m *AppTokenManager) rest(cred *appCredential, d time.Duration) {
+	m.mu.Lock()
+	cred.exhaustedUntil = time.Now().Add(d)
+	m.mu.Unlock()
+}
+
+func untilPacificMidnight(now time.Time) time.Duration {
+	loc, err := time.LoadLocation("America/Los_Angeles")
+	if err != nil {
+		return 24 * time.Hour
+	}
+	local := now.In(loc)
+	midnight := time.Date(local.Year(), local.Month(), local.Day()+1, 0, 0, 0, 0, loc)
+	return midnight.Sub(now)
+}
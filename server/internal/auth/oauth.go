@@ -29,7 +29,7 @@ func InitOAuthConfigs() {
 		ClientID:     os.Getenv("SPOTIFY_CLIENT_ID"),
 		ClientSecret: os.Getenv("SPOTIFY_CLIENT_SECRET"),
 		RedirectURL:  os.Getenv("BACKEND_URL") + "/api/services/callback/spotify",
-		Scopes:       []string{"playlist-read-private", "playlist-read-collaborative", "playlist-modify-public", "playlist-modify-private"},
+		Scopes:       []string{"playlist-read-private", "playlist-read-collaborative", "playlist-modify-public", "playlist-modify-private", "user-read-playback-state", "user-modify-playback-state", "user-read-private"},
 		Endpoint:     spotify.Endpoint,
 	}
 
@@ -8,17 +8,25 @@ import (
 	"time"
 
 	"server/internal/database"
+	"server/internal/lock"
 
 	"golang.org/x/oauth2"
 	"gorm.io/gorm"
 )
 
+// validationClient is shared by token validation calls so connections are reused
+var validationClient = &http.Client{Timeout: 10 * time.Second}
+
+// refreshLockTimeout bounds how long a refresh waits for another instance's refresh
+const refreshLockTimeout = 30 * time.Second
+
 type TokenManager struct {
-	db *gorm.DB
+	db     *gorm.DB
+	locker lock.Locker // serializes refreshes of a connection across instances
 }
 
-func NewTokenManager(db *gorm.DB) *TokenManager {
-	return &TokenManager{db: db}
+func NewTokenManager(db *gorm.DB, locker lock.Locker) *TokenManager {
+	return &TokenManager{db: db, locker: locker}
 }
 
 // RefreshTokenIfNeeded checks if token needs refresh and refreshes it
@@ -28,6 +36,24 @@ func (tm *TokenManager) RefreshTokenIfNeeded(userService *database.UserService)
 		return nil // Token is still valid
 	}
 
+	// Serialize refreshes of one connection across instances; providers may
+	// rotate the refresh token, so concurrent refreshes can invalidate each other
+	ctx, cancel := context.WithTimeout(context.Background(), refreshLockTimeout)
+	defer cancel()
+	release, err := tm.locker.Lock(ctx, fmt.Sprintf("token-refresh:%d", userService.ID))
+	if err != nil {
+		return fmt.Errorf("failed to lock token refresh: %v", err)
+	}
+	defer release()
+
+	// Another instance may have refreshed while we waited for the lock
+	var current database.UserService
+	if err := tm.db.First(&current, userService.ID).Error; err == nil &&
+		current.AccessToken != userService.AccessToken && current.TokenExpiry > time.Now().Add(5*time.Minute).Unix() {
+		*userService = current
+		return nil
+	}
+
 	log.Printf("Refreshing token for %s service (user %d)", userService.ServiceType, userService.UserID)
 
 	config := GetOAuthConfig(userService.ServiceType)
@@ -45,7 +71,7 @@ func (tm *TokenManager) RefreshTokenIfNeeded(userService *database.UserService)
 	tokenSource := config.TokenSource(context.Background(), token)
 	newToken, err := tokenSource.Token()
 	if err != nil {
-		return fmt.Errorf("failed to refresh token: %v", err)
+		return fmt.Errorf("failed to refresh token: %w", err)
 	}
 
 	// Update the service with new tokens
@@ -84,35 +110,46 @@ func (tm *TokenManager) ValidateToken(userService *database.UserService) (bool,
 }
 
 func (tm *TokenManager) validateSpotifyToken(accessToken string) (bool, error) {
-	client := &http.Client{}
 	req, err := http.NewRequest("GET", "https://api.spotify.com/v1/me", nil)
 	if err != nil {
 		return false, err
 	}
 
 	req.Header.Set("Authorization", "Bearer "+accessToken)
-	resp, err := client.Do(req)
+	resp, err := validationClient.Do(req)
 	if err != nil {
 		return false, err
 	}
 	defer resp.Body.Close()
 
-	return resp.StatusCode == http.StatusOK, nil
+	return validationResult(resp.StatusCode)
 }
 
 func (tm *TokenManager) validateYouTubeToken(accessToken string) (bool, error) {
-	client := &http.Client{}
 	req, err := http.NewRequest("GET", "https://www.googleapis.com/oauth2/v2/userinfo", nil)
 	if err != nil {
 		return false, err
 	}
 
 	req.Header.Set("Authorization", "Bearer "+accessToken)
-	resp, err := client.Do(req)
+	resp, err := validationClient.Do(req)
 	if err != nil {
 		return false, err
 	}
 	defer resp.Body.Close()
 
-	return resp.StatusCode == http.StatusOK, nil
+	return validationResult(resp.StatusCode)
+}
+
+// validationResult interprets the status of a validation call: only 400 and 401
+// mean the token was rejected; rate limits and server errors are inconclusive
+func validationResult(status int) (bool, error) {
+	switch status {
+	case http.StatusOK:
+		return true, nil
+	case http.StatusBadRequest, http.StatusUnauthorized:
+		return false, nil
+	default:
+		return false, fmt.Errorf("validation returned status %d", status)
+	}
 }
@@ -0,0 +1,109 @@
+package cache
+
+import (
+	"sync"
+	"time"
+)
+
+// Cache stores opaque values with a time-to-live. Values are raw bytes so a
+// shared backend (e.g. Redis) can implement the same interface.
+type Cache interface {
+	Get(key string) ([]byte, bool)
+	Set(key string, value []byte, ttl time.Duration)
+	Delete(key string)
+}
+
+type entry struct {
+	value     []byte
+	expiresAt time.Time
+}
+
+// MemoryCache is an in-process Cache bounded by a maximum number of entries
+type MemoryCache struct {
+	entries    map[string]entry
+	maxEntries int
+	mu         sync.RWMutex
+}
+
+func NewMemoryCache(maxEntries int) *MemoryCache {
+	c := &MemoryCache{
+		entries:    make(map[string]entry),
+		maxEntries: maxEntries,
+	}
+
+	// Periodically drop expired entries
+	ticker := time.NewTicker(time.Minute)
+	go func() {
+		for range ticker.C {
+			c.evictExpired()
+		}
+	}()
+
+	return c
+}
+
+// Get returns the value for key if it exists and has not expired
+func (c *MemoryCache) Get(key string) ([]byte, bool) {
+	c.mu.RLock()
+	e, exists := c.entries[key]
+	c.mu.RUnlock()
+
+	if !exists || time.Now().After(e.expiresAt) {
+		return nil, false
+	}
+
+	return e.value, true
+}
+
+// Set stores value under key for ttl
+func (c *MemoryCache) Set(key string, value []byte, ttl time.Duration) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	if _, exists := c.entries[key]; !exists && c.maxEntries > 0 && len(c.entries) >= c.maxEntries {
+		c.evictOldestLocked()
+	}
+
+	c.entries[key] = entry{value: value, expiresAt: time.Now().Add(ttl)}
+}
+
+// Delete removes key from the cache
+func (c *MemoryCache) Delete(key string) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	delete(c.entries, key)
+}
+
+// Len returns the number of stored entries, including expired ones not yet evicted
+func (c *MemoryCache) Len() int {
+	c.mu.RLock()
+	defer c.mu.RUnlock()
+
+	return len(c.entries)
+}
+
+func (c *MemoryCache) evictExpired() {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	now := time.Now()
+	for key, e := range c.entries {
+		if now.After(e.expiresAt) {
+			delete(c.entries, key)
+		}
+	}
+}
+
+// evictOldestLocked removes the entry closest to expiry; callers must hold the write lock
+func (c *MemoryCache) evictOldestLocked() {
+	var oldestKey string
+	var oldest time.Time
+	for key, e := range c.entries {
+		if oldestKey == "" || e.expiresAt.Before(oldest) {
+			oldestKey = key
+			oldest = e.expiresAt
+		}
+	}
+	delete(c.entries, oldestKey)
+}
@@ -4,6 +4,7 @@ import (
 	"fmt"
 	"log"
 	"os"
+	"time"
 
 	"gorm.io/driver/postgres"
 	"gorm.io/gorm"
@@ -18,6 +19,67 @@ type User struct {
 	Email     string `gorm:"uniqueIndex"`
 	Name      string
 	AvatarURL string
+	FeedToken string `gorm:"index" json:"-"` // secret for the activity feed URL
+
+	ShareAnonymousStats bool `gorm:"default:false" json:"share_anonymous_stats"` // include in public aggregate stats
+
+	TOTPSecret   string `json:"-"`                      // base32 secret, set at enrollment
+	TOTPEnabled  bool   `gorm:"default:false" json:"-"` // login requires a TOTP or backup code
+	TOTPLastStep int64  `json:"-"`                      // last accepted TOTP step, so codes can't be replayed
+}
+
+// TOTPBackupCode is a single-use code for signing in without the authenticator app
+type TOTPBackupCode struct {
+	ID       uint   `gorm:"primaryKey"`
+	UserID   uint   `gorm:"not null;index"`
+	CodeHash string `gorm:"not null"` // SHA-256 of the code shown to the user
+	UsedAt   int64  // unix time, 0 while unused
+}
+
+// AuthCode is a short-lived, single-use code the frontend exchanges for a JWT,
+// so tokens never appear in redirect URLs
+type AuthCode struct {
+	gorm.Model
+	CodeHash  string `gorm:"not null;uniqueIndex"` // SHA-256 of the code handed to the client
+	UserID    uint   `gorm:"not null"`
+	ExpiresAt int64  `gorm:"not null"`
+}
+
+// Session is a login on one device, identified by the ID claim of its JWT
+type Session struct {
+	ID         uint   `gorm:"primaryKey" json:"id"`
+	UserID     uint   `gorm:"not null;index" json:"-"`
+	TokenID    string `gorm:"not null;uniqueIndex" json:"-"`
+	UserAgent  string `json:"user_agent"`
+	IP         string `json:"ip"`
+	IssuedAt   int64  `json:"issued_at"`
+	LastUsedAt int64  `json:"last_used_at"`
+	ExpiresAt  int64  `json:"expires_at"`
+	RevokedAt  int64  `json:"revoked_at,omitempty"`
+}
+
+// FeatureFlag overrides a flag's environment default at runtime
+type FeatureFlag struct {
+	gorm.Model
+	Name        string `gorm:"not null;uniqueIndex" json:"name"`
+	Enabled     bool   `json:"enabled"`    // master switch; when false the flag is off for everyone
+	Percentage  int    `json:"percentage"` // share of users (0-100) the flag is rolled out to
+	UserIDs     string `json:"user_ids"`   // comma-separated users that always get the flag
+	Description string `json:"description"`
+}
+
+// UserSettings holds per-user defaults consumed by the transfer engine
+type UserSettings struct {
+	gorm.Model
+	UserID               uint    `gorm:"not null;uniqueIndex" json:"-"`
+	DefaultPrivacy       string  `gorm:"default:private" json:"default_privacy"` // "private", "unlisted", "public" or "mirror" (match the source)
+	MinConfidence        float64 `json:"min_confidence"`                         // matches below this count as not found
+	NotificationChannels string  `json:"-"`                                      // comma-separated notifier names, empty for all
+	MatchStrategy        string  `gorm:"default:fuzzy" json:"match_strategy"`    // "fuzzy" or "isrc_first"
+	Region               string  `json:"region"`                                 // ISO 3166-1 market, overrides the Spotify profile
+	DescriptionTemplate  string  `json:"description_template"`                   // default description for created playlists
+	WeeklyDigest         bool    `json:"weekly_digest"`                          // opted in to the weekly summary
+	LastDigestAt         int64   `json:"-"`
 }
 
 type UserService struct {
@@ -29,61 +91,501 @@ type UserService struct {
 	TokenExpiry     int64  `json:"token_expiry"`
 	ServiceUserID   string `json:"service_user_id"`
 	ServiceUserName string `json:"service_user_name"`
+	Market          string `json:"market"` // ISO 3166-1 country from the service profile (Spotify only)
+
+	// Health of the stored tokens, checked in the background
+	Status        string `gorm:"default:active" json:"status"` // ServiceActive or ServiceNeedsReauth
+	StatusReason  string `json:"status_reason,omitempty"`
+	FailedChecks  int    `json:"-"` // consecutive checks the token was rejected
+	LastCheckedAt int64  `json:"last_checked_at"`
 }
 
+// Connection states of a UserService
+const (
+	ServiceActive      = "active"
+	ServiceNeedsReauth = "needs_reauth" // tokens were revoked or expired; the user must reconnect
+)
+
 type Playlist struct {
 	gorm.Model
-	UserID       uint   `gorm:"not null" json:"user_id"`
-	ServiceType  string `gorm:"not null" json:"service_type"` // "spotify", "youtube"
-	ServiceID    string `gorm:"not null" json:"service_id"`   // ID from the service
-	Name         string `json:"name"`
-	Description  string `json:"description"`
-	TrackCount   int    `json:"track_count"`
-	ImageURL     string `json:"image_url"`
-	IsPublic     bool   `json:"is_public"`
-	LastSyncedAt int64  `json:"last_synced_at"`
+	UserID        uint          `gorm:"not null;uniqueIndex:idx_playlists_user_service_id" json:"user_id"`
+	ServiceType   string        `gorm:"not null;uniqueIndex:idx_playlists_user_service_id" json:"service_type"` // "spotify", "youtube"
+	ServiceID     string        `gorm:"not null;uniqueIndex:idx_playlists_user_service_id" json:"service_id"`   // ID from the service
+	Name          string        `json:"name"`
+	Description   string        `json:"description"`
+	TrackCount    int           `json:"track_count"`
+	ImageURL      string        `json:"image_url"`
+	IsPublic      bool          `json:"is_public"`
+	Collaborative bool          `json:"collaborative"`
+	OwnerID       string        `json:"owner_id"`   // service user ID of the playlist owner
+	OwnerName     string        `json:"owner_name"` // display name of the playlist owner
+	Followed      bool          `json:"followed"`   // owned by another account; usable as a source only
+	LastSyncedAt  int64         `json:"last_synced_at"`
+	SnapshotID    string        `json:"snapshot_id,omitempty"` // Spotify version the stored tracks were taken from
+	Tags          []PlaylistTag `gorm:"foreignKey:PlaylistID" json:"tags,omitempty"`
+
+	// Spotify-generated playlists (Discover Weekly, Release Radar, ...) change every week
+	Algorithmic          bool   `json:"algorithmic"`
+	ArchiveWeekly        bool   `json:"archive_weekly"`         // snapshot into a dated playlist every week
+	ArchiveTargetService string `json:"archive_target_service"` // service the snapshots are created on
+	LastArchivedAt       int64  `json:"last_archived_at"`
+
+	ArtworkSource string `json:"-"`                              // ImageURL the cached artwork was fetched from
+	ArtworkURL    string `gorm:"-" json:"artwork_url,omitempty"` // proxied, cached copy of ImageURL
+}
+
+// PlaylistChange is a difference to a stored playlist noticed by a sync
+type PlaylistChange struct {
+	ID          uint   `gorm:"primaryKey" json:"id"`
+	UserID      uint   `gorm:"not null;index" json:"user_id"`
+	ServiceType string `gorm:"not null" json:"service_type"`
+	ServiceID   string `gorm:"not null" json:"service_id"`
+	Name        string `json:"name"`
+	Change      string `gorm:"not null" json:"change"` // "added", "removed", "renamed" or "tracks_changed"
+	Detail      string `json:"detail,omitempty"`
+	DetectedAt  int64  `gorm:"index" json:"detected_at"`
+}
+
+// Export is a generated download of the user's stored playlists. Large
+// exports are built in the background and fetched through a signed URL.
+type Export struct {
+	ID        uint   `gorm:"primaryKey" json:"id"`
+	UserID    uint   `gorm:"not null;index" json:"-"`
+	Format    string `gorm:"not null" json:"format"` // "csv" or "zip"
+	Status    string `gorm:"not null" json:"status"` // "pending", "completed" or "failed"
+	Key       string `json:"-"`                      // object key in storage
+	Size      int64  `json:"size"`                   // bytes
+	Error     string `json:"error,omitempty"`
+	CreatedAt int64  `json:"created_at"`
+	ExpiresAt int64  `gorm:"index" json:"expires_at"` // file is deleted after this
+}
+
+// PlaylistTag groups stored playlists into user-defined folders/tags
+type PlaylistTag struct {
+	gorm.Model
+	PlaylistID uint   `gorm:"not null;uniqueIndex:idx_playlist_tags_playlist_tag" json:"playlist_id"`
+	UserID     uint   `gorm:"not null;index" json:"user_id"`
+	Tag        string `gorm:"not null;uniqueIndex:idx_playlist_tags_playlist_tag" json:"tag"`
 }
 
 type PlaylistTrack struct {
 	gorm.Model
-	PlaylistID   uint   `gorm:"not null" json:"playlist_id"`
-	ServiceType  string `gorm:"not null" json:"service_type"`
-	ServiceID    string `gorm:"not null" json:"service_id"` // Track ID from the service
-	Title        string `json:"title"`
-	Artist       string `json:"artist"`
-	Album        string `json:"album"`
-	Duration     int    `json:"duration"` // in milliseconds
-	ISRC         string `json:"isrc"`     // International Standard Recording Code
-	ThumbnailURL string `json:"thumbnail_url"`
+	PlaylistID   uint    `gorm:"not null;index" json:"playlist_id"`
+	ServiceType  string  `gorm:"not null" json:"service_type"`
+	ServiceID    string  `gorm:"not null" json:"service_id"` // Track ID from the service
+	Title        string  `json:"title"`
+	Artist       string  `json:"artist"`
+	Album        string  `json:"album"`
+	Duration     int     `json:"duration"` // in milliseconds
+	ISRC         string  `json:"isrc"`     // International Standard Recording Code
+	ThumbnailURL string  `json:"thumbnail_url"`
+	AddedAt      int64   `json:"added_at"` // when the track was added to the playlist
+	AddedBy      string  `json:"added_by"` // service user ID of whoever added it, "" if unknown
+	Tempo        float64 `json:"tempo"`    // BPM from Spotify audio features, 0 if unknown
+
+	// YouTube video details from the Videos API, empty for other services
+	VideoCategory string `json:"video_category,omitempty"`
+	ChannelID     string `json:"channel_id,omitempty"`
+	ChannelTitle  string `json:"channel_title,omitempty"`
+
+	CanonicalTrackID uint `gorm:"index" json:"canonical_track_id,omitempty"`
+}
+
+// CanonicalTrack is a recording independent of any service, shared across users
+type CanonicalTrack struct {
+	gorm.Model
+	ISRC          string          `gorm:"index" json:"isrc"`
+	MusicBrainzID string          `gorm:"index" json:"musicbrainz_id"`
+	Title         string          `json:"title"`
+	Artist        string          `json:"artist"`
+	Album         string          `json:"album"`
+	Duration      int             `json:"duration"` // in milliseconds
+	Identities    []TrackIdentity `gorm:"foreignKey:CanonicalTrackID" json:"identities,omitempty"`
+}
+
+// TrackIdentity maps a service's track ID to its canonical track
+type TrackIdentity struct {
+	gorm.Model
+	ServiceType      string  `gorm:"not null;uniqueIndex:idx_track_identities_service_track" json:"service_type"`
+	ServiceTrackID   string  `gorm:"not null;uniqueIndex:idx_track_identities_service_track" json:"service_track_id"`
+	CanonicalTrackID uint    `gorm:"not null;index" json:"canonical_track_id"`
+	Source           string  `json:"source"`     // how the mapping was made: "isrc", "musicbrainz", "match", "new"
+	Confidence       float64 `json:"confidence"` // match confidence for "match" mappings, 1 otherwise
+}
+
+// UserTrackLink remembers a user's fuzzy cross-service match. Unlike a
+// TrackIdentity it only applies to that user's transfers and syncs.
+type UserTrackLink struct {
+	gorm.Model
+	UserID        uint    `gorm:"not null;uniqueIndex:idx_user_track_links_track" json:"user_id"`
+	SourceService string  `gorm:"not null;uniqueIndex:idx_user_track_links_track" json:"source_service"`
+	SourceTrackID string  `gorm:"not null;uniqueIndex:idx_user_track_links_track" json:"source_track_id"`
+	TargetService string  `gorm:"not null;uniqueIndex:idx_user_track_links_track" json:"target_service"`
+	TargetTrackID string  `gorm:"not null" json:"target_track_id"`
+	Confidence    float64 `json:"confidence"`
+}
+
+// TransferStatus is the lifecycle state of a transfer
+type TransferStatus string
+
+const (
+	TransferPending             TransferStatus = "pending"
+	TransferProcessing          TransferStatus = "processing" // refreshing tokens and fetching the source
+	TransferCreatingPlaylist    TransferStatus = "creating_playlist"
+	TransferMatching            TransferStatus = "matching" // resolving known counterparts of the source tracks
+	TransferAddingTracks        TransferStatus = "adding_tracks"
+	TransferPaused              TransferStatus = "paused"      // split transfer waiting for its next daily chunk
+	TransferMaintenance         TransferStatus = "maintenance" // held until its providers leave maintenance mode
+	TransferQuotaExceeded       TransferStatus = "quota_exceeded"
+	TransferCompleted           TransferStatus = "completed"
+	TransferCompletedWithErrors TransferStatus = "completed_with_errors"
+	TransferFailed              TransferStatus = "failed"
+	TransferProviderUnavailable TransferStatus = "provider_unavailable"
+	TransferCancelled           TransferStatus = "cancelled"
+)
+
+// ActiveTransferStatuses are the states of a transfer that is queued or running
+var ActiveTransferStatuses = []TransferStatus{
+	TransferPending, TransferProcessing, TransferCreatingPlaylist, TransferMatching, TransferAddingTracks, TransferMaintenance,
+}
+
+// Phase groups a status into "queued", "running", "paused" or "finished"
+func (s TransferStatus) Phase() string {
+	switch s {
+	case TransferPending:
+		return "queued"
+	case TransferProcessing, TransferCreatingPlaylist, TransferMatching, TransferAddingTracks:
+		return "running"
+	case TransferPaused, TransferMaintenance:
+		return "paused"
+	default:
+		return "finished"
+	}
 }
 
 type Transfer struct {
 	gorm.Model
-	UserID             uint   `gorm:"not null" json:"user_id"`
-	SourceService      string `gorm:"not null" json:"source_service"`
-	SourcePlaylistID   string `gorm:"not null" json:"source_playlist_id"`
-	SourcePlaylistName string `json:"source_playlist_name"`
-	TargetService      string `gorm:"not null" json:"target_service"`
-	TargetPlaylistID   string `json:"target_playlist_id"`
-	TargetPlaylistName string `json:"target_playlist_name"`
-	Status             string `gorm:"not null" json:"status"` // "pending", "processing", "completed", "completed_with_errors", "failed"
-	TracksTotal        int    `json:"tracks_total"`
-	TracksMatched      int    `json:"tracks_matched"`
-	TracksFailed       int    `json:"tracks_failed"`
-	ErrorMessage       string `json:"error_message"`
+	UserID              uint           `gorm:"not null" json:"user_id"`
+	SourceService       string         `gorm:"not null" json:"source_service"`
+	SourcePlaylistID    string         `gorm:"not null" json:"source_playlist_id"`
+	SourcePlaylistName  string         `json:"source_playlist_name"`
+	TargetService       string         `gorm:"not null" json:"target_service"`
+	TargetPlaylistID    string         `json:"target_playlist_id"`
+	TargetPlaylistName  string         `json:"target_playlist_name"`
+	Status              TransferStatus `gorm:"not null" json:"status"`
+	TracksTotal         int            `json:"tracks_total"`
+	TracksMatched       int            `json:"tracks_matched"`
+	TracksFailed        int            `json:"tracks_failed"`
+	TracksSkipped       int            `json:"tracks_skipped"`     // left out by the user's skip rules
+	TracksUnsupported   int            `json:"tracks_unsupported"` // local files, podcast episodes and removed tracks, not part of tracks_total
+	ErrorMessage        string         `json:"error_message"`
+	StartPlayback       bool           `json:"start_playback"`                          // start playing the new Spotify playlist when done
+	TargetPlaylistURL   string         `json:"target_playlist_url"`                     // share link to the created playlist
+	TargetCollaborative bool           `json:"target_collaborative"`                    // created as a collaborative playlist
+	ExistingTarget      bool           `json:"existing_target"`                         // tracks added to a playlist the user already had
+	YouTubeVideoType    string         `json:"youtube_video_type"`                      // preferred upload kind on YouTube: "official_video", "audio", "lyric_video"
+	DescriptionTemplate string         `json:"description_template"`                    // overrides the user's default playlist description
+	PublicSource        bool           `json:"public_source"`                           // source fetched with app credentials instead of the user's connection
+	SplitAcrossDays     bool           `json:"split_across_days"`                       // pause instead of failing when today's YouTube quota runs out
+	Priority            string         `gorm:"not null;default:normal" json:"priority"` // "low", "normal" or "high"
+	SourceSnapshotID    string         `json:"source_snapshot_id,omitempty"`            // Spotify version of the source when the transfer read it
+	SourceChanged       bool           `json:"source_changed,omitempty"`                // the source was modified while the transfer ran
+	RerunOfID           *uint          `json:"rerun_of_id,omitempty"`                   // transfer this one repeats
+	AutoSyncRuleID      *uint          `json:"auto_sync_rule_id,omitempty"`             // rule that started the transfer for a new source playlist
+	OnNameConflict      string         `json:"on_name_conflict,omitempty"`              // "duplicate", "rename", "append" or "fail" when the target name is taken
+	OrderByAddedAt      bool           `json:"order_by_added_at,omitempty"`             // tracks added oldest first instead of in source order
+	Privacy             string         `json:"privacy,omitempty"`                       // requested visibility of a created playlist, "" for the user's default
+	ErrorCode           string         `json:"error_code,omitempty"`                    // machine-readable reason for some failures, e.g. "target_name_exists"
+	TargetNameOriginal  string         `json:"target_name_original,omitempty"`          // requested name, when it was changed to suit the target service
+	DescriptionAdjusted bool           `json:"description_adjusted,omitempty"`          // description was shortened or cleaned for the target service
+	SearchStrategies    string         `json:"search_strategies,omitempty"`             // comma-separated search strategy order, "" for the provider's
+	SearchLimit         int            `json:"search_limit,omitempty"`                  // candidates requested per search, 0 for the provider's
+}
+
+// TransferEvent is an append-only log entry of what happened during a transfer
+type TransferEvent struct {
+	ID         uint           `gorm:"primaryKey" json:"id"`
+	TransferID uint           `gorm:"not null;index" json:"transfer_id"`
+	CreatedAt  time.Time      `json:"created_at"`
+	Type       string         `gorm:"not null" json:"type"` // "status", "error", "retry", "warning", "verify", ...
+	Status     TransferStatus `json:"status,omitempty"`     // status the transfer moved to, for "status" events
+	Message    string         `json:"message,omitempty"`
+}
+
+// TransferChunk is one quota day's share of a transfer split across days
+type TransferChunk struct {
+	gorm.Model
+	TransferID   uint   `gorm:"not null;index" json:"transfer_id"`
+	Sequence     int    `json:"sequence"`
+	StartIndex   int    `json:"start_index"` // first source track of the chunk
+	EndIndex     int    `json:"end_index"`   // exclusive
+	QuotaUnits   int    `json:"quota_units"`
+	ScheduledFor int64  `json:"scheduled_for"`
+	Status       string `gorm:"not null" json:"status"` // "pending", "running", "completed"
+}
+
+// LibraryTransfer moves a user's whole library from one service to another as a
+// plan of steps: one transfer per playlist, one for the liked songs and one
+// step following the same artists
+type LibraryTransfer struct {
+	gorm.Model
+	UserID        uint   `gorm:"not null;index" json:"user_id"`
+	SourceService string `gorm:"not null" json:"source_service"`
+	TargetService string `gorm:"not null" json:"target_service"`
+	Status        string `gorm:"not null" json:"status"` // LibraryTransferRunning, LibraryTransferCompleted or LibraryTransferCompletedWithErrors
+	CompletedAt   int64  `json:"completed_at,omitempty"`
+}
+
+const (
+	LibraryTransferRunning             = "running"
+	LibraryTransferCompleted           = "completed"
+	LibraryTransferCompletedWithErrors = "completed_with_errors"
+)
+
+// LibraryTransferStep is one step of a library transfer's plan. Steps start in
+// sequence as the user's concurrent transfer limit allows.
+type LibraryTransferStep struct {
+	ID                uint   `gorm:"primaryKey" json:"id"`
+	LibraryTransferID uint   `gorm:"not null;index" json:"library_transfer_id"`
+	Sequence          int    `json:"sequence"`
+	Kind              string `gorm:"not null" json:"kind"` // "playlist", "liked_songs" or "artists"
+	SourcePlaylistID  string `json:"source_playlist_id,omitempty"`
+	Name              string `json:"name"`
+	Status            string `gorm:"not null" json:"status"`      // "pending", "running", "completed" or "failed"
+	TransferID        *uint  `json:"transfer_id,omitempty"`       // playlist and liked songs steps
+	ArtistsTotal      int    `json:"artists_total,omitempty"`     // artists step only
+	ArtistsFollowed   int    `json:"artists_followed,omitempty"`  // including ones already followed on the target
+	ArtistsNotFound   int    `json:"artists_not_found,omitempty"` // no matching artist on the target
+	Error             string `json:"error,omitempty"`
+}
+
+// Migration is a library migration run as a wizard whose state survives between
+// its steps: analyze (inventory and quota estimate), plan (what to include),
+// execute (a library transfer) and verify (target playlists re-checked)
+type Migration struct {
+	gorm.Model
+	UserID            uint   `gorm:"not null;index" json:"user_id"`
+	SourceService     string `gorm:"not null" json:"source_service"`
+	TargetService     string `gorm:"not null" json:"target_service"`
+	State             string `gorm:"not null" json:"state"` // one of the Migration* states below
+	LibraryTransferID *uint  `json:"library_transfer_id,omitempty"`
+	MissingTracks     int    `json:"missing_tracks"` // transferred tracks the last verification did not find
+	AnalyzedAt        int64  `json:"analyzed_at"`
+	PlannedAt         int64  `json:"planned_at,omitempty"`
+	ExecutedAt        int64  `json:"executed_at,omitempty"`
+	VerifiedAt        int64  `json:"verified_at,omitempty"`
+}
+
+const (
+	MigrationAnalyzed  = "analyzed"
+	MigrationPlanned   = "planned"
+	MigrationExecuting = "executing"
+	MigrationVerifying = "verifying"
+	MigrationVerified  = "verified"
+)
+
+// MigrationItem is an entry of a migration's inventory
+type MigrationItem struct {
+	ID               uint   `gorm:"primaryKey" json:"id"`
+	MigrationID      uint   `gorm:"not null;index" json:"migration_id"`
+	Kind             string `gorm:"not null" json:"kind"` // "playlist", "liked_songs" or "artists", as LibraryTransferStep
+	SourcePlaylistID string `json:"source_playlist_id,omitempty"`
+	Name             string `json:"name"`
+	Count            int    `json:"count"`    // tracks, or artists for the artists item
+	Excluded         bool   `json:"excluded"` // on the user's exclusion list, not selected by default
+	Selected         bool   `json:"selected"`
+	MissingTracks    int    `json:"missing_tracks"` // matched tracks absent from the target when verified
+	VerifyError      string `json:"verify_error,omitempty"`
+}
+
+// QuotaUsage counts the provider quota units consumed on one quota day
+type QuotaUsage struct {
+	ID      uint   `gorm:"primaryKey" json:"-"`
+	Service string `gorm:"not null;uniqueIndex:idx_quota_usage_day" json:"service"`
+	Day     string `gorm:"not null;uniqueIndex:idx_quota_usage_day" json:"day"` // YYYY-MM-DD, Pacific time for YouTube
+	Units   int    `gorm:"not null;default:0" json:"units"`
+}
+
+// Maintenance puts the whole service ("all") or one provider into maintenance mode for as long as its row exists
+type Maintenance struct {
+	Scope     string `gorm:"primaryKey" json:"scope"` // "all", "spotify" or "youtube"
+	Mode      string `gorm:"not null" json:"mode"`    // "queue" holds new transfers until it ends, "reject" refuses them
+	Message   string `json:"message"`
+	StartedAt int64  `gorm:"not null" json:"started_at"`
+	EndsAt    int64  `json:"ends_at,omitempty"` // expected end shown to users, 0 if unknown
+}
+
+// APICall is one outbound provider API call, kept for quota audits and abuse investigation
+type APICall struct {
+	ID            uint   `gorm:"primaryKey" json:"id"`
+	CreatedAt     int64  `gorm:"not null;index" json:"created_at"`
+	Service       string `gorm:"not null" json:"service"`
+	EndpointClass string `gorm:"not null" json:"endpoint_class"` // provider operation, e.g. "search" or "playlist items"
+	Method        string `json:"method"`
+	QuotaCost     int    `json:"quota_cost"`                     // provider quota units, YouTube only
+	UserID        uint   `gorm:"index" json:"user_id,omitempty"` // 0 for calls not made on behalf of a user
+	TransferID    uint   `gorm:"index" json:"transfer_id,omitempty"`
+}
+
+// APICallRollup counts one UTC day's provider calls per user, service and endpoint class
+type APICallRollup struct {
+	ID            uint   `gorm:"primaryKey" json:"-"`
+	Day           string `gorm:"not null;uniqueIndex:idx_api_call_rollup" json:"day"` // YYYY-MM-DD
+	Service       string `gorm:"not null;uniqueIndex:idx_api_call_rollup" json:"service"`
+	EndpointClass string `gorm:"not null;uniqueIndex:idx_api_call_rollup" json:"endpoint_class"`
+	UserID        uint   `gorm:"not null;uniqueIndex:idx_api_call_rollup" json:"user_id"`
+	Calls         int64  `gorm:"not null;default:0" json:"calls"`
+	QuotaUnits    int64  `gorm:"not null;default:0" json:"quota_units"`
 }
 
 type TransferTrack struct {
 	gorm.Model
-	TransferID      uint    `gorm:"not null" json:"transfer_id"`
-	SourceTrackID   string  `json:"source_track_id"`
-	SourceTrackName string  `json:"source_track_name"`
-	SourceArtist    string  `json:"source_artist"`
-	TargetTrackID   string  `json:"target_track_id"`
-	TargetTrackName string  `json:"target_track_name"`
-	TargetArtist    string  `json:"target_artist"`
-	Status          string  `json:"status"`           // "matched", "not_found", "error"
-	MatchConfidence float64 `json:"match_confidence"` // 0.0 to 1.0
+	TransferID       uint    `gorm:"not null" json:"transfer_id"`
+	SourceTrackID    string  `json:"source_track_id"`
+	SourceTrackName  string  `json:"source_track_name"`
+	SourceArtist     string  `json:"source_artist"`
+	TargetTrackID    string  `json:"target_track_id"`
+	TargetTrackName  string  `json:"target_track_name"`
+	TargetArtist     string  `json:"target_artist"`
+	Status           string  `json:"status"`                       // "matched", "not_found", "unavailable_in_region", "skipped_by_rule", "unsupported_source_track", "skipped_episode", "error", "missing_from_target"
+	MatchConfidence  float64 `json:"match_confidence"`             // 0.0 to 1.0
+	RuleID           *uint   `json:"rule_id,omitempty"`            // content rule that skipped, flagged or replaced the track
+	Flagged          bool    `json:"flagged,omitempty"`            // transferred, but marked for review by a rule
+	SearchStrategy   string  `json:"search_strategy,omitempty"`    // strategy that found the match: "isrc", "fielded", "plain", "title_only", "musicbrainz"
+	TargetEntityType string  `json:"target_entity_type,omitempty"` // YouTube upload kind used: "song", "audio", "official_video", "lyric_video", "video"
+	Substitution     string  `json:"substitution,omitempty"`       // why the best match was replaced by the next one: "age_restricted", "region_blocked"
+	AddedAt          int64   `json:"added_at,omitempty"`           // when the track was added to the source playlist
+	AddedBy          string  `json:"added_by,omitempty"`           // service user ID of whoever added it to the source
+}
+
+// SyncLink keeps the target playlist of a transfer mirroring its source:
+// tracks added to the source are added to the target, its order follows the source and
+// removals are handled according to RemovalMode
+type SyncLink struct {
+	gorm.Model
+	UserID            uint   `gorm:"not null;index" json:"user_id"`
+	TransferID        uint   `json:"transfer_id"` // transfer that created the target playlist
+	SourceService     string `gorm:"not null" json:"source_service"`
+	SourcePlaylistID  string `gorm:"not null" json:"source_playlist_id"`
+	TargetService     string `gorm:"not null" json:"target_service"`
+	TargetPlaylistID  string `gorm:"not null" json:"target_playlist_id"`
+	Enabled           bool   `gorm:"not null;default:true" json:"enabled"`
+	Bidirectional     bool   `json:"bidirectional"`                             // changes to the target are copied to the source too
+	RemovalMode       string `gorm:"not null;default:keep" json:"removal_mode"` // SyncRemovalKeep, SyncRemovalRemove or SyncRemovalArchive
+	ArchivePlaylistID string `json:"archive_playlist_id,omitempty"`             // target playlist removed tracks are moved to, created on first use
+	LastSyncedAt      int64  `json:"last_synced_at"`                            // last successful sync
+	LastAttemptAt     int64  `json:"last_attempt_at"`                           // last sync, successful or not
+	LastError         string `json:"last_error,omitempty"`
+	ErrorStreak       int    `gorm:"not null;default:0" json:"error_streak"` // syncs failed in a row
+}
+
+// What a sync link does with target tracks whose source track was removed
+const (
+	SyncRemovalKeep    = "keep"
+	SyncRemovalRemove  = "remove"
+	SyncRemovalArchive = "archive" // moved to the link's archive playlist
+)
+
+// SyncLinkTrack pairs a source track of a sync link with its target counterpart
+type SyncLinkTrack struct {
+	ID            uint   `gorm:"primaryKey" json:"id"`
+	SyncLinkID    uint   `gorm:"not null;index" json:"sync_link_id"`
+	SourceTrackID string `gorm:"not null" json:"source_track_id"` // "" for a target track of a two-way link with no source match
+	TargetTrackID string `json:"target_track_id"`                 // "" when no match was found; not searched again
+}
+
+// SyncConflict is a change to one side of a two-way sync link held back because
+// the other side changed too. It is applied once the user records a Decision.
+type SyncConflict struct {
+	ID            uint   `gorm:"primaryKey" json:"id"`
+	SyncLinkID    uint   `gorm:"not null;index" json:"sync_link_id"`
+	Side          string `gorm:"not null" json:"side"`   // "source" or "target", the playlist that changed
+	Action        string `gorm:"not null" json:"action"` // "add" or "remove"
+	SourceTrackID string `json:"source_track_id,omitempty"`
+	TargetTrackID string `json:"target_track_id,omitempty"`
+	Title         string `json:"title"`
+	Artist        string `json:"artist"`
+	Decision      string `json:"decision,omitempty"` // SyncKeepBoth, SyncPreferSource or SyncPreferTarget; "" while pending
+	CreatedAt     int64  `json:"created_at"`
+}
+
+// Decisions on a SyncConflict. Keeping both never loses a track: additions are
+// copied to the other side and removals are undone.
+const (
+	SyncKeepBoth     = "keep_both"
+	SyncPreferSource = "prefer_source"
+	SyncPreferTarget = "prefer_target"
+)
+
+// PlaylistExclusion keeps a playlist, or every playlist whose name matches a
+// pattern, out of automatic syncing and batch transfers
+type PlaylistExclusion struct {
+	ID          uint   `gorm:"primaryKey" json:"id"`
+	UserID      uint   `gorm:"not null;index" json:"user_id"`
+	ServiceType string `json:"service_type,omitempty"` // "" applies a pattern to every service
+	PlaylistID  string `json:"playlist_id,omitempty"`  // service playlist ID; "" for a pattern
+	Pattern     string `json:"pattern,omitempty"`      // name glob, * and ? wildcards, case-insensitive
+	CreatedAt   int64  `json:"created_at"`
+}
+
+// AutoSyncRule mirrors playlists that newly appear on a user's source account
+// to the target service, optionally keeping each mirror in sync
+type AutoSyncRule struct {
+	gorm.Model
+	UserID        uint   `gorm:"not null;index" json:"user_id"`
+	SourceService string `gorm:"not null" json:"source_service"`
+	TargetService string `gorm:"not null" json:"target_service"`
+	MatchPrefix   string `json:"match_prefix"`                              // only playlists whose name starts with this; all when empty
+	NamePrefix    string `json:"name_prefix"`                               // prepended to the mirrored playlist's name
+	KeepInSync    bool   `json:"keep_in_sync"`                              // link each mirror to its source once transferred
+	RemovalMode   string `gorm:"not null;default:keep" json:"removal_mode"` // of the sync links it creates
+	Enabled       bool   `gorm:"not null;default:true" json:"enabled"`
+	CheckedAt     int64  `json:"checked_at"` // playlists detected up to here have been handled
+}
+
+// ContentRule applies an action to tracks matching all of its conditions during transfers
+type ContentRule struct {
+	gorm.Model
+	UserID      uint                   `gorm:"not null;index" json:"user_id"`
+	Position    int                    `json:"position"`               // evaluated in ascending order, the first match wins
+	Action      string                 `gorm:"not null" json:"action"` // "skip", "flag" or "replace"
+	Replacement string                 `json:"replacement,omitempty"`  // title searched for instead on "replace"; {title} expands to the original
+	Conditions  []ContentRuleCondition `gorm:"foreignKey:RuleID" json:"conditions"`
+}
+
+// ContentRuleCondition tests one attribute of a track
+type ContentRuleCondition struct {
+	ID     uint   `gorm:"primaryKey" json:"id"`
+	RuleID uint   `gorm:"not null;index" json:"rule_id"`
+	Field  string `gorm:"not null" json:"field"` // "title", "artist", "text" (title or artist), "track_id", "duration" (seconds), "explicit"
+	Op     string `gorm:"not null" json:"op"`    // "contains", "equals", "lt", "gt"
+	Value  string `json:"value"`
+}
+
+type DiscordLink struct {
+	gorm.Model
+	UserID            uint   `gorm:"not null;uniqueIndex" json:"user_id"`
+	DiscordUserID     string `gorm:"index" json:"discord_user_id"`
+	LinkCode          string `gorm:"index" json:"-"`
+	LinkCodeExpiresAt int64  `json:"-"`
+	LinkedAt          int64  `json:"linked_at"`
+}
+
+type SlackIntegration struct {
+	gorm.Model
+	UserID     uint   `gorm:"not null;uniqueIndex" json:"user_id"`
+	WebhookURL string `gorm:"not null" json:"-"`
+	Channel    string `json:"channel"` // display only, the webhook decides the channel
+	Enabled    bool   `gorm:"default:true" json:"enabled"`
+}
+
+type TelegramLink struct {
+	gorm.Model
+	UserID            uint   `gorm:"not null;uniqueIndex" json:"user_id"`
+	ChatID            int64  `gorm:"index" json:"chat_id"`
+	LinkCode          string `gorm:"index" json:"-"`
+	LinkCodeExpiresAt int64  `json:"-"`
+	LinkedAt          int64  `json:"linked_at"`
 }
 
 func InitDB() error {
@@ -101,13 +603,63 @@ func InitDB() error {
 		return err
 	}
 
+	// Remove duplicate stored playlists so the unique upsert index can be created
+	if db.Migrator().HasTable(&Playlist{}) && !db.Migrator().HasIndex(&Playlist{}, "idx_playlists_user_service_id") {
+		err = db.Exec(`DELETE FROM playlists a USING playlists b
+			WHERE a.id < b.id AND a.user_id = b.user_id
+			AND a.service_type = b.service_type AND a.service_id = b.service_id`).Error
+		if err != nil {
+			return err
+		}
+	}
+
 	// Auto migrate tables
-	err = db.AutoMigrate(&User{}, &UserService{}, &Playlist{}, &PlaylistTrack{}, &Transfer{}, &TransferTrack{})
+	err = db.AutoMigrate(&User{}, &AuthCode{}, &Session{}, &TOTPBackupCode{}, &FeatureFlag{}, &UserSettings{}, &UserService{}, &Playlist{}, &PlaylistTag{}, &PlaylistChange{}, &Export{}, &PlaylistTrack{}, &CanonicalTrack{}, &TrackIdentity{}, &UserTrackLink{}, &Transfer{}, &TransferTrack{}, &TransferEvent{}, &TransferChunk{}, &LibraryTransfer{}, &LibraryTransferStep{}, &Migration{}, &MigrationItem{}, &SyncLink{}, &SyncLinkTrack{}, &SyncConflict{}, &AutoSyncRule{}, &PlaylistExclusion{}, &QuotaUsage{}, &APICall{}, &APICallRollup{}, &Maintenance{}, &ContentRule{}, &ContentRuleCondition{}, &DiscordLink{}, &SlackIntegration{}, &TelegramLink{})
 	if err != nil {
 		return err
 	}
 
+	// Skip list entries from before the rules engine become single-condition skip rules
+	if db.Migrator().HasTable("skip_rules") {
+		if err := migrateSkipRules(db); err != nil {
+			return err
+		}
+	}
+
 	DB = db
 	log.Println("Database connection established and tables migrated")
 	return nil
 }
+
+// migrateSkipRules converts the rows of the former skip list table into content rules
+func migrateSkipRules(db *gorm.DB) error {
+	var skips []struct {
+		UserID uint
+		Kind   string
+		Value  string
+	}
+	if err := db.Table("skip_rules").Where("deleted_at IS NULL").Find(&skips).Error; err != nil {
+		return err
+	}
+
+	conditions := map[string]ContentRuleCondition{
+		"artist":  {Field: "artist", Op: "equals"},
+		"track":   {Field: "track_id", Op: "equals"},
+		"keyword": {Field: "text", Op: "contains"},
+	}
+	return db.Transaction(func(tx *gorm.DB) error {
+		for _, skip := range skips {
+			condition, ok := conditions[skip.Kind]
+			if !ok {
+				continue
+			}
+			condition.Value = skip.Value
+			rule := ContentRule{UserID: skip.UserID, Action: "skip", Conditions: []ContentRuleCondition{condition}}
+			if err := tx.Create(&rule).Error; err != nil {
+				return err
+			}
+		}
+		log.Printf("Converted %d skip list entries to content rules", len(skips))
+		return tx.Migrator().DropTable("skip_rules")
+	})
+}
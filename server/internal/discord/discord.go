@@ -0,0 +1,211 @@
+package discord
+
+import (
+	"bytes"
+	"context"
+	"crypto/ed25519"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"time"
+)
+
+const apiBaseURL = "https://discord.com/api/v10"
+
+// Interaction and response types from the Discord interactions API
+const (
+	InteractionPing               = 1
+	InteractionApplicationCommand = 2
+
+	ResponsePong           = 1
+	ResponseChannelMessage = 4
+
+	FlagEphemeral = 64
+
+	optionTypeString = 3
+)
+
+// Client talks to the Discord REST API as a bot
+type Client struct {
+	botToken      string
+	applicationID string
+	httpClient    *http.Client
+}
+
+func NewClient(botToken, applicationID string) *Client {
+	return &Client{
+		botToken:      botToken,
+		applicationID: applicationID,
+		httpClient:    &http.Client{Timeout: 10 * time.Second},
+	}
+}
+
+// Enabled reports whether a bot token is configured
+func (c *Client) Enabled() bool {
+	return c.botToken != ""
+}
+
+// SendDirectMessage opens (or reuses) a DM channel with the user and posts content to it
+func (c *Client) SendDirectMessage(ctx context.Context, discordUserID, content string) error {
+	var channel struct {
+		ID string `json:"id"`
+	}
+	if err := c.call(ctx, "POST", "/users/@me/channels", map[string]string{"recipient_id": discordUserID}, &channel); err != nil {
+		return fmt.Errorf("failed to open DM channel: %w", err)
+	}
+
+	return c.call(ctx, "POST", "/channels/"+channel.ID+"/messages", map[string]string{"content": content}, nil)
+}
+
+// RegisterCommands overwrites the application's global slash commands
+func (c *Client) RegisterCommands(ctx context.Context, commands []Command) error {
+	if c.applicationID == "" {
+		return fmt.Errorf("discord application ID not configured")
+	}
+	return c.call(ctx, "PUT", "/applications/"+c.applicationID+"/commands", commands, nil)
+}
+
+func (c *Client) call(ctx context.Context, method, path string, payload interface{}, out interface{}) error {
+	body, err := json.Marshal(payload)
+	if err != nil {
+		return err
+	}
+
+	req, err := http.NewRequestWithContext(ctx, method, apiBaseURL+path, bytes.NewReader(body))
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Authorization", "Bot "+c.botToken)
+	req.Header.Set("Content-Type", "application/json")
+
+	resp, err := c.httpClient.Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode < 200 || resp.StatusCode >= 300 {
+		respBody, _ := io.ReadAll(resp.Body)
+		return fmt.Errorf("discord API returned status %d: %s", resp.StatusCode, string(respBody))
+	}
+
+	if out != nil {
+		return json.NewDecoder(resp.Body).Decode(out)
+	}
+	return nil
+}
+
+// VerifySignature checks the Ed25519 signature Discord attaches to interaction requests
+func VerifySignature(publicKeyHex, signatureHex, timestamp string, body []byte) bool {
+	publicKey, err := hex.DecodeString(publicKeyHex)
+	if err != nil || len(publicKey) != ed25519.PublicKeySize {
+		return false
+	}
+
+	signature, err := hex.DecodeString(signatureHex)
+	if err != nil || len(signature) != ed25519.SignatureSize {
+		return false
+	}
+
+	return ed25519.Verify(publicKey, append([]byte(timestamp), body...), signature)
+}
+
+type User struct {
+	ID       string `json:"id"`
+	Username string `json:"username"`
+}
+
+// Interaction is an incoming slash command (or ping) from Discord
+type Interaction struct {
+	Type int `json:"type"`
+	Data struct {
+		Name    string `json:"name"`
+		Options []struct {
+			Name  string      `json:"name"`
+			Value interface{} `json:"value"`
+		} `json:"options"`
+	} `json:"data"`
+	Member *struct {
+		User User `json:"user"`
+	} `json:"member"`
+	User *User `json:"user"`
+}
+
+// UserID returns the invoking user for both guild and DM interactions
+func (i Interaction) UserID() string {
+	if i.Member != nil {
+		return i.Member.User.ID
+	}
+	if i.User != nil {
+		return i.User.ID
+	}
+	return ""
+}
+
+// Option returns the string value of the named command option
+func (i Interaction) Option(name string) string {
+	for _, option := range i.Data.Options {
+		if option.Name == name {
+			return fmt.Sprint(option.Value)
+		}
+	}
+	return ""
+}
+
+type InteractionResponse struct {
+	Type int                  `json:"type"`
+	Data *InteractionCallback `json:"data,omitempty"`
+}
+
+type InteractionCallback struct {
+	Content string `json:"content"`
+	Flags   int    `json:"flags,omitempty"`
+}
+
+// Reply builds an ephemeral message response visible only to the invoking user
+func Reply(content string) InteractionResponse {
+	return InteractionResponse{
+		Type: ResponseChannelMessage,
+		Data: &InteractionCallback{Content: content, Flags: FlagEphemeral},
+	}
+}
+
+type Command struct {
+	Name        string          `json:"name"`
+	Description string          `json:"description"`
+	Options     []CommandOption `json:"options,omitempty"`
+}
+
+type CommandOption struct {
+	Type        int    `json:"type"`
+	Name        string `json:"name"`
+	Description string `json:"description"`
+	Required    bool   `json:"required"`
+}
+
+// Commands are the slash commands the bot exposes
+var Commands = []Command{
+	{
+		Name:        "link",
+		Description: "Link your Discord account using a code from the web app",
+		Options: []CommandOption{
+			{Type: optionTypeString, Name: "code", Description: "Link code", Required: true},
+		},
+	},
+	{
+		Name:        "transfer",
+		Description: "Transfer a playlist between connected services",
+		Options: []CommandOption{
+			{Type: optionTypeString, Name: "source_service", Description: "spotify or youtube", Required: true},
+			{Type: optionTypeString, Name: "source_playlist_id", Description: "Playlist ID on the source service", Required: true},
+			{Type: optionTypeString, Name: "target_service", Description: "spotify or youtube", Required: true},
+			{Type: optionTypeString, Name: "name", Description: "Name of the new playlist"},
+		},
+	},
+	{
+		Name:        "status",
+		Description: "Show your most recent transfers",
+	},
+}
@@ -0,0 +1,189 @@
+package fingerprint
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"time"
+
+	"server/internal/config"
+
+	"golang.org/x/time/rate"
+)
+
+// ErrUnavailable means deep matching is disabled or its tools are not installed
+var ErrUnavailable = errors.New("audio fingerprinting unavailable")
+
+// AcoustID allows 3 requests per second per application
+var (
+	acoustIDLimiter = rate.NewLimiter(rate.Limit(3), 1)
+	httpClient      = &http.Client{Timeout: 30 * time.Second}
+)
+
+// maxPreviewBytes bounds preview downloads; 30-second MP3 previews are well below this
+const maxPreviewBytes = 5 << 20
+
+// Available reports whether the deep-match stage can run: it must be enabled with
+// DEEP_MATCH_ENABLED, have an ACOUSTID_API_KEY and find the fpcalc binary
+func Available() bool {
+	if !config.Bool("DEEP_MATCH_ENABLED", false) || config.String("ACOUSTID_API_KEY", "") == "" {
+		return false
+	}
+	_, err := exec.LookPath(config.String("FPCALC_PATH", "fpcalc"))
+	return err == nil
+}
+
+// YouTubeAvailable additionally requires yt-dlp to fetch audio for candidate videos
+func YouTubeAvailable() bool {
+	if !Available() {
+		return false
+	}
+	_, err := exec.LookPath(config.String("YTDLP_PATH", "yt-dlp"))
+	return err == nil
+}
+
+// Fingerprint is a Chromaprint fingerprint of an audio clip
+type Fingerprint struct {
+	Duration    int    `json:"duration"`
+	Fingerprint string `json:"fingerprint"`
+}
+
+// FromURL downloads an audio clip (e.g. a Spotify preview) and fingerprints it
+func FromURL(ctx context.Context, audioURL string) (Fingerprint, error) {
+	dir, err := os.MkdirTemp("", "fingerprint-")
+	if err != nil {
+		return Fingerprint{}, err
+	}
+	defer os.RemoveAll(dir)
+
+	req, err := http.NewRequestWithContext(ctx, "GET", audioURL, nil)
+	if err != nil {
+		return Fingerprint{}, err
+	}
+	resp, err := httpClient.Do(req)
+	if err != nil {
+		return Fingerprint{}, err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		return Fingerprint{}, fmt.Errorf("preview download returned status: %d", resp.StatusCode)
+	}
+
+	path := filepath.Join(dir, "clip")
+	f, err := os.Create(path)
+	if err != nil {
+		return Fingerprint{}, err
+	}
+	_, err = io.Copy(f, io.LimitReader(resp.Body, maxPreviewBytes))
+	f.Close()
+	if err != nil {
+		return Fingerprint{}, err
+	}
+
+	return fromFile(ctx, path)
+}
+
+// FromYouTube fetches a video's audio with yt-dlp and fingerprints it
+func FromYouTube(ctx context.Context, videoID string) (Fingerprint, error) {
+	if !YouTubeAvailable() {
+		return Fingerprint{}, ErrUnavailable
+	}
+
+	dir, err := os.MkdirTemp("", "fingerprint-")
+	if err != nil {
+		return Fingerprint{}, err
+	}
+	defer os.RemoveAll(dir)
+
+	path := filepath.Join(dir, "audio")
+	cmd := exec.CommandContext(ctx, config.String("YTDLP_PATH", "yt-dlp"),
+		"--quiet", "--no-playlist", "-f", "bestaudio[filesize<20M]/bestaudio",
+		"-o", path, "https://www.youtube.com/watch?v="+url.QueryEscape(videoID))
+	if out, err := cmd.CombinedOutput(); err != nil {
+		return Fingerprint{}, fmt.Errorf("yt-dlp failed: %v: %s", err, out)
+	}
+
+	return fromFile(ctx, path)
+}
+
+// fromFile runs fpcalc on the first two minutes of an audio file
+func fromFile(ctx context.Context, path string) (Fingerprint, error) {
+	cmd := exec.CommandContext(ctx, config.String("FPCALC_PATH", "fpcalc"), "-json", "-length", "120", path)
+	out, err := cmd.Output()
+	if err != nil {
+		return Fingerprint{}, fmt.Errorf("fpcalc failed: %v", err)
+	}
+
+	var result struct {
+		Duration    float64 `json:"duration"`
+		Fingerprint string  `json:"fingerprint"`
+	}
+	if err := json.Unmarshal(out, &result); err != nil {
+		return Fingerprint{}, err
+	}
+	return Fingerprint{Duration: int(result.Duration), Fingerprint: result.Fingerprint}, nil
+}
+
+// Lookup returns the MusicBrainz recording IDs AcoustID associates with a fingerprint
+func Lookup(ctx context.Context, fp Fingerprint) ([]string, error) {
+	if err := acoustIDLimiter.Wait(ctx); err != nil {
+		return nil, err
+	}
+
+	form := url.Values{
+		"client":      {config.String("ACOUSTID_API_KEY", "")},
+		"meta":        {"recordingids"},
+		"duration":    {strconv.Itoa(fp.Duration)},
+		"fingerprint": {fp.Fingerprint},
+	}
+	// Fingerprints are several kilobytes, too long for a query string
+	req, err := http.NewRequestWithContext(ctx, "POST", "https://api.acoustid.org/v2/lookup", strings.NewReader(form.Encode()))
+	if err != nil {
+		return nil, err
+	}
+	req.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+
+	resp, err := httpClient.Do(req)
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+
+	var result struct {
+		Status  string `json:"status"`
+		Results []struct {
+			Score      float64 `json:"score"`
+			Recordings []struct {
+				ID string `json:"id"`
+			} `json:"recordings"`
+		} `json:"results"`
+	}
+	if err := json.NewDecoder(resp.Body).Decode(&result); err != nil {
+		return nil, err
+	}
+	if result.Status != "ok" {
+		return nil, fmt.Errorf("acoustid lookup returned status: %s", result.Status)
+	}
+
+	// Weak fingerprint matches are as likely to be covers or remixes
+	minScore := config.Float("ACOUSTID_MIN_SCORE", 0.8)
+	var ids []string
+	for _, r := range result.Results {
+		if r.Score < minScore {
+			continue
+		}
+		for _, recording := range r.Recordings {
+			ids = append(ids, recording.ID)
+		}
+	}
+	return ids, nil
+}
@@ -0,0 +1,159 @@
+package flags
+
+import (
+	"fmt"
+	"hash/fnv"
+	"log"
+	"os"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"server/internal/database"
+)
+
+// Flags are read from the database at most this often, so changes apply without a redeploy
+const refreshInterval = 30 * time.Second
+
+// Flag is the resolved rollout state of a feature
+type Flag struct {
+	Name       string `json:"name"`
+	Enabled    bool   `json:"enabled"`
+	Percentage int    `json:"percentage"`
+	UserIDs    []uint `json:"user_ids"`
+	Source     string `json:"source"` // "database" or "env"
+}
+
+var cache struct {
+	flags     map[string]Flag
+	expiresAt time.Time
+	mu        sync.Mutex
+}
+
+// Enabled reports whether a feature is on for the user. Database rows take
+// precedence over FEATURE_<NAME> environment defaults; unknown flags are off.
+func Enabled(name string, userID uint) bool {
+	flag, ok := lookup(name)
+	if !ok || !flag.Enabled {
+		return false
+	}
+
+	for _, id := range flag.UserIDs {
+		if id == userID {
+			return true
+		}
+	}
+	return bucket(name, userID) < flag.Percentage
+}
+
+// ForUser returns every known flag's state for the user
+func ForUser(userID uint) map[string]bool {
+	result := make(map[string]bool)
+	for name := range All() {
+		result[name] = Enabled(name, userID)
+	}
+	return result
+}
+
+// All returns every flag defined in the database or environment
+func All() map[string]Flag {
+	cache.mu.Lock()
+	defer cache.mu.Unlock()
+
+	if cache.flags == nil || time.Now().After(cache.expiresAt) {
+		cache.flags = load()
+		cache.expiresAt = time.Now().Add(refreshInterval)
+	}
+
+	flags := make(map[string]Flag, len(cache.flags))
+	for name, flag := range cache.flags {
+		flags[name] = flag
+	}
+	return flags
+}
+
+// Invalidate drops cached flags so the next check reads the database
+func Invalidate() {
+	cache.mu.Lock()
+	cache.flags = nil
+	cache.mu.Unlock()
+}
+
+func lookup(name string) (Flag, bool) {
+	flag, ok := All()[name]
+	return flag, ok
+}
+
+func load() map[string]Flag {
+	flags := envFlags()
+
+	if database.DB == nil {
+		return flags
+	}
+
+	var rows []database.FeatureFlag
+	if err := database.DB.Find(&rows).Error; err != nil {
+		log.Printf("Failed to load feature flags, using environment defaults: %v", err)
+		return flags
+	}
+	for _, row := range rows {
+		flags[row.Name] = Flag{
+			Name:       row.Name,
+			Enabled:    row.Enabled,
+			Percentage: clampPercentage(row.Percentage),
+			UserIDs:    ParseUserIDs(row.UserIDs),
+			Source:     "database",
+		}
+	}
+	return flags
+}
+
+// envFlags reads FEATURE_<NAME> variables: "true"/"false" or a rollout percentage like "25"
+func envFlags() map[string]Flag {
+	flags := make(map[string]Flag)
+	for _, kv := range os.Environ() {
+		key, value, _ := strings.Cut(kv, "=")
+		if !strings.HasPrefix(key, "FEATURE_") || value == "" {
+			continue
+		}
+		name := strings.ToLower(strings.TrimPrefix(key, "FEATURE_"))
+
+		percentage := 0
+		if on, err := strconv.ParseBool(value); err == nil {
+			if on {
+				percentage = 100
+			}
+		} else if pct, err := strconv.Atoi(strings.TrimSuffix(value, "%")); err == nil {
+			percentage = clampPercentage(pct)
+		} else {
+			log.Printf("Ignoring %s: expected a bool or percentage, got %q", key, value)
+			continue
+		}
+
+		flags[name] = Flag{Name: name, Enabled: percentage > 0, Percentage: percentage, Source: "env"}
+	}
+	return flags
+}
+
+// ParseUserIDs parses a comma-separated list of user IDs, skipping invalid entries
+func ParseUserIDs(raw string) []uint {
+	var ids []uint
+	for _, part := range strings.Split(raw, ",") {
+		if id, err := strconv.ParseUint(strings.TrimSpace(part), 10, 32); err == nil {
+			ids = append(ids, uint(id))
+		}
+	}
+	return ids
+}
+
+// bucket places a user in 0-99 per flag, so the same users stay in a rollout as it grows
+func bucket(name string, userID uint) int {
+	h := fnv.New32a()
+	fmt.Fprintf(h, "%s:%d", name, userID)
+	return int(h.Sum32() % 100)
+}
+
+func clampPercentage(pct int) int {
+	return min(max(pct, 0), 100)
+}
@@ -0,0 +1,108 @@
+package handlers
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/http"
+	"strconv"
+	"strings"
+
+	"server/internal/database"
+	"server/internal/jobs"
+
+	"github.com/gin-gonic/gin"
+)
+
+// AdminListJobs lists queued, running and recently failed or cancelled background jobs
+func (h *Handlers) AdminListJobs(c *gin.Context) {
+	list := h.Jobs.Jobs()
+
+	if status := c.Query("status"); status != "" {
+		filtered := list[:0]
+		for _, job := range list {
+			if job.Status == status {
+				filtered = append(filtered, job)
+			}
+		}
+		list = filtered
+	}
+
+	c.JSON(http.StatusOK, gin.H{"jobs": list, "queue": h.Jobs.Stats()})
+}
+
+// AdminCancelJob drops a queued job or cancels a running one
+func (h *Handlers) AdminCancelJob(c *gin.Context) {
+	id := c.Param("id")
+	if err := h.Jobs.Cancel(id); err != nil {
+		respondJobError(c, err)
+		return
+	}
+
+	// Queued transfers never start, so their record is closed here; running
+	// ones are closed by the job itself once it stops
+	if transferID, ok := transferIDFromJobID(id); ok {
+		result := h.DB.WithContext(c.Request.Context()).Model(&database.Transfer{}).
+			Where("id = ? AND status = ?", transferID, database.TransferPending).
+			Update("status", database.TransferCancelled)
+		if result.RowsAffected > 0 {
+			recordTransferEvent(h.DB.WithContext(c.Request.Context()), transferID, "status", database.TransferCancelled, "Cancelled by an operator before it started")
+		}
+	}
+
+	c.JSON(http.StatusOK, gin.H{"message": "Job cancelled"})
+}
+
+// AdminRetryJob re-enqueues a failed or cancelled job
+func (h *Handlers) AdminRetryJob(c *gin.Context) {
+	id := c.Param("id")
+	if err := h.Jobs.Retry(id); err != nil {
+		respondJobError(c, err)
+		return
+	}
+
+	if transferID, ok := transferIDFromJobID(id); ok {
+		h.DB.WithContext(c.Request.Context()).Model(&database.Transfer{}).Where("id = ?", transferID).Update("status", database.TransferPending)
+		recordTransferEvent(h.DB.WithContext(c.Request.Context()), transferID, "status", database.TransferPending, "Re-queued by an operator")
+	}
+
+	c.JSON(http.StatusOK, gin.H{"message": "Job re-queued"})
+}
+
+func respondJobError(c *gin.Context, err error) {
+	if errors.Is(err, jobs.ErrJobNotFound) {
+		c.JSON(http.StatusNotFound, gin.H{"error": "Job not found"})
+		return
+	}
+	c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+}
+
+// transferJobResult reports how a transfer job ended to the queue: a failed
+// transfer fails the job, so operators can find and retry it, and a job that was
+// cancelled while running also closes the transfer record
+func (h *Handlers) transferJobResult(ctx context.Context, transferID uint) error {
+	db := h.DB.WithContext(context.WithoutCancel(ctx))
+	if ctx.Err() == nil {
+		var transfer database.Transfer
+		if err := db.Select("status", "error_message").First(&transfer, transferID).Error; err != nil {
+			return nil
+		}
+		if transfer.Status == database.TransferFailed {
+			return fmt.Errorf("transfer %d failed: %s", transferID, transfer.ErrorMessage)
+		}
+		return nil
+	}
+
+	db.Model(&database.Transfer{}).Where("id = ?", transferID).Update("status", database.TransferCancelled)
+	recordTransferEvent(db, transferID, "status", database.TransferCancelled, ctx.Err().Error())
+	return ctx.Err()
+}
+
+func transferIDFromJobID(id string) (uint, bool) {
+	raw, ok := strings.CutPrefix(id, "transfer-")
+	if !ok {
+		return 0, false
+	}
+	transferID, err := strconv.ParseUint(raw, 10, 32)
+	return uint(transferID), err == nil
+}
@@ -0,0 +1,158 @@
+package handlers
+
+import (
+	"context"
+	"log"
+	"net/http"
+	"strconv"
+	"time"
+
+	"server/internal/config"
+	"server/internal/database"
+	"server/internal/ledger"
+	"server/internal/providers"
+	"server/internal/quota"
+	"server/internal/ratelimit"
+
+	"github.com/gin-gonic/gin"
+)
+
+var apiLedgerEnabled = config.Bool("API_LEDGER_ENABLED", true)
+
+// recordAPICalls puts every call client makes to service, retries included, into the ledger
+func recordAPICalls(service ratelimit.ServiceType, client *ratelimit.RateLimitedHTTPClient) {
+	if !apiLedgerEnabled {
+		return
+	}
+	client.OnResponse(func(req *http.Request) {
+		call := database.APICall{
+			Service:       string(service),
+			EndpointClass: providers.OpFromContext(req.Context()),
+			Method:        req.Method,
+		}
+		if call.EndpointClass == "" {
+			call.EndpointClass = "other"
+		}
+		if service == ratelimit.YouTubeService {
+			call.QuotaCost = quota.YouTubeCost(req.Method, req.URL.Path)
+		}
+		call.UserID, _ = ratelimit.UserFromContext(req.Context())
+		call.TransferID, _ = ratelimit.TransferFromContext(req.Context())
+		ledger.Record(call)
+	})
+}
+
+// StartAPILedger writes buffered provider calls to the ledger every
+// API_LEDGER_FLUSH_INTERVAL and prunes entries older than API_LEDGER_RETENTION
+// daily. Rollups are kept after their entries are pruned.
+func (h *Handlers) StartAPILedger(ctx context.Context) {
+	if !apiLedgerEnabled {
+		log.Printf("API call ledger disabled")
+		return
+	}
+	interval := config.Duration("API_LEDGER_FLUSH_INTERVAL", 10*time.Second)
+	retention := config.Duration("API_LEDGER_RETENTION", 30*24*time.Hour)
+
+	go func() {
+		flush := time.NewTicker(interval)
+		defer flush.Stop()
+		prune := time.NewTicker(24 * time.Hour)
+		defer prune.Stop()
+
+		for {
+			select {
+			case <-ctx.Done():
+				ledger.Flush()
+				return
+			case <-flush.C:
+				ledger.Flush()
+			case <-prune.C:
+				if retention <= 0 {
+					continue
+				}
+				h.runScheduled(ctx, "api-ledger-prune", func() {
+					n, err := ledger.Prune(h.DB.WithContext(ctx), retention)
+					if err != nil {
+						log.Printf("Failed to prune API call ledger: %v", err)
+						return
+					}
+					log.Printf("Pruned %d API call ledger entries", n)
+				})
+			}
+		}
+	}()
+}
+
+// AdminListAPICalls lists ledger entries, newest first, filtered by user_id,
+// transfer_id, service, endpoint_class and a since/until unix time range
+func (h *Handlers) AdminListAPICalls(c *gin.Context) {
+	query := h.DB.WithContext(c.Request.Context()).Model(&database.APICall{})
+	for _, filter := range []string{"user_id", "transfer_id", "since", "until"} {
+		raw := c.Query(filter)
+		if raw == "" {
+			continue
+		}
+		n, err := strconv.ParseInt(raw, 10, 64)
+		if err != nil || n < 0 {
+			c.JSON(http.StatusBadRequest, gin.H{"error": filter + " must be a non-negative integer"})
+			return
+		}
+		switch filter {
+		case "since":
+			query = query.Where("created_at >= ?", n)
+		case "until":
+			query = query.Where("created_at < ?", n)
+		default:
+			query = query.Where(filter+" = ?", n)
+		}
+	}
+	if service := c.Query("service"); service != "" {
+		query = query.Where("service = ?", service)
+	}
+	if class := c.Query("endpoint_class"); class != "" {
+		query = query.Where("endpoint_class = ?", class)
+	}
+
+	limit := 100
+	if n, err := strconv.Atoi(c.Query("limit")); err == nil && n > 0 {
+		limit = min(n, 1000)
+	}
+
+	var calls []database.APICall
+	if err := query.Order("created_at DESC, id DESC").Limit(limit).Find(&calls).Error; err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to load API calls"})
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{"calls": calls})
+}
+
+// AdminAPICallRollups reports the users who made the most provider calls on a
+// day (?day=YYYY-MM-DD, default today UTC), with their quota units per service
+func (h *Handlers) AdminAPICallRollups(c *gin.Context) {
+	day := c.DefaultQuery("day", ledger.Day(time.Now()))
+	if _, err := time.Parse("2006-01-02", day); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "day must be formatted YYYY-MM-DD"})
+		return
+	}
+	ledger.Flush()
+
+	type userTotal struct {
+		UserID     uint   `json:"user_id"`
+		Service    string `json:"service"`
+		Calls      int64  `json:"calls"`
+		QuotaUnits int64  `json:"quota_units"`
+	}
+	var totals []userTotal
+	err := h.DB.WithContext(c.Request.Context()).Model(&database.APICallRollup{}).
+		Select("user_id, service, SUM(calls) AS calls, SUM(quota_units) AS quota_units").
+		Where("day = ?", day).
+		Group("user_id, service").
+		Order("calls DESC").
+		Limit(100).
+		Scan(&totals).Error
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to load API call rollups"})
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{"day": day, "users": totals})
+}
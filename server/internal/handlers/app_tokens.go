@@ -0,0 +1,63 @@
+package handlers
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"strings"
+
+	"server/internal/config"
+	"server/internal/providers/youtube"
+
+	"github.com/gin-gonic/gin"
+)
+
+// youtubeAPIKeyPrefix marks a YouTube "token" that is really an app API key
+const youtubeAPIKeyPrefix = youtube.APIKeyPrefix
+
+// appAccessToken returns an app-level credential for reading public data
+// on a service, independent of any user's connection
+func (h *Handlers) appAccessToken(ctx context.Context, service string) (string, error) {
+	switch service {
+	case "spotify":
+		token, err := h.Providers.AppTokens.SpotifyToken()
+		if err != nil {
+			return "", fmt.Errorf("failed to get Spotify app token: %v", err)
+		}
+		return token, nil
+	case "youtube":
+		key, err := h.Providers.AppTokens.YouTubeKey()
+		if err != nil {
+			return "", fmt.Errorf("no YouTube API key available: %v", err)
+		}
+		return youtubeAPIKeyPrefix + key, nil
+	default:
+		return "", fmt.Errorf("unsupported service: %s", service)
+	}
+}
+
+// lookupToken picks the credential for a read-only public lookup (search,
+// track metadata): an app credential when APP_TOKENS_FOR_LOOKUPS is on and
+// one is available, otherwise the user's own token
+func (h *Handlers) lookupToken(ctx context.Context, service, userToken string) string {
+	if !config.Bool("APP_TOKENS_FOR_LOOKUPS", true) || !h.Providers.AppTokens.Configured(service) {
+		return userToken
+	}
+	if token, err := h.appAccessToken(ctx, service); err == nil {
+		return token
+	}
+	return userToken
+}
+
+// noteYouTubeQuota rests an app API key whose daily quota ran out
+func (h *Handlers) noteYouTubeQuota(accessToken string, status int, body []byte) {
+	key, ok := strings.CutPrefix(accessToken, youtubeAPIKeyPrefix)
+	if ok && status == http.StatusForbidden && strings.Contains(string(body), "quotaExceeded") {
+		h.Providers.AppTokens.ReportYouTubeQuotaExceeded(key)
+	}
+}
+
+// AdminAppCredentials reports the app credential pool's usage and resting credentials
+func (h *Handlers) AdminAppCredentials(c *gin.Context) {
+	c.JSON(http.StatusOK, gin.H{"app_credentials": h.Providers.AppTokens.Status()})
+}
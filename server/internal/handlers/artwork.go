@@ -0,0 +1,308 @@
+package handlers
+
+import (
+	"bytes"
+	"context"
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"image"
+	"image/color"
+	_ "image/gif" // decoders for provider artwork formats
+	"image/jpeg"
+	_ "image/png"
+	"io"
+	"log"
+	"net/http"
+	"net/url"
+	"os"
+	"strconv"
+	"strings"
+	"time"
+
+	"server/internal/config"
+	"server/internal/database"
+	"server/internal/storage"
+
+	"github.com/gin-gonic/gin"
+)
+
+// artworkSizes are the widths artwork can be requested at; the largest is the default
+var artworkSizes = []int{64, 150, 300, 640}
+
+// maxArtworkBytes caps how much of a provider image is downloaded
+const maxArtworkBytes = 10 << 20
+
+// maxArtworkDimension caps the width and height of an image that is decoded,
+// since a small file can declare a huge canvas
+const maxArtworkDimension = 4096
+
+// artworkHosts are the provider image CDNs artwork is fetched from; subdomains match too
+var artworkHosts = config.List("ARTWORK_HOSTS", []string{"scdn.co", "spotifycdn.com", "ytimg.com", "ggpht.com", "googleusercontent.com"})
+
+var artworkClient = &http.Client{
+	Timeout: 10 * time.Second,
+	CheckRedirect: func(req *http.Request, via []*http.Request) error {
+		if len(via) >= 5 {
+			return errors.New("too many redirects")
+		}
+		return checkArtworkURL(req.URL)
+	},
+}
+
+// checkArtworkURL refuses image URLs outside the provider CDNs, so playlist
+// artwork can't be used to make the server fetch arbitrary addresses
+func checkArtworkURL(u *url.URL) error {
+	if u.Scheme != "https" {
+		return fmt.Errorf("artwork URL must use https")
+	}
+	host := strings.ToLower(u.Hostname())
+	for _, allowed := range artworkHosts {
+		if host == allowed || strings.HasSuffix(host, "."+allowed) {
+			return nil
+		}
+	}
+	return fmt.Errorf("artwork host %s is not allowed", host)
+}
+
+// checkArtworkImage reads an image's header and refuses formats that can't be
+// decoded and dimensions too large to decode safely
+func checkArtworkImage(body []byte) error {
+	cfg, _, err := image.DecodeConfig(bytes.NewReader(body))
+	if err != nil {
+		return fmt.Errorf("artwork is not a supported image: %w", err)
+	}
+	if cfg.Width > maxArtworkDimension || cfg.Height > maxArtworkDimension {
+		return fmt.Errorf("artwork is %dx%d, larger than %d pixels a side", cfg.Width, cfg.Height, maxArtworkDimension)
+	}
+	return nil
+}
+
+// artworkURL is the proxied address of a stored playlist's artwork. The
+// signature keeps the public endpoint from being walked by playlist ID.
+func artworkURL(playlistID uint) string {
+	return fmt.Sprintf("%s/api/artwork/%d?sig=%s", os.Getenv("BACKEND_URL"), playlistID, signArtwork(playlistID))
+}
+
+func signArtwork(playlistID uint) string {
+	mac := hmac.New(sha256.New, []byte(os.Getenv("JWT_SECRET")))
+	fmt.Fprintf(mac, "artwork:%d", playlistID)
+	return hex.EncodeToString(mac.Sum(nil))[:32]
+}
+
+// withArtworkURLs points playlists with artwork at the caching proxy
+func withArtworkURLs(playlists []database.Playlist) {
+	for i := range playlists {
+		if playlists[i].ImageURL != "" {
+			playlists[i].ArtworkURL = artworkURL(playlists[i].ID)
+		}
+	}
+}
+
+// GetPlaylistArtwork serves a stored playlist's image from storage, resized to
+// ?size= (64, 150, 300 or 640 pixels wide). The provider image is fetched once
+// per cover change, so the last good copy keeps working after its CDN URL expires.
+func (h *Handlers) GetPlaylistArtwork(c *gin.Context) {
+	playlistID, err := strconv.ParseUint(c.Param("id"), 10, 32)
+	if err != nil || !hmac.Equal([]byte(c.Query("sig")), []byte(signArtwork(uint(playlistID)))) {
+		c.JSON(http.StatusNotFound, gin.H{"error": "Artwork not found"})
+		return
+	}
+
+	size := artworkSizes[len(artworkSizes)-1]
+	if raw := c.Query("size"); raw != "" {
+		size = 0
+		requested, _ := strconv.Atoi(raw)
+		for _, allowed := range artworkSizes {
+			if requested == allowed {
+				size = allowed
+			}
+		}
+		if size == 0 {
+			c.JSON(http.StatusBadRequest, gin.H{"error": fmt.Sprintf("Size must be one of %v", artworkSizes)})
+			return
+		}
+	}
+
+	ctx := c.Request.Context()
+	var playlist database.Playlist
+	if err := h.DB.WithContext(ctx).First(&playlist, playlistID).Error; err != nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": "Artwork not found"})
+		return
+	}
+
+	source, err := h.refreshArtwork(ctx, &playlist)
+	if err != nil {
+		log.Printf("Failed to fetch artwork for playlist %d: %v", playlist.ID, err)
+		c.JSON(http.StatusBadGateway, gin.H{"error": "Artwork unavailable"})
+		return
+	}
+
+	key := artworkKey(playlist.ID, source, strconv.Itoa(size))
+	etag := `"` + hashArtworkSource(source) + "-" + strconv.Itoa(size) + `"`
+	if c.GetHeader("If-None-Match") == etag {
+		c.Status(http.StatusNotModified)
+		return
+	}
+
+	body, err := h.artworkVariant(ctx, playlist.ID, source, key, size)
+	if err != nil {
+		log.Printf("Failed to resize artwork for playlist %d: %v", playlist.ID, err)
+		c.JSON(http.StatusBadGateway, gin.H{"error": "Artwork unavailable"})
+		return
+	}
+
+	c.Header("Cache-Control", "public, max-age=86400")
+	c.Header("ETag", etag)
+	c.Data(http.StatusOK, "image/jpeg", body)
+}
+
+// refreshArtwork makes sure storage holds the playlist's current cover and
+// returns the source URL of the copy to serve. When the provider URL no longer
+// loads, the previously cached cover is served instead.
+func (h *Handlers) refreshArtwork(ctx context.Context, playlist *database.Playlist) (string, error) {
+	if playlist.ImageURL == "" || playlist.ImageURL == playlist.ArtworkSource {
+		if playlist.ArtworkSource == "" {
+			return "", errors.New("playlist has no artwork")
+		}
+		return playlist.ArtworkSource, nil
+	}
+
+	original, err := fetchArtwork(ctx, playlist.ImageURL)
+	if err != nil {
+		if playlist.ArtworkSource != "" {
+			return playlist.ArtworkSource, nil
+		}
+		return "", err
+	}
+
+	key := artworkKey(playlist.ID, playlist.ImageURL, "original")
+	if err := storage.Default.Put(ctx, key, bytes.NewReader(original), int64(len(original)), http.DetectContentType(original)); err != nil {
+		return "", err
+	}
+
+	// Variants of the previous cover are no longer reachable
+	if previous := playlist.ArtworkSource; previous != "" {
+		storage.Default.Delete(ctx, artworkKey(playlist.ID, previous, "original"))
+		for _, size := range artworkSizes {
+			storage.Default.Delete(ctx, artworkKey(playlist.ID, previous, strconv.Itoa(size)))
+		}
+	}
+
+	playlist.ArtworkSource = playlist.ImageURL
+	h.DB.WithContext(ctx).Model(playlist).Update("artwork_source", playlist.ArtworkSource)
+	return playlist.ArtworkSource, nil
+}
+
+// artworkVariant returns the cached resized cover, creating it from the original on first use
+func (h *Handlers) artworkVariant(ctx context.Context, playlistID uint, source, key string, size int) ([]byte, error) {
+	if body, err := readStored(ctx, key); err == nil {
+		return body, nil
+	} else if !errors.Is(err, storage.ErrNotFound) {
+		return nil, err
+	}
+
+	original, err := readStored(ctx, artworkKey(playlistID, source, "original"))
+	if err != nil {
+		return nil, err
+	}
+	if err := checkArtworkImage(original); err != nil {
+		return nil, err
+	}
+	img, _, err := image.Decode(bytes.NewReader(original))
+	if err != nil {
+		return nil, err
+	}
+
+	var buf bytes.Buffer
+	if err := jpeg.Encode(&buf, resizeImage(img, size), &jpeg.Options{Quality: 85}); err != nil {
+		return nil, err
+	}
+	if err := storage.Default.Put(ctx, key, bytes.NewReader(buf.Bytes()), int64(buf.Len()), "image/jpeg"); err != nil {
+		log.Printf("Failed to cache artwork %s: %v", key, err)
+	}
+	return buf.Bytes(), nil
+}
+
+func fetchArtwork(ctx context.Context, imageURL string) ([]byte, error) {
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, imageURL, nil)
+	if err != nil {
+		return nil, err
+	}
+	if err := checkArtworkURL(req.URL); err != nil {
+		return nil, err
+	}
+	resp, err := artworkClient.Do(req)
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("artwork returned status %d", resp.StatusCode)
+	}
+
+	body, err := io.ReadAll(io.LimitReader(resp.Body, maxArtworkBytes+1))
+	if err != nil {
+		return nil, err
+	}
+	if len(body) > maxArtworkBytes {
+		return nil, errors.New("artwork too large")
+	}
+	// Make sure it decodes before replacing a working cached copy
+	if err := checkArtworkImage(body); err != nil {
+		return nil, err
+	}
+	return body, nil
+}
+
+func readStored(ctx context.Context, key string) ([]byte, error) {
+	body, err := storage.Default.Get(ctx, key)
+	if err != nil {
+		return nil, err
+	}
+	defer body.Close()
+	return io.ReadAll(body)
+}
+
+func artworkKey(playlistID uint, source, variant string) string {
+	return fmt.Sprintf("artwork/%d/%s-%s", playlistID, hashArtworkSource(source), variant)
+}
+
+func hashArtworkSource(source string) string {
+	sum := sha256.Sum256([]byte(source))
+	return hex.EncodeToString(sum[:8])
+}
+
+// resizeImage scales img down to width pixels, keeping its aspect ratio, by
+// averaging the source pixels each target pixel covers. Smaller images are
+// left at their size.
+func resizeImage(img image.Image, width int) image.Image {
+	bounds := img.Bounds()
+	if bounds.Dx() <= width {
+		return img
+	}
+	height := max(1, bounds.Dy()*width/bounds.Dx())
+
+	dst := image.NewRGBA(image.Rect(0, 0, width, height))
+	for y := 0; y < height; y++ {
+		y0 := bounds.Min.Y + y*bounds.Dy()/height
+		y1 := max(y0+1, bounds.Min.Y+(y+1)*bounds.Dy()/height)
+		for x := 0; x < width; x++ {
+			x0 := bounds.Min.X + x*bounds.Dx()/width
+			x1 := max(x0+1, bounds.Min.X+(x+1)*bounds.Dx()/width)
+
+			var r, g, b, a, n uint64
+			for sy := y0; sy < y1; sy++ {
+				for sx := x0; sx < x1; sx++ {
+					pr, pg, pb, pa := img.At(sx, sy).RGBA()
+					r, g, b, a = r+uint64(pr), g+uint64(pg), b+uint64(pb), a+uint64(pa)
+					n++
+				}
+			}
+			dst.Set(x, y, color.RGBA64{uint16(r / n), uint16(g / n), uint16(b / n), uint16(a / n)})
+		}
+	}
+	return dst
+}
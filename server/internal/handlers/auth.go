@@ -24,14 +24,19 @@ type Claims struct {
 	jwt.RegisteredClaims
 }
 
-func GenerateJWT(userID uint) (string, error) {
-	expirationTime := time.Now().Add(24 * time.Hour)
+// sessionTTL is how long a login token stays valid
+const sessionTTL = 24 * time.Hour
+
+// GenerateJWT signs a login token for the user; sessionID ties it to a stored Session
+func GenerateJWT(userID uint, sessionID string) (string, error) {
+	expirationTime := time.Now().Add(sessionTTL)
 	claims := &Claims{
 		UserID: userID,
 		RegisteredClaims: jwt.RegisteredClaims{
 			ExpiresAt: jwt.NewNumericDate(expirationTime),
 			IssuedAt:  jwt.NewNumericDate(time.Now()),
 			Subject:   strconv.FormatUint(uint64(userID), 10),
+			ID:        sessionID,
 		},
 	}
 
@@ -39,21 +44,46 @@ func GenerateJWT(userID uint) (string, error) {
 	return token.SignedString([]byte(os.Getenv("JWT_SECRET")))
 }
 
-func HandleGoogleLogin(c *gin.Context) {
-	url := auth.GoogleOAuthConfig.AuthCodeURL("state")
+// HandleGoogleLogin starts the Google login. An optional redirect_uri (an allowed
+// web origin or mobile deep link) receives the one-time code afterwards.
+func (h *Handlers) HandleGoogleLogin(c *gin.Context) {
+	redirectURI, err := resolveRedirect(c.Query("redirect_uri"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	if h.Providers.Mock != nil {
+		h.handleMockLogin(c, redirectURI)
+		return
+	}
+
+	state, err := signLoginState(redirectURI)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to start login"})
+		return
+	}
+
+	url := auth.GoogleOAuthConfig.AuthCodeURL(state)
 	c.Redirect(http.StatusTemporaryRedirect, url)
 }
 
-func HandleGoogleCallback(c *gin.Context) {
+func (h *Handlers) HandleGoogleCallback(c *gin.Context) {
 	code := c.Query("code")
 
+	redirectURI, err := parseLoginState(c.Query("state"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid or expired login state"})
+		return
+	}
+
 	if code == "" {
 		c.JSON(http.StatusBadRequest, gin.H{"error": "Authorization code not provided"})
 		return
 	}
 
 	// Exchange code for token
-	token, err := auth.GoogleOAuthConfig.Exchange(context.Background(), code)
+	token, err := auth.GoogleOAuthConfig.Exchange(c.Request.Context(), code)
 	if err != nil {
 		log.Printf("Token exchange error: %v", err)
 		c.JSON(http.StatusBadRequest, gin.H{"error": "Failed to exchange token: " + err.Error()})
@@ -61,7 +91,7 @@ func HandleGoogleCallback(c *gin.Context) {
 	}
 
 	// Get user info from Google
-	client := auth.GoogleOAuthConfig.Client(context.Background(), token)
+	client := auth.GoogleOAuthConfig.Client(c.Request.Context(), token)
 	resp, err := client.Get("https://www.googleapis.com/oauth2/v2/userinfo")
 	if err != nil {
 		log.Printf("User info fetch error: %v", err)
@@ -84,51 +114,59 @@ func HandleGoogleCallback(c *gin.Context) {
 		return
 	}
 
-	// Create or update user in database
+	user, err := h.findOrCreateGoogleUser(c.Request.Context(), userInfo.ID, userInfo.Email, userInfo.Name, userInfo.Picture)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+
+	// The JWT stays out of the URL; the client exchanges this code at /api/auth/exchange
+	authCode, err := h.issueAuthCode(c.Request.Context(), user.ID)
+	if err != nil {
+		log.Printf("Auth code generation error: %v", err)
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to generate login code"})
+		return
+	}
+
+	log.Printf("Redirecting user %d to: %s", user.ID, redirectURI)
+	c.Redirect(http.StatusTemporaryRedirect, withQueryParam(redirectURI, "code", authCode))
+}
+
+// findOrCreateGoogleUser returns the user for a Google account, creating it on first login
+func (h *Handlers) findOrCreateGoogleUser(ctx context.Context, googleID, email, name, picture string) (database.User, error) {
 	var user database.User
-	result := database.DB.Where("google_id = ?", userInfo.ID).First(&user)
+	result := h.DB.WithContext(ctx).Where("google_id = ?", googleID).First(&user)
 	if result.Error == gorm.ErrRecordNotFound {
 		user = database.User{
-			GoogleID:  userInfo.ID,
-			Email:     userInfo.Email,
-			Name:      userInfo.Name,
-			AvatarURL: userInfo.Picture,
+			GoogleID:  googleID,
+			Email:     email,
+			Name:      name,
+			AvatarURL: picture,
 		}
-		if err := database.DB.Create(&user).Error; err != nil {
+		if err := h.DB.WithContext(ctx).Create(&user).Error; err != nil {
 			log.Printf("User creation error: %v", err)
-			c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to create user: " + err.Error()})
-			return
+			return user, fmt.Errorf("Failed to create user: %w", err)
 		}
 		log.Printf("Created new user: %s", user.Email)
 	} else if result.Error != nil {
 		log.Printf("Database error: %v", result.Error)
-		c.JSON(http.StatusInternalServerError, gin.H{"error": "Database error: " + result.Error.Error()})
-		return
+		return user, fmt.Errorf("Database error: %w", result.Error)
 	} else {
 		log.Printf("Logged in existing user: %s", user.Email)
 	}
-
-	// Generate JWT
-	jwtToken, err := GenerateJWT(user.ID)
-	if err != nil {
-		log.Printf("JWT generation error: %v", err)
-		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to generate token: " + err.Error()})
-		return
-	}
-
-	// Redirect to frontend with token
-	frontendURL := os.Getenv("FRONTEND_URL")
-	redirectURL := fmt.Sprintf("%s/auth/success?token=%s", frontendURL, jwtToken)
-	log.Printf("Redirecting to: %s", redirectURL)
-	c.Redirect(http.StatusTemporaryRedirect, redirectURL)
+	return user, nil
 }
 
-func HandleLogout(c *gin.Context) {
-	// In a real app, you might want to blacklist the token
+func (h *Handlers) HandleLogout(c *gin.Context) {
+	// The token's session is revoked so it stops working before it expires
+	if claims, err := middleware.ParseToken(c.GetHeader("Authorization")); err == nil && claims.ID != "" {
+		h.DB.WithContext(c.Request.Context()).Model(&database.Session{}).
+			Where("token_id = ? AND revoked_at = 0", claims.ID).Update("revoked_at", time.Now().Unix())
+	}
 	c.JSON(http.StatusOK, gin.H{"message": "Logged out successfully"})
 }
 
-func HandleGetCurrentUser(c *gin.Context) {
+func (h *Handlers) HandleGetCurrentUser(c *gin.Context) {
 	user, exists := middleware.GetUserFromContext(c)
 	if !exists {
 		c.JSON(http.StatusUnauthorized, gin.H{"error": "User not found in context"})
@@ -141,6 +179,8 @@ func HandleGetCurrentUser(c *gin.Context) {
 			"email":     user.Email,
 			"name":      user.Name,
 			"avatarURL": user.AvatarURL,
+
+			"shareAnonymousStats": user.ShareAnonymousStats,
 		},
 	})
 }
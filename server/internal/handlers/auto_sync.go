@@ -0,0 +1,208 @@
+package handlers
+
+import (
+	"context"
+	"log"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+
+	"server/internal/database"
+	"server/internal/i18n"
+	"server/internal/middleware"
+
+	"github.com/gin-gonic/gin"
+	"gorm.io/gorm"
+)
+
+type AutoSyncRuleRequest struct {
+	SourceService string `json:"source_service" binding:"required"`
+	TargetService string `json:"target_service" binding:"required"`
+	MatchPrefix   string `json:"match_prefix"`
+	NamePrefix    string `json:"name_prefix"`
+	KeepInSync    *bool  `json:"keep_in_sync"` // true by default
+	RemovalMode   string `json:"removal_mode"` // of the sync links created, "keep" by default
+}
+
+// CreateAutoSyncRule starts mirroring playlists created on the source account
+// from now on. Playlists that already exist are left alone.
+func (h *Handlers) CreateAutoSyncRule(c *gin.Context) {
+	user, exists := middleware.GetUserFromContext(c)
+	if !exists {
+		i18n.RespondError(c, http.StatusUnauthorized, i18n.CodeUnauthenticated, "")
+		return
+	}
+
+	var req AutoSyncRuleRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		i18n.RespondError(c, http.StatusBadRequest, i18n.CodeInvalidRequest, err.Error())
+		return
+	}
+	for _, service := range []string{req.SourceService, req.TargetService} {
+		if service != "spotify" && service != "youtube" {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "Unsupported service: " + service})
+			return
+		}
+	}
+	if req.SourceService == req.TargetService {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Source and target service must differ"})
+		return
+	}
+	if req.RemovalMode == "" {
+		req.RemovalMode = database.SyncRemovalKeep
+	}
+	if !validRemovalMode(req.RemovalMode) {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Removal mode must be keep, remove or archive"})
+		return
+	}
+
+	rule := database.AutoSyncRule{
+		UserID:        user.ID,
+		SourceService: req.SourceService,
+		TargetService: req.TargetService,
+		MatchPrefix:   strings.TrimSpace(req.MatchPrefix),
+		NamePrefix:    req.NamePrefix,
+		KeepInSync:    req.KeepInSync == nil || *req.KeepInSync,
+		RemovalMode:   req.RemovalMode,
+		Enabled:       true,
+		CheckedAt:     time.Now().Unix(),
+	}
+	if err := h.DB.WithContext(c.Request.Context()).Create(&rule).Error; err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to create auto-sync rule"})
+		return
+	}
+
+	c.JSON(http.StatusCreated, gin.H{"rule": rule})
+}
+
+// GetAutoSyncRules lists the user's auto-sync rules
+func (h *Handlers) GetAutoSyncRules(c *gin.Context) {
+	user, exists := middleware.GetUserFromContext(c)
+	if !exists {
+		i18n.RespondError(c, http.StatusUnauthorized, i18n.CodeUnauthenticated, "")
+		return
+	}
+
+	var rules []database.AutoSyncRule
+	if err := h.DB.WithContext(c.Request.Context()).Where("user_id = ?", user.ID).Order("id").Find(&rules).Error; err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to fetch auto-sync rules"})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"rules": rules})
+}
+
+// DeleteAutoSyncRule stops mirroring new playlists; mirrors already made keep their sync links
+func (h *Handlers) DeleteAutoSyncRule(c *gin.Context) {
+	user, exists := middleware.GetUserFromContext(c)
+	if !exists {
+		i18n.RespondError(c, http.StatusUnauthorized, i18n.CodeUnauthenticated, "")
+		return
+	}
+
+	id, err := strconv.ParseUint(c.Param("id"), 10, 32)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid rule ID"})
+		return
+	}
+	result := h.DB.WithContext(c.Request.Context()).Where("id = ? AND user_id = ?", uint(id), user.ID).Delete(&database.AutoSyncRule{})
+	if result.Error != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to delete auto-sync rule"})
+		return
+	}
+	if result.RowsAffected == 0 {
+		c.JSON(http.StatusNotFound, gin.H{"error": "Auto-sync rule not found"})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"message": "Auto-sync rule deleted"})
+}
+
+// autoSyncNewPlaylists starts a transfer for every playlist the last sync of a
+// service found new and an auto-sync rule of the user matches
+func (h *Handlers) autoSyncNewPlaylists(ctx context.Context, userID uint, serviceType string) {
+	db := h.DB.WithContext(ctx)
+	var rules []database.AutoSyncRule
+	if err := db.Where("user_id = ? AND source_service = ? AND enabled = ?", userID, serviceType, true).Find(&rules).Error; err != nil || len(rules) == 0 {
+		return
+	}
+
+	exclusions := loadPlaylistExclusions(db, userID)
+	for _, rule := range rules {
+		var added []database.PlaylistChange
+		err := db.Where("user_id = ? AND service_type = ? AND change = ? AND detected_at > ?", userID, serviceType, "added", rule.CheckedAt).
+			Order("detected_at, id").Find(&added).Error
+		if err != nil || len(added) == 0 {
+			continue
+		}
+
+		for _, change := range added {
+			if !strings.HasPrefix(change.Name, rule.MatchPrefix) || exclusions.excludes(serviceType, change.ServiceID, change.Name) ||
+				isTransferTarget(db, userID, serviceType, change.ServiceID) {
+				continue
+			}
+			// Skip playlists deleted again before this sync
+			var stored int64
+			db.Model(&database.Playlist{}).Where("user_id = ? AND service_type = ? AND service_id = ?", userID, serviceType, change.ServiceID).Count(&stored)
+			if stored == 0 {
+				continue
+			}
+
+			req := TransferRequest{
+				SourceService:      rule.SourceService,
+				SourcePlaylistID:   change.ServiceID,
+				TargetService:      rule.TargetService,
+				TargetPlaylistName: rule.NamePrefix + change.Name,
+				Priority:           "low",
+				OnNameConflict:     "duplicate",
+				SplitAcrossDays:    rule.TargetService == "youtube" || rule.SourceService == "youtube",
+				autoSyncRule:       &rule.ID,
+			}
+			transfer, _, err := h.startTransferForUser(ctx, userID, req)
+			if err != nil {
+				log.Printf("Auto-sync rule %d could not mirror %q: %v", rule.ID, change.Name, err)
+				continue
+			}
+			log.Printf("Auto-sync rule %d mirroring %q in transfer %d", rule.ID, change.Name, transfer.ID)
+		}
+
+		db.Model(&rule).Update("checked_at", added[len(added)-1].DetectedAt)
+	}
+}
+
+// isTransferTarget reports whether a playlist was created or filled by one of
+// the user's transfers, so mirrors are not mirrored back to where they came from
+func isTransferTarget(db *gorm.DB, userID uint, serviceType, playlistID string) bool {
+	var count int64
+	db.Model(&database.Transfer{}).
+		Where("user_id = ? AND target_service = ? AND target_playlist_id = ?", userID, serviceType, playlistID).
+		Count(&count)
+	return count > 0
+}
+
+// linkAutoSyncedTransfer keeps the playlist an auto-sync rule mirrored in sync
+// with its source once the transfer has finished
+func linkAutoSyncedTransfer(db *gorm.DB, transferID uint) {
+	var transfer database.Transfer
+	if err := db.First(&transfer, transferID).Error; err != nil || transfer.AutoSyncRuleID == nil || transfer.TargetPlaylistID == "" {
+		return
+	}
+	if (transfer.Status != database.TransferCompleted && transfer.Status != database.TransferCompletedWithErrors) || !syncableSource(transfer) {
+		return
+	}
+
+	var rule database.AutoSyncRule
+	if err := db.First(&rule, *transfer.AutoSyncRuleID).Error; err != nil || !rule.KeepInSync {
+		return
+	}
+	var existing int64
+	db.Model(&database.SyncLink{}).Where("transfer_id = ?", transfer.ID).Count(&existing)
+	if existing > 0 {
+		return
+	}
+
+	if _, err := createSyncLink(db, transfer, rule.RemovalMode, false); err != nil {
+		log.Printf("Failed to link auto-synced transfer %d: %v", transfer.ID, err)
+	}
+}
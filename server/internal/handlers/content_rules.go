@@ -0,0 +1,279 @@
+package handlers
+
+import (
+	"fmt"
+	"log"
+	"net/http"
+	"strconv"
+	"strings"
+
+	"server/internal/database"
+	"server/internal/i18n"
+	"server/internal/middleware"
+
+	"github.com/gin-gonic/gin"
+	"gorm.io/gorm"
+)
+
+// ruleActions are what a content rule can do with a matching track
+var ruleActions = map[string]bool{
+	"skip":    true, // leave the track out, reported as "skipped_by_rule"
+	"flag":    true, // transfer it but mark the result for review
+	"replace": true, // search for the rule's replacement title instead
+}
+
+// ruleFieldOps lists the operators each condition field supports
+var ruleFieldOps = map[string]map[string]bool{
+	"title":    {"contains": true, "equals": true},
+	"artist":   {"contains": true, "equals": true},
+	"text":     {"contains": true, "equals": true}, // title or artist
+	"track_id": {"equals": true},
+	"duration": {"equals": true, "lt": true, "gt": true}, // seconds
+	"explicit": {"equals": true},                         // "true" or "false"
+}
+
+const maxRuleConditions = 10
+
+type RuleConditionRequest struct {
+	Field string `json:"field" binding:"required"`
+	Op    string `json:"op" binding:"required"`
+	Value string `json:"value"`
+}
+
+type ContentRuleRequest struct {
+	Action      string                 `json:"action" binding:"required"`
+	Replacement string                 `json:"replacement"`
+	Position    int                    `json:"position"`
+	Conditions  []RuleConditionRequest `json:"conditions" binding:"required"`
+}
+
+// contentRules is a user's rules in evaluation order
+type contentRules []database.ContentRule
+
+// loadContentRules reads a user's rules; a failed read applies none
+func loadContentRules(db *gorm.DB, userID uint) contentRules {
+	var rules []database.ContentRule
+	if err := db.Preload("Conditions").Where("user_id = ?", userID).Order("position, id").Find(&rules).Error; err != nil {
+		log.Printf("Failed to load content rules for user %d: %v", userID, err)
+	}
+	return rules
+}
+
+// match returns the first rule whose conditions all hold for a track
+func (rules contentRules) match(track Track) (database.ContentRule, bool) {
+	for _, rule := range rules {
+		if len(rule.Conditions) == 0 {
+			continue
+		}
+		matched := true
+		for _, condition := range rule.Conditions {
+			if !conditionHolds(condition, track) {
+				matched = false
+				break
+			}
+		}
+		if matched {
+			return rule, true
+		}
+	}
+	return database.ContentRule{}, false
+}
+
+// conditionHolds evaluates one condition against a track
+func conditionHolds(condition database.ContentRuleCondition, track Track) bool {
+	switch condition.Field {
+	case "title":
+		return textHolds(condition.Op, track.Name, condition.Value)
+	case "artist":
+		return textHolds(condition.Op, track.Artist, condition.Value)
+	case "text":
+		return textHolds(condition.Op, track.Name, condition.Value) || textHolds(condition.Op, track.Artist, condition.Value)
+	case "track_id":
+		return track.ID != "" && track.ID == condition.Value
+	case "duration":
+		seconds, err := strconv.Atoi(condition.Value)
+		if err != nil || track.Duration == 0 {
+			return false
+		}
+		duration := track.Duration / 1000
+		switch condition.Op {
+		case "lt":
+			return duration < seconds
+		case "gt":
+			return duration > seconds
+		}
+		return duration == seconds
+	case "explicit":
+		want, err := strconv.ParseBool(condition.Value)
+		return err == nil && track.Explicit == want
+	}
+	return false
+}
+
+// textHolds compares text case-insensitively
+func textHolds(op, text, value string) bool {
+	text, value = strings.ToLower(text), strings.ToLower(value)
+	if op == "contains" {
+		return value != "" && strings.Contains(text, value)
+	}
+	return text == value
+}
+
+// replacementTrack is the track searched for instead when a "replace" rule matches
+func replacementTrack(rule database.ContentRule, track Track) Track {
+	replaced := track
+	replaced.Name = strings.ReplaceAll(rule.Replacement, "{title}", track.Name)
+	replaced.ISRC = ""
+	return replaced
+}
+
+// validateContentRule checks a rule request and builds the rule it describes
+func validateContentRule(req ContentRuleRequest) (database.ContentRule, error) {
+	action := strings.ToLower(req.Action)
+	if !ruleActions[action] {
+		return database.ContentRule{}, fmt.Errorf("action must be skip, flag or replace")
+	}
+	replacement := strings.TrimSpace(req.Replacement)
+	if action == "replace" && replacement == "" {
+		return database.ContentRule{}, fmt.Errorf("replace rules need a replacement title")
+	}
+	if len(req.Conditions) == 0 || len(req.Conditions) > maxRuleConditions {
+		return database.ContentRule{}, fmt.Errorf("rules need 1-%d conditions", maxRuleConditions)
+	}
+
+	rule := database.ContentRule{Action: action, Replacement: replacement, Position: req.Position}
+	for _, c := range req.Conditions {
+		field, op, value := strings.ToLower(c.Field), strings.ToLower(c.Op), strings.TrimSpace(c.Value)
+		if !ruleFieldOps[field][op] {
+			return database.ContentRule{}, fmt.Errorf("unsupported condition %s %s", field, op)
+		}
+		switch field {
+		case "duration":
+			if _, err := strconv.Atoi(value); err != nil {
+				return database.ContentRule{}, fmt.Errorf("duration must be a number of seconds")
+			}
+		case "explicit":
+			if _, err := strconv.ParseBool(value); err != nil {
+				return database.ContentRule{}, fmt.Errorf("explicit must be true or false")
+			}
+		}
+		if value == "" || len(value) > 200 {
+			return database.ContentRule{}, fmt.Errorf("condition values must be 1-200 characters")
+		}
+		rule.Conditions = append(rule.Conditions, database.ContentRuleCondition{Field: field, Op: op, Value: value})
+	}
+	return rule, nil
+}
+
+// GetContentRules lists the user's content rules in evaluation order
+func (h *Handlers) GetContentRules(c *gin.Context) {
+	user, exists := middleware.GetUserFromContext(c)
+	if !exists {
+		i18n.RespondError(c, http.StatusUnauthorized, i18n.CodeUnauthenticated, "")
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"rules": loadContentRules(h.DB.WithContext(c.Request.Context()), user.ID)})
+}
+
+// CreateContentRule adds a rule to the user's transfer pipeline
+func (h *Handlers) CreateContentRule(c *gin.Context) {
+	user, exists := middleware.GetUserFromContext(c)
+	if !exists {
+		i18n.RespondError(c, http.StatusUnauthorized, i18n.CodeUnauthenticated, "")
+		return
+	}
+
+	var req ContentRuleRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		i18n.RespondError(c, http.StatusBadRequest, i18n.CodeInvalidRequest, err.Error())
+		return
+	}
+
+	rule, err := validateContentRule(req)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+	rule.UserID = user.ID
+
+	if err := h.DB.WithContext(c.Request.Context()).Create(&rule).Error; err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to save rule"})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"rule": rule})
+}
+
+// UpdateContentRule replaces the action and conditions of one of the user's rules
+func (h *Handlers) UpdateContentRule(c *gin.Context) {
+	user, exists := middleware.GetUserFromContext(c)
+	if !exists {
+		i18n.RespondError(c, http.StatusUnauthorized, i18n.CodeUnauthenticated, "")
+		return
+	}
+
+	var existing database.ContentRule
+	if err := h.DB.WithContext(c.Request.Context()).Where("id = ? AND user_id = ?", c.Param("id"), user.ID).First(&existing).Error; err != nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": "Rule not found"})
+		return
+	}
+
+	var req ContentRuleRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		i18n.RespondError(c, http.StatusBadRequest, i18n.CodeInvalidRequest, err.Error())
+		return
+	}
+
+	rule, err := validateContentRule(req)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	err = h.DB.WithContext(c.Request.Context()).Transaction(func(tx *gorm.DB) error {
+		if err := tx.Where("rule_id = ?", existing.ID).Delete(&database.ContentRuleCondition{}).Error; err != nil {
+			return err
+		}
+		existing.Action = rule.Action
+		existing.Replacement = rule.Replacement
+		existing.Position = rule.Position
+		existing.Conditions = rule.Conditions
+		return tx.Save(&existing).Error
+	})
+	if err != nil {
+		log.Printf("Failed to update rule %d: %v", existing.ID, err)
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to update rule"})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"rule": existing})
+}
+
+// DeleteContentRule removes one of the user's rules
+func (h *Handlers) DeleteContentRule(c *gin.Context) {
+	user, exists := middleware.GetUserFromContext(c)
+	if !exists {
+		i18n.RespondError(c, http.StatusUnauthorized, i18n.CodeUnauthenticated, "")
+		return
+	}
+
+	var rule database.ContentRule
+	if err := h.DB.WithContext(c.Request.Context()).Where("id = ? AND user_id = ?", c.Param("id"), user.ID).First(&rule).Error; err != nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": "Rule not found"})
+		return
+	}
+
+	err := h.DB.WithContext(c.Request.Context()).Transaction(func(tx *gorm.DB) error {
+		if err := tx.Where("rule_id = ?", rule.ID).Delete(&database.ContentRuleCondition{}).Error; err != nil {
+			return err
+		}
+		return tx.Delete(&rule).Error
+	})
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to delete rule"})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"message": "Rule deleted"})
+}
@@ -0,0 +1,104 @@
+package handlers
+
+import (
+	"context"
+	"log"
+	"slices"
+
+	"server/internal/config"
+	"server/internal/database"
+	"server/internal/fingerprint"
+)
+
+// Confidence given to a candidate whose audio resolves to the source recording
+const fingerprintMatchConfidence = 0.95
+
+// Deep matching downloads and fingerprints audio, so only a few run at once
+var deepMatchSlots = make(chan struct{}, max(config.Int("DEEP_MATCH_CONCURRENCY", 1), 1))
+
+type youtubeCandidate struct {
+	Track      Track
+	Confidence float64
+	score      float64 // confidence plus the upload type preference, used for ranking
+}
+
+// deepMatchThreshold is the text confidence below which the audio stage runs
+func deepMatchThreshold() float64 {
+	return config.Float("DEEP_MATCH_THRESHOLD", 0.6)
+}
+
+// deepMatchYouTube identifies the source recording by fingerprint (or a known
+// MusicBrainz ID) and returns the first YouTube candidate whose audio
+// resolves to the same recording
+func (h *Handlers) deepMatchYouTube(ctx context.Context, track Track, candidates []youtubeCandidate) (youtubeCandidate, bool) {
+	if !fingerprint.YouTubeAvailable() {
+		return youtubeCandidate{}, false
+	}
+
+	select {
+	case deepMatchSlots <- struct{}{}:
+		defer func() { <-deepMatchSlots }()
+	case <-ctx.Done():
+		return youtubeCandidate{}, false
+	}
+
+	sourceIDs := h.sourceRecordingIDs(ctx, track)
+	if len(sourceIDs) == 0 {
+		return youtubeCandidate{}, false
+	}
+
+	limit := min(config.Int("DEEP_MATCH_CANDIDATES", 3), len(candidates))
+	for _, candidate := range candidates[:limit] {
+		fp, err := fingerprint.FromYouTube(ctx, candidate.Track.ID)
+		if err != nil {
+			log.Printf("Deep match: failed to fingerprint YouTube video %s: %v", candidate.Track.ID, err)
+			continue
+		}
+
+		ids, err := fingerprint.Lookup(ctx, fp)
+		if err != nil {
+			log.Printf("Deep match: AcoustID lookup failed for %s: %v", candidate.Track.ID, err)
+			continue
+		}
+
+		for _, id := range ids {
+			if slices.Contains(sourceIDs, id) {
+				log.Printf("Deep match: %s - %s is YouTube video %s", track.Artist, track.Name, candidate.Track.ID)
+				candidate.Confidence = max(candidate.Confidence, fingerprintMatchConfidence)
+				return candidate, true
+			}
+		}
+	}
+
+	return youtubeCandidate{}, false
+}
+
+// sourceRecordingIDs returns the MusicBrainz recordings a source track is
+// known to be, preferring the canonical identity over fingerprinting its preview
+func (h *Handlers) sourceRecordingIDs(ctx context.Context, track Track) []string {
+	var canonical database.CanonicalTrack
+	err := h.DB.WithContext(ctx).
+		Joins("JOIN track_identities ON track_identities.canonical_track_id = canonical_tracks.id").
+		Where("track_identities.service_type = ? AND track_identities.service_track_id = ?", track.Service, track.ID).
+		First(&canonical).Error
+	if err == nil && canonical.MusicBrainzID != "" {
+		return []string{canonical.MusicBrainzID}
+	}
+
+	if track.PreviewURL == "" {
+		return nil
+	}
+
+	fp, err := fingerprint.FromURL(ctx, track.PreviewURL)
+	if err != nil {
+		log.Printf("Deep match: failed to fingerprint preview of %s: %v", track.ID, err)
+		return nil
+	}
+
+	ids, err := fingerprint.Lookup(ctx, fp)
+	if err != nil {
+		log.Printf("Deep match: AcoustID lookup failed for preview of %s: %v", track.ID, err)
+		return nil
+	}
+	return ids
+}
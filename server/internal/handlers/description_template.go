@@ -0,0 +1,83 @@
+package handlers
+
+import (
+	"context"
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+
+	"server/internal/providers/youtube"
+
+	"gorm.io/gorm"
+)
+
+// Spotify rejects longer playlist descriptions
+const maxPlaylistDescriptionLength = 300
+
+const defaultDescriptionTemplate = "Transferred from {{source}}"
+
+// descriptionVars are the values available to description templates
+type descriptionVars struct {
+	Source         string // source service
+	SourcePlaylist string // source playlist name
+	Target         string // target service
+	Matched        int
+	Failed         int
+	Total          int
+}
+
+// renderDescription fills in {{source}}, {{source_playlist}}, {{target}}, {{date}},
+// {{matched}}, {{failed}} and {{total}}; unknown placeholders are left as-is
+func renderDescription(tmpl string, vars descriptionVars) string {
+	description := strings.NewReplacer(
+		"{{source}}", vars.Source,
+		"{{source_playlist}}", vars.SourcePlaylist,
+		"{{target}}", vars.Target,
+		"{{date}}", time.Now().Format("2006-01-02"),
+		"{{matched}}", strconv.Itoa(vars.Matched),
+		"{{failed}}", strconv.Itoa(vars.Failed),
+		"{{total}}", strconv.Itoa(vars.Total),
+	).Replace(tmpl)
+
+	if runes := []rune(description); len(runes) > maxPlaylistDescriptionLength {
+		description = string(runes[:maxPlaylistDescriptionLength])
+	}
+	return description
+}
+
+// descriptionNeedsResults reports whether the template refers to counts only known after matching
+func descriptionNeedsResults(tmpl string) bool {
+	return strings.Contains(tmpl, "{{matched}}") || strings.Contains(tmpl, "{{failed}}")
+}
+
+// descriptionTemplateFor picks the transfer's own template, then the user's default, then the built-in one
+func descriptionTemplateFor(db *gorm.DB, userID uint, transferTemplate string) string {
+	if transferTemplate != "" {
+		return transferTemplate
+	}
+
+	if settings := loadUserSettings(db, userID); settings.DescriptionTemplate != "" {
+		return settings.DescriptionTemplate
+	}
+
+	return defaultDescriptionTemplate
+}
+
+// updatePlaylistDescription rewrites the description of a playlist created by a transfer
+func (h *Handlers) updatePlaylistDescription(ctx context.Context, serviceType, accessToken, playlistID, name, description string) error {
+	name = sanitizePlaylistName(serviceType, name)
+	description = sanitizePlaylistDescription(serviceType, description)
+
+	switch serviceType {
+	case "spotify":
+		return h.Providers.Spotify.UpdatePlaylistDescription(ctx, accessToken, playlistID, description)
+	case "youtube":
+		// playlists.update replaces the whole snippet, so the title must be resent
+		return h.Providers.YouTube.UpdatePlaylist(ctx, accessToken, youtube.Playlist{
+			ID:      playlistID,
+			Snippet: youtube.PlaylistSnippet{Title: name, Description: description},
+		})
+	}
+	return fmt.Errorf("unsupported service: %s", serviceType)
+}
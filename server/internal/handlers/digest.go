@@ -0,0 +1,136 @@
+package handlers
+
+import (
+	"context"
+	"fmt"
+	"log"
+	"strings"
+	"time"
+
+	"server/internal/config"
+	"server/internal/database"
+	"server/internal/notifications"
+)
+
+// maxDigestLines caps each section of a digest
+const maxDigestLines = 20
+
+func init() {
+	host := config.String("SMTP_HOST", "")
+	if host == "" {
+		return
+	}
+	notifications.Register(notifications.NewEmailNotifier(notifications.EmailConfig{
+		Host:     host,
+		Port:     config.String("SMTP_PORT", "587"),
+		Username: config.String("SMTP_USERNAME", ""),
+		Password: config.String("SMTP_PASSWORD", ""),
+		From:     config.String("SMTP_FROM", "sync-playlist@localhost"),
+	}))
+}
+
+// StartWeeklyDigestScheduler sends users who opted in a summary of the
+// playlist changes syncs detected and the transfers that finished, once per
+// WEEKLY_DIGEST_INTERVAL (default a week)
+func (h *Handlers) StartWeeklyDigestScheduler(ctx context.Context) {
+	interval := config.Duration("WEEKLY_DIGEST_INTERVAL", 7*24*time.Hour)
+	if interval <= 0 {
+		log.Printf("Weekly digest disabled")
+		return
+	}
+
+	go func() {
+		ticker := time.NewTicker(time.Hour)
+		defer ticker.Stop()
+
+		for {
+			select {
+			case <-ctx.Done():
+				return
+			case <-ticker.C:
+				h.runScheduled(ctx, "weekly-digest", func() { h.sendDueDigests(ctx, interval) })
+			}
+		}
+	}()
+}
+
+// sendDueDigests sends a digest to every opted-in user whose last one is older than interval
+func (h *Handlers) sendDueDigests(ctx context.Context, interval time.Duration) {
+	now := time.Now()
+	var due []database.UserSettings
+	err := h.DB.WithContext(ctx).Where("weekly_digest = ? AND last_digest_at < ?", true, now.Add(-interval).Unix()).
+		Find(&due).Error
+	if err != nil {
+		log.Printf("Failed to load users due a digest: %v", err)
+		return
+	}
+
+	for _, settings := range due {
+		since := max(settings.LastDigestAt, now.Add(-interval).Unix())
+		// Mark first so a failing digest is retried next interval rather than every hour
+		h.DB.WithContext(ctx).Model(&settings).Update("last_digest_at", now.Unix())
+
+		title, message, ok := h.buildDigest(ctx, settings.UserID, since)
+		if !ok {
+			continue
+		}
+		notifications.Dispatch(notifications.Event{
+			UserID:   settings.UserID,
+			Type:     notifications.EventWeeklyDigest,
+			Title:    title,
+			Message:  message,
+			Channels: notificationChannels(settings),
+		})
+	}
+
+	// Changes older than a few digests are never reported again
+	h.DB.WithContext(ctx).Where("detected_at < ?", now.Add(-4*interval).Unix()).Delete(&database.PlaylistChange{})
+}
+
+// buildDigest summarizes a user's activity since a unix time; ok is false when there is nothing to report
+func (h *Handlers) buildDigest(ctx context.Context, userID uint, since int64) (string, string, bool) {
+	var changes []database.PlaylistChange
+	h.DB.WithContext(ctx).Where("user_id = ? AND detected_at >= ?", userID, since).Order("detected_at, id").Find(&changes)
+
+	var transfers []database.Transfer
+	h.DB.WithContext(ctx).
+		Where("user_id = ? AND status IN ? AND updated_at >= ?", userID,
+			[]database.TransferStatus{database.TransferCompleted, database.TransferCompletedWithErrors}, time.Unix(since, 0)).
+		Order("updated_at").Find(&transfers)
+
+	if len(changes) == 0 && len(transfers) == 0 {
+		return "", "", false
+	}
+
+	var b strings.Builder
+	if len(changes) > 0 {
+		fmt.Fprintf(&b, "Playlist changes (%d):\n", len(changes))
+		for i, change := range changes {
+			if i == maxDigestLines {
+				fmt.Fprintf(&b, "  ...and %d more\n", len(changes)-i)
+				break
+			}
+			line := fmt.Sprintf("  %s \"%s\" on %s", strings.ReplaceAll(change.Change, "_", " "), change.Name, getServiceDisplayName(change.ServiceType))
+			if change.Detail != "" {
+				line += " (" + change.Detail + ")"
+			}
+			b.WriteString(line + "\n")
+		}
+	}
+	if len(transfers) > 0 {
+		if b.Len() > 0 {
+			b.WriteString("\n")
+		}
+		fmt.Fprintf(&b, "Transfers completed (%d):\n", len(transfers))
+		for i, transfer := range transfers {
+			if i == maxDigestLines {
+				fmt.Fprintf(&b, "  ...and %d more\n", len(transfers)-i)
+				break
+			}
+			fmt.Fprintf(&b, "  \"%s\" to %s: %d/%d tracks\n", transfer.SourcePlaylistName,
+				getServiceDisplayName(transfer.TargetService), transfer.TracksMatched, transfer.TracksTotal)
+		}
+	}
+
+	return "Your weekly playlist digest", strings.TrimRight(b.String(), "\n"), true
+}
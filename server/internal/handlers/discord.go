@@ -0,0 +1,191 @@
+package handlers
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"log"
+	"net/http"
+	"os"
+	"strings"
+	"time"
+
+	"server/internal/database"
+	"server/internal/discord"
+	"server/internal/i18n"
+	"server/internal/middleware"
+	"server/internal/notifications"
+
+	"github.com/gin-gonic/gin"
+)
+
+const discordLinkCodeTTL = 10 * time.Minute
+
+// InitDiscord registers the bot's slash commands and Discord notifications when a bot token is configured
+func (h *Handlers) InitDiscord(ctx context.Context) {
+	if !h.Discord.Enabled() {
+		return
+	}
+
+	if err := h.Discord.RegisterCommands(ctx, discord.Commands); err != nil {
+		log.Printf("Failed to register Discord commands: %v", err)
+	}
+
+	notifications.Register(notifications.NewDiscordNotifier(h.Discord))
+	log.Printf("Discord integration enabled")
+}
+
+// HandleCreateDiscordLinkCode issues a short-lived code the user enters with /link in Discord
+func (h *Handlers) HandleCreateDiscordLinkCode(c *gin.Context) {
+	user, exists := middleware.GetUserFromContext(c)
+	if !exists {
+		i18n.RespondError(c, http.StatusUnauthorized, i18n.CodeUnauthenticated, "")
+		return
+	}
+
+	if !h.Discord.Enabled() {
+		c.JSON(http.StatusNotFound, gin.H{"error": "Discord integration is not enabled"})
+		return
+	}
+
+	code := generateLinkCode()
+	expiresAt := time.Now().Add(discordLinkCodeTTL)
+
+	var link database.DiscordLink
+	h.DB.WithContext(c.Request.Context()).Where("user_id = ?", user.ID).FirstOrInit(&link, database.DiscordLink{UserID: user.ID})
+	link.LinkCode = code
+	link.LinkCodeExpiresAt = expiresAt.Unix()
+	if err := h.DB.WithContext(c.Request.Context()).Save(&link).Error; err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to create link code"})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"code":         code,
+		"expires_at":   expiresAt,
+		"instructions": fmt.Sprintf("Run /link code:%s in Discord", code),
+	})
+}
+
+// HandleDeleteDiscordLink unlinks the user's Discord account
+func (h *Handlers) HandleDeleteDiscordLink(c *gin.Context) {
+	user, exists := middleware.GetUserFromContext(c)
+	if !exists {
+		i18n.RespondError(c, http.StatusUnauthorized, i18n.CodeUnauthenticated, "")
+		return
+	}
+
+	if err := h.DB.WithContext(c.Request.Context()).Where("user_id = ?", user.ID).Delete(&database.DiscordLink{}).Error; err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to unlink Discord"})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"message": "Discord unlinked"})
+}
+
+// HandleDiscordInteraction serves Discord's interactions endpoint for slash commands
+func (h *Handlers) HandleDiscordInteraction(c *gin.Context) {
+	body, err := io.ReadAll(c.Request.Body)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Failed to read request"})
+		return
+	}
+
+	if !discord.VerifySignature(os.Getenv("DISCORD_PUBLIC_KEY"),
+		c.GetHeader("X-Signature-Ed25519"), c.GetHeader("X-Signature-Timestamp"), body) {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "Invalid request signature"})
+		return
+	}
+
+	var interaction discord.Interaction
+	if err := json.Unmarshal(body, &interaction); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid interaction"})
+		return
+	}
+
+	if interaction.Type == discord.InteractionPing {
+		c.JSON(http.StatusOK, discord.InteractionResponse{Type: discord.ResponsePong})
+		return
+	}
+
+	if interaction.Type != discord.InteractionApplicationCommand {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Unsupported interaction type"})
+		return
+	}
+
+	switch interaction.Data.Name {
+	case "link":
+		c.JSON(http.StatusOK, discord.Reply(h.handleDiscordLink(c.Request.Context(), interaction)))
+	case "transfer":
+		c.JSON(http.StatusOK, discord.Reply(h.handleDiscordTransfer(c.Request.Context(), interaction)))
+	case "status":
+		c.JSON(http.StatusOK, discord.Reply(h.handleDiscordStatus(c.Request.Context(), interaction)))
+	default:
+		c.JSON(http.StatusOK, discord.Reply("Unknown command"))
+	}
+}
+
+func (h *Handlers) handleDiscordLink(ctx context.Context, interaction discord.Interaction) string {
+	code := strings.ToUpper(strings.TrimSpace(interaction.Option("code")))
+
+	var link database.DiscordLink
+	err := h.DB.WithContext(ctx).Where("link_code = ? AND link_code_expires_at > ?", code, time.Now().Unix()).First(&link).Error
+	if code == "" || err != nil {
+		return "That link code is invalid or has expired. Generate a new one in the web app."
+	}
+
+	// A Discord account can only be linked to one user
+	h.DB.WithContext(ctx).Where("discord_user_id = ? AND id <> ?", interaction.UserID(), link.ID).Delete(&database.DiscordLink{})
+
+	link.DiscordUserID = interaction.UserID()
+	link.LinkCode = ""
+	link.LinkCodeExpiresAt = 0
+	link.LinkedAt = time.Now().Unix()
+	if err := h.DB.WithContext(ctx).Save(&link).Error; err != nil {
+		return "Failed to link your account, please try again."
+	}
+
+	return "Your Discord account is now linked. You'll receive transfer notifications here."
+}
+
+func (h *Handlers) handleDiscordTransfer(ctx context.Context, interaction discord.Interaction) string {
+	userID, ok := h.discordLinkedUserID(ctx, interaction.UserID())
+	if !ok {
+		return "Link your account first with /link."
+	}
+
+	transfer, _, err := h.startTransferForUser(ctx, userID, TransferRequest{
+		SourceService:      interaction.Option("source_service"),
+		SourcePlaylistID:   interaction.Option("source_playlist_id"),
+		TargetService:      interaction.Option("target_service"),
+		TargetPlaylistName: interaction.Option("name"),
+	})
+	if err != nil {
+		return "Could not start transfer: " + err.Error()
+	}
+
+	return fmt.Sprintf("Transfer #%d started. I'll message you when it finishes.", transfer.ID)
+}
+
+func (h *Handlers) handleDiscordStatus(ctx context.Context, interaction discord.Interaction) string {
+	userID, ok := h.discordLinkedUserID(ctx, interaction.UserID())
+	if !ok {
+		return "Link your account first with /link."
+	}
+
+	return h.recentTransfersSummary(ctx, userID)
+}
+
+// discordLinkedUserID resolves the app user linked to a Discord account
+func (h *Handlers) discordLinkedUserID(ctx context.Context, discordUserID string) (uint, bool) {
+	if discordUserID == "" {
+		return 0, false
+	}
+
+	var link database.DiscordLink
+	if err := h.DB.WithContext(ctx).Where("discord_user_id = ?", discordUserID).First(&link).Error; err != nil {
+		return 0, false
+	}
+	return link.UserID, true
+}
@@ -0,0 +1,347 @@
+package handlers
+
+import (
+	"archive/zip"
+	"context"
+	"crypto/hmac"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/csv"
+	"encoding/hex"
+	"fmt"
+	"io"
+	"log"
+	"net/http"
+	"net/url"
+	"os"
+	"regexp"
+	"strconv"
+	"strings"
+	"time"
+
+	"server/internal/config"
+	"server/internal/database"
+	"server/internal/i18n"
+	"server/internal/jobs"
+	"server/internal/middleware"
+	"server/internal/storage"
+
+	"github.com/gin-gonic/gin"
+)
+
+// Export settings. Exports with more tracks than EXPORT_INLINE_MAX_TRACKS are
+// generated in the background into storage and downloaded through a signed URL.
+var (
+	exportInlineMaxTracks = config.Int64("EXPORT_INLINE_MAX_TRACKS", 2000)
+	exportRetention       = config.Duration("EXPORT_RETENTION", 24*time.Hour)
+	exportURLTTL          = config.Duration("EXPORT_URL_TTL", 15*time.Minute)
+)
+
+var exportHeader = []string{"playlist", "service", "title", "artist", "album", "isrc", "duration_ms", "added_at", "added_by", "service_id"}
+
+var unsafeFileChars = regexp.MustCompile(`[^A-Za-z0-9._-]+`)
+
+type ExportRequest struct {
+	Format      string `json:"format"`       // "csv" (default) or "zip", one CSV per playlist
+	PlaylistIDs []uint `json:"playlist_ids"` // stored playlist IDs; all when empty
+}
+
+// CreateExport exports the user's stored playlists. Small exports are streamed
+// in the response; large ones return 202 with an export to poll for its download URL.
+func (h *Handlers) CreateExport(c *gin.Context) {
+	user, exists := middleware.GetUserFromContext(c)
+	if !exists {
+		i18n.RespondError(c, http.StatusUnauthorized, i18n.CodeUnauthenticated, "")
+		return
+	}
+
+	var req ExportRequest
+	if err := c.ShouldBindJSON(&req); err != nil && err != io.EOF {
+		i18n.RespondError(c, http.StatusBadRequest, i18n.CodeInvalidRequest, err.Error())
+		return
+	}
+	if req.Format == "" {
+		req.Format = "csv"
+	}
+	if req.Format != "csv" && req.Format != "zip" {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Format must be csv or zip"})
+		return
+	}
+
+	ctx := c.Request.Context()
+	h.sweepExpiredExports(ctx)
+
+	query := h.DB.WithContext(ctx).Where("user_id = ?", user.ID)
+	if len(req.PlaylistIDs) > 0 {
+		query = query.Where("id IN ?", req.PlaylistIDs)
+	}
+	var playlists []database.Playlist
+	if err := query.Order("id").Find(&playlists).Error; err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to fetch playlists"})
+		return
+	}
+	if len(playlists) == 0 {
+		i18n.RespondError(c, http.StatusNotFound, i18n.CodePlaylistNotFound, "")
+		return
+	}
+
+	playlistIDs := make([]uint, len(playlists))
+	for i, playlist := range playlists {
+		playlistIDs[i] = playlist.ID
+	}
+	var trackCount int64
+	h.DB.WithContext(ctx).Model(&database.PlaylistTrack{}).Where("playlist_id IN ?", playlistIDs).Count(&trackCount)
+
+	if trackCount <= exportInlineMaxTracks {
+		c.Header("Content-Disposition", fmt.Sprintf(`attachment; filename="playlists.%s"`, req.Format))
+		c.Header("Content-Type", exportContentType(req.Format))
+		c.Status(http.StatusOK)
+		if err := h.writeExport(ctx, c.Writer, req.Format, playlists); err != nil {
+			log.Printf("Inline export for user %d failed: %v", user.ID, err)
+		}
+		return
+	}
+
+	now := time.Now()
+	export := database.Export{
+		UserID:    user.ID,
+		Format:    req.Format,
+		Status:    "pending",
+		CreatedAt: now.Unix(),
+		ExpiresAt: now.Add(exportRetention).Unix(),
+	}
+	if err := h.DB.WithContext(ctx).Create(&export).Error; err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to create export"})
+		return
+	}
+
+	h.Jobs.Enqueue(&jobs.Job{
+		ID:     fmt.Sprintf("export-%d", export.ID),
+		Type:   "export",
+		UserID: user.ID,
+		Run: func(ctx context.Context) error {
+			return h.generateExport(ctx, export, playlists)
+		},
+	})
+
+	c.JSON(http.StatusAccepted, gin.H{"export": export, "track_count": trackCount})
+}
+
+// GetExport reports an export's progress, with a signed download URL once it is ready
+func (h *Handlers) GetExport(c *gin.Context) {
+	user, exists := middleware.GetUserFromContext(c)
+	if !exists {
+		i18n.RespondError(c, http.StatusUnauthorized, i18n.CodeUnauthenticated, "")
+		return
+	}
+
+	var export database.Export
+	err := h.DB.WithContext(c.Request.Context()).
+		Where("id = ? AND user_id = ? AND expires_at > ?", c.Param("id"), user.ID, time.Now().Unix()).
+		First(&export).Error
+	if err != nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": "Export not found"})
+		return
+	}
+
+	response := gin.H{"export": export}
+	if export.Status == "completed" {
+		expires := min(time.Now().Add(exportURLTTL).Unix(), export.ExpiresAt)
+		// Object storage serves the file itself when it can sign links; local files go through DownloadExport
+		downloadURL, err := storage.Default.SignedURL(export.Key, time.Until(time.Unix(expires, 0)))
+		if err != nil {
+			downloadURL = exportDownloadURL(export.ID, expires)
+		}
+		response["download_url"] = downloadURL
+		response["download_expires_at"] = expires
+	}
+	c.JSON(http.StatusOK, response)
+}
+
+// DownloadExport serves a generated export. It is public; the signature in the
+// URL is what authorizes the download, so links work in a plain browser tab.
+func (h *Handlers) DownloadExport(c *gin.Context) {
+	exportID, err := strconv.ParseUint(c.Param("id"), 10, 32)
+	if err != nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": "Export not found"})
+		return
+	}
+	expires, err := strconv.ParseInt(c.Query("expires"), 10, 64)
+	if err != nil || time.Now().Unix() >= expires ||
+		!hmac.Equal([]byte(c.Query("signature")), []byte(signExport(uint(exportID), expires))) {
+		c.JSON(http.StatusForbidden, gin.H{"error": "Download link is invalid or has expired"})
+		return
+	}
+
+	var export database.Export
+	err = h.DB.WithContext(c.Request.Context()).
+		Where("id = ? AND status = ? AND expires_at > ?", exportID, "completed", time.Now().Unix()).
+		First(&export).Error
+	if err != nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": "Export not found"})
+		return
+	}
+
+	body, err := storage.Default.Get(c.Request.Context(), export.Key)
+	if err != nil {
+		log.Printf("Failed to read export %d: %v", export.ID, err)
+		c.JSON(http.StatusNotFound, gin.H{"error": "Export not found"})
+		return
+	}
+	defer body.Close()
+
+	c.DataFromReader(http.StatusOK, export.Size, exportContentType(export.Format), body, map[string]string{
+		"Content-Disposition": fmt.Sprintf(`attachment; filename="playlists.%s"`, export.Format),
+	})
+}
+
+// generateExport builds an export in a temporary file, then moves it to storage
+func (h *Handlers) generateExport(ctx context.Context, export database.Export, playlists []database.Playlist) error {
+	fail := func(err error) error {
+		log.Printf("Export %d failed: %v", export.ID, err)
+		h.DB.WithContext(context.WithoutCancel(ctx)).Model(&export).
+			Updates(map[string]interface{}{"status": "failed", "error": err.Error()})
+		return err
+	}
+
+	// The random part keeps keys unguessable should the bucket ever be exposed
+	buf := make([]byte, 8)
+	if _, err := rand.Read(buf); err != nil {
+		return fail(err)
+	}
+	key := fmt.Sprintf("exports/%d/%d-%s.%s", export.UserID, export.ID, hex.EncodeToString(buf), export.Format)
+
+	file, err := os.CreateTemp("", "export-*")
+	if err != nil {
+		return fail(err)
+	}
+	defer os.Remove(file.Name())
+	defer file.Close()
+
+	if err := h.writeExport(ctx, file, export.Format, playlists); err != nil {
+		return fail(err)
+	}
+	size, err := file.Seek(0, io.SeekCurrent)
+	if err != nil {
+		return fail(err)
+	}
+	if _, err := file.Seek(0, io.SeekStart); err != nil {
+		return fail(err)
+	}
+	if err := storage.Default.Put(ctx, key, file, size, exportContentType(export.Format)); err != nil {
+		return fail(err)
+	}
+
+	return h.DB.WithContext(ctx).Model(&export).Updates(map[string]interface{}{
+		"status": "completed",
+		"key":    key,
+		"size":   size,
+	}).Error
+}
+
+// writeExport writes playlists as one CSV, or as a zip with a CSV per playlist
+func (h *Handlers) writeExport(ctx context.Context, w io.Writer, format string, playlists []database.Playlist) error {
+	if format == "csv" {
+		out := csv.NewWriter(w)
+		out.Write(exportHeader)
+		for _, playlist := range playlists {
+			if err := h.writePlaylistRows(ctx, out, playlist); err != nil {
+				return err
+			}
+		}
+		out.Flush()
+		return out.Error()
+	}
+
+	archive := zip.NewWriter(w)
+	for _, playlist := range playlists {
+		name := unsafeFileChars.ReplaceAllString(playlist.Name, "_")
+		entry, err := archive.Create(fmt.Sprintf("%d-%s.csv", playlist.ID, name))
+		if err != nil {
+			return err
+		}
+		out := csv.NewWriter(entry)
+		out.Write(exportHeader)
+		if err := h.writePlaylistRows(ctx, out, playlist); err != nil {
+			return err
+		}
+		out.Flush()
+		if err := out.Error(); err != nil {
+			return err
+		}
+	}
+	return archive.Close()
+}
+
+func (h *Handlers) writePlaylistRows(ctx context.Context, out *csv.Writer, playlist database.Playlist) error {
+	if err := ctx.Err(); err != nil {
+		return err
+	}
+
+	var tracks []database.PlaylistTrack
+	if err := h.DB.WithContext(ctx).Where("playlist_id = ?", playlist.ID).Order("id").Find(&tracks).Error; err != nil {
+		return err
+	}
+	for _, track := range tracks {
+		out.Write([]string{
+			csvSafe(playlist.Name),
+			playlist.ServiceType,
+			csvSafe(track.Title),
+			csvSafe(track.Artist),
+			csvSafe(track.Album),
+			csvSafe(track.ISRC),
+			strconv.Itoa(track.Duration),
+			strconv.FormatInt(track.AddedAt, 10),
+			csvSafe(track.AddedBy),
+			csvSafe(track.ServiceID),
+		})
+	}
+	return out.Error()
+}
+
+// csvSafe keeps a provider-supplied cell from being run as a formula when the
+// export is opened in a spreadsheet, by prefixing it with a quote
+func csvSafe(cell string) string {
+	if cell != "" && strings.ContainsRune("=+-@\t\r", rune(cell[0])) {
+		return "'" + cell
+	}
+	return cell
+}
+
+// sweepExpiredExports deletes exports past their retention along with their stored files
+func (h *Handlers) sweepExpiredExports(ctx context.Context) {
+	var expired []database.Export
+	if err := h.DB.WithContext(ctx).Where("expires_at <= ?", time.Now().Unix()).Find(&expired).Error; err != nil {
+		return
+	}
+	for _, export := range expired {
+		if export.Key != "" {
+			if err := storage.Default.Delete(ctx, export.Key); err != nil {
+				log.Printf("Failed to delete export %s: %v", export.Key, err)
+				continue
+			}
+		}
+		h.DB.WithContext(ctx).Delete(&export)
+	}
+}
+
+func exportContentType(format string) string {
+	if format == "zip" {
+		return "application/zip"
+	}
+	return "text/csv; charset=utf-8"
+}
+
+// signExport is the HMAC authorizing a download of an export until expires
+func signExport(exportID uint, expires int64) string {
+	mac := hmac.New(sha256.New, []byte(os.Getenv("JWT_SECRET")))
+	fmt.Fprintf(mac, "export:%d:%d", exportID, expires)
+	return hex.EncodeToString(mac.Sum(nil))
+}
+
+func exportDownloadURL(exportID uint, expires int64) string {
+	q := url.Values{}
+	q.Set("expires", strconv.FormatInt(expires, 10))
+	q.Set("signature", signExport(exportID, expires))
+	return fmt.Sprintf("%s/api/exports/%d/download?%s", os.Getenv("BACKEND_URL"), exportID, q.Encode())
+}
@@ -0,0 +1,97 @@
+package handlers
+
+import (
+	"net/http"
+	"regexp"
+	"strconv"
+	"strings"
+
+	"server/internal/database"
+	"server/internal/flags"
+	"server/internal/i18n"
+	"server/internal/middleware"
+
+	"github.com/gin-gonic/gin"
+	"gorm.io/gorm/clause"
+)
+
+var flagNamePattern = regexp.MustCompile(`^[a-z0-9_]{1,64}$`)
+
+// GetFeatures returns which feature flags are enabled for the current user
+func (h *Handlers) GetFeatures(c *gin.Context) {
+	user, exists := middleware.GetUserFromContext(c)
+	if !exists {
+		i18n.RespondError(c, http.StatusUnauthorized, i18n.CodeUnauthenticated, "")
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"features": flags.ForUser(user.ID)})
+}
+
+// AdminListFlags returns every flag with its rollout and where it is defined
+func (h *Handlers) AdminListFlags(c *gin.Context) {
+	c.JSON(http.StatusOK, gin.H{"flags": flags.All()})
+}
+
+type FeatureFlagRequest struct {
+	Enabled     bool   `json:"enabled"`
+	Percentage  int    `json:"percentage" binding:"min=0,max=100"`
+	UserIDs     []uint `json:"user_ids"`
+	Description string `json:"description"`
+}
+
+// AdminPutFlag creates or replaces a flag's database override
+func (h *Handlers) AdminPutFlag(c *gin.Context) {
+	name := c.Param("name")
+	if !flagNamePattern.MatchString(name) {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Flag names use lowercase letters, digits and underscores"})
+		return
+	}
+
+	var req FeatureFlagRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		i18n.RespondError(c, http.StatusBadRequest, i18n.CodeInvalidRequest, err.Error())
+		return
+	}
+
+	userIDs := make([]string, len(req.UserIDs))
+	for i, id := range req.UserIDs {
+		userIDs[i] = strconv.FormatUint(uint64(id), 10)
+	}
+
+	flag := database.FeatureFlag{
+		Name:        name,
+		Enabled:     req.Enabled,
+		Percentage:  req.Percentage,
+		UserIDs:     strings.Join(userIDs, ","),
+		Description: req.Description,
+	}
+	err := h.DB.WithContext(c.Request.Context()).Clauses(clause.OnConflict{
+		Columns:   []clause.Column{{Name: "name"}},
+		DoUpdates: clause.AssignmentColumns([]string{"enabled", "percentage", "user_ids", "description", "updated_at", "deleted_at"}),
+	}).Create(&flag).Error
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to save flag"})
+		return
+	}
+
+	// Other instances pick the change up on their next refresh
+	flags.Invalidate()
+	c.JSON(http.StatusOK, gin.H{"flag": flags.All()[name]})
+}
+
+// AdminDeleteFlag removes a flag's database override, reverting to its environment default
+func (h *Handlers) AdminDeleteFlag(c *gin.Context) {
+	result := h.DB.WithContext(c.Request.Context()).Unscoped().Where("name = ?", c.Param("name")).Delete(&database.FeatureFlag{})
+	if result.Error != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to delete flag"})
+		return
+	}
+	if result.RowsAffected == 0 {
+		c.JSON(http.StatusNotFound, gin.H{"error": "Flag not found"})
+		return
+	}
+
+	flags.Invalidate()
+	c.JSON(http.StatusOK, gin.H{"message": "Flag removed"})
+}
@@ -0,0 +1,152 @@
+package handlers
+
+import (
+	"crypto/rand"
+	"encoding/hex"
+	"encoding/xml"
+	"fmt"
+	"net/http"
+	"os"
+	"time"
+
+	"server/internal/database"
+	"server/internal/i18n"
+	"server/internal/middleware"
+
+	"github.com/gin-gonic/gin"
+)
+
+// How far back the activity feed reaches
+const feedWindow = 30 * 24 * time.Hour
+
+type atomFeed struct {
+	XMLName xml.Name    `xml:"feed"`
+	XMLNS   string      `xml:"xmlns,attr"`
+	ID      string      `xml:"id"`
+	Title   string      `xml:"title"`
+	Updated string      `xml:"updated"`
+	Entries []atomEntry `xml:"entry"`
+}
+
+type atomEntry struct {
+	ID      string   `xml:"id"`
+	Title   string   `xml:"title"`
+	Updated string   `xml:"updated"`
+	Summary string   `xml:"summary"`
+	Link    atomLink `xml:"link"`
+}
+
+type atomLink struct {
+	Href string `xml:"href,attr"`
+}
+
+// HandleRotateFeedToken creates (or replaces) the secret token in the user's feed URL
+func (h *Handlers) HandleRotateFeedToken(c *gin.Context) {
+	user, exists := middleware.GetUserFromContext(c)
+	if !exists {
+		i18n.RespondError(c, http.StatusUnauthorized, i18n.CodeUnauthenticated, "")
+		return
+	}
+
+	buf := make([]byte, 24)
+	if _, err := rand.Read(buf); err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to generate feed token"})
+		return
+	}
+	token := hex.EncodeToString(buf)
+
+	if err := h.DB.WithContext(c.Request.Context()).Model(&database.User{}).Where("id = ?", user.ID).Update("feed_token", token).Error; err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to save feed token"})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"feed_url": fmt.Sprintf("%s/api/feed/%s", os.Getenv("BACKEND_URL"), token),
+	})
+}
+
+// HandleActivityFeed serves an Atom feed of the user's transfers and playlist changes.
+// Feed readers can't send bearer tokens, so the secret token in the URL authenticates.
+func (h *Handlers) HandleActivityFeed(c *gin.Context) {
+	token := c.Param("token")
+
+	var user database.User
+	if token == "" || h.DB.WithContext(c.Request.Context()).Where("feed_token = ?", token).First(&user).Error != nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": "Feed not found"})
+		return
+	}
+
+	since := time.Now().Add(-feedWindow)
+	frontend := frontendURL()
+	var entries []atomEntry
+	updated := since
+
+	addEntry := func(id, title, summary string, at time.Time, link string) {
+		entries = append(entries, atomEntry{
+			ID:      id,
+			Title:   title,
+			Updated: at.UTC().Format(time.RFC3339),
+			Summary: summary,
+			Link:    atomLink{Href: link},
+		})
+		if at.After(updated) {
+			updated = at
+		}
+	}
+
+	var transfers []database.Transfer
+	h.DB.WithContext(c.Request.Context()).Where("user_id = ? AND updated_at > ? AND status NOT IN ?", user.ID, since, database.ActiveTransferStatuses).
+		Order("updated_at DESC").Limit(100).Find(&transfers)
+	for _, transfer := range transfers {
+		addEntry(
+			fmt.Sprintf("urn:playlist-tracker:transfer:%d", transfer.ID),
+			fmt.Sprintf("Transfer \"%s\": %s", transfer.SourcePlaylistName, transfer.Status),
+			fmt.Sprintf("%d/%d tracks transferred from %s to %s, %d failed.",
+				transfer.TracksMatched, transfer.TracksTotal, getServiceDisplayName(transfer.SourceService),
+				getServiceDisplayName(transfer.TargetService), transfer.TracksFailed),
+			transfer.UpdatedAt,
+			fmt.Sprintf("%s/dashboard?transfer=%d", frontend, transfer.ID),
+		)
+	}
+
+	// Playlists that appeared or disappeared during syncs
+	var playlists []database.Playlist
+	h.DB.WithContext(c.Request.Context()).Unscoped().
+		Where("user_id = ? AND (created_at > ? OR deleted_at > ?)", user.ID, since, since).
+		Order("updated_at DESC").Limit(100).Find(&playlists)
+	for _, playlist := range playlists {
+		service := getServiceDisplayName(playlist.ServiceType)
+		if playlist.DeletedAt.Valid && playlist.DeletedAt.Time.After(since) {
+			addEntry(
+				fmt.Sprintf("urn:playlist-tracker:playlist:%d:removed", playlist.ID),
+				fmt.Sprintf("Playlist removed on %s: %s", service, playlist.Name),
+				fmt.Sprintf("\"%s\" no longer exists on %s.", playlist.Name, service),
+				playlist.DeletedAt.Time,
+				frontend+"/dashboard",
+			)
+		}
+		if playlist.CreatedAt.After(since) {
+			addEntry(
+				fmt.Sprintf("urn:playlist-tracker:playlist:%d:added", playlist.ID),
+				fmt.Sprintf("New playlist on %s: %s", service, playlist.Name),
+				fmt.Sprintf("\"%s\" with %d tracks was found on %s.", playlist.Name, playlist.TrackCount, service),
+				playlist.CreatedAt,
+				frontend+"/dashboard",
+			)
+		}
+	}
+
+	feed := atomFeed{
+		XMLNS:   "http://www.w3.org/2005/Atom",
+		ID:      fmt.Sprintf("urn:playlist-tracker:feed:%d", user.ID),
+		Title:   "Playlist Tracker activity",
+		Updated: updated.UTC().Format(time.RFC3339),
+		Entries: entries,
+	}
+
+	c.Header("Content-Type", "application/atom+xml; charset=utf-8")
+	c.String(http.StatusOK, xml.Header)
+	if err := xml.NewEncoder(c.Writer).Encode(feed); err != nil {
+		c.Error(err)
+	}
+}
@@ -0,0 +1,29 @@
+package handlers
+
+import (
+	"server/internal/auth"
+	"server/internal/discord"
+	"server/internal/jobs"
+	"server/internal/lock"
+	"server/internal/telegram"
+
+	"gorm.io/gorm"
+)
+
+// Handlers serves the API routes and runs the background schedulers. Its
+// dependencies are built in main once the database is connected.
+type Handlers struct {
+	DB        *gorm.DB
+	Tokens    *auth.TokenManager
+	Providers *Providers
+	Jobs      *jobs.Queue // runs transfers and other background work
+	Locker    lock.Locker // keeps scheduler passes to one instance at a time
+	Discord   *discord.Client
+	Telegram  *telegram.Client
+}
+
+// New returns handlers using the given database, token manager, provider
+// clients, job queue, locker and chat clients
+func New(db *gorm.DB, tokens *auth.TokenManager, providers *Providers, queue *jobs.Queue, locker lock.Locker, discordBot *discord.Client, telegramBot *telegram.Client) *Handlers {
+	return &Handlers{DB: db, Tokens: tokens, Providers: providers, Jobs: queue, Locker: locker, Discord: discordBot, Telegram: telegramBot}
+}
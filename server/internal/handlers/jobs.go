@@ -0,0 +1,29 @@
+package handlers
+
+import (
+	"context"
+	"log"
+	"net/http"
+
+	"server/internal/lock"
+
+	"github.com/gin-gonic/gin"
+)
+
+// runScheduled runs one pass of a periodic scheduler, skipping it while
+// another replica is running the same pass
+func (h *Handlers) runScheduled(ctx context.Context, name string, fn func()) {
+	ran, err := lock.WithLock(ctx, h.Locker, "scheduler:"+name, fn)
+	if err != nil {
+		log.Printf("Skipping %s pass: %v", name, err)
+		return
+	}
+	if !ran {
+		log.Printf("Skipping %s pass, another instance is running it", name)
+	}
+}
+
+// HandleQueueStatus returns job queue depth and worker autoscaling metrics
+func (h *Handlers) HandleQueueStatus(c *gin.Context) {
+	c.JSON(http.StatusOK, gin.H{"queue": h.Jobs.Stats()})
+}
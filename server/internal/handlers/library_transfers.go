@@ -0,0 +1,638 @@
+package handlers
+
+import (
+	"context"
+	"fmt"
+	"log"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+
+	"server/internal/database"
+	"server/internal/i18n"
+	"server/internal/jobs"
+	"server/internal/middleware"
+	"server/internal/quota"
+	"server/internal/ratelimit"
+
+	"github.com/gin-gonic/gin"
+	"gorm.io/gorm"
+)
+
+type LibraryTransferRequest struct {
+	SourceService string `json:"source_service" binding:"required"`
+	TargetService string `json:"target_service" binding:"required"`
+	Playlists     *bool  `json:"playlists"`   // true by default
+	LikedSongs    *bool  `json:"liked_songs"` // true by default
+	Artists       *bool  `json:"artists"`     // followed artists, true by default
+}
+
+// LibraryTransferProgress is a library transfer with the state of every step
+// and its overall progress
+type LibraryTransferProgress struct {
+	database.LibraryTransfer
+	Steps          []LibraryTransferStepProgress `json:"steps"`
+	StepsCompleted int                           `json:"steps_completed"`
+	StepsFailed    int                           `json:"steps_failed"`
+	TracksTotal    int                           `json:"tracks_total"`
+	TracksMatched  int                           `json:"tracks_matched"`
+	TracksFailed   int                           `json:"tracks_failed"`
+	Percent        int                           `json:"percent"` // 0 to 100
+}
+
+// LibraryTransferStepProgress is a step of a library transfer with its transfer's status
+type LibraryTransferStepProgress struct {
+	database.LibraryTransferStep
+	TransferStatus database.TransferStatus `json:"transfer_status,omitempty"`
+	TracksTotal    int                     `json:"tracks_total"`
+	TracksDone     int                     `json:"tracks_done"`
+}
+
+// StartLibraryTransfer plans and starts moving everything from one service to
+// another: every stored playlist, the liked songs and the followed artists.
+// Playlist transfers run at low priority and start as the user's concurrent
+// transfer limit allows.
+func (h *Handlers) StartLibraryTransfer(c *gin.Context) {
+	user, exists := middleware.GetUserFromContext(c)
+	if !exists {
+		i18n.RespondError(c, http.StatusUnauthorized, i18n.CodeUnauthenticated, "")
+		return
+	}
+
+	var req LibraryTransferRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		i18n.RespondError(c, http.StatusBadRequest, i18n.CodeInvalidRequest, err.Error())
+		return
+	}
+	ctx := c.Request.Context()
+	db := h.DB.WithContext(ctx)
+	if status, err := checkLibraryServices(db, user.ID, req.SourceService, req.TargetService); err != nil {
+		c.JSON(status, gin.H{"error": err.Error()})
+		return
+	}
+
+	if libraryTransferRunning(db, user.ID) {
+		c.JSON(http.StatusConflict, gin.H{"error": "A library transfer is already in progress"})
+		return
+	}
+
+	// The playlist steps come from the stored playlists, so bring them up to date first
+	if req.Playlists == nil || *req.Playlists {
+		var source database.UserService
+		if err := db.Where("user_id = ? AND service_type = ?", user.ID, req.SourceService).First(&source).Error; err != nil {
+			i18n.RespondError(c, http.StatusBadRequest, i18n.CodeServiceNotConnected, "")
+			return
+		}
+		if err := h.Tokens.RefreshTokenIfNeeded(&source); err != nil {
+			c.JSON(http.StatusBadGateway, gin.H{"error": "Source service token refresh failed: " + err.Error()})
+			return
+		}
+		if err := h.refreshStoredPlaylists(ctx, user.ID, source); err != nil {
+			c.JSON(http.StatusBadGateway, gin.H{"error": "Failed to fetch playlists"})
+			return
+		}
+	}
+
+	steps, excluded, err := planLibraryTransfer(db, user.ID, req)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to fetch playlists"})
+		return
+	}
+	if len(steps) == 0 {
+		c.JSON(http.StatusNotFound, gin.H{"error": "Nothing to transfer"})
+		return
+	}
+
+	library, err := h.createLibraryTransfer(db, user.ID, req.SourceService, req.TargetService, steps)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to create library transfer"})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"message":          "Library transfer started",
+		"library_transfer": h.libraryTransferProgress(db, library),
+		"excluded":         excluded, // playlists on the user's exclusion list
+	})
+}
+
+// checkLibraryServices makes sure a library can be moved between two services:
+// both supported, different and connected. On failure it returns the HTTP status to report.
+func checkLibraryServices(db *gorm.DB, userID uint, sourceService, targetService string) (int, error) {
+	for _, service := range []string{sourceService, targetService} {
+		if service != "spotify" && service != "youtube" {
+			return http.StatusBadRequest, fmt.Errorf("Unsupported service: %s", service)
+		}
+	}
+	if sourceService == targetService {
+		return http.StatusBadRequest, fmt.Errorf("Source and target service must differ")
+	}
+	for _, serviceType := range []string{sourceService, targetService} {
+		var service database.UserService
+		if err := db.Where("user_id = ? AND service_type = ?", userID, serviceType).First(&service).Error; err != nil {
+			return http.StatusBadRequest, fmt.Errorf("%s is not connected", getServiceDisplayName(serviceType))
+		}
+		if service.Status == database.ServiceNeedsReauth {
+			return http.StatusConflict, fmt.Errorf("Reconnect %s before transferring", getServiceDisplayName(serviceType))
+		}
+	}
+	return http.StatusOK, nil
+}
+
+// libraryTransferRunning reports whether the user already has a library transfer in progress
+func libraryTransferRunning(db *gorm.DB, userID uint) bool {
+	var running int64
+	db.Model(&database.LibraryTransfer{}).Where("user_id = ? AND status = ?", userID, database.LibraryTransferRunning).Count(&running)
+	return running > 0
+}
+
+// createLibraryTransfer records a library transfer with its steps and queues it
+func (h *Handlers) createLibraryTransfer(db *gorm.DB, userID uint, sourceService, targetService string, steps []database.LibraryTransferStep) (database.LibraryTransfer, error) {
+	library := database.LibraryTransfer{
+		UserID:        userID,
+		SourceService: sourceService,
+		TargetService: targetService,
+		Status:        database.LibraryTransferRunning,
+	}
+	err := db.Transaction(func(tx *gorm.DB) error {
+		if err := tx.Create(&library).Error; err != nil {
+			return err
+		}
+		for i := range steps {
+			steps[i].LibraryTransferID = library.ID
+			steps[i].Sequence = i
+			steps[i].Status = "pending"
+		}
+		return tx.Create(&steps).Error
+	})
+	if err != nil {
+		return library, err
+	}
+
+	log.Printf("Library transfer %d planned with %d steps", library.ID, len(steps))
+	h.enqueueLibraryTransfer(library)
+	return library, nil
+}
+
+// planLibraryTransfer lists the steps of a library transfer and the names of
+// the playlists left out because they are on the exclusion list
+func planLibraryTransfer(db *gorm.DB, userID uint, req LibraryTransferRequest) ([]database.LibraryTransferStep, []string, error) {
+	var steps []database.LibraryTransferStep
+	var excluded []string
+	addStep := func(kind, playlistID, name string) {
+		steps = append(steps, database.LibraryTransferStep{Kind: kind, SourcePlaylistID: playlistID, Name: name})
+	}
+
+	if req.Playlists == nil || *req.Playlists {
+		playlists, skipped, err := libraryPlaylists(db, userID, req.SourceService)
+		if err != nil {
+			return nil, nil, err
+		}
+		for _, playlist := range playlists {
+			addStep("playlist", playlist.ServiceID, playlist.Name)
+		}
+		for _, playlist := range skipped {
+			excluded = append(excluded, playlist.Name)
+		}
+	}
+
+	if req.LikedSongs == nil || *req.LikedSongs {
+		playlistID, name := likedSongsSource(req.SourceService)
+		addStep("liked_songs", playlistID, name)
+	}
+
+	// Following artists is last so it doesn't spend quota the playlists need
+	if req.Artists == nil || *req.Artists {
+		addStep("artists", "", "Followed artists")
+	}
+
+	return steps, excluded, nil
+}
+
+// libraryPlaylists returns the stored playlists of a service that belong in a
+// library transfer and, separately, those on the user's exclusion list. Built-in
+// collections and playlists that are themselves transfer targets are left out.
+func libraryPlaylists(db *gorm.DB, userID uint, serviceType string) ([]database.Playlist, []database.Playlist, error) {
+	var playlists []database.Playlist
+	if err := db.Where("user_id = ? AND service_type = ?", userID, serviceType).Order("name").Find(&playlists).Error; err != nil {
+		return nil, nil, err
+	}
+
+	exclusions := loadPlaylistExclusions(db, userID)
+	var included, excluded []database.Playlist
+	for _, playlist := range playlists {
+		if isPersonalCollection(playlist.ServiceType, playlist.ServiceID) || isTransferTarget(db, userID, playlist.ServiceType, playlist.ServiceID) {
+			continue
+		}
+		if exclusions.excludes(playlist.ServiceType, playlist.ServiceID, playlist.Name) {
+			excluded = append(excluded, playlist)
+			continue
+		}
+		included = append(included, playlist)
+	}
+	return included, excluded, nil
+}
+
+// likedSongsSource returns the playlist ID and name a service's liked songs are transferred from
+func likedSongsSource(serviceType string) (string, string) {
+	if serviceType == "spotify" {
+		return spotifyLikedSongsID, "Liked Songs"
+	}
+	return youtubeLikedVideosID, youtubeSpecialPlaylists[youtubeLikedVideosID]
+}
+
+// GetLibraryTransfers lists the user's library transfers, newest first
+func (h *Handlers) GetLibraryTransfers(c *gin.Context) {
+	user, exists := middleware.GetUserFromContext(c)
+	if !exists {
+		i18n.RespondError(c, http.StatusUnauthorized, i18n.CodeUnauthenticated, "")
+		return
+	}
+
+	db := h.DB.WithContext(c.Request.Context())
+	var libraries []database.LibraryTransfer
+	if err := db.Where("user_id = ?", user.ID).Order("id DESC").Limit(20).Find(&libraries).Error; err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to fetch library transfers"})
+		return
+	}
+
+	progress := make([]LibraryTransferProgress, 0, len(libraries))
+	for _, library := range libraries {
+		progress = append(progress, h.libraryTransferProgress(db, library))
+	}
+	c.JSON(http.StatusOK, gin.H{"library_transfers": progress})
+}
+
+// GetLibraryTransfer reports the progress of one library transfer
+func (h *Handlers) GetLibraryTransfer(c *gin.Context) {
+	user, exists := middleware.GetUserFromContext(c)
+	if !exists {
+		i18n.RespondError(c, http.StatusUnauthorized, i18n.CodeUnauthenticated, "")
+		return
+	}
+
+	id, err := strconv.ParseUint(c.Param("id"), 10, 32)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid library transfer ID"})
+		return
+	}
+	db := h.DB.WithContext(c.Request.Context())
+	var library database.LibraryTransfer
+	if err := db.Where("id = ? AND user_id = ?", uint(id), user.ID).First(&library).Error; err != nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": "Library transfer not found"})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"library_transfer": h.libraryTransferProgress(db, library)})
+}
+
+// libraryTransferProgress collects the steps of a library transfer with the
+// track counts of their transfers. Each step weighs the same in Percent.
+func (h *Handlers) libraryTransferProgress(db *gorm.DB, library database.LibraryTransfer) LibraryTransferProgress {
+	progress := LibraryTransferProgress{LibraryTransfer: library, Steps: []LibraryTransferStepProgress{}}
+
+	var steps []database.LibraryTransferStep
+	db.Where("library_transfer_id = ?", library.ID).Order("sequence").Find(&steps)
+
+	var transferIDs []uint
+	for _, step := range steps {
+		if step.TransferID != nil {
+			transferIDs = append(transferIDs, *step.TransferID)
+		}
+	}
+	transfers := make(map[uint]database.Transfer)
+	if len(transferIDs) > 0 {
+		var found []database.Transfer
+		db.Where("id IN ?", transferIDs).Find(&found)
+		for _, transfer := range found {
+			transfers[transfer.ID] = transfer
+		}
+	}
+
+	done := 0.0
+	for _, step := range steps {
+		stepProgress := LibraryTransferStepProgress{LibraryTransferStep: step}
+		fraction := 0.0
+		switch step.Status {
+		case "completed", "failed":
+			fraction = 1
+		case "running":
+			if step.ArtistsTotal > 0 {
+				fraction = float64(step.ArtistsFollowed+step.ArtistsNotFound) / float64(step.ArtistsTotal)
+			}
+		}
+		if step.TransferID != nil {
+			if transfer, ok := transfers[*step.TransferID]; ok {
+				stepProgress.TransferStatus = transfer.Status
+				stepProgress.TracksTotal = transfer.TracksTotal
+				stepProgress.TracksDone = transfer.TracksMatched + transfer.TracksFailed + transfer.TracksSkipped
+				progress.TracksTotal += transfer.TracksTotal
+				progress.TracksMatched += transfer.TracksMatched
+				progress.TracksFailed += transfer.TracksFailed
+				if step.Status == "running" && transfer.TracksTotal > 0 {
+					fraction = float64(stepProgress.TracksDone) / float64(transfer.TracksTotal)
+				}
+			}
+		}
+		done += min(fraction, 1)
+
+		switch step.Status {
+		case "completed":
+			progress.StepsCompleted++
+		case "failed":
+			progress.StepsFailed++
+		}
+		progress.Steps = append(progress.Steps, stepProgress)
+	}
+	if len(steps) > 0 {
+		progress.Percent = int(done * 100 / float64(len(steps)))
+	}
+	return progress
+}
+
+func (h *Handlers) enqueueLibraryTransfer(library database.LibraryTransfer) {
+	h.Jobs.Enqueue(&jobs.Job{
+		ID:       fmt.Sprintf("library-transfer-%d", library.ID),
+		Type:     "library_transfer",
+		UserID:   library.UserID,
+		Priority: jobs.PriorityLow,
+		Timeout:  transferJobTimeout,
+		Run: func(ctx context.Context) error {
+			h.advanceLibraryTransfer(ratelimit.WithUser(ctx, library.UserID), library.ID)
+			return nil
+		},
+	})
+}
+
+// advanceLibraryTransfer moves a library transfer's plan forward: steps whose
+// transfer finished are settled and pending steps are started in sequence
+// until the user's concurrent transfer limit is reached. It is safe to run
+// concurrently for the same library, steps are claimed before they start.
+func (h *Handlers) advanceLibraryTransfer(ctx context.Context, libraryID uint) {
+	// Step writes must land even once the job is cancelled or times out
+	db := h.DB.WithContext(context.WithoutCancel(ctx))
+
+	var library database.LibraryTransfer
+	if err := db.First(&library, libraryID).Error; err != nil || library.Status != database.LibraryTransferRunning {
+		return
+	}
+	var steps []database.LibraryTransferStep
+	if err := db.Where("library_transfer_id = ?", library.ID).Order("sequence").Find(&steps).Error; err != nil {
+		log.Printf("Failed to load steps of library transfer %d: %v", library.ID, err)
+		return
+	}
+
+	blocked := false
+	for i := range steps {
+		step := &steps[i]
+		switch step.Status {
+		case "running":
+			settleLibraryTransferStep(db, step)
+		case "pending":
+			if blocked || ctx.Err() != nil {
+				continue
+			}
+			claimed := db.Model(step).Where("status = ?", "pending").Update("status", "running")
+			if claimed.Error != nil || claimed.RowsAffected == 0 {
+				continue
+			}
+			if !h.startLibraryTransferStep(ctx, db, library, step) {
+				// Wait for a running transfer to finish before starting more
+				db.Model(step).Update("status", "pending")
+				step.Status = "pending"
+				blocked = true
+			}
+		}
+	}
+
+	failed := false
+	for _, step := range steps {
+		switch step.Status {
+		case "pending", "running":
+			return
+		case "failed":
+			failed = true
+		}
+	}
+	status := database.LibraryTransferCompleted
+	if failed {
+		status = database.LibraryTransferCompletedWithErrors
+	}
+	db.Model(&library).Where("status = ?", database.LibraryTransferRunning).
+		Updates(map[string]interface{}{"status": status, "completed_at": time.Now().Unix()})
+	log.Printf("Library transfer %d %s", library.ID, status)
+}
+
+// startLibraryTransferStep starts a claimed step. It returns false when the
+// step has to wait for a free transfer slot.
+func (h *Handlers) startLibraryTransferStep(ctx context.Context, db *gorm.DB, library database.LibraryTransfer, step *database.LibraryTransferStep) bool {
+	if step.Kind == "artists" {
+		h.transferFollowedArtists(ctx, db, library, step)
+		return true
+	}
+
+	req := TransferRequest{
+		SourceService:    library.SourceService,
+		SourcePlaylistID: step.SourcePlaylistID,
+		TargetService:    library.TargetService,
+		Priority:         "low", // bulk work yields to interactive transfers
+		SplitAcrossDays:  true,  // a library rarely fits into one day of YouTube quota
+	}
+	if step.Kind == "liked_songs" {
+		req.TargetPlaylistName = fmt.Sprintf("%s from %s", step.Name, getServiceDisplayName(library.SourceService))
+	}
+	transfer, status, err := h.startTransferForUser(ctx, library.UserID, req)
+	if status == http.StatusTooManyRequests {
+		return false
+	}
+	if err != nil {
+		log.Printf("Library transfer %d could not start %q: %v", library.ID, step.Name, err)
+		step.Status = "failed"
+		step.Error = err.Error()
+		db.Model(step).Updates(map[string]interface{}{"status": step.Status, "error": step.Error})
+		return true
+	}
+
+	step.TransferID = &transfer.ID
+	db.Model(step).Update("transfer_id", transfer.ID)
+	return true
+}
+
+// settleLibraryTransferStep completes a running step once its transfer has finished
+func settleLibraryTransferStep(db *gorm.DB, step *database.LibraryTransferStep) {
+	if step.TransferID == nil {
+		return
+	}
+	var transfer database.Transfer
+	if err := db.First(&transfer, *step.TransferID).Error; err != nil || transfer.Status.Phase() != "finished" {
+		return
+	}
+
+	step.Status = "completed"
+	if transfer.Status != database.TransferCompleted && transfer.Status != database.TransferCompletedWithErrors {
+		step.Status = "failed"
+		step.Error = transfer.ErrorMessage
+		if step.Error == "" {
+			step.Error = "Transfer " + string(transfer.Status)
+		}
+	}
+	db.Model(step).Updates(map[string]interface{}{"status": step.Status, "error": step.Error})
+}
+
+// continueLibraryTransfer moves the library transfer a finished transfer
+// belongs to, if any, on to its next steps
+func (h *Handlers) continueLibraryTransfer(db *gorm.DB, transferID uint) {
+	var step database.LibraryTransferStep
+	if err := db.Where("transfer_id = ?", transferID).First(&step).Error; err != nil {
+		return
+	}
+	var library database.LibraryTransfer
+	if err := db.First(&library, step.LibraryTransferID).Error; err != nil || library.Status != database.LibraryTransferRunning {
+		return
+	}
+	h.enqueueLibraryTransfer(library)
+}
+
+// scheduleRunningLibraryTransfers queues every running library transfer, picking
+// up plans that waited for a transfer slot or were interrupted by a restart
+func (h *Handlers) scheduleRunningLibraryTransfers(ctx context.Context) {
+	var libraries []database.LibraryTransfer
+	if err := h.DB.WithContext(ctx).Where("status = ?", database.LibraryTransferRunning).Find(&libraries).Error; err != nil {
+		log.Printf("Failed to load library transfers: %v", err)
+		return
+	}
+	for _, library := range libraries {
+		h.enqueueLibraryTransfer(library)
+	}
+}
+
+// transferFollowedArtists follows on the target service the artists the user
+// follows on the source. YouTube artists are their channel subscriptions.
+func (h *Handlers) transferFollowedArtists(ctx context.Context, db *gorm.DB, library database.LibraryTransfer, step *database.LibraryTransferStep) {
+	fail := func(err error) {
+		log.Printf("Library transfer %d could not transfer followed artists: %v", library.ID, err)
+		step.Status = "failed"
+		step.Error = err.Error()
+		db.Model(step).Updates(map[string]interface{}{"status": step.Status, "error": step.Error})
+	}
+
+	var source, target database.UserService
+	if err := db.Where("user_id = ? AND service_type = ?", library.UserID, library.SourceService).First(&source).Error; err != nil {
+		fail(fmt.Errorf("%s is not connected", getServiceDisplayName(library.SourceService)))
+		return
+	}
+	if err := db.Where("user_id = ? AND service_type = ?", library.UserID, library.TargetService).First(&target).Error; err != nil {
+		fail(fmt.Errorf("%s is not connected", getServiceDisplayName(library.TargetService)))
+		return
+	}
+	for _, service := range []*database.UserService{&source, &target} {
+		if err := h.Tokens.RefreshTokenIfNeeded(service); err != nil {
+			fail(fmt.Errorf("%s token refresh failed: %w", getServiceDisplayName(service.ServiceType), err))
+			return
+		}
+	}
+
+	artists, err := h.followedArtistNames(ctx, source)
+	if err != nil {
+		fail(err)
+		return
+	}
+	following, err := h.followedArtistNames(ctx, target)
+	if err != nil {
+		fail(err)
+		return
+	}
+	alreadyFollowed := make(map[string]bool, len(following))
+	for _, name := range following {
+		alreadyFollowed[normalizeArtistName(name)] = true
+	}
+
+	step.ArtistsTotal = len(artists)
+	db.Model(step).Update("artists_total", step.ArtistsTotal)
+
+	for _, name := range artists {
+		if alreadyFollowed[normalizeArtistName(name)] {
+			step.ArtistsFollowed++
+		} else {
+			followed, err := h.followArtist(ctx, target, name)
+			if err != nil {
+				db.Model(step).Updates(map[string]interface{}{"artists_followed": step.ArtistsFollowed, "artists_not_found": step.ArtistsNotFound})
+				fail(fmt.Errorf("Failed to follow %s: %w", name, err))
+				return
+			}
+			if followed {
+				step.ArtistsFollowed++
+			} else {
+				step.ArtistsNotFound++
+			}
+		}
+		db.Model(step).Updates(map[string]interface{}{"artists_followed": step.ArtistsFollowed, "artists_not_found": step.ArtistsNotFound})
+	}
+
+	step.Status = "completed"
+	db.Model(step).Update("status", step.Status)
+}
+
+// followedArtistNames lists the artists an account follows. YouTube
+// subscriptions to auto-generated "<artist> - Topic" channels count as the artist.
+func (h *Handlers) followedArtistNames(ctx context.Context, service database.UserService) ([]string, error) {
+	var names []string
+	switch service.ServiceType {
+	case "spotify":
+		artists, err := h.Providers.Spotify.FollowedArtists(ctx, service.AccessToken, 50)
+		if err != nil {
+			return nil, err
+		}
+		for _, artist := range artists {
+			names = append(names, artist.Name)
+		}
+	case "youtube":
+		subscriptions, err := h.Providers.YouTube.MySubscriptions(ctx, service.AccessToken, 50)
+		if err != nil {
+			return nil, err
+		}
+		for _, subscription := range subscriptions {
+			names = append(names, strings.TrimSuffix(subscription.Snippet.Title, " - Topic"))
+		}
+	}
+	return names, nil
+}
+
+// followArtist looks an artist up by name on the target and follows the exact
+// match. It reports false when no artist of that name was found.
+func (h *Handlers) followArtist(ctx context.Context, service database.UserService, name string) (bool, error) {
+	want := normalizeArtistName(name)
+	switch service.ServiceType {
+	case "spotify":
+		artists, err := h.Providers.Spotify.SearchArtists(ctx, service.AccessToken, name, 5)
+		if err != nil {
+			return false, err
+		}
+		for _, artist := range artists {
+			if normalizeArtistName(artist.Name) == want {
+				return true, h.Providers.Spotify.FollowArtists(ctx, service.AccessToken, []string{artist.ID})
+			}
+		}
+	case "youtube":
+		// A channel search and a subscription cost 150 units; leave the rest for transfers
+		if daily := quota.DailyLimit("youtube"); daily > 0 && quota.Remaining("youtube") < quota.YouTubeSearchCost+quota.YouTubeWriteCost {
+			return false, fmt.Errorf("YouTube quota exhausted for today")
+		}
+		channels, err := h.Providers.YouTube.SearchChannels(ctx, service.AccessToken, name, 5)
+		if err != nil {
+			return false, err
+		}
+		for _, channel := range channels {
+			if normalizeArtistName(strings.TrimSuffix(channel.Snippet.Title, " - Topic")) == want {
+				return true, h.Providers.YouTube.InsertSubscription(ctx, service.AccessToken, channel.ID)
+			}
+		}
+	}
+	return false, nil
+}
+
+// normalizeArtistName folds case and whitespace so artist names can be compared across services
+func normalizeArtistName(name string) string {
+	return strings.Join(strings.Fields(strings.ToLower(name)), " ")
+}
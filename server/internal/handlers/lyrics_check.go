@@ -0,0 +1,59 @@
+package handlers
+
+import (
+	"context"
+	"errors"
+	"log"
+	"strings"
+
+	"server/internal/lyrics"
+	"server/internal/match"
+)
+
+// Lyric similarity above lyricsSameSong confirms a match and below
+// lyricsDifferentSong rejects it; anything between is inconclusive
+const (
+	lyricsSameSong      = 0.3
+	lyricsDifferentSong = 0.1
+
+	// Confidence given to a match whose lyrics were confirmed
+	lyricsConfirmedConfidence = 0.9
+)
+
+var errLyricsMismatch = errors.New("candidate lyrics belong to a different song")
+
+// isGenericTitle reports whether a title is short enough ("Home", "Stay") that
+// many unrelated songs share it, so a title match alone proves little
+func isGenericTitle(name string) bool {
+	cleaned := match.StripVersionSuffix(strings.ToLower(name))
+	return cleaned != "" && len(strings.Fields(cleaned)) <= 2 && len([]rune(cleaned)) <= 12
+}
+
+// checkLyrics compares the lyrics of a source track and its candidate match when the
+// title is generic. It returns the adjusted confidence, or errLyricsMismatch when the
+// lyrics show the candidate is a different song.
+func checkLyrics(ctx context.Context, source, candidate Track, confidence float64) (float64, error) {
+	if !lyrics.Enabled() || !isGenericTitle(source.Name) {
+		return confidence, nil
+	}
+
+	sourceLyrics, err := lyrics.Fetch(ctx, source.Artist, source.Name, source.Duration/1000)
+	if err != nil || sourceLyrics == "" {
+		return confidence, nil
+	}
+	candidateLyrics, err := lyrics.Fetch(ctx, candidate.Artist, candidate.Name, 0)
+	if err != nil || candidateLyrics == "" {
+		return confidence, nil
+	}
+
+	similarity := lyrics.Similarity(sourceLyrics, candidateLyrics)
+	switch {
+	case similarity >= lyricsSameSong:
+		return max(confidence, lyricsConfirmedConfidence), nil
+	case similarity < lyricsDifferentSong:
+		log.Printf("Lyrics of %s - %s do not match candidate %s - %s (similarity %.2f)",
+			source.Artist, source.Name, candidate.Artist, candidate.Name, similarity)
+		return confidence, errLyricsMismatch
+	}
+	return confidence, nil
+}
@@ -0,0 +1,165 @@
+package handlers
+
+import (
+	"context"
+	"fmt"
+	"log"
+	"net/http"
+	"slices"
+	"time"
+
+	"server/internal/database"
+	"server/internal/i18n"
+	"server/internal/maintenance"
+
+	"github.com/gin-gonic/gin"
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+// maintenanceScopes are the services that can be put into maintenance mode on their own, and "all"
+var maintenanceScopes = []string{maintenance.ScopeAll, "spotify", "youtube"}
+
+// maintenanceMessage is the notice shown to users affected by a maintenance window
+func maintenanceMessage(window database.Maintenance) string {
+	if window.Message != "" {
+		return window.Message
+	}
+	message := "Transfers are paused for maintenance"
+	if window.Scope != maintenance.ScopeAll {
+		message = getServiceDisplayName(window.Scope) + " transfers are paused for maintenance"
+	}
+	if window.EndsAt > 0 {
+		message += " until " + time.Unix(window.EndsAt, 0).UTC().Format(time.RFC1123)
+	}
+	return message + "; please try again later"
+}
+
+// holdForMaintenance parks a transfer until its providers leave maintenance
+// mode, keeping the progress made so far
+func holdForMaintenance(db *gorm.DB, transfer *database.Transfer, matchedTracks, failedTracks int, window database.Maintenance) {
+	log.Printf("Transfer %d held for %s maintenance", transfer.ID, window.Scope)
+	updateTransfer(db, transfer, map[string]interface{}{
+		"status":         database.TransferMaintenance,
+		"tracks_matched": matchedTracks,
+		"tracks_failed":  failedTracks,
+		"error_message":  maintenanceMessage(window),
+	})
+}
+
+// resumeMaintenanceTransfers queues every held transfer whose providers are out of maintenance
+func (h *Handlers) resumeMaintenanceTransfers(ctx context.Context) {
+	var transfers []database.Transfer
+	if err := h.DB.WithContext(ctx).Where("status = ?", database.TransferMaintenance).Order("id").Find(&transfers).Error; err != nil {
+		log.Printf("Failed to load transfers held for maintenance: %v", err)
+		return
+	}
+
+	for _, transfer := range transfers {
+		if _, ok := maintenance.For(transfer.SourceService, transfer.TargetService); ok {
+			continue
+		}
+		// Smart playlists keep their tracks only in the job that was stopped
+		if transfer.SourceService == "smart" {
+			updateTransfer(h.DB.WithContext(ctx), &transfer, map[string]interface{}{
+				"status":        database.TransferFailed,
+				"error_message": "Stopped for maintenance; create the smart playlist again",
+			})
+			continue
+		}
+		if err := h.resumeTransfer(ctx, transfer); err != nil {
+			log.Printf("Failed to resume transfer %d after maintenance: %v", transfer.ID, err)
+			continue
+		}
+		log.Printf("Resumed transfer %d after maintenance", transfer.ID)
+	}
+}
+
+// GetMaintenance lists the maintenance windows in effect, so clients can show a notice
+func (h *Handlers) GetMaintenance(c *gin.Context) {
+	windows := make([]gin.H, 0)
+	for _, window := range maintenance.All() {
+		windows = append(windows, gin.H{
+			"scope":   window.Scope,
+			"mode":    window.Mode,
+			"message": maintenanceMessage(window),
+			"ends_at": window.EndsAt,
+		})
+	}
+	c.JSON(http.StatusOK, gin.H{"maintenance": windows})
+}
+
+// AdminListMaintenance lists the maintenance windows in effect as stored
+func (h *Handlers) AdminListMaintenance(c *gin.Context) {
+	c.JSON(http.StatusOK, gin.H{"maintenance": maintenance.All()})
+}
+
+type MaintenanceRequest struct {
+	Mode    string `json:"mode"` // "queue" (default) or "reject"
+	Message string `json:"message"`
+	EndsAt  int64  `json:"ends_at"` // shown to users; maintenance only ends when deleted
+}
+
+// AdminPutMaintenance puts the service or one provider into maintenance mode.
+// Transfers using it are held or refused, and running ones pause after their
+// current track; other instances pick the change up on their next refresh.
+func (h *Handlers) AdminPutMaintenance(c *gin.Context) {
+	scope := c.Param("scope")
+	if !slices.Contains(maintenanceScopes, scope) {
+		c.JSON(http.StatusBadRequest, gin.H{"error": fmt.Sprintf("Unknown maintenance scope %q", scope)})
+		return
+	}
+
+	var req MaintenanceRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		i18n.RespondError(c, http.StatusBadRequest, i18n.CodeInvalidRequest, err.Error())
+		return
+	}
+	if req.Mode == "" {
+		req.Mode = maintenance.ModeQueue
+	}
+	if req.Mode != maintenance.ModeQueue && req.Mode != maintenance.ModeReject {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "mode must be queue or reject"})
+		return
+	}
+
+	window := database.Maintenance{
+		Scope:     scope,
+		Mode:      req.Mode,
+		Message:   req.Message,
+		StartedAt: time.Now().Unix(),
+		EndsAt:    req.EndsAt,
+	}
+	// Changing the mode or message of an ongoing window keeps its start time
+	err := h.DB.WithContext(c.Request.Context()).Clauses(clause.OnConflict{
+		Columns:   []clause.Column{{Name: "scope"}},
+		DoUpdates: clause.AssignmentColumns([]string{"mode", "message", "ends_at"}),
+	}).Create(&window).Error
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to start maintenance"})
+		return
+	}
+
+	maintenance.Invalidate()
+	log.Printf("Maintenance started for %s (%s)", scope, req.Mode)
+	c.JSON(http.StatusOK, gin.H{"maintenance": maintenance.All()[scope]})
+}
+
+// AdminDeleteMaintenance ends maintenance mode for the service or a provider and
+// resumes the transfers that were held for it
+func (h *Handlers) AdminDeleteMaintenance(c *gin.Context) {
+	result := h.DB.WithContext(c.Request.Context()).Where("scope = ?", c.Param("scope")).Delete(&database.Maintenance{})
+	if result.Error != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to end maintenance"})
+		return
+	}
+	if result.RowsAffected == 0 {
+		c.JSON(http.StatusNotFound, gin.H{"error": "No maintenance in effect for " + c.Param("scope")})
+		return
+	}
+
+	maintenance.Invalidate()
+	log.Printf("Maintenance ended for %s", c.Param("scope"))
+	go h.runScheduled(context.Background(), "maintenance-resume", func() { h.resumeMaintenanceTransfers(context.Background()) })
+	c.JSON(http.StatusOK, gin.H{"message": "Maintenance ended"})
+}
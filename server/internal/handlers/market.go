@@ -0,0 +1,29 @@
+package handlers
+
+import (
+	"errors"
+	"regexp"
+
+	"server/internal/database"
+
+	"gorm.io/gorm"
+)
+
+// errUnavailableInRegion is returned by searches that found the track but it cannot be played in the user's market
+var errUnavailableInRegion = errors.New("track unavailable in region")
+
+var marketPattern = regexp.MustCompile(`^[A-Z]{2}$`)
+
+// userMarket returns the market searches run in: the user's region setting, else their Spotify profile country
+func userMarket(db *gorm.DB, userID uint) string {
+	if settings := loadUserSettings(db, userID); settings.Region != "" {
+		return settings.Region
+	}
+
+	var service database.UserService
+	if err := db.Select("market").Where("user_id = ? AND service_type = ?", userID, "spotify").First(&service).Error; err == nil {
+		return service.Market
+	}
+
+	return ""
+}
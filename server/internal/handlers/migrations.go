@@ -0,0 +1,535 @@
+package handlers
+
+import (
+	"context"
+	"fmt"
+	"log"
+	"net/http"
+	"strconv"
+	"time"
+
+	"server/internal/database"
+	"server/internal/i18n"
+	"server/internal/jobs"
+	"server/internal/middleware"
+	"server/internal/quota"
+	"server/internal/ratelimit"
+
+	"github.com/gin-gonic/gin"
+	"gorm.io/gorm"
+)
+
+type MigrationRequest struct {
+	SourceService string `json:"source_service" binding:"required"`
+	TargetService string `json:"target_service" binding:"required"`
+}
+
+type MigrationPlanRequest struct {
+	ItemIDs []uint `json:"item_ids"` // inventory items to include; everything else is left out
+}
+
+// MigrationView is a migration with its inventory, the estimate for the
+// selected items and, once executing, the library transfer's progress
+type MigrationView struct {
+	database.Migration
+	Items    []database.MigrationItem `json:"items"`
+	Estimate TransferEstimate         `json:"estimate"`
+	Library  *LibraryTransferProgress `json:"library_transfer,omitempty"`
+	NextStep string                   `json:"next_step"` // "plan", "execute", "wait", "verify" or "done"
+}
+
+// StartMigration begins a migration wizard by analyzing the source library
+func (h *Handlers) StartMigration(c *gin.Context) {
+	user, exists := middleware.GetUserFromContext(c)
+	if !exists {
+		i18n.RespondError(c, http.StatusUnauthorized, i18n.CodeUnauthenticated, "")
+		return
+	}
+
+	var req MigrationRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		i18n.RespondError(c, http.StatusBadRequest, i18n.CodeInvalidRequest, err.Error())
+		return
+	}
+	ctx := c.Request.Context()
+	db := h.DB.WithContext(ctx)
+	if status, err := checkLibraryServices(db, user.ID, req.SourceService, req.TargetService); err != nil {
+		c.JSON(status, gin.H{"error": err.Error()})
+		return
+	}
+
+	migration := database.Migration{
+		UserID:        user.ID,
+		SourceService: req.SourceService,
+		TargetService: req.TargetService,
+		State:         database.MigrationAnalyzed,
+	}
+	if err := db.Create(&migration).Error; err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to create migration"})
+		return
+	}
+	if status, err := h.analyzeMigration(ctx, &migration); err != nil {
+		db.Delete(&migration)
+		c.JSON(status, gin.H{"error": err.Error()})
+		return
+	}
+
+	c.JSON(http.StatusCreated, gin.H{"migration": h.migrationView(db, migration)})
+}
+
+// GetMigrations lists the user's migrations, newest first
+func (h *Handlers) GetMigrations(c *gin.Context) {
+	user, exists := middleware.GetUserFromContext(c)
+	if !exists {
+		i18n.RespondError(c, http.StatusUnauthorized, i18n.CodeUnauthenticated, "")
+		return
+	}
+
+	var migrations []database.Migration
+	if err := h.DB.WithContext(c.Request.Context()).Where("user_id = ?", user.ID).Order("id DESC").Find(&migrations).Error; err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to fetch migrations"})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"migrations": migrations})
+}
+
+// GetMigration returns a migration in its current state, so the wizard can be resumed
+func (h *Handlers) GetMigration(c *gin.Context) {
+	user, exists := middleware.GetUserFromContext(c)
+	if !exists {
+		i18n.RespondError(c, http.StatusUnauthorized, i18n.CodeUnauthenticated, "")
+		return
+	}
+
+	migration, ok := h.userMigrationFromParam(c, user.ID)
+	if !ok {
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"migration": h.migrationView(h.DB.WithContext(c.Request.Context()), migration)})
+}
+
+// AnalyzeMigration takes a fresh inventory of the source library. The plan
+// made so far is discarded.
+func (h *Handlers) AnalyzeMigration(c *gin.Context) {
+	user, exists := middleware.GetUserFromContext(c)
+	if !exists {
+		i18n.RespondError(c, http.StatusUnauthorized, i18n.CodeUnauthenticated, "")
+		return
+	}
+
+	migration, ok := h.userMigrationFromParam(c, user.ID)
+	if !ok {
+		return
+	}
+	if migration.State != database.MigrationAnalyzed && migration.State != database.MigrationPlanned {
+		c.JSON(http.StatusConflict, gin.H{"error": "The migration has already been executed"})
+		return
+	}
+
+	if status, err := h.analyzeMigration(c.Request.Context(), &migration); err != nil {
+		c.JSON(status, gin.H{"error": err.Error()})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"migration": h.migrationView(h.DB.WithContext(c.Request.Context()), migration)})
+}
+
+// PlanMigration records which inventory items the migration includes
+func (h *Handlers) PlanMigration(c *gin.Context) {
+	user, exists := middleware.GetUserFromContext(c)
+	if !exists {
+		i18n.RespondError(c, http.StatusUnauthorized, i18n.CodeUnauthenticated, "")
+		return
+	}
+
+	migration, ok := h.userMigrationFromParam(c, user.ID)
+	if !ok {
+		return
+	}
+	if migration.State != database.MigrationAnalyzed && migration.State != database.MigrationPlanned {
+		c.JSON(http.StatusConflict, gin.H{"error": "The migration has already been executed"})
+		return
+	}
+
+	var req MigrationPlanRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		i18n.RespondError(c, http.StatusBadRequest, i18n.CodeInvalidRequest, err.Error())
+		return
+	}
+	if len(req.ItemIDs) == 0 {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Select at least one item"})
+		return
+	}
+
+	db := h.DB.WithContext(c.Request.Context())
+	var known int64
+	db.Model(&database.MigrationItem{}).Where("migration_id = ? AND id IN ?", migration.ID, req.ItemIDs).Count(&known)
+	if int(known) != len(req.ItemIDs) {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Unknown item in item_ids"})
+		return
+	}
+
+	err := db.Transaction(func(tx *gorm.DB) error {
+		if err := tx.Model(&database.MigrationItem{}).Where("migration_id = ?", migration.ID).Update("selected", false).Error; err != nil {
+			return err
+		}
+		if err := tx.Model(&database.MigrationItem{}).Where("migration_id = ? AND id IN ?", migration.ID, req.ItemIDs).Update("selected", true).Error; err != nil {
+			return err
+		}
+		return tx.Model(&migration).Updates(map[string]interface{}{"state": database.MigrationPlanned, "planned_at": time.Now().Unix()}).Error
+	})
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to save plan"})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"migration": h.migrationView(db, migration)})
+}
+
+// ExecuteMigration starts a library transfer of the planned items
+func (h *Handlers) ExecuteMigration(c *gin.Context) {
+	user, exists := middleware.GetUserFromContext(c)
+	if !exists {
+		i18n.RespondError(c, http.StatusUnauthorized, i18n.CodeUnauthenticated, "")
+		return
+	}
+
+	migration, ok := h.userMigrationFromParam(c, user.ID)
+	if !ok {
+		return
+	}
+	if migration.State != database.MigrationPlanned {
+		c.JSON(http.StatusConflict, gin.H{"error": "Plan the migration before executing it"})
+		return
+	}
+
+	db := h.DB.WithContext(c.Request.Context())
+	if status, err := checkLibraryServices(db, user.ID, migration.SourceService, migration.TargetService); err != nil {
+		c.JSON(status, gin.H{"error": err.Error()})
+		return
+	}
+	if libraryTransferRunning(db, user.ID) {
+		c.JSON(http.StatusConflict, gin.H{"error": "A library transfer is already in progress"})
+		return
+	}
+
+	// Claim the migration so two concurrent requests can't both start it
+	claim := db.Model(&database.Migration{}).Where("id = ? AND state = ?", migration.ID, database.MigrationPlanned).
+		Update("state", database.MigrationExecuting)
+	if claim.Error != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to start migration"})
+		return
+	}
+	if claim.RowsAffected != 1 {
+		c.JSON(http.StatusConflict, gin.H{"error": "Migration is already executing"})
+		return
+	}
+	release := func() {
+		db.Model(&database.Migration{}).Where("id = ?", migration.ID).Update("state", database.MigrationPlanned)
+	}
+
+	var items []database.MigrationItem
+	if err := db.Where("migration_id = ? AND selected = ?", migration.ID, true).Order("id").Find(&items).Error; err != nil {
+		release()
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to load migration items"})
+		return
+	}
+	steps := make([]database.LibraryTransferStep, 0, len(items))
+	for _, item := range items {
+		steps = append(steps, database.LibraryTransferStep{Kind: item.Kind, SourcePlaylistID: item.SourcePlaylistID, Name: item.Name})
+	}
+
+	library, err := h.createLibraryTransfer(db, user.ID, migration.SourceService, migration.TargetService, steps)
+	if err != nil {
+		release()
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to create library transfer"})
+		return
+	}
+	migration.State = database.MigrationExecuting
+	migration.LibraryTransferID = &library.ID
+	migration.ExecutedAt = time.Now().Unix()
+	if err := db.Save(&migration).Error; err != nil {
+		// The library transfer is already queued, so the migration stays claimed
+		log.Printf("Failed to link migration %d to library transfer %d: %v", migration.ID, library.ID, err)
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to save migration"})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"migration": h.migrationView(db, migration)})
+}
+
+// VerifyMigration re-reads the target playlists of a finished migration in the
+// background and counts the transferred tracks missing from them
+func (h *Handlers) VerifyMigration(c *gin.Context) {
+	user, exists := middleware.GetUserFromContext(c)
+	if !exists {
+		i18n.RespondError(c, http.StatusUnauthorized, i18n.CodeUnauthenticated, "")
+		return
+	}
+
+	migration, ok := h.userMigrationFromParam(c, user.ID)
+	if !ok {
+		return
+	}
+	db := h.DB.WithContext(c.Request.Context())
+	// A verification cut short by a restart can be started again
+	if migration.State != database.MigrationExecuting && migration.State != database.MigrationVerifying && migration.State != database.MigrationVerified {
+		c.JSON(http.StatusConflict, gin.H{"error": "Only executed migrations can be verified"})
+		return
+	}
+	if library := migrationLibrary(db, migration); library == nil || library.Status == database.LibraryTransferRunning {
+		c.JSON(http.StatusConflict, gin.H{"error": "Wait for the migration to finish before verifying it"})
+		return
+	}
+
+	db.Model(&migration).Update("state", database.MigrationVerifying)
+	h.Jobs.Enqueue(&jobs.Job{
+		ID:       fmt.Sprintf("verify-migration-%d", migration.ID),
+		Type:     "verify_migration",
+		UserID:   user.ID,
+		Priority: jobs.PriorityLow,
+		Run: func(ctx context.Context) error {
+			h.verifyMigration(ratelimit.WithUser(ctx, migration.UserID), migration)
+			return nil
+		},
+	})
+
+	c.JSON(http.StatusAccepted, gin.H{"migration": h.migrationView(db, migration)})
+}
+
+// DeleteMigration abandons a migration that has not been executed yet
+func (h *Handlers) DeleteMigration(c *gin.Context) {
+	user, exists := middleware.GetUserFromContext(c)
+	if !exists {
+		i18n.RespondError(c, http.StatusUnauthorized, i18n.CodeUnauthenticated, "")
+		return
+	}
+
+	migration, ok := h.userMigrationFromParam(c, user.ID)
+	if !ok {
+		return
+	}
+	if migration.State != database.MigrationAnalyzed && migration.State != database.MigrationPlanned {
+		c.JSON(http.StatusConflict, gin.H{"error": "The migration has already been executed"})
+		return
+	}
+
+	err := h.DB.WithContext(c.Request.Context()).Transaction(func(tx *gorm.DB) error {
+		if err := tx.Where("migration_id = ?", migration.ID).Delete(&database.MigrationItem{}).Error; err != nil {
+			return err
+		}
+		return tx.Delete(&migration).Error
+	})
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to delete migration"})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"message": "Migration deleted"})
+}
+
+// userMigrationFromParam loads the migration named by the :id parameter if it belongs to the user,
+// writing the error response otherwise
+func (h *Handlers) userMigrationFromParam(c *gin.Context, userID uint) (database.Migration, bool) {
+	var migration database.Migration
+	id, err := strconv.ParseUint(c.Param("id"), 10, 32)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid migration ID"})
+		return migration, false
+	}
+	if err := h.DB.WithContext(c.Request.Context()).Where("id = ? AND user_id = ?", uint(id), userID).First(&migration).Error; err != nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": "Migration not found"})
+		return migration, false
+	}
+	return migration, true
+}
+
+// analyzeMigration replaces the migration's inventory with the source library
+// as it is now: every playlist, the liked songs and the followed artists.
+// Everything not on the exclusion list starts out selected. On failure it
+// returns the HTTP status to report.
+func (h *Handlers) analyzeMigration(ctx context.Context, migration *database.Migration) (int, error) {
+	db := h.DB.WithContext(ctx)
+	var source database.UserService
+	if err := db.Where("user_id = ? AND service_type = ?", migration.UserID, migration.SourceService).First(&source).Error; err != nil {
+		return http.StatusBadRequest, fmt.Errorf("Source service not connected")
+	}
+	if err := h.Tokens.RefreshTokenIfNeeded(&source); err != nil {
+		return http.StatusBadGateway, fmt.Errorf("Source service token refresh failed: %v", err)
+	}
+	ctx = ratelimit.WithUser(ctx, migration.UserID)
+
+	// A failed refresh leaves the playlists from the last sync
+	h.syncServicePlaylists(ctx, migration.UserID, source)
+
+	playlists, excluded, err := libraryPlaylists(db, migration.UserID, migration.SourceService)
+	if err != nil {
+		return http.StatusInternalServerError, fmt.Errorf("Failed to fetch playlists")
+	}
+	var items []database.MigrationItem
+	for _, playlist := range playlists {
+		items = append(items, database.MigrationItem{Kind: "playlist", SourcePlaylistID: playlist.ServiceID, Name: playlist.Name, Count: playlist.TrackCount, Selected: true})
+	}
+	for _, playlist := range excluded {
+		items = append(items, database.MigrationItem{Kind: "playlist", SourcePlaylistID: playlist.ServiceID, Name: playlist.Name, Count: playlist.TrackCount, Excluded: true})
+	}
+
+	likedID, likedName := likedSongsSource(migration.SourceService)
+	liked, _, err := h.fetchPlaylistTracks(ctx, migration.SourceService, source.AccessToken, likedID)
+	if err != nil {
+		log.Printf("Failed to count liked songs for migration %d: %v", migration.ID, err)
+	}
+	items = append(items, database.MigrationItem{Kind: "liked_songs", SourcePlaylistID: likedID, Name: likedName, Count: len(supportedTracks(liked)), Selected: true})
+
+	artists, err := h.followedArtistNames(ctx, source)
+	if err != nil {
+		log.Printf("Failed to count followed artists for migration %d: %v", migration.ID, err)
+	}
+	items = append(items, database.MigrationItem{Kind: "artists", Name: "Followed artists", Count: len(artists), Selected: true})
+
+	err = db.Transaction(func(tx *gorm.DB) error {
+		if err := tx.Where("migration_id = ?", migration.ID).Delete(&database.MigrationItem{}).Error; err != nil {
+			return err
+		}
+		for i := range items {
+			items[i].MigrationID = migration.ID
+		}
+		if err := tx.Create(&items).Error; err != nil {
+			return err
+		}
+		migration.State = database.MigrationAnalyzed
+		migration.AnalyzedAt = time.Now().Unix()
+		migration.PlannedAt = 0
+		return tx.Save(migration).Error
+	})
+	if err != nil {
+		return http.StatusInternalServerError, fmt.Errorf("Failed to save inventory")
+	}
+	return http.StatusOK, nil
+}
+
+// migrationView collects what the wizard shows for a migration
+func (h *Handlers) migrationView(db *gorm.DB, migration database.Migration) MigrationView {
+	view := MigrationView{Migration: migration}
+	db.Where("migration_id = ?", migration.ID).Order("id").Find(&view.Items)
+	view.Estimate = h.estimateMigration(view.Items, migration.SourceService, migration.TargetService, userMarket(db, migration.UserID))
+
+	library := migrationLibrary(db, migration)
+	if library != nil {
+		progress := h.libraryTransferProgress(db, *library)
+		view.Library = &progress
+	}
+
+	switch migration.State {
+	case database.MigrationAnalyzed:
+		view.NextStep = "plan"
+	case database.MigrationPlanned:
+		view.NextStep = "execute"
+	case database.MigrationExecuting:
+		view.NextStep = "verify"
+		if library != nil && library.Status == database.LibraryTransferRunning {
+			view.NextStep = "wait"
+		}
+	case database.MigrationVerifying:
+		view.NextStep = "wait"
+	default:
+		view.NextStep = "done"
+	}
+	return view
+}
+
+// migrationLibrary loads the library transfer an executed migration started
+func migrationLibrary(db *gorm.DB, migration database.Migration) *database.LibraryTransfer {
+	if migration.LibraryTransferID == nil {
+		return nil
+	}
+	var library database.LibraryTransfer
+	if err := db.First(&library, *migration.LibraryTransferID).Error; err != nil {
+		return nil
+	}
+	return &library
+}
+
+// estimateMigration adds up the estimates of the selected items. Known
+// counterparts are not looked up, so it is an upper bound.
+func (h *Handlers) estimateMigration(items []database.MigrationItem, sourceService, targetService, market string) TransferEstimate {
+	var estimate TransferEstimate
+	req := TransferRequest{SourceService: sourceService, TargetService: targetService}
+	for _, item := range items {
+		if !item.Selected {
+			continue
+		}
+		if item.Kind == "artists" {
+			// A search and a follow per artist
+			estimate.TargetCalls += 2 * item.Count
+			if targetService == "youtube" {
+				estimate.QuotaUnits += item.Count * (quota.YouTubeSearchCost + quota.YouTubeWriteCost)
+			}
+			if rps := h.serviceRequestsPerSecond(targetService); rps > 0 {
+				estimate.DurationSeconds += int(float64(2*item.Count) / rps)
+			}
+			continue
+		}
+
+		costs := h.transferCosts(req, item.Count, 0, market)
+		estimate.Tracks += costs.Tracks
+		estimate.SourceCalls += costs.SourceCalls
+		estimate.TargetCalls += costs.TargetCalls
+		estimate.QuotaUnits += costs.QuotaUnits
+		estimate.DurationSeconds += costs.DurationSeconds
+	}
+	estimate.addOutlook("migration")
+	return estimate
+}
+
+// verifyMigration re-reads the target playlist of every transferred item and
+// records how many of its matched tracks are missing
+func (h *Handlers) verifyMigration(ctx context.Context, migration database.Migration) {
+	db := h.DB.WithContext(context.WithoutCancel(ctx))
+
+	var items []database.MigrationItem
+	db.Where("migration_id = ? AND selected = ?", migration.ID, true).Find(&items)
+	var steps []database.LibraryTransferStep
+	if migration.LibraryTransferID != nil {
+		db.Where("library_transfer_id = ?", *migration.LibraryTransferID).Find(&steps)
+	}
+	stepsByItem := make(map[string]database.LibraryTransferStep, len(steps))
+	for _, step := range steps {
+		stepsByItem[step.Kind+"/"+step.SourcePlaylistID] = step
+	}
+
+	missing := 0
+	for _, item := range items {
+		step, ok := stepsByItem[item.Kind+"/"+item.SourcePlaylistID]
+		item.MissingTracks, item.VerifyError = 0, ""
+		switch {
+		case !ok:
+			item.VerifyError = "Not part of the library transfer"
+		case step.Status == "failed":
+			item.VerifyError = step.Error
+		case step.TransferID != nil:
+			var transfer database.Transfer
+			if err := db.First(&transfer, *step.TransferID).Error; err != nil {
+				item.VerifyError = "Transfer not found"
+				break
+			}
+			drift, _, _, err := h.transferDrift(ctx, transfer)
+			if err != nil {
+				item.VerifyError = err.Error()
+				break
+			}
+			item.MissingTracks = len(drift.Removed)
+			missing += item.MissingTracks
+		}
+		db.Model(&item).Updates(map[string]interface{}{"missing_tracks": item.MissingTracks, "verify_error": item.VerifyError})
+	}
+
+	db.Model(&migration).Updates(map[string]interface{}{
+		"state":          database.MigrationVerified,
+		"missing_tracks": missing,
+		"verified_at":    time.Now().Unix(),
+	})
+	log.Printf("Verified migration %d: %d tracks missing", migration.ID, missing)
+}
@@ -0,0 +1,127 @@
+package handlers
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"log"
+	"net/http"
+	"net/url"
+	"os"
+	"time"
+
+	"server/internal/config"
+	"server/internal/database"
+	"server/internal/i18n"
+
+	"github.com/gin-gonic/gin"
+)
+
+const googleTokenInfoURL = "https://oauth2.googleapis.com/tokeninfo"
+
+var mobileAuthClient = &http.Client{Timeout: 10 * time.Second}
+
+type MobileAuthRequest struct {
+	Provider string `json:"provider" binding:"required"` // only "google"
+	Token    string `json:"token" binding:"required"`    // Google ID token
+}
+
+// HandleMobileAuth signs in native clients with a Google ID token obtained from the
+// platform SDK. Spotify access tokens are not accepted: they carry no audience, so a
+// token issued to any other app would sign its holder in as the linked user.
+func (h *Handlers) HandleMobileAuth(c *gin.Context) {
+	var req MobileAuthRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		i18n.RespondError(c, http.StatusBadRequest, i18n.CodeInvalidRequest, err.Error())
+		return
+	}
+
+	var user database.User
+	switch req.Provider {
+	case "google":
+		info, err := verifyGoogleIDToken(c.Request.Context(), req.Token)
+		if err != nil {
+			log.Printf("Mobile Google sign-in rejected: %v", err)
+			i18n.RespondError(c, http.StatusUnauthorized, i18n.CodeInvalidToken, "")
+			return
+		}
+
+		user, err = h.findOrCreateGoogleUser(c.Request.Context(), info.Sub, info.Email, info.Name, info.Picture)
+		if err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+			return
+		}
+	default:
+		i18n.RespondError(c, http.StatusBadRequest, i18n.CodeUnsupportedProvider, "")
+		return
+	}
+
+	if requireSecondFactor(c, user) {
+		return
+	}
+
+	jwtToken, err := h.issueSessionToken(c, user.ID)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to generate token"})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"token": jwtToken,
+		"user": map[string]interface{}{
+			"id":        user.ID,
+			"email":     user.Email,
+			"name":      user.Name,
+			"avatarURL": user.AvatarURL,
+		},
+	})
+}
+
+type googleIDTokenInfo struct {
+	Sub           string `json:"sub"`
+	Aud           string `json:"aud"`
+	Iss           string `json:"iss"`
+	Email         string `json:"email"`
+	EmailVerified string `json:"email_verified"`
+	Name          string `json:"name"`
+	Picture       string `json:"picture"`
+}
+
+// verifyGoogleIDToken validates an ID token with Google and checks it was issued to one of our clients
+func verifyGoogleIDToken(ctx context.Context, idToken string) (*googleIDTokenInfo, error) {
+	req, err := http.NewRequestWithContext(ctx, "GET", googleTokenInfoURL+"?id_token="+url.QueryEscape(idToken), nil)
+	if err != nil {
+		return nil, err
+	}
+
+	resp, err := mobileAuthClient.Do(req)
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("tokeninfo returned status: %d", resp.StatusCode)
+	}
+
+	var info googleIDTokenInfo
+	if err := json.NewDecoder(resp.Body).Decode(&info); err != nil {
+		return nil, err
+	}
+
+	if info.Iss != "accounts.google.com" && info.Iss != "https://accounts.google.com" {
+		return nil, fmt.Errorf("unexpected issuer %q", info.Iss)
+	}
+	if info.EmailVerified != "true" {
+		return nil, fmt.Errorf("email not verified")
+	}
+
+	// Native apps use their own OAuth client IDs alongside the web one
+	audiences := append([]string{os.Getenv("GOOGLE_OAUTH_CLIENT_ID")}, config.List("GOOGLE_MOBILE_CLIENT_IDS", nil)...)
+	for _, aud := range audiences {
+		if aud != "" && info.Aud == aud {
+			return &info, nil
+		}
+	}
+	return nil, fmt.Errorf("token audience %q is not an allowed client", info.Aud)
+}
@@ -0,0 +1,67 @@
+package handlers
+
+import (
+	"fmt"
+	"log"
+	"net/http"
+	"time"
+
+	"server/internal/database"
+	"server/internal/i18n"
+	"server/internal/providers/mock"
+
+	"github.com/gin-gonic/gin"
+)
+
+// Demo user signed in when the mock provider replaces Google login
+const (
+	mockUserGoogleID = "mock-demo-user"
+	mockUserEmail    = "demo@example.com"
+	mockUserName     = "Demo User"
+)
+
+// handleMockLogin signs in the demo user in place of the Google OAuth flow
+func (h *Handlers) handleMockLogin(c *gin.Context, redirectURI string) {
+	user, err := h.findOrCreateGoogleUser(c.Request.Context(), mockUserGoogleID, mockUserEmail, mockUserName, "")
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+
+	authCode, err := h.issueAuthCode(c.Request.Context(), user.ID)
+	if err != nil {
+		log.Printf("Auth code generation error: %v", err)
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to generate login code"})
+		return
+	}
+
+	c.Redirect(http.StatusTemporaryRedirect, withQueryParam(redirectURI, "code", authCode))
+}
+
+// connectMockService links the mock account for a service in place of its OAuth flow
+func (h *Handlers) connectMockService(c *gin.Context, provider string, userID uint) {
+	userService := database.UserService{
+		UserID:       userID,
+		ServiceType:  provider,
+		AccessToken:  mock.AccessToken,
+		RefreshToken: mock.AccessToken,
+		// Mock tokens never expire, so the token manager never tries to refresh them
+		TokenExpiry: time.Now().AddDate(10, 0, 0).Unix(),
+	}
+	switch provider {
+	case "spotify":
+		userService.ServiceUserID = mock.SpotifyUserID
+		userService.ServiceUserName = mock.SpotifyUserName
+		userService.Market = mock.Market
+	case "youtube":
+		userService.ServiceUserID = mock.YouTubeChannelID
+		userService.ServiceUserName = mock.YouTubeUserName
+	default:
+		i18n.RespondError(c, http.StatusBadRequest, i18n.CodeUnsupportedProvider, "")
+		return
+	}
+
+	h.saveServiceConnection(c.Request.Context(), userService)
+
+	c.Redirect(http.StatusTemporaryRedirect, fmt.Sprintf("%s/dashboard?message=%s_connected", frontendURL(), provider))
+}
@@ -0,0 +1,105 @@
+package handlers
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log"
+	"strings"
+
+	"server/internal/database"
+
+	"gorm.io/gorm"
+)
+
+// nameConflictModes are what a transfer does when the target service already
+// has a playlist with the name it would create:
+//   - "duplicate" creates another playlist with the same name
+//   - "rename" appends " (2)", " (3)", ... until the name is free
+//   - "append" adds the tracks to the existing playlist instead
+//   - "fail" stops with error code "target_name_exists"
+var nameConflictModes = []string{"duplicate", "rename", "append", "fail"}
+
+// maxRenameAttempts bounds the " (n)" suffixes tried before giving up on a free name
+const maxRenameAttempts = 100
+
+const errCodeTargetNameExists = "target_name_exists"
+
+var errTargetNameExists = errors.New("A playlist with this name already exists on the target service")
+
+// resolveNameConflict decides which playlist a transfer writes to. It returns
+// the name to create the playlist under, or the ID of an existing playlist to
+// append to. The user's playlists are read live from the service, falling back
+// to the stored copies when that fails.
+func (h *Handlers) resolveNameConflict(ctx context.Context, db *gorm.DB, transfer *database.Transfer, targetService database.UserService, name string) (string, string, error) {
+	mode := transfer.OnNameConflict
+	if mode == "" || mode == "duplicate" {
+		return name, "", nil
+	}
+
+	owned := h.ownedPlaylistNames(ctx, db, targetService)
+	existingID, taken := owned[strings.ToLower(name)]
+	if !taken {
+		return name, "", nil
+	}
+
+	switch mode {
+	case "append":
+		recordTransferEvent(db, transfer.ID, "name_conflict", "", fmt.Sprintf("Adding to the existing playlist %q", name))
+		return name, existingID, nil
+	case "rename":
+		for n := 2; n <= maxRenameAttempts; n++ {
+			suffix := fmt.Sprintf(" (%d)", n)
+			candidate := name + suffix
+			if limit := playlistTextLimits[targetService.ServiceType].nameRunes; limit > 0 {
+				candidate = truncateRunes(name, limit-len(suffix)) + suffix
+			}
+			if _, taken := owned[strings.ToLower(candidate)]; !taken {
+				recordTransferEvent(db, transfer.ID, "name_conflict", "", fmt.Sprintf("%q already exists, created %q instead", name, candidate))
+				return candidate, "", nil
+			}
+		}
+		return "", "", errTargetNameExists
+	default:
+		return "", "", errTargetNameExists
+	}
+}
+
+// ownedPlaylistNames maps the lower-cased names of the playlists the user owns on a service to their IDs
+func (h *Handlers) ownedPlaylistNames(ctx context.Context, db *gorm.DB, service database.UserService) map[string]string {
+	names := make(map[string]string)
+
+	playlists, err := h.fetchPlaylistsFromService(ctx, service.ServiceType, service.AccessToken)
+	if err == nil {
+		markFollowedPlaylists(playlists, service)
+		for _, playlist := range playlists {
+			if _, seen := names[strings.ToLower(playlist.Name)]; !seen && !playlist.Followed {
+				names[strings.ToLower(playlist.Name)] = playlist.ServiceID
+			}
+		}
+		return names
+	}
+
+	log.Printf("Failed to list %s playlists for name check, using stored playlists: %v", service.ServiceType, err)
+	var stored []database.Playlist
+	db.Where("user_id = ? AND service_type = ? AND followed = ?", service.UserID, service.ServiceType, false).
+		Order("id").Find(&stored)
+	for _, playlist := range stored {
+		if _, seen := names[strings.ToLower(playlist.Name)]; !seen {
+			names[strings.ToLower(playlist.Name)] = playlist.ServiceID
+		}
+	}
+	return names
+}
+
+// storedPlaylistNamed returns the ID of a stored playlist the user owns with the given name, if any
+func (h *Handlers) storedPlaylistNamed(ctx context.Context, userID uint, serviceType, name string) string {
+	var playlist database.Playlist
+	err := h.DB.WithContext(ctx).
+		Where("user_id = ? AND service_type = ? AND followed = ? AND LOWER(name) = LOWER(?)", userID, serviceType, false, name).
+		First(&playlist).Error
+	if err != nil {
+		return ""
+	}
+	return playlist.ServiceID
+}
@@ -0,0 +1,103 @@
+package handlers
+
+import (
+	"context"
+	"crypto/rand"
+	"fmt"
+	"log"
+	"strings"
+
+	"server/internal/database"
+	"server/internal/notifications"
+
+	"gorm.io/gorm"
+)
+
+// notifyTransferFinished sends a completion or failure notification for a finished transfer
+func notifyTransferFinished(db *gorm.DB, transferID uint) {
+	var transfer database.Transfer
+	if err := db.First(&transfer, transferID).Error; err != nil {
+		log.Printf("Failed to load transfer %d for notification: %v", transferID, err)
+		return
+	}
+
+	event := notifications.Event{
+		UserID:     transfer.UserID,
+		TransferID: transfer.ID,
+		Channels:   notificationChannels(loadUserSettings(db, transfer.UserID)),
+	}
+
+	switch transfer.Status {
+	case database.TransferCompleted, database.TransferCompletedWithErrors:
+		event.Type = notifications.EventTransferCompleted
+		event.Title = fmt.Sprintf("Transfer of \"%s\" finished", transfer.SourcePlaylistName)
+		event.Message = fmt.Sprintf("%d/%d tracks transferred from %s to %s (%d failed).",
+			transfer.TracksMatched, transfer.TracksTotal, getServiceDisplayName(transfer.SourceService),
+			getServiceDisplayName(transfer.TargetService), transfer.TracksFailed)
+		if transfer.TargetPlaylistURL != "" {
+			event.Message += "\n" + transfer.TargetPlaylistURL
+		}
+	case database.TransferPending, database.TransferProcessing, database.TransferCreatingPlaylist,
+		database.TransferMatching, database.TransferAddingTracks, database.TransferPaused, database.TransferMaintenance:
+		return
+	default:
+		event.Type = notifications.EventTransferFailed
+		event.Title = fmt.Sprintf("Transfer #%d failed", transfer.ID)
+		event.Message = transfer.ErrorMessage
+	}
+
+	notifications.Dispatch(event)
+}
+
+// reportTransferProgress notifies the user each time another quarter of the tracks has been processed
+func (h *Handlers) reportTransferProgress(ctx context.Context, transfer database.Transfer, processed int) {
+	total := transfer.TracksTotal
+	if total < 20 || processed >= total {
+		return
+	}
+
+	quarter := total / 4
+	if processed%quarter != 0 {
+		return
+	}
+
+	notifications.Dispatch(notifications.Event{
+		UserID:     transfer.UserID,
+		Type:       notifications.EventTransferProgress,
+		TransferID: transfer.ID,
+		Title:      fmt.Sprintf("Transfer of \"%s\" in progress", transfer.SourcePlaylistName),
+		Message:    fmt.Sprintf("%d/%d tracks processed", processed, total),
+		Channels:   notificationChannels(loadUserSettings(h.DB.WithContext(ctx), transfer.UserID)),
+	})
+}
+
+// recentTransfersSummary formats the user's latest transfers for chat replies
+func (h *Handlers) recentTransfersSummary(ctx context.Context, userID uint) string {
+	var transfers []database.Transfer
+	h.DB.WithContext(ctx).Where("user_id = ?", userID).Order("created_at DESC").Limit(5).Find(&transfers)
+	if len(transfers) == 0 {
+		return "You have no transfers yet."
+	}
+
+	var lines []string
+	for _, transfer := range transfers {
+		lines = append(lines, fmt.Sprintf("#%d %s → %s: %s (%d/%d tracks)",
+			transfer.ID, getServiceDisplayName(transfer.SourceService), getServiceDisplayName(transfer.TargetService),
+			transfer.Status, transfer.TracksMatched, transfer.TracksTotal))
+	}
+	return strings.Join(lines, "\n")
+}
+
+// generateLinkCode returns a random 8 character code without ambiguous characters
+func generateLinkCode() string {
+	const alphabet = "ABCDEFGHJKLMNPQRSTUVWXYZ23456789"
+
+	buf := make([]byte, 8)
+	if _, err := rand.Read(buf); err != nil {
+		panic(err)
+	}
+	for i, b := range buf {
+		buf[i] = alphabet[int(b)%len(alphabet)]
+	}
+	return string(buf)
+}
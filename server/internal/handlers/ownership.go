@@ -0,0 +1,201 @@
+package handlers
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/http"
+	"strings"
+
+	"server/internal/database"
+	"server/internal/ratelimit"
+)
+
+// Specific reasons a playlist operation cannot go ahead
+var (
+	errPlaylistNotFound = errors.New("playlist not found, it may have been deleted")
+	errPlaylistPrivate  = errors.New("playlist is private")
+	errNotPlaylistOwner = errors.New("playlist belongs to another account")
+	errAccountMismatch  = errors.New("connected account no longer matches the linked one, reconnect the service")
+)
+
+// youtubeLimitReasons are the YouTube 403 error reasons that mean a quota or
+// rate limit was hit rather than that the playlist is off limits
+var youtubeLimitReasons = []string{"quotaExceeded", "rateLimitExceeded", "userRateLimitExceeded", "dailyLimitExceeded"}
+
+// playlistAccessError maps a provider's response to a playlist read to a specific error
+func playlistAccessError(status int, body string) error {
+	switch status {
+	case http.StatusNotFound:
+		return errPlaylistNotFound
+	case http.StatusForbidden:
+		for _, reason := range youtubeLimitReasons {
+			if strings.Contains(body, `"`+reason+`"`) {
+				return nil
+			}
+		}
+		return errPlaylistPrivate
+	}
+	return nil
+}
+
+// playlistErrorStatus maps a playlist access error to the HTTP status to report
+func playlistErrorStatus(err error) int {
+	switch {
+	case errors.Is(err, errPlaylistNotFound):
+		return http.StatusNotFound
+	case errors.Is(err, errPlaylistPrivate), errors.Is(err, errNotPlaylistOwner), errors.Is(err, errAccountMismatch):
+		return http.StatusForbidden
+	case errors.Is(err, ratelimit.ErrProviderUnavailable):
+		return http.StatusServiceUnavailable
+	}
+	return http.StatusBadGateway
+}
+
+// isOwnershipError reports whether err is one of the specific access errors;
+// other failures (network, throttling) are left for the transfer itself to handle
+func isOwnershipError(err error) bool {
+	return errors.Is(err, errPlaylistNotFound) || errors.Is(err, errPlaylistPrivate) ||
+		errors.Is(err, errNotPlaylistOwner) || errors.Is(err, errAccountMismatch)
+}
+
+// checkTransferSource probes the source playlist of a transfer request with the
+// credentials the transfer will use, returning only specific access errors
+func (h *Handlers) checkTransferSource(ctx context.Context, sourceService database.UserService, publicSource bool, req TransferRequest) error {
+	ctx = ratelimit.WithUser(ctx, sourceService.UserID)
+
+	var token string
+	if publicSource {
+		appToken, err := h.appAccessToken(ctx, req.SourceService)
+		if err != nil {
+			return nil
+		}
+		token = appToken
+	} else {
+		if err := h.Tokens.RefreshTokenIfNeeded(&sourceService); err != nil {
+			return nil
+		}
+		token = sourceService.AccessToken
+	}
+
+	if err := h.checkSourceAccess(ctx, req.SourceService, token, req.SourcePlaylistID); isOwnershipError(err) {
+		return fmt.Errorf("Source playlist unavailable: %w", err)
+	}
+	return nil
+}
+
+// checkTransferTarget makes sure an existing playlist picked as a transfer target
+// can be modified, refusing playlists the user only follows
+func (h *Handlers) checkTransferTarget(ctx context.Context, targetService database.UserService, playlistID string) error {
+	var stored database.Playlist
+	err := h.DB.WithContext(ctx).Where("user_id = ? AND service_type = ? AND service_id = ?", targetService.UserID, targetService.ServiceType, playlistID).
+		First(&stored).Error
+	if err == nil && stored.Followed && !stored.Collaborative {
+		return fmt.Errorf("Cannot add to target playlist: %w", errNotPlaylistOwner)
+	}
+
+	if err := h.Tokens.RefreshTokenIfNeeded(&targetService); err != nil {
+		return nil
+	}
+	ctx = ratelimit.WithUser(ctx, targetService.UserID)
+	if err := h.verifyPlaylistOwner(ctx, targetService, playlistID); isOwnershipError(err) {
+		return fmt.Errorf("Cannot add to target playlist: %w", err)
+	}
+	return nil
+}
+
+// checkSourceAccess makes sure a playlist can be read before a transfer is queued
+func (h *Handlers) checkSourceAccess(ctx context.Context, serviceType, accessToken, playlistID string) error {
+	if isPersonalCollection(serviceType, playlistID) {
+		return nil
+	}
+	_, err := h.fetchPlaylistOwner(ctx, serviceType, accessToken, playlistID)
+	return err
+}
+
+// comparableAccountID reports whether a connection's stored account ID can be
+// compared with owner IDs the provider returns. YouTube connections may hold a
+// Google profile ID instead of a channel ID, which never matches.
+func comparableAccountID(service database.UserService) bool {
+	return service.ServiceUserID != "" && (service.ServiceType != "youtube" || strings.HasPrefix(service.ServiceUserID, "UC"))
+}
+
+// verifyTargetAccount checks that a connection's token still belongs to the
+// account that was linked, so playlists are not created in someone else's library
+func (h *Handlers) verifyTargetAccount(ctx context.Context, service database.UserService) error {
+	if !comparableAccountID(service) {
+		return nil
+	}
+
+	accountID, err := h.fetchAccountID(ctx, service)
+	if err != nil {
+		return err
+	}
+	if accountID != service.ServiceUserID {
+		return errAccountMismatch
+	}
+	return nil
+}
+
+// verifyPlaylistOwner checks that an existing playlist can be modified by the connected account
+func (h *Handlers) verifyPlaylistOwner(ctx context.Context, service database.UserService, playlistID string) error {
+	owner, err := h.fetchPlaylistOwner(ctx, service.ServiceType, service.AccessToken, playlistID)
+	if err != nil {
+		return err
+	}
+	if !comparableAccountID(service) {
+		return nil
+	}
+	if owner.id != "" && owner.id != service.ServiceUserID && !owner.collaborative {
+		return errNotPlaylistOwner
+	}
+	return nil
+}
+
+type playlistOwner struct {
+	id            string
+	collaborative bool
+}
+
+// fetchPlaylistOwner reads just the owner of a playlist
+func (h *Handlers) fetchPlaylistOwner(ctx context.Context, serviceType, accessToken, playlistID string) (playlistOwner, error) {
+	switch serviceType {
+	case "spotify":
+		playlist, err := h.Providers.Spotify.Playlist(ctx, accessToken, playlistID, "owner(id),collaborative")
+		if err != nil {
+			return playlistOwner{}, playlistFetchError(err)
+		}
+		return playlistOwner{id: playlist.Owner.ID, collaborative: playlist.Collaborative}, nil
+	case "youtube":
+		playlists, err := h.Providers.YouTube.Playlists(ctx, accessToken, playlistID)
+		if err != nil {
+			return playlistOwner{}, playlistFetchError(err)
+		}
+		// YouTube omits private playlists of other channels instead of refusing them
+		if len(playlists) == 0 {
+			return playlistOwner{}, fmt.Errorf("%w (or private)", errPlaylistNotFound)
+		}
+		return playlistOwner{id: playlists[0].Snippet.ChannelID}, nil
+	}
+	return playlistOwner{}, fmt.Errorf("unsupported service: %s", serviceType)
+}
+
+// fetchAccountID returns the ID of the account a connection's token belongs to
+func (h *Handlers) fetchAccountID(ctx context.Context, service database.UserService) (string, error) {
+	if service.ServiceType == "youtube" {
+		channels, err := h.Providers.YouTube.MyChannels(ctx, service.AccessToken)
+		if err != nil {
+			return "", err
+		}
+		if len(channels) == 0 {
+			return "", errAccountMismatch
+		}
+		return channels[0].ID, nil
+	}
+
+	user, err := h.Providers.Spotify.CurrentUser(ctx, service.AccessToken)
+	if err != nil {
+		return "", err
+	}
+	return user.ID, nil
+}
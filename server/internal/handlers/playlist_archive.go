@@ -0,0 +1,163 @@
+package handlers
+
+import (
+	"context"
+	"fmt"
+	"log"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+
+	"server/internal/config"
+	"server/internal/database"
+	"server/internal/i18n"
+	"server/internal/middleware"
+
+	"github.com/gin-gonic/gin"
+)
+
+// Spotify's personalised playlists are owned by the "spotify" user and
+// regenerated on a schedule, so their contents are lost unless archived.
+var spotifyAlgorithmicPrefixes = []string{
+	"discover weekly",
+	"release radar",
+	"daily mix",
+	"on repeat",
+	"repeat rewind",
+	"daylist",
+}
+
+// isSpotifyAlgorithmicPlaylist reports whether a playlist is generated by Spotify for the user
+func isSpotifyAlgorithmicPlaylist(ownerID, name string) bool {
+	if ownerID != "spotify" {
+		return false
+	}
+
+	name = strings.ToLower(strings.TrimSpace(name))
+	for _, prefix := range spotifyAlgorithmicPrefixes {
+		if strings.HasPrefix(name, prefix) {
+			return true
+		}
+	}
+	return false
+}
+
+type PlaylistArchiveRequest struct {
+	Enabled       bool   `json:"enabled"`
+	TargetService string `json:"target_service"` // defaults to spotify
+}
+
+// SetPlaylistArchive enables or disables weekly snapshots of an algorithmic playlist
+func (h *Handlers) SetPlaylistArchive(c *gin.Context) {
+	user, exists := middleware.GetUserFromContext(c)
+	if !exists {
+		i18n.RespondError(c, http.StatusUnauthorized, i18n.CodeUnauthenticated, "")
+		return
+	}
+
+	playlistID, err := strconv.ParseUint(c.Param("id"), 10, 32)
+	if err != nil {
+		i18n.RespondError(c, http.StatusBadRequest, i18n.CodeInvalidPlaylistID, "")
+		return
+	}
+
+	var req PlaylistArchiveRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		i18n.RespondError(c, http.StatusBadRequest, i18n.CodeInvalidRequest, err.Error())
+		return
+	}
+	if req.TargetService == "" {
+		req.TargetService = "spotify"
+	}
+
+	var playlist database.Playlist
+	if err := h.DB.WithContext(c.Request.Context()).Where("id = ? AND user_id = ?", playlistID, user.ID).First(&playlist).Error; err != nil {
+		i18n.RespondError(c, http.StatusNotFound, i18n.CodePlaylistNotFound, "")
+		return
+	}
+
+	if req.Enabled && !playlist.Algorithmic {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Only Spotify-generated playlists can be archived weekly"})
+		return
+	}
+
+	if req.Enabled {
+		var count int64
+		h.DB.WithContext(c.Request.Context()).Model(&database.UserService{}).Where("user_id = ? AND service_type = ?", user.ID, req.TargetService).Count(&count)
+		if count == 0 {
+			i18n.RespondError(c, http.StatusBadRequest, i18n.CodeTargetServiceNotConnected, "")
+			return
+		}
+	}
+
+	err = h.DB.WithContext(c.Request.Context()).Model(&playlist).Updates(map[string]interface{}{
+		"archive_weekly":         req.Enabled,
+		"archive_target_service": req.TargetService,
+	}).Error
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to update archive settings"})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"playlist_id":            playlist.ID,
+		"archive_weekly":         req.Enabled,
+		"archive_target_service": req.TargetService,
+	})
+}
+
+// StartPlaylistArchiveScheduler snapshots algorithmic playlists marked for
+// archiving into dated playlists once per PLAYLIST_ARCHIVE_INTERVAL (default a week)
+func (h *Handlers) StartPlaylistArchiveScheduler(ctx context.Context) {
+	interval := config.Duration("PLAYLIST_ARCHIVE_INTERVAL", 7*24*time.Hour)
+	if interval <= 0 {
+		log.Printf("Playlist archiving disabled")
+		return
+	}
+
+	go func() {
+		ticker := time.NewTicker(time.Hour)
+		defer ticker.Stop()
+
+		for {
+			select {
+			case <-ctx.Done():
+				return
+			case <-ticker.C:
+				h.runScheduled(ctx, "playlist-archive", func() { h.archiveDuePlaylists(ctx, interval) })
+			}
+		}
+	}()
+}
+
+// archiveDuePlaylists starts a snapshot transfer for every archived playlist not snapshotted within interval
+func (h *Handlers) archiveDuePlaylists(ctx context.Context, interval time.Duration) {
+	var playlists []database.Playlist
+	err := h.DB.WithContext(ctx).Where("archive_weekly = ? AND last_archived_at < ?", true, time.Now().Add(-interval).Unix()).
+		Find(&playlists).Error
+	if err != nil {
+		log.Printf("Failed to load playlists to archive: %v", err)
+		return
+	}
+
+	for _, playlist := range playlists {
+		// Mark first so a failing playlist is retried next interval rather than every hour
+		h.DB.WithContext(ctx).Model(&playlist).Update("last_archived_at", time.Now().Unix())
+
+		name := fmt.Sprintf("%s %s", playlist.Name, time.Now().Format("2006-01-02"))
+		transfer, _, err := h.startTransferForUser(ctx, playlist.UserID, TransferRequest{
+			SourceService:      playlist.ServiceType,
+			SourcePlaylistID:   playlist.ServiceID,
+			TargetService:      playlist.ArchiveTargetService,
+			Priority:           "low",
+			TargetPlaylistName: name,
+		})
+		if err != nil {
+			log.Printf("Failed to archive playlist %d for user %d: %v", playlist.ID, playlist.UserID, err)
+			continue
+		}
+
+		log.Printf("Archiving playlist %d as %q (transfer %d)", playlist.ID, name, transfer.ID)
+	}
+}
@@ -0,0 +1,52 @@
+package handlers
+
+import (
+	"fmt"
+
+	"server/internal/database"
+)
+
+// diffStoredPlaylists lists what a sync changed compared to the stored
+// playlists of a service. The first sync of a service records nothing, as
+// every playlist would otherwise show up as added.
+func diffStoredPlaylists(userID uint, serviceType string, stored []database.Playlist, fetched []PlaylistResponse, now int64) []database.PlaylistChange {
+	if len(stored) == 0 {
+		return nil
+	}
+
+	change := func(serviceID, name, kind, detail string) database.PlaylistChange {
+		return database.PlaylistChange{
+			UserID:      userID,
+			ServiceType: serviceType,
+			ServiceID:   serviceID,
+			Name:        name,
+			Change:      kind,
+			Detail:      detail,
+			DetectedAt:  now,
+		}
+	}
+
+	previous := make(map[string]database.Playlist, len(stored))
+	for _, playlist := range stored {
+		previous[playlist.ServiceID] = playlist
+	}
+
+	var changes []database.PlaylistChange
+	for _, playlist := range fetched {
+		old, ok := previous[playlist.ServiceID]
+		delete(previous, playlist.ServiceID)
+		switch {
+		case !ok:
+			changes = append(changes, change(playlist.ServiceID, playlist.Name, "added", ""))
+		case old.Name != playlist.Name:
+			changes = append(changes, change(playlist.ServiceID, playlist.Name, "renamed", fmt.Sprintf("was %q", old.Name)))
+		}
+		if ok && old.TrackCount != playlist.TrackCount {
+			changes = append(changes, change(playlist.ServiceID, playlist.Name, "tracks_changed", fmt.Sprintf("%d → %d tracks", old.TrackCount, playlist.TrackCount)))
+		}
+	}
+	for _, old := range previous {
+		changes = append(changes, change(old.ServiceID, old.Name, "removed", ""))
+	}
+	return changes
+}
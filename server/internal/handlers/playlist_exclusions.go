@@ -0,0 +1,157 @@
+package handlers
+
+import (
+	"log"
+	"net/http"
+	"regexp"
+	"strconv"
+	"strings"
+	"time"
+
+	"server/internal/database"
+	"server/internal/i18n"
+	"server/internal/middleware"
+
+	"github.com/gin-gonic/gin"
+	"gorm.io/gorm"
+)
+
+type PlaylistExclusionRequest struct {
+	ServiceType string `json:"service_type"` // required with playlist_id
+	PlaylistID  string `json:"playlist_id"`  // ID or link of one playlist
+	Pattern     string `json:"pattern"`      // or a name pattern such as "Daily Mix*"
+}
+
+// playlistExclusions is a user's exclusion list
+type playlistExclusions []database.PlaylistExclusion
+
+// loadPlaylistExclusions reads a user's exclusions; a failed read excludes nothing
+func loadPlaylistExclusions(db *gorm.DB, userID uint) playlistExclusions {
+	var exclusions []database.PlaylistExclusion
+	if err := db.Where("user_id = ?", userID).Find(&exclusions).Error; err != nil {
+		log.Printf("Failed to load playlist exclusions for user %d: %v", userID, err)
+	}
+	return exclusions
+}
+
+// excludes reports whether a playlist is on the list by ID or name
+func (exclusions playlistExclusions) excludes(serviceType, playlistID, name string) bool {
+	for _, exclusion := range exclusions {
+		if exclusion.ServiceType != "" && exclusion.ServiceType != serviceType {
+			continue
+		}
+		if exclusion.PlaylistID != "" && exclusion.PlaylistID == playlistID {
+			return true
+		}
+		if exclusion.Pattern != "" && globPattern(exclusion.Pattern).MatchString(name) {
+			return true
+		}
+	}
+	return false
+}
+
+// globPattern compiles a name pattern where * matches any run of characters
+// and ? a single one; everything else is literal
+func globPattern(pattern string) *regexp.Regexp {
+	quoted := regexp.QuoteMeta(strings.TrimSpace(pattern))
+	quoted = strings.ReplaceAll(quoted, `\*`, `.*`)
+	quoted = strings.ReplaceAll(quoted, `\?`, `.`)
+	return regexp.MustCompile(`(?is)^` + quoted + `$`)
+}
+
+// GetPlaylistExclusions lists the user's exclusion list
+func (h *Handlers) GetPlaylistExclusions(c *gin.Context) {
+	user, exists := middleware.GetUserFromContext(c)
+	if !exists {
+		i18n.RespondError(c, http.StatusUnauthorized, i18n.CodeUnauthenticated, "")
+		return
+	}
+
+	var exclusions []database.PlaylistExclusion
+	if err := h.DB.WithContext(c.Request.Context()).Where("user_id = ?", user.ID).Order("id").Find(&exclusions).Error; err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to fetch exclusions"})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"exclusions": exclusions})
+}
+
+// CreatePlaylistExclusion adds a playlist or a name pattern to the exclusion list
+func (h *Handlers) CreatePlaylistExclusion(c *gin.Context) {
+	user, exists := middleware.GetUserFromContext(c)
+	if !exists {
+		i18n.RespondError(c, http.StatusUnauthorized, i18n.CodeUnauthenticated, "")
+		return
+	}
+
+	var req PlaylistExclusionRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		i18n.RespondError(c, http.StatusBadRequest, i18n.CodeInvalidRequest, err.Error())
+		return
+	}
+	req.Pattern = strings.TrimSpace(req.Pattern)
+	if (req.PlaylistID == "") == (req.Pattern == "") {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Set either playlist_id or pattern"})
+		return
+	}
+	if req.ServiceType != "" && req.ServiceType != "spotify" && req.ServiceType != "youtube" {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Unsupported service: " + req.ServiceType})
+		return
+	}
+
+	exclusion := database.PlaylistExclusion{
+		UserID:      user.ID,
+		ServiceType: req.ServiceType,
+		Pattern:     req.Pattern,
+		CreatedAt:   time.Now().Unix(),
+	}
+	if req.PlaylistID != "" {
+		if req.ServiceType == "" {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "service_type is required with playlist_id"})
+			return
+		}
+		playlistID, err := parsePlaylistReference(req.ServiceType, req.PlaylistID)
+		if err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+			return
+		}
+		exclusion.PlaylistID = playlistID
+	}
+	if len(exclusion.Pattern) > 200 {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Pattern is too long"})
+		return
+	}
+
+	if err := h.DB.WithContext(c.Request.Context()).Create(&exclusion).Error; err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to create exclusion"})
+		return
+	}
+
+	c.JSON(http.StatusCreated, gin.H{"exclusion": exclusion})
+}
+
+// DeletePlaylistExclusion removes an entry from the exclusion list
+func (h *Handlers) DeletePlaylistExclusion(c *gin.Context) {
+	user, exists := middleware.GetUserFromContext(c)
+	if !exists {
+		i18n.RespondError(c, http.StatusUnauthorized, i18n.CodeUnauthenticated, "")
+		return
+	}
+
+	id, err := strconv.ParseUint(c.Param("id"), 10, 32)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid exclusion ID"})
+		return
+	}
+	result := h.DB.WithContext(c.Request.Context()).Where("id = ? AND user_id = ?", uint(id), user.ID).Delete(&database.PlaylistExclusion{})
+	if result.Error != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to delete exclusion"})
+		return
+	}
+	if result.RowsAffected == 0 {
+		c.JSON(http.StatusNotFound, gin.H{"error": "Exclusion not found"})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"message": "Exclusion deleted"})
+}
@@ -0,0 +1,50 @@
+package handlers
+
+import (
+	"net/url"
+	"strings"
+)
+
+// parsePlaylistLink extracts the service and playlist ID from a pasted
+// Spotify or YouTube playlist URL (or Spotify URI)
+func parsePlaylistLink(link string) (string, string, bool) {
+	link = strings.TrimSpace(link)
+
+	// spotify:playlist:<id>
+	if strings.HasPrefix(link, "spotify:playlist:") {
+		id := strings.TrimPrefix(link, "spotify:playlist:")
+		return "spotify", id, id != ""
+	}
+
+	u, err := url.Parse(link)
+	if err != nil || u.Host == "" {
+		return "", "", false
+	}
+
+	host := strings.TrimPrefix(strings.ToLower(u.Host), "www.")
+	switch host {
+	case "open.spotify.com":
+		// https://open.spotify.com/playlist/<id> (optionally prefixed with a locale segment)
+		parts := strings.Split(strings.Trim(u.Path, "/"), "/")
+		for i := 0; i+1 < len(parts); i++ {
+			if parts[i] == "playlist" && parts[i+1] != "" {
+				return "spotify", parts[i+1], true
+			}
+		}
+	case "youtube.com", "m.youtube.com", "music.youtube.com", "youtu.be":
+		// https://www.youtube.com/playlist?list=<id>, also accepted on watch URLs
+		if id := u.Query().Get("list"); id != "" {
+			return "youtube", id, true
+		}
+	}
+
+	return "", "", false
+}
+
+// otherService returns the default transfer target for a source service
+func otherService(service string) string {
+	if service == "spotify" {
+		return "youtube"
+	}
+	return "spotify"
+}
@@ -0,0 +1,196 @@
+package handlers
+
+import (
+	"context"
+	"net/http"
+	"strconv"
+	"sync"
+
+	"server/internal/config"
+	"server/internal/database"
+	"server/internal/i18n"
+	"server/internal/middleware"
+
+	"github.com/gin-gonic/gin"
+)
+
+// previewLookupWorkers bounds the concurrent counterpart lookups of a preview
+const previewLookupWorkers = 8
+
+// PreviewTrack is a source track with how hard it is likely to be to match
+type PreviewTrack struct {
+	Track
+	Known      bool   `json:"known"`      // counterpart on the target already known
+	Difficulty string `json:"difficulty"` // "easy", "moderate" or "hard"
+}
+
+// PreviewSummary estimates how well a transfer of the previewed tracks will go
+type PreviewSummary struct {
+	Tracks        int    `json:"tracks"`
+	WithISRC      int    `json:"with_isrc"`
+	Known         int    `json:"known"`
+	MissingArtist int    `json:"missing_artist"`
+	Difficulty    string `json:"difficulty"` // "easy", "moderate" or "hard" overall
+}
+
+// GetPlaylistPreview returns the first tracks of a playlist and how hard they
+// will be to match on the target service, before committing to a transfer.
+// The target defaults to the other service; limit defaults to PLAYLIST_PREVIEW_TRACKS.
+func (h *Handlers) GetPlaylistPreview(c *gin.Context) {
+	user, exists := middleware.GetUserFromContext(c)
+	if !exists {
+		i18n.RespondError(c, http.StatusUnauthorized, i18n.CodeUnauthenticated, "")
+		return
+	}
+
+	serviceType := c.Param("service")
+	target := c.DefaultQuery("target", otherService(serviceType))
+	if !isPlaylistService(serviceType) || !isPlaylistService(target) {
+		i18n.RespondError(c, http.StatusBadRequest, i18n.CodeUnsupportedProvider, "")
+		return
+	}
+	playlistID, err := parsePlaylistReference(serviceType, c.Param("id"))
+	if err != nil {
+		i18n.RespondError(c, http.StatusBadRequest, i18n.CodeInvalidPlaylistID, err.Error())
+		return
+	}
+	limit := config.Int("PLAYLIST_PREVIEW_TRACKS", 20)
+	if n, err := strconv.Atoi(c.Query("limit")); err == nil && n > 0 {
+		limit = min(n, 100)
+	}
+
+	ctx := c.Request.Context()
+	token, err := h.sourceAccessToken(ctx, user.ID, serviceType)
+	if err != nil {
+		i18n.RespondError(c, http.StatusBadRequest, i18n.CodeServiceNotConnected, "")
+		return
+	}
+
+	// The provider fetch and the stored copy's metadata load side by side
+	var (
+		wg       sync.WaitGroup
+		tracks   []Track
+		source   SourcePlaylist
+		fetchErr error
+		stored   database.Playlist
+		isStored bool
+	)
+	wg.Add(2)
+	go func() {
+		defer wg.Done()
+		tracks, source, fetchErr = h.fetchPlaylistTracks(ctx, serviceType, token, playlistID)
+	}()
+	go func() {
+		defer wg.Done()
+		isStored = h.DB.WithContext(ctx).Where("user_id = ? AND service_type = ? AND service_id = ?", user.ID, serviceType, playlistID).
+			First(&stored).Error == nil
+	}()
+	wg.Wait()
+
+	if fetchErr != nil {
+		c.JSON(playlistErrorStatus(fetchErr), gin.H{"error": "Failed to fetch playlist: " + fetchErr.Error()})
+		return
+	}
+
+	totalTracks := len(tracks)
+	if isStored && stored.TrackCount > totalTracks {
+		totalTracks = stored.TrackCount
+	}
+	if len(tracks) > limit {
+		tracks = tracks[:limit]
+	}
+	preview, summary := h.previewTracks(ctx, user.ID, tracks, target)
+
+	c.JSON(http.StatusOK, gin.H{
+		"playlist": gin.H{
+			"service":       serviceType,
+			"id":            playlistID,
+			"name":          source.Name,
+			"owner_name":    source.OwnerName,
+			"collaborative": source.Collaborative,
+			"track_count":   totalTracks,
+			"image_url":     stored.ImageURL,
+		},
+		"target_service": target,
+		"tracks":         preview,
+		"summary":        summary,
+	})
+}
+
+// sourceAccessToken returns the user's token for a service, or the app's own
+// credentials when the service is not connected
+func (h *Handlers) sourceAccessToken(ctx context.Context, userID uint, serviceType string) (string, error) {
+	var service database.UserService
+	if err := h.DB.WithContext(ctx).Where("user_id = ? AND service_type = ?", userID, serviceType).First(&service).Error; err != nil {
+		return h.appAccessToken(ctx, serviceType)
+	}
+	if err := h.Tokens.RefreshTokenIfNeeded(&service); err != nil {
+		return "", err
+	}
+	return service.AccessToken, nil
+}
+
+// previewTracks rates each track's match difficulty, looking up known
+// counterparts concurrently
+func (h *Handlers) previewTracks(ctx context.Context, userID uint, tracks []Track, target string) ([]PreviewTrack, PreviewSummary) {
+	known := make([]bool, len(tracks))
+	indexes := make(chan int)
+	var wg sync.WaitGroup
+	for range min(previewLookupWorkers, len(tracks)) {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for i := range indexes {
+				_, _, known[i] = knownTargetTrack(h.DB.WithContext(ctx), userID, tracks[i], target)
+			}
+		}()
+	}
+	for i := range tracks {
+		indexes <- i
+	}
+	close(indexes)
+	wg.Wait()
+
+	preview := make([]PreviewTrack, len(tracks))
+	summary := PreviewSummary{Tracks: len(tracks)}
+	easy, hard := 0, 0
+	for i, track := range tracks {
+		difficulty := "moderate"
+		switch {
+		case known[i] || (track.ISRC != "" && target == "spotify"):
+			difficulty = "easy"
+		case track.Artist == "":
+			difficulty = "hard"
+		}
+		preview[i] = PreviewTrack{Track: track, Known: known[i], Difficulty: difficulty}
+
+		if track.ISRC != "" {
+			summary.WithISRC++
+		}
+		if known[i] {
+			summary.Known++
+		}
+		if track.Artist == "" {
+			summary.MissingArtist++
+		}
+		switch difficulty {
+		case "easy":
+			easy++
+		case "hard":
+			hard++
+		}
+	}
+
+	summary.Difficulty = "moderate"
+	switch {
+	case len(tracks) == 0 || easy*4 >= len(tracks)*3:
+		summary.Difficulty = "easy"
+	case hard*4 >= len(tracks):
+		summary.Difficulty = "hard"
+	}
+	return preview, summary
+}
+
+func isPlaylistService(service string) bool {
+	return service == "spotify" || service == "youtube"
+}
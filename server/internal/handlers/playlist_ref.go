@@ -0,0 +1,51 @@
+package handlers
+
+import (
+	"fmt"
+	"net/url"
+	"regexp"
+	"strings"
+)
+
+var (
+	spotifyPlaylistIDPattern = regexp.MustCompile(`^[A-Za-z0-9]{22}$`)
+	youtubePlaylistIDPattern = regexp.MustCompile(`^[A-Za-z0-9_-]{2,64}$`)
+)
+
+// parsePlaylistReference accepts a bare playlist ID, a share URL or a Spotify
+// URI and returns the playlist ID, so users can paste links to any playlist
+func parsePlaylistReference(service, ref string) (string, error) {
+	ref = strings.TrimSpace(ref)
+
+	switch service {
+	case "spotify":
+		if ref == spotifyLikedSongsID {
+			return ref, nil
+		}
+		if id, ok := strings.CutPrefix(ref, "spotify:playlist:"); ok {
+			ref = id
+		} else if u, err := url.Parse(ref); err == nil && u.Host != "" {
+			// https://open.spotify.com/playlist/<id>?si=..., optionally with a locale segment
+			parts := strings.Split(strings.Trim(u.Path, "/"), "/")
+			ref = ""
+			for i := 0; i+1 < len(parts); i++ {
+				if parts[i] == "playlist" {
+					ref = parts[i+1]
+				}
+			}
+		}
+		if !spotifyPlaylistIDPattern.MatchString(ref) {
+			return "", fmt.Errorf("Invalid Spotify playlist ID or URL")
+		}
+	case "youtube":
+		if u, err := url.Parse(ref); err == nil && u.Host != "" {
+			// youtube.com/playlist?list=<id>, music.youtube.com/..., or a watch URL inside a playlist
+			ref = u.Query().Get("list")
+		}
+		if !youtubePlaylistIDPattern.MatchString(ref) {
+			return "", fmt.Errorf("Invalid YouTube playlist ID or URL")
+		}
+	}
+
+	return ref, nil
+}
@@ -0,0 +1,110 @@
+package handlers
+
+import (
+	"strings"
+	"unicode"
+	"unicode/utf8"
+)
+
+// playlistTextLimit is what a service accepts for playlist names and descriptions
+type playlistTextLimit struct {
+	nameRunes        int
+	descriptionRunes int
+	descriptionBytes int  // 0 when only the rune count matters
+	stripAngles      bool // '<' and '>' are rejected
+	stripNameEmoji   bool // emoji in titles are rejected or mangled
+	singleLine       bool // descriptions may not contain line breaks
+}
+
+var playlistTextLimits = map[string]playlistTextLimit{
+	"spotify": {nameRunes: 100, descriptionRunes: 300, singleLine: true},
+	"youtube": {nameRunes: 150, descriptionRunes: 5000, descriptionBytes: 5000, stripAngles: true, stripNameEmoji: true},
+}
+
+// untitledPlaylistName replaces names that sanitize to nothing
+const untitledPlaylistName = "Untitled playlist"
+
+// sanitizePlaylistName makes a name acceptable to the service: valid UTF-8,
+// no control characters, collapsed whitespace and within the length limit
+func sanitizePlaylistName(serviceType, name string) string {
+	limit := playlistTextLimits[serviceType]
+
+	name = strings.ToValidUTF8(name, "")
+	name = strings.Map(func(r rune) rune {
+		switch {
+		case unicode.IsControl(r):
+			return ' '
+		case limit.stripAngles && (r == '<' || r == '>'):
+			return -1
+		case limit.stripNameEmoji && isEmoji(r):
+			return -1
+		}
+		return r
+	}, name)
+	name = strings.Join(strings.Fields(name), " ")
+
+	if limit.nameRunes > 0 {
+		name = truncateRunes(name, limit.nameRunes)
+	}
+	if name == "" {
+		return untitledPlaylistName
+	}
+	return name
+}
+
+// sanitizePlaylistDescription applies the same rules to descriptions, keeping
+// line breaks where the service allows them
+func sanitizePlaylistDescription(serviceType, description string) string {
+	limit := playlistTextLimits[serviceType]
+
+	description = strings.ToValidUTF8(description, "")
+	description = strings.Map(func(r rune) rune {
+		switch {
+		case r == '\n' && !limit.singleLine:
+			return r
+		case unicode.IsControl(r):
+			return ' '
+		case limit.stripAngles && (r == '<' || r == '>'):
+			return -1
+		}
+		return r
+	}, description)
+	if limit.singleLine {
+		description = strings.Join(strings.Fields(description), " ")
+	}
+	description = strings.TrimSpace(description)
+
+	if limit.descriptionRunes > 0 {
+		description = truncateRunes(description, limit.descriptionRunes)
+	}
+	if limit.descriptionBytes > 0 && len(description) > limit.descriptionBytes {
+		cut := limit.descriptionBytes
+		for cut > 0 && !utf8.RuneStart(description[cut]) {
+			cut--
+		}
+		description = strings.TrimSpace(description[:cut])
+	}
+	return description
+}
+
+// truncateRunes shortens s to at most n runes, without leaving trailing space
+func truncateRunes(s string, n int) string {
+	if utf8.RuneCountInString(s) <= n {
+		return s
+	}
+	return strings.TrimSpace(string([]rune(s)[:n]))
+}
+
+// isEmoji reports whether r is a pictograph or one of the joiners and
+// selectors emoji sequences are built from
+func isEmoji(r rune) bool {
+	switch {
+	case r >= 0x1F000 && r <= 0x1FAFF, // pictographs, emoticons, transport, flags
+		r >= 0x2600 && r <= 0x27BF,   // miscellaneous symbols and dingbats
+		r >= 0xFE00 && r <= 0xFE0F,   // variation selectors
+		r >= 0xE0020 && r <= 0xE007F, // tag sequences
+		r == 0x200D, r == 0x20E3:
+		return true
+	}
+	return false
+}
@@ -1,63 +1,94 @@
 package handlers
 
 import (
-	"encoding/json"
+	"context"
 	"fmt"
 	"log"
 	"net/http"
+	"os"
+	"strconv"
+	"strings"
 	"time"
 
-	"server/internal/auth"
+	"server/internal/cache"
 	"server/internal/database"
+	"server/internal/i18n"
 	"server/internal/middleware"
+	"server/internal/providers"
 	"server/internal/ratelimit"
 
 	"github.com/gin-gonic/gin"
 	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
 )
 
-var tokenManager = auth.NewTokenManager(database.DB)
+// monitorRequests reports provider call outcomes to the rate limit monitor
+func monitorRequests(monitor *ratelimit.RateLimitMonitor, service ratelimit.ServiceType) providers.Observer {
+	return func(rateLimited, failed bool) {
+		monitor.RecordRequest(service, rateLimited, failed)
+	}
+}
 
-var (
-	rateLimiter = ratelimit.NewRateLimiter()
-	rateMonitor = ratelimit.NewRateLimitMonitor(rateLimiter)
-)
+// configureProviderCache enables response caching for provider GET calls when PROVIDER_CACHE=memory
+func configureProviderCache(clients ...*ratelimit.RateLimitedHTTPClient) {
+	switch os.Getenv("PROVIDER_CACHE") {
+	case "":
+		return
+	case "memory":
+	default:
+		log.Printf("Unsupported PROVIDER_CACHE backend %q, caching disabled", os.Getenv("PROVIDER_CACHE"))
+		return
+	}
+
+	ttl := 60 * time.Second
+	if v, err := time.ParseDuration(os.Getenv("PROVIDER_CACHE_TTL")); err == nil && v > 0 {
+		ttl = v
+	}
+
+	maxEntries := 10000
+	if v, err := strconv.Atoi(os.Getenv("PROVIDER_CACHE_MAX_ENTRIES")); err == nil && v > 0 {
+		maxEntries = v
+	}
 
-func init() {
-	rateMonitor.StartMonitoring()
+	responseCache := cache.NewMemoryCache(maxEntries)
+	for _, client := range clients {
+		client.SetCache(responseCache, ttl)
+	}
+
+	log.Printf("Provider response cache enabled (ttl %v, max %d entries)", ttl, maxEntries)
 }
 
 // GetPlaylists fetches playlists from a specific service for the authenticated user
-func GetPlaylists(c *gin.Context) {
+func (h *Handlers) GetPlaylists(c *gin.Context) {
 	serviceType := c.Param("service")
 	user, exists := middleware.GetUserFromContext(c)
 	if !exists {
-		c.JSON(http.StatusUnauthorized, gin.H{"error": "User not authenticated"})
+		i18n.RespondError(c, http.StatusUnauthorized, i18n.CodeUnauthenticated, "")
 		return
 	}
 
 	// Get the user's service connection
 	var userService database.UserService
-	result := database.DB.Where("user_id = ? AND service_type = ?", user.ID, serviceType).First(&userService)
+	result := h.DB.WithContext(c.Request.Context()).Where("user_id = ? AND service_type = ?", user.ID, serviceType).First(&userService)
 	if result.Error != nil {
-		c.JSON(http.StatusNotFound, gin.H{"error": "Service not connected"})
+		i18n.RespondError(c, http.StatusNotFound, i18n.CodeServiceNotConnected, "")
 		return
 	}
 
 	// Refresh token if needed
-	if err := tokenManager.RefreshTokenIfNeeded(&userService); err != nil {
+	if err := h.Tokens.RefreshTokenIfNeeded(&userService); err != nil {
 		log.Printf("Token refresh failed for %s: %v", serviceType, err)
 		c.JSON(http.StatusInternalServerError, gin.H{"error": "Token refresh failed: " + err.Error()})
 		return
 	}
 
 	// Fetch playlists from the service
-	playlists, err := fetchPlaylistsFromService(serviceType, userService.AccessToken)
+	playlists, err := h.fetchPlaylistsFromService(c.Request.Context(), serviceType, userService.AccessToken)
 	if err != nil {
 		log.Printf("Failed to fetch playlists from %s: %v", serviceType, err)
 
 		// If API call fails, try to validate token
-		if valid, _ := tokenManager.ValidateToken(&userService); !valid {
+		if valid, _ := h.Tokens.ValidateToken(&userService); !valid {
 			c.JSON(http.StatusUnauthorized, gin.H{"error": "Service connection expired. Please reconnect."})
 			return
 		}
@@ -66,26 +97,52 @@ func GetPlaylists(c *gin.Context) {
 		return
 	}
 
+	markFollowedPlaylists(playlists, userService)
+
 	// Store playlists in database (async)
-	go storePlaylistsInDatabase(user.ID, serviceType, playlists)
+	go h.storePlaylistsInDatabase(user.ID, serviceType, playlists, true)
 
 	c.JSON(http.StatusOK, gin.H{
 		"service":   serviceType,
-		"playlists": playlists,
+		"playlists": filterPlaylistsByOwnership(playlists, c.Query("ownership")),
 	})
 }
 
+// markFollowedPlaylists flags playlists owned by another account, which can be
+// used as transfer sources but not modified. YouTube only lists the channel's own
+// playlists (mine=true), so none of them are followed.
+func markFollowedPlaylists(playlists []PlaylistResponse, service database.UserService) {
+	for i := range playlists {
+		owner := playlists[i].OwnerID
+		playlists[i].Followed = service.ServiceType != "youtube" && service.ServiceUserID != "" && owner != "" && owner != service.ServiceUserID
+	}
+}
+
+// filterPlaylistsByOwnership keeps "owned" or "followed" playlists, or all of them for any other value
+func filterPlaylistsByOwnership(playlists []PlaylistResponse, ownership string) []PlaylistResponse {
+	if ownership != "owned" && ownership != "followed" {
+		return playlists
+	}
+	filtered := make([]PlaylistResponse, 0, len(playlists))
+	for _, playlist := range playlists {
+		if playlist.Followed == (ownership == "followed") {
+			filtered = append(filtered, playlist)
+		}
+	}
+	return filtered
+}
+
 // SyncAllPlaylists triggers sync for all connected services
-func SyncAllPlaylists(c *gin.Context) {
+func (h *Handlers) SyncAllPlaylists(c *gin.Context) {
 	user, exists := middleware.GetUserFromContext(c)
 	if !exists {
-		c.JSON(http.StatusUnauthorized, gin.H{"error": "User not authenticated"})
+		i18n.RespondError(c, http.StatusUnauthorized, i18n.CodeUnauthenticated, "")
 		return
 	}
 
 	// Get user's connected services
 	var services []database.UserService
-	result := database.DB.Where("user_id = ?", user.ID).Find(&services)
+	result := h.DB.WithContext(c.Request.Context()).Where("user_id = ?", user.ID).Find(&services)
 	if result.Error != nil {
 		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to fetch services"})
 		return
@@ -93,30 +150,47 @@ func SyncAllPlaylists(c *gin.Context) {
 
 	// Sync each service
 	for _, service := range services {
-		go syncServicePlaylists(user.ID, service)
+		go h.syncServicePlaylists(ratelimit.WithUser(context.Background(), user.ID), user.ID, service)
 	}
 
-	c.JSON(http.StatusOK, gin.H{
+	response := gin.H{
 		"message":  "Sync started for all services",
 		"services": len(services),
-	})
+	}
+	if maxPlaylistsPerSync > 0 {
+		response["max_playlists_per_service"] = maxPlaylistsPerSync
+	}
+	c.JSON(http.StatusOK, response)
 }
 
 // GetStoredPlaylists returns playlists from database (faster than API calls)
-func GetStoredPlaylists(c *gin.Context) {
+func (h *Handlers) GetStoredPlaylists(c *gin.Context) {
 	serviceType := c.Param("service")
 	user, exists := middleware.GetUserFromContext(c)
 	if !exists {
-		c.JSON(http.StatusUnauthorized, gin.H{"error": "User not authenticated"})
+		i18n.RespondError(c, http.StatusUnauthorized, i18n.CodeUnauthenticated, "")
 		return
 	}
 
+	query := h.DB.WithContext(c.Request.Context()).Preload("Tags").Where("user_id = ? AND service_type = ?", user.ID, serviceType)
+	if tag := c.Query("tag"); tag != "" {
+		query = query.Where("id IN (?)", h.DB.WithContext(c.Request.Context()).Model(&database.PlaylistTag{}).
+			Select("playlist_id").Where("user_id = ? AND tag = ?", user.ID, strings.ToLower(tag)))
+	}
+	switch c.Query("ownership") {
+	case "owned":
+		query = query.Where("followed = ?", false)
+	case "followed":
+		query = query.Where("followed = ?", true)
+	}
+
 	var playlists []database.Playlist
-	result := database.DB.Where("user_id = ? AND service_type = ?", user.ID, serviceType).Find(&playlists)
+	result := query.Find(&playlists)
 	if result.Error != nil {
 		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to fetch playlists"})
 		return
 	}
+	withArtworkURLs(playlists)
 
 	c.JSON(http.StatusOK, gin.H{
 		"service":   serviceType,
@@ -125,12 +199,12 @@ func GetStoredPlaylists(c *gin.Context) {
 }
 
 // fetchPlaylistsFromService calls the appropriate service API
-func fetchPlaylistsFromService(serviceType string, accessToken string) ([]PlaylistResponse, error) {
+func (h *Handlers) fetchPlaylistsFromService(ctx context.Context, serviceType string, accessToken string) ([]PlaylistResponse, error) {
 	switch serviceType {
 	case "spotify":
-		return fetchSpotifyPlaylists(accessToken)
+		return h.fetchSpotifyPlaylists(ctx, accessToken)
 	case "youtube":
-		return fetchYouTubePlaylists(accessToken)
+		return h.fetchYouTubePlaylists(ctx, accessToken)
 	default:
 		return nil, fmt.Errorf("unsupported service: %s", serviceType)
 	}
@@ -138,73 +212,44 @@ func fetchPlaylistsFromService(serviceType string, accessToken string) ([]Playli
 
 // PlaylistResponse represents a standardized playlist response
 type PlaylistResponse struct {
-	ServiceID   string `json:"service_id"`
-	Name        string `json:"name"`
-	Description string `json:"description"`
-	TrackCount  int    `json:"track_count"`
-	ImageURL    string `json:"image_url"`
-	IsPublic    bool   `json:"is_public"`
+	ServiceID     string `json:"service_id"`
+	Name          string `json:"name"`
+	Description   string `json:"description"`
+	TrackCount    int    `json:"track_count"`
+	ImageURL      string `json:"image_url"`
+	IsPublic      bool   `json:"is_public"`
+	Collaborative bool   `json:"collaborative"`
+	OwnerID       string `json:"owner_id"`
+	OwnerName     string `json:"owner_name"`
+	Algorithmic   bool   `json:"algorithmic"` // generated by Spotify, e.g. Discover Weekly
+	Followed      bool   `json:"followed"`    // owned by another account, see markFollowedPlaylists
 }
 
 // Spotify API integration
-func fetchSpotifyPlaylists(accessToken string) ([]PlaylistResponse, error) {
-	client := ratelimit.NewRateLimitedHTTPClient(ratelimit.SpotifyService, rateLimiter)
-
-	req, err := http.NewRequest("GET", "https://api.spotify.com/v1/me/playlists?limit=50", nil)
+func (h *Handlers) fetchSpotifyPlaylists(ctx context.Context, accessToken string) ([]PlaylistResponse, error) {
+	items, err := h.Providers.Spotify.MyPlaylists(ctx, accessToken, 50)
 	if err != nil {
-		rateMonitor.RecordRequest(ratelimit.SpotifyService, false, true)
-		return nil, err
-	}
-
-	req.Header.Set("Authorization", "Bearer "+accessToken)
-
-	resp, err := client.Do(req)
-	if err != nil {
-		rateMonitor.RecordRequest(ratelimit.SpotifyService, false, true)
-		return nil, err
-	}
-	defer resp.Body.Close()
-
-	wasRateLimited := resp.StatusCode == http.StatusTooManyRequests
-	rateMonitor.RecordRequest(ratelimit.SpotifyService, wasRateLimited, false)
-
-	if resp.StatusCode != http.StatusOK {
-		return nil, fmt.Errorf("spotify API returned status: %d", resp.StatusCode)
-	}
-
-	var spotifyResponse struct {
-		Items []struct {
-			ID          string `json:"id"`
-			Name        string `json:"name"`
-			Description string `json:"description"`
-			Tracks      struct {
-				Total int `json:"total"`
-			} `json:"tracks"`
-			Images []struct {
-				URL string `json:"url"`
-			} `json:"images"`
-			Public bool `json:"public"`
-		} `json:"items"`
-	}
-
-	if err := json.NewDecoder(resp.Body).Decode(&spotifyResponse); err != nil {
 		return nil, err
 	}
 
 	var playlists []PlaylistResponse
-	for _, item := range spotifyResponse.Items {
+	for _, item := range items {
 		imageURL := ""
 		if len(item.Images) > 0 {
 			imageURL = item.Images[0].URL
 		}
 
 		playlists = append(playlists, PlaylistResponse{
-			ServiceID:   item.ID,
-			Name:        item.Name,
-			Description: item.Description,
-			TrackCount:  item.Tracks.Total,
-			ImageURL:    imageURL,
-			IsPublic:    item.Public,
+			ServiceID:     item.ID,
+			Name:          item.Name,
+			Description:   item.Description,
+			TrackCount:    item.Tracks.Total,
+			ImageURL:      imageURL,
+			IsPublic:      item.Public,
+			Collaborative: item.Collaborative,
+			OwnerID:       item.Owner.ID,
+			OwnerName:     item.Owner.DisplayName,
+			Algorithmic:   isSpotifyAlgorithmicPlaylist(item.Owner.ID, item.Name),
 		})
 	}
 
@@ -212,110 +257,139 @@ func fetchSpotifyPlaylists(accessToken string) ([]PlaylistResponse, error) {
 }
 
 // YouTube API integration
-func fetchYouTubePlaylists(accessToken string) ([]PlaylistResponse, error) {
-	client := ratelimit.NewRateLimitedHTTPClient(ratelimit.YouTubeService, rateLimiter)
-
-	req, err := http.NewRequest("GET", "https://www.googleapis.com/youtube/v3/playlists?part=snippet,contentDetails&mine=true&maxResults=50", nil)
+func (h *Handlers) fetchYouTubePlaylists(ctx context.Context, accessToken string) ([]PlaylistResponse, error) {
+	items, err := h.Providers.YouTube.MyPlaylists(ctx, accessToken, 50)
 	if err != nil {
-		rateMonitor.RecordRequest(ratelimit.YouTubeService, false, true)
 		return nil, err
 	}
 
-	req.Header.Set("Authorization", "Bearer "+accessToken)
-
-	resp, err := client.Do(req)
-	if err != nil {
-		rateMonitor.RecordRequest(ratelimit.YouTubeService, false, true)
-		return nil, err
-	}
-	defer resp.Body.Close()
-
-	wasRateLimited := resp.StatusCode == http.StatusTooManyRequests
-	rateMonitor.RecordRequest(ratelimit.YouTubeService, wasRateLimited, false)
-
-	if resp.StatusCode != http.StatusOK {
-		return nil, fmt.Errorf("youtube API returned status: %d", resp.StatusCode)
-	}
-
-	var youtubeResponse struct {
-		Items []struct {
-			ID      string `json:"id"`
-			Snippet struct {
-				Title       string `json:"title"`
-				Description string `json:"description"`
-				Thumbnails  struct {
-					Default struct {
-						URL string `json:"url"`
-					} `json:"default"`
-				} `json:"thumbnails"`
-			} `json:"snippet"`
-			ContentDetails struct {
-				ItemCount int `json:"itemCount"`
-			} `json:"contentDetails"`
-		} `json:"items"`
-	}
-
-	if err := json.NewDecoder(resp.Body).Decode(&youtubeResponse); err != nil {
-		return nil, err
-	}
-
-	var playlists []PlaylistResponse
-	for _, item := range youtubeResponse.Items {
-		playlists = append(playlists, PlaylistResponse{
+	playlists := youtubeSpecialPlaylistResponses()
+	for _, item := range items {
+		response := PlaylistResponse{
 			ServiceID:   item.ID,
 			Name:        item.Snippet.Title,
 			Description: item.Snippet.Description,
-			TrackCount:  item.ContentDetails.ItemCount,
-			ImageURL:    item.Snippet.Thumbnails.Default.URL,
 			IsPublic:    true, // YouTube doesn't expose this easily in this endpoint
-		})
+			OwnerID:     item.Snippet.ChannelID,
+			OwnerName:   item.Snippet.ChannelTitle,
+		}
+		if item.ContentDetails != nil {
+			response.TrackCount = item.ContentDetails.ItemCount
+		}
+		if item.Snippet.Thumbnails != nil {
+			response.ImageURL = item.Snippet.Thumbnails.Default.URL
+		}
+		playlists = append(playlists, response)
 	}
 
 	return playlists, nil
 }
 
-// storePlaylistsInDatabase saves playlists to the database
-func storePlaylistsInDatabase(userID uint, serviceType string, playlists []PlaylistResponse) {
+// storePlaylistsInDatabase saves playlists to the database with a single
+// batched upsert. When complete is set and nothing was left out, stored
+// playlists missing from the list are removed as deleted on the service.
+func (h *Handlers) storePlaylistsInDatabase(userID uint, serviceType string, playlists []PlaylistResponse, complete bool) {
+	fitted := fitPlaylistStorage(h.DB, userID, serviceType, playlists)
+	complete = complete && len(fitted) == len(playlists)
+	playlists = fitted
+	now := time.Now().Unix()
+	serviceIDs := make([]string, 0, len(playlists))
+	dbPlaylists := make([]database.Playlist, 0, len(playlists))
+
 	for _, playlist := range playlists {
-		var existingPlaylist database.Playlist
-		result := database.DB.Where("user_id = ? AND service_type = ? AND service_id = ?", userID, serviceType, playlist.ServiceID).First(&existingPlaylist)
-
-		dbPlaylist := database.Playlist{
-			UserID:       userID,
-			ServiceType:  serviceType,
-			ServiceID:    playlist.ServiceID,
-			Name:         playlist.Name,
-			Description:  playlist.Description,
-			TrackCount:   playlist.TrackCount,
-			ImageURL:     playlist.ImageURL,
-			IsPublic:     playlist.IsPublic,
-			LastSyncedAt: time.Now().Unix(),
+		serviceIDs = append(serviceIDs, playlist.ServiceID)
+		dbPlaylists = append(dbPlaylists, database.Playlist{
+			UserID:        userID,
+			ServiceType:   serviceType,
+			ServiceID:     playlist.ServiceID,
+			Name:          playlist.Name,
+			Description:   playlist.Description,
+			TrackCount:    playlist.TrackCount,
+			ImageURL:      playlist.ImageURL,
+			IsPublic:      playlist.IsPublic,
+			Collaborative: playlist.Collaborative,
+			OwnerID:       playlist.OwnerID,
+			OwnerName:     playlist.OwnerName,
+			Algorithmic:   playlist.Algorithmic,
+			Followed:      playlist.Followed,
+			LastSyncedAt:  now,
+		})
+	}
+
+	// Note what changed since the last sync, for the weekly digest
+	var stored []database.Playlist
+	h.DB.Where("user_id = ? AND service_type = ?", userID, serviceType).Find(&stored)
+	changes := diffStoredPlaylists(userID, serviceType, stored, playlists, now)
+
+	err := h.DB.Transaction(func(tx *gorm.DB) error {
+		if len(changes) > 0 {
+			if err := tx.CreateInBatches(changes, 100).Error; err != nil {
+				return err
+			}
+		}
+		if len(dbPlaylists) > 0 {
+			// Soft-deleted rows are revived by resetting deleted_at
+			err := tx.Clauses(clause.OnConflict{
+				Columns: []clause.Column{{Name: "user_id"}, {Name: "service_type"}, {Name: "service_id"}},
+				DoUpdates: clause.AssignmentColumns([]string{
+					"name", "description", "track_count", "image_url", "is_public",
+					"collaborative", "owner_id", "owner_name", "algorithmic", "followed",
+					"last_synced_at", "updated_at", "deleted_at",
+				}),
+			}).CreateInBatches(&dbPlaylists, 100).Error
+			if err != nil {
+				return err
+			}
 		}
 
-		if result.Error == gorm.ErrRecordNotFound {
-			// Create new playlist
-			database.DB.Create(&dbPlaylist)
-		} else if result.Error == nil {
-			// Update existing playlist
-			existingPlaylist.Name = dbPlaylist.Name
-			existingPlaylist.Description = dbPlaylist.Description
-			existingPlaylist.TrackCount = dbPlaylist.TrackCount
-			existingPlaylist.ImageURL = dbPlaylist.ImageURL
-			existingPlaylist.IsPublic = dbPlaylist.IsPublic
-			existingPlaylist.LastSyncedAt = dbPlaylist.LastSyncedAt
-			database.DB.Save(&existingPlaylist)
+		// Anything stored but not returned by the service has been deleted there.
+		// A partial or empty list proves nothing, so nothing is removed then.
+		if !complete || len(serviceIDs) == 0 {
+			return nil
+		}
+		result := tx.Where("user_id = ? AND service_type = ? AND service_id NOT IN ?", userID, serviceType, serviceIDs).
+			Delete(&database.Playlist{})
+		if result.Error != nil {
+			return result.Error
+		}
+		if result.RowsAffected > 0 {
+			log.Printf("Removed %d deleted %s playlists for user %d", result.RowsAffected, serviceType, userID)
 		}
+		return nil
+	})
+	if err != nil {
+		log.Printf("Failed to store %s playlists for user %d: %v", serviceType, userID, err)
+		return
 	}
+
 	log.Printf("Stored %d %s playlists for user %d", len(playlists), serviceType, userID)
+	enforcePlaylistStorage(h.DB, userID)
 }
 
 // syncServicePlaylists syncs playlists for a specific service
-func syncServicePlaylists(userID uint, service database.UserService) {
-	playlists, err := fetchPlaylistsFromService(service.ServiceType, service.AccessToken)
+func (h *Handlers) syncServicePlaylists(ctx context.Context, userID uint, service database.UserService) error {
+	if err := h.refreshStoredPlaylists(ctx, userID, service); err != nil {
+		return err
+	}
+	h.autoSyncNewPlaylists(ctx, userID, service.ServiceType)
+	return nil
+}
+
+// refreshStoredPlaylists replaces the stored playlists of a service with every
+// playlist the service currently lists
+func (h *Handlers) refreshStoredPlaylists(ctx context.Context, userID uint, service database.UserService) error {
+	playlists, err := h.fetchPlaylistsFromService(ctx, service.ServiceType, service.AccessToken)
 	if err != nil {
 		log.Printf("Failed to sync %s playlists for user %d: %v", service.ServiceType, userID, err)
-		return
+		return err
+	}
+
+	playlists, capped := capSyncedPlaylists(playlists)
+	if capped {
+		log.Printf("Synced only the first %d %s playlists for user %d (MAX_PLAYLISTS_PER_SYNC)", len(playlists), service.ServiceType, userID)
 	}
 
-	storePlaylistsInDatabase(userID, service.ServiceType, playlists)
+	markFollowedPlaylists(playlists, service)
+	h.storePlaylistsInDatabase(userID, service.ServiceType, playlists, !capped)
+	return nil
 }
@@ -0,0 +1,80 @@
+package handlers
+
+import (
+	"log"
+	"os"
+
+	"server/internal/auth"
+	"server/internal/config"
+	"server/internal/providers"
+	"server/internal/providers/mock"
+	"server/internal/providers/spotify"
+	"server/internal/providers/vcr"
+	"server/internal/providers/youtube"
+	"server/internal/ratelimit"
+)
+
+// Providers is the registry of provider API clients handlers call through
+type Providers struct {
+	Spotify *spotify.Client
+	YouTube *youtube.Client
+
+	// Mock is set when the clients are served by the mock provider, which
+	// also replaces the login and connection OAuth flows
+	Mock *mock.Provider
+
+	// The rate limited HTTP clients behind live calls, the limiter pacing
+	// them and the monitor counting their outcomes
+	SpotifyHTTP *ratelimit.RateLimitedHTTPClient
+	YouTubeHTTP *ratelimit.RateLimitedHTTPClient
+	Limiter     *ratelimit.RateLimiter
+	Monitor     *ratelimit.RateLimitMonitor
+
+	// AppTokens pools app-level credentials for public, read-only requests
+	AppTokens *auth.AppTokenManager
+}
+
+// NewProviders builds the registry PROVIDER_MODE selects: the live APIs
+// through spotifyHTTP and youtubeHTTP, the mock provider ("mock"), or a
+// cassette at PROVIDER_CASSETTE ("record" or "replay"). It registers the
+// response cache, quota and ledger hooks on the HTTP clients, so it must be
+// called before they are used.
+func NewProviders(spotifyHTTP, youtubeHTTP *ratelimit.RateLimitedHTTPClient, limiter *ratelimit.RateLimiter, monitor *ratelimit.RateLimitMonitor, appTokens *auth.AppTokenManager) *Providers {
+	configureProviderCache(spotifyHTTP, youtubeHTTP)
+	countYouTubeQuota(youtubeHTTP)
+	recordAPICalls(ratelimit.SpotifyService, spotifyHTTP)
+	recordAPICalls(ratelimit.YouTubeService, youtubeHTTP)
+
+	var spotifyDoer, youtubeDoer providers.Doer = spotifyHTTP, youtubeHTTP
+	var mockProvider *mock.Provider
+
+	path := config.String("PROVIDER_CASSETTE", "data/provider_cassette.json")
+	switch os.Getenv("PROVIDER_MODE") {
+	case "mock":
+		log.Printf("PROVIDER_MODE=mock: Spotify and YouTube are served from an in-memory catalog")
+		mockProvider = mock.New()
+		spotifyDoer, youtubeDoer = mockProvider, mockProvider
+	case "record":
+		log.Printf("PROVIDER_MODE=record: provider responses are saved to %s", path)
+		cassette := vcr.New(path)
+		spotifyDoer, youtubeDoer = cassette.Record(spotifyHTTP), cassette.Record(youtubeHTTP)
+	case "replay":
+		cassette, err := vcr.Load(path)
+		if err != nil {
+			log.Fatalf("PROVIDER_MODE=replay: failed to load %s: %v", path, err)
+		}
+		log.Printf("PROVIDER_MODE=replay: provider responses are served from %s", path)
+		spotifyDoer, youtubeDoer = cassette.Replay(), cassette.Replay()
+	}
+
+	return &Providers{
+		Spotify:     spotify.New(spotifyDoer, monitorRequests(monitor, ratelimit.SpotifyService)),
+		YouTube:     youtube.New(youtubeDoer, monitorRequests(monitor, ratelimit.YouTubeService)),
+		Mock:        mockProvider,
+		SpotifyHTTP: spotifyHTTP,
+		YouTubeHTTP: youtubeHTTP,
+		Limiter:     limiter,
+		Monitor:     monitor,
+		AppTokens:   appTokens,
+	}
+}
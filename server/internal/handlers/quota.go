@@ -0,0 +1,189 @@
+package handlers
+
+import (
+	"context"
+	"fmt"
+	"math"
+	"net/http"
+	"time"
+
+	"server/internal/database"
+	"server/internal/i18n"
+	"server/internal/middleware"
+	"server/internal/quota"
+	"server/internal/ratelimit"
+
+	"github.com/gin-gonic/gin"
+)
+
+// countYouTubeQuota charges every call client makes against the project's
+// daily YouTube unit budget
+func countYouTubeQuota(client *ratelimit.RateLimitedHTTPClient) {
+	client.OnResponse(func(req *http.Request) {
+		quota.Record("youtube", quota.YouTubeCost(req.Method, req.URL.Path))
+	})
+}
+
+// TransferEstimate predicts the API usage and duration of a transfer
+type TransferEstimate struct {
+	Tracks          int      `json:"tracks"`
+	KnownTracks     int      `json:"known_tracks"` // counterparts already known, no search needed
+	SourceCalls     int      `json:"source_calls"`
+	TargetCalls     int      `json:"target_calls"`
+	QuotaUnits      int      `json:"quota_units"`     // YouTube Data API units
+	QuotaRemaining  int      `json:"quota_remaining"` // YouTube units left today, -1 if unlimited
+	QuotaResetsAt   int64    `json:"quota_resets_at"`
+	DurationSeconds int      `json:"estimated_duration_seconds"`
+	FitsToday       bool     `json:"fits_today"`
+	DaysNeeded      int      `json:"days_needed"`
+	Warnings        []string `json:"warnings,omitempty"`
+}
+
+// EstimateTransfer returns the expected cost of a transfer without starting it
+func (h *Handlers) EstimateTransfer(c *gin.Context) {
+	user, exists := middleware.GetUserFromContext(c)
+	if !exists {
+		i18n.RespondError(c, http.StatusUnauthorized, i18n.CodeUnauthenticated, "")
+		return
+	}
+
+	var req TransferRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		i18n.RespondError(c, http.StatusBadRequest, i18n.CodeInvalidRequest, err.Error())
+		return
+	}
+
+	estimate, status, err := h.estimateTransfer(c.Request.Context(), user.ID, req)
+	if err != nil {
+		c.JSON(status, gin.H{"error": err.Error()})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"estimate": estimate})
+}
+
+// estimateTransfer fetches the source playlist and predicts the calls, YouTube
+// quota units and time the transfer needs. On failure it returns the HTTP status to report.
+func (h *Handlers) estimateTransfer(ctx context.Context, userID uint, req TransferRequest) (TransferEstimate, int, error) {
+	playlistID, err := parsePlaylistReference(req.SourceService, req.SourcePlaylistID)
+	if err != nil {
+		return TransferEstimate{}, http.StatusBadRequest, err
+	}
+
+	var sourceToken string
+	var sourceService database.UserService
+	if err := h.DB.WithContext(ctx).Where("user_id = ? AND service_type = ?", userID, req.SourceService).First(&sourceService).Error; err == nil {
+		if err := h.Tokens.RefreshTokenIfNeeded(&sourceService); err != nil {
+			return TransferEstimate{}, http.StatusBadGateway, fmt.Errorf("Source service token refresh failed: %v", err)
+		}
+		sourceToken = sourceService.AccessToken
+	} else if sourceToken, err = h.appAccessToken(ctx, req.SourceService); err != nil {
+		return TransferEstimate{}, http.StatusBadRequest, fmt.Errorf("Source service not connected")
+	}
+
+	tracks, _, err := h.fetchPlaylistTracks(ctx, req.SourceService, sourceToken, playlistID)
+	if err != nil {
+		return TransferEstimate{}, http.StatusBadGateway, fmt.Errorf("Failed to fetch source playlist: %v", err)
+	}
+
+	tracks = supportedTracks(tracks)
+	known := 0
+	for _, track := range tracks {
+		if _, _, ok := knownTargetTrack(h.DB.WithContext(ctx), userID, track, req.TargetService); ok {
+			known++
+		}
+	}
+
+	market := userMarket(h.DB.WithContext(ctx), userID)
+	return h.buildTransferEstimate(req, len(tracks), known, market), http.StatusOK, nil
+}
+
+// buildTransferEstimate assumes every searched track is found and added, so
+// the estimate is an upper bound
+func (h *Handlers) buildTransferEstimate(req TransferRequest, tracks, known int, market string) TransferEstimate {
+	estimate := h.transferCosts(req, tracks, known, market)
+	estimate.addOutlook("transfer")
+	return estimate
+}
+
+// transferCosts predicts the calls, quota units and time of a transfer
+func (h *Handlers) transferCosts(req TransferRequest, tracks, known int, market string) TransferEstimate {
+	estimate := TransferEstimate{Tracks: tracks, KnownTracks: known}
+	searched := tracks - known
+	calls := map[string]int{}
+
+	// Source: the playlist fetch (plus the playlist name and video details on YouTube)
+	estimate.SourceCalls = 1
+	if req.SourceService == "youtube" {
+		estimate.SourceCalls = 2
+		if youtubeHydrateTracks {
+			estimate.SourceCalls += int(math.Ceil(float64(tracks) / 50))
+		}
+		estimate.QuotaUnits += estimate.SourceCalls * quota.YouTubeReadCost
+	}
+	calls[req.SourceService] += estimate.SourceCalls
+
+	// Target: create the playlist, search unknown tracks, add every track
+	estimate.TargetCalls = 1 + searched + tracks
+	switch req.TargetService {
+	case "youtube":
+		estimate.QuotaUnits += quota.YouTubeWriteCost + searched*quota.YouTubeSearchCost + tracks*quota.YouTubeWriteCost
+		// Age and region restrictions of the results are checked after every search
+		estimate.TargetCalls += searched
+		estimate.QuotaUnits += searched * quota.YouTubeReadCost
+	case "spotify":
+		if market != "" && known > 0 {
+			estimate.TargetCalls += int(math.Ceil(float64(known) / 50))
+		}
+	}
+	calls[req.TargetService] += estimate.TargetCalls
+
+	for service, n := range calls {
+		if rps := h.serviceRequestsPerSecond(service); rps > 0 {
+			estimate.DurationSeconds += int(math.Ceil(float64(n) / rps))
+		}
+	}
+
+	return estimate
+}
+
+// addOutlook fills in whether the estimated quota fits into today's remaining
+// YouTube budget and warns about long or quota-heavy work; what names the work in warnings
+func (estimate *TransferEstimate) addOutlook(what string) {
+	now := time.Now()
+	estimate.QuotaRemaining = quota.Remaining("youtube")
+	estimate.QuotaResetsAt = quota.NextReset(now).Unix()
+	estimate.FitsToday = true
+	estimate.DaysNeeded = 1
+	if estimate.QuotaUnits > 0 && estimate.QuotaRemaining >= 0 && estimate.QuotaUnits > estimate.QuotaRemaining {
+		estimate.FitsToday = false
+		daily := quota.DailyLimit("youtube")
+		estimate.DaysNeeded = 1 + int(math.Ceil(float64(estimate.QuotaUnits-estimate.QuotaRemaining)/float64(daily)))
+		estimate.Warnings = append(estimate.Warnings, fmt.Sprintf(
+			"This %s needs about %d YouTube quota units but only %d remain today; it would take %d days",
+			what, estimate.QuotaUnits, estimate.QuotaRemaining, estimate.DaysNeeded))
+	} else if estimate.QuotaRemaining >= 0 && estimate.QuotaUnits > estimate.QuotaRemaining/2 {
+		estimate.Warnings = append(estimate.Warnings, fmt.Sprintf("This %s uses most of today's remaining YouTube quota", what))
+	}
+	if estimate.DurationSeconds > 3600 {
+		estimate.Warnings = append(estimate.Warnings, fmt.Sprintf("Expected to take about %d minutes", estimate.DurationSeconds/60))
+	}
+}
+
+// serviceRequestsPerSecond returns the current rate limit for a service
+func (h *Handlers) serviceRequestsPerSecond(service string) float64 {
+	stats := h.Providers.Limiter.GetLimiterStats(ratelimit.ServiceType(service))
+	if stats == nil {
+		return 0
+	}
+	limit, _ := stats["limit"].(int)
+	return float64(limit)
+}
+
+// youtubeTrackCost is the quota a YouTube target spends on one track
+func youtubeTrackCost(searched bool) int {
+	if searched {
+		return quota.YouTubeSearchCost + quota.YouTubeReadCost + quota.YouTubeWriteCost
+	}
+	return quota.YouTubeWriteCost
+}
@@ -0,0 +1,186 @@
+package handlers
+
+import (
+	"context"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"net/http"
+	"net/url"
+	"os"
+	"strings"
+	"time"
+
+	"server/internal/config"
+	"server/internal/database"
+	"server/internal/i18n"
+
+	"github.com/gin-gonic/gin"
+	"github.com/golang-jwt/jwt/v5"
+	"gorm.io/gorm/clause"
+)
+
+const (
+	authCodeTTL   = time.Minute
+	loginStateTTL = 10 * time.Minute
+)
+
+var errRedirectNotAllowed = errors.New("redirect URI is not allowed")
+
+// loginState travels through the OAuth provider as the signed state parameter
+type loginState struct {
+	RedirectURI string `json:"redirect_uri"`
+	jwt.RegisteredClaims
+}
+
+func frontendURL() string {
+	return strings.TrimRight(os.Getenv("FRONTEND_URL"), "/")
+}
+
+// resolveRedirect validates a client-supplied redirect URI against the allowed web
+// origins and mobile deep-link schemes, defaulting to the frontend's success page
+func resolveRedirect(raw string) (string, error) {
+	if raw == "" {
+		return frontendURL() + "/auth/success", nil
+	}
+
+	u, err := url.Parse(raw)
+	if err != nil || u.Scheme == "" {
+		return "", errRedirectNotAllowed
+	}
+
+	scheme := strings.ToLower(u.Scheme)
+	if scheme == "http" || scheme == "https" {
+		origin := scheme + "://" + strings.ToLower(u.Host)
+		for _, allowed := range allowedRedirectOrigins() {
+			if origin == allowed {
+				return u.String(), nil
+			}
+		}
+		return "", errRedirectNotAllowed
+	}
+
+	for _, allowed := range config.List("MOBILE_DEEP_LINK_SCHEMES", nil) {
+		if scheme == strings.ToLower(allowed) {
+			return u.String(), nil
+		}
+	}
+	return "", errRedirectNotAllowed
+}
+
+// allowedRedirectOrigins is FRONTEND_URL's origin plus REDIRECT_ALLOWED_ORIGINS
+func allowedRedirectOrigins() []string {
+	var origins []string
+	for _, raw := range append([]string{frontendURL()}, config.List("REDIRECT_ALLOWED_ORIGINS", nil)...) {
+		if u, err := url.Parse(raw); err == nil && u.Host != "" {
+			origins = append(origins, strings.ToLower(u.Scheme+"://"+u.Host))
+		}
+	}
+	return origins
+}
+
+// signLoginState binds the validated redirect to the OAuth round trip
+func signLoginState(redirectURI string) (string, error) {
+	claims := &loginState{
+		RedirectURI: redirectURI,
+		RegisteredClaims: jwt.RegisteredClaims{
+			ExpiresAt: jwt.NewNumericDate(time.Now().Add(loginStateTTL)),
+			IssuedAt:  jwt.NewNumericDate(time.Now()),
+		},
+	}
+	return jwt.NewWithClaims(jwt.SigningMethodHS256, claims).SignedString([]byte(os.Getenv("JWT_SECRET")))
+}
+
+func parseLoginState(state string) (string, error) {
+	claims := &loginState{}
+	token, err := jwt.ParseWithClaims(state, claims, func(token *jwt.Token) (interface{}, error) {
+		return []byte(os.Getenv("JWT_SECRET")), nil
+	}, jwt.WithValidMethods([]string{jwt.SigningMethodHS256.Alg()}))
+	if err != nil || !token.Valid {
+		return "", errors.New("invalid login state")
+	}
+
+	// Re-check in case the allow-list changed while the user was at the provider
+	return resolveRedirect(claims.RedirectURI)
+}
+
+// issueAuthCode stores a single-use code for the user and returns it
+func (h *Handlers) issueAuthCode(ctx context.Context, userID uint) (string, error) {
+	buf := make([]byte, 32)
+	if _, err := rand.Read(buf); err != nil {
+		return "", err
+	}
+	code := hex.EncodeToString(buf)
+
+	authCode := database.AuthCode{
+		CodeHash:  hashAuthCode(code),
+		UserID:    userID,
+		ExpiresAt: time.Now().Add(authCodeTTL).Unix(),
+	}
+	if err := h.DB.WithContext(ctx).Create(&authCode).Error; err != nil {
+		return "", err
+	}
+	return code, nil
+}
+
+func hashAuthCode(code string) string {
+	sum := sha256.Sum256([]byte(code))
+	return hex.EncodeToString(sum[:])
+}
+
+// withQueryParam appends key=value to a redirect URI, keeping any existing query
+func withQueryParam(redirectURI, key, value string) string {
+	u, err := url.Parse(redirectURI)
+	if err != nil {
+		return redirectURI
+	}
+	q := u.Query()
+	q.Set(key, value)
+	u.RawQuery = q.Encode()
+	return u.String()
+}
+
+type AuthExchangeRequest struct {
+	Code string `json:"code" binding:"required"`
+}
+
+// HandleAuthExchange trades a one-time code from the login redirect for a JWT
+func (h *Handlers) HandleAuthExchange(c *gin.Context) {
+	var req AuthExchangeRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		i18n.RespondError(c, http.StatusBadRequest, i18n.CodeInvalidRequest, err.Error())
+		return
+	}
+
+	// Deleting with RETURNING makes the code single-use even under concurrent exchanges
+	var authCode database.AuthCode
+	result := h.DB.WithContext(c.Request.Context()).Clauses(clause.Returning{}).
+		Unscoped().
+		Where("code_hash = ? AND expires_at > ?", hashAuthCode(req.Code), time.Now().Unix()).
+		Delete(&authCode)
+	if result.Error != nil || result.RowsAffected == 0 {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "Invalid or expired code"})
+		return
+	}
+
+	var user database.User
+	if err := h.DB.WithContext(c.Request.Context()).First(&user, authCode.UserID).Error; err != nil {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "Invalid or expired code"})
+		return
+	}
+	if requireSecondFactor(c, user) {
+		return
+	}
+
+	jwtToken, err := h.issueSessionToken(c, user.ID)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to generate token"})
+		return
+	}
+
+	// Expired codes that were never exchanged are swept opportunistically
+	h.DB.WithContext(c.Request.Context()).Unscoped().Where("expires_at <= ?", time.Now().Unix()).Delete(&database.AuthCode{})
+
+	c.JSON(http.StatusOK, gin.H{"token": jwtToken})
+}
@@ -0,0 +1,137 @@
+package handlers
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log"
+	"net"
+	"net/http"
+	"time"
+
+	"server/internal/config"
+	"server/internal/database"
+	"server/internal/providers"
+	"server/internal/ratelimit"
+)
+
+// errReauthRequired means a provider rejected a connection's token and refused to refresh it
+var errReauthRequired = errors.New("service needs to be reconnected")
+
+// errCodeReauthRequired is the Transfer.ErrorCode of transfers stopped by errReauthRequired
+const errCodeReauthRequired = "reauth_required"
+
+// isTransientError reports whether a failed provider call may succeed when
+// retried: throttling, server errors and timeouts. Not found, invalid IDs and
+// an open circuit are permanent for the current attempt.
+func isTransientError(err error) bool {
+	var statusErr *providers.StatusError
+	if errors.As(err, &statusErr) {
+		return statusErr.Status == http.StatusTooManyRequests || statusErr.Status >= 500
+	}
+	if errors.Is(err, ratelimit.ErrRateLimited) || errors.Is(err, context.DeadlineExceeded) {
+		return true
+	}
+	var netErr net.Error
+	return errors.As(err, &netErr) && netErr.Timeout()
+}
+
+// withTrackRetry runs a per-track provider operation, retrying transient
+// failures up to TRACK_RETRY_ATTEMPTS times with exponential backoff. op is
+// given a context on which the HTTP client does not retry, so attempts are not
+// multiplied across layers. onRetry, if set, is told about every retry.
+func withTrackRetry(ctx context.Context, what string, onRetry func(what string, attempt int, err error), op func(ctx context.Context) error) error {
+	return retryTrackOp(ctx, what, onRetry, op, nil)
+}
+
+// withTrackWriteRetry runs a provider write that is not idempotent, such as
+// adding a track. A write that failed without a clear answer (server error,
+// timeout) may have been applied, so it is only retried once landed has re-read
+// the target and found it missing; if that read fails it is not retried.
+func withTrackWriteRetry(ctx context.Context, what string, onRetry func(what string, attempt int, err error), op func(ctx context.Context) error, landed func(ctx context.Context) (bool, error)) error {
+	return retryTrackOp(ctx, what, onRetry, op, landed)
+}
+
+// refusedBeforeApplied reports whether a failed call was turned away by the
+// provider's rate limiting, so it certainly had no effect
+func refusedBeforeApplied(err error) bool {
+	var statusErr *providers.StatusError
+	if errors.As(err, &statusErr) {
+		return statusErr.Status == http.StatusTooManyRequests
+	}
+	return errors.Is(err, ratelimit.ErrRateLimited)
+}
+
+// retryTrackOp is the retry loop behind withTrackRetry and withTrackWriteRetry
+func retryTrackOp(ctx context.Context, what string, onRetry func(what string, attempt int, err error), op func(ctx context.Context) error, landed func(ctx context.Context) (bool, error)) error {
+	attempts := config.Int("TRACK_RETRY_ATTEMPTS", 3)
+	delay := config.Duration("TRACK_RETRY_BASE_DELAY", time.Second)
+	opCtx := ratelimit.WithoutRetries(ctx)
+
+	var err error
+	for attempt := 1; ; attempt++ {
+		err = op(opCtx)
+		if err == nil || attempt > attempts || !isTransientError(err) || ctx.Err() != nil {
+			return err
+		}
+
+		log.Printf("Transient error during %s (retry %d/%d in %v): %v", what, attempt, attempts, delay, err)
+		if onRetry != nil {
+			onRetry(what, attempt, err)
+		}
+		select {
+		case <-ctx.Done():
+			return err
+		case <-time.After(delay):
+		}
+		delay *= 2
+
+		if landed != nil && !refusedBeforeApplied(err) {
+			applied, checkErr := landed(ratelimit.WithoutCache(ctx))
+			if checkErr != nil {
+				log.Printf("Could not check whether %s went through, not retrying: %v", what, checkErr)
+				return err
+			}
+			if applied {
+				return nil
+			}
+		}
+	}
+}
+
+// withTokenRefresh runs a provider call with the connection's token. A 401 means
+// the token was revoked or expired early, so it is refreshed and the call retried
+// once. A refresh that fails transiently is retried with backoff; only when the
+// provider rejects the grant is the connection flagged and errReauthRequired
+// returned.
+func (h *Handlers) withTokenRefresh(ctx context.Context, service *database.UserService, op func() error) error {
+	err := op()
+	var statusErr *providers.StatusError
+	if !errors.As(err, &statusErr) || statusErr.Status != http.StatusUnauthorized {
+		return err
+	}
+
+	log.Printf("%s rejected the token of user %d, refreshing it", service.ServiceType, service.UserID)
+	attempts := config.Int("TOKEN_REFRESH_ATTEMPTS", 3)
+	delay := config.Duration("TRACK_RETRY_BASE_DELAY", time.Second)
+	for attempt := 1; ; attempt++ {
+		refreshErr := h.Tokens.ForceRefreshToken(service)
+		if refreshErr == nil {
+			return op()
+		}
+		if grantRevoked(refreshErr) {
+			h.recordServiceHealth(ctx, service, false, refreshErr)
+			return fmt.Errorf("%w: %v", errReauthRequired, refreshErr)
+		}
+		log.Printf("Refreshing the %s token of user %d failed (attempt %d/%d): %v", service.ServiceType, service.UserID, attempt, attempts, refreshErr)
+		if attempt >= attempts {
+			return err
+		}
+		select {
+		case <-ctx.Done():
+			return err
+		case <-time.After(delay):
+		}
+		delay *= 2
+	}
+}
@@ -0,0 +1,136 @@
+package handlers
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"server/internal/cache"
+	"server/internal/config"
+	"server/internal/providers"
+	"server/internal/ratelimit"
+)
+
+// searchCache keeps provider search results by normalized query, shared by
+// all users since search results don't depend on who asks. Retries, reviews
+// and overlapping playlists then reuse results instead of spending quota.
+var (
+	searchCacheTTL = config.Duration("SEARCH_CACHE_TTL", 6*time.Hour)
+	searchCache    = newSearchCache()
+)
+
+// searchFlight is a provider search in progress that identical searches wait on
+type searchFlight struct {
+	done    chan struct{}
+	results any
+	err     error
+}
+
+// In-flight searches by cache key. Concurrent transfers searching for the same
+// track share one provider request instead of each spending quota on it.
+var (
+	searchFlightsMu   sync.Mutex
+	searchFlights     = make(map[string]*searchFlight)
+	searchesCoalesced atomic.Int64
+)
+
+func newSearchCache() cache.Cache {
+	if searchCacheTTL <= 0 {
+		return nil
+	}
+	maxEntries := config.Int("SEARCH_CACHE_MAX_ENTRIES", 50000)
+	log.Printf("Search result cache enabled (ttl %v, max %d entries)", searchCacheTTL, maxEntries)
+	return cache.NewMemoryCache(maxEntries)
+}
+
+// normalizeSearchQuery folds case and whitespace so equivalent queries share an entry
+func normalizeSearchQuery(query string) string {
+	return strings.Join(strings.Fields(strings.ToLower(query)), " ")
+}
+
+// cachedSearch returns the cached results of a search, running it on a miss.
+// Options that change the results, e.g. the market or result limit, belong in params.
+func cachedSearch[T any](ctx context.Context, service, query string, params []string, search func() ([]T, error)) ([]T, error) {
+	key := fmt.Sprintf("search:%s:%s:%s", service, strings.Join(params, ","), normalizeSearchQuery(query))
+	if searchCache != nil {
+		if data, ok := searchCache.Get(key); ok {
+			var results []T
+			if err := json.Unmarshal(data, &results); err == nil {
+				return results, nil
+			}
+		}
+	}
+
+	results, err := coalescedSearch(ctx, key, search)
+	if err != nil {
+		return nil, err
+	}
+	if searchCache != nil {
+		if data, err := json.Marshal(results); err == nil {
+			searchCache.Set(key, data, searchCacheTTL)
+		}
+	}
+	return results, nil
+}
+
+// coalescedSearch runs search unless an identical one is already in flight, in
+// which case it waits for and shares that search's results, giving up when ctx
+// ends. Only provider outages are shared as errors: a search that failed because
+// of its caller, whether cancelled or refused for its token, is run again rather
+// than shared.
+func coalescedSearch[T any](ctx context.Context, key string, search func() ([]T, error)) ([]T, error) {
+	searchFlightsMu.Lock()
+	if flight, ok := searchFlights[key]; ok {
+		searchFlightsMu.Unlock()
+		select {
+		case <-flight.done:
+		case <-ctx.Done():
+			return nil, ctx.Err()
+		}
+		if flight.err == nil {
+			searchesCoalesced.Add(1)
+			shared, _ := flight.results.([]T)
+			return append([]T(nil), shared...), nil
+		}
+		if !shareableSearchError(flight.err) {
+			return search()
+		}
+		return nil, flight.err
+	}
+	flight := &searchFlight{done: make(chan struct{})}
+	searchFlights[key] = flight
+	searchFlightsMu.Unlock()
+
+	results, err := search()
+	flight.results, flight.err = results, err
+
+	searchFlightsMu.Lock()
+	delete(searchFlights, key)
+	searchFlightsMu.Unlock()
+	close(flight.done)
+
+	return results, err
+}
+
+// shareableSearchError reports whether a failed search would fail the same way
+// for anyone: the provider being down or erroring, not a cancelled caller or a
+// 4xx such as a rejected token
+func shareableSearchError(err error) bool {
+	if errors.Is(err, context.Canceled) || errors.Is(err, context.DeadlineExceeded) {
+		return false
+	}
+	if errors.Is(err, ratelimit.ErrProviderUnavailable) {
+		return true
+	}
+	var statusErr *providers.StatusError
+	if errors.As(err, &statusErr) {
+		return statusErr.Status >= 500
+	}
+	return false
+}
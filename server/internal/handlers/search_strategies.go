@@ -0,0 +1,118 @@
+package handlers
+
+import (
+	"context"
+	"fmt"
+	"log"
+	"slices"
+	"strings"
+
+	"server/internal/config"
+	"server/internal/identity"
+)
+
+// maxSearchLimit is the most results a search may ask either provider for
+const maxSearchLimit = 50
+
+// searchStrategies lists the strategies each target service supports:
+//   - "isrc" looks the recording up by ISRC (Spotify only)
+//   - "fielded" searches the track and artist fields (Spotify only)
+//   - "plain" searches title and artist as free text
+//   - "title_only" searches the title alone
+//   - "musicbrainz" uses a counterpart found through the track's MusicBrainz recording
+var searchStrategies = map[string][]string{
+	"spotify": {"isrc", "fielded", "plain", "title_only", "musicbrainz"},
+	"youtube": {"plain", "title_only", "musicbrainz"},
+}
+
+// providerSearchStrategies is the configured strategy order per target service.
+// Every YouTube strategy after the first may cost another search's quota.
+var providerSearchStrategies = map[string][]string{
+	"spotify": configuredSearchStrategies("spotify", "SPOTIFY_SEARCH_STRATEGIES", []string{"fielded", "plain", "title_only"}),
+	"youtube": configuredSearchStrategies("youtube", "YOUTUBE_SEARCH_STRATEGIES", []string{"plain"}),
+}
+
+// providerSearchLimits is the configured number of candidates requested per search
+var providerSearchLimits = map[string]int{
+	"spotify": min(config.Int("SPOTIFY_SEARCH_LIMIT", 5), maxSearchLimit),
+	"youtube": min(config.Int("YOUTUBE_SEARCH_LIMIT", 5), maxSearchLimit),
+}
+
+// configuredSearchStrategies reads a strategy order from key, dropping strategies
+// the service does not support; def is used when none are left
+func configuredSearchStrategies(service, key string, def []string) []string {
+	var valid []string
+	for _, strategy := range config.List(key, def) {
+		if !slices.Contains(searchStrategies[service], strategy) {
+			log.Printf("Ignoring unsupported %s search strategy %q in %s", service, strategy, key)
+			continue
+		}
+		valid = append(valid, strategy)
+	}
+	if len(valid) == 0 {
+		return def
+	}
+	return valid
+}
+
+// checkSearchOptions validates the search strategies and result limit requested for a transfer
+func checkSearchOptions(targetService string, strategies []string, limit int) error {
+	for i, strategy := range strategies {
+		if !slices.Contains(searchStrategies[targetService], strategy) {
+			return fmt.Errorf("search_strategies must be chosen from: %s", strings.Join(searchStrategies[targetService], ", "))
+		}
+		if slices.Contains(strategies[:i], strategy) {
+			return fmt.Errorf("search_strategies lists %s twice", strategy)
+		}
+	}
+	if limit < 0 || limit > maxSearchLimit {
+		return fmt.Errorf("search_limit must be between 1 and %d, or 0 for the default", maxSearchLimit)
+	}
+	return nil
+}
+
+// splitSearchStrategies reads a stored comma-separated strategy order
+func splitSearchStrategies(stored string) []string {
+	if stored == "" {
+		return nil
+	}
+	return strings.Split(stored, ",")
+}
+
+// strategies returns the order searches on service try: the transfer's own,
+// else the provider's. The isrc_first match strategy puts an ISRC lookup first.
+func (options SearchOptions) strategies(service string) []string {
+	if len(options.Strategies) > 0 {
+		return options.Strategies
+	}
+	strategies := providerSearchStrategies[service]
+	if options.MatchStrategy == "isrc_first" && slices.Contains(searchStrategies[service], "isrc") {
+		strategies = append([]string{"isrc"}, slices.DeleteFunc(slices.Clone(strategies), func(s string) bool { return s == "isrc" })...)
+	}
+	return strategies
+}
+
+// limit returns how many candidates searches on service request
+func (options SearchOptions) limit(service string) int {
+	if options.Limit > 0 {
+		return options.Limit
+	}
+	if limit := providerSearchLimits[service]; limit > 0 {
+		return limit
+	}
+	return 5
+}
+
+// searchMusicBrainzCounterpart resolves the track's canonical recording, which
+// joins tracks sharing a MusicBrainz recording when lookups are enabled, and
+// returns a version of it already known on the target service
+func (h *Handlers) searchMusicBrainzCounterpart(ctx context.Context, track Track, targetService string) (Track, float64, error) {
+	if track.Service == "" || track.ID == "" || resolveTrackIdentity(ctx, h.DB.WithContext(ctx), track) == 0 {
+		return Track{}, 0.0, errTrackNotFound
+	}
+	counterpartID, confidence, ok := identity.Counterpart(h.DB.WithContext(ctx), track.Service, track.ID, targetService)
+	if !ok {
+		return Track{}, 0.0, errTrackNotFound
+	}
+	return Track{ID: counterpartID, Name: track.Name, Artist: track.Artist, Service: targetService}, confidence, nil
+}
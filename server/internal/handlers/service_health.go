@@ -0,0 +1,127 @@
+package handlers
+
+import (
+	"context"
+	"errors"
+	"log"
+	"net/http"
+	"time"
+
+	"server/internal/config"
+	"server/internal/database"
+
+	"golang.org/x/oauth2"
+)
+
+const (
+	// reauthAfterFailedChecks tolerates a provider briefly rejecting a good token
+	reauthAfterFailedChecks = 2
+	// serviceHealthBatchSize spreads validation calls over several passes
+	serviceHealthBatchSize = 200
+)
+
+// StartServiceHealthScheduler validates stored tokens every SERVICE_HEALTH_INTERVAL
+// (default 6h) and flags connections that need the user to reconnect
+func (h *Handlers) StartServiceHealthScheduler(ctx context.Context) {
+	interval := config.Duration("SERVICE_HEALTH_INTERVAL", 6*time.Hour)
+	if interval <= 0 {
+		log.Printf("Service health checks disabled")
+		return
+	}
+	// Mock connections hold tokens the real providers would reject
+	if h.Providers.Mock != nil {
+		log.Printf("Service health checks disabled in mock provider mode")
+		return
+	}
+
+	go func() {
+		ticker := time.NewTicker(time.Hour)
+		defer ticker.Stop()
+
+		for {
+			select {
+			case <-ctx.Done():
+				return
+			case <-ticker.C:
+				h.runScheduled(ctx, "service-health", func() { h.checkDueServices(ctx, interval) })
+			}
+		}
+	}()
+}
+
+// checkDueServices validates active connections not checked within interval
+func (h *Handlers) checkDueServices(ctx context.Context, interval time.Duration) {
+	var services []database.UserService
+	err := h.DB.WithContext(ctx).
+		Where("status <> ? AND last_checked_at < ?", database.ServiceNeedsReauth, time.Now().Add(-interval).Unix()).
+		Order("last_checked_at").Limit(serviceHealthBatchSize).Find(&services).Error
+	if err != nil {
+		log.Printf("Failed to load services for health check: %v", err)
+		return
+	}
+
+	for _, service := range services {
+		if ctx.Err() != nil {
+			return
+		}
+		valid, err := h.Tokens.ValidateToken(&service)
+		h.recordServiceHealth(ctx, &service, valid, err)
+	}
+}
+
+// grantRevoked reports whether a token refresh failed because the provider no
+// longer honours the grant, rather than because its token endpoint was unavailable
+func grantRevoked(err error) bool {
+	var refreshErr *oauth2.RetrieveError
+	if !errors.As(err, &refreshErr) {
+		return false
+	}
+	if refreshErr.ErrorCode == "invalid_grant" {
+		return true
+	}
+	if refreshErr.Response == nil {
+		return false
+	}
+	return refreshErr.Response.StatusCode == http.StatusBadRequest || refreshErr.Response.StatusCode == http.StatusUnauthorized
+}
+
+// recordServiceHealth stores the outcome of a token validation. A refresh the
+// provider refuses means the grant is gone; a token rejected on repeated checks
+// is treated the same. Network errors, rate limits and provider outages say
+// nothing about the token and are ignored.
+func (h *Handlers) recordServiceHealth(ctx context.Context, service *database.UserService, valid bool, err error) {
+	if h.Providers.Mock != nil {
+		return
+	}
+	updates := map[string]interface{}{"last_checked_at": time.Now().Unix()}
+
+	switch {
+	case valid:
+		updates["status"] = database.ServiceActive
+		updates["status_reason"] = ""
+		updates["failed_checks"] = 0
+	case grantRevoked(err):
+		updates["status"] = database.ServiceNeedsReauth
+		updates["status_reason"] = "Access was revoked or has expired"
+	case err == nil:
+		service.FailedChecks++
+		updates["failed_checks"] = service.FailedChecks
+		if service.FailedChecks >= reauthAfterFailedChecks {
+			updates["status"] = database.ServiceNeedsReauth
+			updates["status_reason"] = "The service no longer accepts the stored token"
+		}
+	default:
+		log.Printf("Health check of %s for user %d inconclusive: %v", service.ServiceType, service.UserID, err)
+	}
+
+	if status, ok := updates["status"].(string); ok {
+		if status == database.ServiceNeedsReauth && service.Status != database.ServiceNeedsReauth {
+			log.Printf("%s connection of user %d needs to be reconnected", getServiceDisplayName(service.ServiceType), service.UserID)
+		}
+		service.Status = status
+		service.StatusReason = updates["status_reason"].(string)
+	}
+	if err := h.DB.WithContext(ctx).Model(&database.UserService{}).Where("id = ?", service.ID).Updates(updates).Error; err != nil {
+		log.Printf("Failed to record health of service %d: %v", service.ID, err)
+	}
+}
@@ -4,35 +4,33 @@ import (
 	"context"
 	"encoding/json"
 	"fmt"
-	"io"
 	"log"
 	"net/http"
-	"net/url"
-	"os"
 	"strconv"
-	"strings"
 	"time"
 
 	"server/internal/auth"
 	"server/internal/database"
+	"server/internal/i18n"
 	"server/internal/middleware"
+	"server/internal/providers/spotify"
+	"server/internal/providers/youtube"
+	"server/internal/ratelimit"
 
 	"github.com/gin-gonic/gin"
 	"golang.org/x/oauth2"
 	"gorm.io/gorm"
 )
 
-const (
-	googleRevocationURL  = "https://oauth2.googleapis.com/revoke"
-	spotifyRevocationURL = "https://accounts.spotify.com/api/token"
-)
+// revocationClient is shared by token revocation calls, which bypass the provider rate limiters
+var revocationClient = &http.Client{Timeout: 10 * time.Second}
 
-func HandleConnectService(c *gin.Context) {
+func (h *Handlers) HandleConnectService(c *gin.Context) {
 	provider := c.Param("provider")
 
 	config := auth.GetOAuthConfig(provider)
 	if config == nil {
-		c.JSON(http.StatusBadRequest, gin.H{"error": "Unsupported service provider"})
+		i18n.RespondError(c, http.StatusBadRequest, i18n.CodeUnsupportedProvider, "")
 		return
 	}
 
@@ -45,6 +43,11 @@ func HandleConnectService(c *gin.Context) {
 		}
 	}
 
+	if h.Providers.Mock != nil {
+		h.connectMockService(c, provider, userID)
+		return
+	}
+
 	state := fmt.Sprintf("user-%d", userID)
 
 	var authURL string
@@ -65,7 +68,7 @@ func HandleConnectService(c *gin.Context) {
 	c.Redirect(http.StatusTemporaryRedirect, authURL)
 }
 
-func HandleServiceCallback(c *gin.Context) {
+func (h *Handlers) HandleServiceCallback(c *gin.Context) {
 	provider := c.Param("provider")
 	code := c.Query("code")
 	state := c.Query("state")
@@ -88,14 +91,14 @@ func HandleServiceCallback(c *gin.Context) {
 
 	config := auth.GetOAuthConfig(provider)
 	if config == nil {
-		c.JSON(http.StatusBadRequest, gin.H{"error": "Unsupported service provider"})
+		i18n.RespondError(c, http.StatusBadRequest, i18n.CodeUnsupportedProvider, "")
 		return
 	}
 
 	log.Printf("Exchanging code for %s token", provider)
 
 	// Exchange code for token
-	token, err := config.Exchange(context.Background(), code)
+	token, err := config.Exchange(c.Request.Context(), code)
 	if err != nil {
 		log.Printf("Token exchange error for %s: %v", provider, err)
 		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to exchange token: " + err.Error()})
@@ -104,47 +107,29 @@ func HandleServiceCallback(c *gin.Context) {
 
 	log.Printf("Successfully obtained %s token", provider)
 
-	var serviceUserID, serviceUserName string
+	var serviceUserID, serviceUserName, market string
 
 	// Get user info from the service
 	switch provider {
 	case "spotify":
-		client := config.Client(context.Background(), token)
-		resp, err := client.Get("https://api.spotify.com/v1/me")
+		spotifyUser, err := h.Providers.Spotify.CurrentUser(c.Request.Context(), token.AccessToken)
 		if err != nil {
 			log.Printf("Failed to get Spotify user profile: %v", err)
 			c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to get user profile: " + err.Error()})
 			return
 		}
-		defer resp.Body.Close()
-
-		if resp.StatusCode != http.StatusOK {
-			log.Printf("Spotify API returned status: %d", resp.StatusCode)
-			c.JSON(http.StatusInternalServerError, gin.H{"error": "Spotify API returned non-200 status"})
-			return
-		}
 
-		// Parse Spotify user info
-		var spotifyUser struct {
-			ID          string `json:"id"`
-			DisplayName string `json:"display_name"`
-			Email       string `json:"email"`
-		}
-
-		if err := json.NewDecoder(resp.Body).Decode(&spotifyUser); err != nil {
-			log.Printf("Failed to parse Spotify user info: %v", err)
-		} else {
-			serviceUserID = spotifyUser.ID
-			serviceUserName = spotifyUser.DisplayName
-			if serviceUserName == "" && spotifyUser.Email != "" {
-				serviceUserName = spotifyUser.Email
-			}
-			log.Printf("Spotify user: %s (%s)", serviceUserName, serviceUserID)
+		serviceUserID = spotifyUser.ID
+		serviceUserName = spotifyUser.DisplayName
+		market = spotifyUser.Country
+		if serviceUserName == "" && spotifyUser.Email != "" {
+			serviceUserName = spotifyUser.Email
 		}
+		log.Printf("Spotify user: %s (%s)", serviceUserName, serviceUserID)
 
 	case "youtube":
 		log.Printf("YouTube token obtained: %+v", token)
-		client := config.Client(context.Background(), token)
+		client := config.Client(c.Request.Context(), token)
 
 		// First, try to get basic Google user info (this usually works with any Google scope)
 		userInfoResp, err := client.Get("https://www.googleapis.com/oauth2/v2/userinfo")
@@ -172,37 +157,14 @@ func HandleServiceCallback(c *gin.Context) {
 
 		// Try to get YouTube channel info with the readonly scope
 		// Note: This might fail with youtube.readonly scope, but let's try
-		channelResp, err := client.Get("https://www.googleapis.com/youtube/v3/channels?part=snippet&mine=true")
+		channels, err := h.Providers.YouTube.MyChannels(c.Request.Context(), token.AccessToken)
 		if err != nil {
 			log.Printf("Failed to get YouTube channels (this might be expected with readonly scope): %v", err)
-			// This is expected with youtube.readonly scope, so we don't treat it as an error
-		} else {
-			defer channelResp.Body.Close()
-
-			if channelResp.StatusCode == http.StatusOK {
-				// Parse YouTube channel info
-				var youtubeResponse struct {
-					Items []struct {
-						ID      string `json:"id"`
-						Snippet struct {
-							Title string `json:"title"`
-						} `json:"snippet"`
-					} `json:"items"`
-				}
-
-				if err := json.NewDecoder(channelResp.Body).Decode(&youtubeResponse); err != nil {
-					log.Printf("Failed to parse YouTube response: %v", err)
-				} else if len(youtubeResponse.Items) > 0 {
-					// If we successfully got channel info, use it instead of basic user info
-					serviceUserID = youtubeResponse.Items[0].ID
-					serviceUserName = youtubeResponse.Items[0].Snippet.Title
-					log.Printf("YouTube channel: %s (%s)", serviceUserName, serviceUserID)
-				}
-			} else {
-				body, _ := io.ReadAll(channelResp.Body)
-				log.Printf("YouTube channels API returned status: %d, body: %s", channelResp.StatusCode, string(body))
-				// This is normal with reduced scopes, so we don't treat it as an error
-			}
+		} else if len(channels) > 0 {
+			// If we successfully got channel info, use it instead of basic user info
+			serviceUserID = channels[0].ID
+			serviceUserName = channels[0].Snippet.Title
+			log.Printf("YouTube channel: %s (%s)", serviceUserName, serviceUserID)
 		}
 
 		// If we still don't have user info, set a default
@@ -230,52 +192,89 @@ func HandleServiceCallback(c *gin.Context) {
 		TokenExpiry:     token.Expiry.Unix(),
 		ServiceUserID:   serviceUserID,
 		ServiceUserName: serviceUserName,
+		Market:          market,
 	}
 
+	h.saveServiceConnection(c.Request.Context(), userService)
+
+	// Redirect to frontend with success message
+	redirectURL := fmt.Sprintf("%s/dashboard?message=%s_connected", frontendURL(), provider)
+	c.Redirect(http.StatusTemporaryRedirect, redirectURL)
+}
+
+// saveServiceConnection creates the user's connection to a service or updates the existing one.
+// Reconnecting the same service account, even after a disconnect, reuses its
+// row and restores its stored playlists so tags, archives and history carry over.
+func (h *Handlers) saveServiceConnection(ctx context.Context, userService database.UserService) {
+	provider := userService.ServiceType
+
 	// Check if service already exists for this user
 	var existingService database.UserService
-	result := database.DB.Where("user_id = ? AND service_type = ?", userService.UserID, provider).First(&existingService)
+	result := h.DB.WithContext(ctx).Where("user_id = ? AND service_type = ?", userService.UserID, provider).First(&existingService)
+	if result.Error == gorm.ErrRecordNotFound && userService.ServiceUserID != "" {
+		// A previous connection to this account, disconnected or revoked
+		result = h.DB.WithContext(ctx).Unscoped().
+			Where("user_id = ? AND service_type = ? AND service_user_id = ?", userService.UserID, provider, userService.ServiceUserID).
+			Order("id DESC").First(&existingService)
+	}
 
 	switch result.Error {
 	case gorm.ErrRecordNotFound:
 		// Create new service connection
-		if err := database.DB.Create(&userService).Error; err != nil {
+		if err := h.DB.WithContext(ctx).Create(&userService).Error; err != nil {
 			log.Printf("Failed to create service connection: %v", err)
 		} else {
 			log.Printf("Created new %s service connection for user %d", provider, userService.UserID)
 		}
 	case nil:
+		sameAccount := existingService.ServiceUserID == "" || userService.ServiceUserID == "" ||
+			existingService.ServiceUserID == userService.ServiceUserID
+		disconnectedAt := existingService.DeletedAt
+
 		// Update existing service connection
 		existingService.AccessToken = userService.AccessToken
 		existingService.RefreshToken = userService.RefreshToken
 		existingService.TokenExpiry = userService.TokenExpiry
 		existingService.ServiceUserID = userService.ServiceUserID
 		existingService.ServiceUserName = userService.ServiceUserName
+		existingService.Market = userService.Market
+		existingService.Status = database.ServiceActive
+		existingService.StatusReason = ""
+		existingService.FailedChecks = 0
+		existingService.DeletedAt = gorm.DeletedAt{}
 
-		if err := database.DB.Save(&existingService).Error; err != nil {
+		if err := h.DB.WithContext(ctx).Unscoped().Save(&existingService).Error; err != nil {
 			log.Printf("Failed to update service connection: %v", err)
-		} else {
-			log.Printf("Updated %s service connection for user %d", provider, userService.UserID)
+			return
+		}
+		log.Printf("Updated %s service connection for user %d", provider, userService.UserID)
+
+		switch {
+		case !sameAccount:
+			// Playlists of the previous account don't belong to this one; the next sync stores the new account's
+			h.DB.WithContext(ctx).Where("user_id = ? AND service_type = ?", userService.UserID, provider).Delete(&database.Playlist{})
+			log.Printf("User %d switched %s accounts, cleared stored playlists of the previous account", userService.UserID, provider)
+		case disconnectedAt.Valid:
+			// Only the playlists the disconnect removed, not ones deleted earlier for other reasons
+			h.DB.WithContext(ctx).Unscoped().Model(&database.Playlist{}).
+				Where("user_id = ? AND service_type = ? AND deleted_at >= ?", userService.UserID, provider, disconnectedAt.Time).
+				Update("deleted_at", nil)
+			log.Printf("Restored stored %s playlists of user %d on reconnect", provider, userService.UserID)
 		}
 	}
-
-	// Redirect to frontend with success message
-	frontendURL := os.Getenv("FRONTEND_URL")
-	redirectURL := fmt.Sprintf("%s/dashboard?message=%s_connected", frontendURL, provider)
-	c.Redirect(http.StatusTemporaryRedirect, redirectURL)
 }
 
-func HandleGetConnectedServices(c *gin.Context) {
+func (h *Handlers) HandleGetConnectedServices(c *gin.Context) {
 	// Get user from context
 	user, exists := middleware.GetUserFromContext(c)
 	if !exists {
-		c.JSON(http.StatusUnauthorized, gin.H{"error": "User not authenticated"})
+		i18n.RespondError(c, http.StatusUnauthorized, i18n.CodeUnauthenticated, "")
 		return
 	}
 
 	// Get services for the authenticated user only
 	var services []database.UserService
-	result := database.DB.Where("user_id = ?", user.ID).Find(&services)
+	result := h.DB.WithContext(c.Request.Context()).Where("user_id = ?", user.ID).Find(&services)
 	if result.Error != nil {
 		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to fetch services"})
 		return
@@ -287,10 +286,10 @@ func HandleGetConnectedServices(c *gin.Context) {
 	c.JSON(http.StatusOK, gin.H{"services": services})
 }
 
-func HandleDisconnectService(c *gin.Context) {
+func (h *Handlers) HandleDisconnectService(c *gin.Context) {
 	user, exists := middleware.GetUserFromContext(c)
 	if !exists {
-		c.JSON(http.StatusUnauthorized, gin.H{"error": "User not authenticated"})
+		i18n.RespondError(c, http.StatusUnauthorized, i18n.CodeUnauthenticated, "")
 		return
 	}
 
@@ -298,26 +297,26 @@ func HandleDisconnectService(c *gin.Context) {
 
 	// Validate provider
 	if provider != "spotify" && provider != "youtube" {
-		c.JSON(http.StatusBadRequest, gin.H{"error": "Unsupported service provider"})
+		i18n.RespondError(c, http.StatusBadRequest, i18n.CodeUnsupportedProvider, "")
 		return
 	}
 
 	// Get the service connection first
 	var userService database.UserService
-	result := database.DB.Where("user_id = ? AND service_type = ?", user.ID, provider).First(&userService)
+	result := h.DB.WithContext(c.Request.Context()).Where("user_id = ? AND service_type = ?", user.ID, provider).First(&userService)
 	if result.Error != nil {
 		c.JSON(http.StatusNotFound, gin.H{"error": "Service connection not found"})
 		return
 	}
 
 	// Revoke the token before deleting
-	if err := revokeServiceToken(provider, userService.AccessToken); err != nil {
+	if err := h.revokeServiceToken(c.Request.Context(), provider, userService.AccessToken); err != nil {
 		log.Printf("Failed to revoke token for %s: %v", provider, err)
 		// Continue with deletion even if revocation fails
 	}
 
 	// Delete the service connection
-	result = database.DB.Where("user_id = ? AND service_type = ?", user.ID, provider).Delete(&database.UserService{})
+	result = h.DB.WithContext(c.Request.Context()).Where("user_id = ? AND service_type = ?", user.ID, provider).Delete(&database.UserService{})
 	if result.Error != nil {
 		log.Printf("Failed to delete service connection: %v", result.Error)
 		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to disconnect service"})
@@ -325,7 +324,7 @@ func HandleDisconnectService(c *gin.Context) {
 	}
 
 	// Also delete any playlists associated with this service
-	database.DB.Where("user_id = ? AND service_type = ?", user.ID, provider).Delete(&database.Playlist{})
+	h.DB.WithContext(c.Request.Context()).Where("user_id = ? AND service_type = ?", user.ID, provider).Delete(&database.Playlist{})
 
 	log.Printf("User %d disconnected %s service", user.ID, provider)
 
@@ -334,71 +333,40 @@ func HandleDisconnectService(c *gin.Context) {
 	})
 }
 
-func revokeServiceToken(provider, accessToken string) error {
+func (h *Handlers) revokeServiceToken(ctx context.Context, provider, accessToken string) error {
+	if h.Providers.Mock != nil {
+		return nil
+	}
+
 	switch provider {
 	case "spotify":
-		return revokeSpotifyToken(accessToken)
+		return revokeSpotifyToken(ctx, accessToken)
 	case "youtube":
-		return revokeGoogleToken(accessToken)
+		return revokeGoogleToken(ctx, accessToken)
 	default:
 		return fmt.Errorf("unsupported provider: %s", provider)
 	}
 }
 
-func revokeSpotifyToken(accessToken string) error {
+func revokeSpotifyToken(ctx context.Context, accessToken string) error {
 	config := auth.GetOAuthConfig("spotify")
 	if config == nil {
 		return fmt.Errorf("spotify OAuth config not found")
 	}
 
-	data := url.Values{}
-	data.Set("token", accessToken)
-	data.Set("token_type_hint", "access_token")
-
-	req, err := http.NewRequest("POST", spotifyRevocationURL, strings.NewReader(data.Encode()))
-	if err != nil {
-		return err
-	}
-
-	req.SetBasicAuth(config.ClientID, config.ClientSecret)
-	req.Header.Set("Content-Type", "application/x-www-form-urlencoded")
-
-	client := &http.Client{}
-	resp, err := client.Do(req)
-	if err != nil {
+	if err := spotify.RevokeToken(ctx, revocationClient, config.ClientID, config.ClientSecret, accessToken); err != nil {
 		return err
 	}
-	defer resp.Body.Close()
-
-	if resp.StatusCode != http.StatusOK {
-		return fmt.Errorf("spotify revocation returned status: %d", resp.StatusCode)
-	}
 
 	log.Printf("Successfully revoked Spotify token")
 	return nil
 }
 
-func revokeGoogleToken(accessToken string) error {
-	req, err := http.NewRequest("POST", googleRevocationURL, nil)
-	if err != nil {
+func revokeGoogleToken(ctx context.Context, accessToken string) error {
+	if err := youtube.RevokeToken(ctx, revocationClient, accessToken); err != nil {
 		return err
 	}
 
-	q := req.URL.Query()
-	q.Add("token", accessToken)
-	req.URL.RawQuery = q.Encode()
-
-	client := &http.Client{}
-	resp, err := client.Do(req)
-	if err != nil {
-		return err
-	}
-	defer resp.Body.Close()
-
-	if resp.StatusCode != http.StatusOK {
-		return fmt.Errorf("google revocation returned status: %d", resp.StatusCode)
-	}
-
 	log.Printf("Successfully revoked Google token")
 	return nil
 }
@@ -414,16 +382,16 @@ func getServiceDisplayName(serviceType string) string {
 	}
 }
 
-func HandleTokenHealth(c *gin.Context) {
+func (h *Handlers) HandleTokenHealth(c *gin.Context) {
 	user, exists := middleware.GetUserFromContext(c)
 	if !exists {
-		c.JSON(http.StatusUnauthorized, gin.H{"error": "User not authenticated"})
+		i18n.RespondError(c, http.StatusUnauthorized, i18n.CodeUnauthenticated, "")
 		return
 	}
 
 	// Get all services for the user
 	var services []database.UserService
-	result := database.DB.Where("user_id = ?", user.ID).Find(&services)
+	result := h.DB.WithContext(c.Request.Context()).Where("user_id = ?", user.ID).Find(&services)
 	if result.Error != nil {
 		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to fetch services"})
 		return
@@ -431,7 +399,8 @@ func HandleTokenHealth(c *gin.Context) {
 
 	healthStatus := make(map[string]interface{})
 	for _, service := range services {
-		valid, err := tokenManager.ValidateToken(&service)
+		valid, err := h.Tokens.ValidateToken(&service)
+		h.recordServiceHealth(c.Request.Context(), &service, valid, err)
 		status := "healthy"
 		if err != nil || !valid {
 			status = "unhealthy"
@@ -439,6 +408,7 @@ func HandleTokenHealth(c *gin.Context) {
 
 		healthStatus[service.ServiceType] = map[string]interface{}{
 			"status":     status,
+			"connection": service.Status,
 			"error":      err,
 			"expires_in": time.Until(time.Unix(service.TokenExpiry, 0)).String(),
 		}
@@ -449,22 +419,24 @@ func HandleTokenHealth(c *gin.Context) {
 	})
 }
 
-func HandleRateLimitStatus(c *gin.Context) {
-	metrics := rateMonitor.GetMetrics()
+// AdminRateLimitMetrics reports request, throttling and error counts per provider since startup
+func (h *Handlers) AdminRateLimitMetrics(c *gin.Context) {
+	c.JSON(http.StatusOK, gin.H{"metrics": h.Providers.Monitor.GetMetrics()})
+}
+
+func (h *Handlers) HandleRateLimitStatus(c *gin.Context) {
+	metrics := h.Providers.Monitor.GetMetrics()
 
 	c.JSON(http.StatusOK, gin.H{
 		"rate_limits": metrics,
 		"service_limits": map[string]interface{}{
-			"spotify": map[string]interface{}{
-				"requests_per_second": 10,
-				"burst":               20,
-				"description":         "Spotify Web API rate limits",
-			},
-			"youtube": map[string]interface{}{
-				"requests_per_second": 1,
-				"burst":               5,
-				"description":         "YouTube Data API conservative limits",
-			},
+			"spotify": h.Providers.Limiter.GetLimiterStats(ratelimit.SpotifyService),
+			"youtube": h.Providers.Limiter.GetLimiterStats(ratelimit.YouTubeService),
+		},
+		"cache": map[string]interface{}{
+			"spotify": h.Providers.SpotifyHTTP.CacheStats(),
+			"youtube": h.Providers.YouTubeHTTP.CacheStats(),
 		},
+		"searches_coalesced": searchesCoalesced.Load(),
 	})
 }
@@ -0,0 +1,112 @@
+package handlers
+
+import (
+	"crypto/rand"
+	"encoding/hex"
+	"net/http"
+	"strconv"
+	"time"
+
+	"server/internal/database"
+	"server/internal/i18n"
+	"server/internal/middleware"
+
+	"github.com/gin-gonic/gin"
+)
+
+// maxUserAgentLength keeps stored user agents to a sensible size
+const maxUserAgentLength = 512
+
+// issueSessionToken records a session for the requesting device and returns its JWT
+func (h *Handlers) issueSessionToken(c *gin.Context, userID uint) (string, error) {
+	buf := make([]byte, 16)
+	if _, err := rand.Read(buf); err != nil {
+		return "", err
+	}
+
+	userAgent := c.Request.UserAgent()
+	if len(userAgent) > maxUserAgentLength {
+		userAgent = userAgent[:maxUserAgentLength]
+	}
+	now := time.Now()
+	session := database.Session{
+		UserID:     userID,
+		TokenID:    hex.EncodeToString(buf),
+		UserAgent:  userAgent,
+		IP:         c.ClientIP(),
+		IssuedAt:   now.Unix(),
+		LastUsedAt: now.Unix(),
+		ExpiresAt:  now.Add(sessionTTL).Unix(),
+	}
+	if err := h.DB.WithContext(c.Request.Context()).Create(&session).Error; err != nil {
+		return "", err
+	}
+
+	// Expired sessions are swept opportunistically
+	h.DB.WithContext(c.Request.Context()).Where("user_id = ? AND expires_at <= ?", userID, now.Unix()).Delete(&database.Session{})
+
+	return GenerateJWT(userID, session.TokenID)
+}
+
+// GetSessions lists the user's active sessions, marking the one making the request
+func (h *Handlers) GetSessions(c *gin.Context) {
+	user, exists := middleware.GetUserFromContext(c)
+	if !exists {
+		i18n.RespondError(c, http.StatusUnauthorized, i18n.CodeUnauthenticated, "")
+		return
+	}
+
+	var sessions []database.Session
+	err := h.DB.WithContext(c.Request.Context()).
+		Where("user_id = ? AND revoked_at = 0 AND expires_at > ?", user.ID, time.Now().Unix()).
+		Order("last_used_at DESC").Find(&sessions).Error
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to fetch sessions"})
+		return
+	}
+
+	current := middleware.GetSessionID(c)
+	response := make([]gin.H, 0, len(sessions))
+	for _, session := range sessions {
+		response = append(response, gin.H{
+			"id":           session.ID,
+			"user_agent":   session.UserAgent,
+			"ip":           session.IP,
+			"issued_at":    session.IssuedAt,
+			"last_used_at": session.LastUsedAt,
+			"expires_at":   session.ExpiresAt,
+			"current":      session.TokenID == current,
+		})
+	}
+
+	c.JSON(http.StatusOK, gin.H{"sessions": response})
+}
+
+// RevokeSession signs a device out; its token stops working immediately
+func (h *Handlers) RevokeSession(c *gin.Context) {
+	user, exists := middleware.GetUserFromContext(c)
+	if !exists {
+		i18n.RespondError(c, http.StatusUnauthorized, i18n.CodeUnauthenticated, "")
+		return
+	}
+
+	sessionID, err := strconv.ParseUint(c.Param("id"), 10, 32)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid session ID"})
+		return
+	}
+
+	result := h.DB.WithContext(c.Request.Context()).Model(&database.Session{}).
+		Where("id = ? AND user_id = ? AND revoked_at = 0", sessionID, user.ID).
+		Update("revoked_at", time.Now().Unix())
+	if result.Error != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to revoke session"})
+		return
+	}
+	if result.RowsAffected == 0 {
+		c.JSON(http.StatusNotFound, gin.H{"error": "Session not found"})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"message": "Session revoked"})
+}
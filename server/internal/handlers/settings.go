@@ -0,0 +1,163 @@
+package handlers
+
+import (
+	"context"
+	"log"
+	"net/http"
+	"slices"
+	"strings"
+
+	"server/internal/database"
+	"server/internal/i18n"
+	"server/internal/middleware"
+
+	"github.com/gin-gonic/gin"
+	"gorm.io/gorm"
+)
+
+var (
+	privacyOptions  = []string{"private", "unlisted", "public", "mirror"}
+	matchStrategies = []string{"fuzzy", "isrc_first"}
+	notifierNames   = []string{"discord", "slack", "telegram", "email"}
+)
+
+// SettingsRequest updates only the fields that are present
+type SettingsRequest struct {
+	DefaultPrivacy       *string   `json:"default_privacy"`
+	MinConfidence        *float64  `json:"min_confidence"`
+	NotificationChannels *[]string `json:"notification_channels"` // empty list means all channels
+	MatchStrategy        *string   `json:"match_strategy"`
+	Region               *string   `json:"region"`
+	DescriptionTemplate  *string   `json:"description_template"`
+	WeeklyDigest         *bool     `json:"weekly_digest"`
+}
+
+// loadUserSettings returns the user's settings, or the defaults if none were saved
+func loadUserSettings(db *gorm.DB, userID uint) database.UserSettings {
+	settings := database.UserSettings{
+		UserID:         userID,
+		DefaultPrivacy: "private",
+		MatchStrategy:  "fuzzy",
+	}
+	if err := db.Where("user_id = ?", userID).First(&settings).Error; err != nil && err != gorm.ErrRecordNotFound {
+		log.Printf("Failed to load settings for user %d: %v", userID, err)
+	}
+	return settings
+}
+
+// notificationChannels returns the notifiers the user wants events on, nil for all
+func notificationChannels(settings database.UserSettings) []string {
+	if settings.NotificationChannels == "" {
+		return nil
+	}
+	return strings.Split(settings.NotificationChannels, ",")
+}
+
+func (h *Handlers) settingsResponse(ctx context.Context, settings database.UserSettings) gin.H {
+	channels := notificationChannels(settings)
+	if channels == nil {
+		channels = []string{}
+	}
+
+	return gin.H{
+		"default_privacy":       settings.DefaultPrivacy,
+		"min_confidence":        settings.MinConfidence,
+		"notification_channels": channels,
+		"match_strategy":        settings.MatchStrategy,
+		"region":                settings.Region,
+		"effective_region":      userMarket(h.DB.WithContext(ctx), settings.UserID),
+		"description_template":  settings.DescriptionTemplate,
+		"weekly_digest":         settings.WeeklyDigest,
+	}
+}
+
+// GetSettings returns the user's transfer defaults
+func (h *Handlers) GetSettings(c *gin.Context) {
+	user, exists := middleware.GetUserFromContext(c)
+	if !exists {
+		i18n.RespondError(c, http.StatusUnauthorized, i18n.CodeUnauthenticated, "")
+		return
+	}
+
+	c.JSON(http.StatusOK, h.settingsResponse(c.Request.Context(), loadUserSettings(h.DB.WithContext(c.Request.Context()), user.ID)))
+}
+
+// UpdateSettings validates and saves changes to the user's transfer defaults
+func (h *Handlers) UpdateSettings(c *gin.Context) {
+	user, exists := middleware.GetUserFromContext(c)
+	if !exists {
+		i18n.RespondError(c, http.StatusUnauthorized, i18n.CodeUnauthenticated, "")
+		return
+	}
+
+	var req SettingsRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		i18n.RespondError(c, http.StatusBadRequest, i18n.CodeInvalidRequest, err.Error())
+		return
+	}
+
+	settings := loadUserSettings(h.DB.WithContext(c.Request.Context()), user.ID)
+
+	if req.DefaultPrivacy != nil {
+		if !slices.Contains(privacyOptions, *req.DefaultPrivacy) {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "default_privacy must be one of: " + strings.Join(privacyOptions, ", ")})
+			return
+		}
+		settings.DefaultPrivacy = *req.DefaultPrivacy
+	}
+
+	if req.MinConfidence != nil {
+		if *req.MinConfidence < 0 || *req.MinConfidence > 1 {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "min_confidence must be between 0 and 1"})
+			return
+		}
+		settings.MinConfidence = *req.MinConfidence
+	}
+
+	if req.NotificationChannels != nil {
+		for _, channel := range *req.NotificationChannels {
+			if !slices.Contains(notifierNames, channel) {
+				c.JSON(http.StatusBadRequest, gin.H{"error": "Unknown notification channel: " + channel})
+				return
+			}
+		}
+		settings.NotificationChannels = strings.Join(*req.NotificationChannels, ",")
+	}
+
+	if req.MatchStrategy != nil {
+		if !slices.Contains(matchStrategies, *req.MatchStrategy) {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "match_strategy must be one of: " + strings.Join(matchStrategies, ", ")})
+			return
+		}
+		settings.MatchStrategy = *req.MatchStrategy
+	}
+
+	if req.Region != nil {
+		region := strings.ToUpper(strings.TrimSpace(*req.Region))
+		if region != "" && !marketPattern.MatchString(region) {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "region must be a two-letter country code"})
+			return
+		}
+		settings.Region = region
+	}
+
+	if req.DescriptionTemplate != nil {
+		if len(*req.DescriptionTemplate) > 1000 {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "description_template is too long"})
+			return
+		}
+		settings.DescriptionTemplate = *req.DescriptionTemplate
+	}
+
+	if req.WeeklyDigest != nil {
+		settings.WeeklyDigest = *req.WeeklyDigest
+	}
+
+	if err := h.DB.WithContext(c.Request.Context()).Save(&settings).Error; err != nil {
+		log.Printf("Failed to save settings for user %d: %v", user.ID, err)
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to save settings"})
+		return
+	}
+
+	c.JSON(http.StatusOK, h.settingsResponse(c.Request.Context(), settings))
+}
@@ -0,0 +1,121 @@
+package handlers
+
+import (
+	"net/http"
+	"strings"
+	"time"
+
+	"server/internal/database"
+	"server/internal/i18n"
+	"server/internal/middleware"
+	"server/internal/notifications"
+
+	"github.com/gin-gonic/gin"
+)
+
+const slackWebhookPrefix = "https://hooks.slack.com/"
+
+type SlackIntegrationRequest struct {
+	WebhookURL string `json:"webhook_url" binding:"required"`
+	Channel    string `json:"channel"`
+	Enabled    *bool  `json:"enabled"`
+}
+
+func init() {
+	notifications.Register(notifications.NewSlackNotifier())
+}
+
+// HandleGetSlackIntegration returns the user's Slack notification settings
+func (h *Handlers) HandleGetSlackIntegration(c *gin.Context) {
+	user, exists := middleware.GetUserFromContext(c)
+	if !exists {
+		i18n.RespondError(c, http.StatusUnauthorized, i18n.CodeUnauthenticated, "")
+		return
+	}
+
+	var integration database.SlackIntegration
+	if err := h.DB.WithContext(c.Request.Context()).Where("user_id = ?", user.ID).First(&integration).Error; err != nil {
+		c.JSON(http.StatusOK, gin.H{"configured": false})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"configured": true,
+		"slack":      integration,
+	})
+}
+
+// HandlePutSlackIntegration stores the user's Slack incoming webhook
+func (h *Handlers) HandlePutSlackIntegration(c *gin.Context) {
+	user, exists := middleware.GetUserFromContext(c)
+	if !exists {
+		i18n.RespondError(c, http.StatusUnauthorized, i18n.CodeUnauthenticated, "")
+		return
+	}
+
+	var req SlackIntegrationRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		i18n.RespondError(c, http.StatusBadRequest, i18n.CodeInvalidRequest, err.Error())
+		return
+	}
+
+	// Only Slack webhooks are accepted so the server can't be used to POST to arbitrary URLs
+	if !strings.HasPrefix(req.WebhookURL, slackWebhookPrefix) {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Webhook URL must start with " + slackWebhookPrefix})
+		return
+	}
+
+	var integration database.SlackIntegration
+	h.DB.WithContext(c.Request.Context()).Where("user_id = ?", user.ID).FirstOrInit(&integration, database.SlackIntegration{UserID: user.ID, Enabled: true})
+	integration.WebhookURL = req.WebhookURL
+	integration.Channel = req.Channel
+	if req.Enabled != nil {
+		integration.Enabled = *req.Enabled
+	}
+
+	if err := h.DB.WithContext(c.Request.Context()).Save(&integration).Error; err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to save Slack settings"})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"slack": integration})
+}
+
+// HandleDeleteSlackIntegration removes the user's Slack webhook
+func (h *Handlers) HandleDeleteSlackIntegration(c *gin.Context) {
+	user, exists := middleware.GetUserFromContext(c)
+	if !exists {
+		i18n.RespondError(c, http.StatusUnauthorized, i18n.CodeUnauthenticated, "")
+		return
+	}
+
+	if err := h.DB.WithContext(c.Request.Context()).Where("user_id = ?", user.ID).Delete(&database.SlackIntegration{}).Error; err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to remove Slack settings"})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"message": "Slack notifications removed"})
+}
+
+// HandleTestSlackIntegration sends a test message to the configured webhook
+func (h *Handlers) HandleTestSlackIntegration(c *gin.Context) {
+	user, exists := middleware.GetUserFromContext(c)
+	if !exists {
+		i18n.RespondError(c, http.StatusUnauthorized, i18n.CodeUnauthenticated, "")
+		return
+	}
+
+	var integration database.SlackIntegration
+	if err := h.DB.WithContext(c.Request.Context()).Where("user_id = ?", user.ID).First(&integration).Error; err != nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": "Slack is not configured"})
+		return
+	}
+
+	client := &http.Client{Timeout: 10 * time.Second}
+	if err := notifications.SendSlackMessage(c.Request.Context(), client, integration.WebhookURL, "Playlist Tracker notifications are working :tada:"); err != nil {
+		c.JSON(http.StatusBadGateway, gin.H{"error": "Failed to send test message: " + err.Error()})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"message": "Test message sent"})
+}
@@ -0,0 +1,380 @@
+package handlers
+
+import (
+	"context"
+	"fmt"
+	"log"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+
+	"server/internal/database"
+	"server/internal/i18n"
+	"server/internal/jobs"
+	"server/internal/maintenance"
+	"server/internal/middleware"
+	"server/internal/ratelimit"
+
+	"github.com/gin-gonic/gin"
+	"gorm.io/gorm"
+)
+
+const maxSmartPlaylistTracks = 500
+
+// SmartPlaylistRules selects tracks from the user's stored playlists. Empty
+// fields are ignored; all set fields must match.
+type SmartPlaylistRules struct {
+	Artist          string  `json:"artist"`            // case-insensitive substring
+	Album           string  `json:"album"`             // case-insensitive substring
+	AddedWithinDays int     `json:"added_within_days"` // added to a playlist in the last N days
+	MinTempo        float64 `json:"min_tempo"`         // BPM, Spotify audio features only
+	MaxTempo        float64 `json:"max_tempo"`
+	SourceService   string  `json:"source_service"` // only playlists from this service
+	Tag             string  `json:"tag"`            // only playlists with this tag
+}
+
+type SmartPlaylistRequest struct {
+	Name          string             `json:"name" binding:"required"`
+	Description   string             `json:"description"`
+	TargetService string             `json:"target_service" binding:"required"`
+	Rules         SmartPlaylistRules `json:"rules"`
+	Limit         int                `json:"limit"`
+	Preview       bool               `json:"preview"` // return the matching tracks without creating a playlist
+}
+
+// BuildSmartPlaylist creates a playlist on the target service from stored tracks matching the rules
+func (h *Handlers) BuildSmartPlaylist(c *gin.Context) {
+	user, exists := middleware.GetUserFromContext(c)
+	if !exists {
+		i18n.RespondError(c, http.StatusUnauthorized, i18n.CodeUnauthenticated, "")
+		return
+	}
+
+	var req SmartPlaylistRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		i18n.RespondError(c, http.StatusBadRequest, i18n.CodeInvalidRequest, err.Error())
+		return
+	}
+
+	if req.Limit <= 0 || req.Limit > maxSmartPlaylistTracks {
+		req.Limit = maxSmartPlaylistTracks
+	}
+
+	tracks, err := h.findSmartPlaylistTracks(c.Request.Context(), user.ID, req.Rules, req.Limit)
+	if err != nil {
+		log.Printf("Failed to evaluate smart playlist rules for user %d: %v", user.ID, err)
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to evaluate rules"})
+		return
+	}
+
+	if req.Preview {
+		c.JSON(http.StatusOK, gin.H{"tracks": tracks, "count": len(tracks)})
+		return
+	}
+
+	if len(tracks) == 0 {
+		c.JSON(http.StatusNotFound, gin.H{"error": "No stored tracks match these rules"})
+		return
+	}
+
+	var targetService database.UserService
+	if err := h.DB.WithContext(c.Request.Context()).Where("user_id = ? AND service_type = ?", user.ID, req.TargetService).First(&targetService).Error; err != nil {
+		i18n.RespondError(c, http.StatusBadRequest, i18n.CodeTargetServiceNotConnected, "")
+		return
+	}
+
+	// The tracks of a smart playlist only live in its job, so it can't be held for maintenance
+	if window, ok := maintenance.For(req.TargetService); ok {
+		c.JSON(http.StatusServiceUnavailable, gin.H{"error": maintenanceMessage(window)})
+		return
+	}
+
+	// Smart playlists are tracked as transfers so progress, history and notifications work as usual
+	transfer := database.Transfer{
+		UserID:             user.ID,
+		SourceService:      "smart",
+		SourcePlaylistID:   "smart",
+		SourcePlaylistName: req.Name,
+		TargetService:      req.TargetService,
+		Status:             database.TransferPending,
+	}
+	if err := h.DB.WithContext(c.Request.Context()).Create(&transfer).Error; err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to create transfer record"})
+		return
+	}
+
+	description := req.Description
+	if description == "" {
+		description = "Smart playlist built by sync-playlist"
+	}
+
+	h.Jobs.Enqueue(&jobs.Job{
+		ID:      fmt.Sprintf("transfer-%d", transfer.ID),
+		Type:    "smart_playlist",
+		UserID:  user.ID,
+		Timeout: transferJobTimeout,
+		Run: func(ctx context.Context) error {
+			ctx = ratelimit.WithUser(ctx, user.ID)
+			h.processSmartPlaylist(ctx, transfer, targetService, tracks, req.Name, description)
+			return h.transferJobResult(ctx, transfer.ID)
+		},
+	})
+
+	c.JSON(http.StatusOK, gin.H{
+		"message":     "Smart playlist started",
+		"transfer_id": transfer.ID,
+		"track_count": len(tracks),
+	})
+}
+
+// findSmartPlaylistTracks evaluates the rules against the user's stored tracks, de-duplicated by title and artist
+func (h *Handlers) findSmartPlaylistTracks(ctx context.Context, userID uint, rules SmartPlaylistRules, limit int) ([]Track, error) {
+	query := h.DB.WithContext(ctx).Model(&database.PlaylistTrack{}).
+		Joins("JOIN playlists ON playlists.id = playlist_tracks.playlist_id AND playlists.deleted_at IS NULL").
+		Where("playlists.user_id = ?", userID)
+
+	if rules.Artist != "" {
+		query = query.Where("LOWER(playlist_tracks.artist) LIKE ?", "%"+strings.ToLower(rules.Artist)+"%")
+	}
+	if rules.Album != "" {
+		query = query.Where("LOWER(playlist_tracks.album) LIKE ?", "%"+strings.ToLower(rules.Album)+"%")
+	}
+	if rules.AddedWithinDays > 0 {
+		since := time.Now().AddDate(0, 0, -rules.AddedWithinDays).Unix()
+		query = query.Where("playlist_tracks.added_at >= ?", since)
+	}
+	if rules.MinTempo > 0 {
+		query = query.Where("playlist_tracks.tempo >= ?", rules.MinTempo)
+	}
+	if rules.MaxTempo > 0 {
+		query = query.Where("playlist_tracks.tempo > 0 AND playlist_tracks.tempo <= ?", rules.MaxTempo)
+	}
+	if rules.SourceService != "" {
+		query = query.Where("playlists.service_type = ?", rules.SourceService)
+	}
+	if rules.Tag != "" {
+		query = query.Where("playlists.id IN (?)", h.DB.WithContext(ctx).Model(&database.PlaylistTag{}).
+			Select("playlist_id").Where("user_id = ? AND tag = ?", userID, strings.ToLower(rules.Tag)))
+	}
+
+	var stored []database.PlaylistTrack
+	if err := query.Order("playlist_tracks.added_at DESC").Find(&stored).Error; err != nil {
+		return nil, err
+	}
+
+	seen := make(map[string]bool)
+	tracks := []Track{}
+	for _, st := range stored {
+		// The same recording may be stored from several services; prefer the canonical identity
+		key := strings.ToLower(st.Title) + "\x00" + strings.ToLower(st.Artist)
+		if st.CanonicalTrackID != 0 {
+			key = strconv.FormatUint(uint64(st.CanonicalTrackID), 10)
+		}
+		if seen[key] {
+			continue
+		}
+		seen[key] = true
+
+		tracks = append(tracks, Track{
+			ID:       st.ServiceID,
+			Name:     st.Title,
+			Artist:   st.Artist,
+			Album:    st.Album,
+			Duration: st.Duration,
+			ISRC:     st.ISRC,
+			Service:  st.ServiceType,
+			AddedAt:  st.AddedAt,
+			AddedBy:  st.AddedBy,
+
+			VideoCategory: st.VideoCategory,
+			ChannelID:     st.ChannelID,
+			ChannelTitle:  st.ChannelTitle,
+		})
+		if len(tracks) >= limit {
+			break
+		}
+	}
+
+	return tracks, nil
+}
+
+func (h *Handlers) processSmartPlaylist(ctx context.Context, transfer database.Transfer, targetService database.UserService, tracks []Track, name, description string) {
+	// Status writes must land even once the job is cancelled or times out
+	db := h.DB.WithContext(context.WithoutCancel(ctx)).Session(&gorm.Session{NewDB: true})
+	defer notifyTransferFinished(db, transfer.ID)
+	defer archiveTransferReport(db, transfer.ID)
+
+	if err := h.Tokens.RefreshTokenIfNeeded(&targetService); err != nil {
+		log.Printf("Failed to refresh target token: %v", err)
+		updateTransfer(db, &transfer, map[string]interface{}{
+			"status":        database.TransferFailed,
+			"error_message": "Target service token refresh failed: " + err.Error(),
+		})
+		return
+	}
+
+	setTransferStatus(db, &transfer, database.TransferProcessing)
+	h.copyTracksToNewPlaylist(ctx, db, &transfer, targetService, tracks, name, description, PlaylistCreateOptions{})
+}
+
+// SyncStoredPlaylistTracks refreshes the stored tracks of one playlist so smart playlist rules can use them
+func (h *Handlers) SyncStoredPlaylistTracks(c *gin.Context) {
+	user, exists := middleware.GetUserFromContext(c)
+	if !exists {
+		i18n.RespondError(c, http.StatusUnauthorized, i18n.CodeUnauthenticated, "")
+		return
+	}
+
+	playlistID, err := strconv.ParseUint(c.Param("id"), 10, 32)
+	if err != nil {
+		i18n.RespondError(c, http.StatusBadRequest, i18n.CodeInvalidPlaylistID, "")
+		return
+	}
+
+	var playlist database.Playlist
+	if err := h.DB.WithContext(c.Request.Context()).Where("id = ? AND user_id = ?", playlistID, user.ID).First(&playlist).Error; err != nil {
+		i18n.RespondError(c, http.StatusNotFound, i18n.CodePlaylistNotFound, "")
+		return
+	}
+
+	var service database.UserService
+	if err := h.DB.WithContext(c.Request.Context()).Where("user_id = ? AND service_type = ?", user.ID, playlist.ServiceType).First(&service).Error; err != nil {
+		i18n.RespondError(c, http.StatusBadRequest, i18n.CodeServiceNotConnected, "")
+		return
+	}
+
+	if err := h.Tokens.RefreshTokenIfNeeded(&service); err != nil {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "Token refresh failed, please reconnect " + playlist.ServiceType})
+		return
+	}
+
+	// An unchanged snapshot means the stored tracks are current; skip the full fetch
+	if playlist.ServiceType == "spotify" && playlist.SnapshotID != "" {
+		current, err := h.Providers.Spotify.Playlist(c.Request.Context(), service.AccessToken, playlist.ServiceID, "snapshot_id")
+		if err == nil && current.SnapshotID == playlist.SnapshotID {
+			var trackCount int64
+			h.DB.WithContext(c.Request.Context()).Model(&database.PlaylistTrack{}).Where("playlist_id = ?", playlist.ID).Count(&trackCount)
+			c.JSON(http.StatusOK, gin.H{
+				"message":     "Playlist tracks unchanged",
+				"playlist_id": playlist.ID,
+				"track_count": trackCount,
+				"unchanged":   true,
+			})
+			return
+		}
+	}
+
+	tracks, source, err := h.fetchPlaylistTracks(c.Request.Context(), playlist.ServiceType, service.AccessToken, playlist.ServiceID)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to fetch playlist tracks: " + err.Error()})
+		return
+	}
+
+	h.snapshotPlaylistTracks(c.Request.Context(), h.DB.WithContext(c.Request.Context()), user.ID, service, playlist.ServiceID, source.SnapshotID, tracks)
+
+	c.JSON(http.StatusOK, gin.H{
+		"message":     "Playlist tracks synced",
+		"playlist_id": playlist.ID,
+		"track_count": len(tracks),
+	})
+}
+
+// snapshotPlaylistTracks replaces the stored tracks of a playlist the user has
+// synced. Spotify tracks are enriched with tempo from the audio features API.
+// snapshotID is the Spotify version the tracks were read at; when the stored
+// tracks already came from it, nothing is rewritten.
+func (h *Handlers) snapshotPlaylistTracks(ctx context.Context, db *gorm.DB, userID uint, service database.UserService, playlistServiceID, snapshotID string, tracks []Track) {
+	var playlist database.Playlist
+	err := db.Where("user_id = ? AND service_type = ? AND service_id = ?", userID, service.ServiceType, playlistServiceID).
+		First(&playlist).Error
+	if err != nil {
+		// Only playlists that were synced to the database keep a track snapshot
+		return
+	}
+	if snapshotID != "" && snapshotID == playlist.SnapshotID {
+		return
+	}
+
+	tempos := map[string]float64{}
+	if service.ServiceType == "spotify" {
+		tempos = h.fetchSpotifyTempos(ctx, h.lookupToken(ctx, "spotify", service.AccessToken), tracks)
+	}
+
+	stored := make([]database.PlaylistTrack, 0, len(tracks))
+	for _, track := range tracks {
+		if track.ID == "" {
+			continue
+		}
+		stored = append(stored, database.PlaylistTrack{
+			PlaylistID:  playlist.ID,
+			ServiceType: service.ServiceType,
+			ServiceID:   track.ID,
+			Title:       track.Name,
+			Artist:      track.Artist,
+			Album:       track.Album,
+			Duration:    track.Duration,
+			ISRC:        track.ISRC,
+			AddedAt:     track.AddedAt,
+			AddedBy:     track.AddedBy,
+			Tempo:       tempos[track.ID],
+
+			VideoCategory: track.VideoCategory,
+			ChannelID:     track.ChannelID,
+			ChannelTitle:  track.ChannelTitle,
+
+			CanonicalTrackID: resolveTrackIdentity(ctx, db, track),
+		})
+	}
+
+	if err := reserveTrackStorage(db, userID, playlist.ID, len(stored)); err != nil {
+		log.Printf("Not storing tracks for playlist %d: %v", playlist.ID, err)
+		return
+	}
+
+	err = db.Transaction(func(tx *gorm.DB) error {
+		if err := tx.Unscoped().Where("playlist_id = ?", playlist.ID).Delete(&database.PlaylistTrack{}).Error; err != nil {
+			return err
+		}
+		if len(stored) > 0 {
+			if err := tx.CreateInBatches(stored, 100).Error; err != nil {
+				return err
+			}
+		}
+		return tx.Model(&playlist).Update("snapshot_id", snapshotID).Error
+	})
+	if err != nil {
+		log.Printf("Failed to store tracks for playlist %d: %v", playlist.ID, err)
+	}
+}
+
+// fetchSpotifyTempos looks up the tempo of Spotify tracks, 100 IDs per request.
+// Failures are logged and leave the tempo unknown.
+func (h *Handlers) fetchSpotifyTempos(ctx context.Context, accessToken string, tracks []Track) map[string]float64 {
+	tempos := make(map[string]float64)
+
+	var ids []string
+	for _, track := range tracks {
+		if track.ID != "" {
+			ids = append(ids, track.ID)
+		}
+	}
+
+	for start := 0; start < len(ids); start += 100 {
+		end := min(start+100, len(ids))
+
+		features, err := h.Providers.Spotify.AudioFeatures(ctx, accessToken, ids[start:end])
+		if err != nil {
+			log.Printf("Failed to fetch Spotify audio features: %v", err)
+			return tempos
+		}
+
+		for _, f := range features {
+			if f != nil {
+				tempos[f.ID] = f.Tempo
+			}
+		}
+	}
+
+	return tempos
+}
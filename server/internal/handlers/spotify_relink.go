@@ -0,0 +1,54 @@
+package handlers
+
+import (
+	"context"
+	"log"
+)
+
+// spotifyRelink is the playable version of a track in a market, with its Spotify metadata
+type spotifyRelink struct {
+	ID       string
+	Playable bool
+	Name     string
+	Artist   string
+	ISRC     string
+}
+
+// fetchSpotifyRelinks looks tracks up 50 per request. In a market Spotify
+// relinks tracks that are region-locked there to an equivalent playable release
+// and reports the original ID in linked_from; without one every track counts as
+// playable. The result is keyed by the ID asked for and leaves out unknown IDs.
+func (h *Handlers) fetchSpotifyRelinks(ctx context.Context, accessToken string, ids []string, market string) (map[string]spotifyRelink, error) {
+	relinks := make(map[string]spotifyRelink)
+
+	for start := 0; start < len(ids); start += 50 {
+		end := min(start+50, len(ids))
+
+		tracks, err := h.Providers.Spotify.Tracks(ctx, accessToken, ids[start:end], market)
+		if err != nil {
+			return relinks, err
+		}
+
+		// Results come back in request order, with null for unknown IDs
+		for i, track := range tracks {
+			if track == nil || start+i >= end {
+				continue
+			}
+
+			requested := ids[start+i]
+			relink := spotifyRelink{
+				ID:       track.ID,
+				Playable: track.IsPlayable == nil || *track.IsPlayable,
+				Name:     track.Name,
+				Artist:   track.FirstArtist(),
+				ISRC:     track.ExternalIDs.ISRC,
+			}
+			if track.LinkedFrom != nil && track.ID != requested {
+				log.Printf("Spotify relinked track %s to %s in %s", requested, track.ID, market)
+			}
+			relinks[requested] = relink
+		}
+	}
+
+	return relinks, nil
+}
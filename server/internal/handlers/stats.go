@@ -0,0 +1,176 @@
+package handlers
+
+import (
+	"net/http"
+	"sync"
+	"time"
+
+	"server/internal/database"
+	"server/internal/i18n"
+	"server/internal/middleware"
+
+	"github.com/gin-gonic/gin"
+)
+
+type transferTotals struct {
+	Transfers     int64 `json:"transfers"`
+	TracksTotal   int64 `json:"tracks_total"`
+	TracksMatched int64 `json:"tracks_matched"`
+	TracksFailed  int64 `json:"tracks_failed"`
+}
+
+// matchRate returns matched tracks as a fraction of all processed tracks
+func (t transferTotals) matchRate() float64 {
+	if t.TracksMatched+t.TracksFailed == 0 {
+		return 0
+	}
+	return float64(t.TracksMatched) / float64(t.TracksMatched+t.TracksFailed)
+}
+
+// GetUserStats returns transfer totals, match rates and failure hot spots for the user
+func (h *Handlers) GetUserStats(c *gin.Context) {
+	user, exists := middleware.GetUserFromContext(c)
+	if !exists {
+		i18n.RespondError(c, http.StatusUnauthorized, i18n.CodeUnauthenticated, "")
+		return
+	}
+
+	var totals transferTotals
+	err := h.DB.WithContext(c.Request.Context()).Model(&database.Transfer{}).
+		Select("COUNT(*) AS transfers, COALESCE(SUM(tracks_total), 0) AS tracks_total, "+
+			"COALESCE(SUM(tracks_matched), 0) AS tracks_matched, COALESCE(SUM(tracks_failed), 0) AS tracks_failed").
+		Where("user_id = ?", user.ID).
+		Scan(&totals).Error
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to fetch stats"})
+		return
+	}
+
+	var statuses []struct {
+		Status string `json:"status"`
+		Count  int64  `json:"count"`
+	}
+	h.DB.WithContext(c.Request.Context()).Model(&database.Transfer{}).
+		Select("status, COUNT(*) AS count").
+		Where("user_id = ?", user.ID).
+		Group("status").
+		Scan(&statuses)
+
+	var providers []struct {
+		SourceService string  `json:"source_service"`
+		TargetService string  `json:"target_service"`
+		Transfers     int64   `json:"transfers"`
+		TracksTotal   int64   `json:"tracks_total"`
+		TracksMatched int64   `json:"tracks_matched"`
+		TracksFailed  int64   `json:"tracks_failed"`
+		MatchRate     float64 `json:"match_rate"`
+	}
+	h.DB.WithContext(c.Request.Context()).Model(&database.Transfer{}).
+		Select("source_service, target_service, COUNT(*) AS transfers, COALESCE(SUM(tracks_total), 0) AS tracks_total, "+
+			"COALESCE(SUM(tracks_matched), 0) AS tracks_matched, COALESCE(SUM(tracks_failed), 0) AS tracks_failed").
+		Where("user_id = ?", user.ID).
+		Group("source_service, target_service").
+		Order("transfers DESC").
+		Scan(&providers)
+	for i := range providers {
+		p := &providers[i]
+		p.MatchRate = transferTotals{TracksMatched: p.TracksMatched, TracksFailed: p.TracksFailed}.matchRate()
+	}
+
+	var failedArtists []struct {
+		Artist string `json:"artist"`
+		Failed int64  `json:"failed"`
+	}
+	h.DB.WithContext(c.Request.Context()).Model(&database.TransferTrack{}).
+		Select("transfer_tracks.source_artist AS artist, COUNT(*) AS failed").
+		Joins("JOIN transfers ON transfers.id = transfer_tracks.transfer_id").
+		Where("transfers.user_id = ? AND transfer_tracks.status NOT IN ? AND transfer_tracks.source_artist <> ''", user.ID, []string{"matched", "skipped_by_rule", "unsupported_source_track", "skipped_episode"}).
+		Group("transfer_tracks.source_artist").
+		Order("failed DESC").
+		Limit(10).
+		Scan(&failedArtists)
+
+	c.JSON(http.StatusOK, gin.H{
+		"transfers_run":       totals.Transfers,
+		"tracks_total":        totals.TracksTotal,
+		"tracks_moved":        totals.TracksMatched,
+		"tracks_failed":       totals.TracksFailed,
+		"average_match_rate":  totals.matchRate(),
+		"status_breakdown":    statuses,
+		"provider_breakdown":  providers,
+		"most_failed_artists": failedArtists,
+	})
+}
+
+// Public aggregate stats are cached since the endpoint is unauthenticated
+const globalStatsTTL = 5 * time.Minute
+
+var globalStatsCache struct {
+	data      gin.H
+	expiresAt time.Time
+	mu        sync.Mutex
+}
+
+type StatsSharingRequest struct {
+	Enabled bool `json:"enabled"`
+}
+
+// SetStatsSharing opts the user in or out of the anonymous aggregate stats
+func (h *Handlers) SetStatsSharing(c *gin.Context) {
+	user, exists := middleware.GetUserFromContext(c)
+	if !exists {
+		i18n.RespondError(c, http.StatusUnauthorized, i18n.CodeUnauthenticated, "")
+		return
+	}
+
+	var req StatsSharingRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		i18n.RespondError(c, http.StatusBadRequest, i18n.CodeInvalidRequest, err.Error())
+		return
+	}
+
+	if err := h.DB.WithContext(c.Request.Context()).Model(&database.User{}).Where("id = ?", user.ID).Update("share_anonymous_stats", req.Enabled).Error; err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to update stats sharing"})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"share_anonymous_stats": req.Enabled})
+}
+
+// GetGlobalStats returns anonymous totals across users who opted in. No per-user data is exposed.
+func (h *Handlers) GetGlobalStats(c *gin.Context) {
+	globalStatsCache.mu.Lock()
+	defer globalStatsCache.mu.Unlock()
+
+	if globalStatsCache.data != nil && time.Now().Before(globalStatsCache.expiresAt) {
+		c.JSON(http.StatusOK, globalStatsCache.data)
+		return
+	}
+
+	optedIn := h.DB.WithContext(c.Request.Context()).Model(&database.User{}).Select("id").Where("share_anonymous_stats = ?", true)
+
+	var totals transferTotals
+	err := h.DB.WithContext(c.Request.Context()).Model(&database.Transfer{}).
+		Select("COUNT(*) AS transfers, COALESCE(SUM(tracks_total), 0) AS tracks_total, "+
+			"COALESCE(SUM(tracks_matched), 0) AS tracks_matched, COALESCE(SUM(tracks_failed), 0) AS tracks_failed").
+		Where("user_id IN (?)", optedIn).
+		Scan(&totals).Error
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to fetch stats"})
+		return
+	}
+
+	var contributors int64
+	h.DB.WithContext(c.Request.Context()).Model(&database.User{}).Where("share_anonymous_stats = ?", true).Count(&contributors)
+
+	globalStatsCache.data = gin.H{
+		"contributors":       contributors,
+		"transfers_run":      totals.Transfers,
+		"tracks_transferred": totals.TracksMatched,
+		"overall_match_rate": totals.matchRate(),
+		"generated_at":       time.Now().UTC(),
+	}
+	globalStatsCache.expiresAt = time.Now().Add(globalStatsTTL)
+
+	c.JSON(http.StatusOK, globalStatsCache.data)
+}
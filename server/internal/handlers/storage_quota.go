@@ -0,0 +1,189 @@
+package handlers
+
+import (
+	"errors"
+	"fmt"
+	"log"
+
+	"server/internal/config"
+	"server/internal/database"
+
+	"gorm.io/gorm"
+)
+
+// Per-user storage quotas for public instances; 0 disables a quota. In the
+// default "cleanup" mode the user's oldest data is removed to make room, in
+// "reject" mode new data is refused instead.
+var (
+	storageMaxPlaylists = config.Int("STORAGE_MAX_PLAYLISTS_PER_USER", 0)
+	storageMaxTracks    = config.Int("STORAGE_MAX_TRACKS_PER_USER", 0)
+	storageMaxTransfers = config.Int("STORAGE_MAX_TRANSFERS_PER_USER", 0)
+	storageQuotaMode    = config.String("STORAGE_QUOTA_MODE", "cleanup")
+)
+
+// errStorageQuotaExceeded is reported with 402 Payment Required
+var errStorageQuotaExceeded = errors.New("Storage quota exceeded")
+
+// StorageUsage is what a user has stored against their quotas
+type StorageUsage struct {
+	Playlists    int64  `json:"playlists"`
+	Tracks       int64  `json:"tracks"`
+	Transfers    int64  `json:"transfers"`
+	MaxPlaylists int    `json:"max_playlists"` // 0 = unlimited
+	MaxTracks    int    `json:"max_tracks"`
+	MaxTransfers int    `json:"max_transfers"`
+	Mode         string `json:"mode"` // "cleanup" or "reject"
+}
+
+func storageUsage(db *gorm.DB, userID uint) StorageUsage {
+	usage := StorageUsage{
+		MaxPlaylists: storageMaxPlaylists,
+		MaxTracks:    storageMaxTracks,
+		MaxTransfers: storageMaxTransfers,
+		Mode:         storageQuotaMode,
+	}
+	db.Model(&database.Playlist{}).Where("user_id = ?", userID).Count(&usage.Playlists)
+	db.Model(&database.PlaylistTrack{}).Where("playlist_id IN (?)", userPlaylistIDs(db, userID)).Count(&usage.Tracks)
+	db.Model(&database.Transfer{}).Where("user_id = ?", userID).Count(&usage.Transfers)
+	return usage
+}
+
+func userPlaylistIDs(db *gorm.DB, userID uint) *gorm.DB {
+	return db.Model(&database.Playlist{}).Select("id").Where("user_id = ?", userID)
+}
+
+// reserveTransferStorage makes room for one more transfer in the user's history.
+// Only finished transfers are ever cleaned up.
+func reserveTransferStorage(db *gorm.DB, userID uint) error {
+	if storageMaxTransfers <= 0 {
+		return nil
+	}
+	var count int64
+	db.Model(&database.Transfer{}).Where("user_id = ?", userID).Count(&count)
+	excess := int(count) - storageMaxTransfers + 1
+	if excess <= 0 {
+		return nil
+	}
+	if storageQuotaMode == "reject" {
+		return fmt.Errorf("%w: %d of %d transfers stored", errStorageQuotaExceeded, count, storageMaxTransfers)
+	}
+
+	var ids []uint
+	db.Model(&database.Transfer{}).
+		Where("user_id = ? AND status NOT IN ? AND status <> ?", userID, database.ActiveTransferStatuses, database.TransferPaused).
+		Order("id").Limit(excess).Pluck("id", &ids)
+	if len(ids) < excess {
+		return fmt.Errorf("%w: %d of %d transfers stored and the rest are still running", errStorageQuotaExceeded, count, storageMaxTransfers)
+	}
+
+	err := db.Transaction(func(tx *gorm.DB) error {
+		for _, model := range []interface{}{&database.TransferTrack{}, &database.TransferEvent{}, &database.TransferChunk{}} {
+			if err := tx.Unscoped().Where("transfer_id IN ?", ids).Delete(model).Error; err != nil {
+				return err
+			}
+		}
+		return tx.Unscoped().Where("id IN ?", ids).Delete(&database.Transfer{}).Error
+	})
+	if err != nil {
+		return err
+	}
+	log.Printf("Removed %d old transfers of user %d to stay within the storage quota", len(ids), userID)
+	return nil
+}
+
+// fitPlaylistStorage limits the playlists a sync of one service may store.
+// In reject mode playlists beyond the quota are left out; in cleanup mode
+// all are kept and enforcePlaylistStorage trims the oldest afterwards.
+func fitPlaylistStorage(db *gorm.DB, userID uint, serviceType string, playlists []PlaylistResponse) []PlaylistResponse {
+	if storageMaxPlaylists <= 0 || storageQuotaMode != "reject" {
+		return playlists
+	}
+	var others int64
+	db.Model(&database.Playlist{}).Where("user_id = ? AND service_type <> ?", userID, serviceType).Count(&others)
+	room := max(storageMaxPlaylists-int(others), 0)
+	if len(playlists) > room {
+		log.Printf("Storage quota: keeping %d of %d %s playlists for user %d", room, len(playlists), serviceType, userID)
+		return playlists[:room]
+	}
+	return playlists
+}
+
+// enforcePlaylistStorage removes the least recently synced playlists, with
+// their tracks and tags, while the user is over the playlist quota
+func enforcePlaylistStorage(db *gorm.DB, userID uint) {
+	if storageMaxPlaylists <= 0 {
+		return
+	}
+	var count int64
+	db.Model(&database.Playlist{}).Where("user_id = ?", userID).Count(&count)
+	excess := int(count) - storageMaxPlaylists
+	if excess <= 0 {
+		return
+	}
+
+	var ids []uint
+	db.Model(&database.Playlist{}).Where("user_id = ?", userID).
+		Order("last_synced_at, id").Limit(excess).Pluck("id", &ids)
+	if err := deleteStoredPlaylists(db, ids); err != nil {
+		log.Printf("Failed to clean up playlists of user %d: %v", userID, err)
+		return
+	}
+	log.Printf("Removed %d old playlists of user %d to stay within the storage quota", len(ids), userID)
+}
+
+// reserveTrackStorage makes room for a playlist's track snapshot of size tracks,
+// removing the snapshots of the least recently synced other playlists in
+// cleanup mode
+func reserveTrackStorage(db *gorm.DB, userID, playlistID uint, tracks int) error {
+	if storageMaxTracks <= 0 {
+		return nil
+	}
+	if tracks > storageMaxTracks {
+		return fmt.Errorf("%w: playlist has %d tracks, %d may be stored", errStorageQuotaExceeded, tracks, storageMaxTracks)
+	}
+
+	others := db.Model(&database.PlaylistTrack{}).
+		Where("playlist_id IN (?) AND playlist_id <> ?", userPlaylistIDs(db, userID), playlistID)
+	var stored int64
+	others.Count(&stored)
+	if int(stored)+tracks <= storageMaxTracks {
+		return nil
+	}
+	if storageQuotaMode == "reject" {
+		return fmt.Errorf("%w: %d of %d tracks stored", errStorageQuotaExceeded, stored, storageMaxTracks)
+	}
+
+	var candidates []database.Playlist
+	db.Where("user_id = ? AND id <> ?", userID, playlistID).Order("last_synced_at, id").Find(&candidates)
+	for _, playlist := range candidates {
+		var n int64
+		db.Model(&database.PlaylistTrack{}).Where("playlist_id = ?", playlist.ID).Count(&n)
+		if n == 0 {
+			continue
+		}
+		if err := db.Unscoped().Where("playlist_id = ?", playlist.ID).Delete(&database.PlaylistTrack{}).Error; err != nil {
+			return err
+		}
+		log.Printf("Dropped the track snapshot of playlist %d to stay within the storage quota", playlist.ID)
+		stored -= n
+		if int(stored)+tracks <= storageMaxTracks {
+			return nil
+		}
+	}
+	return fmt.Errorf("%w: %d of %d tracks stored", errStorageQuotaExceeded, stored, storageMaxTracks)
+}
+
+// deleteStoredPlaylists permanently removes stored playlists with their tracks and tags
+func deleteStoredPlaylists(db *gorm.DB, ids []uint) error {
+	if len(ids) == 0 {
+		return nil
+	}
+	return db.Transaction(func(tx *gorm.DB) error {
+		for _, model := range []interface{}{&database.PlaylistTrack{}, &database.PlaylistTag{}} {
+			if err := tx.Unscoped().Where("playlist_id IN ?", ids).Delete(model).Error; err != nil {
+				return err
+			}
+		}
+		return tx.Unscoped().Where("id IN ?", ids).Delete(&database.Playlist{}).Error
+	})
+}
@@ -0,0 +1,368 @@
+package handlers
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log"
+	"net/http"
+	"time"
+
+	"server/internal/database"
+	"server/internal/i18n"
+	"server/internal/jobs"
+	"server/internal/middleware"
+
+	"github.com/gin-gonic/gin"
+	"gorm.io/gorm"
+)
+
+var errSyncConflicts = errors.New("both playlists changed since the last sync; resolve the sync conflicts to continue")
+
+type SyncConflictDecision struct {
+	ID       uint   `json:"id"`
+	Decision string `json:"decision"`
+}
+
+type ResolveSyncConflictsRequest struct {
+	Decision  string                 `json:"decision"`  // applied to every conflict without its own decision
+	Decisions []SyncConflictDecision `json:"decisions"` // per conflict
+}
+
+func validSyncDecision(decision string) bool {
+	switch decision {
+	case database.SyncKeepBoth, database.SyncPreferSource, database.SyncPreferTarget:
+		return true
+	}
+	return false
+}
+
+// GetSyncConflicts lists the changes of a two-way link waiting for a decision
+func (h *Handlers) GetSyncConflicts(c *gin.Context) {
+	user, exists := middleware.GetUserFromContext(c)
+	if !exists {
+		i18n.RespondError(c, http.StatusUnauthorized, i18n.CodeUnauthenticated, "")
+		return
+	}
+
+	link, ok := h.userSyncLinkFromParam(c, user.ID)
+	if !ok {
+		return
+	}
+
+	var conflicts []database.SyncConflict
+	if err := h.DB.WithContext(c.Request.Context()).Where("sync_link_id = ?", link.ID).Order("id").Find(&conflicts).Error; err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to fetch sync conflicts"})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"conflicts": conflicts})
+}
+
+// ResolveSyncConflicts records a decision for every pending conflict of a link
+// and queues a sync to apply them
+func (h *Handlers) ResolveSyncConflicts(c *gin.Context) {
+	user, exists := middleware.GetUserFromContext(c)
+	if !exists {
+		i18n.RespondError(c, http.StatusUnauthorized, i18n.CodeUnauthenticated, "")
+		return
+	}
+
+	link, ok := h.userSyncLinkFromParam(c, user.ID)
+	if !ok {
+		return
+	}
+
+	var req ResolveSyncConflictsRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		i18n.RespondError(c, http.StatusBadRequest, i18n.CodeInvalidRequest, err.Error())
+		return
+	}
+	decisions := make(map[uint]string, len(req.Decisions))
+	for _, decision := range req.Decisions {
+		decisions[decision.ID] = decision.Decision
+	}
+	if req.Decision != "" && !validSyncDecision(req.Decision) {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Decision must be keep_both, prefer_source or prefer_target"})
+		return
+	}
+	for _, decision := range decisions {
+		if !validSyncDecision(decision) {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "Decision must be keep_both, prefer_source or prefer_target"})
+			return
+		}
+	}
+
+	db := h.DB.WithContext(c.Request.Context())
+	var pending []database.SyncConflict
+	if err := db.Where("sync_link_id = ? AND decision = ''", link.ID).Find(&pending).Error; err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to fetch sync conflicts"})
+		return
+	}
+	if len(pending) == 0 {
+		c.JSON(http.StatusConflict, gin.H{"error": "No conflicts are waiting for a decision"})
+		return
+	}
+
+	for i := range pending {
+		pending[i].Decision = req.Decision
+		if decision, ok := decisions[pending[i].ID]; ok {
+			pending[i].Decision = decision
+		}
+		if pending[i].Decision == "" {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "Every conflict needs a decision", "conflict_id": pending[i].ID})
+			return
+		}
+	}
+	err := db.Transaction(func(tx *gorm.DB) error {
+		for _, conflict := range pending {
+			if err := tx.Model(&conflict).Update("decision", conflict.Decision).Error; err != nil {
+				return err
+			}
+		}
+		return nil
+	})
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to save decisions"})
+		return
+	}
+
+	h.enqueueSyncLink(link, jobs.PriorityNormal)
+	c.JSON(http.StatusAccepted, gin.H{"message": "Sync started", "resolved": len(pending)})
+}
+
+// syncSide is one playlist of a sync link as it was just read
+type syncSide struct {
+	service    database.UserService
+	playlistID string
+	tracks     []Track
+	complete   bool // every page of the playlist was read, see SourcePlaylist.Complete
+}
+
+// syncState is both playlists of a two-way link with the pairs from the last sync
+type syncState struct {
+	source, target syncSide
+	pairs          []database.SyncLinkTrack
+	matcher        syncLinkMatcher
+}
+
+// sides returns the playlist that changed and the other one
+func (s *syncState) sides(side string) (*syncSide, *syncSide) {
+	if side == "source" {
+		return &s.source, &s.target
+	}
+	return &s.target, &s.source
+}
+
+func (h *Handlers) loadSyncState(ctx context.Context, db *gorm.DB, link *database.SyncLink, source, target database.UserService) (*syncState, error) {
+	state := &syncState{
+		source:  syncSide{service: source, playlistID: link.SourcePlaylistID},
+		target:  syncSide{service: target, playlistID: link.TargetPlaylistID},
+		matcher: newSyncLinkMatcher(db, link.UserID),
+	}
+	for _, side := range []*syncSide{&state.source, &state.target} {
+		tracks, playlist, err := h.fetchPlaylistTracks(ctx, side.service.ServiceType, side.service.AccessToken, side.playlistID)
+		if err != nil {
+			return nil, fmt.Errorf("failed to read %s playlist: %w", getServiceDisplayName(side.service.ServiceType), err)
+		}
+		side.tracks = supportedTracks(tracks)
+		side.complete = playlist.Complete()
+	}
+	if err := db.Where("sync_link_id = ?", link.ID).Find(&state.pairs).Error; err != nil {
+		return nil, err
+	}
+	return state, nil
+}
+
+// reconcileSyncLink copies changes made to the target of a two-way link over
+// to the source. When both playlists changed, the target's and the source's
+// changes are stored as conflicts and the sync waits for the user's decisions.
+func (h *Handlers) reconcileSyncLink(ctx context.Context, db *gorm.DB, link *database.SyncLink, source, target database.UserService) error {
+	var conflicts []database.SyncConflict
+	if err := db.Where("sync_link_id = ?", link.ID).Order("id").Find(&conflicts).Error; err != nil {
+		return err
+	}
+	for _, conflict := range conflicts {
+		if conflict.Decision == "" {
+			return errSyncConflicts
+		}
+	}
+
+	state, err := h.loadSyncState(ctx, db, link, source, target)
+	if err != nil {
+		return err
+	}
+
+	if len(conflicts) > 0 {
+		for _, conflict := range conflicts {
+			if err := h.applySyncChange(ctx, db, link, state, conflict, conflict.Decision); err != nil {
+				return err
+			}
+			if err := db.Delete(&conflict).Error; err != nil {
+				return err
+			}
+		}
+		log.Printf("Sync link %d applied %d conflict decisions", link.ID, len(conflicts))
+		if state, err = h.loadSyncState(ctx, db, link, source, target); err != nil {
+			return err
+		}
+	}
+
+	sourceChanges, targetChanges := detectSyncChanges(link, state)
+	if len(targetChanges) == 0 {
+		return nil
+	}
+	if len(sourceChanges) > 0 {
+		now := time.Now().Unix()
+		changes := append(sourceChanges, targetChanges...)
+		for i := range changes {
+			changes[i].CreatedAt = now
+		}
+		if err := db.CreateInBatches(changes, 100).Error; err != nil {
+			return err
+		}
+		log.Printf("Sync link %d has %d conflicting changes", link.ID, len(changes))
+		return errSyncConflicts
+	}
+
+	for _, change := range targetChanges {
+		if err := h.applySyncChange(ctx, db, link, state, change, database.SyncPreferTarget); err != nil {
+			return err
+		}
+	}
+	log.Printf("Sync link %d copied %d target changes to the source", link.ID, len(targetChanges))
+	return nil
+}
+
+// detectSyncChanges compares both playlists with the pairs from the last sync.
+// Removals only count when the link propagates them. Nothing is compared
+// unless both playlists were read in full, as tracks on a missing page would
+// look removed.
+func detectSyncChanges(link *database.SyncLink, state *syncState) (sourceChanges, targetChanges []database.SyncConflict) {
+	if !state.source.complete || !state.target.complete {
+		log.Printf("Sync link %d could not read both playlists in full, skipping two-way changes", link.ID)
+		return nil, nil
+	}
+	sourceByID := make(map[string]Track, len(state.source.tracks))
+	for _, track := range state.source.tracks {
+		sourceByID[track.ID] = track
+	}
+	targetByID := make(map[string]Track, len(state.target.tracks))
+	for _, track := range state.target.tracks {
+		targetByID[track.ID] = track
+	}
+	pairedSources := make(map[string]bool, len(state.pairs))
+	pairedTargets := make(map[string]bool, len(state.pairs))
+	for _, pair := range state.pairs {
+		pairedSources[pair.SourceTrackID] = true
+		pairedTargets[pair.TargetTrackID] = true
+	}
+
+	for _, track := range state.source.tracks {
+		if !pairedSources[track.ID] {
+			pairedSources[track.ID] = true
+			sourceChanges = append(sourceChanges, database.SyncConflict{
+				SyncLinkID: link.ID, Side: "source", Action: "add", SourceTrackID: track.ID, Title: track.Name, Artist: track.Artist,
+			})
+		}
+	}
+	for _, track := range state.target.tracks {
+		if !pairedTargets[track.ID] {
+			pairedTargets[track.ID] = true
+			targetChanges = append(targetChanges, database.SyncConflict{
+				SyncLinkID: link.ID, Side: "target", Action: "add", TargetTrackID: track.ID, Title: track.Name, Artist: track.Artist,
+			})
+		}
+	}
+
+	if link.RemovalMode == database.SyncRemovalKeep {
+		return sourceChanges, targetChanges
+	}
+	for _, pair := range state.pairs {
+		if pair.SourceTrackID == "" || pair.TargetTrackID == "" {
+			continue
+		}
+		sourceTrack, inSource := sourceByID[pair.SourceTrackID]
+		targetTrack, inTarget := targetByID[pair.TargetTrackID]
+		switch {
+		case !inSource && inTarget:
+			sourceChanges = append(sourceChanges, database.SyncConflict{
+				SyncLinkID: link.ID, Side: "source", Action: "remove", SourceTrackID: pair.SourceTrackID, TargetTrackID: pair.TargetTrackID,
+				Title: targetTrack.Name, Artist: targetTrack.Artist,
+			})
+		case inSource && !inTarget:
+			targetChanges = append(targetChanges, database.SyncConflict{
+				SyncLinkID: link.ID, Side: "target", Action: "remove", SourceTrackID: pair.SourceTrackID, TargetTrackID: pair.TargetTrackID,
+				Title: sourceTrack.Name, Artist: sourceTrack.Artist,
+			})
+		}
+	}
+	return sourceChanges, targetChanges
+}
+
+// applySyncChange carries a change over to the other playlist, or undoes it
+// when the decision prefers the other playlist. Removals carried over from the
+// target are always plain removals; the archive playlist only exists on the target.
+func (h *Handlers) applySyncChange(ctx context.Context, db *gorm.DB, link *database.SyncLink, state *syncState, change database.SyncConflict, decision string) error {
+	propagate := decision == "prefer_"+change.Side || (decision == database.SyncKeepBoth && change.Action == "add")
+	changed, other := state.sides(change.Side)
+	changedID, otherID := change.SourceTrackID, change.TargetTrackID
+	if change.Side == "target" {
+		changedID, otherID = otherID, changedID
+	}
+
+	switch {
+	case change.Action == "add" && propagate:
+		track, ok := findTrack(changed.tracks, changedID)
+		if !ok {
+			return nil // removed again since
+		}
+		match, err := h.matchSyncLinkTrack(ctx, db, state.matcher, other.service, track)
+		if err != nil {
+			return err
+		}
+		if match.ID != "" {
+			if err := h.addTrackToPlaylist(ctx, other.service.ServiceType, other.service.AccessToken, other.playlistID, match.ID); err != nil {
+				return fmt.Errorf("failed to add %s - %s: %w", track.Artist, track.Name, err)
+			}
+		}
+		pair := database.SyncLinkTrack{SyncLinkID: link.ID, SourceTrackID: track.ID, TargetTrackID: match.ID}
+		if change.Side == "target" {
+			pair.SourceTrackID, pair.TargetTrackID = match.ID, track.ID
+		}
+		return db.Create(&pair).Error
+
+	case change.Action == "add":
+		return h.removeTracks(ctx, changed.service, changed.playlistID, tracksWithID(changed.tracks, changedID))
+
+	case propagate:
+		if err := h.removeTracks(ctx, other.service, other.playlistID, tracksWithID(other.tracks, otherID)); err != nil {
+			return err
+		}
+		return db.Where("sync_link_id = ? AND source_track_id = ? AND target_track_id = ?", link.ID, change.SourceTrackID, change.TargetTrackID).
+			Delete(&database.SyncLinkTrack{}).Error
+
+	default:
+		return h.addTrackToPlaylist(ctx, changed.service.ServiceType, changed.service.AccessToken, changed.playlistID, changedID)
+	}
+}
+
+func findTrack(tracks []Track, id string) (Track, bool) {
+	for _, track := range tracks {
+		if track.ID == id {
+			return track, true
+		}
+	}
+	return Track{}, false
+}
+
+// tracksWithID returns every entry of a track in a playlist
+func tracksWithID(tracks []Track, id string) []Track {
+	var matches []Track
+	for _, track := range tracks {
+		if track.ID == id {
+			matches = append(matches, track)
+		}
+	}
+	return matches
+}
@@ -0,0 +1,67 @@
+package handlers
+
+import (
+	"context"
+	"fmt"
+	"testing"
+
+	"server/internal/database"
+	"server/internal/providers/mock"
+	"server/internal/providers/spotify"
+	"server/internal/providers/youtube"
+)
+
+func TestDetectSyncChangesNeedsFullPlaylists(t *testing.T) {
+	ctx := context.Background()
+	provider := mock.New()
+	h := &Handlers{Providers: &Providers{Spotify: spotify.New(provider, nil), YouTube: youtube.New(provider, nil), Mock: provider}}
+
+	// More tracks than Spotify returns with the playlist itself
+	var uris []string
+	for i := range 150 {
+		song := mock.Song{Title: fmt.Sprintf("Song %d", i), Artist: "Mira Vale", SpotifyID: fmt.Sprintf("mockspbulk%012d", i)}
+		provider.AddSongs(song)
+		uris = append(uris, "spotify:track:"+song.SpotifyID)
+	}
+	created, err := h.Providers.Spotify.CreatePlaylist(ctx, mock.AccessToken, mock.SpotifyUserID, spotify.CreatePlaylistRequest{Name: "Long"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	for start := 0; start < len(uris); start += 100 {
+		if err := h.Providers.Spotify.AddTracks(ctx, mock.AccessToken, created.ID, uris[start:min(start+100, len(uris))]); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	tracks, playlist, err := h.fetchPlaylistTracks(ctx, "spotify", mock.AccessToken, created.ID)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(tracks) != len(uris) || playlist.Total != len(uris) || !playlist.Complete() {
+		t.Fatalf("fetchPlaylistTracks read %d tracks of %d", len(tracks), playlist.Total)
+	}
+
+	link := &database.SyncLink{RemovalMode: database.SyncRemovalRemove}
+	link.ID = 1
+	var pairs []database.SyncLinkTrack
+	for _, track := range tracks {
+		pairs = append(pairs, database.SyncLinkTrack{SyncLinkID: link.ID, SourceTrackID: track.ID, TargetTrackID: track.ID})
+	}
+
+	// Only the target's first page came back, so its other tracks look removed
+	state := &syncState{
+		source: syncSide{tracks: tracks, complete: true},
+		target: syncSide{tracks: tracks[:100], complete: false},
+		pairs:  pairs,
+	}
+	if sourceChanges, targetChanges := detectSyncChanges(link, state); len(sourceChanges)+len(targetChanges) > 0 {
+		t.Errorf("detectSyncChanges found %d source and %d target changes in a partial read", len(sourceChanges), len(targetChanges))
+	}
+
+	// Read in full, a track missing from the target is a removal
+	state.target = syncSide{tracks: tracks[:len(tracks)-1], complete: true}
+	sourceChanges, targetChanges := detectSyncChanges(link, state)
+	if len(sourceChanges) != 0 || len(targetChanges) != 1 || targetChanges[0].Action != "remove" {
+		t.Errorf("detectSyncChanges of full reads returned %+v, %+v; want one target removal", sourceChanges, targetChanges)
+	}
+}
@@ -0,0 +1,153 @@
+package handlers
+
+import (
+	"context"
+	"errors"
+	"net/http"
+
+	"server/internal/database"
+	"server/internal/i18n"
+	"server/internal/middleware"
+	"server/internal/ratelimit"
+
+	"github.com/gin-gonic/gin"
+)
+
+// SyncLinkDifferences are the changes the next sync of a link would carry over
+type SyncLinkDifferences struct {
+	SourceAdded   int  `json:"source_added"`
+	SourceRemoved int  `json:"source_removed"`
+	TargetAdded   int  `json:"target_added"`   // two-way links only
+	TargetRemoved int  `json:"target_removed"` // two-way links only
+	Reorders      int  `json:"reorders"`       // moves needed to put the target in the source's order
+	InSync        bool `json:"in_sync"`
+}
+
+// UnmatchedSyncTrack is a track the link found no counterpart for on the other side
+type UnmatchedSyncTrack struct {
+	TrackID string `json:"track_id"`
+	Name    string `json:"name"`
+	Artist  string `json:"artist"`
+}
+
+// SyncLinkStatus reports how healthy a sync link is and how far its playlists have drifted
+type SyncLinkStatus struct {
+	SyncLinkID       uint                 `json:"sync_link_id"`
+	Enabled          bool                 `json:"enabled"`
+	Healthy          bool                 `json:"healthy"`
+	LastSyncedAt     int64                `json:"last_synced_at"`
+	LastAttemptAt    int64                `json:"last_attempt_at"`
+	LastError        string               `json:"last_error,omitempty"`
+	ErrorStreak      int                  `json:"error_streak"`
+	PendingConflicts int                  `json:"pending_conflicts"`
+	Differences      *SyncLinkDifferences `json:"differences"`                 // nil when the playlists could not be read
+	DifferencesError string               `json:"differences_error,omitempty"` // why they could not
+	UnmatchedSource  []UnmatchedSyncTrack `json:"unmatched_source"`            // source tracks missing from the target
+	UnmatchedTarget  []UnmatchedSyncTrack `json:"unmatched_target"`            // target tracks missing from the source, two-way links only
+}
+
+// GetSyncLinkStatus reports a link's last successful sync and error streak,
+// and re-reads both playlists to show what is waiting for the next sync
+func (h *Handlers) GetSyncLinkStatus(c *gin.Context) {
+	user, exists := middleware.GetUserFromContext(c)
+	if !exists {
+		i18n.RespondError(c, http.StatusUnauthorized, i18n.CodeUnauthenticated, "")
+		return
+	}
+
+	link, ok := h.userSyncLinkFromParam(c, user.ID)
+	if !ok {
+		return
+	}
+
+	ctx := c.Request.Context()
+	db := h.DB.WithContext(ctx)
+	status := SyncLinkStatus{
+		SyncLinkID:      link.ID,
+		Enabled:         link.Enabled,
+		LastSyncedAt:    link.LastSyncedAt,
+		LastAttemptAt:   link.LastAttemptAt,
+		LastError:       link.LastError,
+		ErrorStreak:     link.ErrorStreak,
+		UnmatchedSource: []UnmatchedSyncTrack{},
+		UnmatchedTarget: []UnmatchedSyncTrack{},
+	}
+	var pending int64
+	db.Model(&database.SyncConflict{}).Where("sync_link_id = ? AND decision = ''", link.ID).Count(&pending)
+	status.PendingConflicts = int(pending)
+	status.Healthy = link.Enabled && link.ErrorStreak == 0 && pending == 0
+
+	state, err := h.loadSyncLinkState(ctx, link)
+	if err != nil {
+		status.DifferencesError = err.Error()
+		if errors.Is(err, ratelimit.ErrProviderUnavailable) {
+			status.DifferencesError = "The service is busy; try again later"
+		}
+		c.JSON(http.StatusOK, gin.H{"status": status})
+		return
+	}
+
+	sourceChanges, targetChanges := detectSyncChanges(&link, state)
+	differences := &SyncLinkDifferences{}
+	for _, change := range sourceChanges {
+		if change.Action == "add" {
+			differences.SourceAdded++
+		} else {
+			differences.SourceRemoved++
+		}
+	}
+	if link.Bidirectional {
+		for _, change := range targetChanges {
+			if change.Action == "add" {
+				differences.TargetAdded++
+			} else {
+				differences.TargetRemoved++
+			}
+		}
+	}
+
+	pairs := make(map[string]string, len(state.pairs))
+	unmatchedSource := make(map[string]bool)
+	unmatchedTarget := make(map[string]bool)
+	for _, pair := range state.pairs {
+		switch {
+		case pair.SourceTrackID == "":
+			unmatchedTarget[pair.TargetTrackID] = true
+		case pair.TargetTrackID == "":
+			unmatchedSource[pair.SourceTrackID] = true
+			pairs[pair.SourceTrackID] = ""
+		default:
+			pairs[pair.SourceTrackID] = pair.TargetTrackID
+		}
+	}
+	moves, _ := sourceOrderMoves(state.source.tracks, state.target.tracks, pairs)
+	differences.Reorders = len(moves)
+	differences.InSync = differences.SourceAdded+differences.SourceRemoved+differences.TargetAdded+differences.TargetRemoved+differences.Reorders == 0
+	status.Differences = differences
+
+	for _, track := range state.source.tracks {
+		if unmatchedSource[track.ID] {
+			delete(unmatchedSource, track.ID)
+			status.UnmatchedSource = append(status.UnmatchedSource, UnmatchedSyncTrack{TrackID: track.ID, Name: track.Name, Artist: track.Artist})
+		}
+	}
+	if link.Bidirectional {
+		for _, track := range state.target.tracks {
+			if unmatchedTarget[track.ID] {
+				delete(unmatchedTarget, track.ID)
+				status.UnmatchedTarget = append(status.UnmatchedTarget, UnmatchedSyncTrack{TrackID: track.ID, Name: track.Name, Artist: track.Artist})
+			}
+		}
+	}
+
+	c.JSON(http.StatusOK, gin.H{"status": status})
+}
+
+// loadSyncLinkState reads both playlists of a link with fresh tokens
+func (h *Handlers) loadSyncLinkState(ctx context.Context, link database.SyncLink) (*syncState, error) {
+	source, target, err := h.syncLinkServices(ctx, link)
+	if err != nil {
+		return nil, err
+	}
+	return h.loadSyncState(ctx, h.DB.WithContext(ctx), &link, source, target)
+}
@@ -0,0 +1,832 @@
+package handlers
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+
+	"server/internal/config"
+	"server/internal/database"
+	"server/internal/i18n"
+	"server/internal/jobs"
+	"server/internal/middleware"
+	"server/internal/providers"
+	"server/internal/ratelimit"
+
+	"github.com/gin-gonic/gin"
+	"gorm.io/gorm"
+)
+
+type SyncLinkRequest struct {
+	TransferID    uint   `json:"transfer_id" binding:"required"`
+	RemovalMode   string `json:"removal_mode"`  // "keep" (default), "remove" or "archive"
+	Bidirectional bool   `json:"bidirectional"` // also copy changes to the target back to the source
+}
+
+type UpdateSyncLinkRequest struct {
+	Enabled       *bool  `json:"enabled"`
+	RemovalMode   string `json:"removal_mode"`
+	Bidirectional *bool  `json:"bidirectional"`
+}
+
+func validRemovalMode(mode string) bool {
+	switch mode {
+	case database.SyncRemovalKeep, database.SyncRemovalRemove, database.SyncRemovalArchive:
+		return true
+	}
+	return false
+}
+
+// CreateSyncLink keeps the target playlist of a finished transfer in sync with its source
+func (h *Handlers) CreateSyncLink(c *gin.Context) {
+	user, exists := middleware.GetUserFromContext(c)
+	if !exists {
+		i18n.RespondError(c, http.StatusUnauthorized, i18n.CodeUnauthenticated, "")
+		return
+	}
+
+	var req SyncLinkRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		i18n.RespondError(c, http.StatusBadRequest, i18n.CodeInvalidRequest, err.Error())
+		return
+	}
+	if req.RemovalMode == "" {
+		req.RemovalMode = database.SyncRemovalKeep
+	}
+	if !validRemovalMode(req.RemovalMode) {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Removal mode must be keep, remove or archive"})
+		return
+	}
+
+	db := h.DB.WithContext(c.Request.Context())
+	var transfer database.Transfer
+	if err := db.Where("id = ? AND user_id = ?", req.TransferID, user.ID).First(&transfer).Error; err != nil {
+		i18n.RespondError(c, http.StatusNotFound, i18n.CodeTransferNotFound, "")
+		return
+	}
+	if transfer.TargetPlaylistID == "" || (transfer.Status != database.TransferCompleted && transfer.Status != database.TransferCompletedWithErrors) {
+		c.JSON(http.StatusConflict, gin.H{"error": "Only completed transfers can be kept in sync"})
+		return
+	}
+	if !syncableSource(transfer) {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Only transfers from a playlist on a connected service can be kept in sync"})
+		return
+	}
+
+	var existing int64
+	db.Model(&database.SyncLink{}).
+		Where("user_id = ? AND target_service = ? AND target_playlist_id = ?", user.ID, transfer.TargetService, transfer.TargetPlaylistID).
+		Count(&existing)
+	if existing > 0 {
+		c.JSON(http.StatusConflict, gin.H{"error": "This playlist is already kept in sync"})
+		return
+	}
+
+	link, err := createSyncLink(db, transfer, req.RemovalMode, req.Bidirectional)
+	if err != nil {
+		log.Printf("Failed to create sync link for transfer %d: %v", transfer.ID, err)
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to create sync link"})
+		return
+	}
+
+	c.JSON(http.StatusCreated, gin.H{"sync_link": link})
+}
+
+// syncableSource reports whether a transfer's source was read through the
+// user's own connection, which syncing needs, see syncLinkServices. Smart
+// playlists and public playlists read with app credentials were not.
+func syncableSource(transfer database.Transfer) bool {
+	return transfer.SourceService != "smart" && !transfer.PublicSource
+}
+
+// createSyncLink links a finished transfer's target playlist to its source
+func createSyncLink(db *gorm.DB, transfer database.Transfer, removalMode string, bidirectional bool) (database.SyncLink, error) {
+	link := database.SyncLink{
+		UserID:           transfer.UserID,
+		TransferID:       transfer.ID,
+		SourceService:    transfer.SourceService,
+		SourcePlaylistID: transfer.SourcePlaylistID,
+		TargetService:    transfer.TargetService,
+		TargetPlaylistID: transfer.TargetPlaylistID,
+		Enabled:          true,
+		RemovalMode:      removalMode,
+		Bidirectional:    bidirectional,
+		LastSyncedAt:     transfer.UpdatedAt.Unix(),
+	}
+	err := db.Transaction(func(tx *gorm.DB) error {
+		if err := tx.Create(&link).Error; err != nil {
+			return err
+		}
+		return seedSyncLinkTracks(tx, link, transfer.ID)
+	})
+	return link, err
+}
+
+// seedSyncLinkTracks pairs the tracks the transfer handled, so the first sync
+// only searches for tracks added to the source since
+func seedSyncLinkTracks(tx *gorm.DB, link database.SyncLink, transferID uint) error {
+	var results []database.TransferTrack
+	if err := tx.Where("transfer_id = ? AND source_track_id <> ''", transferID).Order("id").Find(&results).Error; err != nil {
+		return err
+	}
+
+	pairs := make([]database.SyncLinkTrack, 0, len(results))
+	seen := make(map[string]bool)
+	for _, result := range results {
+		if seen[result.SourceTrackID] {
+			continue
+		}
+		seen[result.SourceTrackID] = true
+		pair := database.SyncLinkTrack{SyncLinkID: link.ID, SourceTrackID: result.SourceTrackID}
+		if result.Status == "matched" {
+			pair.TargetTrackID = result.TargetTrackID
+		}
+		pairs = append(pairs, pair)
+	}
+	if len(pairs) == 0 {
+		return nil
+	}
+	return tx.CreateInBatches(pairs, 100).Error
+}
+
+// GetSyncLinks lists the user's sync links
+func (h *Handlers) GetSyncLinks(c *gin.Context) {
+	user, exists := middleware.GetUserFromContext(c)
+	if !exists {
+		i18n.RespondError(c, http.StatusUnauthorized, i18n.CodeUnauthenticated, "")
+		return
+	}
+
+	var links []database.SyncLink
+	if err := h.DB.WithContext(c.Request.Context()).Where("user_id = ?", user.ID).Order("id").Find(&links).Error; err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to fetch sync links"})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"sync_links": links})
+}
+
+// UpdateSyncLink pauses or resumes a link, changes how source removals are
+// handled and turns two-way syncing on or off
+func (h *Handlers) UpdateSyncLink(c *gin.Context) {
+	user, exists := middleware.GetUserFromContext(c)
+	if !exists {
+		i18n.RespondError(c, http.StatusUnauthorized, i18n.CodeUnauthenticated, "")
+		return
+	}
+
+	link, ok := h.userSyncLinkFromParam(c, user.ID)
+	if !ok {
+		return
+	}
+
+	var req UpdateSyncLinkRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		i18n.RespondError(c, http.StatusBadRequest, i18n.CodeInvalidRequest, err.Error())
+		return
+	}
+	updates := map[string]interface{}{}
+	if req.Enabled != nil {
+		updates["enabled"] = *req.Enabled
+	}
+	if req.RemovalMode != "" {
+		if !validRemovalMode(req.RemovalMode) {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "Removal mode must be keep, remove or archive"})
+			return
+		}
+		updates["removal_mode"] = req.RemovalMode
+	}
+	if req.Bidirectional != nil {
+		updates["bidirectional"] = *req.Bidirectional
+	}
+
+	if len(updates) > 0 {
+		err := h.DB.WithContext(c.Request.Context()).Transaction(func(tx *gorm.DB) error {
+			if err := tx.Model(&link).Updates(updates).Error; err != nil {
+				return err
+			}
+			// Conflicts only exist between the two sides of a two-way link
+			if !link.Bidirectional {
+				return tx.Where("sync_link_id = ?", link.ID).Delete(&database.SyncConflict{}).Error
+			}
+			return nil
+		})
+		if err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to update sync link"})
+			return
+		}
+	}
+	c.JSON(http.StatusOK, gin.H{"sync_link": link})
+}
+
+// DeleteSyncLink stops keeping a playlist in sync; both playlists are left as they are
+func (h *Handlers) DeleteSyncLink(c *gin.Context) {
+	user, exists := middleware.GetUserFromContext(c)
+	if !exists {
+		i18n.RespondError(c, http.StatusUnauthorized, i18n.CodeUnauthenticated, "")
+		return
+	}
+
+	link, ok := h.userSyncLinkFromParam(c, user.ID)
+	if !ok {
+		return
+	}
+
+	err := h.DB.WithContext(c.Request.Context()).Transaction(func(tx *gorm.DB) error {
+		if err := tx.Where("sync_link_id = ?", link.ID).Delete(&database.SyncLinkTrack{}).Error; err != nil {
+			return err
+		}
+		if err := tx.Where("sync_link_id = ?", link.ID).Delete(&database.SyncConflict{}).Error; err != nil {
+			return err
+		}
+		return tx.Delete(&link).Error
+	})
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to delete sync link"})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"message": "Sync link deleted"})
+}
+
+// SyncLinkNow queues an immediate sync of a link
+func (h *Handlers) SyncLinkNow(c *gin.Context) {
+	user, exists := middleware.GetUserFromContext(c)
+	if !exists {
+		i18n.RespondError(c, http.StatusUnauthorized, i18n.CodeUnauthenticated, "")
+		return
+	}
+
+	link, ok := h.userSyncLinkFromParam(c, user.ID)
+	if !ok {
+		return
+	}
+
+	h.enqueueSyncLink(link, jobs.PriorityNormal)
+	c.JSON(http.StatusAccepted, gin.H{"message": "Sync started", "sync_link_id": link.ID})
+}
+
+// userSyncLinkFromParam loads the sync link named by the :id parameter if it belongs to the user,
+// writing the error response otherwise
+func (h *Handlers) userSyncLinkFromParam(c *gin.Context, userID uint) (database.SyncLink, bool) {
+	var link database.SyncLink
+	id, err := strconv.ParseUint(c.Param("id"), 10, 32)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid sync link ID"})
+		return link, false
+	}
+	if err := h.DB.WithContext(c.Request.Context()).Where("id = ? AND user_id = ?", uint(id), userID).First(&link).Error; err != nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": "Sync link not found"})
+		return link, false
+	}
+	return link, true
+}
+
+// StartSyncLinkScheduler syncs every enabled link once per SYNC_LINK_INTERVAL (default 6h)
+func (h *Handlers) StartSyncLinkScheduler(ctx context.Context) {
+	interval := config.Duration("SYNC_LINK_INTERVAL", 6*time.Hour)
+	if interval <= 0 {
+		log.Printf("Sync links disabled")
+		return
+	}
+
+	go func() {
+		ticker := time.NewTicker(time.Hour)
+		defer ticker.Stop()
+
+		for {
+			select {
+			case <-ctx.Done():
+				return
+			case <-ticker.C:
+				h.runScheduled(ctx, "sync-links", func() { h.scheduleDueSyncLinks(ctx, interval) })
+			}
+		}
+	}()
+}
+
+// scheduleDueSyncLinks queues a sync of every enabled link not synced within
+// interval. Links from excluded playlists only sync when asked to.
+func (h *Handlers) scheduleDueSyncLinks(ctx context.Context, interval time.Duration) {
+	var links []database.SyncLink
+	err := h.DB.WithContext(ctx).Where("enabled = ? AND last_synced_at < ?", true, time.Now().Add(-interval).Unix()).
+		Find(&links).Error
+	if err != nil {
+		log.Printf("Failed to load sync links: %v", err)
+		return
+	}
+
+	exclusions := make(map[uint]playlistExclusions)
+	for _, link := range links {
+		if _, ok := exclusions[link.UserID]; !ok {
+			exclusions[link.UserID] = loadPlaylistExclusions(h.DB.WithContext(ctx), link.UserID)
+		}
+		if len(exclusions[link.UserID]) > 0 {
+			var source database.Playlist
+			h.DB.WithContext(ctx).Where("user_id = ? AND service_type = ? AND service_id = ?", link.UserID, link.SourceService, link.SourcePlaylistID).
+				First(&source)
+			if exclusions[link.UserID].excludes(link.SourceService, link.SourcePlaylistID, source.Name) {
+				continue
+			}
+		}
+		h.enqueueSyncLink(link, jobs.PriorityLow)
+	}
+}
+
+func (h *Handlers) enqueueSyncLink(link database.SyncLink, priority int) {
+	h.Jobs.Enqueue(&jobs.Job{
+		ID:       fmt.Sprintf("sync-link-%d", link.ID),
+		Type:     "sync_link",
+		UserID:   link.UserID,
+		Priority: priority,
+		Run: func(ctx context.Context) error {
+			ctx = ratelimit.WithUser(ctx, link.UserID)
+			err := h.syncLink(ctx, link)
+			h.recordSyncLinkResult(ctx, link, err)
+			return err
+		},
+	})
+}
+
+// recordSyncLinkResult stores when a link last synced, or why it could not
+func (h *Handlers) recordSyncLinkResult(ctx context.Context, link database.SyncLink, err error) {
+	now := time.Now().Unix()
+	updates := map[string]interface{}{"last_synced_at": now, "last_attempt_at": now, "last_error": "", "error_streak": 0}
+	if err != nil {
+		log.Printf("Sync link %d failed: %v", link.ID, err)
+		updates = map[string]interface{}{"last_attempt_at": now, "last_error": err.Error(), "error_streak": gorm.Expr("error_streak + 1")}
+	}
+	h.DB.WithContext(context.WithoutCancel(ctx)).Model(&link).Updates(updates)
+}
+
+// syncLink brings a link's target up to date: tracks new to the source are
+// matched and added, then the target is put in the source's order. Two-way
+// links first bring changes made to the target over to the source.
+func (h *Handlers) syncLink(ctx context.Context, link database.SyncLink) error {
+	db := h.DB.WithContext(ctx)
+
+	source, target, err := h.syncLinkServices(ctx, link)
+	if err != nil {
+		return err
+	}
+
+	// Playlists this sync has changed are read past the response cache
+	fresh := ratelimit.WithoutCache(ctx)
+	sourceCtx := ctx
+	if link.Bidirectional {
+		if err := h.reconcileSyncLink(ctx, db, &link, source, target); err != nil {
+			return err
+		}
+		sourceCtx = fresh
+	}
+
+	sourceTracks, sourcePlaylist, err := h.fetchPlaylistTracks(sourceCtx, link.SourceService, source.AccessToken, link.SourcePlaylistID)
+	if err != nil {
+		return fmt.Errorf("failed to read source playlist: %w", err)
+	}
+	sourceTracks = supportedTracks(sourceTracks)
+
+	pairs, err := loadSyncLinkPairs(db, link.ID)
+	if err != nil {
+		return err
+	}
+
+	added, err := h.addNewSyncLinkTracks(ctx, db, link, &target, sourceTracks, pairs)
+	if added > 0 {
+		log.Printf("Sync link %d added %d tracks", link.ID, added)
+	}
+	if err != nil {
+		return err
+	}
+
+	targetTracks, targetPlaylist, err := h.fetchPlaylistTracks(fresh, link.TargetService, target.AccessToken, link.TargetPlaylistID)
+	if err != nil {
+		return fmt.Errorf("failed to read target playlist: %w", err)
+	}
+	removing := link.RemovalMode == database.SyncRemovalRemove || link.RemovalMode == database.SyncRemovalArchive
+	// Tracks on a page that failed to load would look removed from the source
+	if removing && (!sourcePlaylist.Complete() || !targetPlaylist.Complete()) {
+		log.Printf("Sync link %d read %d/%d source and %d/%d target tracks, skipping removals",
+			link.ID, sourcePlaylist.Fetched, sourcePlaylist.Total, targetPlaylist.Fetched, targetPlaylist.Total)
+		removing = false
+	}
+	if removing {
+		targetTracks, err = h.propagateRemovals(ctx, db, &link, target, targetPlaylist.Name, sourceTracks, targetTracks, pairs)
+		if err != nil {
+			return err
+		}
+	}
+	moved, err := h.mirrorSourceOrder(ctx, link, target, sourceTracks, targetTracks, pairs)
+	if moved > 0 {
+		log.Printf("Sync link %d moved %d tracks", link.ID, moved)
+	}
+	return err
+}
+
+// syncLinkServices loads the user's connections to both sides of a link with fresh tokens
+func (h *Handlers) syncLinkServices(ctx context.Context, link database.SyncLink) (database.UserService, database.UserService, error) {
+	db := h.DB.WithContext(ctx)
+	var source, target database.UserService
+	if err := db.Where("user_id = ? AND service_type = ?", link.UserID, link.SourceService).First(&source).Error; err != nil {
+		return source, target, fmt.Errorf("%s is not connected", getServiceDisplayName(link.SourceService))
+	}
+	if err := db.Where("user_id = ? AND service_type = ?", link.UserID, link.TargetService).First(&target).Error; err != nil {
+		return source, target, fmt.Errorf("%s is not connected", getServiceDisplayName(link.TargetService))
+	}
+	for _, service := range []*database.UserService{&source, &target} {
+		if err := h.Tokens.RefreshTokenIfNeeded(service); err != nil {
+			return source, target, fmt.Errorf("%s token refresh failed: %w", getServiceDisplayName(service.ServiceType), err)
+		}
+	}
+	return source, target, nil
+}
+
+// addNewSyncLinkTracks matches source tracks the link has not seen before and
+// appends the matches to the target. Tracks without a match are remembered so
+// they are not searched on every sync.
+func (h *Handlers) addNewSyncLinkTracks(ctx context.Context, db *gorm.DB, link database.SyncLink, target *database.UserService, sourceTracks []Track, pairs map[string]string) (int, error) {
+	matcher := newSyncLinkMatcher(db, link.UserID)
+
+	added := 0
+	for _, track := range sourceTracks {
+		if _, seen := pairs[track.ID]; seen {
+			continue
+		}
+		if ctx.Err() != nil {
+			return added, ctx.Err()
+		}
+
+		match, err := h.matchSyncLinkTrack(ctx, db, matcher, *target, track)
+		if err != nil {
+			return added, err
+		}
+		if match.ID != "" {
+			err := withTrackWriteRetry(ctx, "adding track", nil, func(ctx context.Context) error {
+				return h.withTokenRefresh(ctx, target, func() error {
+					return h.addTrackToPlaylist(ctx, target.ServiceType, target.AccessToken, link.TargetPlaylistID, match.ID)
+				})
+			}, func(ctx context.Context) (bool, error) {
+				return h.playlistHasTracks(ctx, target.ServiceType, target.AccessToken, link.TargetPlaylistID, []string{match.ID})
+			})
+			if err != nil {
+				return added, fmt.Errorf("failed to add %s - %s: %w", track.Artist, track.Name, err)
+			}
+			added++
+		}
+		if err := db.Create(&database.SyncLinkTrack{SyncLinkID: link.ID, SourceTrackID: track.ID, TargetTrackID: match.ID}).Error; err != nil {
+			return added, err
+		}
+		pairs[track.ID] = match.ID
+	}
+	return added, nil
+}
+
+// syncLinkMatcher holds the user's match settings for the tracks of one sync
+type syncLinkMatcher struct {
+	options       SearchOptions
+	minConfidence float64
+}
+
+func newSyncLinkMatcher(db *gorm.DB, userID uint) syncLinkMatcher {
+	settings := loadUserSettings(db, userID)
+	return syncLinkMatcher{
+		options:       SearchOptions{Market: userMarket(db, userID), MatchStrategy: settings.MatchStrategy},
+		minConfidence: settings.MinConfidence,
+	}
+}
+
+// matchSyncLinkTrack finds track on the service of to. A track without a good
+// enough match comes back with an empty ID; the error is only set when the
+// sync has to stop.
+func (h *Handlers) matchSyncLinkTrack(ctx context.Context, db *gorm.DB, matcher syncLinkMatcher, to database.UserService, track Track) (Track, error) {
+	if match, _, known := knownTargetTrack(db, to.UserID, track, to.ServiceType); known {
+		return match, nil
+	}
+
+	found, confidence, err := h.searchTrack(ctx, to.ServiceType, to.AccessToken, track, matcher.options)
+	// Throttling, quota and outages end this sync; the track is searched again next time
+	var statusErr *providers.StatusError
+	if errors.Is(err, ratelimit.ErrProviderUnavailable) || errors.As(err, &statusErr) || isTransientError(err) {
+		return Track{}, err
+	}
+	if err != nil || found.ID == "" || confidence < matcher.minConfidence {
+		return Track{}, nil
+	}
+	if confidence >= identityLinkConfidence {
+		linkTrackIdentities(ctx, db, to.UserID, track, found, confidence)
+	}
+	return found, nil
+}
+
+// loadSyncLinkPairs maps the source track IDs of a link's pairs to their
+// target track IDs. Pairs of target-only tracks, see SyncLinkTrack, are left out.
+func loadSyncLinkPairs(db *gorm.DB, linkID uint) (map[string]string, error) {
+	var pairList []database.SyncLinkTrack
+	if err := db.Where("sync_link_id = ?", linkID).Find(&pairList).Error; err != nil {
+		return nil, err
+	}
+	pairs := make(map[string]string, len(pairList))
+	for _, pair := range pairList {
+		if pair.SourceTrackID != "" {
+			pairs[pair.SourceTrackID] = pair.TargetTrackID
+		}
+	}
+	return pairs, nil
+}
+
+// propagateRemovals takes target tracks whose source track is gone out of the
+// target, first copying them to the archive playlist in archive mode. It returns
+// the target tracks that remain.
+func (h *Handlers) propagateRemovals(ctx context.Context, db *gorm.DB, link *database.SyncLink, target database.UserService, targetName string, sourceTracks, targetTracks []Track, pairs map[string]string) ([]Track, error) {
+	inSource := make(map[string]bool, len(sourceTracks))
+	stillWanted := make(map[string]bool, len(sourceTracks))
+	for _, track := range sourceTracks {
+		inSource[track.ID] = true
+		stillWanted[pairs[track.ID]] = true
+	}
+
+	var removedSources []string
+	removed := make(map[string]bool)
+	for sourceID, targetID := range pairs {
+		if inSource[sourceID] {
+			continue
+		}
+		removedSources = append(removedSources, sourceID)
+		// Another source track may have matched the same target track
+		if targetID != "" && !stillWanted[targetID] {
+			removed[targetID] = true
+		}
+	}
+	if len(removedSources) == 0 {
+		return targetTracks, nil
+	}
+
+	remaining := make([]Track, 0, len(targetTracks))
+	var gone []Track
+	for _, track := range targetTracks {
+		if removed[track.ID] {
+			gone = append(gone, track)
+		} else {
+			remaining = append(remaining, track)
+		}
+	}
+
+	if link.RemovalMode == database.SyncRemovalArchive && len(gone) > 0 {
+		if err := h.archiveSyncLinkTracks(ctx, db, link, target, targetName, gone); err != nil {
+			return targetTracks, err
+		}
+	}
+	if err := h.removeTracks(ctx, target, link.TargetPlaylistID, gone); err != nil {
+		return targetTracks, fmt.Errorf("failed to remove tracks from target playlist: %w", err)
+	}
+	if len(gone) > 0 {
+		log.Printf("Sync link %d removed %d tracks", link.ID, len(gone))
+	}
+
+	// Forget the pairs so a track put back in the source is added again
+	if err := db.Where("sync_link_id = ? AND source_track_id IN ?", link.ID, removedSources).Delete(&database.SyncLinkTrack{}).Error; err != nil {
+		return remaining, err
+	}
+	for _, sourceID := range removedSources {
+		delete(pairs, sourceID)
+	}
+	return remaining, nil
+}
+
+// archiveSyncLinkTracks adds tracks to the link's archive playlist, creating it on first use
+func (h *Handlers) archiveSyncLinkTracks(ctx context.Context, db *gorm.DB, link *database.SyncLink, target database.UserService, targetName string, tracks []Track) error {
+	if link.ArchivePlaylistID == "" {
+		playlistID, err := h.createPlaylist(ctx, target.ServiceType, target.AccessToken, targetName+" (removed)",
+			"Tracks removed from "+targetName+" by playlist sync", PlaylistCreateOptions{Privacy: "private"})
+		if err != nil {
+			return fmt.Errorf("failed to create archive playlist: %w", err)
+		}
+		link.ArchivePlaylistID = playlistID
+		if err := db.Model(link).Update("archive_playlist_id", playlistID).Error; err != nil {
+			return err
+		}
+	}
+
+	archived := make(map[string]bool, len(tracks))
+	for _, track := range tracks {
+		if archived[track.ID] {
+			continue
+		}
+		if err := h.addTrackToPlaylist(ctx, target.ServiceType, target.AccessToken, link.ArchivePlaylistID, track.ID); err != nil {
+			return fmt.Errorf("failed to archive %s - %s: %w", track.Artist, track.Name, err)
+		}
+		archived[track.ID] = true
+	}
+	return nil
+}
+
+// removeTracks takes entries out of a playlist. Spotify removes every
+// occurrence of a track at once; YouTube entries go one by one.
+func (h *Handlers) removeTracks(ctx context.Context, service database.UserService, playlistID string, tracks []Track) error {
+	if len(tracks) == 0 {
+		return nil
+	}
+	switch service.ServiceType {
+	case "spotify":
+		var uris []string
+		seen := make(map[string]bool, len(tracks))
+		for _, track := range tracks {
+			if !seen[track.ID] {
+				seen[track.ID] = true
+				uris = append(uris, "spotify:track:"+track.ID)
+			}
+		}
+		// The API takes at most 100 tracks per request
+		for start := 0; start < len(uris); start += 100 {
+			if err := h.Providers.Spotify.RemoveTracks(ctx, service.AccessToken, playlistID, uris[start:min(start+100, len(uris))]); err != nil {
+				return err
+			}
+		}
+		return nil
+	case "youtube":
+		for _, track := range tracks {
+			if err := h.Providers.YouTube.DeletePlaylistItem(ctx, service.AccessToken, track.ItemID); err != nil {
+				return err
+			}
+		}
+		return nil
+	default:
+		return fmt.Errorf("unsupported service: %s", service.ServiceType)
+	}
+}
+
+// mirrorSourceOrder moves the target's tracks into the order of their source
+// counterparts. Target tracks the link did not add keep their relative order
+// after the mirrored ones.
+func (h *Handlers) mirrorSourceOrder(ctx context.Context, link database.SyncLink, target database.UserService, sourceTracks, targetTracks []Track, pairs map[string]string) (int, error) {
+	moves, entries := sourceOrderMoves(sourceTracks, targetTracks, pairs)
+	for n, move := range moves {
+		if ctx.Err() != nil {
+			return n, ctx.Err()
+		}
+		if err := h.moveTrack(ctx, target, link.TargetPlaylistID, entries[move.Key], move); err != nil {
+			return n, fmt.Errorf("failed to reorder target playlist: %w", err)
+		}
+	}
+	return len(moves), nil
+}
+
+// sourceOrderMoves plans the moves mirrorSourceOrder makes, with the target
+// entries they refer to by key
+func sourceOrderMoves(sourceTracks, targetTracks []Track, pairs map[string]string) ([]playlistMove, map[string]Track) {
+	// A track can be in a playlist more than once, so entries are keyed by ID and occurrence
+	current := occurrenceKeys(len(targetTracks), func(i int) string { return targetTracks[i].ID })
+	entries := make(map[string]Track, len(targetTracks))
+	for i, key := range current {
+		entries[key] = targetTracks[i]
+	}
+	wanted := occurrenceKeys(len(sourceTracks), func(i int) string { return pairs[sourceTracks[i].ID] })
+
+	desired := make([]string, 0, len(current))
+	placed := make(map[string]bool, len(current))
+	for _, key := range wanted {
+		if strings.HasPrefix(key, "#") {
+			continue // unpaired source track
+		}
+		if _, ok := entries[key]; ok {
+			desired = append(desired, key)
+			placed[key] = true
+		}
+	}
+	for _, key := range current {
+		if !placed[key] {
+			desired = append(desired, key)
+		}
+	}
+
+	return planReorder(current, desired), entries
+}
+
+// occurrenceKeys names n playlist entries by ID and occurrence ("id#0", "id#1", ...).
+// Entries with an empty ID, such as local files, are named by position ("#3"),
+// so key indexes stay the entries' positions in the playlist.
+func occurrenceKeys(n int, id func(i int) string) []string {
+	keys := make([]string, 0, n)
+	seen := make(map[string]int, n)
+	for i := range n {
+		if id(i) == "" {
+			keys = append(keys, fmt.Sprintf("#%d", i))
+			continue
+		}
+		keys = append(keys, fmt.Sprintf("%s#%d", id(i), seen[id(i)]))
+		seen[id(i)]++
+	}
+	return keys
+}
+
+// playlistMove takes the entry at From so that it ends up at index To
+type playlistMove struct {
+	Key      string
+	From, To int
+}
+
+// planReorder returns the moves that turn current into desired, two orderings
+// of the same keys. Entries on the longest run already in order stay put, so
+// dragging one track to a new place takes a single move.
+func planReorder(current, desired []string) []playlistMove {
+	rank := make(map[string]int, len(desired))
+	for i, key := range desired {
+		rank[key] = i
+	}
+
+	// Longest increasing run of desired ranks, by patience sorting
+	var tails []int // index into current of the smallest tail of each run length
+	prev := make([]int, len(current))
+	for i, key := range current {
+		lo, hi := 0, len(tails)
+		for lo < hi {
+			mid := (lo + hi) / 2
+			if rank[current[tails[mid]]] < rank[key] {
+				lo = mid + 1
+			} else {
+				hi = mid
+			}
+		}
+		prev[i] = -1
+		if lo > 0 {
+			prev[i] = tails[lo-1]
+		}
+		if lo == len(tails) {
+			tails = append(tails, i)
+		} else {
+			tails[lo] = i
+		}
+	}
+	settled := make(map[string]bool, len(tails))
+	if len(tails) > 0 {
+		for i := tails[len(tails)-1]; i >= 0; i = prev[i] {
+			settled[current[i]] = true
+		}
+	}
+
+	working := append([]string(nil), current...)
+	var moves []playlistMove
+	for d, key := range desired {
+		if settled[key] {
+			continue
+		}
+		from := indexOf(working, key)
+		// Place the entry right after its nearest settled predecessor
+		to := 0
+		for p := d - 1; p >= 0; p-- {
+			if settled[desired[p]] {
+				to = indexOf(working, desired[p])
+				if to < from {
+					to++
+				}
+				break
+			}
+		}
+		moves = append(moves, playlistMove{Key: key, From: from, To: to})
+		moveKey(working, from, to)
+		settled[key] = true
+	}
+	return moves
+}
+
+func indexOf(keys []string, key string) int {
+	for i, k := range keys {
+		if k == key {
+			return i
+		}
+	}
+	return -1
+}
+
+// moveKey moves the key at from so it ends up at index to
+func moveKey(keys []string, from, to int) {
+	key := keys[from]
+	if from < to {
+		copy(keys[from:to], keys[from+1:to+1])
+	} else {
+		copy(keys[to+1:from+1], keys[to:from])
+	}
+	keys[to] = key
+}
+
+// moveTrack applies one planned move to a playlist
+func (h *Handlers) moveTrack(ctx context.Context, service database.UserService, playlistID string, track Track, move playlistMove) error {
+	switch service.ServiceType {
+	case "spotify":
+		// Spotify inserts before a position counted before the track is taken out
+		insertBefore := move.To
+		if move.To > move.From {
+			insertBefore++
+		}
+		return h.Providers.Spotify.ReorderTracks(ctx, service.AccessToken, playlistID, move.From, insertBefore)
+	case "youtube":
+		return h.Providers.YouTube.MovePlaylistItem(ctx, service.AccessToken, track.ItemID, playlistID, track.ID, move.To)
+	default:
+		return fmt.Errorf("unsupported service: %s", service.ServiceType)
+	}
+}
@@ -0,0 +1,98 @@
+package handlers
+
+import (
+	"context"
+	"fmt"
+	"log"
+	"math/rand"
+	"time"
+
+	"server/internal/config"
+	"server/internal/database"
+	"server/internal/jobs"
+	"server/internal/ratelimit"
+)
+
+// Providers throttled beyond this pressure are skipped until the next run
+const syncPressureThreshold = 0.5
+
+// StartPlaylistSyncScheduler periodically refreshes stored playlists for every
+// connected service. It is disabled when PLAYLIST_SYNC_INTERVAL is 0.
+func (h *Handlers) StartPlaylistSyncScheduler(ctx context.Context) {
+	interval := config.Duration("PLAYLIST_SYNC_INTERVAL", time.Hour)
+	if interval <= 0 {
+		log.Printf("Periodic playlist sync disabled")
+		return
+	}
+	jitter := config.Duration("PLAYLIST_SYNC_JITTER", 5*time.Minute)
+
+	log.Printf("Periodic playlist sync every %v (jitter up to %v)", interval, jitter)
+
+	go func() {
+		for {
+			// Jitter keeps replicas and restarts from hitting providers in lockstep
+			wait := interval
+			if jitter > 0 {
+				wait += time.Duration(rand.Int63n(int64(jitter)))
+			}
+
+			select {
+			case <-ctx.Done():
+				return
+			case <-time.After(wait):
+				h.runScheduled(ctx, "playlist-sync", func() { h.schedulePlaylistSyncs(ctx, interval) })
+			}
+		}
+	}()
+}
+
+// schedulePlaylistSyncs queues a sync job for every stale service connection
+func (h *Handlers) schedulePlaylistSyncs(ctx context.Context, interval time.Duration) {
+	var services []database.UserService
+	if err := h.DB.WithContext(ctx).Find(&services).Error; err != nil {
+		log.Printf("Failed to load services for periodic sync: %v", err)
+		return
+	}
+
+	maxPerProvider := config.Int("PLAYLIST_SYNC_MAX_PER_PROVIDER", 100)
+	scheduled := make(map[string]int)
+	throttled := make(map[string]bool)
+
+	for _, service := range services {
+		if throttled[service.ServiceType] || scheduled[service.ServiceType] >= maxPerProvider {
+			continue
+		}
+
+		if pressure := h.Providers.Limiter.ServicePressure(ratelimit.ServiceType(service.ServiceType)); pressure >= syncPressureThreshold {
+			log.Printf("Skipping periodic %s sync, provider under rate-limit pressure (%.2f)", service.ServiceType, pressure)
+			throttled[service.ServiceType] = true
+			continue
+		}
+
+		// Skip connections that were synced recently, e.g. through a manual sync
+		var lastSyncedAt int64
+		h.DB.WithContext(ctx).Model(&database.Playlist{}).
+			Where("user_id = ? AND service_type = ?", service.UserID, service.ServiceType).
+			Select("COALESCE(MAX(last_synced_at), 0)").
+			Scan(&lastSyncedAt)
+		if time.Since(time.Unix(lastSyncedAt, 0)) < interval/2 {
+			continue
+		}
+
+		scheduled[service.ServiceType]++
+		h.Jobs.Enqueue(&jobs.Job{
+			ID:       fmt.Sprintf("sync-%d-%s", service.UserID, service.ServiceType),
+			Type:     "sync",
+			UserID:   service.UserID,
+			Priority: jobs.PriorityLow,
+			Run: func(ctx context.Context) error {
+				if err := h.Tokens.RefreshTokenIfNeeded(&service); err != nil {
+					return err
+				}
+				return h.syncServicePlaylists(ratelimit.WithUser(ctx, service.UserID), service.UserID, service)
+			},
+		})
+	}
+
+	log.Printf("Scheduled periodic playlist sync: %v", scheduled)
+}
@@ -0,0 +1,179 @@
+package handlers
+
+import (
+	"log"
+	"net/http"
+	"strconv"
+	"strings"
+
+	"server/internal/database"
+	"server/internal/i18n"
+	"server/internal/middleware"
+
+	"github.com/gin-gonic/gin"
+	"gorm.io/gorm"
+)
+
+type PlaylistTagsRequest struct {
+	Tags []string `json:"tags"`
+}
+
+type BatchTransferRequest struct {
+	Tag           string `json:"tag" binding:"required"`
+	TargetService string `json:"target_service" binding:"required"`
+	SourceService string `json:"source_service"` // optional, restricts the batch to one service
+}
+
+// normalizeTags trims, lowercases and de-duplicates tag names
+func normalizeTags(tags []string) []string {
+	seen := make(map[string]bool)
+	var normalized []string
+	for _, tag := range tags {
+		tag = strings.ToLower(strings.TrimSpace(tag))
+		if tag == "" || len(tag) > 64 || seen[tag] {
+			continue
+		}
+		seen[tag] = true
+		normalized = append(normalized, tag)
+	}
+	return normalized
+}
+
+// SetPlaylistTags replaces the tags on one of the user's stored playlists
+func (h *Handlers) SetPlaylistTags(c *gin.Context) {
+	user, exists := middleware.GetUserFromContext(c)
+	if !exists {
+		i18n.RespondError(c, http.StatusUnauthorized, i18n.CodeUnauthenticated, "")
+		return
+	}
+
+	playlistID, err := strconv.ParseUint(c.Param("id"), 10, 32)
+	if err != nil {
+		i18n.RespondError(c, http.StatusBadRequest, i18n.CodeInvalidPlaylistID, "")
+		return
+	}
+
+	var req PlaylistTagsRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		i18n.RespondError(c, http.StatusBadRequest, i18n.CodeInvalidRequest, err.Error())
+		return
+	}
+
+	var playlist database.Playlist
+	if err := h.DB.WithContext(c.Request.Context()).Where("id = ? AND user_id = ?", playlistID, user.ID).First(&playlist).Error; err != nil {
+		i18n.RespondError(c, http.StatusNotFound, i18n.CodePlaylistNotFound, "")
+		return
+	}
+
+	tags := normalizeTags(req.Tags)
+	err = h.DB.WithContext(c.Request.Context()).Transaction(func(tx *gorm.DB) error {
+		if err := tx.Unscoped().Where("playlist_id = ?", playlist.ID).Delete(&database.PlaylistTag{}).Error; err != nil {
+			return err
+		}
+		for _, tag := range tags {
+			if err := tx.Create(&database.PlaylistTag{PlaylistID: playlist.ID, UserID: user.ID, Tag: tag}).Error; err != nil {
+				return err
+			}
+		}
+		return nil
+	})
+	if err != nil {
+		log.Printf("Failed to update tags for playlist %d: %v", playlist.ID, err)
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to update tags"})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"playlist_id": playlist.ID,
+		"tags":        tags,
+	})
+}
+
+// GetPlaylistTags lists the user's tags with the number of playlists in each
+func (h *Handlers) GetPlaylistTags(c *gin.Context) {
+	user, exists := middleware.GetUserFromContext(c)
+	if !exists {
+		i18n.RespondError(c, http.StatusUnauthorized, i18n.CodeUnauthenticated, "")
+		return
+	}
+
+	var tags []struct {
+		Tag   string `json:"tag"`
+		Count int    `json:"count"`
+	}
+	result := h.DB.WithContext(c.Request.Context()).Model(&database.PlaylistTag{}).
+		Select("tag, COUNT(*) AS count").
+		Where("user_id = ?", user.ID).
+		Group("tag").Order("tag").
+		Scan(&tags)
+	if result.Error != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to fetch tags"})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"tags": tags})
+}
+
+// StartBatchTransfer starts a transfer for every stored playlist carrying a tag,
+// except those on the user's exclusion list
+func (h *Handlers) StartBatchTransfer(c *gin.Context) {
+	user, exists := middleware.GetUserFromContext(c)
+	if !exists {
+		i18n.RespondError(c, http.StatusUnauthorized, i18n.CodeUnauthenticated, "")
+		return
+	}
+
+	var req BatchTransferRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		i18n.RespondError(c, http.StatusBadRequest, i18n.CodeInvalidRequest, err.Error())
+		return
+	}
+
+	query := h.DB.WithContext(c.Request.Context()).
+		Joins("JOIN playlist_tags ON playlist_tags.playlist_id = playlists.id AND playlist_tags.deleted_at IS NULL").
+		Where("playlists.user_id = ? AND playlist_tags.tag = ?", user.ID, strings.ToLower(strings.TrimSpace(req.Tag))).
+		Where("playlists.service_type <> ?", req.TargetService)
+	if req.SourceService != "" {
+		query = query.Where("playlists.service_type = ?", req.SourceService)
+	}
+
+	var playlists []database.Playlist
+	if err := query.Find(&playlists).Error; err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to fetch playlists"})
+		return
+	}
+
+	if len(playlists) == 0 {
+		c.JSON(http.StatusNotFound, gin.H{"error": "No playlists with that tag to transfer"})
+		return
+	}
+
+	exclusions := loadPlaylistExclusions(h.DB.WithContext(c.Request.Context()), user.ID)
+	var transferIDs []uint
+	var failures []gin.H
+	var excluded []uint
+	for _, playlist := range playlists {
+		if exclusions.excludes(playlist.ServiceType, playlist.ServiceID, playlist.Name) {
+			excluded = append(excluded, playlist.ID)
+			continue
+		}
+		transfer, _, err := h.startTransferForUser(c.Request.Context(), user.ID, TransferRequest{
+			SourceService:    playlist.ServiceType,
+			SourcePlaylistID: playlist.ServiceID,
+			TargetService:    req.TargetService,
+			Priority:         "low", // bulk work yields to interactive transfers
+		})
+		if err != nil {
+			failures = append(failures, gin.H{"playlist_id": playlist.ID, "error": err.Error()})
+			continue
+		}
+		transferIDs = append(transferIDs, transfer.ID)
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"message":      "Batch transfer started",
+		"transfer_ids": transferIDs,
+		"failed":       failures,
+		"excluded":     excluded, // playlists on the user's exclusion list
+	})
+}
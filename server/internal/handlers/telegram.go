@@ -0,0 +1,220 @@
+package handlers
+
+import (
+	"context"
+	"crypto/subtle"
+	"fmt"
+	"log"
+	"net/http"
+	"os"
+	"strings"
+	"time"
+
+	"server/internal/database"
+	"server/internal/i18n"
+	"server/internal/middleware"
+	"server/internal/notifications"
+	"server/internal/telegram"
+
+	"github.com/gin-gonic/gin"
+)
+
+const telegramLinkCodeTTL = 10 * time.Minute
+
+const telegramHelp = `Commands:
+/link CODE - link this chat using a code from the web app
+/status - show your most recent transfers
+Paste a Spotify or YouTube playlist link to transfer it to your other service,
+or use /transfer LINK spotify|youtube to pick the target.`
+
+// InitTelegram points the bot's webhook at this server and registers Telegram notifications
+func (h *Handlers) InitTelegram(ctx context.Context) {
+	if !h.Telegram.Enabled() {
+		return
+	}
+
+	secret := os.Getenv("TELEGRAM_WEBHOOK_SECRET")
+	if secret == "" {
+		log.Printf("TELEGRAM_WEBHOOK_SECRET not set, Telegram integration disabled")
+		return
+	}
+
+	webhookURL := os.Getenv("BACKEND_URL") + "/api/integrations/telegram/webhook"
+	if err := h.Telegram.SetWebhook(ctx, webhookURL, secret); err != nil {
+		log.Printf("Failed to set Telegram webhook: %v", err)
+	}
+
+	notifications.Register(notifications.NewTelegramNotifier(h.Telegram))
+	log.Printf("Telegram integration enabled")
+}
+
+// HandleCreateTelegramLinkCode issues a short-lived code (and bot deep link) to link a Telegram chat
+func (h *Handlers) HandleCreateTelegramLinkCode(c *gin.Context) {
+	user, exists := middleware.GetUserFromContext(c)
+	if !exists {
+		i18n.RespondError(c, http.StatusUnauthorized, i18n.CodeUnauthenticated, "")
+		return
+	}
+
+	if !h.Telegram.Enabled() {
+		c.JSON(http.StatusNotFound, gin.H{"error": "Telegram integration is not enabled"})
+		return
+	}
+
+	code := generateLinkCode()
+	expiresAt := time.Now().Add(telegramLinkCodeTTL)
+
+	var link database.TelegramLink
+	h.DB.WithContext(c.Request.Context()).Where("user_id = ?", user.ID).FirstOrInit(&link, database.TelegramLink{UserID: user.ID})
+	link.LinkCode = code
+	link.LinkCodeExpiresAt = expiresAt.Unix()
+	if err := h.DB.WithContext(c.Request.Context()).Save(&link).Error; err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to create link code"})
+		return
+	}
+
+	response := gin.H{
+		"code":       code,
+		"expires_at": expiresAt,
+	}
+	if botUsername := os.Getenv("TELEGRAM_BOT_USERNAME"); botUsername != "" {
+		response["deep_link"] = fmt.Sprintf("https://t.me/%s?start=%s", botUsername, code)
+	}
+
+	c.JSON(http.StatusOK, response)
+}
+
+// HandleDeleteTelegramLink unlinks the user's Telegram chat
+func (h *Handlers) HandleDeleteTelegramLink(c *gin.Context) {
+	user, exists := middleware.GetUserFromContext(c)
+	if !exists {
+		i18n.RespondError(c, http.StatusUnauthorized, i18n.CodeUnauthenticated, "")
+		return
+	}
+
+	if err := h.DB.WithContext(c.Request.Context()).Where("user_id = ?", user.ID).Delete(&database.TelegramLink{}).Error; err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to unlink Telegram"})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"message": "Telegram unlinked"})
+}
+
+// HandleTelegramWebhook receives bot updates from Telegram
+func (h *Handlers) HandleTelegramWebhook(c *gin.Context) {
+	secret := os.Getenv("TELEGRAM_WEBHOOK_SECRET")
+	provided := c.GetHeader("X-Telegram-Bot-Api-Secret-Token")
+	if secret == "" || subtle.ConstantTimeCompare([]byte(secret), []byte(provided)) != 1 {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "Invalid webhook secret"})
+		return
+	}
+
+	var update telegram.Update
+	if err := c.ShouldBindJSON(&update); err != nil || update.Message == nil {
+		// Acknowledge anything we don't understand so Telegram doesn't redeliver it
+		c.Status(http.StatusOK)
+		return
+	}
+
+	chatID := update.Message.Chat.ID
+	reply := h.handleTelegramMessage(c.Request.Context(), chatID, strings.TrimSpace(update.Message.Text))
+	if err := h.Telegram.SendMessage(c.Request.Context(), chatID, reply); err != nil {
+		log.Printf("Failed to reply to Telegram chat %d: %v", chatID, err)
+	}
+
+	c.Status(http.StatusOK)
+}
+
+func (h *Handlers) handleTelegramMessage(ctx context.Context, chatID int64, text string) string {
+	fields := strings.Fields(text)
+	if len(fields) == 0 {
+		return telegramHelp
+	}
+
+	switch command := strings.SplitN(fields[0], "@", 2)[0]; command {
+	case "/start", "/link":
+		if len(fields) < 2 {
+			return telegramHelp
+		}
+		return h.linkTelegramChat(ctx, chatID, fields[1])
+	case "/status":
+		userID, ok := h.telegramLinkedUserID(ctx, chatID)
+		if !ok {
+			return "Link this chat first with /link CODE."
+		}
+		return h.recentTransfersSummary(ctx, userID)
+	case "/transfer":
+		if len(fields) < 2 {
+			return telegramHelp
+		}
+		target := ""
+		if len(fields) > 2 {
+			target = fields[2]
+		}
+		return h.startTelegramTransfer(ctx, chatID, fields[1], target)
+	case "/help":
+		return telegramHelp
+	default:
+		// Treat a pasted playlist link as a transfer request
+		if _, _, ok := parsePlaylistLink(fields[0]); ok {
+			return h.startTelegramTransfer(ctx, chatID, fields[0], "")
+		}
+		return telegramHelp
+	}
+}
+
+func (h *Handlers) linkTelegramChat(ctx context.Context, chatID int64, code string) string {
+	var link database.TelegramLink
+	err := h.DB.WithContext(ctx).Where("link_code = ? AND link_code_expires_at > ?", strings.ToUpper(code), time.Now().Unix()).First(&link).Error
+	if err != nil {
+		return "That link code is invalid or has expired. Generate a new one in the web app."
+	}
+
+	// A chat can only be linked to one user
+	h.DB.WithContext(ctx).Where("chat_id = ? AND id <> ?", chatID, link.ID).Delete(&database.TelegramLink{})
+
+	link.ChatID = chatID
+	link.LinkCode = ""
+	link.LinkCodeExpiresAt = 0
+	link.LinkedAt = time.Now().Unix()
+	if err := h.DB.WithContext(ctx).Save(&link).Error; err != nil {
+		return "Failed to link this chat, please try again."
+	}
+
+	return "This chat is now linked. Paste a playlist link to transfer it."
+}
+
+func (h *Handlers) startTelegramTransfer(ctx context.Context, chatID int64, playlistLink, target string) string {
+	userID, ok := h.telegramLinkedUserID(ctx, chatID)
+	if !ok {
+		return "Link this chat first with /link CODE."
+	}
+
+	source, playlistID, ok := parsePlaylistLink(playlistLink)
+	if !ok {
+		return "That doesn't look like a Spotify or YouTube playlist link."
+	}
+	if target == "" {
+		target = otherService(source)
+	}
+
+	transfer, _, err := h.startTransferForUser(ctx, userID, TransferRequest{
+		SourceService:    source,
+		SourcePlaylistID: playlistID,
+		TargetService:    strings.ToLower(target),
+	})
+	if err != nil {
+		return "Could not start transfer: " + err.Error()
+	}
+
+	return fmt.Sprintf("Transfer #%d to %s started. I'll keep you posted.", transfer.ID, getServiceDisplayName(transfer.TargetService))
+}
+
+// telegramLinkedUserID resolves the app user linked to a Telegram chat
+func (h *Handlers) telegramLinkedUserID(ctx context.Context, chatID int64) (uint, bool) {
+	var link database.TelegramLink
+	if err := h.DB.WithContext(ctx).Where("chat_id = ?", chatID).First(&link).Error; err != nil {
+		return 0, false
+	}
+	return link.UserID, true
+}
@@ -0,0 +1,86 @@
+package handlers
+
+import (
+	"context"
+	"log"
+	"net/http"
+
+	"server/internal/database"
+	"server/internal/identity"
+
+	"github.com/gin-gonic/gin"
+	"gorm.io/gorm"
+)
+
+// Matches at or above this confidence are remembered as the same recording
+const identityLinkConfidence = 0.8
+
+// resolveTrackIdentity returns the canonical track ID for a service track, or 0 if it cannot be resolved
+func resolveTrackIdentity(ctx context.Context, db *gorm.DB, track Track) uint {
+	canonical, err := identity.Resolve(ctx, db, trackInfo(track))
+	if err != nil {
+		log.Printf("Failed to resolve identity of %s track %s: %v", track.Service, track.ID, err)
+		return 0
+	}
+	return canonical.ID
+}
+
+// linkTrackIdentities records a confident cross-service match so later transfers can skip the search
+func linkTrackIdentities(ctx context.Context, db *gorm.DB, userID uint, source, target Track, confidence float64) {
+	if source.Service == "" || source.ID == "" || target.Service == "" || target.ID == "" {
+		return
+	}
+
+	if err := identity.Link(ctx, db, userID, trackInfo(source), trackInfo(target), confidence); err != nil {
+		log.Printf("Failed to link %s track %s to %s track %s: %v", source.Service, source.ID, target.Service, target.ID, err)
+	}
+}
+
+// knownTargetTrack returns the target service's version of a track without searching:
+// the track itself when it already lives there, or a counterpart linked before,
+// with the confidence of that link
+func knownTargetTrack(db *gorm.DB, userID uint, track Track, targetService string) (Track, float64, bool) {
+	if track.Service == targetService {
+		return track, 1.0, true
+	}
+
+	counterpartID, confidence, ok := identity.Counterpart(db, track.Service, track.ID, targetService)
+	if !ok {
+		counterpartID, confidence, ok = identity.UserCounterpart(db, userID, track.Service, track.ID, targetService)
+	}
+	if ok {
+		return Track{ID: counterpartID, Name: track.Name, Artist: track.Artist, Service: targetService}, confidence, true
+	}
+
+	return Track{}, 0, false
+}
+
+func trackInfo(track Track) identity.TrackInfo {
+	return identity.TrackInfo{
+		Service:  track.Service,
+		ID:       track.ID,
+		Title:    track.Name,
+		Artist:   track.Artist,
+		Album:    track.Album,
+		Duration: track.Duration,
+		ISRC:     track.ISRC,
+	}
+}
+
+// GetTrackIdentity returns the canonical track and all known service IDs for a service track
+func (h *Handlers) GetTrackIdentity(c *gin.Context) {
+	var mapping database.TrackIdentity
+	err := h.DB.WithContext(c.Request.Context()).Where("service_type = ? AND service_track_id = ?", c.Param("service"), c.Param("id")).First(&mapping).Error
+	if err != nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": "Track not known"})
+		return
+	}
+
+	var canonical database.CanonicalTrack
+	if err := h.DB.WithContext(c.Request.Context()).Preload("Identities").First(&canonical, mapping.CanonicalTrackID).Error; err != nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": "Track not known"})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"track": canonical})
+}
@@ -0,0 +1,299 @@
+package handlers
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log"
+	"net/http"
+	"sort"
+	"strconv"
+
+	"server/internal/database"
+	"server/internal/i18n"
+	"server/internal/jobs"
+	"server/internal/middleware"
+	"server/internal/ratelimit"
+
+	"github.com/gin-gonic/gin"
+)
+
+// DriftTrack is a transferred track whose place in the target playlist changed
+type DriftTrack struct {
+	TrackID          string `json:"track_id"`
+	Name             string `json:"name"`
+	Artist           string `json:"artist"`
+	ExpectedPosition int    `json:"expected_position"`
+	CurrentPosition  int    `json:"current_position,omitempty"` // 0 when removed
+}
+
+// TransferDrift compares a transfer's result with the target playlist as it is now
+type TransferDrift struct {
+	Expected  int          `json:"expected"`
+	Current   int          `json:"current"`
+	Removed   []DriftTrack `json:"removed"`
+	Moved     []DriftTrack `json:"moved"`
+	Added     int          `json:"added"` // tracks in the playlist that the transfer did not add
+	Reordered bool         `json:"reordered"`
+	InSync    bool         `json:"in_sync"`
+}
+
+// userTransferFromParam loads the transfer named by the :id parameter if it belongs to the user,
+// writing the error response otherwise
+func (h *Handlers) userTransferFromParam(c *gin.Context, userID uint) (database.Transfer, bool) {
+	var transfer database.Transfer
+	id, err := strconv.ParseUint(c.Param("id"), 10, 32)
+	if err != nil {
+		i18n.RespondError(c, http.StatusBadRequest, i18n.CodeInvalidTransferID, "")
+		return transfer, false
+	}
+	if err := h.DB.WithContext(c.Request.Context()).Where("id = ? AND user_id = ?", uint(id), userID).First(&transfer).Error; err != nil {
+		i18n.RespondError(c, http.StatusNotFound, i18n.CodeTransferNotFound, "")
+		return transfer, false
+	}
+	return transfer, true
+}
+
+// GetTransferDrift re-reads the target playlist of a transfer and reports tracks
+// removed or reordered since the transfer ran
+func (h *Handlers) GetTransferDrift(c *gin.Context) {
+	user, exists := middleware.GetUserFromContext(c)
+	if !exists {
+		i18n.RespondError(c, http.StatusUnauthorized, i18n.CodeUnauthenticated, "")
+		return
+	}
+
+	transfer, ok := h.userTransferFromParam(c, user.ID)
+	if !ok {
+		return
+	}
+
+	drift, _, status, err := h.transferDrift(c.Request.Context(), transfer)
+	if err != nil {
+		c.JSON(status, gin.H{"error": err.Error()})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"transfer_id": transfer.ID, "drift": drift})
+}
+
+// RepairTransfer re-adds the tracks that were removed from a transfer's target playlist
+func (h *Handlers) RepairTransfer(c *gin.Context) {
+	user, exists := middleware.GetUserFromContext(c)
+	if !exists {
+		i18n.RespondError(c, http.StatusUnauthorized, i18n.CodeUnauthenticated, "")
+		return
+	}
+
+	transfer, ok := h.userTransferFromParam(c, user.ID)
+	if !ok {
+		return
+	}
+
+	drift, targetService, status, err := h.transferDrift(c.Request.Context(), transfer)
+	if err != nil {
+		c.JSON(status, gin.H{"error": err.Error()})
+		return
+	}
+	if len(drift.Removed) == 0 {
+		c.JSON(http.StatusOK, gin.H{"message": "Nothing to repair", "drift": drift})
+		return
+	}
+	if err := h.verifyPlaylistOwner(c.Request.Context(), targetService, transfer.TargetPlaylistID); isOwnershipError(err) {
+		c.JSON(playlistErrorStatus(err), gin.H{"error": "Cannot add to target playlist: " + err.Error()})
+		return
+	}
+
+	h.Jobs.Enqueue(&jobs.Job{
+		ID:     fmt.Sprintf("repair-%d", transfer.ID),
+		Type:   "repair",
+		UserID: user.ID,
+		Run: func(ctx context.Context) error {
+			ctx = ratelimit.WithUser(ctx, transfer.UserID)
+			return h.repairTransfer(ctx, transfer, targetService, drift.Removed)
+		},
+	})
+	recordTransferEvent(h.DB.WithContext(c.Request.Context()), transfer.ID, "repair", "", fmt.Sprintf("Repair queued for %d removed tracks", len(drift.Removed)))
+
+	c.JSON(http.StatusOK, gin.H{"message": "Repair started", "tracks": len(drift.Removed)})
+}
+
+// transferDrift loads the transfer's target playlist, uncached, and diffs it against the tracks the
+// transfer added. On failure it returns the HTTP status to report.
+func (h *Handlers) transferDrift(ctx context.Context, transfer database.Transfer) (TransferDrift, database.UserService, int, error) {
+	var targetService database.UserService
+	if transfer.TargetPlaylistID == "" {
+		return TransferDrift{}, targetService, http.StatusConflict, fmt.Errorf("Transfer did not create a target playlist")
+	}
+
+	if err := h.DB.WithContext(ctx).Where("user_id = ? AND service_type = ?", transfer.UserID, transfer.TargetService).First(&targetService).Error; err != nil {
+		return TransferDrift{}, targetService, http.StatusBadRequest, fmt.Errorf("Target service not connected")
+	}
+	if err := h.Tokens.RefreshTokenIfNeeded(&targetService); err != nil {
+		return TransferDrift{}, targetService, http.StatusBadGateway, fmt.Errorf("Target service token refresh failed: %v", err)
+	}
+
+	// Drift is about the playlist as it is now, so skip the response cache
+	current, playlist, err := h.fetchPlaylistTracks(ratelimit.WithoutCache(ctx), transfer.TargetService, targetService.AccessToken, transfer.TargetPlaylistID)
+	if err != nil {
+		status := http.StatusBadGateway
+		if errors.Is(err, ratelimit.ErrProviderUnavailable) {
+			status = http.StatusServiceUnavailable
+		}
+		return TransferDrift{}, targetService, status, fmt.Errorf("Failed to read target playlist: %v", err)
+	}
+	// Tracks on a page that did not load would be reported as removed
+	if !playlist.Complete() {
+		return TransferDrift{}, targetService, http.StatusBadGateway, fmt.Errorf("Read only %d of the target playlist's %d tracks", playlist.Fetched, playlist.Total)
+	}
+
+	var expected []database.TransferTrack
+	if err := h.DB.WithContext(ctx).Where("transfer_id = ? AND status = ?", transfer.ID, "matched").Order("id").Find(&expected).Error; err != nil {
+		return TransferDrift{}, targetService, http.StatusInternalServerError, fmt.Errorf("Failed to load transfer tracks")
+	}
+
+	return diffTransferTracks(expected, current), targetService, http.StatusOK, nil
+}
+
+// diffTransferTracks reports which expected tracks are missing from current and
+// which kept tracks are no longer in the order the transfer added them
+func diffTransferTracks(expected []database.TransferTrack, current []Track) TransferDrift {
+	drift := TransferDrift{Expected: len(expected), Current: len(current), Removed: []DriftTrack{}, Moved: []DriftTrack{}}
+
+	positions := make(map[string]int, len(current))
+	for i, track := range current {
+		if _, seen := positions[track.ID]; !seen {
+			positions[track.ID] = i + 1
+		}
+	}
+
+	expectedIDs := make(map[string]bool, len(expected))
+	var kept []DriftTrack
+	for i, track := range expected {
+		expectedIDs[track.TargetTrackID] = true
+		entry := DriftTrack{
+			TrackID:          track.TargetTrackID,
+			Name:             track.TargetTrackName,
+			Artist:           track.TargetArtist,
+			ExpectedPosition: i + 1,
+		}
+		if position, ok := positions[track.TargetTrackID]; ok {
+			entry.CurrentPosition = position
+			kept = append(kept, entry)
+		} else {
+			drift.Removed = append(drift.Removed, entry)
+		}
+	}
+
+	for _, track := range current {
+		if !expectedIDs[track.ID] {
+			drift.Added++
+		}
+	}
+
+	// The longest run of kept tracks still in transfer order stayed put; the rest moved
+	stayed := longestIncreasingRun(kept)
+	for i, track := range kept {
+		if !stayed[i] {
+			drift.Reordered = true
+			drift.Moved = append(drift.Moved, track)
+		}
+	}
+
+	drift.InSync = len(drift.Removed) == 0 && !drift.Reordered
+	return drift
+}
+
+// longestIncreasingRun marks the largest subsequence of tracks whose current
+// positions increase, i.e. the tracks that can be considered unmoved
+func longestIncreasingRun(tracks []DriftTrack) []bool {
+	tails := []int{}                 // index of the last track of the best run of each length
+	prev := make([]int, len(tracks)) // predecessor of each track in its run
+	for i, track := range tracks {
+		n := sort.Search(len(tails), func(k int) bool {
+			return tracks[tails[k]].CurrentPosition >= track.CurrentPosition
+		})
+		prev[i] = -1
+		if n > 0 {
+			prev[i] = tails[n-1]
+		}
+		if n == len(tails) {
+			tails = append(tails, i)
+		} else {
+			tails[n] = i
+		}
+	}
+
+	stayed := make([]bool, len(tracks))
+	if len(tails) > 0 {
+		for i := tails[len(tails)-1]; i >= 0; i = prev[i] {
+			stayed[i] = true
+		}
+	}
+	return stayed
+}
+
+// repairTransfer appends removed tracks back to the target playlist. Tracks
+// already somewhere in the target, e.g. put back since the drift was read,
+// are not added a second time.
+func (h *Handlers) repairTransfer(ctx context.Context, transfer database.Transfer, targetService database.UserService, removed []DriftTrack) error {
+	current, playlist, err := h.fetchPlaylistTracks(ratelimit.WithoutCache(ctx), transfer.TargetService, targetService.AccessToken, transfer.TargetPlaylistID)
+	if err == nil && !playlist.Complete() {
+		err = fmt.Errorf("read only %d of %d tracks", playlist.Fetched, playlist.Total)
+	}
+	if err != nil {
+		recordTransferEvent(h.DB.WithContext(ctx), transfer.ID, "error", "", fmt.Sprintf("Repair could not read the target playlist: %v", err))
+		return err
+	}
+	present := make(map[string]bool, len(current))
+	for _, track := range current {
+		present[track.ID] = true
+	}
+
+	restored, skipped, back := 0, 0, 0
+	rules := loadContentRules(h.DB.WithContext(ctx), transfer.UserID)
+	var pending []DriftTrack
+	for _, track := range removed {
+		if present[track.TrackID] {
+			back++
+			continue
+		}
+		// Tracks the user has since added a skip rule for stay removed
+		if rule, ok := rules.match(Track{Name: track.Name, Artist: track.Artist}); ok && rule.Action == "skip" {
+			skipped++
+			continue
+		}
+		present[track.TrackID] = true
+		pending = append(pending, track)
+	}
+
+	// Spotify takes a batch of tracks per request
+	if transfer.TargetService == "spotify" {
+		if restored, err = h.addTracksToSpotifyPlaylist(ctx, targetService.AccessToken, transfer.TargetPlaylistID, driftTrackIDs(pending)); err != nil {
+			recordTransferEvent(h.DB.WithContext(ctx), transfer.ID, "error", "", fmt.Sprintf("Repair stopped after %d tracks: %v", restored, err))
+			return err
+		}
+		pending = nil
+	}
+
+	for _, track := range pending {
+		err := withTrackWriteRetry(ctx, "re-adding track", nil, func(ctx context.Context) error {
+			return h.addTrackToPlaylist(ctx, transfer.TargetService, targetService.AccessToken, transfer.TargetPlaylistID, track.TrackID)
+		}, func(ctx context.Context) (bool, error) {
+			return h.playlistHasTracks(ctx, transfer.TargetService, targetService.AccessToken, transfer.TargetPlaylistID, []string{track.TrackID})
+		})
+		if errors.Is(err, ratelimit.ErrProviderUnavailable) || ctx.Err() != nil {
+			recordTransferEvent(h.DB.WithContext(ctx), transfer.ID, "error", "", fmt.Sprintf("Repair stopped after %d tracks: %v", restored, err))
+			return err
+		}
+		if err != nil {
+			log.Printf("Failed to restore %s to playlist %s: %v", track.TrackID, transfer.TargetPlaylistID, err)
+			recordTransferEvent(h.DB.WithContext(ctx), transfer.ID, "error", "", fmt.Sprintf("Re-adding %s - %s failed: %v", track.Artist, track.Name, err))
+			continue
+		}
+		restored++
+	}
+
+	recordTransferEvent(h.DB.WithContext(ctx), transfer.ID, "repair", "", fmt.Sprintf("Restored %d of %d removed tracks, %d skipped by rule, %d already back", restored, len(removed), skipped, back))
+	return nil
+}
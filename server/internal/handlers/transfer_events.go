@@ -0,0 +1,47 @@
+package handlers
+
+import (
+	"fmt"
+	"log"
+
+	"server/internal/database"
+
+	"gorm.io/gorm"
+)
+
+// recordTransferEvent appends an entry to a transfer's event log
+func recordTransferEvent(db *gorm.DB, transferID uint, eventType string, status database.TransferStatus, message string) {
+	event := database.TransferEvent{
+		TransferID: transferID,
+		Type:       eventType,
+		Status:     status,
+		Message:    message,
+	}
+	if err := db.Create(&event).Error; err != nil {
+		log.Printf("Failed to record %s event for transfer %d: %v", eventType, transferID, err)
+	}
+}
+
+// updateTransfer writes fields to a transfer, logging status changes and errors
+// in the transfer's event log
+func updateTransfer(db *gorm.DB, transfer *database.Transfer, fields map[string]interface{}) {
+	if err := db.Model(transfer).Updates(fields).Error; err != nil {
+		log.Printf("Failed to update transfer %d: %v", transfer.ID, err)
+	}
+
+	message, _ := fields["error_message"].(string)
+	if status, ok := fields["status"].(database.TransferStatus); ok {
+		transfer.Status = status
+		recordTransferEvent(db, transfer.ID, "status", status, message)
+	} else if message != "" {
+		recordTransferEvent(db, transfer.ID, "error", "", message)
+	}
+}
+
+// trackRetryRecorder logs retries of a track's provider calls in the transfer's event log
+func trackRetryRecorder(db *gorm.DB, transferID uint, track Track) func(what string, attempt int, err error) {
+	return func(what string, attempt int, err error) {
+		recordTransferEvent(db, transferID, "retry", "",
+			fmt.Sprintf("Retry %d of %s for %s - %s: %v", attempt, what, track.Artist, track.Name, err))
+	}
+}
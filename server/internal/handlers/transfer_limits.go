@@ -0,0 +1,58 @@
+package handlers
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+
+	"server/internal/config"
+	"server/internal/database"
+)
+
+// Safety caps for small deployments; 0 disables a cap
+var (
+	maxTracksPerTransfer          = config.Int("MAX_TRACKS_PER_TRANSFER", 5000)
+	maxConcurrentTransfersPerUser = config.Int("MAX_CONCURRENT_TRANSFERS_PER_USER", 3)
+	maxPlaylistsPerSync           = config.Int("MAX_PLAYLISTS_PER_SYNC", 500)
+)
+
+// checkTransferLimits refuses a transfer while the user already has
+// MAX_CONCURRENT_TRANSFERS_PER_USER queued or running, or when the stored copy
+// of the source playlist is known to exceed MAX_TRACKS_PER_TRANSFER
+func (h *Handlers) checkTransferLimits(ctx context.Context, userID uint, req TransferRequest) (int, error) {
+	if maxConcurrentTransfersPerUser > 0 {
+		var active int64
+		h.DB.WithContext(ctx).Model(&database.Transfer{}).
+			Where("user_id = ? AND status IN ?", userID, database.ActiveTransferStatuses).
+			Count(&active)
+		if active >= int64(maxConcurrentTransfersPerUser) {
+			return http.StatusTooManyRequests, fmt.Errorf("You already have %d transfers in progress (limit %d), wait for one to finish", active, maxConcurrentTransfersPerUser)
+		}
+	}
+
+	var stored database.Playlist
+	if h.DB.WithContext(ctx).Where("user_id = ? AND service_type = ? AND service_id = ?", userID, req.SourceService, req.SourcePlaylistID).
+		First(&stored).Error == nil {
+		if err := checkTransferSize(stored.TrackCount); err != nil {
+			return http.StatusBadRequest, err
+		}
+	}
+	return http.StatusOK, nil
+}
+
+// checkTransferSize enforces MAX_TRACKS_PER_TRANSFER
+func checkTransferSize(tracks int) error {
+	if maxTracksPerTransfer > 0 && tracks > maxTracksPerTransfer {
+		return fmt.Errorf("Playlist has %d tracks, more than the %d allowed per transfer on this server", tracks, maxTracksPerTransfer)
+	}
+	return nil
+}
+
+// capSyncedPlaylists trims a fetched playlist list to MAX_PLAYLISTS_PER_SYNC,
+// reporting whether anything was left out
+func capSyncedPlaylists(playlists []PlaylistResponse) ([]PlaylistResponse, bool) {
+	if maxPlaylistsPerSync > 0 && len(playlists) > maxPlaylistsPerSync {
+		return playlists[:maxPlaylistsPerSync], true
+	}
+	return playlists, false
+}
@@ -0,0 +1,211 @@
+package handlers
+
+import (
+	"context"
+	"log"
+	"net/http"
+	"time"
+
+	"server/internal/config"
+	"server/internal/database"
+	"server/internal/i18n"
+	"server/internal/middleware"
+	"server/internal/quota"
+
+	"github.com/gin-gonic/gin"
+	"gorm.io/gorm"
+)
+
+// StartTransferPlanScheduler resumes split transfers whose next daily chunk is
+// due, transfers held for a maintenance window that has ended and library
+// transfers waiting to start their next steps
+func (h *Handlers) StartTransferPlanScheduler(ctx context.Context) {
+	interval := config.Duration("TRANSFER_PLAN_CHECK_INTERVAL", 5*time.Minute)
+	if interval <= 0 {
+		log.Printf("Transfer plan scheduler disabled")
+		return
+	}
+
+	go func() {
+		ticker := time.NewTicker(interval)
+		defer ticker.Stop()
+
+		for {
+			select {
+			case <-ctx.Done():
+				return
+			case <-ticker.C:
+				h.runScheduled(ctx, "transfer-plans", func() { h.scheduleDueTransferChunks(ctx) })
+				h.runScheduled(ctx, "maintenance-resume", func() { h.resumeMaintenanceTransfers(ctx) })
+				h.runScheduled(ctx, "library-transfers", func() { h.scheduleRunningLibraryTransfers(ctx) })
+			}
+		}
+	}()
+}
+
+// scheduleDueTransferChunks queues a job for every waiting transfer whose next chunk is due
+func (h *Handlers) scheduleDueTransferChunks(ctx context.Context) {
+	var chunks []database.TransferChunk
+	err := h.DB.WithContext(ctx).
+		Joins("JOIN transfers ON transfers.id = transfer_chunks.transfer_id").
+		Where("transfers.status = ? AND transfer_chunks.status = ? AND transfer_chunks.scheduled_for <= ?", database.TransferPaused, "pending", time.Now().Unix()).
+		Order("transfer_chunks.sequence").
+		Find(&chunks).Error
+	if err != nil {
+		log.Printf("Failed to load due transfer chunks: %v", err)
+		return
+	}
+
+	queued := make(map[uint]bool)
+	for _, chunk := range chunks {
+		if queued[chunk.TransferID] {
+			continue
+		}
+		queued[chunk.TransferID] = true
+
+		var transfer database.Transfer
+		if err := h.DB.WithContext(ctx).First(&transfer, chunk.TransferID).Error; err != nil {
+			continue
+		}
+		if err := h.resumeTransfer(ctx, transfer); err != nil {
+			log.Printf("Failed to resume transfer %d: %v", transfer.ID, err)
+			continue
+		}
+		log.Printf("Queued chunk %d of transfer %d", chunk.Sequence+1, transfer.ID)
+	}
+}
+
+// resumeTransfer queues the next chunk of a paused transfer
+func (h *Handlers) resumeTransfer(ctx context.Context, transfer database.Transfer) error {
+	var sourceService, targetService database.UserService
+	if transfer.PublicSource {
+		sourceService = database.UserService{UserID: transfer.UserID, ServiceType: transfer.SourceService}
+	} else if err := h.DB.WithContext(ctx).Where("user_id = ? AND service_type = ?", transfer.UserID, transfer.SourceService).First(&sourceService).Error; err != nil {
+		return err
+	}
+	if err := h.DB.WithContext(ctx).Where("user_id = ? AND service_type = ?", transfer.UserID, transfer.TargetService).First(&targetService).Error; err != nil {
+		return err
+	}
+
+	setTransferStatus(h.DB.WithContext(ctx), &transfer, database.TransferPending)
+	h.enqueueTransfer(transfer, sourceService, targetService, transfer.TargetPlaylistName)
+	return nil
+}
+
+// planTransferChunks replaces the pending chunks of a transfer with a fresh plan
+// for the tracks from offset on. costs holds the quota units each source track needs.
+func planTransferChunks(db *gorm.DB, transferID uint, costs []int, offset int, startNow bool) []database.TransferChunk {
+	db.Where("transfer_id = ? AND status = ?", transferID, "pending").Delete(&database.TransferChunk{})
+
+	var sequence int64
+	db.Model(&database.TransferChunk{}).Where("transfer_id = ?", transferID).Count(&sequence)
+
+	daily := quota.DailyLimit("youtube")
+	now := time.Now()
+	at, budget := quota.NextReset(now), daily
+	if startNow {
+		at, budget = now, quota.Remaining("youtube")
+	}
+
+	var chunks []database.TransferChunk
+	for start := offset; start < len(costs); {
+		end, units := start, 0
+		for end < len(costs) && (daily <= 0 || units+costs[end] <= budget) {
+			units += costs[end]
+			end++
+		}
+		if end > start {
+			chunks = append(chunks, database.TransferChunk{
+				TransferID:   transferID,
+				Sequence:     int(sequence) + len(chunks),
+				StartIndex:   start,
+				EndIndex:     end,
+				QuotaUnits:   units,
+				ScheduledFor: at.Unix(),
+				Status:       "pending",
+			})
+			start = end
+		} else if budget >= daily {
+			// A track that does not fit in a whole day would never be scheduled
+			break
+		}
+		at, budget = quota.NextReset(at), daily
+	}
+
+	if len(chunks) > 0 {
+		if err := db.Create(&chunks).Error; err != nil {
+			log.Printf("Failed to save plan for transfer %d: %v", transferID, err)
+		}
+	}
+	return chunks
+}
+
+// claimTransferChunk returns the range of source tracks the current run of a
+// split transfer covers, planning the transfer on its first run
+func claimTransferChunk(db *gorm.DB, transfer *database.Transfer, costs []int, offset int) (int, bool) {
+	var chunk database.TransferChunk
+	err := db.Where("transfer_id = ? AND status IN ?", transfer.ID, []string{"pending", "running"}).
+		Order("sequence").First(&chunk).Error
+	if err != nil {
+		chunks := planTransferChunks(db, transfer.ID, costs, offset, true)
+		if len(chunks) == 0 {
+			return len(costs), true
+		}
+		chunk = chunks[0]
+	}
+
+	if chunk.ScheduledFor > time.Now().Unix() {
+		return offset, false
+	}
+
+	db.Model(&chunk).Update("status", "running")
+	return chunk.EndIndex, true
+}
+
+// finishTransferChunk marks the running chunk of a transfer as done
+func finishTransferChunk(db *gorm.DB, transferID uint) {
+	db.Model(&database.TransferChunk{}).
+		Where("transfer_id = ? AND status = ?", transferID, "running").
+		Update("status", "completed")
+}
+
+// GetTransferPlan returns the daily chunks of a split transfer
+func (h *Handlers) GetTransferPlan(c *gin.Context) {
+	user, exists := middleware.GetUserFromContext(c)
+	if !exists {
+		i18n.RespondError(c, http.StatusUnauthorized, i18n.CodeUnauthenticated, "")
+		return
+	}
+
+	transfer, ok := h.userTransferFromParam(c, user.ID)
+	if !ok {
+		return
+	}
+
+	var chunks []database.TransferChunk
+	if err := h.DB.WithContext(c.Request.Context()).Where("transfer_id = ?", transfer.ID).Order("sequence").Find(&chunks).Error; err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to load transfer plan"})
+		return
+	}
+
+	plan := make([]gin.H, 0, len(chunks))
+	for _, chunk := range chunks {
+		plan = append(plan, gin.H{
+			"sequence":      chunk.Sequence,
+			"first_track":   chunk.StartIndex + 1,
+			"last_track":    chunk.EndIndex,
+			"tracks":        chunk.EndIndex - chunk.StartIndex,
+			"quota_units":   chunk.QuotaUnits,
+			"scheduled_for": chunk.ScheduledFor,
+			"status":        chunk.Status,
+		})
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"transfer_id":  transfer.ID,
+		"status":       transfer.Status,
+		"tracks_total": transfer.TracksTotal,
+		"split":        transfer.SplitAcrossDays,
+		"chunks":       plan,
+	})
+}
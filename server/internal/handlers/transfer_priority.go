@@ -0,0 +1,56 @@
+package handlers
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"time"
+
+	"server/internal/config"
+	"server/internal/database"
+	"server/internal/jobs"
+)
+
+// transferJobPriorities maps a transfer's priority to its job queue priority
+var transferJobPriorities = map[string]int{
+	"low":    jobs.PriorityLow,
+	"normal": jobs.PriorityNormal,
+	"high":   jobs.PriorityHigh,
+}
+
+// checkTransferPriority validates a requested priority against the operator's
+// caps: HIGH_PRIORITY_TRANSFERS_PER_DAY per user (0 disables high priority)
+// and HIGH_PRIORITY_MAX_QUEUED unfinished high priority transfers overall
+func (h *Handlers) checkTransferPriority(ctx context.Context, userID uint, priority string) (int, error) {
+	if _, ok := transferJobPriorities[priority]; !ok {
+		return http.StatusBadRequest, fmt.Errorf("Invalid priority")
+	}
+	if priority != "high" {
+		return http.StatusOK, nil
+	}
+
+	perDay := config.Int("HIGH_PRIORITY_TRANSFERS_PER_DAY", 5)
+	if perDay <= 0 {
+		return http.StatusForbidden, fmt.Errorf("High priority transfers are disabled")
+	}
+
+	var usedToday int64
+	h.DB.WithContext(ctx).Model(&database.Transfer{}).
+		Where("user_id = ? AND priority = ? AND created_at > ?", userID, "high", time.Now().Add(-24*time.Hour)).
+		Count(&usedToday)
+	if usedToday >= int64(perDay) {
+		return http.StatusTooManyRequests, fmt.Errorf("High priority limit reached (%d per day)", perDay)
+	}
+
+	if maxQueued := config.Int("HIGH_PRIORITY_MAX_QUEUED", 20); maxQueued > 0 {
+		var queued int64
+		h.DB.WithContext(ctx).Model(&database.Transfer{}).
+			Where("priority = ? AND status IN ?", "high", database.ActiveTransferStatuses).
+			Count(&queued)
+		if queued >= int64(maxQueued) {
+			return http.StatusTooManyRequests, fmt.Errorf("Too many high priority transfers are queued, try again later or use normal priority")
+		}
+	}
+
+	return http.StatusOK, nil
+}
@@ -0,0 +1,90 @@
+package handlers
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log"
+	"net/http"
+	"strconv"
+	"time"
+
+	"server/internal/database"
+	"server/internal/i18n"
+	"server/internal/middleware"
+	"server/internal/storage"
+
+	"github.com/gin-gonic/gin"
+	"gorm.io/gorm"
+)
+
+// TransferReport is the archived record of a finished transfer, kept in
+// storage so it outlives the transfer's rows in the database
+type TransferReport struct {
+	Transfer   database.Transfer        `json:"transfer"`
+	Tracks     []database.TransferTrack `json:"tracks"`
+	Events     []database.TransferEvent `json:"events"`
+	ArchivedAt int64                    `json:"archived_at"`
+}
+
+func transferReportKey(userID, transferID uint) string {
+	return fmt.Sprintf("reports/%d/transfer-%d.json", userID, transferID)
+}
+
+// archiveTransferReport stores the report of a transfer once it has finished.
+// Reruns and repairs overwrite it with the latest outcome.
+func archiveTransferReport(db *gorm.DB, transferID uint) {
+	var report TransferReport
+	if err := db.First(&report.Transfer, transferID).Error; err != nil {
+		return
+	}
+	if report.Transfer.Status.Phase() != "finished" {
+		return
+	}
+	db.Where("transfer_id = ?", transferID).Order("id").Find(&report.Tracks)
+	db.Where("transfer_id = ?", transferID).Order("id").Find(&report.Events)
+	report.ArchivedAt = time.Now().Unix()
+
+	body, err := json.Marshal(report)
+	if err != nil {
+		log.Printf("Failed to encode report for transfer %d: %v", transferID, err)
+		return
+	}
+	key := transferReportKey(report.Transfer.UserID, transferID)
+	if err := storage.Default.Put(db.Statement.Context, key, bytes.NewReader(body), int64(len(body)), "application/json"); err != nil {
+		log.Printf("Failed to archive report for transfer %d: %v", transferID, err)
+	}
+}
+
+// GetTransferReport downloads the archived report of a finished transfer
+func (h *Handlers) GetTransferReport(c *gin.Context) {
+	user, exists := middleware.GetUserFromContext(c)
+	if !exists {
+		i18n.RespondError(c, http.StatusUnauthorized, i18n.CodeUnauthenticated, "")
+		return
+	}
+
+	transferID, err := strconv.ParseUint(c.Param("id"), 10, 32)
+	if err != nil {
+		i18n.RespondError(c, http.StatusBadRequest, i18n.CodeInvalidTransferID, "")
+		return
+	}
+
+	// The user ID in the key keeps users to their own reports
+	body, err := storage.Default.Get(c.Request.Context(), transferReportKey(user.ID, uint(transferID)))
+	if errors.Is(err, storage.ErrNotFound) {
+		c.JSON(http.StatusNotFound, gin.H{"error": "No report archived for this transfer"})
+		return
+	}
+	if err != nil {
+		log.Printf("Failed to read report for transfer %d: %v", transferID, err)
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to read report"})
+		return
+	}
+	defer body.Close()
+
+	c.DataFromReader(http.StatusOK, -1, "application/json", body, map[string]string{
+		"Content-Disposition": fmt.Sprintf(`attachment; filename="transfer-%d.json"`, transferID),
+	})
+}
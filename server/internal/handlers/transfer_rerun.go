@@ -0,0 +1,60 @@
+package handlers
+
+import (
+	"fmt"
+	"net/http"
+
+	"server/internal/i18n"
+	"server/internal/middleware"
+
+	"github.com/gin-gonic/gin"
+)
+
+// RerunTransfer starts a new transfer with the source, target and options of a
+// past one, e.g. after the source playlist changed
+func (h *Handlers) RerunTransfer(c *gin.Context) {
+	user, exists := middleware.GetUserFromContext(c)
+	if !exists {
+		i18n.RespondError(c, http.StatusUnauthorized, i18n.CodeUnauthenticated, "")
+		return
+	}
+
+	original, ok := h.userTransferFromParam(c, user.ID)
+	if !ok {
+		return
+	}
+
+	if original.Status.Phase() != "finished" {
+		c.JSON(http.StatusConflict, gin.H{"error": "Transfer is still in progress"})
+		return
+	}
+
+	transfer, status, err := h.startTransferForUser(c.Request.Context(), user.ID, TransferRequest{
+		SourceService:       original.SourceService,
+		SourcePlaylistID:    original.SourcePlaylistID,
+		TargetService:       original.TargetService,
+		TargetPlaylistName:  original.TargetPlaylistName,
+		StartPlayback:       original.StartPlayback,
+		YouTubeVideoType:    original.YouTubeVideoType,
+		DescriptionTemplate: original.DescriptionTemplate,
+		SplitAcrossDays:     original.SplitAcrossDays,
+		Priority:            original.Priority,
+		OnNameConflict:      original.OnNameConflict,
+		Privacy:             original.Privacy,
+		OrderByAddedAt:      original.OrderByAddedAt,
+		SearchStrategies:    splitSearchStrategies(original.SearchStrategies),
+		SearchLimit:         original.SearchLimit,
+		rerunOf:             &original.ID,
+	})
+	if err != nil {
+		c.JSON(status, gin.H{"error": err.Error()})
+		return
+	}
+
+	recordTransferEvent(h.DB.WithContext(c.Request.Context()), transfer.ID, "status", transfer.Status, fmt.Sprintf("Re-run of transfer %d", original.ID))
+
+	c.JSON(http.StatusOK, gin.H{
+		"message":     "Transfer started",
+		"transfer_id": transfer.ID,
+	})
+}
@@ -0,0 +1,128 @@
+package handlers
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log"
+	"time"
+
+	"server/internal/config"
+	"server/internal/database"
+	"server/internal/quota"
+	"server/internal/ratelimit"
+
+	"gorm.io/gorm"
+)
+
+var (
+	transferVerifyEnabled = config.Bool("TRANSFER_VERIFY_ENABLED", true)
+	// Provider playlist reads can lag behind inserts for a moment
+	transferVerifyDelay = config.Duration("TRANSFER_VERIFY_DELAY", 5*time.Second)
+)
+
+// verifyTransfer re-reads the target playlist of a finished transfer and checks
+// that every matched track is in it, since YouTube sometimes accepts an insert
+// without adding the video. Missing tracks are inserted again once; those still
+// missing afterwards are reported as failed.
+func (h *Handlers) verifyTransfer(ctx context.Context, db *gorm.DB, transfer *database.Transfer) {
+	if !transferVerifyEnabled || transfer.TracksMatched == 0 || transfer.TargetPlaylistID == "" {
+		return
+	}
+	ctx = ratelimit.WithoutCache(ctx)
+
+	missing, targetService, ok := h.missingTargetTracks(ctx, db, transfer)
+	if !ok || len(missing) == 0 {
+		return
+	}
+	log.Printf("Transfer %d: %d matched tracks missing from the target playlist, adding them again", transfer.ID, len(missing))
+	recordTransferEvent(db, transfer.ID, "verify", "", fmt.Sprintf("%d matched tracks missing from the target playlist, adding them again", len(missing)))
+
+	if transfer.TargetService == "spotify" {
+		if _, err := h.addTracksToSpotifyPlaylist(ctx, targetService.AccessToken, transfer.TargetPlaylistID, driftTrackIDs(missing)); err != nil {
+			log.Printf("Failed to re-add tracks to playlist %s: %v", transfer.TargetPlaylistID, err)
+		}
+	} else {
+		for _, track := range missing {
+			if remaining := quota.Remaining("youtube"); remaining >= 0 && remaining < quota.YouTubeWriteCost {
+				recordTransferEvent(db, transfer.ID, "warning", "", "YouTube quota ran out while re-adding missing tracks")
+				break
+			}
+			err := withTrackWriteRetry(ctx, "re-adding track", nil, func(ctx context.Context) error {
+				return h.addTrackToPlaylist(ctx, transfer.TargetService, targetService.AccessToken, transfer.TargetPlaylistID, track.TrackID)
+			}, func(ctx context.Context) (bool, error) {
+				return h.playlistHasTracks(ctx, transfer.TargetService, targetService.AccessToken, transfer.TargetPlaylistID, []string{track.TrackID})
+			})
+			if errors.Is(err, ratelimit.ErrProviderUnavailable) || ctx.Err() != nil {
+				break
+			}
+			if err != nil {
+				log.Printf("Failed to re-add %s to playlist %s: %v", track.TrackID, transfer.TargetPlaylistID, err)
+			}
+		}
+	}
+
+	// Without a second read the report is left as it was rather than guessed
+	if missing, _, ok = h.missingTargetTracks(ctx, db, transfer); !ok {
+		return
+	}
+	markTracksMissing(db, transfer, missing)
+}
+
+// missingTargetTracks waits for the target playlist to settle and returns the
+// matched tracks it lacks; ok is false when the playlist could not be read
+func (h *Handlers) missingTargetTracks(ctx context.Context, db *gorm.DB, transfer *database.Transfer) ([]DriftTrack, database.UserService, bool) {
+	select {
+	case <-ctx.Done():
+		return nil, database.UserService{}, false
+	case <-time.After(transferVerifyDelay):
+	}
+
+	drift, targetService, _, err := h.transferDrift(ctx, *transfer)
+	if err != nil {
+		log.Printf("Failed to verify transfer %d: %v", transfer.ID, err)
+		recordTransferEvent(db, transfer.ID, "warning", "", "Could not verify the target playlist: "+err.Error())
+		return nil, targetService, false
+	}
+	return drift.Removed, targetService, true
+}
+
+// markTracksMissing reports matched tracks that never made it into the target
+// playlist as failed and adjusts the transfer's counts and status
+func markTracksMissing(db *gorm.DB, transfer *database.Transfer, missing []DriftTrack) {
+	if len(missing) == 0 {
+		recordTransferEvent(db, transfer.ID, "verify", "", "All matched tracks are in the target playlist")
+		return
+	}
+
+	result := db.Model(&database.TransferTrack{}).
+		Where("transfer_id = ? AND status = ? AND target_track_id IN ?", transfer.ID, "matched", driftTrackIDs(missing)).
+		Update("status", "missing_from_target")
+	if result.Error != nil {
+		log.Printf("Failed to mark missing tracks of transfer %d: %v", transfer.ID, result.Error)
+		return
+	}
+
+	n := int(result.RowsAffected)
+	transfer.TracksMatched -= n
+	transfer.TracksFailed += n
+	status := database.TransferCompletedWithErrors
+	if transfer.TracksMatched == 0 {
+		status = database.TransferFailed
+	}
+	recordTransferEvent(db, transfer.ID, "verify", "", fmt.Sprintf("%d tracks could not be added to the target playlist", n))
+	fields := map[string]interface{}{"tracks_matched": transfer.TracksMatched, "tracks_failed": transfer.TracksFailed}
+	if status != transfer.Status {
+		fields["status"] = status
+	}
+	updateTransfer(db, transfer, fields)
+}
+
+// driftTrackIDs returns the target track IDs of tracks
+func driftTrackIDs(tracks []DriftTrack) []string {
+	ids := make([]string, 0, len(tracks))
+	for _, track := range tracks {
+		ids = append(ids, track.TrackID)
+	}
+	return ids
+}
@@ -1,18 +1,28 @@
 package handlers
 
 import (
-	"encoding/json"
+	"context"
+	"errors"
 	"fmt"
-	"io"
 	"log"
 	"net/http"
-	"net/url"
-	"regexp"
+	"slices"
+	"sort"
 	"strconv"
 	"strings"
+	"time"
 
+	"server/internal/config"
 	"server/internal/database"
+	"server/internal/i18n"
+	"server/internal/jobs"
+	"server/internal/maintenance"
+	"server/internal/match"
 	"server/internal/middleware"
+	"server/internal/providers"
+	"server/internal/providers/spotify"
+	"server/internal/providers/youtube"
+	"server/internal/quota"
 	"server/internal/ratelimit"
 
 	"github.com/gin-gonic/gin"
@@ -20,10 +30,58 @@ import (
 )
 
 type TransferRequest struct {
-	SourceService      string `json:"source_service" binding:"required"`
-	SourcePlaylistID   string `json:"source_playlist_id" binding:"required"`
-	TargetService      string `json:"target_service" binding:"required"`
-	TargetPlaylistName string `json:"target_playlist_name"`
+	SourceService       string   `json:"source_service" binding:"required"`
+	SourcePlaylistID    string   `json:"source_playlist_id" binding:"required"`
+	TargetService       string   `json:"target_service" binding:"required"`
+	TargetPlaylistName  string   `json:"target_playlist_name"`
+	TargetPlaylistID    string   `json:"target_playlist_id"`   // add to this existing playlist instead of creating one
+	StartPlayback       bool     `json:"start_playback"`       // Spotify targets only
+	YouTubeVideoType    string   `json:"youtube_video_type"`   // YouTube targets only, see youtubeVideoTypes
+	DescriptionTemplate string   `json:"description_template"` // see renderDescription
+	SplitAcrossDays     bool     `json:"split_across_days"`    // allow transfers larger than today's YouTube quota
+	Priority            string   `json:"priority"`             // "low", "normal" (default) or "high", see checkTransferPriority
+	OnNameConflict      string   `json:"on_name_conflict"`     // see nameConflictModes, "duplicate" by default
+	Privacy             string   `json:"privacy"`              // see privacyOptions; the user's default_privacy when empty
+	OrderByAddedAt      bool     `json:"order_by_added_at"`    // add tracks in the order they were added to the source
+	SearchStrategies    []string `json:"search_strategies"`    // order of search strategies, see searchStrategies; the provider's when empty
+	SearchLimit         int      `json:"search_limit"`         // candidates requested per search, the provider's when 0
+
+	rerunOf      *uint // set by RerunTransfer
+	autoSyncRule *uint // set when an auto-sync rule mirrors a new playlist
+}
+
+// SourcePlaylist holds the metadata of a playlist whose tracks were fetched
+type SourcePlaylist struct {
+	Name          string
+	Collaborative bool
+	OwnerID       string
+	OwnerName     string
+	Privacy       string // "public", "private" or "unlisted" (YouTube only), "" if unknown
+	SnapshotID    string // Spotify only, see spotify.Playlist
+	Total         int    // item count the provider reports for the playlist
+	Fetched       int    // items read, including ones later skipped as non-music
+}
+
+// Complete reports whether every item the provider counts in the playlist was
+// read. A short read means a page went missing, so nothing should be inferred
+// from what isn't there.
+func (p SourcePlaylist) Complete() bool {
+	return p.Fetched >= p.Total
+}
+
+// SearchOptions tunes how tracks are looked up on the target service
+type SearchOptions struct {
+	YouTubeVideoType string   // preferred kind of upload, see youtubeVideoTypes
+	Market           string   // ISO 3166-1 country the user listens from, "" if unknown
+	MatchStrategy    string   // "fuzzy" or "isrc_first"
+	Strategies       []string // search strategy order, see searchStrategies; the provider's when empty
+	Limit            int      // candidates requested per search, the provider's when 0
+}
+
+// PlaylistCreateOptions controls how a target playlist is created
+type PlaylistCreateOptions struct {
+	Collaborative bool   // Spotify only; collaborative playlists must be private
+	Privacy       string // "private", "unlisted" or "public", see targetPrivacy
 }
 
 type Track struct {
@@ -33,69 +91,332 @@ type Track struct {
 	Album    string `json:"album"`
 	Duration int    `json:"duration"`
 	ISRC     string `json:"isrc"`
+	Service  string `json:"service,omitempty"`  // service the ID belongs to
+	AddedAt  int64  `json:"added_at,omitempty"` // when the track was added to its playlist
+	AddedBy  string `json:"added_by,omitempty"` // service user ID of whoever added it
+	Explicit bool   `json:"explicit,omitempty"` // Spotify sources only
+
+	VideoCategory string `json:"video_category,omitempty"` // YouTube category ID, YouTube sources only
+	ChannelID     string `json:"channel_id,omitempty"`     // channel that uploaded the video
+	ChannelTitle  string `json:"channel_title,omitempty"`
+
+	PreviewURL   string `json:"preview_url,omitempty"`  // 30-second audio clip (Spotify only)
+	MatchedBy    string `json:"matched_by,omitempty"`   // search strategy that found this track as a match
+	EntityType   string `json:"entity_type,omitempty"`  // kind of YouTube upload, see youtubeEntityChain
+	Substitution string `json:"substitution,omitempty"` // why the best-ranked match was passed over: "age_restricted", "region_blocked"
+	Unsupported  string `json:"unsupported,omitempty"`  // why it cannot be transferred: "local_file", "episode", "unavailable" (removed from the service)
+	ItemID       string `json:"item_id,omitempty"`      // YouTube playlist item the track is in, used to move it
+}
+
+// errTrackNotFound means a search returned no results
+var errTrackNotFound = errors.New("track not found")
+
+// unixOrZero converts an optional provider timestamp, keeping missing values at 0
+func unixOrZero(t time.Time) int64 {
+	if t.IsZero() {
+		return 0
+	}
+	return t.Unix()
+}
+
+// supportedTracks leaves out source tracks that cannot be transferred
+func supportedTracks(tracks []Track) []Track {
+	supported := make([]Track, 0, len(tracks))
+	for _, track := range tracks {
+		if track.Unsupported == "" {
+			supported = append(supported, track)
+		}
+	}
+	return supported
+}
+
+// addedBy is the Spotify user who added a playlist item, "" for items too old to record it
+func addedBy(owner *spotify.Owner) string {
+	if owner == nil {
+		return ""
+	}
+	return owner.ID
 }
 
 // In StartTransfer function, make sure we save the transfer before starting the goroutine
-func StartTransfer(c *gin.Context) {
+func (h *Handlers) StartTransfer(c *gin.Context) {
 	user, exists := middleware.GetUserFromContext(c)
 	if !exists {
-		c.JSON(http.StatusUnauthorized, gin.H{"error": "User not authenticated"})
+		i18n.RespondError(c, http.StatusUnauthorized, i18n.CodeUnauthenticated, "")
 		return
 	}
 
 	var req TransferRequest
 	if err := c.ShouldBindJSON(&req); err != nil {
-		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid request: " + err.Error()})
+		i18n.RespondError(c, http.StatusBadRequest, i18n.CodeInvalidRequest, err.Error())
 		return
 	}
 
-	// Validate services are connected
-	var sourceService, targetService database.UserService
-	if err := database.DB.Where("user_id = ? AND service_type = ?", user.ID, req.SourceService).First(&sourceService).Error; err != nil {
-		c.JSON(http.StatusBadRequest, gin.H{"error": "Source service not connected"})
+	transfer, status, err := h.startTransferForUser(c.Request.Context(), user.ID, req)
+	if errors.Is(err, errTargetNameExists) {
+		c.JSON(status, gin.H{"error": err.Error(), "code": errCodeTargetNameExists})
 		return
 	}
-	if err := database.DB.Where("user_id = ? AND service_type = ?", user.ID, req.TargetService).First(&targetService).Error; err != nil {
-		c.JSON(http.StatusBadRequest, gin.H{"error": "Target service not connected"})
+	var overQuota *quotaShortfallError
+	if errors.As(err, &overQuota) {
+		c.JSON(status, gin.H{"error": err.Error(), "estimate": overQuota.estimate})
 		return
 	}
+	if err != nil {
+		c.JSON(status, gin.H{"error": err.Error()})
+		return
+	}
+
+	message := "Transfer started"
+	if transfer.Status == database.TransferMaintenance {
+		message = "Transfer queued until maintenance ends"
+	}
+	c.JSON(http.StatusOK, gin.H{
+		"message":     message,
+		"transfer_id": transfer.ID,
+	})
+}
+
+// quotaShortfallError refuses a transfer that cannot finish within today's
+// YouTube quota and was not allowed to split across days
+type quotaShortfallError struct {
+	estimate TransferEstimate
+}
+
+func (e *quotaShortfallError) Error() string {
+	return "Playlist does not fit in today's YouTube quota; set split_across_days to continue over several days"
+}
+
+// startTransferForUser validates the request, records the transfer and queues
+// it for processing. On failure it returns the HTTP status to report.
+func (h *Handlers) startTransferForUser(ctx context.Context, userID uint, req TransferRequest) (database.Transfer, int, error) {
+	// Validate services are connected
+	var sourceService, targetService database.UserService
+	publicSource := false
+	if err := h.DB.WithContext(ctx).Where("user_id = ? AND service_type = ?", userID, req.SourceService).First(&sourceService).Error; err != nil {
+		// Without a connection the source must be a public playlist read with app credentials
+		if _, err := h.appAccessToken(ctx, req.SourceService); err != nil {
+			return database.Transfer{}, http.StatusBadRequest, fmt.Errorf("Source service not connected")
+		}
+		if isPersonalCollection(req.SourceService, req.SourcePlaylistID) {
+			return database.Transfer{}, http.StatusBadRequest, fmt.Errorf("Source service not connected")
+		}
+		sourceService = database.UserService{UserID: userID, ServiceType: req.SourceService}
+		publicSource = true
+	}
+	if err := h.DB.WithContext(ctx).Where("user_id = ? AND service_type = ?", userID, req.TargetService).First(&targetService).Error; err != nil {
+		return database.Transfer{}, http.StatusBadRequest, fmt.Errorf("Target service not connected")
+	}
+	for _, service := range []database.UserService{sourceService, targetService} {
+		if service.Status == database.ServiceNeedsReauth {
+			return database.Transfer{}, http.StatusConflict, fmt.Errorf("Reconnect %s before transferring", getServiceDisplayName(service.ServiceType))
+		}
+	}
+
+	// During maintenance new transfers are refused, or recorded and held until it ends
+	window, inMaintenance := maintenance.For(req.SourceService, req.TargetService)
+	if inMaintenance && window.Mode == maintenance.ModeReject {
+		return database.Transfer{}, http.StatusServiceUnavailable, errors.New(maintenanceMessage(window))
+	}
+
+	if !youtubeVideoTypes[req.YouTubeVideoType] {
+		return database.Transfer{}, http.StatusBadRequest, fmt.Errorf("Invalid youtube_video_type")
+	}
+
+	if req.Priority == "" {
+		req.Priority = "normal"
+	}
+	if status, err := h.checkTransferPriority(ctx, userID, req.Priority); err != nil {
+		return database.Transfer{}, status, err
+	}
+
+	// The source may be any playlist the user can read, e.g. a friend's public playlist link
+	playlistID, err := parsePlaylistReference(req.SourceService, req.SourcePlaylistID)
+	if err != nil {
+		return database.Transfer{}, http.StatusBadRequest, err
+	}
+	req.SourcePlaylistID = playlistID
+
+	if status, err := h.checkTransferLimits(ctx, userID, req); err != nil {
+		return database.Transfer{}, status, err
+	}
+
+	if req.OnNameConflict == "" {
+		req.OnNameConflict = "duplicate"
+	}
+	if req.Privacy != "" && !slices.Contains(privacyOptions, req.Privacy) {
+		return database.Transfer{}, http.StatusBadRequest, fmt.Errorf("privacy must be one of: %s", strings.Join(privacyOptions, ", "))
+	}
+	if err := checkSearchOptions(req.TargetService, req.SearchStrategies, req.SearchLimit); err != nil {
+		return database.Transfer{}, http.StatusBadRequest, err
+	}
+	if !slices.Contains(nameConflictModes, req.OnNameConflict) {
+		return database.Transfer{}, http.StatusBadRequest, fmt.Errorf("on_name_conflict must be one of: %s", strings.Join(nameConflictModes, ", "))
+	}
+	if req.TargetPlaylistID == "" && req.OnNameConflict == "fail" && req.TargetPlaylistName != "" &&
+		h.storedPlaylistNamed(ctx, userID, req.TargetService, req.TargetPlaylistName) != "" {
+		return database.Transfer{}, http.StatusConflict, errTargetNameExists
+	}
+
+	// Fail early with a specific reason when the source playlist cannot be read;
+	// providers in maintenance are left alone and the transfer checks it once it runs
+	if !inMaintenance {
+		if err := h.checkTransferSource(ctx, sourceService, publicSource, req); err != nil {
+			return database.Transfer{}, playlistErrorStatus(err), err
+		}
+	}
+
+	// Followed playlists can be copied from but never modified in place
+	if req.TargetPlaylistID != "" {
+		targetID, err := parsePlaylistReference(req.TargetService, req.TargetPlaylistID)
+		if err != nil {
+			return database.Transfer{}, http.StatusBadRequest, err
+		}
+		req.TargetPlaylistID = targetID
+		if isPersonalCollection(req.TargetService, targetID) {
+			return database.Transfer{}, http.StatusBadRequest, fmt.Errorf("Cannot add to a built-in playlist")
+		}
+		if !inMaintenance {
+			if err := h.checkTransferTarget(ctx, targetService, targetID); err != nil {
+				return database.Transfer{}, playlistErrorStatus(err), err
+			}
+		}
+		if req.TargetPlaylistName == "" {
+			var stored database.Playlist
+			if h.DB.WithContext(ctx).Where("user_id = ? AND service_type = ? AND service_id = ?", userID, req.TargetService, targetID).First(&stored).Error == nil {
+				req.TargetPlaylistName = stored.Name
+			}
+		}
+	}
+
+	// Refuse transfers that cannot finish within today's YouTube quota unless splitting was requested
+	if (req.SourceService == "youtube" || req.TargetService == "youtube") && !req.SplitAcrossDays {
+		estimate, _, err := h.estimateTransfer(ctx, userID, req)
+		if err == nil && !estimate.FitsToday {
+			return database.Transfer{}, http.StatusConflict, &quotaShortfallError{estimate: estimate}
+		}
+	}
 
 	// Create and save transfer record first
 	transfer := database.Transfer{
-		UserID:           user.ID,
+		UserID:           userID,
 		SourceService:    req.SourceService,
 		SourcePlaylistID: req.SourcePlaylistID,
 		TargetService:    req.TargetService,
-		Status:           "pending",
+		Status:           database.TransferPending,
+		StartPlayback:    req.StartPlayback && req.TargetService == "spotify",
+
+		DescriptionTemplate: req.DescriptionTemplate,
+		PublicSource:        publicSource,
+		Priority:            req.Priority,
+		RerunOfID:           req.rerunOf,
+		AutoSyncRuleID:      req.autoSyncRule,
+		OnNameConflict:      req.OnNameConflict,
+		Privacy:             req.Privacy,
+		OrderByAddedAt:      req.OrderByAddedAt,
+		SearchStrategies:    strings.Join(req.SearchStrategies, ","),
+		SearchLimit:         req.SearchLimit,
+	}
+	if req.TargetService == "youtube" {
+		transfer.YouTubeVideoType = req.YouTubeVideoType
+		transfer.SplitAcrossDays = req.SplitAcrossDays
+	}
+	if req.TargetPlaylistID != "" {
+		transfer.TargetPlaylistID = req.TargetPlaylistID
+		transfer.TargetPlaylistName = req.TargetPlaylistName
+		transfer.TargetPlaylistURL = playlistShareURL(req.TargetService, req.TargetPlaylistID)
+		transfer.ExistingTarget = true
+	}
+
+	if err := reserveTransferStorage(h.DB.WithContext(ctx), userID); err != nil {
+		if errors.Is(err, errStorageQuotaExceeded) {
+			return database.Transfer{}, http.StatusPaymentRequired, err
+		}
+		return database.Transfer{}, http.StatusInternalServerError, fmt.Errorf("Failed to clean up old transfers")
 	}
 
 	// Save the transfer to get an ID
-	if err := database.DB.Create(&transfer).Error; err != nil {
-		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to create transfer record"})
-		return
+	if err := h.DB.WithContext(ctx).Create(&transfer).Error; err != nil {
+		return database.Transfer{}, http.StatusInternalServerError, fmt.Errorf("Failed to create transfer record")
 	}
 
 	log.Printf("Created transfer record with ID: %d", transfer.ID)
+	recordTransferEvent(h.DB.WithContext(ctx), transfer.ID, "status", transfer.Status, "Transfer created")
 
-	// Start transfer in background
-	go processTransfer(transfer, sourceService, targetService, req.TargetPlaylistName)
+	if inMaintenance {
+		holdForMaintenance(h.DB.WithContext(ctx), &transfer, 0, 0, window)
+		return transfer, http.StatusOK, nil
+	}
+	h.enqueueTransfer(transfer, sourceService, targetService, req.TargetPlaylistName)
 
-	c.JSON(http.StatusOK, gin.H{
-		"message":     "Transfer started",
-		"transfer_id": transfer.ID,
+	return transfer, http.StatusOK, nil
+}
+
+// transferJobTimeout bounds how long one transfer job may run, so a hung
+// upstream API can't hold a worker forever
+var transferJobTimeout = config.Duration("TRANSFER_JOB_TIMEOUT", 2*time.Hour)
+
+// enqueueTransfer queues a transfer, or the next chunk of a split one, for a background worker
+func (h *Handlers) enqueueTransfer(transfer database.Transfer, sourceService, targetService database.UserService, targetPlaylistName string) {
+	h.Jobs.Enqueue(&jobs.Job{
+		ID:       fmt.Sprintf("transfer-%d", transfer.ID),
+		Type:     "transfer",
+		UserID:   transfer.UserID,
+		Priority: transferJobPriorities[transfer.Priority],
+		Timeout:  transferJobTimeout,
+		Run: func(ctx context.Context) error {
+			// Maintenance may have started while the transfer was queued
+			if window, ok := maintenance.For(transfer.SourceService, transfer.TargetService); ok {
+				holdForMaintenance(h.DB.WithContext(context.WithoutCancel(ctx)), &transfer, transfer.TracksMatched, transfer.TracksFailed, window)
+				return nil
+			}
+			// A retried job runs again later, so use the connections as they are now
+			if err := h.reloadTransferServices(ctx, transfer, &sourceService, &targetService); err != nil {
+				if ctx.Err() != nil {
+					return h.transferJobResult(ctx, transfer.ID)
+				}
+				updateTransfer(h.DB.WithContext(context.WithoutCancel(ctx)), &transfer, map[string]interface{}{
+					"status":        database.TransferFailed,
+					"error_message": "Service no longer connected",
+				})
+				return err
+			}
+			ctx = ratelimit.WithTransfer(ratelimit.WithUser(ctx, transfer.UserID), transfer.ID)
+			h.processTransfer(ctx, transfer, sourceService, targetService, targetPlaylistName)
+			return h.transferJobResult(ctx, transfer.ID)
+		},
 	})
 }
 
+// reloadTransferServices reads a transfer's service connections again, so a
+// queued or retried job doesn't use tokens that were refreshed or revoked since.
+// Public sources have no connection and are left as they are.
+func (h *Handlers) reloadTransferServices(ctx context.Context, transfer database.Transfer, sourceService, targetService *database.UserService) error {
+	if !transfer.PublicSource {
+		var source database.UserService
+		if err := h.DB.WithContext(ctx).Where("user_id = ? AND service_type = ?", transfer.UserID, transfer.SourceService).First(&source).Error; err != nil {
+			return err
+		}
+		*sourceService = source
+	}
+	var target database.UserService
+	if err := h.DB.WithContext(ctx).Where("user_id = ? AND service_type = ?", transfer.UserID, transfer.TargetService).First(&target).Error; err != nil {
+		return err
+	}
+	*targetService = target
+	return nil
+}
+
 // GetTransfers returns transfer history for the user
-func GetTransfers(c *gin.Context) {
+func (h *Handlers) GetTransfers(c *gin.Context) {
 	user, exists := middleware.GetUserFromContext(c)
 	if !exists {
-		c.JSON(http.StatusUnauthorized, gin.H{"error": "User not authenticated"})
+		i18n.RespondError(c, http.StatusUnauthorized, i18n.CodeUnauthenticated, "")
 		return
 	}
 
 	var transfers []database.Transfer
-	result := database.DB.Where("user_id = ?", user.ID).Order("created_at DESC").Limit(50).Find(&transfers)
+	result := h.DB.WithContext(c.Request.Context()).Where("user_id = ?", user.ID).Order("created_at DESC").Limit(50).Find(&transfers)
 	if result.Error != nil {
 		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to fetch transfers"})
 		return
@@ -105,10 +426,10 @@ func GetTransfers(c *gin.Context) {
 }
 
 // GetTransferDetails returns detailed information about a transfer
-func GetTransferDetails(c *gin.Context) {
+func (h *Handlers) GetTransferDetails(c *gin.Context) {
 	user, exists := middleware.GetUserFromContext(c)
 	if !exists {
-		c.JSON(http.StatusUnauthorized, gin.H{"error": "User not authenticated"})
+		i18n.RespondError(c, http.StatusUnauthorized, i18n.CodeUnauthenticated, "")
 		return
 	}
 
@@ -117,7 +438,7 @@ func GetTransferDetails(c *gin.Context) {
 
 	if transferID == "" || transferID == "undefined" {
 		log.Printf("Empty or undefined transfer ID: %s", transferID)
-		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid transfer ID"})
+		i18n.RespondError(c, http.StatusBadRequest, i18n.CodeInvalidTransferID, "")
 		return
 	}
 
@@ -125,41 +446,55 @@ func GetTransferDetails(c *gin.Context) {
 	id, err := strconv.ParseUint(transferID, 10, 32)
 	if err != nil {
 		log.Printf("Invalid transfer ID: %s, error: %v", transferID, err)
-		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid transfer ID"})
+		i18n.RespondError(c, http.StatusBadRequest, i18n.CodeInvalidTransferID, "")
 		return
 	}
 
 	var transfer database.Transfer
-	if err := database.DB.Where("id = ? AND user_id = ?", uint(id), user.ID).First(&transfer).Error; err != nil {
+	if err := h.DB.WithContext(c.Request.Context()).Where("id = ? AND user_id = ?", uint(id), user.ID).First(&transfer).Error; err != nil {
 		log.Printf("Transfer not found: ID=%d, UserID=%d, Error=%v", uint(id), user.ID, err)
-		c.JSON(http.StatusNotFound, gin.H{"error": "Transfer not found"})
+		i18n.RespondError(c, http.StatusNotFound, i18n.CodeTransferNotFound, "")
 		return
 	}
 
 	var transferTracks []database.TransferTrack
-	if err := database.DB.Where("transfer_id = ?", transfer.ID).Find(&transferTracks).Error; err != nil {
+	if err := h.DB.WithContext(c.Request.Context()).Where("transfer_id = ?", transfer.ID).Find(&transferTracks).Error; err != nil {
 		log.Printf("Error fetching transfer tracks: %v", err)
 		// Continue without tracks
 	}
 
+	var events []database.TransferEvent
+	if err := h.DB.WithContext(c.Request.Context()).Where("transfer_id = ?", transfer.ID).Order("id").Find(&events).Error; err != nil {
+		log.Printf("Error fetching transfer events: %v", err)
+	}
+
 	log.Printf("Found transfer: %+v", transfer)
 	log.Printf("Found %d transfer tracks", len(transferTracks))
 
 	c.JSON(http.StatusOK, gin.H{
 		"transfer": transfer,
+		"phase":    transfer.Status.Phase(),
 		"tracks":   transferTracks,
+		"events":   events,
 	})
 }
 
 // Update the processTransfer function to call debug at the beginning:
-func processTransfer(transfer database.Transfer, sourceService, targetService database.UserService, targetPlaylistName string) {
-	db := database.DB.Session(&gorm.Session{NewDB: true})
+func (h *Handlers) processTransfer(ctx context.Context, transfer database.Transfer, sourceService, targetService database.UserService, targetPlaylistName string) {
+	// Status writes must land even once the job is cancelled or times out
+	db := h.DB.WithContext(context.WithoutCancel(ctx)).Session(&gorm.Session{NewDB: true})
+
+	// Notify the user about the outcome however the transfer ends
+	defer notifyTransferFinished(db, transfer.ID)
+	defer linkAutoSyncedTransfer(db, transfer.ID)
+	defer h.continueLibraryTransfer(db, transfer.ID)
+	defer archiveTransferReport(db, transfer.ID)
 
 	defer func() {
 		if r := recover(); r != nil {
 			log.Printf("PANIC in transfer %d: %v", transfer.ID, r)
-			db.Model(&transfer).Updates(map[string]interface{}{
-				"status":        "failed",
+			updateTransfer(db, &transfer, map[string]interface{}{
+				"status":        database.TransferFailed,
 				"error_message": fmt.Sprintf("Panic: %v", r),
 			})
 		}
@@ -168,89 +503,469 @@ func processTransfer(transfer database.Transfer, sourceService, targetService da
 	log.Printf("=== STARTING TRANSFER %d ===", transfer.ID)
 	log.Printf("Source: %s, Playlist: %s", transfer.SourceService, transfer.SourcePlaylistID)
 	log.Printf("Target: %s", transfer.TargetService)
-	log.Printf("Source Token: %s...", sourceService.AccessToken[:20])
-	log.Printf("Target Token: %s...", targetService.AccessToken[:20])
 
-	// Refresh tokens before starting transfer
-	if err := tokenManager.RefreshTokenIfNeeded(&sourceService); err != nil {
+	// Refresh tokens before starting transfer; public sources use the app's own credentials
+	if transfer.PublicSource {
+		token, err := h.appAccessToken(ctx, transfer.SourceService)
+		if err != nil {
+			log.Printf("Failed to get app credentials: %v", err)
+			updateTransfer(db, &transfer, map[string]interface{}{
+				"status":        database.TransferFailed,
+				"error_message": "Public playlist access unavailable: " + err.Error(),
+			})
+			return
+		}
+		sourceService.AccessToken = token
+	} else if err := h.Tokens.RefreshTokenIfNeeded(&sourceService); err != nil {
 		log.Printf("Failed to refresh source token: %v", err)
-		db.Model(&transfer).Updates(map[string]interface{}{
-			"status":        "failed",
+		updateTransfer(db, &transfer, map[string]interface{}{
+			"status":        database.TransferFailed,
 			"error_message": "Source service token refresh failed: " + err.Error(),
 		})
 		return
 	}
 
-	if err := tokenManager.RefreshTokenIfNeeded(&targetService); err != nil {
+	if err := h.Tokens.RefreshTokenIfNeeded(&targetService); err != nil {
 		log.Printf("Failed to refresh target token: %v", err)
-		db.Model(&transfer).Updates(map[string]interface{}{
-			"status":        "failed",
+		updateTransfer(db, &transfer, map[string]interface{}{
+			"status":        database.TransferFailed,
 			"error_message": "Target service token refresh failed: " + err.Error(),
 		})
 		return
 	}
 
 	// Update transfer status using the new session
-	db.Model(&transfer).Update("status", "processing")
+	setTransferStatus(db, &transfer, database.TransferProcessing)
 
 	// Fetch source playlist tracks
 	log.Printf("Fetching source playlist tracks...")
-	sourceTracks, sourcePlaylistName, err := fetchPlaylistTracks(transfer.SourceService, sourceService.AccessToken, transfer.SourcePlaylistID)
+	var sourceTracks []Track
+	var sourcePlaylist SourcePlaylist
+	fetchSource := func() error {
+		var err error
+		sourceTracks, sourcePlaylist, err = h.fetchPlaylistTracks(ctx, transfer.SourceService, sourceService.AccessToken, transfer.SourcePlaylistID)
+		return err
+	}
+	var err error
+	if transfer.PublicSource {
+		err = fetchSource()
+	} else {
+		err = h.withTokenRefresh(ctx, &sourceService, fetchSource)
+	}
 	if err != nil {
 		log.Printf("Failed to fetch source playlist: %v", err)
-		db.Model(&transfer).Updates(map[string]interface{}{
-			"status":        "failed",
+		fields := map[string]interface{}{
+			"status":        transferFailureStatus(err),
 			"error_message": "Failed to fetch source playlist: " + err.Error(),
-		})
+		}
+		if errors.Is(err, errReauthRequired) {
+			fields["error_code"] = errCodeReauthRequired
+		}
+		updateTransfer(db, &transfer, fields)
 		return
 	}
 
-	log.Printf("Fetched %d tracks from source playlist: %s", len(sourceTracks), sourcePlaylistName)
+	log.Printf("Fetched %d tracks from source playlist: %s", len(sourceTracks), sourcePlaylist.Name)
 
 	if len(sourceTracks) == 0 {
 		log.Printf("Source playlist is empty")
-		db.Model(&transfer).Updates(map[string]interface{}{
-			"status":        "failed",
+		updateTransfer(db, &transfer, map[string]interface{}{
+			"status":        database.TransferFailed,
 			"error_message": "Source playlist is empty",
 		})
 		return
 	}
 
+	// Non-music videos skipped from YouTube lists still count toward the size
+	if err := checkTransferSize(max(sourcePlaylist.Total, sourcePlaylist.Fetched)); err != nil {
+		log.Printf("Refusing transfer %d: %v", transfer.ID, err)
+		updateTransfer(db, &transfer, map[string]interface{}{
+			"status":        database.TransferFailed,
+			"error_message": err.Error(),
+		})
+		return
+	}
+
 	// Update source playlist name
-	transfer.SourcePlaylistName = sourcePlaylistName
+	transfer.SourcePlaylistName = sourcePlaylist.Name
+	if transfer.SourceSnapshotID == "" {
+		transfer.SourceSnapshotID = sourcePlaylist.SnapshotID
+	} else {
+		// A later chunk of a split transfer: earlier chunks worked from another version
+		noteSourceChange(db, &transfer, sourcePlaylist.SnapshotID)
+	}
 	db.Save(&transfer)
 
-	// Set target playlist name if not provided
+	// Set target playlist name if not provided; same-service copies get a
+	// distinct name so they can be told apart from the original
 	if targetPlaylistName == "" {
-		targetPlaylistName = sourcePlaylistName
+		targetPlaylistName = sourcePlaylist.Name
+		if transfer.SourceService == transfer.TargetService {
+			targetPlaylistName += " (copy)"
+		}
 	}
 
-	// Create target playlist
-	log.Printf("Creating target playlist: %s", targetPlaylistName)
-	targetPlaylistID, err := createPlaylist(targetService.ServiceType, targetService.AccessToken, targetPlaylistName, "Transferred from "+transfer.SourceService)
-	if err != nil {
-		log.Printf("Failed to create target playlist: %v", err)
-		db.Model(&transfer).Updates(map[string]interface{}{
-			"status":        "failed",
-			"error_message": "Failed to create target playlist: " + err.Error(),
-		})
+	// Keep the stored copy of the source playlist's tracks up to date
+	h.snapshotPlaylistTracks(ctx, db, transfer.UserID, sourceService, transfer.SourcePlaylistID, sourcePlaylist.SnapshotID, sourceTracks)
+
+	// Collaborative sources stay collaborative where the target supports it
+	createOptions := PlaylistCreateOptions{
+		Collaborative: sourcePlaylist.Collaborative && targetService.ServiceType == "spotify",
+	}
+	privacy := transfer.Privacy
+	if privacy == "" {
+		privacy = loadUserSettings(db, transfer.UserID).DefaultPrivacy
+	}
+	createOptions.Privacy = targetPrivacy(privacy, sourcePlaylist.Privacy, targetService.ServiceType)
+
+	// Both services keep tracks in insertion order, so adding oldest first reproduces the source's history.
+	// The sort is stable and repeated for every chunk of a split transfer, keeping chunk offsets valid.
+	if transfer.OrderByAddedAt {
+		sourceTracks = slices.Clone(sourceTracks)
+		sort.SliceStable(sourceTracks, func(i, j int) bool { return sourceTracks[i].AddedAt < sourceTracks[j].AddedAt })
+	}
+
+	descriptionTemplate := descriptionTemplateFor(db, transfer.UserID, transfer.DescriptionTemplate)
+	h.copyTracksToNewPlaylist(ctx, db, &transfer, targetService, sourceTracks, targetPlaylistName, descriptionTemplate, createOptions)
+
+	// Edits made to the source while its tracks were copied are not in the target
+	if transfer.SourceService == "spotify" && transfer.SourceSnapshotID != "" && transfer.Status.Phase() == "finished" {
+		current, err := h.Providers.Spotify.Playlist(ctx, sourceService.AccessToken, transfer.SourcePlaylistID, "snapshot_id")
+		if err != nil {
+			log.Printf("Failed to recheck source of transfer %d: %v", transfer.ID, err)
+			return
+		}
+		noteSourceChange(db, &transfer, current.SnapshotID)
+	}
+}
+
+// resumeOrder moves the source tracks a transfer already has results for to the
+// front, keeping the order of both parts, and returns how many there are: the
+// index to resume at. A track listed more than once counts once per result.
+func resumeOrder(db *gorm.DB, transferID uint, sourceTracks []Track) ([]Track, int) {
+	var processed []database.TransferTrack
+	db.Select("source_track_id", "source_track_name", "source_artist").Where("transfer_id = ?", transferID).Find(&processed)
+	if len(processed) == 0 {
+		return sourceTracks, 0
+	}
+
+	// Tracks without an ID, such as local files, are told apart by name and artist
+	key := func(id, name, artist string) string {
+		if id != "" {
+			return id
+		}
+		return "\x00" + name + "\x00" + artist
+	}
+	pending := make(map[string]int, len(processed))
+	for _, result := range processed {
+		pending[key(result.SourceTrackID, result.SourceTrackName, result.SourceArtist)]++
+	}
+
+	done := make([]Track, 0, len(sourceTracks))
+	var remaining []Track
+	for _, track := range sourceTracks {
+		k := key(track.ID, track.Name, track.Artist)
+		if pending[k] > 0 {
+			pending[k]--
+			done = append(done, track)
+			continue
+		}
+		remaining = append(remaining, track)
+	}
+	return append(done, remaining...), len(done)
+}
+
+// noteSourceChange warns, once, when the source playlist's snapshot no longer
+// matches the one the transfer started from
+func noteSourceChange(db *gorm.DB, transfer *database.Transfer, snapshotID string) {
+	if snapshotID == "" || snapshotID == transfer.SourceSnapshotID || transfer.SourceChanged {
 		return
 	}
+	transfer.SourceChanged = true
+	db.Model(transfer).Update("source_changed", true)
+	recordTransferEvent(db, transfer.ID, "warning", "", "The source playlist changed while it was being transferred; rerun the transfer to pick up the changes")
+}
+
+// copyTracksToNewPlaylist creates the target playlist, matches every track on the
+// target service and records the per-track results and final status on the transfer
+func (h *Handlers) copyTracksToNewPlaylist(ctx context.Context, db *gorm.DB, transfer *database.Transfer, targetService database.UserService, sourceTracks []Track, targetPlaylistName, descriptionTemplate string, createOptions PlaylistCreateOptions) {
+	settings := loadUserSettings(db, transfer.UserID)
+	if createOptions.Privacy == "" {
+		// Callers that leave privacy unset have no source visibility to mirror
+		createOptions.Privacy = targetPrivacy(settings.DefaultPrivacy, "", targetService.ServiceType)
+	}
+
+	descriptionValues := descriptionVars{
+		Source:         transfer.SourceService,
+		SourcePlaylist: transfer.SourcePlaylistName,
+		Target:         targetService.ServiceType,
+		Total:          len(sourceTracks),
+	}
+	description := renderDescription(descriptionTemplate, descriptionValues)
+
+	// Later chunks of a split transfer continue in the playlist the first chunk
+	// created, and in-place transfers add to the playlist the user picked
+	targetPlaylistID := transfer.TargetPlaylistID
+	offset := 0
+	if targetPlaylistID != "" {
+		if err := h.verifyPlaylistOwner(ctx, targetService, targetPlaylistID); isOwnershipError(err) {
+			updateTransfer(db, transfer, map[string]interface{}{
+				"status":        database.TransferFailed,
+				"error_message": "Cannot add to target playlist: " + err.Error(),
+			})
+			return
+		}
 
-	log.Printf("Created target playlist: %s", targetPlaylistID)
+		// The source may have changed since the last run, so the tracks already
+		// handled are found by ID rather than by how many there were
+		sourceTracks, offset = resumeOrder(db, transfer.ID, sourceTracks)
+		if transfer.TracksTotal == 0 {
+			transfer.TracksTotal = len(supportedTracks(sourceTracks))
+			db.Save(transfer)
+		}
+		log.Printf("Resuming transfer %d at track %d in playlist %s", transfer.ID, offset+1, targetPlaylistID)
+	} else {
+		if err := h.verifyTargetAccount(ctx, targetService); isOwnershipError(err) {
+			updateTransfer(db, transfer, map[string]interface{}{
+				"status":        database.TransferFailed,
+				"error_message": "Cannot create target playlist: " + err.Error(),
+			})
+			return
+		}
 
-	transfer.TargetPlaylistID = targetPlaylistID
-	transfer.TargetPlaylistName = targetPlaylistName
-	transfer.TracksTotal = len(sourceTracks)
-	db.Save(&transfer)
+		// Names and descriptions are cleaned up to the service's limits, noting any change
+		if sanitized := sanitizePlaylistName(targetService.ServiceType, targetPlaylistName); sanitized != targetPlaylistName {
+			recordTransferEvent(db, transfer.ID, "sanitized", "", fmt.Sprintf("Playlist name changed from %q to %q to suit %s",
+				targetPlaylistName, sanitized, getServiceDisplayName(targetService.ServiceType)))
+			transfer.TargetNameOriginal = targetPlaylistName
+			targetPlaylistName = sanitized
+		}
+		if sanitized := sanitizePlaylistDescription(targetService.ServiceType, description); sanitized != description {
+			recordTransferEvent(db, transfer.ID, "sanitized", "", fmt.Sprintf("Playlist description shortened or cleaned to suit %s",
+				getServiceDisplayName(targetService.ServiceType)))
+			transfer.DescriptionAdjusted = true
+			description = sanitized
+		}
 
-	// Match and add tracks
-	matchedTracks := 0
-	failedTracks := 0
+		resolvedName, existingID, err := h.resolveNameConflict(ctx, db, transfer, targetService, targetPlaylistName)
+		if err != nil {
+			updateTransfer(db, transfer, map[string]interface{}{
+				"status":        database.TransferFailed,
+				"error_message": err.Error(),
+				"error_code":    errCodeTargetNameExists,
+			})
+			return
+		}
+
+		if existingID != "" {
+			targetPlaylistID = existingID
+			transfer.ExistingTarget = true
+		} else {
+			targetPlaylistName = resolvedName
+
+			// Create target playlist
+			log.Printf("Creating target playlist: %s", targetPlaylistName)
+			setTransferStatus(db, transfer, database.TransferCreatingPlaylist)
+			var createdID string
+			err := h.withTokenRefresh(ctx, &targetService, func() error {
+				var createErr error
+				createdID, createErr = h.createPlaylist(ctx, targetService.ServiceType, targetService.AccessToken, targetPlaylistName, description, createOptions)
+				return createErr
+			})
+			if err != nil {
+				log.Printf("Failed to create target playlist: %v", err)
+				updateTransfer(db, transfer, map[string]interface{}{
+					"status":        transferFailureStatus(err),
+					"error_message": "Failed to create target playlist: " + err.Error(),
+				})
+				return
+			}
+
+			log.Printf("Created target playlist: %s", createdID)
+			targetPlaylistID = createdID
+			transfer.TargetCollaborative = createOptions.Collaborative
+		}
+
+		transfer.TargetPlaylistID = targetPlaylistID
+		transfer.TargetPlaylistName = targetPlaylistName
+		transfer.TargetPlaylistURL = playlistShareURL(targetService.ServiceType, targetPlaylistID)
+		transfer.TracksTotal = len(supportedTracks(sourceTracks))
+		db.Save(transfer)
+	}
 
+	// Match and add tracks
+	matchedTracks := transfer.TracksMatched
+	failedTracks := transfer.TracksFailed
+	searchOptions := SearchOptions{
+		YouTubeVideoType: transfer.YouTubeVideoType,
+		Market:           userMarket(db, transfer.UserID),
+		MatchStrategy:    settings.MatchStrategy,
+		Strategies:       splitSearchStrategies(transfer.SearchStrategies),
+		Limit:            transfer.SearchLimit,
+	}
+
+	// Tracks already on the target service are added as-is, and tracks whose
+	// counterpart is already known skip the search entirely
+	setTransferStatus(db, transfer, database.TransferMatching)
+	rules := loadContentRules(db, transfer.UserID)
+	ruleHits := make(map[int]database.ContentRule)
+	knownTracks := make(map[int]Track)
+	knownConfidence := make(map[int]float64)
 	for i, track := range sourceTracks {
+		if track.Unsupported != "" {
+			continue
+		}
+		if rule, ok := rules.match(track); ok {
+			ruleHits[i] = rule
+			if rule.Action != "flag" {
+				// Skipped tracks need no lookup and replaced ones are searched by their new title
+				continue
+			}
+		}
+		if known, confidence, ok := knownTargetTrack(db, transfer.UserID, track, targetService.ServiceType); ok {
+			knownTracks[i] = known
+			knownConfidence[i] = confidence
+		}
+	}
+
+	// YouTube targets spend daily quota per track; split transfers only cover
+	// the current daily chunk of the plan
+	var trackCosts []int
+	if targetService.ServiceType == "youtube" {
+		trackCosts = make([]int, len(sourceTracks))
+		for i := range sourceTracks {
+			if sourceTracks[i].Unsupported != "" {
+				continue
+			}
+			if rule, ok := ruleHits[i]; ok && rule.Action == "skip" {
+				continue
+			}
+			_, isKnown := knownTracks[i]
+			trackCosts[i] = youtubeTrackCost(!isKnown)
+		}
+	}
+	end := len(sourceTracks)
+	if transfer.SplitAcrossDays {
+		var due bool
+		if end, due = claimTransferChunk(db, transfer, trackCosts, offset); !due {
+			pauseForQuota(db, transfer, matchedTracks, failedTracks, trackCosts, offset)
+			return
+		}
+	}
+
+	// Known Spotify IDs may come from another account or market; a batched lookup
+	// relinks them to playable versions and fills in their Spotify metadata
+	var relinks map[string]spotifyRelink
+	var err error
+	if targetService.ServiceType == "spotify" && len(knownTracks) > 0 {
+		ids := make([]string, 0, len(knownTracks))
+		for _, known := range knownTracks {
+			ids = append(ids, known.ID)
+		}
+		relinks, err = h.fetchSpotifyRelinks(ctx, h.lookupToken(ctx, "spotify", targetService.AccessToken), ids, searchOptions.Market)
+		if errors.Is(err, ratelimit.ErrProviderUnavailable) {
+			abortUnavailableTransfer(db, transfer, matchedTracks, failedTracks, err)
+			return
+		}
+		if err == nil {
+			// Tracks Spotify no longer knows are searched for instead
+			for i, known := range knownTracks {
+				if _, ok := relinks[known.ID]; !ok {
+					delete(knownTracks, i)
+				}
+			}
+		}
+	}
+
+	setTransferStatus(db, transfer, database.TransferAddingTracks)
+	for i := offset; i < end; i++ {
+		if ctx.Err() != nil {
+			log.Printf("Stopping transfer %d after %d tracks: %v", transfer.ID, i-offset, ctx.Err())
+			updateTransfer(db, transfer, map[string]interface{}{
+				"tracks_matched": matchedTracks,
+				"tracks_failed":  failedTracks,
+			})
+			return
+		}
+		// Maintenance pauses the transfer between tracks; it resumes here once it ends.
+		// Smart playlists can't be resumed, so they stop with what they added.
+		if window, ok := maintenance.For(transfer.SourceService, targetService.ServiceType); ok {
+			if transfer.SourceService == "smart" {
+				updateTransfer(db, transfer, map[string]interface{}{
+					"status":         database.TransferFailed,
+					"tracks_matched": matchedTracks,
+					"tracks_failed":  failedTracks,
+					"error_message":  maintenanceMessage(window),
+				})
+				return
+			}
+			holdForMaintenance(db, transfer, matchedTracks, failedTracks, window)
+			return
+		}
+		track := sourceTracks[i]
 		log.Printf("Processing track %d/%d: %s - %s", i+1, len(sourceTracks), track.Artist, track.Name)
 
+		// Local files, episodes and removed tracks have nothing to match on;
+		// they are reported but count neither as failures nor towards the total
+		if track.Unsupported != "" {
+			log.Printf("Track %d of transfer %d is unsupported: %s", i+1, transfer.ID, track.Unsupported)
+			status := "unsupported_source_track"
+			if track.Unsupported == "episode" {
+				status = "skipped_episode"
+			}
+			unsupported := database.TransferTrack{
+				TransferID:      transfer.ID,
+				SourceTrackID:   track.ID,
+				SourceTrackName: track.Name,
+				SourceArtist:    track.Artist,
+				Status:          status,
+				AddedAt:         track.AddedAt,
+				AddedBy:         track.AddedBy,
+			}
+			if err := db.Create(&unsupported).Error; err != nil {
+				log.Printf("Failed to save track result: %v", err)
+			}
+			transfer.TracksUnsupported++
+			db.Model(transfer).Update("tracks_unsupported", transfer.TracksUnsupported)
+			h.reportTransferProgress(ctx, *transfer, i+1)
+			continue
+		}
+
+		rule, ruleHit := ruleHits[i]
+		if ruleHit && rule.Action == "skip" {
+			log.Printf("Skipping %s - %s by rule %d", track.Artist, track.Name, rule.ID)
+			skipped := database.TransferTrack{
+				TransferID:      transfer.ID,
+				SourceTrackID:   track.ID,
+				SourceTrackName: track.Name,
+				SourceArtist:    track.Artist,
+				Status:          "skipped_by_rule",
+				RuleID:          &rule.ID,
+				AddedAt:         track.AddedAt,
+				AddedBy:         track.AddedBy,
+			}
+			if err := db.Create(&skipped).Error; err != nil {
+				log.Printf("Failed to save track result: %v", err)
+			}
+			transfer.TracksSkipped++
+			db.Model(transfer).Update("tracks_skipped", transfer.TracksSkipped)
+			h.reportTransferProgress(ctx, *transfer, i+1)
+			continue
+		}
+
+		// Stop before running out of quota instead of failing the remaining tracks;
+		// split transfers continue on the next quota day
+		if trackCosts != nil {
+			if remaining := quota.Remaining("youtube"); remaining >= 0 && remaining < trackCosts[i] {
+				if transfer.SplitAcrossDays {
+					pauseForQuota(db, transfer, matchedTracks, failedTracks, trackCosts, i)
+				} else {
+					stopForQuota(db, transfer, matchedTracks, failedTracks, i)
+				}
+				return
+			}
+		}
+
 		trackResult := database.TransferTrack{
 			TransferID:      transfer.ID,
 			SourceTrackID:   track.ID,
@@ -258,21 +973,104 @@ func processTransfer(transfer database.Transfer, sourceService, targetService da
 			SourceArtist:    track.Artist,
 			Status:          "not_found",
 			MatchConfidence: 0.0,
+			AddedAt:         track.AddedAt,
+			AddedBy:         track.AddedBy,
+		}
+		if ruleHit {
+			trackResult.RuleID = &rule.ID
+			trackResult.Flagged = rule.Action == "flag"
+		}
+		searchFor := track
+		if ruleHit && rule.Action == "replace" {
+			searchFor = replacementTrack(rule, track)
+			log.Printf("Rule %d replaces %s - %s with a search for %s", rule.ID, track.Artist, track.Name, searchFor.Name)
 		}
 
-		// Search for track on target service
-		targetTrack, confidence, err := searchTrack(targetService.ServiceType, targetService.AccessToken, track)
-		if err != nil {
+		targetTrack, confidence := track, 1.0
+		onRetry := trackRetryRecorder(db, transfer.ID, track)
+		var err error
+		if known, ok := knownTracks[i]; ok {
+			targetTrack, confidence = known, knownConfidence[i]
+			if relink, ok := relinks[known.ID]; ok {
+				targetTrack.ID = relink.ID
+				targetTrack.Name, targetTrack.Artist, targetTrack.ISRC = relink.Name, relink.Artist, relink.ISRC
+				if !relink.Playable {
+					err = errUnavailableInRegion
+				}
+			}
+		} else {
+			err = withTrackRetry(ctx, "track search", onRetry, func(ctx context.Context) error {
+				return h.withTokenRefresh(ctx, &targetService, func() error {
+					var searchErr error
+					targetTrack, confidence, searchErr = h.searchTrack(ctx, targetService.ServiceType, targetService.AccessToken, searchFor, searchOptions)
+					return searchErr
+				})
+			})
+			if err == nil && targetTrack.ID != "" {
+				confidence, err = checkLyrics(ctx, track, targetTrack, confidence)
+			}
+			// A replacement is a different recording, so it is not linked to the source track
+			if (err == nil || errors.Is(err, errUnavailableInRegion)) && confidence >= identityLinkConfidence && searchFor.Name == track.Name {
+				linkTrackIdentities(ctx, db, transfer.UserID, track, targetTrack, confidence)
+			}
+		}
+		if errors.Is(err, ratelimit.ErrProviderUnavailable) {
+			abortUnavailableTransfer(db, transfer, matchedTracks, failedTracks, err)
+			return
+		}
+		if errors.Is(err, errReauthRequired) {
+			abortReauthTransfer(db, transfer, targetService.ServiceType, matchedTracks, failedTracks, err)
+			return
+		}
+		trackResult.SearchStrategy = targetTrack.MatchedBy
+		trackResult.TargetEntityType = targetTrack.EntityType
+		if targetTrack.Substitution != "" {
+			trackResult.Substitution = targetTrack.Substitution
+			recordTransferEvent(db, transfer.ID, "substituted", "", fmt.Sprintf("Best match for %s - %s is %s; used %s - %s instead",
+				track.Artist, track.Name, strings.ReplaceAll(targetTrack.Substitution, "_", " "), targetTrack.Artist, targetTrack.Name))
+		}
+		if errors.Is(err, errUnavailableInRegion) {
+			log.Printf("Track %s - %s exists but is unavailable in %s", targetTrack.Artist, targetTrack.Name, searchOptions.Market)
+			trackResult.Status = "unavailable_in_region"
+			trackResult.TargetTrackID = targetTrack.ID
+			trackResult.TargetTrackName = targetTrack.Name
+			trackResult.TargetArtist = targetTrack.Artist
+			trackResult.MatchConfidence = confidence
+			failedTracks++
+		} else if err != nil {
 			log.Printf("Track search failed: %v", err)
+			recordTransferEvent(db, transfer.ID, "error", "", fmt.Sprintf("Search failed for %s - %s: %v", track.Artist, track.Name, err))
 			trackResult.Status = "not_found"
 			failedTracks++
+		} else if targetTrack.ID != "" && confidence < settings.MinConfidence {
+			log.Printf("Rejecting match %s - %s below minimum confidence (%.2f < %.2f)", targetTrack.Artist, targetTrack.Name, confidence, settings.MinConfidence)
+			trackResult.TargetTrackID = targetTrack.ID
+			trackResult.TargetTrackName = targetTrack.Name
+			trackResult.TargetArtist = targetTrack.Artist
+			trackResult.MatchConfidence = confidence
+			failedTracks++
 		} else if targetTrack.ID != "" {
 			log.Printf("Found track match: %s - %s (confidence: %.2f)", targetTrack.Artist, targetTrack.Name, confidence)
 
 			// Add track to target playlist
-			err = addTrackToPlaylist(targetService.ServiceType, targetService.AccessToken, targetPlaylistID, targetTrack.ID)
+			err = withTrackWriteRetry(ctx, "adding track", onRetry, func(ctx context.Context) error {
+				return h.withTokenRefresh(ctx, &targetService, func() error {
+					return h.addTrackToPlaylist(ctx, targetService.ServiceType, targetService.AccessToken, targetPlaylistID, targetTrack.ID)
+				})
+			}, func(ctx context.Context) (bool, error) {
+				return h.playlistHasTracks(ctx, targetService.ServiceType, targetService.AccessToken, targetPlaylistID, []string{targetTrack.ID})
+			})
+			if errors.Is(err, ratelimit.ErrProviderUnavailable) {
+				abortUnavailableTransfer(db, transfer, matchedTracks, failedTracks, err)
+				return
+			}
+			if errors.Is(err, errReauthRequired) {
+				abortReauthTransfer(db, transfer, targetService.ServiceType, matchedTracks, failedTracks, err)
+				return
+			}
 			if err != nil {
 				log.Printf("Failed to add track to playlist: %v", err)
+				recordTransferEvent(db, transfer.ID, "error", "", fmt.Sprintf("Adding %s - %s failed: %v", targetTrack.Artist, targetTrack.Name, err))
 				trackResult.Status = "error"
 				trackResult.TargetTrackID = targetTrack.ID
 				trackResult.TargetTrackName = targetTrack.Name
@@ -297,721 +1095,708 @@ func processTransfer(transfer database.Transfer, sourceService, targetService da
 		if err := db.Create(&trackResult).Error; err != nil {
 			log.Printf("Failed to save track result: %v", err)
 		}
+
+		h.reportTransferProgress(ctx, *transfer, i+1)
+	}
+
+	if end < len(sourceTracks) {
+		pauseForQuota(db, transfer, matchedTracks, failedTracks, trackCosts, end)
+		return
+	}
+	if transfer.SplitAcrossDays {
+		finishTransferChunk(db, transfer.ID)
 	}
 
 	// Update transfer with results
 	transfer.TracksMatched = matchedTracks
 	transfer.TracksFailed = failedTracks
-	status := "failed"
-	if matchedTracks > 0 {
+	status := database.TransferFailed
+	if matchedTracks > 0 || (failedTracks == 0 && transfer.TracksSkipped > 0) {
 		if failedTracks == 0 {
-			status = "completed"
+			status = database.TransferCompleted
 		} else {
-			status = "completed_with_errors"
+			status = database.TransferCompletedWithErrors
 		}
 	}
 	transfer.Status = status
 
-	if err := db.Save(&transfer).Error; err != nil {
+	if err := db.Save(transfer).Error; err != nil {
 		log.Printf("Failed to update transfer status: %v", err)
 	}
+	recordTransferEvent(db, transfer.ID, "status", status, fmt.Sprintf("%d matched, %d failed, %d skipped", matchedTracks, failedTracks, transfer.TracksSkipped))
 
 	log.Printf("Transfer %d completed: %d/%d tracks transferred, %d failed, status: %s",
 		transfer.ID, matchedTracks, transfer.TracksTotal, failedTracks, status)
+
+	h.verifyTransfer(ctx, db, transfer)
+	matchedTracks, failedTracks = transfer.TracksMatched, transfer.TracksFailed
+
+	// The description of a playlist the user already had is left alone
+	if descriptionNeedsResults(descriptionTemplate) && !transfer.ExistingTarget {
+		descriptionValues.Matched = matchedTracks
+		descriptionValues.Failed = failedTracks
+		description = renderDescription(descriptionTemplate, descriptionValues)
+		if err := h.updatePlaylistDescription(ctx, targetService.ServiceType, targetService.AccessToken, targetPlaylistID, targetPlaylistName, description); err != nil {
+			log.Printf("Failed to update description for transfer %d: %v", transfer.ID, err)
+		}
+	}
+
+	if transfer.StartPlayback && matchedTracks > 0 {
+		if err := h.startSpotifyPlayback(ctx, targetService.AccessToken, targetPlaylistID); err != nil {
+			log.Printf("Failed to start playback for transfer %d: %v", transfer.ID, err)
+		}
+	}
+}
+
+// pauseForQuota leaves a split transfer waiting for its next daily chunk,
+// re-planning the tracks from next on
+func pauseForQuota(db *gorm.DB, transfer *database.Transfer, matchedTracks, failedTracks int, trackCosts []int, next int) {
+	finishTransferChunk(db, transfer.ID)
+	resumesAt := quota.NextReset(time.Now())
+	if chunks := planTransferChunks(db, transfer.ID, trackCosts, next, false); len(chunks) > 0 {
+		resumesAt = time.Unix(chunks[0].ScheduledFor, 0)
+	}
+
+	log.Printf("Transfer %d paused at track %d until %s", transfer.ID, next+1, resumesAt.Format(time.RFC3339))
+	updateTransfer(db, transfer, map[string]interface{}{
+		"status":         database.TransferPaused,
+		"tracks_matched": matchedTracks,
+		"tracks_failed":  failedTracks,
+		"error_message":  "Paused until the YouTube quota resets at " + resumesAt.Format(time.RFC3339),
+	})
+}
+
+// stopForQuota ends a transfer that ran out of today's YouTube quota
+func stopForQuota(db *gorm.DB, transfer *database.Transfer, matchedTracks, failedTracks, next int) {
+	log.Printf("Transfer %d stopped at track %d: YouTube quota exhausted", transfer.ID, next+1)
+	updateTransfer(db, transfer, map[string]interface{}{
+		"status":         database.TransferQuotaExceeded,
+		"tracks_matched": matchedTracks,
+		"tracks_failed":  failedTracks,
+		"error_message":  fmt.Sprintf("YouTube quota exhausted after %d of %d tracks", next, transfer.TracksTotal),
+	})
+}
+
+// setTransferStatus records the phase a running transfer is in
+func setTransferStatus(db *gorm.DB, transfer *database.Transfer, status database.TransferStatus) {
+	transfer.Status = status
+	if err := db.Model(transfer).Update("status", status).Error; err != nil {
+		log.Printf("Failed to update status of transfer %d: %v", transfer.ID, err)
+	}
+	recordTransferEvent(db, transfer.ID, "status", status, "")
+}
+
+// transferFailureStatus maps an error to the status a failed transfer should get
+func transferFailureStatus(err error) database.TransferStatus {
+	if errors.Is(err, ratelimit.ErrProviderUnavailable) {
+		return database.TransferProviderUnavailable
+	}
+	return database.TransferFailed
+}
+
+// abortUnavailableTransfer stops a transfer whose provider circuit opened mid-run, keeping partial counts
+func abortUnavailableTransfer(db *gorm.DB, transfer *database.Transfer, matchedTracks, failedTracks int, err error) {
+	log.Printf("Aborting transfer %d: %v", transfer.ID, err)
+	updateTransfer(db, transfer, map[string]interface{}{
+		"status":         database.TransferProviderUnavailable,
+		"tracks_matched": matchedTracks,
+		"tracks_failed":  failedTracks,
+		"error_message":  "Provider unavailable: " + err.Error(),
+	})
+}
+
+// abortReauthTransfer stops a transfer whose target connection was revoked mid-run, keeping partial counts
+func abortReauthTransfer(db *gorm.DB, transfer *database.Transfer, serviceType string, matchedTracks, failedTracks int, err error) {
+	log.Printf("Aborting transfer %d: %v", transfer.ID, err)
+	updateTransfer(db, transfer, map[string]interface{}{
+		"status":         database.TransferFailed,
+		"tracks_matched": matchedTracks,
+		"tracks_failed":  failedTracks,
+		"error_message":  fmt.Sprintf("Reconnect %s to finish this transfer", getServiceDisplayName(serviceType)),
+		"error_code":     errCodeReauthRequired,
+	})
 }
 
 // fetchPlaylistTracks gets tracks from a playlist
-func fetchPlaylistTracks(serviceType, accessToken, playlistID string) ([]Track, string, error) {
+func (h *Handlers) fetchPlaylistTracks(ctx context.Context, serviceType, accessToken, playlistID string) ([]Track, SourcePlaylist, error) {
 	switch serviceType {
 	case "spotify":
-		return fetchSpotifyPlaylistTracks(accessToken, playlistID)
+		return h.fetchSpotifyPlaylistTracks(ctx, accessToken, playlistID)
 	case "youtube":
-		return fetchYouTubePlaylistTracks(accessToken, playlistID)
+		return h.fetchYouTubePlaylistTracks(ctx, accessToken, playlistID)
 	default:
-		return nil, "", fmt.Errorf("unsupported service: %s", serviceType)
+		return nil, SourcePlaylist{}, fmt.Errorf("unsupported service: %s", serviceType)
 	}
 }
 
 // fetchSpotifyPlaylistTracks gets tracks from a Spotify playlist
-func fetchSpotifyPlaylistTracks(accessToken, playlistID string) ([]Track, string, error) {
-	client := ratelimit.NewRateLimitedHTTPClient(ratelimit.SpotifyService, rateLimiter)
-
-	// Simple request without fields filter
-	url := fmt.Sprintf("https://api.spotify.com/v1/playlists/%s", playlistID)
+func (h *Handlers) fetchSpotifyPlaylistTracks(ctx context.Context, accessToken, playlistID string) ([]Track, SourcePlaylist, error) {
+	if playlistID == spotifyLikedSongsID {
+		return h.fetchSpotifyLikedSongs(ctx, accessToken)
+	}
 
-	req, err := http.NewRequest("GET", url, nil)
+	playlist, err := h.Providers.Spotify.Playlist(ctx, accessToken, playlistID, "")
 	if err != nil {
-		rateMonitor.RecordRequest(ratelimit.SpotifyService, false, true)
-		return nil, "", err
+		return nil, SourcePlaylist{}, playlistFetchError(err)
 	}
 
-	req.Header.Set("Authorization", "Bearer "+accessToken)
-	resp, err := client.Do(req)
-	if err != nil {
-		rateMonitor.RecordRequest(ratelimit.SpotifyService, false, true)
-		return nil, "", err
+	items := playlist.Tracks.Items
+	if playlist.Tracks.Next != "" {
+		rest, err := h.Providers.Spotify.PlaylistTracks(ctx, accessToken, playlistID, len(items), 100)
+		if err != nil {
+			return nil, SourcePlaylist{}, playlistFetchError(err)
+		}
+		items = append(items, rest...)
 	}
-	defer resp.Body.Close()
 
-	wasRateLimited := resp.StatusCode == http.StatusTooManyRequests
-	rateMonitor.RecordRequest(ratelimit.SpotifyService, wasRateLimited, false)
+	log.Printf("Spotify playlist '%s' has %d tracks", playlist.Name, len(items))
 
-	if resp.StatusCode != http.StatusOK {
-		body, _ := io.ReadAll(resp.Body)
-		log.Printf("Spotify playlist API error: %d, body: %s", resp.StatusCode, string(body))
-		return nil, "", fmt.Errorf("spotify API returned status: %d", resp.StatusCode)
-	}
+	tracks := spotifyItemTracks(items)
 
-	var spotifyResponse struct {
-		Name   string `json:"name"`
-		Tracks struct {
-			Items []struct {
-				Track struct {
-					ID      string `json:"id"`
-					Name    string `json:"name"`
-					Artists []struct {
-						Name string `json:"name"`
-					} `json:"artists"`
-					Album struct {
-						Name string `json:"name"`
-					} `json:"album"`
-				} `json:"track"`
-			} `json:"items"`
-		} `json:"tracks"`
+	privacy := "private"
+	if playlist.Public {
+		privacy = "public"
 	}
 
-	if err := json.NewDecoder(resp.Body).Decode(&spotifyResponse); err != nil {
-		return nil, "", err
-	}
+	return tracks, SourcePlaylist{
+		Name:          playlist.Name,
+		Collaborative: playlist.Collaborative,
+		OwnerID:       playlist.Owner.ID,
+		OwnerName:     playlist.Owner.DisplayName,
+		Privacy:       privacy,
+		SnapshotID:    playlist.SnapshotID,
+		Total:         playlist.Tracks.Total,
+		Fetched:       len(items),
+	}, nil
+}
 
-	log.Printf("Spotify playlist '%s' has %d tracks", spotifyResponse.Name, len(spotifyResponse.Tracks.Items))
+// fetchSpotifyLikedSongs gets the user's Liked Songs as if they were a private playlist
+func (h *Handlers) fetchSpotifyLikedSongs(ctx context.Context, accessToken string) ([]Track, SourcePlaylist, error) {
+	items, err := h.Providers.Spotify.SavedTracks(ctx, accessToken, 50)
+	if err != nil {
+		return nil, SourcePlaylist{}, playlistFetchError(err)
+	}
+	return spotifyItemTracks(items), SourcePlaylist{Name: "Liked Songs", Privacy: "private", Total: len(items), Fetched: len(items)}, nil
+}
 
+// spotifyItemTracks converts Spotify playlist or library items to tracks
+func spotifyItemTracks(items []spotify.PlaylistItem) []Track {
 	var tracks []Track
-	for _, item := range spotifyResponse.Tracks.Items {
-		artist := ""
-		if len(item.Track.Artists) > 0 {
-			artist = item.Track.Artists[0].Name
+	for _, item := range items {
+		// Local files keep their name and artist but have no ID; a null track
+		// (removed from Spotify) decodes to an empty one
+		unsupported := ""
+		switch {
+		case item.Track.IsLocal:
+			unsupported = "local_file"
+		case item.Track.Type == "episode":
+			// A podcast episode searched as a song only finds unrelated music
+			unsupported = "episode"
+		case item.Track.ID == "":
+			unsupported = "unavailable"
 		}
 
 		tracks = append(tracks, Track{
-			ID:     item.Track.ID,
-			Name:   item.Track.Name,
-			Artist: artist,
-			Album:  item.Track.Album.Name,
+			ID:       item.Track.ID,
+			Name:     item.Track.Name,
+			Artist:   item.Track.FirstArtist(),
+			Album:    item.Track.Album.Name,
+			Duration: item.Track.DurationMS,
+			ISRC:     item.Track.ExternalIDs.ISRC,
+			Explicit: item.Track.Explicit,
+			Service:  "spotify",
+			AddedAt:  unixOrZero(item.AddedAt),
+			AddedBy:  addedBy(item.AddedBy),
+
+			Unsupported: unsupported,
+			PreviewURL:  item.Track.PreviewURL,
 		})
 	}
-
-	return tracks, spotifyResponse.Name, nil
+	return tracks
 }
 
 // fetchYouTubePlaylistTracks gets tracks from a YouTube playlist
-func fetchYouTubePlaylistTracks(accessToken, playlistID string) ([]Track, string, error) {
-	client := ratelimit.NewRateLimitedHTTPClient(ratelimit.YouTubeService, rateLimiter)
-	url := fmt.Sprintf("https://www.googleapis.com/youtube/v3/playlistItems?part=snippet,contentDetails&playlistId=%s&maxResults=50", playlistID)
-
-	req, err := http.NewRequest("GET", url, nil)
-	if err != nil {
-		rateMonitor.RecordRequest(ratelimit.YouTubeService, false, true)
-		return nil, "", err
-	}
-
-	req.Header.Set("Authorization", "Bearer "+accessToken)
-	resp, err := client.Do(req)
+func (h *Handlers) fetchYouTubePlaylistTracks(ctx context.Context, accessToken, playlistID string) ([]Track, SourcePlaylist, error) {
+	items, total, err := h.Providers.YouTube.PlaylistItems(ctx, accessToken, playlistID, 50)
 	if err != nil {
-		rateMonitor.RecordRequest(ratelimit.YouTubeService, false, true)
-		return nil, "", err
-	}
-	defer resp.Body.Close()
-
-	wasRateLimited := resp.StatusCode == http.StatusTooManyRequests
-	rateMonitor.RecordRequest(ratelimit.YouTubeService, wasRateLimited, false)
-
-	if resp.StatusCode != http.StatusOK {
-		body, _ := io.ReadAll(resp.Body)
-		log.Printf("YouTube playlist items API error: %d, body: %s", resp.StatusCode, string(body))
-		return nil, "", fmt.Errorf("youtube API returned status: %d", resp.StatusCode)
-	}
-
-	var youtubeResponse struct {
-		Items []struct {
-			Snippet struct {
-				Title      string `json:"title"`
-				ResourceID struct {
-					VideoID string `json:"videoId"`
-				} `json:"resourceId"`
-			} `json:"snippet"`
-		} `json:"items"`
+		return nil, SourcePlaylist{}, playlistFetchError(err)
 	}
 
-	if err := json.NewDecoder(resp.Body).Decode(&youtubeResponse); err != nil {
-		return nil, "", err
+	// For YouTube, we need to get the playlist name and visibility separately
+	privacy := "private"
+	playlistName, special := youtubeSpecialPlaylists[playlistID]
+	if !special {
+		playlistName, privacy, err = h.getYouTubePlaylistInfo(ctx, accessToken, playlistID)
+		if err != nil {
+			playlistName = "YouTube Playlist"
+		}
 	}
 
-	// For YouTube, we need to get the playlist name separately
-	playlistName, err := getYouTubePlaylistName(accessToken, playlistID)
-	if err != nil {
-		playlistName = "YouTube Playlist"
+	// Liked videos mixes music with everything else the user liked,
+	// so its video categories are looked up even without hydration
+	var videos map[string]youtube.Video
+	if youtubeHydrateTracks || special {
+		videoIDs := make([]string, 0, len(items))
+		for _, item := range items {
+			videoIDs = append(videoIDs, item.Snippet.ResourceID.VideoID)
+		}
+		videos = h.fetchYouTubeVideoDetails(ctx, accessToken, videoIDs)
 	}
 
 	var tracks []Track
-	for _, item := range youtubeResponse.Items {
+	for _, item := range items {
 		// Parse title to extract artist and track name
 		title := item.Snippet.Title
-		artist, trackName := parseYouTubeTitle(title)
+		artist, trackName := match.ParseYouTubeTitle(title)
+		video, hydrated := videos[item.Snippet.ResourceID.VideoID]
+
+		if special && !isLikelyMusicVideo(item.Snippet.VideoOwnerChannelTitle, title, video.Snippet.CategoryID == youtube.MusicCategoryID) {
+			log.Printf("Skipping non-music video in %s: '%s'", playlistName, title)
+			continue
+		}
 
 		log.Printf("YouTube track - Original: '%s', Parsed: Artist='%s', Track='%s'", title, artist, trackName)
 
-		tracks = append(tracks, Track{
-			ID:     item.Snippet.ResourceID.VideoID,
-			Name:   trackName,
-			Artist: artist,
-		})
+		track := Track{
+			ID:      item.Snippet.ResourceID.VideoID,
+			Name:    trackName,
+			Artist:  artist,
+			Service: "youtube",
+			AddedAt: unixOrZero(item.Snippet.PublishedAt),
+			AddedBy: item.Snippet.ChannelID,
+			ItemID:  item.ID,
+		}
+		if hydrated {
+			hydrateYouTubeTrack(&track, video)
+		}
+		tracks = append(tracks, track)
 	}
 
-	return tracks, playlistName, nil
+	return tracks, SourcePlaylist{Name: playlistName, Privacy: privacy, Total: total, Fetched: len(items)}, nil
 }
 
-// getYouTubePlaylistName gets the name of a YouTube playlist
-func getYouTubePlaylistName(accessToken, playlistID string) (string, error) {
-	client := &http.Client{}
-	url := fmt.Sprintf("https://www.googleapis.com/youtube/v3/playlists?part=snippet&id=%s", playlistID)
-
-	req, err := http.NewRequest("GET", url, nil)
-	if err != nil {
-		return "", err
+// playlistFetchError maps a failed playlist read to a specific access error where possible
+func playlistFetchError(err error) error {
+	var statusErr *providers.StatusError
+	if errors.As(err, &statusErr) {
+		if accessErr := playlistAccessError(statusErr.Status, statusErr.Body); accessErr != nil {
+			return accessErr
+		}
 	}
+	return err
+}
 
-	req.Header.Set("Authorization", "Bearer "+accessToken)
-	resp, err := client.Do(req)
+// getYouTubePlaylistInfo gets the name and privacy status of a YouTube playlist
+func (h *Handlers) getYouTubePlaylistInfo(ctx context.Context, accessToken, playlistID string) (string, string, error) {
+	playlists, err := h.Providers.YouTube.Playlists(ctx, accessToken, playlistID)
 	if err != nil {
-		return "", err
-	}
-	defer resp.Body.Close()
-
-	if resp.StatusCode != http.StatusOK {
-		return "", fmt.Errorf("youtube API returned status: %d", resp.StatusCode)
+		return "", "", err
 	}
-
-	var response struct {
-		Items []struct {
-			Snippet struct {
-				Title string `json:"title"`
-			} `json:"snippet"`
-		} `json:"items"`
-	}
-
-	if err := json.NewDecoder(resp.Body).Decode(&response); err != nil {
-		return "", err
+	if len(playlists) == 0 {
+		return "", "", fmt.Errorf("playlist not found")
 	}
-
-	if len(response.Items) == 0 {
-		return "", fmt.Errorf("playlist not found")
+	privacy := ""
+	if playlists[0].Status != nil {
+		privacy = playlists[0].Status.PrivacyStatus
 	}
-
-	return response.Items[0].Snippet.Title, nil
+	return playlists[0].Snippet.Title, privacy, nil
 }
 
-// parseYouTubeTitle attempts to parse artist and track name from YouTube video title
-// parseYouTubeTitle attempts to parse artist and track name from YouTube video title
-func parseYouTubeTitle(title string) (string, string) {
-	title = strings.TrimSpace(title)
-
-	// Remove common YouTube suffixes
-	suffixes := []string{
-		"(Official Video)", "(Official Audio)", "[Official Video]", "[Official Audio]",
-		"(Official Music Video)", "[Official Music Video]", "(Lyric Video)", "[Lyric Video]",
-		"(Visualizer)", "[Visualizer]", "(Lyrics)", "[Lyrics]", "(Live)", "[Live]",
-		"(Acoustic)", "[Acoustic]", "(Remix)", "[Remix]", "(Cover)", "[Cover]",
-		"| Official Video", "| Official Audio", "| Official Music Video",
+// targetPrivacy is the visibility a created playlist gets. "mirror" copies the
+// source's; Spotify has no unlisted playlists, and its private ones can still be
+// opened by link, so unlisted maps to private there.
+func targetPrivacy(requested, sourcePrivacy, targetService string) string {
+	privacy := requested
+	if privacy == "mirror" {
+		privacy = sourcePrivacy
 	}
-
-	for _, suffix := range suffixes {
-		title = strings.Replace(title, suffix, "", -1)
-	}
-
-	title = strings.TrimSpace(title)
-
-	// Try different patterns
-	patterns := []string{
-		`^(.*?)\s*[-–—]\s*(.*)$`, // "Artist - Track"
-		`^(.*?)\s*:\s*(.*)$`,     // "Artist: Track"
-		`^(.*?)\s*\|\s*(.*)$`,    // "Artist | Track"
-		`^(.*?)\s*-\s*(.*)$`,     // "Artist - Track" (regular dash)
+	if privacy == "" || privacy == "mirror" {
+		privacy = "private"
 	}
-
-	for _, pattern := range patterns {
-		re := regexp.MustCompile(pattern)
-		matches := re.FindStringSubmatch(title)
-		if len(matches) == 3 {
-			artist := strings.TrimSpace(matches[1])
-			track := strings.TrimSpace(matches[2])
-			if artist != "" && track != "" {
-				return artist, track
-			}
-		}
+	if targetService == "spotify" && privacy == "unlisted" {
+		privacy = "private"
 	}
-
-	// If no pattern matches, return the whole title as track name
-	return "", title
+	return privacy
 }
 
 // searchTrack searches for a track on the target service
-func searchTrack(serviceType, accessToken string, track Track) (Track, float64, error) {
+func (h *Handlers) searchTrack(ctx context.Context, serviceType, accessToken string, track Track, options SearchOptions) (Track, float64, error) {
+	// Searching is public, so it can draw on the app credential pool instead of the user's token
+	accessToken = h.lookupToken(ctx, serviceType, accessToken)
+
 	switch serviceType {
 	case "spotify":
-		return searchSpotifyTrack(accessToken, track)
+		return h.searchSpotifyTrack(ctx, accessToken, track, options)
 	case "youtube":
-		return searchYouTubeTrack(accessToken, track)
+		return h.searchYouTubeTrack(ctx, accessToken, track, options)
 	default:
 		return Track{}, 0.0, fmt.Errorf("unsupported service: %s", serviceType)
 	}
 }
 
-// searchSpotifyTrack searches for a track on Spotify
-func searchSpotifyTrack(accessToken string, track Track) (Track, float64, error) {
-	client := ratelimit.NewRateLimitedHTTPClient(ratelimit.SpotifyService, rateLimiter)
-
-	// Build search query - handle empty artist
-	var query string
-	if track.Artist != "" {
-		query = fmt.Sprintf("track:%s artist:%s", track.Name, track.Artist)
-	} else {
-		query = fmt.Sprintf("track:%s", track.Name)
+// searchSpotifyTrack tries the search strategies in order until one finds the
+// track. A hit that cannot be played in the user's market is only returned,
+// with errUnavailableInRegion, when no strategy finds a playable one.
+func (h *Handlers) searchSpotifyTrack(ctx context.Context, accessToken string, track Track, options SearchOptions) (Track, float64, error) {
+	var unavailable *Track
+	var unavailableConfidence float64
+
+	err := errTrackNotFound
+	for _, strategy := range options.strategies("spotify") {
+		var hit Track
+		var confidence float64
+		if strategy == "musicbrainz" {
+			hit, confidence, err = h.searchMusicBrainzCounterpart(ctx, track, "spotify")
+		} else {
+			query, ok := spotifyStrategyQuery(strategy, track)
+			if !ok {
+				continue
+			}
+			hit, confidence, err = h.searchSpotifyQuery(ctx, accessToken, query, track, options.Market, options.limit("spotify"))
+			if strategy == "isrc" {
+				// An ISRC hit is the same recording however its title is formatted
+				confidence = 1.0
+			}
+		}
+		if errors.Is(err, errTrackNotFound) {
+			continue
+		}
+		hit.MatchedBy = strategy
+		if errors.Is(err, errUnavailableInRegion) {
+			// Another release of the recording may be playable
+			if unavailable == nil {
+				unavailable, unavailableConfidence = &hit, confidence
+			}
+			continue
+		}
+		return hit, confidence, err
+	}
+	if unavailable != nil {
+		return *unavailable, unavailableConfidence, errUnavailableInRegion
 	}
+	return Track{}, 0.0, err
+}
 
-	encodedQuery := url.QueryEscape(query)
+// spotifyStrategyQuery builds the search query of a strategy; ok is false when
+// the strategy does not apply to the track, e.g. an ISRC lookup without an ISRC
+func spotifyStrategyQuery(strategy string, track Track) (string, bool) {
+	switch strategy {
+	case "isrc":
+		return "isrc:" + track.ISRC, track.ISRC != ""
+	case "fielded":
+		if track.Artist == "" {
+			return "track:" + track.Name, true
+		}
+		return fmt.Sprintf("track:%s artist:%s", track.Name, track.Artist), true
+	case "plain":
+		return track.Name + " " + track.Artist, track.Artist != ""
+	case "title_only":
+		return track.Name, true
+	}
+	return "", false
+}
 
+// searchSpotifyQuery runs a Spotify track search and returns the result scoring best against
+// the source track, preferring results playable in market
+func (h *Handlers) searchSpotifyQuery(ctx context.Context, accessToken, query string, track Track, market string, limit int) (Track, float64, error) {
 	log.Printf("Searching Spotify for: %s", query)
 
-	req, err := http.NewRequest("GET",
-		fmt.Sprintf("https://api.spotify.com/v1/search?q=%s&type=track&limit=5", encodedQuery),
-		nil)
-	if err != nil {
-		rateMonitor.RecordRequest(ratelimit.SpotifyService, false, true)
-		return Track{}, 0.0, err
-	}
-
-	req.Header.Set("Authorization", "Bearer "+accessToken)
-	resp, err := client.Do(req)
+	results, err := cachedSearch(ctx, "spotify", query, []string{market, strconv.Itoa(limit)}, func() ([]spotify.Track, error) {
+		return h.Providers.Spotify.SearchTracks(ctx, accessToken, query, market, limit)
+	})
 	if err != nil {
-		rateMonitor.RecordRequest(ratelimit.SpotifyService, false, true)
 		return Track{}, 0.0, err
 	}
-	defer resp.Body.Close()
-
-	wasRateLimited := resp.StatusCode == http.StatusTooManyRequests
-	rateMonitor.RecordRequest(ratelimit.SpotifyService, wasRateLimited, false)
-
-	if resp.StatusCode != http.StatusOK {
-		body, _ := io.ReadAll(resp.Body)
-		log.Printf("Spotify search API error: %d, body: %s", resp.StatusCode, string(body))
-		return Track{}, 0.0, fmt.Errorf("spotify API returned status: %d", resp.StatusCode)
-	}
-
-	var searchResponse struct {
-		Tracks struct {
-			Items []struct {
-				ID      string `json:"id"`
-				Name    string `json:"name"`
-				Artists []struct {
-					Name string `json:"name"`
-				} `json:"artists"`
-			} `json:"items"`
-		} `json:"tracks"`
+	if len(results) == 0 {
+		return Track{}, 0.0, errTrackNotFound
 	}
 
-	if err := json.NewDecoder(resp.Body).Decode(&searchResponse); err != nil {
-		return Track{}, 0.0, err
+	// Greyed-out tracks are left out while any result is playable in the market
+	candidates := make([]spotify.Track, 0, len(results))
+	for _, result := range results {
+		if result.IsPlayable == nil || *result.IsPlayable {
+			candidates = append(candidates, result)
+		}
 	}
-
-	if len(searchResponse.Tracks.Items) == 0 {
-		return Track{}, 0.0, fmt.Errorf("track not found")
+	playable := len(candidates) > 0
+	if !playable {
+		candidates = results
 	}
 
-	// Return the first result for now
-	bestMatch := searchResponse.Tracks.Items[0]
-	artist := ""
-	if len(bestMatch.Artists) > 0 {
-		artist = bestMatch.Artists[0].Name
+	// The best scoring result wins; among equally good ones the closest in length,
+	// when the source's length is known
+	bestMatch, confidence := candidates[0], -1.0
+	for _, result := range candidates {
+		score := match.Confidence(track.Name, track.Artist, result.Name, result.FirstArtist())
+		if score > confidence || (score == confidence && durationGap(track.Duration, result.DurationMS) < durationGap(track.Duration, bestMatch.DurationMS)) {
+			bestMatch, confidence = result, score
+		}
 	}
-
-	confidence := calculateMatchConfidence(track.Name, track.Artist, bestMatch.Name, artist)
+	artist := bestMatch.FirstArtist()
 
 	log.Printf("Found track: %s - %s (confidence: %.2f)", artist, bestMatch.Name, confidence)
 
-	return Track{
-		ID:     bestMatch.ID,
-		Name:   bestMatch.Name,
-		Artist: artist,
-	}, confidence, nil
-}
-
-// searchYouTubeTrack searches for a track on YouTube
-func searchYouTubeTrack(accessToken string, track Track) (Track, float64, error) {
-	client := ratelimit.NewRateLimitedHTTPClient(ratelimit.YouTubeService, rateLimiter)
-
-	// Build better search query for music
-	query := fmt.Sprintf("%s %s official audio", track.Name, track.Artist)
-	encodedQuery := url.QueryEscape(query)
-	url := fmt.Sprintf("https://www.googleapis.com/youtube/v3/search?part=snippet&q=%s&type=video&maxResults=5&videoCategoryId=10", encodedQuery) // category 10 is music
-
-	req, err := http.NewRequest("GET", url, nil)
-	if err != nil {
-		rateMonitor.RecordRequest(ratelimit.YouTubeService, false, true)
-		return Track{}, 0.0, err
+	found := Track{
+		ID:      bestMatch.ID,
+		Name:    bestMatch.Name,
+		Artist:  artist,
+		ISRC:    bestMatch.ExternalIDs.ISRC,
+		Service: "spotify",
 	}
 
-	req.Header.Set("Authorization", "Bearer "+accessToken)
-	resp, err := client.Do(req)
-	if err != nil {
-		rateMonitor.RecordRequest(ratelimit.YouTubeService, false, true)
-		return Track{}, 0.0, err
+	if !playable {
+		return found, confidence, errUnavailableInRegion
 	}
-	defer resp.Body.Close()
 
-	wasRateLimited := resp.StatusCode == http.StatusTooManyRequests
-	rateMonitor.RecordRequest(ratelimit.YouTubeService, wasRateLimited, false)
-
-	if resp.StatusCode != http.StatusOK {
-		body, _ := io.ReadAll(resp.Body)
-		log.Printf("YouTube search API error: %d, body: %s", resp.StatusCode, string(body))
-		return Track{}, 0.0, fmt.Errorf("youtube API returned status: %d", resp.StatusCode)
-	}
-
-	var searchResponse struct {
-		Items []struct {
-			ID struct {
-				VideoID string `json:"videoId"`
-			} `json:"id"`
-			Snippet struct {
-				Title       string `json:"title"`
-				Description string `json:"description"`
-			} `json:"snippet"`
-		} `json:"items"`
-	}
-
-	if err := json.NewDecoder(resp.Body).Decode(&searchResponse); err != nil {
-		return Track{}, 0.0, err
-	}
+	return found, confidence, nil
+}
 
-	if len(searchResponse.Items) == 0 {
-		return Track{}, 0.0, fmt.Errorf("no results found")
+// durationGap is how far a candidate's length is from the source's, 0 when the source length is unknown
+func durationGap(sourceMS, candidateMS int) int {
+	if sourceMS == 0 {
+		return 0
 	}
+	return max(sourceMS-candidateMS, candidateMS-sourceMS)
+}
 
-	// Find the best match
-	bestMatch := searchResponse.Items[0]
-	bestConfidence := 0.0
-
-	for _, item := range searchResponse.Items {
-		confidence := calculateYouTubeMatchConfidence(track, item.Snippet.Title, item.Snippet.Description)
-		if confidence > bestConfidence {
-			bestMatch = item
-			bestConfidence = confidence
+// searchYouTubeTrack tries the search strategies in order until one finds the track
+func (h *Handlers) searchYouTubeTrack(ctx context.Context, accessToken string, track Track, options SearchOptions) (Track, float64, error) {
+	err := errTrackNotFound
+	for _, strategy := range options.strategies("youtube") {
+		var hit Track
+		var confidence float64
+		switch strategy {
+		case "musicbrainz":
+			hit, confidence, err = h.searchMusicBrainzCounterpart(ctx, track, "youtube")
+		case "plain":
+			hit, confidence, err = h.searchYouTubeQuery(ctx, accessToken, fmt.Sprintf("%s %s %s", track.Name, track.Artist, youtubeVideoTypeQuery(options.YouTubeVideoType)), track, options)
+		case "title_only":
+			if track.Artist == "" {
+				// Same query as plain, not worth another search's quota
+				continue
+			}
+			hit, confidence, err = h.searchYouTubeQuery(ctx, accessToken, fmt.Sprintf("%s %s", track.Name, youtubeVideoTypeQuery(options.YouTubeVideoType)), track, options)
+		default:
+			continue
 		}
+		if errors.Is(err, errTrackNotFound) {
+			continue
+		}
+		hit.MatchedBy = strategy
+		return hit, confidence, err
 	}
-
-	artist, trackName := parseYouTubeTitle(bestMatch.Snippet.Title)
-
-	return Track{
-		ID:     bestMatch.ID.VideoID,
-		Name:   trackName,
-		Artist: artist,
-	}, bestConfidence, nil
+	return Track{}, 0.0, err
 }
 
-// Add a YouTube-specific confidence calculator
-func calculateYouTubeMatchConfidence(track Track, title, description string) float64 {
-	confidence := 0.0
-	titleLower := strings.ToLower(title)
-	descLower := strings.ToLower(description)
-	trackNameLower := strings.ToLower(track.Name)
-	artistLower := strings.ToLower(track.Artist)
-
-	// Check for track name in title
-	if strings.Contains(titleLower, trackNameLower) {
-		confidence += 0.4
+// searchYouTubeQuery runs a YouTube search and picks the best playable candidate for the source track
+func (h *Handlers) searchYouTubeQuery(ctx context.Context, accessToken, query string, track Track, options SearchOptions) (Track, float64, error) {
+	search := youtube.SearchRequest{
+		Query:      strings.TrimSpace(query),
+		MaxResults: options.limit("youtube"),
+		CategoryID: youtube.MusicCategoryID,
+		RegionCode: options.Market,
 	}
-
-	// Check for artist in title
-	if strings.Contains(titleLower, artistLower) {
-		confidence += 0.3
+	results, err := cachedSearch(ctx, "youtube", search.Query, []string{search.RegionCode, search.CategoryID, strconv.Itoa(search.MaxResults)}, func() ([]youtube.SearchResult, error) {
+		return h.Providers.YouTube.Search(ctx, accessToken, search)
+	})
+	if err != nil {
+		var statusErr *providers.StatusError
+		if errors.As(err, &statusErr) {
+			h.noteYouTubeQuota(accessToken, statusErr.Status, []byte(statusErr.Body))
+		}
+		return Track{}, 0.0, err
 	}
 
-	// Check for "official" in title (indicates official music video/audio)
-	if strings.Contains(titleLower, "official") {
-		confidence += 0.2
+	if len(results) == 0 {
+		return Track{}, 0.0, errTrackNotFound
 	}
 
-	// Check for music-related terms
-	if strings.Contains(titleLower, "audio") || strings.Contains(descLower, "music") {
-		confidence += 0.1
+	// Rank candidates, putting the preferred kind of upload first
+	candidates := make([]youtubeCandidate, 0, len(results))
+	for _, item := range results {
+		confidence := match.YouTubeConfidence(track.Name, track.Artist, item.Snippet.Title, item.Snippet.Description)
+		artist, trackName := match.ParseYouTubeTitle(item.Snippet.Title)
+		entityType := youtubeEntityType(item.Snippet.Title, item.Snippet.ChannelTitle, item.Snippet.Description)
+		candidates = append(candidates, youtubeCandidate{
+			Track:      Track{ID: item.ID.VideoID, Name: trackName, Artist: artist, Service: "youtube", EntityType: entityType},
+			Confidence: confidence,
+			score:      confidence + youtubeVideoTypeBonus(options.YouTubeVideoType, entityType, item.Snippet.Title, item.Snippet.ChannelTitle),
+		})
 	}
+	sort.SliceStable(candidates, func(i, j int) bool { return candidates[i].score > candidates[j].score })
 
-	return confidence
-}
-
-// calculateMatchConfidence calculates how well two tracks match
-func calculateMatchConfidence(sourceName, sourceArtist, targetName, targetArtist string) float64 {
-	confidence := 0.0
-
-	// Normalize strings for comparison
-	sourceNameNorm := strings.ToLower(strings.TrimSpace(sourceName))
-	targetNameNorm := strings.ToLower(strings.TrimSpace(targetName))
-	sourceArtistNorm := strings.ToLower(strings.TrimSpace(sourceArtist))
-	targetArtistNorm := strings.ToLower(strings.TrimSpace(targetArtist))
-
-	// Name matching
-	if sourceNameNorm == targetNameNorm {
-		confidence += 0.6
-	} else if strings.Contains(sourceNameNorm, targetNameNorm) || strings.Contains(targetNameNorm, sourceNameNorm) {
-		confidence += 0.4
-	} else {
-		// Try to remove common suffixes
-		sourceClean := removeCommonSuffixes(sourceNameNorm)
-		targetClean := removeCommonSuffixes(targetNameNorm)
-		if sourceClean == targetClean {
-			confidence += 0.5
+	// Text matching was unsure; let the audio decide between the candidates
+	if candidates[0].Confidence < deepMatchThreshold() {
+		if deep, ok := h.deepMatchYouTube(ctx, track, candidates); ok {
+			i := slices.IndexFunc(candidates, func(c youtubeCandidate) bool { return c.Track.ID == deep.Track.ID })
+			candidates = append([]youtubeCandidate{deep}, slices.Delete(candidates, i, i+1)...)
 		}
 	}
 
-	// Artist matching
-	if sourceArtistNorm == targetArtistNorm {
-		confidence += 0.4
-	} else if strings.Contains(sourceArtistNorm, targetArtistNorm) || strings.Contains(targetArtistNorm, sourceArtistNorm) {
-		confidence += 0.2
+	// regionCode only ranks results, so check the chosen video is actually
+	// playable; restricted videos make way for the next-best candidate
+	ids := make([]string, len(candidates))
+	for i, candidate := range candidates {
+		ids[i] = candidate.Track.ID
 	}
-
-	return confidence
-}
-
-// removeCommonSuffixes removes common track name suffixes
-func removeCommonSuffixes(name string) string {
-	suffixes := []string{" - remaster", " (remaster", " - live", " (live", " - acoustic", " (acoustic"}
-	result := name
-	for _, suffix := range suffixes {
-		if idx := strings.Index(result, suffix); idx != -1 {
-			result = result[:idx]
-		}
+	best, restriction := pickUnrestrictedCandidate(candidates, h.youtubeRestrictions(ctx, accessToken, ids, options.Market))
+	if restriction == restrictionRegionBlocked {
+		return best.Track, best.Confidence, errUnavailableInRegion
 	}
-	return strings.TrimSpace(result)
+
+	return best.Track, best.Confidence, nil
 }
 
 // createPlaylist creates a new playlist on the target service
-func createPlaylist(serviceType, accessToken, name, description string) (string, error) {
+func (h *Handlers) createPlaylist(ctx context.Context, serviceType, accessToken, name, description string, options PlaylistCreateOptions) (string, error) {
+	name = sanitizePlaylistName(serviceType, name)
+	description = sanitizePlaylistDescription(serviceType, description)
+
 	switch serviceType {
 	case "spotify":
-		return createSpotifyPlaylist(accessToken, name, description)
+		return h.createSpotifyPlaylist(ctx, accessToken, name, description, options)
 	case "youtube":
-		return createYouTubePlaylist(accessToken, name, description)
+		return h.createYouTubePlaylist(ctx, accessToken, name, description, options)
 	default:
 		return "", fmt.Errorf("unsupported service: %s", serviceType)
 	}
 }
 
 // createSpotifyPlaylist creates a Spotify playlist
-func createSpotifyPlaylist(accessToken, name, description string) (string, error) {
-	client := ratelimit.NewRateLimitedHTTPClient(ratelimit.SpotifyService, rateLimiter)
-
-	req, err := http.NewRequest("GET", "https://api.spotify.com/v1/me", nil)
-	if err != nil {
-		rateMonitor.RecordRequest(ratelimit.SpotifyService, false, true)
-		return "", err
-	}
-
-	req.Header.Set("Authorization", "Bearer "+accessToken)
-	resp, err := client.Do(req)
-	if err != nil {
-		rateMonitor.RecordRequest(ratelimit.SpotifyService, false, true)
-		return "", err
-	}
-	defer resp.Body.Close()
-
-	wasRateLimited := resp.StatusCode == http.StatusTooManyRequests
-	rateMonitor.RecordRequest(ratelimit.SpotifyService, wasRateLimited, false)
-
-	if resp.StatusCode != http.StatusOK {
-		return "", fmt.Errorf("failed to get user info: %d", resp.StatusCode)
-	}
-
-	var userInfo struct {
-		ID string `json:"id"`
-	}
-	if err := json.NewDecoder(resp.Body).Decode(&userInfo); err != nil {
-		return "", err
-	}
-
-	// Create the playlist
-	createData := map[string]interface{}{
-		"name":        name,
-		"description": description,
-		"public":      false,
-	}
-	createBody, _ := json.Marshal(createData)
-
-	req, err = http.NewRequest("POST", fmt.Sprintf("https://api.spotify.com/v1/users/%s/playlists", userInfo.ID), strings.NewReader(string(createBody)))
+func (h *Handlers) createSpotifyPlaylist(ctx context.Context, accessToken, name, description string, options PlaylistCreateOptions) (string, error) {
+	user, err := h.Providers.Spotify.CurrentUser(ctx, accessToken)
 	if err != nil {
-		return "", err
+		return "", fmt.Errorf("failed to get user info: %w", err)
 	}
 
-	req.Header.Set("Authorization", "Bearer "+accessToken)
-	req.Header.Set("Content-Type", "application/json")
-	resp, err = client.Do(req)
+	playlist, err := h.Providers.Spotify.CreatePlaylist(ctx, accessToken, user.ID, spotify.CreatePlaylistRequest{
+		Name:          name,
+		Description:   description,
+		Public:        options.Privacy == "public" && !options.Collaborative,
+		Collaborative: options.Collaborative,
+	})
 	if err != nil {
 		return "", err
 	}
-	defer resp.Body.Close()
 
-	if resp.StatusCode != http.StatusCreated && resp.StatusCode != http.StatusOK {
-		body, _ := io.ReadAll(resp.Body)
-		log.Printf("Spotify playlist creation error: %d, body: %s", resp.StatusCode, string(body))
-		return "", fmt.Errorf("failed to create playlist: %d", resp.StatusCode)
-	}
-
-	var playlistResponse struct {
-		ID string `json:"id"`
-	}
-	if err := json.NewDecoder(resp.Body).Decode(&playlistResponse); err != nil {
-		return "", err
-	}
-
-	return playlistResponse.ID, nil
+	return playlist.ID, nil
 }
 
 // createYouTubePlaylist creates a YouTube playlist
-func createYouTubePlaylist(accessToken, name, description string) (string, error) {
-	client := ratelimit.NewRateLimitedHTTPClient(ratelimit.YouTubeService, rateLimiter)
-
-	createData := map[string]interface{}{
-		"snippet": map[string]string{
-			"title":       name,
-			"description": description,
-		},
-		"status": map[string]string{
-			"privacyStatus": "private",
-		},
-	}
-	createBody, _ := json.Marshal(createData)
-
-	req, err := http.NewRequest("POST", "https://www.googleapis.com/youtube/v3/playlists?part=snippet,status", strings.NewReader(string(createBody)))
-	if err != nil {
-		rateMonitor.RecordRequest(ratelimit.YouTubeService, false, true)
-		return "", err
+func (h *Handlers) createYouTubePlaylist(ctx context.Context, accessToken, name, description string, options PlaylistCreateOptions) (string, error) {
+	privacy := options.Privacy
+	if privacy == "" {
+		privacy = "private"
 	}
 
-	req.Header.Set("Authorization", "Bearer "+accessToken)
-	req.Header.Set("Content-Type", "application/json")
-	resp, err := client.Do(req)
+	playlist, err := h.Providers.YouTube.InsertPlaylist(ctx, accessToken, youtube.Playlist{
+		Snippet: youtube.PlaylistSnippet{Title: name, Description: description},
+		Status:  &youtube.PlaylistStatus{PrivacyStatus: privacy},
+	})
 	if err != nil {
-		rateMonitor.RecordRequest(ratelimit.YouTubeService, false, true)
 		return "", err
 	}
-	defer resp.Body.Close()
-
-	wasRateLimited := resp.StatusCode == http.StatusTooManyRequests
-	rateMonitor.RecordRequest(ratelimit.YouTubeService, wasRateLimited, false)
 
-	if resp.StatusCode != http.StatusOK {
-		body, _ := io.ReadAll(resp.Body)
-		log.Printf("YouTube playlist creation error: %d, body: %s", resp.StatusCode, string(body))
-		return "", fmt.Errorf("failed to create playlist: %d", resp.StatusCode)
-	}
+	return playlist.ID, nil
+}
 
-	var playlistResponse struct {
-		ID string `json:"id"`
-	}
-	if err := json.NewDecoder(resp.Body).Decode(&playlistResponse); err != nil {
-		return "", err
+// playlistShareURL returns the public link to a playlist on its service
+func playlistShareURL(serviceType, playlistID string) string {
+	switch serviceType {
+	case "spotify":
+		return "https://open.spotify.com/playlist/" + playlistID
+	case "youtube":
+		return "https://www.youtube.com/playlist?list=" + playlistID
+	default:
+		return ""
 	}
-
-	return playlistResponse.ID, nil
 }
 
 // addTrackToPlaylist adds a track to a playlist
-func addTrackToPlaylist(serviceType, accessToken, playlistID, trackID string) error {
+func (h *Handlers) addTrackToPlaylist(ctx context.Context, serviceType, accessToken, playlistID, trackID string) error {
 	switch serviceType {
 	case "spotify":
-		return addTrackToSpotifyPlaylist(accessToken, playlistID, trackID)
+		return h.addTrackToSpotifyPlaylist(ctx, accessToken, playlistID, trackID)
 	case "youtube":
-		return addTrackToYouTubePlaylist(accessToken, playlistID, trackID)
+		return h.addTrackToYouTubePlaylist(ctx, accessToken, playlistID, trackID)
 	default:
 		return fmt.Errorf("unsupported service: %s", serviceType)
 	}
 }
 
-// addTrackToSpotifyPlaylist adds a track to a Spotify playlist
-func addTrackToSpotifyPlaylist(accessToken, playlistID, trackID string) error {
-	client := ratelimit.NewRateLimitedHTTPClient(ratelimit.SpotifyService, rateLimiter)
-
-	addData := map[string]interface{}{
-		"uris": []string{fmt.Sprintf("spotify:track:%s", trackID)},
-	}
-	addBody, _ := json.Marshal(addData)
-
-	req, err := http.NewRequest("POST", fmt.Sprintf("https://api.spotify.com/v1/playlists/%s/tracks", playlistID), strings.NewReader(string(addBody)))
+// playlistHasTracks reads the whole playlist and reports whether every one of
+// ids is in it. A track the playlist held before an add also counts, so an
+// ambiguous add of a duplicate is not retried. A read that came back short is
+// an error, as the tracks could be on the missing pages.
+func (h *Handlers) playlistHasTracks(ctx context.Context, serviceType, accessToken, playlistID string, ids []string) (bool, error) {
+	tracks, playlist, err := h.fetchPlaylistTracks(ctx, serviceType, accessToken, playlistID)
 	if err != nil {
-		rateMonitor.RecordRequest(ratelimit.SpotifyService, false, true)
-		return err
+		return false, err
 	}
-
-	req.Header.Set("Authorization", "Bearer "+accessToken)
-	req.Header.Set("Content-Type", "application/json")
-	resp, err := client.Do(req)
-	if err != nil {
-		rateMonitor.RecordRequest(ratelimit.SpotifyService, false, true)
-		return err
+	if !playlist.Complete() {
+		return false, fmt.Errorf("read only %d of the playlist's %d tracks", playlist.Fetched, playlist.Total)
 	}
-	defer resp.Body.Close()
-
-	wasRateLimited := resp.StatusCode == http.StatusTooManyRequests
-	rateMonitor.RecordRequest(ratelimit.SpotifyService, wasRateLimited, false)
-
-	if resp.StatusCode != http.StatusCreated && resp.StatusCode != http.StatusOK {
-		body, _ := io.ReadAll(resp.Body)
-		log.Printf("Spotify add track error: %d, body: %s", resp.StatusCode, string(body))
-		return fmt.Errorf("failed to add track: %d", resp.StatusCode)
+	present := make(map[string]bool, len(tracks))
+	for _, track := range tracks {
+		present[track.ID] = true
 	}
-
-	return nil
-}
-
-// addTrackToYouTubePlaylist adds a track to a YouTube playlist
-func addTrackToYouTubePlaylist(accessToken, playlistID, trackID string) error {
-	client := ratelimit.NewRateLimitedHTTPClient(ratelimit.YouTubeService, rateLimiter)
-
-	addData := map[string]interface{}{
-		"snippet": map[string]interface{}{
-			"playlistId": playlistID,
-			"resourceId": map[string]string{
-				"kind":    "youtube#video",
-				"videoId": trackID,
-			},
-		},
+	for _, id := range ids {
+		if !present[id] {
+			return false, nil
+		}
 	}
-	addBody, _ := json.Marshal(addData)
+	return true, nil
+}
 
-	req, err := http.NewRequest("POST", "https://www.googleapis.com/youtube/v3/playlistItems?part=snippet", strings.NewReader(string(addBody)))
-	if err != nil {
-		rateMonitor.RecordRequest(ratelimit.YouTubeService, false, true)
-		return err
+// spotifyAddBatchSize is the most tracks Spotify adds to a playlist in one request
+const spotifyAddBatchSize = 100
+
+// addTracksToSpotifyPlaylist adds tracks to a Spotify playlist in batches,
+// retrying transient failures of batches that did not land. It returns how many were added before a batch failed.
+func (h *Handlers) addTracksToSpotifyPlaylist(ctx context.Context, accessToken, playlistID string, trackIDs []string) (int, error) {
+	added := 0
+	for start := 0; start < len(trackIDs); start += spotifyAddBatchSize {
+		end := min(start+spotifyAddBatchSize, len(trackIDs))
+		uris := make([]string, 0, end-start)
+		for _, id := range trackIDs[start:end] {
+			uris = append(uris, "spotify:track:"+id)
+		}
+		batch := trackIDs[start:end]
+		err := withTrackWriteRetry(ctx, "adding tracks", nil, func(ctx context.Context) error {
+			return h.Providers.Spotify.AddTracks(ctx, accessToken, playlistID, uris)
+		}, func(ctx context.Context) (bool, error) {
+			return h.playlistHasTracks(ctx, "spotify", accessToken, playlistID, batch)
+		})
+		if err != nil {
+			return added, err
+		}
+		added += len(uris)
 	}
+	return added, nil
+}
 
-	req.Header.Set("Authorization", "Bearer "+accessToken)
-	req.Header.Set("Content-Type", "application/json")
-	resp, err := client.Do(req)
-	if err != nil {
-		rateMonitor.RecordRequest(ratelimit.YouTubeService, false, true)
-		return err
-	}
-	defer resp.Body.Close()
+// addTrackToSpotifyPlaylist adds a track to a Spotify playlist
+func (h *Handlers) addTrackToSpotifyPlaylist(ctx context.Context, accessToken, playlistID, trackID string) error {
+	return h.Providers.Spotify.AddTracks(ctx, accessToken, playlistID, []string{"spotify:track:" + trackID})
+}
 
-	wasRateLimited := resp.StatusCode == http.StatusTooManyRequests
-	rateMonitor.RecordRequest(ratelimit.YouTubeService, wasRateLimited, false)
+// addTrackToYouTubePlaylist adds a track to a YouTube playlist
+func (h *Handlers) addTrackToYouTubePlaylist(ctx context.Context, accessToken, playlistID, trackID string) error {
+	return h.Providers.YouTube.InsertPlaylistItem(ctx, accessToken, playlistID, trackID)
+}
 
-	if resp.StatusCode != http.StatusOK {
-		body, _ := io.ReadAll(resp.Body)
-		log.Printf("YouTube add track error: %d, body: %s", resp.StatusCode, string(body))
-		return fmt.Errorf("failed to add track: %d", resp.StatusCode)
+// startSpotifyPlayback starts playing a playlist on the user's active Spotify device
+func (h *Handlers) startSpotifyPlayback(ctx context.Context, accessToken, playlistID string) error {
+	err := h.Providers.Spotify.Play(ctx, accessToken, "spotify:playlist:"+playlistID)
+	var statusErr *providers.StatusError
+	if errors.As(err, &statusErr) {
+		switch statusErr.Status {
+		case http.StatusNotFound:
+			return fmt.Errorf("no active Spotify device")
+		case http.StatusForbidden, http.StatusUnauthorized:
+			return fmt.Errorf("playback not permitted (Premium and the playback scope are required, reconnect Spotify)")
+		}
+		return fmt.Errorf("failed to start playback: %d", statusErr.Status)
 	}
-
-	return nil
+	return err
 }
@@ -0,0 +1,418 @@
+package handlers
+
+import (
+	"context"
+	"crypto/rand"
+	"encoding/hex"
+	"errors"
+	"net/http"
+	"os"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"server/internal/auth"
+	"server/internal/config"
+	"server/internal/database"
+	"server/internal/i18n"
+	"server/internal/middleware"
+
+	"github.com/gin-gonic/gin"
+	"github.com/golang-jwt/jwt/v5"
+)
+
+const (
+	twoFactorChallengeTTL = 5 * time.Minute
+	maxTwoFactorAttempts  = 5 // wrong codes allowed per challenge, or per user when changing 2FA
+	backupCodeCount       = 10
+)
+
+var totpIssuer = config.String("TOTP_ISSUER", "sync-playlist")
+
+// twoFactorChallenge is handed out instead of a login token when 2FA is on. It
+// deliberately has no Subject, so AuthMiddleware never accepts it as a login.
+type twoFactorChallenge struct {
+	PendingUserID uint `json:"pending_user_id"`
+	jwt.RegisteredClaims
+}
+
+// challengeAttempts counts wrong codes per challenge ID, and per user for
+// changes to an enabled 2FA setup (see settingsAttemptKey)
+var challengeAttempts = struct {
+	sync.Mutex
+	byID map[string]challengeAttempt
+}{byID: make(map[string]challengeAttempt)}
+
+type challengeAttempt struct {
+	count     int
+	expiresAt time.Time
+}
+
+type TwoFactorCodeRequest struct {
+	Code string `json:"code" binding:"required"`
+}
+
+type TwoFactorChallengeRequest struct {
+	Challenge string `json:"challenge" binding:"required"`
+	Code      string `json:"code" binding:"required"` // TOTP or backup code
+}
+
+// requireSecondFactor answers a login with a 2FA challenge when the user has
+// it enabled; it returns false when the caller should issue the token itself
+func requireSecondFactor(c *gin.Context, user database.User) bool {
+	if !user.TOTPEnabled {
+		return false
+	}
+
+	challenge, err := signTwoFactorChallenge(user.ID)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to generate token"})
+		return true
+	}
+	c.JSON(http.StatusOK, gin.H{
+		"two_factor_required": true,
+		"challenge":           challenge,
+		"expires_in":          int(twoFactorChallengeTTL.Seconds()),
+	})
+	return true
+}
+
+func signTwoFactorChallenge(userID uint) (string, error) {
+	buf := make([]byte, 16)
+	if _, err := rand.Read(buf); err != nil {
+		return "", err
+	}
+	claims := &twoFactorChallenge{
+		PendingUserID: userID,
+		RegisteredClaims: jwt.RegisteredClaims{
+			ExpiresAt: jwt.NewNumericDate(time.Now().Add(twoFactorChallengeTTL)),
+			IssuedAt:  jwt.NewNumericDate(time.Now()),
+			ID:        hex.EncodeToString(buf),
+		},
+	}
+	return jwt.NewWithClaims(jwt.SigningMethodHS256, claims).SignedString([]byte(os.Getenv("JWT_SECRET")))
+}
+
+func parseTwoFactorChallenge(challenge string) (*twoFactorChallenge, error) {
+	claims := &twoFactorChallenge{}
+	token, err := jwt.ParseWithClaims(challenge, claims, func(token *jwt.Token) (interface{}, error) {
+		return []byte(os.Getenv("JWT_SECRET")), nil
+	}, jwt.WithValidMethods([]string{jwt.SigningMethodHS256.Alg()}))
+	if err != nil || !token.Valid || claims.PendingUserID == 0 || claims.ID == "" || claims.Subject != "" {
+		return nil, errors.New("invalid challenge")
+	}
+	return claims, nil
+}
+
+// recordFailedAttempt counts a wrong code and reports whether the challenge is used up
+func recordFailedAttempt(claims *twoFactorChallenge) bool {
+	return countFailedAttempt(claims.ID, claims.ExpiresAt.Time)
+}
+
+func challengeExhausted(id string) bool {
+	challengeAttempts.Lock()
+	defer challengeAttempts.Unlock()
+	return challengeAttempts.byID[id].count >= maxTwoFactorAttempts
+}
+
+// countFailedAttempt counts a wrong code against key until expiresAt and
+// reports whether the allowed attempts are used up
+func countFailedAttempt(key string, expiresAt time.Time) bool {
+	challengeAttempts.Lock()
+	defer challengeAttempts.Unlock()
+
+	now := time.Now()
+	for id, attempt := range challengeAttempts.byID {
+		if now.After(attempt.expiresAt) {
+			delete(challengeAttempts.byID, id)
+		}
+	}
+
+	attempt := challengeAttempts.byID[key]
+	attempt.count++
+	attempt.expiresAt = expiresAt
+	challengeAttempts.byID[key] = attempt
+	return attempt.count >= maxTwoFactorAttempts
+}
+
+// settingsAttemptKey is where wrong codes sent to change a user's 2FA setup are
+// counted. The count is per user, so every session shares it.
+func settingsAttemptKey(userID uint) string {
+	return "user:" + strconv.FormatUint(uint64(userID), 10)
+}
+
+// settingsLockedOut reports whether a user sent too many wrong codes to change
+// their 2FA setup and must wait
+func settingsLockedOut(userID uint) bool {
+	challengeAttempts.Lock()
+	defer challengeAttempts.Unlock()
+	attempt, ok := challengeAttempts.byID[settingsAttemptKey(userID)]
+	return ok && attempt.count >= maxTwoFactorAttempts && time.Now().Before(attempt.expiresAt)
+}
+
+// checkSettingsCode verifies the code sent to change an enabled 2FA setup with
+// the same attempt limit as sign in: after maxTwoFactorAttempts wrong codes the
+// user is locked out for twoFactorChallengeTTL. It answers the request on failure.
+func (h *Handlers) checkSettingsCode(c *gin.Context, user *database.User, code string, allowBackup bool) bool {
+	if settingsLockedOut(user.ID) {
+		c.JSON(http.StatusTooManyRequests, gin.H{"error": "Too many wrong codes, try again later"})
+		return false
+	}
+	if !h.verifySecondFactor(c.Request.Context(), user, code, allowBackup) {
+		if countFailedAttempt(settingsAttemptKey(user.ID), time.Now().Add(twoFactorChallengeTTL)) {
+			c.JSON(http.StatusTooManyRequests, gin.H{"error": "Too many wrong codes, try again later"})
+			return false
+		}
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid code"})
+		return false
+	}
+
+	challengeAttempts.Lock()
+	delete(challengeAttempts.byID, settingsAttemptKey(user.ID))
+	challengeAttempts.Unlock()
+	return true
+}
+
+// HandleTwoFactorChallenge completes a login that was answered with a 2FA challenge
+func (h *Handlers) HandleTwoFactorChallenge(c *gin.Context) {
+	var req TwoFactorChallengeRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		i18n.RespondError(c, http.StatusBadRequest, i18n.CodeInvalidRequest, err.Error())
+		return
+	}
+
+	claims, err := parseTwoFactorChallenge(req.Challenge)
+	if err != nil || challengeExhausted(claims.ID) {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "Invalid or expired challenge, sign in again"})
+		return
+	}
+
+	var user database.User
+	if err := h.DB.WithContext(c.Request.Context()).First(&user, claims.PendingUserID).Error; err != nil || !user.TOTPEnabled {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "Invalid or expired challenge, sign in again"})
+		return
+	}
+
+	if !h.verifySecondFactor(c.Request.Context(), &user, req.Code, true) {
+		if recordFailedAttempt(claims) {
+			c.JSON(http.StatusUnauthorized, gin.H{"error": "Too many wrong codes, sign in again"})
+			return
+		}
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "Invalid code"})
+		return
+	}
+
+	jwtToken, err := h.issueSessionToken(c, user.ID)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to generate token"})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"token": jwtToken})
+}
+
+// verifySecondFactor accepts a current TOTP code, or when allowBackup is set
+// an unused backup code, which is then spent
+func (h *Handlers) verifySecondFactor(ctx context.Context, user *database.User, code string, allowBackup bool) bool {
+	code = strings.TrimSpace(code)
+	if step, ok := auth.ValidateTOTP(user.TOTPSecret, code, time.Now(), user.TOTPLastStep); ok {
+		// Conditional update so two requests can't both spend the same code
+		result := h.DB.WithContext(ctx).Model(&database.User{}).
+			Where("id = ? AND totp_last_step < ?", user.ID, step).
+			Update("totp_last_step", step)
+		if result.Error != nil || result.RowsAffected == 0 {
+			return false
+		}
+		user.TOTPLastStep = step
+		return true
+	}
+
+	if !allowBackup {
+		return false
+	}
+	result := h.DB.WithContext(ctx).Model(&database.TOTPBackupCode{}).
+		Where("user_id = ? AND code_hash = ? AND used_at = 0", user.ID, hashAuthCode(normalizeBackupCode(code))).
+		Update("used_at", time.Now().Unix())
+	return result.Error == nil && result.RowsAffected > 0
+}
+
+func normalizeBackupCode(code string) string {
+	code = strings.ToLower(code)
+	return strings.NewReplacer("-", "", " ", "").Replace(code)
+}
+
+// replaceBackupCodes discards the user's backup codes and returns a fresh set
+func (h *Handlers) replaceBackupCodes(ctx context.Context, userID uint) ([]string, error) {
+	codes := make([]string, 0, backupCodeCount)
+	rows := make([]database.TOTPBackupCode, 0, backupCodeCount)
+	for range backupCodeCount {
+		buf := make([]byte, 5)
+		if _, err := rand.Read(buf); err != nil {
+			return nil, err
+		}
+		code := hex.EncodeToString(buf)
+		codes = append(codes, code[:5]+"-"+code[5:])
+		rows = append(rows, database.TOTPBackupCode{UserID: userID, CodeHash: hashAuthCode(code)})
+	}
+
+	if err := h.DB.WithContext(ctx).Where("user_id = ?", userID).Delete(&database.TOTPBackupCode{}).Error; err != nil {
+		return nil, err
+	}
+	if err := h.DB.WithContext(ctx).Create(&rows).Error; err != nil {
+		return nil, err
+	}
+	return codes, nil
+}
+
+// GetTwoFactorStatus reports whether 2FA is on and how many backup codes are left
+func (h *Handlers) GetTwoFactorStatus(c *gin.Context) {
+	user, exists := middleware.GetUserFromContext(c)
+	if !exists {
+		i18n.RespondError(c, http.StatusUnauthorized, i18n.CodeUnauthenticated, "")
+		return
+	}
+
+	var remaining int64
+	if user.TOTPEnabled {
+		h.DB.WithContext(c.Request.Context()).Model(&database.TOTPBackupCode{}).
+			Where("user_id = ? AND used_at = 0", user.ID).Count(&remaining)
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"enabled":                user.TOTPEnabled,
+		"backup_codes_remaining": remaining,
+	})
+}
+
+// EnrollTwoFactor creates a TOTP secret for the user's authenticator app. 2FA
+// is not enforced until a code from the app is confirmed with VerifyTwoFactor.
+func (h *Handlers) EnrollTwoFactor(c *gin.Context) {
+	user, exists := middleware.GetUserFromContext(c)
+	if !exists {
+		i18n.RespondError(c, http.StatusUnauthorized, i18n.CodeUnauthenticated, "")
+		return
+	}
+	if user.TOTPEnabled {
+		c.JSON(http.StatusConflict, gin.H{"error": "Two-factor authentication is already enabled"})
+		return
+	}
+
+	secret, err := auth.GenerateTOTPSecret()
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to generate secret"})
+		return
+	}
+	err = h.DB.WithContext(c.Request.Context()).Model(&database.User{}).Where("id = ?", user.ID).
+		Updates(map[string]interface{}{"totp_secret": secret, "totp_last_step": 0}).Error
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to save secret"})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"secret":      secret,
+		"otpauth_url": auth.TOTPURI(totpIssuer, user.Email, secret),
+	})
+}
+
+// VerifyTwoFactor confirms enrollment with a code from the app, turns 2FA on
+// and returns the backup codes, which are only ever shown once
+func (h *Handlers) VerifyTwoFactor(c *gin.Context) {
+	user, exists := middleware.GetUserFromContext(c)
+	if !exists {
+		i18n.RespondError(c, http.StatusUnauthorized, i18n.CodeUnauthenticated, "")
+		return
+	}
+
+	var req TwoFactorCodeRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		i18n.RespondError(c, http.StatusBadRequest, i18n.CodeInvalidRequest, err.Error())
+		return
+	}
+	if user.TOTPEnabled {
+		c.JSON(http.StatusConflict, gin.H{"error": "Two-factor authentication is already enabled"})
+		return
+	}
+	if user.TOTPSecret == "" {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Start enrollment first"})
+		return
+	}
+	if !h.verifySecondFactor(c.Request.Context(), user, req.Code, false) {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid code"})
+		return
+	}
+
+	codes, err := h.replaceBackupCodes(c.Request.Context(), user.ID)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to generate backup codes"})
+		return
+	}
+	if err := h.DB.WithContext(c.Request.Context()).Model(&database.User{}).Where("id = ?", user.ID).
+		Update("totp_enabled", true).Error; err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to enable two-factor authentication"})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"enabled": true, "backup_codes": codes})
+}
+
+// RegenerateBackupCodes replaces the backup codes, invalidating the old ones
+func (h *Handlers) RegenerateBackupCodes(c *gin.Context) {
+	user, req, ok := h.bindTwoFactorCode(c)
+	if !ok {
+		return
+	}
+	if !h.checkSettingsCode(c, user, req.Code, false) {
+		return
+	}
+
+	codes, err := h.replaceBackupCodes(c.Request.Context(), user.ID)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to generate backup codes"})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"backup_codes": codes})
+}
+
+// DisableTwoFactor turns 2FA off; it takes a TOTP or backup code so a stolen
+// session alone can't remove it
+func (h *Handlers) DisableTwoFactor(c *gin.Context) {
+	user, req, ok := h.bindTwoFactorCode(c)
+	if !ok {
+		return
+	}
+	if !h.checkSettingsCode(c, user, req.Code, true) {
+		return
+	}
+
+	err := h.DB.WithContext(c.Request.Context()).Model(&database.User{}).Where("id = ?", user.ID).
+		Updates(map[string]interface{}{"totp_enabled": false, "totp_secret": "", "totp_last_step": 0}).Error
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to disable two-factor authentication"})
+		return
+	}
+	h.DB.WithContext(c.Request.Context()).Where("user_id = ?", user.ID).Delete(&database.TOTPBackupCode{})
+
+	c.JSON(http.StatusOK, gin.H{"enabled": false})
+}
+
+// bindTwoFactorCode reads the code of a request that changes an enabled 2FA setup
+func (h *Handlers) bindTwoFactorCode(c *gin.Context) (*database.User, TwoFactorCodeRequest, bool) {
+	var req TwoFactorCodeRequest
+	user, exists := middleware.GetUserFromContext(c)
+	if !exists {
+		i18n.RespondError(c, http.StatusUnauthorized, i18n.CodeUnauthenticated, "")
+		return nil, req, false
+	}
+	if err := c.ShouldBindJSON(&req); err != nil {
+		i18n.RespondError(c, http.StatusBadRequest, i18n.CodeInvalidRequest, err.Error())
+		return nil, req, false
+	}
+	if !user.TOTPEnabled {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Two-factor authentication is not enabled"})
+		return nil, req, false
+	}
+	return user, req, true
+}
@@ -0,0 +1,83 @@
+package handlers
+
+import (
+	"net/http"
+	"strconv"
+	"time"
+
+	"server/internal/i18n"
+	"server/internal/ledger"
+	"server/internal/middleware"
+	"server/internal/quota"
+	"server/internal/ratelimit"
+
+	"github.com/gin-gonic/gin"
+)
+
+// GetUsage reports the provider calls made for the user today by endpoint, their
+// daily history (?days=, default 7), the remaining provider quota and how long
+// new requests currently wait for the rate limiter
+func (h *Handlers) GetUsage(c *gin.Context) {
+	user, exists := middleware.GetUserFromContext(c)
+	if !exists {
+		i18n.RespondError(c, http.StatusUnauthorized, i18n.CodeUnauthenticated, "")
+		return
+	}
+
+	days := 7
+	if n, err := strconv.Atoi(c.Query("days")); err == nil && n > 0 {
+		days = min(n, 90)
+	}
+
+	db := h.DB.WithContext(c.Request.Context())
+	day := ledger.Day(time.Now())
+	rollups, err := ledger.Rollups(db, user.ID, day)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to load API usage"})
+		return
+	}
+	history, err := ledger.History(db, user.ID, days)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to load API usage"})
+		return
+	}
+
+	providers := gin.H{}
+	for _, service := range []ratelimit.ServiceType{ratelimit.SpotifyService, ratelimit.YouTubeService} {
+		var calls, units int64
+		endpoints := gin.H{}
+		for _, rollup := range rollups {
+			if rollup.Service != string(service) {
+				continue
+			}
+			calls += rollup.Calls
+			units += rollup.QuotaUnits
+			endpoints[rollup.EndpointClass] = gin.H{"calls": rollup.Calls, "quota_units": rollup.QuotaUnits}
+		}
+		usage := gin.H{
+			"calls_today":         calls,
+			"quota_units_today":   units,
+			"endpoints":           endpoints,
+			"wait_seconds":        h.Providers.Limiter.WaitTime(service).Seconds(),
+			"requests_per_second": h.serviceRequestsPerSecond(string(service)),
+			"circuit_state":       h.Providers.Limiter.CircuitBreaker(service).State(),
+			"quota":               nil,
+		}
+		if limit := quota.DailyLimit(string(service)); limit > 0 {
+			usage["quota"] = gin.H{
+				"daily_limit": limit,
+				"used":        quota.Used(string(service)),
+				"remaining":   quota.Remaining(string(service)),
+				"resets_at":   quota.NextReset(time.Now()).Unix(),
+			}
+		}
+		providers[string(service)] = usage
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"day":       day,
+		"providers": providers,
+		"history":   history,
+		"storage":   storageUsage(db, user.ID),
+	})
+}
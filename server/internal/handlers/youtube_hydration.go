@@ -0,0 +1,56 @@
+package handlers
+
+import (
+	"context"
+	"log"
+	"strings"
+
+	"server/internal/config"
+	"server/internal/providers/youtube"
+)
+
+// youtubeHydrateTracks looks up the duration, category and channel of every
+// video read from a YouTube playlist, at one quota unit per 50 videos
+var youtubeHydrateTracks = config.Bool("YOUTUBE_HYDRATE_TRACKS", true)
+
+// fetchYouTubeVideoDetails looks up videos 50 at a time. Failures are logged
+// and leave the remaining videos out.
+func (h *Handlers) fetchYouTubeVideoDetails(ctx context.Context, accessToken string, videoIDs []string) map[string]youtube.Video {
+	videos := make(map[string]youtube.Video, len(videoIDs))
+
+	for start := 0; start < len(videoIDs); start += 50 {
+		end := min(start+50, len(videoIDs))
+
+		batch, err := h.Providers.YouTube.Videos(ctx, accessToken, videoIDs[start:end], "snippet,contentDetails")
+		if err != nil {
+			log.Printf("Failed to fetch YouTube video details: %v", err)
+			return videos
+		}
+		for _, video := range batch {
+			videos[video.ID] = video
+		}
+	}
+
+	return videos
+}
+
+// hydrateYouTubeTrack adds a video's details to the track parsed from its playlist item.
+// Titles without an artist take it from an auto-generated artist channel.
+func hydrateYouTubeTrack(track *Track, video youtube.Video) {
+	track.Duration = video.DurationMS()
+	track.VideoCategory = video.Snippet.CategoryID
+	track.ChannelID = video.Snippet.ChannelID
+	track.ChannelTitle = video.Snippet.ChannelTitle
+	if track.Artist == "" {
+		track.Artist = youtubeChannelArtist(video.Snippet.ChannelTitle)
+	}
+}
+
+// youtubeChannelArtist returns the artist of an auto-generated "<artist> - Topic"
+// channel, "" for any other channel
+func youtubeChannelArtist(channelTitle string) string {
+	if artist, ok := strings.CutSuffix(channelTitle, " - Topic"); ok {
+		return artist
+	}
+	return ""
+}
@@ -0,0 +1,51 @@
+package handlers
+
+import (
+	"context"
+	"log"
+	"slices"
+)
+
+// Reasons a YouTube video is passed over as a match
+const (
+	restrictionAgeRestricted = "age_restricted"
+	restrictionRegionBlocked = "region_blocked"
+)
+
+// youtubeRestrictions looks up the candidates' content details in one call and
+// returns why each unusable one is restricted. Age-restricted videos only play
+// for signed-in adults; region is checked only when set. Lookup failures count
+// as unrestricted.
+func (h *Handlers) youtubeRestrictions(ctx context.Context, accessToken string, videoIDs []string, region string) map[string]string {
+	restrictions := map[string]string{}
+	videos, err := h.Providers.YouTube.Videos(ctx, accessToken, videoIDs, "contentDetails")
+	if err != nil {
+		log.Printf("Failed to read restrictions of %d videos: %v", len(videoIDs), err)
+		return restrictions
+	}
+
+	for _, video := range videos {
+		details := video.ContentDetails
+		switch {
+		case region != "" && len(details.RegionRestriction.Allowed) > 0 && !slices.Contains(details.RegionRestriction.Allowed, region),
+			region != "" && slices.Contains(details.RegionRestriction.Blocked, region):
+			restrictions[video.ID] = restrictionRegionBlocked
+		case details.ContentRating.YTRating == "ytAgeRestricted":
+			restrictions[video.ID] = restrictionAgeRestricted
+		}
+	}
+	return restrictions
+}
+
+// pickUnrestrictedCandidate returns the first of the ranked candidates that is
+// not restricted, recording on it why the better ones were passed over. When
+// all are restricted the first is returned with its restriction.
+func pickUnrestrictedCandidate(ranked []youtubeCandidate, restrictions map[string]string) (youtubeCandidate, string) {
+	for _, candidate := range ranked {
+		if restrictions[candidate.Track.ID] == "" {
+			candidate.Track.Substitution = restrictions[ranked[0].Track.ID]
+			return candidate, ""
+		}
+	}
+	return ranked[0], restrictions[ranked[0].Track.ID]
+}
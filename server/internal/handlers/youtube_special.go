@@ -0,0 +1,55 @@
+package handlers
+
+import (
+	"strings"
+)
+
+// Liked videos has a fixed ID for the authenticated user. Watch later ("WL")
+// is not readable through the Data API, so it is not offered.
+const youtubeLikedVideosID = "LL"
+
+var youtubeSpecialPlaylists = map[string]string{
+	youtubeLikedVideosID: "Liked videos",
+}
+
+// spotifyLikedSongsID stands for the user's Spotify Liked Songs, which has no playlist ID
+const spotifyLikedSongsID = "liked"
+
+// isPersonalCollection reports whether a playlist ID names one of the account's
+// built-in collections, which only its owner can read and nobody can add to
+func isPersonalCollection(serviceType, playlistID string) bool {
+	if serviceType == "spotify" {
+		return playlistID == spotifyLikedSongsID
+	}
+	_, special := youtubeSpecialPlaylists[playlistID]
+	return special && serviceType == "youtube"
+}
+
+// youtubeSpecialPlaylistResponses lists the built-in playlist alongside the user's own.
+// Its size is not reported by the playlists API, so the track count is left at 0.
+func youtubeSpecialPlaylistResponses() []PlaylistResponse {
+	return []PlaylistResponse{
+		{ServiceID: youtubeLikedVideosID, Name: youtubeSpecialPlaylists[youtubeLikedVideosID]},
+	}
+}
+
+// isLikelyMusicVideo applies the music heuristics to a video from Liked videos
+func isLikelyMusicVideo(channelTitle, title string, musicCategory bool) bool {
+	if musicCategory {
+		return true
+	}
+
+	// Auto-generated artist channels and label channels only publish music
+	if strings.HasSuffix(channelTitle, " - Topic") || strings.Contains(strings.ToUpper(channelTitle), "VEVO") {
+		return true
+	}
+
+	lower := strings.ToLower(title)
+	for _, marker := range []string{"official video", "official audio", "official music video", "lyric video", "(audio)"} {
+		if strings.Contains(lower, marker) {
+			return true
+		}
+	}
+
+	return false
+}
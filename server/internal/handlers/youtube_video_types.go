@@ -0,0 +1,83 @@
+package handlers
+
+import (
+	"slices"
+	"strings"
+)
+
+// youtubeVideoTypes lists the accepted upload preferences for YouTube targets ("" follows youtubeEntityChain)
+var youtubeVideoTypes = map[string]bool{
+	"":               true,
+	"official_video": true,
+	"audio":          true,
+	"lyric_video":    true,
+}
+
+// youtubeVideoTypeQuery returns the search terms that steer results towards the preferred upload kind
+func youtubeVideoTypeQuery(videoType string) string {
+	switch videoType {
+	case "official_video":
+		return "official music video"
+	case "lyric_video":
+		return "lyrics"
+	default:
+		return "official audio"
+	}
+}
+
+// youtubeEntityChain is the order kinds of uploads are preferred in when the
+// user has no preference: YouTube Music "song" entities (the auto-generated art
+// tracks) first, then official audio, the official video, lyric videos and
+// finally any other video
+var youtubeEntityChain = []string{"song", "audio", "official_video", "lyric_video", "video"}
+
+// youtubeEntityType classifies a search result into one of youtubeEntityChain
+func youtubeEntityType(title, channelTitle, description string) string {
+	lower := strings.ToLower(title)
+	switch {
+	// Art tracks live on "Topic" channels and carry the distributor's credit line
+	case strings.HasSuffix(channelTitle, " - Topic") || strings.HasPrefix(description, "Provided to YouTube by "):
+		return "song"
+	case strings.Contains(lower, "official audio") || strings.Contains(lower, "(audio)"):
+		return "audio"
+	case strings.Contains(lower, "official video") || strings.Contains(lower, "official music video") ||
+		strings.Contains(strings.ToUpper(channelTitle), "VEVO"):
+		return "official_video"
+	case strings.Contains(lower, "lyric"):
+		return "lyric_video"
+	}
+	return "video"
+}
+
+// youtubeVideoTypeBonus boosts candidates of the preferred kind so they win over otherwise equal matches
+func youtubeVideoTypeBonus(videoType, entityType, title, channelTitle string) float64 {
+	lower := strings.ToLower(title)
+
+	switch videoType {
+	case "":
+		// Each step down the chain costs a little, so a clearly better text match still wins
+		rank := slices.Index(youtubeEntityChain, entityType)
+		return float64(len(youtubeEntityChain)-1-rank) * 0.05
+	case "official_video":
+		if strings.Contains(lower, "official video") || strings.Contains(lower, "official music video") {
+			return 0.3
+		}
+		if strings.Contains(strings.ToUpper(channelTitle), "VEVO") {
+			return 0.2
+		}
+	case "audio":
+		// "Topic" channels carry the auto-generated art tracks
+		if strings.HasSuffix(channelTitle, " - Topic") {
+			return 0.3
+		}
+		if strings.Contains(lower, "official audio") || strings.Contains(lower, "(audio)") {
+			return 0.2
+		}
+	case "lyric_video":
+		if strings.Contains(lower, "lyric") {
+			return 0.3
+		}
+	}
+
+	return 0
+}
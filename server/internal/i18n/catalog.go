@@ -0,0 +1,69 @@
+package i18n
+
+// catalog holds the user-facing messages per language. English must contain every code.
+var catalog = map[string]map[Code]string{
+	"en": {
+		CodeUnauthenticated:           "User not authenticated",
+		CodeAuthHeaderRequired:        "Authorization header required",
+		CodeAuthHeaderInvalid:         "Authorization header format must be Bearer {token}",
+		CodeInvalidToken:              "Invalid token",
+		CodeInvalidRequest:            "Invalid request",
+		CodeRequestTooLarge:           "Request body too large",
+		CodeUnsupportedProvider:       "Unsupported service provider",
+		CodeServiceNotConnected:       "Service not connected",
+		CodeSourceServiceNotConnected: "Source service not connected",
+		CodeTargetServiceNotConnected: "Target service not connected",
+		CodePlaylistNotFound:          "Playlist not found",
+		CodeInvalidPlaylistID:         "Invalid playlist ID",
+		CodeTransferNotFound:          "Transfer not found",
+		CodeInvalidTransferID:         "Invalid transfer ID",
+	},
+	"es": {
+		CodeUnauthenticated:           "Usuario no autenticado",
+		CodeAuthHeaderRequired:        "Se requiere el encabezado Authorization",
+		CodeAuthHeaderInvalid:         "El encabezado Authorization debe tener el formato Bearer {token}",
+		CodeInvalidToken:              "Token no válido",
+		CodeInvalidRequest:            "Solicitud no válida",
+		CodeRequestTooLarge:           "El cuerpo de la solicitud es demasiado grande",
+		CodeUnsupportedProvider:       "Proveedor de servicio no compatible",
+		CodeServiceNotConnected:       "Servicio no conectado",
+		CodeSourceServiceNotConnected: "Servicio de origen no conectado",
+		CodeTargetServiceNotConnected: "Servicio de destino no conectado",
+		CodePlaylistNotFound:          "Lista de reproducción no encontrada",
+		CodeInvalidPlaylistID:         "ID de lista de reproducción no válido",
+		CodeTransferNotFound:          "Transferencia no encontrada",
+		CodeInvalidTransferID:         "ID de transferencia no válido",
+	},
+	"de": {
+		CodeUnauthenticated:           "Benutzer nicht angemeldet",
+		CodeAuthHeaderRequired:        "Authorization-Header erforderlich",
+		CodeAuthHeaderInvalid:         "Authorization-Header muss das Format Bearer {token} haben",
+		CodeInvalidToken:              "Ungültiges Token",
+		CodeInvalidRequest:            "Ungültige Anfrage",
+		CodeRequestTooLarge:           "Anfrage ist zu groß",
+		CodeUnsupportedProvider:       "Nicht unterstützter Dienst",
+		CodeServiceNotConnected:       "Dienst nicht verbunden",
+		CodeSourceServiceNotConnected: "Quelldienst nicht verbunden",
+		CodeTargetServiceNotConnected: "Zieldienst nicht verbunden",
+		CodePlaylistNotFound:          "Playlist nicht gefunden",
+		CodeInvalidPlaylistID:         "Ungültige Playlist-ID",
+		CodeTransferNotFound:          "Übertragung nicht gefunden",
+		CodeInvalidTransferID:         "Ungültige Übertragungs-ID",
+	},
+	"fr": {
+		CodeUnauthenticated:           "Utilisateur non authentifié",
+		CodeAuthHeaderRequired:        "En-tête Authorization requis",
+		CodeAuthHeaderInvalid:         "L'en-tête Authorization doit être au format Bearer {token}",
+		CodeInvalidToken:              "Jeton invalide",
+		CodeInvalidRequest:            "Requête invalide",
+		CodeRequestTooLarge:           "Corps de la requête trop volumineux",
+		CodeUnsupportedProvider:       "Fournisseur de service non pris en charge",
+		CodeServiceNotConnected:       "Service non connecté",
+		CodeSourceServiceNotConnected: "Service source non connecté",
+		CodeTargetServiceNotConnected: "Service cible non connecté",
+		CodePlaylistNotFound:          "Playlist introuvable",
+		CodeInvalidPlaylistID:         "ID de playlist invalide",
+		CodeTransferNotFound:          "Transfert introuvable",
+		CodeInvalidTransferID:         "ID de transfert invalide",
+	},
+}
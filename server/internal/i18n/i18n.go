@@ -0,0 +1,91 @@
+package i18n
+
+import (
+	"sort"
+	"strconv"
+	"strings"
+
+	"github.com/gin-gonic/gin"
+)
+
+// Code identifies an error independently of the language it is reported in
+type Code string
+
+const (
+	CodeUnauthenticated           Code = "unauthenticated"
+	CodeAuthHeaderRequired        Code = "auth_header_required"
+	CodeAuthHeaderInvalid         Code = "auth_header_invalid"
+	CodeInvalidToken              Code = "invalid_token"
+	CodeInvalidRequest            Code = "invalid_request"
+	CodeRequestTooLarge           Code = "request_too_large"
+	CodeUnsupportedProvider       Code = "unsupported_provider"
+	CodeServiceNotConnected       Code = "service_not_connected"
+	CodeSourceServiceNotConnected Code = "source_service_not_connected"
+	CodeTargetServiceNotConnected Code = "target_service_not_connected"
+	CodePlaylistNotFound          Code = "playlist_not_found"
+	CodeInvalidPlaylistID         Code = "invalid_playlist_id"
+	CodeTransferNotFound          Code = "transfer_not_found"
+	CodeInvalidTransferID         Code = "invalid_transfer_id"
+)
+
+const DefaultLanguage = "en"
+
+// Message returns the message for code in lang, falling back to English and then to the code itself
+func Message(lang string, code Code) string {
+	if msg, ok := catalog[lang][code]; ok {
+		return msg
+	}
+	if msg, ok := catalog[DefaultLanguage][code]; ok {
+		return msg
+	}
+	return string(code)
+}
+
+// Negotiate picks the best supported language from an Accept-Language header
+func Negotiate(acceptLanguage string) string {
+	type candidate struct {
+		lang string
+		q    float64
+	}
+
+	var candidates []candidate
+	for _, part := range strings.Split(acceptLanguage, ",") {
+		fields := strings.Split(strings.TrimSpace(part), ";")
+		tag := strings.ToLower(strings.TrimSpace(fields[0]))
+		if tag == "" {
+			continue
+		}
+
+		q := 1.0
+		for _, param := range fields[1:] {
+			if v, ok := strings.CutPrefix(strings.TrimSpace(param), "q="); ok {
+				if parsed, err := strconv.ParseFloat(v, 64); err == nil {
+					q = parsed
+				}
+			}
+		}
+
+		// Region subtags share the base language's messages ("de-AT" -> "de")
+		base, _, _ := strings.Cut(tag, "-")
+		candidates = append(candidates, candidate{lang: base, q: q})
+	}
+
+	sort.SliceStable(candidates, func(i, j int) bool { return candidates[i].q > candidates[j].q })
+	for _, c := range candidates {
+		if _, ok := catalog[c.lang]; ok && c.q > 0 {
+			return c.lang
+		}
+	}
+	return DefaultLanguage
+}
+
+// RespondError writes a localized error with its code. A non-empty detail,
+// usually an underlying error, is appended to the message untranslated.
+func RespondError(c *gin.Context, status int, code Code, detail string) {
+	msg := Message(Negotiate(c.GetHeader("Accept-Language")), code)
+	if detail != "" {
+		msg += ": " + detail
+	}
+
+	c.JSON(status, gin.H{"error": msg, "code": code})
+}
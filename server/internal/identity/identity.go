@@ -0,0 +1,166 @@
+package identity
+
+import (
+	"context"
+	"errors"
+	"log"
+	"strings"
+
+	"server/internal/database"
+
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+// Mapping sources
+const (
+	SourceISRC        = "isrc"
+	SourceMusicBrainz = "musicbrainz"
+	SourceMatch       = "match"
+	SourceNew         = "new"
+)
+
+// TrackInfo describes a track on a single service
+type TrackInfo struct {
+	Service  string
+	ID       string
+	Title    string
+	Artist   string
+	Album    string
+	Duration int
+	ISRC     string
+}
+
+// Resolve returns the canonical track for a service track, creating it when
+// the track has not been seen before. Tracks sharing an ISRC (or, when
+// MusicBrainz lookups are enabled, a MusicBrainz recording) resolve to the
+// same canonical track regardless of service or user.
+func Resolve(ctx context.Context, db *gorm.DB, info TrackInfo) (database.CanonicalTrack, error) {
+	var canonical database.CanonicalTrack
+	if info.Service == "" || info.ID == "" {
+		return canonical, errors.New("service and track ID are required")
+	}
+
+	var mapping database.TrackIdentity
+	err := db.Where("service_type = ? AND service_track_id = ?", info.Service, info.ID).First(&mapping).Error
+	if err == nil {
+		if err := db.First(&canonical, mapping.CanonicalTrackID).Error; err != nil {
+			return canonical, err
+		}
+		// Fill in an ISRC learned later, e.g. when a Spotify track was first seen without one
+		if canonical.ISRC == "" && info.ISRC != "" {
+			db.Model(&canonical).Update("isrc", strings.ToUpper(info.ISRC))
+		}
+		return canonical, nil
+	}
+	if !errors.Is(err, gorm.ErrRecordNotFound) {
+		return canonical, err
+	}
+
+	source := SourceNew
+	isrc := strings.ToUpper(strings.TrimSpace(info.ISRC))
+	if isrc != "" {
+		if err := db.Where("isrc = ?", isrc).First(&canonical).Error; err == nil {
+			source = SourceISRC
+		}
+	}
+
+	if canonical.ID == 0 {
+		canonical = database.CanonicalTrack{
+			ISRC:     isrc,
+			Title:    info.Title,
+			Artist:   info.Artist,
+			Album:    info.Album,
+			Duration: info.Duration,
+		}
+
+		if isrc != "" && musicBrainzEnabled() {
+			if mbid, err := lookupRecordingByISRC(ctx, isrc); err != nil {
+				log.Printf("MusicBrainz lookup for ISRC %s failed: %v", isrc, err)
+			} else if mbid != "" {
+				var existing database.CanonicalTrack
+				if err := db.Where("music_brainz_id = ?", mbid).First(&existing).Error; err == nil {
+					canonical, source = existing, SourceMusicBrainz
+				} else {
+					canonical.MusicBrainzID = mbid
+				}
+			}
+		}
+
+		if canonical.ID == 0 {
+			if err := db.Create(&canonical).Error; err != nil {
+				return canonical, err
+			}
+		}
+	}
+
+	return canonical, addIdentity(db, info, canonical.ID, source, 1)
+}
+
+// Link records a confident cross-service match, e.g. from a transfer. Only a
+// match the two tracks' ISRCs agree on joins the target to the source's
+// canonical track, unless it already has one of its own; fuzzier matches are
+// remembered for userID alone so one user's mistake doesn't spread to others.
+func Link(ctx context.Context, db *gorm.DB, userID uint, source, target TrackInfo, confidence float64) error {
+	sourceISRC := strings.ToUpper(strings.TrimSpace(source.ISRC))
+	if sourceISRC == "" || sourceISRC != strings.ToUpper(strings.TrimSpace(target.ISRC)) {
+		return db.Clauses(clause.OnConflict{
+			Columns:   []clause.Column{{Name: "user_id"}, {Name: "source_service"}, {Name: "source_track_id"}, {Name: "target_service"}},
+			DoUpdates: clause.AssignmentColumns([]string{"target_track_id", "confidence", "updated_at"}),
+		}).Create(&database.UserTrackLink{
+			UserID:        userID,
+			SourceService: source.Service,
+			SourceTrackID: source.ID,
+			TargetService: target.Service,
+			TargetTrackID: target.ID,
+			Confidence:    confidence,
+		}).Error
+	}
+
+	canonical, err := Resolve(ctx, db, source)
+	if err != nil {
+		return err
+	}
+	return addIdentity(db, target, canonical.ID, SourceMatch, confidence)
+}
+
+// Counterpart returns the ID of the same recording on another service, if
+// known to everyone, with the confidence of the mapping
+func Counterpart(db *gorm.DB, service, trackID, targetService string) (string, float64, bool) {
+	var target database.TrackIdentity
+	err := db.Where("service_type = ? AND canonical_track_id = (?)", targetService,
+		db.Model(&database.TrackIdentity{}).Select("canonical_track_id").
+			Where("service_type = ? AND service_track_id = ?", service, trackID)).
+		First(&target).Error
+	if err != nil {
+		return "", 0, false
+	}
+	// Mappings from before confidences were kept were exact
+	if target.Confidence == 0 {
+		target.Confidence = 1
+	}
+	return target.ServiceTrackID, target.Confidence, true
+}
+
+// UserCounterpart returns the target service track a user's earlier match
+// linked a track to, with the confidence of that match
+func UserCounterpart(db *gorm.DB, userID uint, service, trackID, targetService string) (string, float64, bool) {
+	var link database.UserTrackLink
+	err := db.Where("user_id = ? AND source_service = ? AND source_track_id = ? AND target_service = ?", userID, service, trackID, targetService).
+		First(&link).Error
+	if err != nil {
+		return "", 0, false
+	}
+	return link.TargetTrackID, link.Confidence, true
+}
+
+// addIdentity stores a mapping, keeping the existing one if another request created it first
+func addIdentity(db *gorm.DB, info TrackInfo, canonicalID uint, source string, confidence float64) error {
+	return db.Clauses(clause.OnConflict{DoNothing: true}).Create(&database.TrackIdentity{
+		ServiceType:      info.Service,
+		ServiceTrackID:   info.ID,
+		CanonicalTrackID: canonicalID,
+		Source:           source,
+		Confidence:       confidence,
+	}).Error
+}
@@ -0,0 +1,66 @@
+package identity
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/url"
+	"time"
+
+	"server/internal/config"
+
+	"golang.org/x/time/rate"
+)
+
+// MusicBrainz allows one request per second per client
+var (
+	musicBrainzLimiter = rate.NewLimiter(rate.Limit(1), 1)
+	musicBrainzClient  = &http.Client{Timeout: 10 * time.Second}
+)
+
+func musicBrainzEnabled() bool {
+	return config.Bool("MUSICBRAINZ_ENABLED", false)
+}
+
+// lookupRecordingByISRC returns the MusicBrainz recording ID for an ISRC, or "" if unknown
+func lookupRecordingByISRC(ctx context.Context, isrc string) (string, error) {
+	if err := musicBrainzLimiter.Wait(ctx); err != nil {
+		return "", err
+	}
+
+	apiURL := fmt.Sprintf("https://musicbrainz.org/ws/2/isrc/%s?fmt=json", url.PathEscape(isrc))
+	req, err := http.NewRequestWithContext(ctx, "GET", apiURL, nil)
+	if err != nil {
+		return "", err
+	}
+	// MusicBrainz rejects requests without an identifying User-Agent
+	req.Header.Set("User-Agent", config.String("MUSICBRAINZ_USER_AGENT", "sync-playlist/1.0"))
+
+	resp, err := musicBrainzClient.Do(req)
+	if err != nil {
+		return "", err
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode == http.StatusNotFound {
+		return "", nil
+	}
+	if resp.StatusCode != http.StatusOK {
+		return "", fmt.Errorf("musicbrainz API returned status: %d", resp.StatusCode)
+	}
+
+	var result struct {
+		Recordings []struct {
+			ID string `json:"id"`
+		} `json:"recordings"`
+	}
+	if err := json.NewDecoder(resp.Body).Decode(&result); err != nil {
+		return "", err
+	}
+
+	if len(result.Recordings) == 0 {
+		return "", nil
+	}
+	return result.Recordings[0].ID, nil
+}
@@ -0,0 +1,128 @@
+package jobs
+
+import (
+	"errors"
+	"time"
+)
+
+// historySize bounds how many failed and cancelled jobs are kept for inspection
+const historySize = 100
+
+var ErrJobNotFound = errors.New("job not found")
+
+// JobInfo is a snapshot of a job for operators
+type JobInfo struct {
+	ID         string     `json:"id"`
+	Type       string     `json:"type"`
+	UserID     uint       `json:"user_id"`
+	Priority   int        `json:"priority"`
+	Status     string     `json:"status"` // "queued", "running", "failed" or "cancelled"
+	Error      string     `json:"error,omitempty"`
+	EnqueuedAt time.Time  `json:"enqueued_at"`
+	StartedAt  *time.Time `json:"started_at,omitempty"`
+	FinishedAt *time.Time `json:"finished_at,omitempty"`
+}
+
+// Jobs lists queued, running and recently failed or cancelled jobs
+func (q *Queue) Jobs() []JobInfo {
+	q.mu.Lock()
+	defer q.mu.Unlock()
+
+	infos := make([]JobInfo, 0, len(q.pending)+len(q.active)+len(q.history))
+	for _, job := range q.pending {
+		infos = append(infos, job.info("queued"))
+	}
+	for job := range q.active {
+		infos = append(infos, job.info("running"))
+	}
+	for i := len(q.history) - 1; i >= 0; i-- {
+		job := q.history[i]
+		status := "failed"
+		if job.cancelled {
+			status = "cancelled"
+		}
+		infos = append(infos, job.info(status))
+	}
+	return infos
+}
+
+// Cancel removes a queued job or cancels the context of a running one
+func (q *Queue) Cancel(id string) error {
+	q.mu.Lock()
+	defer q.mu.Unlock()
+
+	for i, job := range q.pending {
+		if job.ID == id {
+			q.pending = append(q.pending[:i], q.pending[i+1:]...)
+			job.cancelled = true
+			job.finishedAt = time.Now()
+			q.recordLocked(job)
+			return nil
+		}
+	}
+
+	for job, cancel := range q.active {
+		if job.ID == id {
+			// The worker records the job once Run returns
+			job.cancelled = true
+			cancel()
+			return nil
+		}
+	}
+
+	return ErrJobNotFound
+}
+
+// Retry re-enqueues the most recent failed or cancelled job with the given ID
+func (q *Queue) Retry(id string) error {
+	q.mu.Lock()
+	var job *Job
+	for i := len(q.history) - 1; i >= 0; i-- {
+		if q.history[i].ID == id {
+			job = q.history[i]
+			q.history = append(q.history[:i], q.history[i+1:]...)
+			break
+		}
+	}
+	q.mu.Unlock()
+
+	if job == nil {
+		return ErrJobNotFound
+	}
+
+	// A copy keeps everything the job was enqueued with, minus the last run's outcome
+	retry := *job
+	retry.startedAt, retry.finishedAt, retry.err, retry.cancelled = time.Time{}, time.Time{}, nil, false
+	q.Enqueue(&retry)
+	return nil
+}
+
+// recordLocked keeps a finished job in the bounded history
+func (q *Queue) recordLocked(job *Job) {
+	q.history = append(q.history, job)
+	if len(q.history) > historySize {
+		q.history = q.history[len(q.history)-historySize:]
+	}
+}
+
+func (job *Job) info(status string) JobInfo {
+	info := JobInfo{
+		ID:         job.ID,
+		Type:       job.Type,
+		UserID:     job.UserID,
+		Priority:   job.Priority,
+		Status:     status,
+		EnqueuedAt: job.EnqueuedAt,
+	}
+	if job.err != nil {
+		info.Error = job.err.Error()
+	}
+	// Copies, since the worker keeps updating the job after the snapshot
+	if startedAt := job.startedAt; !startedAt.IsZero() {
+		info.StartedAt = &startedAt
+	}
+	if finishedAt := job.finishedAt; !finishedAt.IsZero() {
+		info.FinishedAt = &finishedAt
+	}
+	return info
+}
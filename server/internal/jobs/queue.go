@@ -0,0 +1,281 @@
+package jobs
+
+import (
+	"context"
+	"fmt"
+	"log"
+	"math"
+	"sync"
+	"time"
+)
+
+// Job priorities; higher priorities are served first
+const (
+	PriorityLow    = -1 // background syncs and bulk work
+	PriorityNormal = 0
+	PriorityHigh   = 1
+)
+
+// Job is a unit of background work
+type Job struct {
+	ID         string
+	Type       string // "transfer", "sync", ...
+	UserID     uint
+	Priority   int
+	Run        func(ctx context.Context) error
+	Timeout    time.Duration // zero runs the job until it finishes or is cancelled
+	EnqueuedAt time.Time
+
+	startedAt  time.Time
+	finishedAt time.Time
+	err        error
+	cancelled  bool
+}
+
+type Config struct {
+	MinWorkers    int
+	MaxWorkers    int
+	JobsPerWorker int           // pending jobs a single worker is expected to absorb
+	ScaleInterval time.Duration // how often the worker count is re-evaluated
+
+	// Pressure reports provider rate-limit pressure from 0 (none) to 1
+	// (fully throttled). Adding workers under pressure only burns quota,
+	// so the desired worker count is scaled down accordingly.
+	Pressure func() float64
+
+	// Lock, when set, is taken on the job ID before a job runs. Jobs whose
+	// lock is held elsewhere (e.g. by another replica) are skipped.
+	Lock func(ctx context.Context, name string) (release func(), ok bool, err error)
+}
+
+// Queue is a priority job queue, FIFO within a priority, served by an autoscaling worker pool
+type Queue struct {
+	cfg     Config
+	pending []*Job
+	workers int
+	target  int
+	running int
+
+	// active maps running jobs to their cancel funcs; history keeps recently
+	// failed and cancelled jobs so operators can inspect and retry them
+	active  map[*Job]context.CancelFunc
+	history []*Job
+
+	processed   int64
+	failed      int64
+	skipped     int64
+	scaleEvents int64
+	lastScaled  time.Time
+
+	mu   sync.Mutex
+	cond *sync.Cond
+}
+
+func NewQueue(cfg Config) *Queue {
+	if cfg.MinWorkers < 1 {
+		cfg.MinWorkers = 1
+	}
+	if cfg.MaxWorkers < cfg.MinWorkers {
+		cfg.MaxWorkers = cfg.MinWorkers
+	}
+	if cfg.JobsPerWorker < 1 {
+		cfg.JobsPerWorker = 1
+	}
+	if cfg.ScaleInterval <= 0 {
+		cfg.ScaleInterval = 5 * time.Second
+	}
+
+	q := &Queue{cfg: cfg, active: make(map[*Job]context.CancelFunc)}
+	q.cond = sync.NewCond(&q.mu)
+	return q
+}
+
+// Start launches the minimum number of workers and the autoscaler
+func (q *Queue) Start(ctx context.Context) {
+	q.mu.Lock()
+	q.setTargetLocked(ctx, q.cfg.MinWorkers)
+	q.mu.Unlock()
+
+	go func() {
+		<-ctx.Done()
+		q.cond.Broadcast()
+	}()
+
+	ticker := time.NewTicker(q.cfg.ScaleInterval)
+	go func() {
+		defer ticker.Stop()
+		for {
+			select {
+			case <-ctx.Done():
+				return
+			case <-ticker.C:
+				q.autoscale(ctx)
+			}
+		}
+	}()
+}
+
+// Enqueue adds a job behind every pending job of the same or higher priority
+func (q *Queue) Enqueue(job *Job) {
+	job.EnqueuedAt = time.Now()
+
+	q.mu.Lock()
+	i := len(q.pending)
+	for i > 0 && q.pending[i-1].Priority < job.Priority {
+		i--
+	}
+	q.pending = append(q.pending, nil)
+	copy(q.pending[i+1:], q.pending[i:])
+	q.pending[i] = job
+	q.mu.Unlock()
+
+	q.cond.Signal()
+}
+
+// Stats returns queue depth and worker metrics
+func (q *Queue) Stats() map[string]interface{} {
+	q.mu.Lock()
+	defer q.mu.Unlock()
+
+	pressure := 0.0
+	if q.cfg.Pressure != nil {
+		pressure = q.cfg.Pressure()
+	}
+
+	return map[string]interface{}{
+		"pending":      len(q.pending),
+		"running":      q.running,
+		"workers":      q.workers,
+		"min_workers":  q.cfg.MinWorkers,
+		"max_workers":  q.cfg.MaxWorkers,
+		"processed":    q.processed,
+		"failed":       q.failed,
+		"skipped":      q.skipped,
+		"scale_events": q.scaleEvents,
+		"last_scaled":  q.lastScaled,
+		"pressure":     pressure,
+	}
+}
+
+// desiredWorkersLocked computes the worker count for the current backlog and rate-limit pressure
+func (q *Queue) desiredWorkersLocked() int {
+	backlog := len(q.pending) + q.running
+	desired := int(math.Ceil(float64(backlog) / float64(q.cfg.JobsPerWorker)))
+
+	if q.cfg.Pressure != nil {
+		pressure := math.Min(math.Max(q.cfg.Pressure(), 0), 1)
+		desired = int(math.Ceil(float64(desired) * (1 - pressure)))
+	}
+
+	return min(max(desired, q.cfg.MinWorkers), q.cfg.MaxWorkers)
+}
+
+func (q *Queue) autoscale(ctx context.Context) {
+	q.mu.Lock()
+	defer q.mu.Unlock()
+
+	desired := q.desiredWorkersLocked()
+	if desired == q.target {
+		return
+	}
+
+	log.Printf("Scaling job workers from %d to %d (pending: %d, running: %d)",
+		q.target, desired, len(q.pending), q.running)
+	q.setTargetLocked(ctx, desired)
+	q.scaleEvents++
+	q.lastScaled = time.Now()
+}
+
+// setTargetLocked spawns workers up to target; surplus workers exit once idle
+func (q *Queue) setTargetLocked(ctx context.Context, target int) {
+	q.target = target
+	for q.workers < q.target {
+		q.workers++
+		go q.worker(ctx)
+	}
+	q.cond.Broadcast()
+}
+
+func (q *Queue) worker(ctx context.Context) {
+	for {
+		q.mu.Lock()
+		for len(q.pending) == 0 && q.workers <= q.target && ctx.Err() == nil {
+			q.cond.Wait()
+		}
+		if q.workers > q.target || ctx.Err() != nil {
+			q.workers--
+			q.mu.Unlock()
+			return
+		}
+
+		job := q.pending[0]
+		q.pending = q.pending[1:]
+		q.running++
+		jobCtx, cancel := context.WithCancel(ctx)
+		if job.Timeout > 0 {
+			jobCtx, cancel = context.WithTimeout(ctx, job.Timeout)
+		}
+		q.active[job] = cancel
+		job.startedAt = time.Now()
+		q.mu.Unlock()
+
+		ran, err := q.runLocked(jobCtx, job)
+		cancel()
+
+		q.mu.Lock()
+		q.running--
+		delete(q.active, job)
+		job.finishedAt = time.Now()
+		job.err = err
+		switch {
+		case !ran:
+			q.skipped++
+		case job.cancelled:
+			q.processed++
+			q.recordLocked(job)
+		case err != nil:
+			q.processed++
+			q.failed++
+			q.recordLocked(job)
+		default:
+			q.processed++
+		}
+		q.mu.Unlock()
+	}
+}
+
+// runLocked runs a job under its lock, reporting false if another holder already has it
+func (q *Queue) runLocked(ctx context.Context, job *Job) (bool, error) {
+	if q.cfg.Lock == nil {
+		return true, q.run(ctx, job)
+	}
+
+	release, ok, err := q.cfg.Lock(ctx, "job:"+job.ID)
+	if err != nil {
+		// Failing open keeps a single instance working when the lock backend is unavailable
+		log.Printf("Failed to lock job %s, running unlocked: %v", job.ID, err)
+		return true, q.run(ctx, job)
+	}
+	if !ok {
+		log.Printf("Skipping job %s (%s), already running elsewhere", job.ID, job.Type)
+		return false, nil
+	}
+	defer release()
+
+	return true, q.run(ctx, job)
+}
+
+// run executes a job, converting panics into errors so a worker never dies
+func (q *Queue) run(ctx context.Context, job *Job) (err error) {
+	defer func() {
+		if r := recover(); r != nil {
+			err = fmt.Errorf("panic: %v", r)
+		}
+		if err != nil {
+			log.Printf("Job %s (%s) failed: %v", job.ID, job.Type, err)
+		}
+	}()
+
+	log.Printf("Running job %s (%s), waited %v", job.ID, job.Type, time.Since(job.EnqueuedAt))
+	return job.Run(ctx)
+}
@@ -0,0 +1,143 @@
+// Package ledger keeps a persistent record of every outbound provider API call
+// and daily per-user rollups of them. Calls are buffered in memory and written
+// in batches by Flush.
+package ledger
+
+import (
+	"log"
+	"sync"
+	"time"
+
+	"server/internal/database"
+
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+// flushSize is the number of buffered calls that triggers an early flush
+const flushSize = 500
+
+var (
+	mu      sync.Mutex
+	pending []database.APICall
+)
+
+// Day returns the UTC day rollups count t towards
+func Day(t time.Time) string {
+	return t.UTC().Format("2006-01-02")
+}
+
+// Record buffers a call for the next flush
+func Record(call database.APICall) {
+	if call.CreatedAt == 0 {
+		call.CreatedAt = time.Now().Unix()
+	}
+
+	mu.Lock()
+	pending = append(pending, call)
+	full := len(pending) >= flushSize
+	mu.Unlock()
+
+	if full {
+		go Flush()
+	}
+}
+
+// rollupKey identifies a rollup row
+type rollupKey struct {
+	day, service, endpointClass string
+	userID                      uint
+}
+
+// Flush writes the buffered calls and adds them to the daily rollups
+func Flush() {
+	mu.Lock()
+	calls := pending
+	pending = nil
+	mu.Unlock()
+
+	if len(calls) == 0 || database.DB == nil {
+		return
+	}
+
+	if err := database.DB.CreateInBatches(calls, 500).Error; err != nil {
+		log.Printf("Failed to write %d API ledger entries: %v", len(calls), err)
+	}
+
+	rollups := make(map[rollupKey]*database.APICallRollup)
+	for _, call := range calls {
+		key := rollupKey{Day(time.Unix(call.CreatedAt, 0)), call.Service, call.EndpointClass, call.UserID}
+		rollup, ok := rollups[key]
+		if !ok {
+			rollup = &database.APICallRollup{Day: key.day, Service: key.service, EndpointClass: key.endpointClass, UserID: key.userID}
+			rollups[key] = rollup
+		}
+		rollup.Calls++
+		rollup.QuotaUnits += int64(call.QuotaCost)
+	}
+	for _, rollup := range rollups {
+		err := database.DB.Clauses(clause.OnConflict{
+			Columns: []clause.Column{{Name: "day"}, {Name: "service"}, {Name: "endpoint_class"}, {Name: "user_id"}},
+			DoUpdates: clause.Assignments(map[string]interface{}{
+				"calls":       gorm.Expr("api_call_rollups.calls + ?", rollup.Calls),
+				"quota_units": gorm.Expr("api_call_rollups.quota_units + ?", rollup.QuotaUnits),
+			}),
+		}).Create(rollup).Error
+		if err != nil {
+			log.Printf("Failed to update API call rollup for %s %s: %v", rollup.Service, rollup.Day, err)
+		}
+	}
+}
+
+// Prune deletes ledger entries older than retention; rollups are kept
+func Prune(db *gorm.DB, retention time.Duration) (int64, error) {
+	result := db.Where("created_at < ?", time.Now().Add(-retention).Unix()).Delete(&database.APICall{})
+	return result.RowsAffected, result.Error
+}
+
+// Rollups returns a user's rollups for a day, including calls not flushed yet
+func Rollups(db *gorm.DB, userID uint, day string) ([]database.APICallRollup, error) {
+	var rollups []database.APICallRollup
+	if err := db.Where("user_id = ? AND day = ?", userID, day).Order("service, endpoint_class").Find(&rollups).Error; err != nil {
+		return nil, err
+	}
+
+	mu.Lock()
+	defer mu.Unlock()
+	for _, call := range pending {
+		if call.UserID != userID || Day(time.Unix(call.CreatedAt, 0)) != day {
+			continue
+		}
+		i := 0
+		for i < len(rollups) && (rollups[i].Service != call.Service || rollups[i].EndpointClass != call.EndpointClass) {
+			i++
+		}
+		if i == len(rollups) {
+			rollups = append(rollups, database.APICallRollup{Day: day, Service: call.Service, EndpointClass: call.EndpointClass, UserID: userID})
+		}
+		rollups[i].Calls++
+		rollups[i].QuotaUnits += int64(call.QuotaCost)
+	}
+	return rollups, nil
+}
+
+// DailyTotal is a day's calls and quota units for one service
+type DailyTotal struct {
+	Day        string `json:"day"`
+	Service    string `json:"service"`
+	Calls      int64  `json:"calls"`
+	QuotaUnits int64  `json:"quota_units"`
+}
+
+// History returns a user's daily totals per service from the rollups of the last days days
+func History(db *gorm.DB, userID uint, days int) ([]DailyTotal, error) {
+	var totals []DailyTotal
+	since := Day(time.Now().AddDate(0, 0, 1-days))
+	err := db.Model(&database.APICallRollup{}).
+		Select("day, service, SUM(calls) AS calls, SUM(quota_units) AS quota_units").
+		Where("user_id = ? AND day >= ?", userID, since).
+		Group("day, service").
+		Order("day, service").
+		Scan(&totals).Error
+	return totals, err
+}
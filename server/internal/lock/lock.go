@@ -0,0 +1,186 @@
+package lock
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"hash/fnv"
+	"log"
+	"sync"
+	"time"
+
+	"server/internal/config"
+
+	"gorm.io/gorm"
+)
+
+// Locker coordinates work across server instances. Locks are named; the
+// returned release func must be called exactly once.
+type Locker interface {
+	// TryLock acquires the lock if it is free and reports whether it did
+	TryLock(ctx context.Context, name string) (release func(), ok bool, err error)
+	// Lock waits until the lock is acquired or ctx is done
+	Lock(ctx context.Context, name string) (release func(), err error)
+}
+
+// New returns the locker LOCK_BACKEND selects: "postgres" (default) for
+// replicas sharing db, or "memory" for a single instance
+func New(db *gorm.DB) Locker {
+	switch backend := config.String("LOCK_BACKEND", "postgres"); backend {
+	case "memory":
+		return NewMemoryLocker()
+	case "postgres":
+		return NewPostgresLocker(db)
+	default:
+		log.Printf("Unknown LOCK_BACKEND %q, using postgres", backend)
+		return NewPostgresLocker(db)
+	}
+}
+
+// PostgresLocker uses session-level advisory locks, so a lock is released
+// automatically if the instance holding it dies and its connection drops
+type PostgresLocker struct {
+	db *gorm.DB
+}
+
+func NewPostgresLocker(db *gorm.DB) *PostgresLocker {
+	return &PostgresLocker{db: db}
+}
+
+// lockPollInterval is how often Lock retries while another session holds the lock
+const lockPollInterval = 200 * time.Millisecond
+
+func (l *PostgresLocker) TryLock(ctx context.Context, name string) (func(), bool, error) {
+	if l.db == nil {
+		return nil, false, errors.New("database not initialized")
+	}
+	sqlDB, err := l.db.DB()
+	if err != nil {
+		return nil, false, err
+	}
+
+	// Advisory locks belong to a session, so hold a dedicated connection until release
+	conn, err := sqlDB.Conn(ctx)
+	if err != nil {
+		return nil, false, err
+	}
+
+	key := advisoryKey(name)
+	var acquired bool
+	if err := conn.QueryRowContext(ctx, "SELECT pg_try_advisory_lock($1)", key).Scan(&acquired); err != nil {
+		conn.Close()
+		return nil, false, err
+	}
+	if !acquired {
+		conn.Close()
+		return nil, false, nil
+	}
+
+	return advisoryRelease(conn, name, key), true, nil
+}
+
+func (l *PostgresLocker) Lock(ctx context.Context, name string) (func(), error) {
+	// Polling with try-lock keeps ctx cancellation simple and never parks a
+	// pooled connection inside a blocking pg_advisory_lock call
+	for {
+		release, ok, err := l.TryLock(ctx, name)
+		if err != nil || ok {
+			return release, err
+		}
+
+		select {
+		case <-ctx.Done():
+			return nil, ctx.Err()
+		case <-time.After(lockPollInterval):
+		}
+	}
+}
+
+func advisoryRelease(conn *sql.Conn, name string, key int64) func() {
+	var once sync.Once
+	return func() {
+		once.Do(func() {
+			defer conn.Close()
+			if _, err := conn.ExecContext(context.Background(), "SELECT pg_advisory_unlock($1)", key); err != nil {
+				log.Printf("Failed to release lock %s: %v", name, err)
+			}
+		})
+	}
+}
+
+// advisoryKey maps a lock name onto Postgres' 64-bit advisory lock key space
+func advisoryKey(name string) int64 {
+	h := fnv.New64a()
+	h.Write([]byte("sync-playlist:" + name))
+	return int64(h.Sum64())
+}
+
+// MemoryLocker only coordinates goroutines within one process
+type MemoryLocker struct {
+	held map[string]chan struct{}
+	mu   sync.Mutex
+}
+
+func NewMemoryLocker() *MemoryLocker {
+	return &MemoryLocker{held: make(map[string]chan struct{})}
+}
+
+func (l *MemoryLocker) TryLock(ctx context.Context, name string) (func(), bool, error) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	if _, held := l.held[name]; held {
+		return nil, false, nil
+	}
+
+	done := make(chan struct{})
+	l.held[name] = done
+
+	var once sync.Once
+	return func() {
+		once.Do(func() {
+			l.mu.Lock()
+			delete(l.held, name)
+			l.mu.Unlock()
+			close(done)
+		})
+	}, true, nil
+}
+
+func (l *MemoryLocker) Lock(ctx context.Context, name string) (func(), error) {
+	for {
+		release, ok, _ := l.TryLock(ctx, name)
+		if ok {
+			return release, nil
+		}
+
+		l.mu.Lock()
+		done, held := l.held[name]
+		l.mu.Unlock()
+		if !held {
+			continue
+		}
+
+		select {
+		case <-ctx.Done():
+			return nil, ctx.Err()
+		case <-done:
+		}
+	}
+}
+
+// WithLock runs fn only if name can be locked right away, reporting whether it ran
+func WithLock(ctx context.Context, l Locker, name string, fn func()) (bool, error) {
+	release, ok, err := l.TryLock(ctx, name)
+	if err != nil {
+		return false, fmt.Errorf("lock %s: %w", name, err)
+	}
+	if !ok {
+		return false, nil
+	}
+	defer release()
+
+	fn()
+	return true, nil
+}
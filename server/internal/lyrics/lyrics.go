@@ -0,0 +1,112 @@
+package lyrics
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/url"
+	"strconv"
+	"strings"
+	"time"
+	"unicode"
+
+	"server/internal/cache"
+	"server/internal/config"
+
+	"golang.org/x/time/rate"
+)
+
+// Lyrics come from LRCLIB, a free API without keys; be a polite client
+var (
+	limiter    = rate.NewLimiter(rate.Limit(2), 2)
+	httpClient = &http.Client{Timeout: 10 * time.Second}
+	lyricCache = cache.NewMemoryCache(5000)
+)
+
+const cacheTTL = 24 * time.Hour
+
+// Enabled reports whether lyrics checks are turned on with LYRICS_CHECK_ENABLED
+func Enabled() bool {
+	return config.Bool("LYRICS_CHECK_ENABLED", false)
+}
+
+// Fetch returns the plain lyrics of a song, or "" when the provider has none.
+// durationSec narrows the lookup when known and may be 0.
+func Fetch(ctx context.Context, artist, title string, durationSec int) (string, error) {
+	key := strings.ToLower(artist + "\x00" + title)
+	if cached, ok := lyricCache.Get(key); ok {
+		return string(cached), nil
+	}
+
+	if err := limiter.Wait(ctx); err != nil {
+		return "", err
+	}
+
+	params := url.Values{"artist_name": {artist}, "track_name": {title}}
+	if durationSec > 0 {
+		params.Set("duration", strconv.Itoa(durationSec))
+	}
+	apiURL := config.String("LYRICS_API_URL", "https://lrclib.net/api") + "/get?" + params.Encode()
+
+	req, err := http.NewRequestWithContext(ctx, "GET", apiURL, nil)
+	if err != nil {
+		return "", err
+	}
+	req.Header.Set("User-Agent", "sync-playlist/1.0")
+
+	resp, err := httpClient.Do(req)
+	if err != nil {
+		return "", err
+	}
+	defer resp.Body.Close()
+
+	var text string
+	switch resp.StatusCode {
+	case http.StatusOK:
+		var result struct {
+			PlainLyrics  string `json:"plainLyrics"`
+			Instrumental bool   `json:"instrumental"`
+		}
+		if err := json.NewDecoder(resp.Body).Decode(&result); err != nil {
+			return "", err
+		}
+		text = result.PlainLyrics
+	case http.StatusNotFound:
+		// Cache misses too, so unknown songs are not looked up again
+	default:
+		return "", fmt.Errorf("lyrics API returned status: %d", resp.StatusCode)
+	}
+
+	lyricCache.Set(key, []byte(text), cacheTTL)
+	return text, nil
+}
+
+// Similarity compares two lyrics as sets of word pairs, from 0 (unrelated) to 1 (identical)
+func Similarity(a, b string) float64 {
+	setA, setB := shingles(a), shingles(b)
+	if len(setA) == 0 || len(setB) == 0 {
+		return 0
+	}
+
+	shared := 0
+	for s := range setA {
+		if setB[s] {
+			shared++
+		}
+	}
+	return float64(shared) / float64(len(setA)+len(setB)-shared)
+}
+
+// shingles returns the set of adjacent word pairs, ignoring case and punctuation
+func shingles(text string) map[string]bool {
+	words := strings.FieldsFunc(strings.ToLower(text), func(r rune) bool {
+		return !unicode.IsLetter(r) && !unicode.IsNumber(r) && r != '\''
+	})
+
+	set := make(map[string]bool)
+	for i := 0; i+1 < len(words); i++ {
+		set[words[i]+" "+words[i+1]] = true
+	}
+	return set
+}
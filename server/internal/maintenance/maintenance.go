@@ -0,0 +1,87 @@
+// Package maintenance tracks which providers, or the whole service, are in
+// maintenance mode. The state lives in the database so every instance sees it.
+package maintenance
+
+import (
+	"log"
+	"sync"
+	"time"
+
+	"server/internal/database"
+)
+
+// Maintenance rows are read from the database at most this often
+const refreshInterval = 30 * time.Second
+
+const (
+	ScopeAll   = "all"
+	ModeQueue  = "queue"  // new transfers are held until maintenance ends
+	ModeReject = "reject" // new transfers are refused
+)
+
+var cache struct {
+	windows   map[string]database.Maintenance
+	expiresAt time.Time
+	mu        sync.Mutex
+}
+
+// All returns the maintenance windows in effect, keyed by scope
+func All() map[string]database.Maintenance {
+	cache.mu.Lock()
+	defer cache.mu.Unlock()
+
+	if cache.windows == nil || time.Now().After(cache.expiresAt) {
+		cache.windows = load()
+		cache.expiresAt = time.Now().Add(refreshInterval)
+	}
+
+	windows := make(map[string]database.Maintenance, len(cache.windows))
+	for scope, window := range cache.windows {
+		windows[scope] = window
+	}
+	return windows
+}
+
+// For returns the maintenance window affecting work that uses services: the
+// service-wide one if set, else the first provider one found. A window lasts
+// until it is deleted; its EndsAt is only shown to users and never ends it.
+func For(services ...string) (database.Maintenance, bool) {
+	windows := All()
+	if window, ok := windows[ScopeAll]; ok {
+		return window, true
+	}
+	for _, service := range services {
+		if window, ok := windows[service]; ok {
+			return window, true
+		}
+	}
+	return database.Maintenance{}, false
+}
+
+// Invalidate drops the cached state so the next check reads the database
+func Invalidate() {
+	cache.mu.Lock()
+	cache.windows = nil
+	cache.mu.Unlock()
+}
+
+func load() map[string]database.Maintenance {
+	windows := make(map[string]database.Maintenance)
+	if database.DB == nil {
+		return windows
+	}
+
+	var rows []database.Maintenance
+	if err := database.DB.Find(&rows).Error; err != nil {
+		// Keep the last known state rather than ending maintenance on a read error
+		log.Printf("Failed to load maintenance state: %v", err)
+		if cache.windows != nil {
+			return cache.windows
+		}
+		return windows
+	}
+	for _, row := range rows {
+		windows[row.Scope] = row
+	}
+	return windows
+}
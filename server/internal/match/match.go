@@ -0,0 +1,116 @@
+package match
+
+import (
+	"regexp"
+	"strings"
+)
+
+// youtubeTitleSuffixes are decorations YouTube uploads add to a track title
+var youtubeTitleSuffixes = []string{
+	"(Official Video)", "(Official Audio)", "[Official Video]", "[Official Audio]",
+	"(Official Music Video)", "[Official Music Video]", "(Lyric Video)", "[Lyric Video]",
+	"(Visualizer)", "[Visualizer]", "(Lyrics)", "[Lyrics]", "(Live)", "[Live]",
+	"(Acoustic)", "[Acoustic]", "(Remix)", "[Remix]", "(Cover)", "[Cover]",
+	"| Official Video", "| Official Audio", "| Official Music Video",
+}
+
+// youtubeTitlePatterns split "Artist - Track" style titles, tried in order
+var youtubeTitlePatterns = []*regexp.Regexp{
+	regexp.MustCompile(`^(.*?)\s*[-–—]\s*(.*)$`), // "Artist - Track"
+	regexp.MustCompile(`^(.*?)\s*:\s*(.*)$`),     // "Artist: Track"
+	regexp.MustCompile(`^(.*?)\s*\|\s*(.*)$`),    // "Artist | Track"
+	regexp.MustCompile(`^(.*?)\s*-\s*(.*)$`),     // "Artist - Track" (regular dash)
+}
+
+// versionSuffixes mark alternate versions of a track in a lowercased title
+var versionSuffixes = []string{" - remaster", " (remaster", " - live", " (live", " - acoustic", " (acoustic"}
+
+// ParseYouTubeTitle splits a YouTube video title into artist and track name.
+// The artist is empty when the title does not follow a known pattern.
+func ParseYouTubeTitle(title string) (string, string) {
+	title = strings.TrimSpace(title)
+	for _, suffix := range youtubeTitleSuffixes {
+		title = strings.ReplaceAll(title, suffix, "")
+	}
+	title = strings.TrimSpace(title)
+
+	for _, re := range youtubeTitlePatterns {
+		matches := re.FindStringSubmatch(title)
+		if len(matches) == 3 {
+			artist := strings.TrimSpace(matches[1])
+			track := strings.TrimSpace(matches[2])
+			if artist != "" && track != "" {
+				return artist, track
+			}
+		}
+	}
+
+	// If no pattern matches, the whole title is the track name
+	return "", title
+}
+
+// StripVersionSuffix cuts remaster/live/acoustic markers off a lowercased track name
+func StripVersionSuffix(name string) string {
+	result := name
+	for _, suffix := range versionSuffixes {
+		if idx := strings.Index(result, suffix); idx != -1 {
+			result = result[:idx]
+		}
+	}
+	return strings.TrimSpace(result)
+}
+
+// Confidence scores how well a candidate track matches a source track, from 0.0 to 1.0
+func Confidence(sourceName, sourceArtist, targetName, targetArtist string) float64 {
+	confidence := 0.0
+
+	sourceNameNorm := strings.ToLower(strings.TrimSpace(sourceName))
+	targetNameNorm := strings.ToLower(strings.TrimSpace(targetName))
+	sourceArtistNorm := strings.ToLower(strings.TrimSpace(sourceArtist))
+	targetArtistNorm := strings.ToLower(strings.TrimSpace(targetArtist))
+
+	// Name matching
+	if sourceNameNorm == targetNameNorm {
+		confidence += 0.6
+	} else if strings.Contains(sourceNameNorm, targetNameNorm) || strings.Contains(targetNameNorm, sourceNameNorm) {
+		confidence += 0.4
+	} else if StripVersionSuffix(sourceNameNorm) == StripVersionSuffix(targetNameNorm) {
+		confidence += 0.5
+	}
+
+	// Artist matching
+	if sourceArtistNorm == targetArtistNorm {
+		confidence += 0.4
+	} else if strings.Contains(sourceArtistNorm, targetArtistNorm) || strings.Contains(targetArtistNorm, sourceArtistNorm) {
+		confidence += 0.2
+	}
+
+	return confidence
+}
+
+// YouTubeConfidence scores a YouTube video as a match for a track from its title and description
+func YouTubeConfidence(name, artist, title, description string) float64 {
+	confidence := 0.0
+	titleLower := strings.ToLower(title)
+	descLower := strings.ToLower(description)
+
+	// Track name and artist in the title
+	if strings.Contains(titleLower, strings.ToLower(name)) {
+		confidence += 0.4
+	}
+	if strings.Contains(titleLower, strings.ToLower(artist)) {
+		confidence += 0.3
+	}
+
+	// "official" indicates an official music video/audio
+	if strings.Contains(titleLower, "official") {
+		confidence += 0.2
+	}
+
+	// Music-related terms
+	if strings.Contains(titleLower, "audio") || strings.Contains(descLower, "music") {
+		confidence += 0.1
+	}
+
+	return confidence
+}
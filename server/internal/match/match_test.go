@@ -0,0 +1,96 @@
+package match
+
+import "testing"
+
+func TestParseYouTubeTitle(t *testing.T) {
+	tests := []struct {
+		title, artist, track string
+	}{
+		{"Mira Vale - Northern Lights (Official Video)", "Mira Vale", "Northern Lights"},
+		{"Coastal Drift: Low Tide [Official Audio]", "Coastal Drift", "Low Tide"},
+		{"Coastal Drift | Low Tide", "Coastal Drift", "Low Tide"},
+		{"Northern Lights (Lyrics)", "", "Northern Lights"},
+		{"  Northern Lights  ", "", "Northern Lights"},
+	}
+	for _, tt := range tests {
+		artist, track := ParseYouTubeTitle(tt.title)
+		if artist != tt.artist || track != tt.track {
+			t.Errorf("ParseYouTubeTitle(%q) = %q, %q; want %q, %q", tt.title, artist, track, tt.artist, tt.track)
+		}
+	}
+}
+
+func TestStripVersionSuffix(t *testing.T) {
+	tests := []struct {
+		name, want string
+	}{
+		{"northern lights - remastered 2011", "northern lights"},
+		{"northern lights (live at the roundhouse)", "northern lights"},
+		{"northern lights (acoustic)", "northern lights"},
+		{"northern lights", "northern lights"},
+	}
+	for _, tt := range tests {
+		if got := StripVersionSuffix(tt.name); got != tt.want {
+			t.Errorf("StripVersionSuffix(%q) = %q; want %q", tt.name, got, tt.want)
+		}
+	}
+}
+
+func TestConfidence(t *testing.T) {
+	tests := []struct {
+		sourceName, sourceArtist, targetName, targetArtist string
+		want                                               float64
+	}{
+		{"Northern Lights", "Mira Vale", "northern lights", "mira vale", 1.0},
+		{"Northern Lights", "Mira Vale", "Northern Lights - Remastered", "Mira Vale", 0.8},
+		{"Northern Lights - Live", "Mira Vale", "Northern Lights (Live)", "Mira Vale", 0.9},
+		{"Northern Lights", "Mira Vale", "Northern Lights", "Mira Vale & Coastal Drift", 0.8},
+		{"Northern Lights", "Mira Vale", "Low Tide", "Coastal Drift", 0.0},
+	}
+	for _, tt := range tests {
+		got := Confidence(tt.sourceName, tt.sourceArtist, tt.targetName, tt.targetArtist)
+		if diff := got - tt.want; diff > 1e-9 || diff < -1e-9 {
+			t.Errorf("Confidence(%q, %q, %q, %q) = %v; want %v", tt.sourceName, tt.sourceArtist, tt.targetName, tt.targetArtist, got, tt.want)
+		}
+	}
+}
+
+func TestYouTubeConfidence(t *testing.T) {
+	got := YouTubeConfidence("Northern Lights", "Mira Vale", "Mira Vale - Northern Lights (Official Audio)", "")
+	if diff := got - 1.0; diff > 1e-9 || diff < -1e-9 {
+		t.Errorf("YouTubeConfidence of an official upload = %v; want 1.0", got)
+	}
+	if got := YouTubeConfidence("Northern Lights", "Mira Vale", "Low Tide", "a podcast"); got != 0 {
+		t.Errorf("YouTubeConfidence of an unrelated video = %v; want 0", got)
+	}
+}
+
+func BenchmarkParseYouTubeTitle(b *testing.B) {
+	for i := 0; i < b.N; i++ {
+		ParseYouTubeTitle("Mira Vale - Northern Lights (Official Music Video)")
+	}
+}
+
+func BenchmarkParseYouTubeTitleNoArtist(b *testing.B) {
+	for i := 0; i < b.N; i++ {
+		ParseYouTubeTitle("Northern Lights [Lyric Video]")
+	}
+}
+
+func BenchmarkStripVersionSuffix(b *testing.B) {
+	for i := 0; i < b.N; i++ {
+		StripVersionSuffix("northern lights - remastered 2011")
+	}
+}
+
+func BenchmarkConfidence(b *testing.B) {
+	for i := 0; i < b.N; i++ {
+		Confidence("Northern Lights - Live", "Mira Vale", "Northern Lights (Live)", "Mira Vale & Coastal Drift")
+	}
+}
+
+func BenchmarkYouTubeConfidence(b *testing.B) {
+	for i := 0; i < b.N; i++ {
+		YouTubeConfidence("Northern Lights", "Mira Vale", "Mira Vale - Northern Lights (Official Audio)", "Provided to YouTube by a music label")
+	}
+}
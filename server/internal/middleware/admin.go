@@ -0,0 +1,33 @@
+package middleware
+
+import (
+	"net/http"
+	"strings"
+
+	"server/internal/config"
+
+	"github.com/gin-gonic/gin"
+)
+
+// RequireAdmin restricts a route to users whose email is listed in ADMIN_EMAILS.
+// It must run after AuthMiddleware.
+func RequireAdmin() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		user, exists := GetUserFromContext(c)
+		if !exists || !isAdminEmail(user.Email) {
+			c.JSON(http.StatusForbidden, gin.H{"error": "Admin access required"})
+			c.Abort()
+			return
+		}
+		c.Next()
+	}
+}
+
+func isAdminEmail(email string) bool {
+	for _, admin := range config.List("ADMIN_EMAILS", nil) {
+		if email != "" && strings.EqualFold(admin, email) {
+			return true
+		}
+	}
+	return false
+}
@@ -1,11 +1,15 @@
 package middleware
 
 import (
+	"errors"
 	"net/http"
 	"os"
 	"strings"
+	"time"
 
 	"server/internal/database"
+	"server/internal/i18n"
+	"server/internal/ratelimit"
 
 	"github.com/gin-gonic/gin"
 	"github.com/golang-jwt/jwt/v5"
@@ -16,29 +20,19 @@ func AuthMiddleware() gin.HandlerFunc {
 	return func(c *gin.Context) {
 		authHeader := c.GetHeader("Authorization")
 		if authHeader == "" {
-			c.JSON(http.StatusUnauthorized, gin.H{"error": "Authorization header required"})
+			i18n.RespondError(c, http.StatusUnauthorized, i18n.CodeAuthHeaderRequired, "")
 			c.Abort()
 			return
 		}
 
-		// Extract token from "Bearer <token>"
-		parts := strings.Split(authHeader, " ")
-		if len(parts) != 2 || parts[0] != "Bearer" {
-			c.JSON(http.StatusUnauthorized, gin.H{"error": "Authorization header format must be Bearer {token}"})
+		claims, err := ParseToken(authHeader)
+		if errors.Is(err, errMalformedHeader) {
+			i18n.RespondError(c, http.StatusUnauthorized, i18n.CodeAuthHeaderInvalid, "")
 			c.Abort()
 			return
 		}
-
-		tokenString := parts[1]
-
-		// Parse and validate token
-		claims := &jwt.RegisteredClaims{}
-		token, err := jwt.ParseWithClaims(tokenString, claims, func(token *jwt.Token) (interface{}, error) {
-			return []byte(os.Getenv("JWT_SECRET")), nil
-		})
-
-		if err != nil || !token.Valid {
-			c.JSON(http.StatusUnauthorized, gin.H{"error": "Invalid token"})
+		if err != nil {
+			i18n.RespondError(c, http.StatusUnauthorized, i18n.CodeInvalidToken, "")
 			c.Abort()
 			return
 		}
@@ -60,12 +54,68 @@ func AuthMiddleware() gin.HandlerFunc {
 			return
 		}
 
-		// Add user to context
+		// Tokens carry the session they were issued for; revoked sessions are refused
+		if claims.ID != "" {
+			if !touchSession(claims.ID, user.ID) {
+				c.JSON(http.StatusUnauthorized, gin.H{"error": "Session revoked"})
+				c.Abort()
+				return
+			}
+			c.Set("session_id", claims.ID)
+		}
+
+		// Add user to context; provider calls made with the request context are attributed to them
 		c.Set("user", user)
+		c.Request = c.Request.WithContext(ratelimit.WithUser(c.Request.Context(), user.ID))
 		c.Next()
 	}
 }
 
+var errMalformedHeader = errors.New("malformed authorization header")
+
+// ParseToken validates a "Bearer <token>" header and returns the token's claims
+func ParseToken(authHeader string) (*jwt.RegisteredClaims, error) {
+	parts := strings.Split(authHeader, " ")
+	if len(parts) != 2 || parts[0] != "Bearer" {
+		return nil, errMalformedHeader
+	}
+
+	claims := &jwt.RegisteredClaims{}
+	token, err := jwt.ParseWithClaims(parts[1], claims, func(token *jwt.Token) (interface{}, error) {
+		return []byte(os.Getenv("JWT_SECRET")), nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	if !token.Valid {
+		return nil, errors.New("invalid token")
+	}
+	return claims, nil
+}
+
+// sessionTouchInterval limits how often a session's last use is written
+const sessionTouchInterval = time.Minute
+
+// touchSession reports whether a session is still active and records its use
+func touchSession(tokenID string, userID uint) bool {
+	var session database.Session
+	if err := database.DB.Where("token_id = ? AND user_id = ?", tokenID, userID).First(&session).Error; err != nil {
+		return false
+	}
+	if session.RevokedAt != 0 {
+		return false
+	}
+	if now := time.Now().Unix(); now-session.LastUsedAt >= int64(sessionTouchInterval.Seconds()) {
+		database.DB.Model(&session).Update("last_used_at", now)
+	}
+	return true
+}
+
+// GetSessionID returns the session the request's token belongs to, "" for tokens without one
+func GetSessionID(c *gin.Context) string {
+	return c.GetString("session_id")
+}
+
 // GetUserFromContext retrieves the user from context (to be used in handlers)
 func GetUserFromContext(c *gin.Context) (*database.User, bool) {
 	user, exists := c.Get("user")
@@ -0,0 +1,24 @@
+package middleware
+
+import (
+	"net/http"
+
+	"server/internal/i18n"
+
+	"github.com/gin-gonic/gin"
+)
+
+// BodySizeLimit rejects requests whose body exceeds maxBytes
+func BodySizeLimit(maxBytes int64) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		if c.Request.ContentLength > maxBytes {
+			i18n.RespondError(c, http.StatusRequestEntityTooLarge, i18n.CodeRequestTooLarge, "")
+			c.Abort()
+			return
+		}
+
+		// Guard against bodies without (or with a lying) Content-Length
+		c.Request.Body = http.MaxBytesReader(c.Writer, c.Request.Body, maxBytes)
+		c.Next()
+	}
+}
@@ -0,0 +1,30 @@
+package middleware
+
+import (
+	"net/http"
+
+	"server/internal/flags"
+
+	"github.com/gin-gonic/gin"
+)
+
+// RequireFeature hides a route (404) from users the feature flag is not enabled for.
+// It must run after AuthMiddleware.
+func RequireFeature(name string) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		user, exists := GetUserFromContext(c)
+		if !exists || !flags.Enabled(name, user.ID) {
+			c.JSON(http.StatusNotFound, gin.H{"error": "Not found"})
+			c.Abort()
+			return
+		}
+		c.Next()
+	}
+}
+
+// FeatureEnabled reports whether a feature is on for the request's user, for
+// handlers that switch behaviour rather than hide a route
+func FeatureEnabled(c *gin.Context, name string) bool {
+	user, exists := GetUserFromContext(c)
+	return exists && flags.Enabled(name, user.ID)
+}
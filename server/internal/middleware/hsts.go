@@ -0,0 +1,24 @@
+package middleware
+
+import (
+	"fmt"
+	"time"
+
+	"github.com/gin-gonic/gin"
+)
+
+// HSTS tells browsers to use HTTPS for maxAge. The header is only sent on
+// TLS connections, since browsers ignore it over plain HTTP.
+func HSTS(maxAge time.Duration, includeSubdomains bool) gin.HandlerFunc {
+	value := fmt.Sprintf("max-age=%d", int64(maxAge.Seconds()))
+	if includeSubdomains {
+		value += "; includeSubDomains"
+	}
+
+	return func(c *gin.Context) {
+		if c.Request.TLS != nil {
+			c.Header("Strict-Transport-Security", value)
+		}
+		c.Next()
+	}
+}
@@ -0,0 +1,39 @@
+package notifications
+
+import (
+	"context"
+	"fmt"
+
+	"server/internal/database"
+	"server/internal/discord"
+)
+
+// DiscordNotifier sends events as direct messages to users who linked their Discord account
+type DiscordNotifier struct {
+	client *discord.Client
+}
+
+func NewDiscordNotifier(client *discord.Client) *DiscordNotifier {
+	return &DiscordNotifier{client: client}
+}
+
+func (n *DiscordNotifier) Name() string {
+	return "discord"
+}
+
+func (n *DiscordNotifier) Notify(ctx context.Context, event Event) error {
+	// Progress updates are too chatty for this channel
+	if event.Type == EventTransferProgress {
+		return nil
+	}
+
+	var link database.DiscordLink
+	err := database.DB.WithContext(ctx).
+		Where("user_id = ? AND discord_user_id <> ''", event.UserID).
+		First(&link).Error
+	if err != nil {
+		return nil // Discord not linked
+	}
+
+	return n.client.SendDirectMessage(ctx, link.DiscordUserID, fmt.Sprintf("**%s**\n%s", event.Title, event.Message))
+}
@@ -0,0 +1,69 @@
+package notifications
+
+import (
+	"context"
+	"fmt"
+	"net"
+	"net/smtp"
+	"strings"
+
+	"server/internal/database"
+)
+
+// EmailConfig is the SMTP server mail is sent through
+type EmailConfig struct {
+	Host     string
+	Port     string
+	Username string
+	Password string
+	From     string
+}
+
+// EmailNotifier mails digests to the address of the user's account. Other
+// events are left to the chat channels, which suit them better.
+type EmailNotifier struct {
+	config EmailConfig
+}
+
+func NewEmailNotifier(config EmailConfig) *EmailNotifier {
+	return &EmailNotifier{config: config}
+}
+
+func (n *EmailNotifier) Name() string {
+	return "email"
+}
+
+func (n *EmailNotifier) Notify(ctx context.Context, event Event) error {
+	if event.Type != EventWeeklyDigest {
+		return nil
+	}
+
+	var user database.User
+	if err := database.DB.WithContext(ctx).First(&user, event.UserID).Error; err != nil || user.Email == "" {
+		return nil // no address to mail
+	}
+
+	return n.send(user.Email, event.Title, event.Message)
+}
+
+func (n *EmailNotifier) send(to, subject, body string) error {
+	var auth smtp.Auth
+	if n.config.Username != "" {
+		auth = smtp.PlainAuth("", n.config.Username, n.config.Password, n.config.Host)
+	}
+
+	msg := strings.Join([]string{
+		"From: " + n.config.From,
+		"To: " + to,
+		"Subject: " + subject,
+		"MIME-Version: 1.0",
+		"Content-Type: text/plain; charset=UTF-8",
+		"",
+		body,
+	}, "\r\n")
+
+	if err := smtp.SendMail(net.JoinHostPort(n.config.Host, n.config.Port), auth, n.config.From, []string{to}, []byte(msg)); err != nil {
+		return fmt.Errorf("smtp: %w", err)
+	}
+	return nil
+}
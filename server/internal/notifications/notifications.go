@@ -0,0 +1,70 @@
+package notifications
+
+import (
+	"context"
+	"log"
+	"slices"
+	"sync"
+	"time"
+)
+
+// Event types
+const (
+	EventTransferProgress  = "transfer_progress"
+	EventTransferCompleted = "transfer_completed"
+	EventTransferFailed    = "transfer_failed"
+	EventWeeklyDigest      = "weekly_digest"
+)
+
+// Event describes something a user may want to be notified about
+type Event struct {
+	UserID     uint
+	Type       string // one of the Event* constants
+	Title      string
+	Message    string
+	TransferID uint
+	Channels   []string // notifier names the user wants this on, empty for all
+}
+
+// Notifier delivers events over a single channel. Implementations decide on
+// their own whether the user has that channel configured and return nil if not.
+type Notifier interface {
+	Name() string
+	Notify(ctx context.Context, event Event) error
+}
+
+var (
+	notifiers []Notifier
+	mu        sync.RWMutex
+)
+
+// Register adds a notifier that receives every dispatched event
+func Register(n Notifier) {
+	mu.Lock()
+	defer mu.Unlock()
+
+	notifiers = append(notifiers, n)
+	log.Printf("Registered %s notifier", n.Name())
+}
+
+// Dispatch delivers the event through all registered notifiers in the background
+func Dispatch(event Event) {
+	mu.RLock()
+	registered := append([]Notifier(nil), notifiers...)
+	mu.RUnlock()
+
+	for _, n := range registered {
+		if len(event.Channels) > 0 && !slices.Contains(event.Channels, n.Name()) {
+			continue
+		}
+
+		go func(n Notifier) {
+			ctx, cancel := context.WithTimeout(context.Background(), 30*time.Second)
+			defer cancel()
+
+			if err := n.Notify(ctx, event); err != nil {
+				log.Printf("Failed to send %s notification to user %d via %s: %v", event.Type, event.UserID, n.Name(), err)
+			}
+		}(n)
+	}
+}
@@ -0,0 +1,67 @@
+package notifications
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"time"
+
+	"server/internal/database"
+)
+
+// SlackNotifier posts events to the incoming webhook a user configured
+type SlackNotifier struct {
+	httpClient *http.Client
+}
+
+func NewSlackNotifier() *SlackNotifier {
+	return &SlackNotifier{httpClient: &http.Client{Timeout: 10 * time.Second}}
+}
+
+func (n *SlackNotifier) Name() string {
+	return "slack"
+}
+
+func (n *SlackNotifier) Notify(ctx context.Context, event Event) error {
+	// Progress updates are too chatty for this channel
+	if event.Type == EventTransferProgress {
+		return nil
+	}
+
+	var integration database.SlackIntegration
+	err := database.DB.WithContext(ctx).
+		Where("user_id = ? AND enabled = ?", event.UserID, true).
+		First(&integration).Error
+	if err != nil {
+		return nil // Slack not configured
+	}
+
+	return SendSlackMessage(ctx, n.httpClient, integration.WebhookURL, fmt.Sprintf("*%s*\n%s", event.Title, event.Message))
+}
+
+// SendSlackMessage posts a plain text message to a Slack incoming webhook
+func SendSlackMessage(ctx context.Context, client *http.Client, webhookURL, text string) error {
+	body, err := json.Marshal(map[string]string{"text": text})
+	if err != nil {
+		return err
+	}
+
+	req, err := http.NewRequestWithContext(ctx, "POST", webhookURL, bytes.NewReader(body))
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Content-Type", "application/json")
+
+	resp, err := client.Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		return fmt.Errorf("slack webhook returned status: %d", resp.StatusCode)
+	}
+	return nil
+}
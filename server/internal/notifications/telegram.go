@@ -0,0 +1,33 @@
+package notifications
+
+import (
+	"context"
+
+	"server/internal/database"
+	"server/internal/telegram"
+)
+
+// TelegramNotifier messages the chat a user linked, including progress updates
+type TelegramNotifier struct {
+	client *telegram.Client
+}
+
+func NewTelegramNotifier(client *telegram.Client) *TelegramNotifier {
+	return &TelegramNotifier{client: client}
+}
+
+func (n *TelegramNotifier) Name() string {
+	return "telegram"
+}
+
+func (n *TelegramNotifier) Notify(ctx context.Context, event Event) error {
+	var link database.TelegramLink
+	err := database.DB.WithContext(ctx).
+		Where("user_id = ? AND chat_id <> 0", event.UserID).
+		First(&link).Error
+	if err != nil {
+		return nil // Telegram not linked
+	}
+
+	return n.client.SendMessage(ctx, link.ChatID, event.Title+"\n"+event.Message)
+}
@@ -0,0 +1,238 @@
+// Package mock imitates the Spotify Web API and the YouTube Data API with an
+// in-memory catalog, so the transfer flow can run in tests and demo
+// deployments without real accounts. Provider satisfies providers.Doer and
+// can be handed to spotify.New and youtube.New in place of an HTTP client.
+package mock
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"slices"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+)
+
+// Account details every mock token resolves to
+const (
+	SpotifyUserID    = "mock-user"
+	SpotifyUserName  = "Demo Listener"
+	YouTubeChannelID = "UCmockchannel"
+	YouTubeUserName  = "Demo Channel"
+	Market           = "US"
+	AccessToken      = "mock-access-token"
+)
+
+// Song is a recording in the catalog. An empty SpotifyID or YouTubeID leaves
+// it missing on that service, so transfers have something to fail on.
+type Song struct {
+	Title      string
+	Artist     string
+	Album      string
+	ISRC       string
+	DurationMS int
+	Explicit   bool
+	SpotifyID  string
+	YouTubeID  string
+}
+
+type playlist struct {
+	id          string
+	name        string
+	description string
+	public      bool
+	items       []playlistItem
+}
+
+type playlistItem struct {
+	id      string // YouTube playlist item ID
+	songID  string // Spotify track or YouTube video ID
+	addedAt time.Time
+}
+
+// Provider serves the mock APIs. It is safe for concurrent use.
+type Provider struct {
+	mu         sync.Mutex
+	songs      []Song
+	spotify    []*playlist
+	youtube    []*playlist
+	liked      []playlistItem // Spotify Liked Songs, most recently saved first
+	following  []string       // artists followed on Spotify, by name
+	subscribed []string       // YouTube channel subscriptions, by artist name
+	nextID     int
+	nextItemID int
+	mux        *http.ServeMux
+}
+
+// New returns a provider seeded with the demo catalog and playlists
+func New() *Provider {
+	p := &Provider{songs: DemoCatalog}
+
+	p.spotify = []*playlist{
+		p.seed("Road Trip", "Songs for the long drive", DemoCatalog[0].SpotifyID, DemoCatalog[1].SpotifyID, DemoCatalog[2].SpotifyID, DemoCatalog[3].SpotifyID, DemoCatalog[9].SpotifyID),
+		p.seed("Late Night", "", DemoCatalog[4].SpotifyID, DemoCatalog[5].SpotifyID, DemoCatalog[6].SpotifyID),
+	}
+	p.youtube = []*playlist{
+		p.seed("Morning Mix", "", DemoCatalog[7].YouTubeID, DemoCatalog[8].YouTubeID, DemoCatalog[0].YouTubeID, DemoCatalog[10].YouTubeID),
+	}
+	p.liked = p.seed("", "", DemoCatalog[8].SpotifyID, DemoCatalog[5].SpotifyID, DemoCatalog[1].SpotifyID).items
+	p.following = []string{"Mira Vale", "Coastal Drift"}
+	p.subscribed = []string{"The Paper Lanterns"}
+
+	p.mux = http.NewServeMux()
+	p.routeSpotify()
+	p.routeYouTube()
+	return p
+}
+
+// DemoCatalog is the catalog New seeds. The last two songs each exist on one service only.
+var DemoCatalog = []Song{
+	{"Harbor Lights", "The Paper Lanterns", "Low Tide", "QZDEM2400001", 214000, false, "mocksp0000000000000001", "mockyt00001"},
+	{"Glass Orchard", "Mira Vale", "Glass Orchard", "QZDEM2400002", 187000, false, "mocksp0000000000000002", "mockyt00002"},
+	{"Northbound", "Static Parade", "Signals", "QZDEM2400003", 243000, true, "mocksp0000000000000003", "mockyt00003"},
+	{"Paper Moons", "The Paper Lanterns", "Low Tide", "QZDEM2400004", 201000, false, "mocksp0000000000000004", "mockyt00004"},
+	{"Slow Burn", "June Okafor", "Ember", "QZDEM2400005", 259000, false, "mocksp0000000000000005", "mockyt00005"},
+	{"Velvet Hour", "Mira Vale", "Afterglow", "QZDEM2400006", 228000, false, "mocksp0000000000000006", "mockyt00006"},
+	{"Neon Rain - Remastered", "Static Parade", "Signals (Deluxe)", "QZDEM2400007", 236000, false, "mocksp0000000000000007", "mockyt00007"},
+	{"Sunday Static", "June Okafor", "Ember", "QZDEM2400008", 195000, false, "mocksp0000000000000008", "mockyt00008"},
+	{"First Light", "Coastal Drift", "Horizons", "QZDEM2400009", 221000, false, "mocksp0000000000000009", "mockyt00009"},
+	{"Unreleased Demo", "The Paper Lanterns", "", "QZDEM2400010", 176000, false, "mocksp0000000000000010", ""},
+	{"Live at the Pier", "Coastal Drift", "", "", 312000, false, "", "mockyt00011"},
+}
+
+// AddSongs extends the catalog, for playlists larger than the demo one allows
+func (p *Provider) AddSongs(songs ...Song) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	p.songs = append(slices.Clip(p.songs), songs...)
+}
+
+func (p *Provider) seed(name, description string, songIDs ...string) *playlist {
+	pl := &playlist{id: p.newID(), name: name, description: description}
+	added := time.Date(2024, time.January, 1, 12, 0, 0, 0, time.UTC)
+	for i, id := range songIDs {
+		pl.items = append(pl.items, playlistItem{id: p.newItemID(), songID: id, addedAt: added.AddDate(0, 0, i)})
+	}
+	return pl
+}
+
+func (p *Provider) newID() string {
+	p.nextID++
+	return fmt.Sprintf("mockpl%06d", p.nextID)
+}
+
+func (p *Provider) newItemID() string {
+	p.nextItemID++
+	return fmt.Sprintf("mockitem%06d", p.nextItemID)
+}
+
+// Do serves a request against the in-memory APIs
+func (p *Provider) Do(req *http.Request) (*http.Response, error) {
+	if err := req.Context().Err(); err != nil {
+		return nil, err
+	}
+	rec := httptest.NewRecorder()
+	p.mux.ServeHTTP(rec, req)
+	return rec.Result(), nil
+}
+
+// authorized accepts any bearer token or API key, like a freshly connected account
+func authorized(w http.ResponseWriter, r *http.Request) bool {
+	if token, ok := strings.CutPrefix(r.Header.Get("Authorization"), "Bearer "); (ok && token != "") || r.URL.Query().Get("key") != "" {
+		return true
+	}
+	writeJSON(w, http.StatusUnauthorized, map[string]interface{}{"error": map[string]interface{}{"status": 401, "message": "No token provided"}})
+	return false
+}
+
+func writeJSON(w http.ResponseWriter, status int, body interface{}) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(status)
+	json.NewEncoder(w).Encode(body)
+}
+
+func notFound(w http.ResponseWriter) {
+	writeJSON(w, http.StatusNotFound, map[string]interface{}{"error": map[string]interface{}{"status": 404, "message": "Not found"}})
+}
+
+func queryInt(r *http.Request, name string, def int) int {
+	if n, err := strconv.Atoi(r.URL.Query().Get(name)); err == nil && n > 0 {
+		return n
+	}
+	return def
+}
+
+// moveItem moves the item at from so it ends up at index to
+func moveItem(items []playlistItem, from, to int) {
+	item := items[from]
+	if from < to {
+		copy(items[from:to], items[from+1:to+1])
+	} else {
+		copy(items[to+1:from+1], items[to:from])
+	}
+	items[to] = item
+}
+
+// artistSlug derives the stable part of an artist's mock Spotify ID and YouTube channel ID
+func artistSlug(name string) string {
+	var b strings.Builder
+	for _, r := range strings.ToLower(name) {
+		if (r >= 'a' && r <= 'z') || (r >= '0' && r <= '9') {
+			b.WriteRune(r)
+		}
+	}
+	return b.String()
+}
+
+// artists returns the catalog's artists whose name appears in the query, or all when query is empty
+func (p *Provider) artists(query string, limit int) []string {
+	query = strings.ToLower(query)
+	var names []string
+	for _, song := range p.songs {
+		if slices.Contains(names, song.Artist) || (query != "" && !strings.Contains(query, strings.ToLower(song.Artist))) {
+			continue
+		}
+		names = append(names, song.Artist)
+		if len(names) == limit {
+			break
+		}
+	}
+	return names
+}
+
+// findPlaylist looks a playlist up; callers hold p.mu
+func findPlaylist(playlists []*playlist, id string) *playlist {
+	for _, pl := range playlists {
+		if pl.id == id {
+			return pl
+		}
+	}
+	return nil
+}
+
+// search returns songs whose title appears in the query, or whose ISRC is asked for
+func (p *Provider) search(query string, onService func(Song) bool, limit int) []Song {
+	query = strings.ToLower(query)
+	isrc, byISRC := strings.CutPrefix(query, "isrc:")
+
+	var results []Song
+	for _, song := range p.songs {
+		if !onService(song) {
+			continue
+		}
+		if byISRC {
+			if song.ISRC != "" && strings.EqualFold(song.ISRC, strings.TrimSpace(isrc)) {
+				results = append(results, song)
+			}
+		} else if strings.Contains(query, strings.ToLower(song.Title)) {
+			results = append(results, song)
+		}
+		if len(results) == limit {
+			break
+		}
+	}
+	return results
+}
@@ -0,0 +1,425 @@
+package mock
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"slices"
+	"strconv"
+	"strings"
+	"time"
+
+	"server/internal/providers/spotify"
+)
+
+func (p *Provider) routeSpotify() {
+	p.mux.HandleFunc("GET api.spotify.com/v1/me", p.spotifyMe)
+	p.mux.HandleFunc("GET api.spotify.com/v1/me/playlists", p.spotifyMyPlaylists)
+	p.mux.HandleFunc("GET api.spotify.com/v1/me/tracks", p.spotifySavedTracks)
+	p.mux.HandleFunc("GET api.spotify.com/v1/me/following", p.spotifyFollowing)
+	p.mux.HandleFunc("PUT api.spotify.com/v1/me/following", p.spotifyFollow)
+	p.mux.HandleFunc("PUT api.spotify.com/v1/me/player/play", p.spotifyPlay)
+	p.mux.HandleFunc("GET api.spotify.com/v1/playlists/{id}", p.spotifyPlaylist)
+	p.mux.HandleFunc("PUT api.spotify.com/v1/playlists/{id}", p.spotifyUpdatePlaylist)
+	p.mux.HandleFunc("GET api.spotify.com/v1/playlists/{id}/tracks", p.spotifyPlaylistTracks)
+	p.mux.HandleFunc("POST api.spotify.com/v1/playlists/{id}/tracks", p.spotifyAddTracks)
+	p.mux.HandleFunc("PUT api.spotify.com/v1/playlists/{id}/tracks", p.spotifyReorderTracks)
+	p.mux.HandleFunc("DELETE api.spotify.com/v1/playlists/{id}/tracks", p.spotifyRemoveTracks)
+	p.mux.HandleFunc("POST api.spotify.com/v1/users/{user}/playlists", p.spotifyCreatePlaylist)
+	p.mux.HandleFunc("GET api.spotify.com/v1/search", p.spotifySearch)
+	p.mux.HandleFunc("GET api.spotify.com/v1/tracks", p.spotifyTracks)
+	p.mux.HandleFunc("GET api.spotify.com/v1/audio-features", p.spotifyAudioFeatures)
+}
+
+func spotifyTrack(song Song) spotify.Track {
+	return spotify.Track{
+		ID:          song.SpotifyID,
+		Name:        song.Title,
+		DurationMS:  song.DurationMS,
+		Explicit:    song.Explicit,
+		Artists:     []spotify.Artist{{Name: song.Artist}},
+		Album:       spotify.Album{Name: song.Album},
+		ExternalIDs: spotify.ExternalIDs{ISRC: song.ISRC},
+	}
+}
+
+func (p *Provider) spotifySong(id string) (Song, bool) {
+	for _, song := range p.songs {
+		if song.SpotifyID != "" && song.SpotifyID == id {
+			return song, true
+		}
+	}
+	return Song{}, false
+}
+
+func (p *Provider) spotifyPlaylistBody(pl *playlist, withTracks bool) spotify.Playlist {
+	body := spotify.Playlist{
+		ID:          pl.id,
+		Name:        pl.name,
+		Description: pl.description,
+		Public:      pl.public,
+		Owner:       spotify.Owner{ID: SpotifyUserID, DisplayName: SpotifyUserName},
+	}
+	body.Tracks.Total = len(pl.items)
+	if withTracks {
+		// Like Spotify, the playlist carries its first 100 items and links to the rest
+		body.Tracks.Items, body.Tracks.Next = p.spotifyPlaylistPage(pl, 0, 100)
+	}
+	return body
+}
+
+// spotifyPlaylistPage returns limit items of pl from offset, and the next page's URL if any
+func (p *Provider) spotifyPlaylistPage(pl *playlist, offset, limit int) ([]spotify.PlaylistItem, string) {
+	items := []spotify.PlaylistItem{}
+	for i := offset; i < len(pl.items) && i < offset+limit; i++ {
+		song, _ := p.spotifySong(pl.items[i].songID)
+		items = append(items, spotify.PlaylistItem{AddedAt: pl.items[i].addedAt, Track: spotifyTrack(song)})
+	}
+	next := ""
+	if offset+limit < len(pl.items) {
+		next = fmt.Sprintf("https://api.spotify.com/v1/playlists/%s/tracks?offset=%d&limit=%d", pl.id, offset+limit, limit)
+	}
+	return items, next
+}
+
+func (p *Provider) spotifyMe(w http.ResponseWriter, r *http.Request) {
+	if !authorized(w, r) {
+		return
+	}
+	writeJSON(w, http.StatusOK, spotify.User{ID: SpotifyUserID, DisplayName: SpotifyUserName, Country: Market})
+}
+
+func (p *Provider) spotifyMyPlaylists(w http.ResponseWriter, r *http.Request) {
+	if !authorized(w, r) {
+		return
+	}
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	items := []spotify.Playlist{}
+	for _, pl := range p.spotify {
+		items = append(items, p.spotifyPlaylistBody(pl, false))
+	}
+	if limit := queryInt(r, "limit", 20); len(items) > limit {
+		items = items[:limit]
+	}
+	writeJSON(w, http.StatusOK, map[string]interface{}{"items": items})
+}
+
+func (p *Provider) spotifySavedTracks(w http.ResponseWriter, r *http.Request) {
+	if !authorized(w, r) {
+		return
+	}
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	items := []spotify.PlaylistItem{}
+	for _, item := range p.liked {
+		song, _ := p.spotifySong(item.songID)
+		items = append(items, spotify.PlaylistItem{AddedAt: item.addedAt, Track: spotifyTrack(song)})
+	}
+	if limit := queryInt(r, "limit", 20); len(items) > limit {
+		items = items[:limit]
+	}
+	writeJSON(w, http.StatusOK, map[string]interface{}{"items": items})
+}
+
+func spotifyArtists(names []string) []spotify.ArtistProfile {
+	artists := []spotify.ArtistProfile{}
+	for _, name := range names {
+		artists = append(artists, spotify.ArtistProfile{ID: "mockartist" + artistSlug(name), Name: name})
+	}
+	return artists
+}
+
+func (p *Provider) spotifyFollowing(w http.ResponseWriter, r *http.Request) {
+	if !authorized(w, r) {
+		return
+	}
+	p.mu.Lock()
+	names := slices.Clone(p.following)
+	p.mu.Unlock()
+
+	if limit := queryInt(r, "limit", 20); len(names) > limit {
+		names = names[:limit]
+	}
+	writeJSON(w, http.StatusOK, map[string]interface{}{"artists": map[string]interface{}{"items": spotifyArtists(names)}})
+}
+
+func (p *Provider) spotifyFollow(w http.ResponseWriter, r *http.Request) {
+	if !authorized(w, r) {
+		return
+	}
+	var body struct {
+		IDs []string `json:"ids"`
+	}
+	if err := json.NewDecoder(r.Body).Decode(&body); err != nil {
+		writeJSON(w, http.StatusBadRequest, map[string]interface{}{"error": "invalid body"})
+		return
+	}
+
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	for _, id := range body.IDs {
+		for _, name := range p.artists("", 0) {
+			if "mockartist"+artistSlug(name) == id && !slices.Contains(p.following, name) {
+				p.following = append(p.following, name)
+			}
+		}
+	}
+	w.WriteHeader(http.StatusNoContent)
+}
+
+// spotifyPlay reports no active device, which is what a demo account has
+func (p *Provider) spotifyPlay(w http.ResponseWriter, r *http.Request) {
+	if !authorized(w, r) {
+		return
+	}
+	notFound(w)
+}
+
+func (p *Provider) spotifyPlaylist(w http.ResponseWriter, r *http.Request) {
+	if !authorized(w, r) {
+		return
+	}
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	pl := findPlaylist(p.spotify, r.PathValue("id"))
+	if pl == nil {
+		notFound(w)
+		return
+	}
+	writeJSON(w, http.StatusOK, p.spotifyPlaylistBody(pl, true))
+}
+
+func (p *Provider) spotifyPlaylistTracks(w http.ResponseWriter, r *http.Request) {
+	if !authorized(w, r) {
+		return
+	}
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	pl := findPlaylist(p.spotify, r.PathValue("id"))
+	if pl == nil {
+		notFound(w)
+		return
+	}
+	offset, _ := strconv.Atoi(r.URL.Query().Get("offset"))
+	items, next := p.spotifyPlaylistPage(pl, offset, queryInt(r, "limit", 100))
+	writeJSON(w, http.StatusOK, map[string]interface{}{"items": items, "next": next, "total": len(pl.items)})
+}
+
+func (p *Provider) spotifyUpdatePlaylist(w http.ResponseWriter, r *http.Request) {
+	if !authorized(w, r) {
+		return
+	}
+	var body struct {
+		Description *string `json:"description"`
+	}
+	if err := json.NewDecoder(r.Body).Decode(&body); err != nil {
+		writeJSON(w, http.StatusBadRequest, map[string]interface{}{"error": "invalid body"})
+		return
+	}
+
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	pl := findPlaylist(p.spotify, r.PathValue("id"))
+	if pl == nil {
+		notFound(w)
+		return
+	}
+	if body.Description != nil {
+		pl.description = *body.Description
+	}
+	w.WriteHeader(http.StatusOK)
+}
+
+func (p *Provider) spotifyAddTracks(w http.ResponseWriter, r *http.Request) {
+	if !authorized(w, r) {
+		return
+	}
+	var body struct {
+		URIs []string `json:"uris"`
+	}
+	if err := json.NewDecoder(r.Body).Decode(&body); err != nil {
+		writeJSON(w, http.StatusBadRequest, map[string]interface{}{"error": "invalid body"})
+		return
+	}
+
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	pl := findPlaylist(p.spotify, r.PathValue("id"))
+	if pl == nil {
+		notFound(w)
+		return
+	}
+	for _, uri := range body.URIs {
+		id := strings.TrimPrefix(uri, "spotify:track:")
+		if _, ok := p.spotifySong(id); !ok {
+			writeJSON(w, http.StatusBadRequest, map[string]interface{}{"error": "Invalid track uri: " + uri})
+			return
+		}
+		pl.items = append(pl.items, playlistItem{id: p.newItemID(), songID: id, addedAt: time.Now().UTC()})
+	}
+	writeJSON(w, http.StatusCreated, map[string]interface{}{"snapshot_id": pl.id})
+}
+
+func (p *Provider) spotifyReorderTracks(w http.ResponseWriter, r *http.Request) {
+	if !authorized(w, r) {
+		return
+	}
+	var body struct {
+		RangeStart   int `json:"range_start"`
+		InsertBefore int `json:"insert_before"`
+	}
+	if err := json.NewDecoder(r.Body).Decode(&body); err != nil {
+		writeJSON(w, http.StatusBadRequest, map[string]interface{}{"error": "invalid body"})
+		return
+	}
+
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	pl := findPlaylist(p.spotify, r.PathValue("id"))
+	if pl == nil {
+		notFound(w)
+		return
+	}
+	if body.RangeStart < 0 || body.RangeStart >= len(pl.items) || body.InsertBefore < 0 || body.InsertBefore > len(pl.items) {
+		writeJSON(w, http.StatusBadRequest, map[string]interface{}{"error": "Index out of bounds"})
+		return
+	}
+	to := body.InsertBefore
+	if to > body.RangeStart {
+		to--
+	}
+	moveItem(pl.items, body.RangeStart, to)
+	writeJSON(w, http.StatusOK, map[string]interface{}{"snapshot_id": pl.id})
+}
+
+func (p *Provider) spotifyRemoveTracks(w http.ResponseWriter, r *http.Request) {
+	if !authorized(w, r) {
+		return
+	}
+	var body struct {
+		Tracks []struct {
+			URI string `json:"uri"`
+		} `json:"tracks"`
+	}
+	if err := json.NewDecoder(r.Body).Decode(&body); err != nil {
+		writeJSON(w, http.StatusBadRequest, map[string]interface{}{"error": "invalid body"})
+		return
+	}
+
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	pl := findPlaylist(p.spotify, r.PathValue("id"))
+	if pl == nil {
+		notFound(w)
+		return
+	}
+	for _, track := range body.Tracks {
+		id := strings.TrimPrefix(track.URI, "spotify:track:")
+		pl.items = slices.DeleteFunc(pl.items, func(item playlistItem) bool { return item.songID == id })
+	}
+	writeJSON(w, http.StatusOK, map[string]interface{}{"snapshot_id": pl.id})
+}
+
+func (p *Provider) spotifyCreatePlaylist(w http.ResponseWriter, r *http.Request) {
+	if !authorized(w, r) {
+		return
+	}
+	if r.PathValue("user") != SpotifyUserID {
+		writeJSON(w, http.StatusForbidden, map[string]interface{}{"error": "You cannot create a playlist for another user"})
+		return
+	}
+	var body spotify.CreatePlaylistRequest
+	if err := json.NewDecoder(r.Body).Decode(&body); err != nil || body.Name == "" {
+		writeJSON(w, http.StatusBadRequest, map[string]interface{}{"error": "Missing required field: name"})
+		return
+	}
+
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	pl := &playlist{id: p.newID(), name: body.Name, description: body.Description, public: body.Public}
+	p.spotify = append(p.spotify, pl)
+	writeJSON(w, http.StatusCreated, p.spotifyPlaylistBody(pl, false))
+}
+
+func (p *Provider) spotifySearch(w http.ResponseWriter, r *http.Request) {
+	if !authorized(w, r) {
+		return
+	}
+	if r.URL.Query().Get("type") == "artist" {
+		p.mu.Lock()
+		names := p.artists(r.URL.Query().Get("q"), queryInt(r, "limit", 20))
+		p.mu.Unlock()
+		writeJSON(w, http.StatusOK, map[string]interface{}{"artists": map[string]interface{}{"items": spotifyArtists(names)}})
+		return
+	}
+
+	p.mu.Lock()
+	songs := p.search(r.URL.Query().Get("q"), func(s Song) bool { return s.SpotifyID != "" }, queryInt(r, "limit", 20))
+	p.mu.Unlock()
+
+	items := []spotify.Track{}
+	for _, song := range songs {
+		track := spotifyTrack(song)
+		if r.URL.Query().Get("market") != "" {
+			playable := true
+			track.IsPlayable = &playable
+		}
+		items = append(items, track)
+	}
+	writeJSON(w, http.StatusOK, map[string]interface{}{"tracks": map[string]interface{}{"items": items}})
+}
+
+func (p *Provider) spotifyTracks(w http.ResponseWriter, r *http.Request) {
+	if !authorized(w, r) {
+		return
+	}
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	tracks := []*spotify.Track{}
+	for _, id := range strings.Split(r.URL.Query().Get("ids"), ",") {
+		song, ok := p.spotifySong(id)
+		if !ok {
+			tracks = append(tracks, nil)
+			continue
+		}
+		track := spotifyTrack(song)
+		if r.URL.Query().Get("market") != "" {
+			playable := true
+			track.IsPlayable = &playable
+		}
+		tracks = append(tracks, &track)
+	}
+	writeJSON(w, http.StatusOK, map[string]interface{}{"tracks": tracks})
+}
+
+// spotifyAudioFeatures derives a stable tempo from the track's duration
+func (p *Provider) spotifyAudioFeatures(w http.ResponseWriter, r *http.Request) {
+	if !authorized(w, r) {
+		return
+	}
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	features := []*spotify.AudioFeatures{}
+	for _, id := range strings.Split(r.URL.Query().Get("ids"), ",") {
+		song, ok := p.spotifySong(id)
+		if !ok {
+			features = append(features, nil)
+			continue
+		}
+		features = append(features, &spotify.AudioFeatures{ID: id, Tempo: float64(80 + song.DurationMS/1000%80)})
+	}
+	writeJSON(w, http.StatusOK, map[string]interface{}{"audio_features": features})
+}
@@ -0,0 +1,387 @@
+package mock
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"slices"
+	"strconv"
+	"strings"
+	"time"
+
+	"server/internal/providers/youtube"
+)
+
+func (p *Provider) routeYouTube() {
+	p.mux.HandleFunc("GET www.googleapis.com/youtube/v3/playlists", p.youtubePlaylists)
+	p.mux.HandleFunc("POST www.googleapis.com/youtube/v3/playlists", p.youtubeInsertPlaylist)
+	p.mux.HandleFunc("PUT www.googleapis.com/youtube/v3/playlists", p.youtubeUpdatePlaylist)
+	p.mux.HandleFunc("GET www.googleapis.com/youtube/v3/playlistItems", p.youtubePlaylistItems)
+	p.mux.HandleFunc("POST www.googleapis.com/youtube/v3/playlistItems", p.youtubeInsertPlaylistItem)
+	p.mux.HandleFunc("PUT www.googleapis.com/youtube/v3/playlistItems", p.youtubeUpdatePlaylistItem)
+	p.mux.HandleFunc("DELETE www.googleapis.com/youtube/v3/playlistItems", p.youtubeDeletePlaylistItem)
+	p.mux.HandleFunc("GET www.googleapis.com/youtube/v3/search", p.youtubeSearch)
+	p.mux.HandleFunc("GET www.googleapis.com/youtube/v3/videos", p.youtubeVideos)
+	p.mux.HandleFunc("GET www.googleapis.com/youtube/v3/channels", p.youtubeChannels)
+	p.mux.HandleFunc("GET www.googleapis.com/youtube/v3/subscriptions", p.youtubeSubscriptions)
+	p.mux.HandleFunc("POST www.googleapis.com/youtube/v3/subscriptions", p.youtubeSubscribe)
+}
+
+// youtubeTitle is how an upload of the song is titled
+func youtubeTitle(song Song) string {
+	return song.Artist + " - " + song.Title + " (Official Audio)"
+}
+
+func (p *Provider) youtubeSong(id string) (Song, bool) {
+	for _, song := range p.songs {
+		if song.YouTubeID != "" && song.YouTubeID == id {
+			return song, true
+		}
+	}
+	return Song{}, false
+}
+
+func youtubePlaylistBody(pl *playlist) youtube.Playlist {
+	privacy := "private"
+	if pl.public {
+		privacy = "public"
+	}
+	body := youtube.Playlist{
+		ID: pl.id,
+		Snippet: youtube.PlaylistSnippet{
+			Title:        pl.name,
+			Description:  pl.description,
+			ChannelID:    YouTubeChannelID,
+			ChannelTitle: YouTubeUserName,
+			Thumbnails:   &youtube.Thumbnails{},
+		},
+		Status: &youtube.PlaylistStatus{PrivacyStatus: privacy},
+	}
+	body.ContentDetails = &struct {
+		ItemCount int `json:"itemCount"`
+	}{ItemCount: len(pl.items)}
+	return body
+}
+
+func (p *Provider) youtubePlaylists(w http.ResponseWriter, r *http.Request) {
+	if !authorized(w, r) {
+		return
+	}
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	items := []youtube.Playlist{}
+	if ids := r.URL.Query().Get("id"); ids != "" {
+		for _, id := range strings.Split(ids, ",") {
+			if pl := findPlaylist(p.youtube, id); pl != nil {
+				items = append(items, youtubePlaylistBody(pl))
+			}
+		}
+	} else {
+		for _, pl := range p.youtube {
+			items = append(items, youtubePlaylistBody(pl))
+		}
+	}
+	if max := queryInt(r, "maxResults", 5); len(items) > max {
+		items = items[:max]
+	}
+	writeJSON(w, http.StatusOK, map[string]interface{}{"items": items})
+}
+
+func (p *Provider) youtubeInsertPlaylist(w http.ResponseWriter, r *http.Request) {
+	if !authorized(w, r) {
+		return
+	}
+	var body youtube.Playlist
+	if err := json.NewDecoder(r.Body).Decode(&body); err != nil || body.Snippet.Title == "" {
+		writeJSON(w, http.StatusBadRequest, map[string]interface{}{"error": "Playlist title is required"})
+		return
+	}
+
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	pl := &playlist{id: p.newID(), name: body.Snippet.Title, description: body.Snippet.Description}
+	pl.public = body.Status != nil && body.Status.PrivacyStatus == "public"
+	p.youtube = append(p.youtube, pl)
+	writeJSON(w, http.StatusOK, youtubePlaylistBody(pl))
+}
+
+func (p *Provider) youtubeUpdatePlaylist(w http.ResponseWriter, r *http.Request) {
+	if !authorized(w, r) {
+		return
+	}
+	var body youtube.Playlist
+	if err := json.NewDecoder(r.Body).Decode(&body); err != nil || body.Snippet.Title == "" {
+		writeJSON(w, http.StatusBadRequest, map[string]interface{}{"error": "Playlist title is required"})
+		return
+	}
+
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	pl := findPlaylist(p.youtube, body.ID)
+	if pl == nil {
+		notFound(w)
+		return
+	}
+	pl.name = body.Snippet.Title
+	pl.description = body.Snippet.Description
+	writeJSON(w, http.StatusOK, youtubePlaylistBody(pl))
+}
+
+func (p *Provider) youtubePlaylistItems(w http.ResponseWriter, r *http.Request) {
+	if !authorized(w, r) {
+		return
+	}
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	pl := findPlaylist(p.youtube, r.URL.Query().Get("playlistId"))
+	if pl == nil {
+		notFound(w)
+		return
+	}
+
+	items := []youtube.PlaylistItem{}
+	for i, entry := range pl.items {
+		song, _ := p.youtubeSong(entry.songID)
+		var item youtube.PlaylistItem
+		item.ID = entry.id
+		item.Snippet.Position = i
+		item.Snippet.PlaylistID = pl.id
+		item.Snippet.Title = youtubeTitle(song)
+		item.Snippet.PublishedAt = entry.addedAt
+		item.Snippet.VideoOwnerChannelTitle = song.Artist + " - Topic"
+		item.Snippet.ResourceID = youtube.ResourceID{Kind: "youtube#video", VideoID: entry.songID}
+		items = append(items, item)
+	}
+	// Page tokens are plain offsets here; YouTube's are opaque
+	total := len(items)
+	start, _ := strconv.Atoi(r.URL.Query().Get("pageToken"))
+	start = min(start, total)
+	end := min(start+queryInt(r, "maxResults", 5), total)
+	body := map[string]interface{}{"items": items[start:end], "pageInfo": map[string]int{"totalResults": total}}
+	if end < total {
+		body["nextPageToken"] = strconv.Itoa(end)
+	}
+	writeJSON(w, http.StatusOK, body)
+}
+
+func (p *Provider) youtubeInsertPlaylistItem(w http.ResponseWriter, r *http.Request) {
+	if !authorized(w, r) {
+		return
+	}
+	var body struct {
+		Snippet struct {
+			PlaylistID string             `json:"playlistId"`
+			ResourceID youtube.ResourceID `json:"resourceId"`
+		} `json:"snippet"`
+	}
+	if err := json.NewDecoder(r.Body).Decode(&body); err != nil {
+		writeJSON(w, http.StatusBadRequest, map[string]interface{}{"error": "invalid body"})
+		return
+	}
+
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	pl := findPlaylist(p.youtube, body.Snippet.PlaylistID)
+	if pl == nil {
+		notFound(w)
+		return
+	}
+	if _, ok := p.youtubeSong(body.Snippet.ResourceID.VideoID); !ok {
+		notFound(w)
+		return
+	}
+	item := playlistItem{id: p.newItemID(), songID: body.Snippet.ResourceID.VideoID, addedAt: time.Now().UTC()}
+	pl.items = append(pl.items, item)
+	writeJSON(w, http.StatusOK, map[string]interface{}{"id": item.id})
+}
+
+func (p *Provider) youtubeUpdatePlaylistItem(w http.ResponseWriter, r *http.Request) {
+	if !authorized(w, r) {
+		return
+	}
+	var body struct {
+		ID      string `json:"id"`
+		Snippet struct {
+			PlaylistID string `json:"playlistId"`
+			Position   *int   `json:"position"`
+		} `json:"snippet"`
+	}
+	if err := json.NewDecoder(r.Body).Decode(&body); err != nil {
+		writeJSON(w, http.StatusBadRequest, map[string]interface{}{"error": "invalid body"})
+		return
+	}
+
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	pl := findPlaylist(p.youtube, body.Snippet.PlaylistID)
+	if pl == nil {
+		notFound(w)
+		return
+	}
+	from := slices.IndexFunc(pl.items, func(item playlistItem) bool { return item.id == body.ID })
+	if from < 0 {
+		notFound(w)
+		return
+	}
+	if body.Snippet.Position != nil {
+		if *body.Snippet.Position < 0 || *body.Snippet.Position >= len(pl.items) {
+			writeJSON(w, http.StatusBadRequest, map[string]interface{}{"error": "Invalid playlist item position"})
+			return
+		}
+		moveItem(pl.items, from, *body.Snippet.Position)
+	}
+	writeJSON(w, http.StatusOK, map[string]interface{}{"id": body.ID})
+}
+
+func (p *Provider) youtubeDeletePlaylistItem(w http.ResponseWriter, r *http.Request) {
+	if !authorized(w, r) {
+		return
+	}
+	id := r.URL.Query().Get("id")
+
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	for _, pl := range p.youtube {
+		if i := slices.IndexFunc(pl.items, func(item playlistItem) bool { return item.id == id }); i >= 0 {
+			pl.items = slices.Delete(pl.items, i, i+1)
+			w.WriteHeader(http.StatusNoContent)
+			return
+		}
+	}
+	notFound(w)
+}
+
+func (p *Provider) youtubeSearch(w http.ResponseWriter, r *http.Request) {
+	if !authorized(w, r) {
+		return
+	}
+	if r.URL.Query().Get("type") == "channel" {
+		p.mu.Lock()
+		names := p.artists(r.URL.Query().Get("q"), queryInt(r, "maxResults", 5))
+		p.mu.Unlock()
+
+		items := []map[string]interface{}{}
+		for _, name := range names {
+			items = append(items, map[string]interface{}{
+				"id":      map[string]string{"kind": "youtube#channel", "channelId": youtubeArtistChannelID(name)},
+				"snippet": map[string]string{"title": name + " - Topic"},
+			})
+		}
+		writeJSON(w, http.StatusOK, map[string]interface{}{"items": items})
+		return
+	}
+
+	p.mu.Lock()
+	songs := p.search(r.URL.Query().Get("q"), func(s Song) bool { return s.YouTubeID != "" }, queryInt(r, "maxResults", 5))
+	p.mu.Unlock()
+
+	items := []youtube.SearchResult{}
+	for _, song := range songs {
+		var result youtube.SearchResult
+		result.ID.VideoID = song.YouTubeID
+		result.Snippet.Title = youtubeTitle(song)
+		result.Snippet.Description = "Provided to YouTube by Mock Music. " + song.Album
+		result.Snippet.ChannelTitle = song.Artist + " - Topic"
+		items = append(items, result)
+	}
+	writeJSON(w, http.StatusOK, map[string]interface{}{"items": items})
+}
+
+func (p *Provider) youtubeVideos(w http.ResponseWriter, r *http.Request) {
+	if !authorized(w, r) {
+		return
+	}
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	items := []youtube.Video{}
+	for _, id := range strings.Split(r.URL.Query().Get("id"), ",") {
+		song, ok := p.youtubeSong(id)
+		if !ok {
+			continue
+		}
+		var video youtube.Video
+		video.ID = id
+		video.Snippet.Title = youtubeTitle(song)
+		video.Snippet.CategoryID = youtube.MusicCategoryID
+		video.Snippet.ChannelID = youtubeArtistChannelID(song.Artist)
+		video.Snippet.ChannelTitle = song.Artist + " - Topic"
+		video.ContentDetails.Duration = fmt.Sprintf("PT%dM%dS", song.DurationMS/60000, song.DurationMS/1000%60)
+		items = append(items, video)
+	}
+	writeJSON(w, http.StatusOK, map[string]interface{}{"items": items})
+}
+
+func (p *Provider) youtubeChannels(w http.ResponseWriter, r *http.Request) {
+	if !authorized(w, r) {
+		return
+	}
+	var channel youtube.Channel
+	channel.ID = YouTubeChannelID
+	channel.Snippet.Title = YouTubeUserName
+	writeJSON(w, http.StatusOK, map[string]interface{}{"items": []youtube.Channel{channel}})
+}
+
+func youtubeArtistChannelID(name string) string {
+	return "UCmock" + artistSlug(name)
+}
+
+func (p *Provider) youtubeSubscriptions(w http.ResponseWriter, r *http.Request) {
+	if !authorized(w, r) {
+		return
+	}
+	p.mu.Lock()
+	names := slices.Clone(p.subscribed)
+	p.mu.Unlock()
+
+	if limit := queryInt(r, "maxResults", 5); len(names) > limit {
+		names = names[:limit]
+	}
+	items := []youtube.Subscription{}
+	for _, name := range names {
+		var subscription youtube.Subscription
+		subscription.ID = "mocksub" + artistSlug(name)
+		subscription.Snippet.Title = name + " - Topic"
+		subscription.Snippet.ResourceID.Kind = "youtube#channel"
+		subscription.Snippet.ResourceID.ChannelID = youtubeArtistChannelID(name)
+		items = append(items, subscription)
+	}
+	writeJSON(w, http.StatusOK, map[string]interface{}{"items": items})
+}
+
+func (p *Provider) youtubeSubscribe(w http.ResponseWriter, r *http.Request) {
+	if !authorized(w, r) {
+		return
+	}
+	var body youtube.Subscription
+	if err := json.NewDecoder(r.Body).Decode(&body); err != nil {
+		writeJSON(w, http.StatusBadRequest, map[string]interface{}{"error": "invalid body"})
+		return
+	}
+
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	for _, name := range p.artists("", 0) {
+		if youtubeArtistChannelID(name) != body.Snippet.ResourceID.ChannelID {
+			continue
+		}
+		if slices.Contains(p.subscribed, name) {
+			writeJSON(w, http.StatusBadRequest, map[string]interface{}{"error": map[string]interface{}{"code": 400, "message": "subscriptionDuplicate"}})
+			return
+		}
+		p.subscribed = append(p.subscribed, name)
+		body.ID = "mocksub" + artistSlug(name)
+		body.Snippet.Title = name + " - Topic"
+		writeJSON(w, http.StatusOK, body)
+		return
+	}
+	notFound(w)
+}
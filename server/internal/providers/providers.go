@@ -0,0 +1,114 @@
+package providers
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"log"
+	"net/http"
+)
+
+// Doer sends HTTP requests. *http.Client and the rate-limited provider
+// clients both satisfy it, and tests can substitute their own.
+type Doer interface {
+	Do(req *http.Request) (*http.Response, error)
+}
+
+// Observer is told the outcome of every provider call: whether it was
+// throttled, and whether it failed, either before a response arrived or with
+// an unexpected status
+type Observer func(rateLimited, failed bool)
+
+// StatusError is a provider response with an unexpected HTTP status
+type StatusError struct {
+	Service string
+	Op      string
+	Status  int
+	Body    string
+}
+
+func (e *StatusError) Error() string {
+	return fmt.Sprintf("%s %s returned status: %d", e.Service, e.Op, e.Status)
+}
+
+// Request describes one JSON API call
+type Request struct {
+	Service string
+	Op      string // short description used in errors and logs, e.g. "search"
+	Method  string
+	URL     string
+	Auth    func(req *http.Request)
+	Body    interface{} // encoded as JSON when set
+	OK      []int       // accepted statuses, 200 when empty
+}
+
+type opContextKey struct{}
+
+// OpFromContext returns the Op of the call an outgoing request was made for,
+// "" for requests not sent through Do
+func OpFromContext(ctx context.Context) string {
+	op, _ := ctx.Value(opContextKey{}).(string)
+	return op
+}
+
+// Do sends a request and decodes a JSON response into out, if out is set.
+// Unexpected statuses are returned as *StatusError with the response body.
+func Do(ctx context.Context, client Doer, observe Observer, r Request, out interface{}) error {
+	var body io.Reader
+	if r.Body != nil {
+		data, err := json.Marshal(r.Body)
+		if err != nil {
+			return err
+		}
+		body = bytes.NewReader(data)
+	}
+
+	req, err := http.NewRequestWithContext(context.WithValue(ctx, opContextKey{}, r.Op), r.Method, r.URL, body)
+	if err != nil {
+		return err
+	}
+	if r.Auth != nil {
+		r.Auth(req)
+	}
+	if r.Body != nil {
+		req.Header.Set("Content-Type", "application/json")
+	}
+
+	resp, err := client.Do(req)
+	if err != nil {
+		if observe != nil {
+			observe(false, true)
+		}
+		return err
+	}
+	defer resp.Body.Close()
+	ok := statusOK(resp.StatusCode, r.OK)
+	if observe != nil {
+		observe(resp.StatusCode == http.StatusTooManyRequests, !ok)
+	}
+
+	if !ok {
+		respBody, _ := io.ReadAll(resp.Body)
+		log.Printf("%s %s error: %d, body: %s", r.Service, r.Op, resp.StatusCode, string(respBody))
+		return &StatusError{Service: r.Service, Op: r.Op, Status: resp.StatusCode, Body: string(respBody)}
+	}
+
+	if out == nil {
+		return nil
+	}
+	return json.NewDecoder(resp.Body).Decode(out)
+}
+
+func statusOK(status int, ok []int) bool {
+	if len(ok) == 0 {
+		return status == http.StatusOK
+	}
+	for _, s := range ok {
+		if status == s {
+			return true
+		}
+	}
+	return false
+}
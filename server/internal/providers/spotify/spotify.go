@@ -0,0 +1,379 @@
+package spotify
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"net/url"
+	"strings"
+	"time"
+
+	"server/internal/providers"
+)
+
+const (
+	DefaultBaseURL = "https://api.spotify.com/v1"
+	RevocationURL  = "https://accounts.spotify.com/api/token"
+)
+
+// Client calls the Spotify Web API with a caller-supplied access token
+type Client struct {
+	HTTP    providers.Doer
+	BaseURL string
+	Observe providers.Observer
+}
+
+// New returns a client for the public Spotify API sending requests through httpClient
+func New(httpClient providers.Doer, observe providers.Observer) *Client {
+	return &Client{HTTP: httpClient, BaseURL: DefaultBaseURL, Observe: observe}
+}
+
+type Artist struct {
+	Name string `json:"name"`
+}
+
+type Album struct {
+	Name string `json:"name"`
+}
+
+type ExternalIDs struct {
+	ISRC string `json:"isrc"`
+}
+
+// Show is the podcast an episode belongs to
+type Show struct {
+	Name string `json:"name"`
+}
+
+// Track is a playlist item. Playlists may also hold podcast episodes, which
+// have Type "episode" and a Show instead of artists and an album.
+type Track struct {
+	ID          string      `json:"id"`
+	Type        string      `json:"type"` // "track" or "episode"
+	Name        string      `json:"name"`
+	DurationMS  int         `json:"duration_ms"`
+	Explicit    bool        `json:"explicit"`
+	IsLocal     bool        `json:"is_local"` // a file from the user's device, with no Spotify ID
+	PreviewURL  string      `json:"preview_url"`
+	Artists     []Artist    `json:"artists"`
+	Album       Album       `json:"album"`
+	ExternalIDs ExternalIDs `json:"external_ids"`
+	Show        *Show       `json:"show"`        // episodes only
+	IsPlayable  *bool       `json:"is_playable"` // only present when a market is given
+	LinkedFrom  *struct {
+		ID string `json:"id"`
+	} `json:"linked_from"` // set when the track was relinked for the market
+}
+
+// FirstArtist returns the primary artist's name, or the show of an episode,
+// or "" when none is listed
+func (t Track) FirstArtist() string {
+	if t.Show != nil {
+		return t.Show.Name
+	}
+	if len(t.Artists) == 0 {
+		return ""
+	}
+	return t.Artists[0].Name
+}
+
+// ArtistProfile is an artist as listed by artist searches and the user's follows
+type ArtistProfile struct {
+	ID   string `json:"id"`
+	Name string `json:"name"`
+}
+
+type Image struct {
+	URL string `json:"url"`
+}
+
+type Owner struct {
+	ID          string `json:"id"`
+	DisplayName string `json:"display_name"`
+}
+
+type PlaylistItem struct {
+	AddedAt time.Time `json:"added_at"`
+	AddedBy *Owner    `json:"added_by"` // null for very old items
+	Track   Track     `json:"track"`
+}
+
+type Playlist struct {
+	ID            string  `json:"id"`
+	Name          string  `json:"name"`
+	Description   string  `json:"description"`
+	Public        bool    `json:"public"`
+	Collaborative bool    `json:"collaborative"`
+	SnapshotID    string  `json:"snapshot_id"` // changes whenever the playlist is modified
+	Owner         Owner   `json:"owner"`
+	Images        []Image `json:"images"`
+	Tracks        struct {
+		Total int            `json:"total"`
+		Items []PlaylistItem `json:"items"`
+		Next  string         `json:"next"` // set when more items follow the first page
+	} `json:"tracks"`
+}
+
+type User struct {
+	ID          string `json:"id"`
+	DisplayName string `json:"display_name"`
+	Email       string `json:"email"`
+	Country     string `json:"country"`
+}
+
+type AudioFeatures struct {
+	ID    string  `json:"id"`
+	Tempo float64 `json:"tempo"`
+}
+
+// CreatePlaylistRequest is the body of a playlist creation
+type CreatePlaylistRequest struct {
+	Name          string `json:"name"`
+	Description   string `json:"description"`
+	Public        bool   `json:"public"`
+	Collaborative bool   `json:"collaborative"`
+}
+
+func (c *Client) call(ctx context.Context, token, op, method, path string, body, out interface{}, ok ...int) error {
+	return providers.Do(ctx, c.HTTP, c.Observe, providers.Request{
+		Service: "spotify",
+		Op:      op,
+		Method:  method,
+		URL:     c.BaseURL + path,
+		Auth:    func(req *http.Request) { req.Header.Set("Authorization", "Bearer "+token) },
+		Body:    body,
+		OK:      ok,
+	}, out)
+}
+
+// CurrentUser returns the profile of the account the token belongs to
+func (c *Client) CurrentUser(ctx context.Context, token string) (User, error) {
+	var user User
+	err := c.call(ctx, token, "profile", "GET", "/me", nil, &user)
+	return user, err
+}
+
+// MyPlaylists lists every playlist the user owns or follows, limit per page
+func (c *Client) MyPlaylists(ctx context.Context, token string, limit int) ([]Playlist, error) {
+	var playlists []Playlist
+	for offset := 0; ; {
+		var page struct {
+			Items []Playlist `json:"items"`
+			Next  string     `json:"next"`
+		}
+		if err := c.call(ctx, token, "playlists", "GET", fmt.Sprintf("/me/playlists?limit=%d&offset=%d", limit, offset), nil, &page); err != nil {
+			return nil, err
+		}
+		playlists = append(playlists, page.Items...)
+		if page.Next == "" || len(page.Items) == 0 {
+			return playlists, nil
+		}
+		offset += len(page.Items)
+	}
+}
+
+// Playlist fetches a playlist with its first page of tracks. fields, if set,
+// limits the response to the given Spotify field filter.
+func (c *Client) Playlist(ctx context.Context, token, playlistID, fields string) (Playlist, error) {
+	// Episodes are requested in their own format so they can be told apart from tracks
+	query := url.Values{"additional_types": {"track,episode"}}
+	if fields != "" {
+		query.Set("fields", fields)
+	}
+	path := "/playlists/" + playlistID + "?" + query.Encode()
+	var playlist Playlist
+	err := c.call(ctx, token, "playlist", "GET", path, nil, &playlist)
+	return playlist, err
+}
+
+// PlaylistTracks returns a playlist's items from offset to the end, fetching limit per page
+func (c *Client) PlaylistTracks(ctx context.Context, token, playlistID string, offset, limit int) ([]PlaylistItem, error) {
+	var items []PlaylistItem
+	for {
+		var page struct {
+			Items []PlaylistItem `json:"items"`
+			Next  string         `json:"next"`
+		}
+		path := fmt.Sprintf("/playlists/%s/tracks?additional_types=track,episode&limit=%d&offset=%d", playlistID, limit, offset)
+		if err := c.call(ctx, token, "playlist tracks", "GET", path, nil, &page); err != nil {
+			return nil, err
+		}
+		items = append(items, page.Items...)
+		if page.Next == "" || len(page.Items) == 0 {
+			return items, nil
+		}
+		offset += len(page.Items)
+	}
+}
+
+// SearchTracks runs a track search. With a market Spotify relinks tracks to
+// versions playable there and reports is_playable.
+func (c *Client) SearchTracks(ctx context.Context, token, query, market string, limit int) ([]Track, error) {
+	path := fmt.Sprintf("/search?q=%s&type=track&limit=%d", url.QueryEscape(query), limit)
+	if market != "" {
+		path += "&market=" + market
+	}
+	var result struct {
+		Tracks struct {
+			Items []Track `json:"items"`
+		} `json:"tracks"`
+	}
+	err := c.call(ctx, token, "search", "GET", path, nil, &result)
+	return result.Tracks.Items, err
+}
+
+// SavedTracks returns all of the user's Liked Songs, most recently saved first,
+// fetching limit per page
+func (c *Client) SavedTracks(ctx context.Context, token string, limit int) ([]PlaylistItem, error) {
+	var items []PlaylistItem
+	for offset := 0; ; {
+		var page struct {
+			Items []PlaylistItem `json:"items"`
+			Next  string         `json:"next"`
+		}
+		if err := c.call(ctx, token, "saved tracks", "GET", fmt.Sprintf("/me/tracks?limit=%d&offset=%d", limit, offset), nil, &page); err != nil {
+			return nil, err
+		}
+		items = append(items, page.Items...)
+		if page.Next == "" || len(page.Items) == 0 {
+			return items, nil
+		}
+		offset += len(page.Items)
+	}
+}
+
+// FollowedArtists returns all the artists the user follows, fetching limit per page
+func (c *Client) FollowedArtists(ctx context.Context, token string, limit int) ([]ArtistProfile, error) {
+	var artists []ArtistProfile
+	after := ""
+	for {
+		var result struct {
+			Artists struct {
+				Items   []ArtistProfile `json:"items"`
+				Next    string          `json:"next"`
+				Cursors struct {
+					After string `json:"after"`
+				} `json:"cursors"`
+			} `json:"artists"`
+		}
+		path := fmt.Sprintf("/me/following?type=artist&limit=%d", limit)
+		if after != "" {
+			path += "&after=" + url.QueryEscape(after)
+		}
+		if err := c.call(ctx, token, "followed artists", "GET", path, nil, &result); err != nil {
+			return nil, err
+		}
+		artists = append(artists, result.Artists.Items...)
+		if result.Artists.Next == "" || result.Artists.Cursors.After == "" {
+			return artists, nil
+		}
+		after = result.Artists.Cursors.After
+	}
+}
+
+// SearchArtists runs an artist search
+func (c *Client) SearchArtists(ctx context.Context, token, query string, limit int) ([]ArtistProfile, error) {
+	path := fmt.Sprintf("/search?q=%s&type=artist&limit=%d", url.QueryEscape(query), limit)
+	var result struct {
+		Artists struct {
+			Items []ArtistProfile `json:"items"`
+		} `json:"artists"`
+	}
+	err := c.call(ctx, token, "search", "GET", path, nil, &result)
+	return result.Artists.Items, err
+}
+
+// FollowArtists follows up to 50 artists by ID
+func (c *Client) FollowArtists(ctx context.Context, token string, ids []string) error {
+	body := map[string]interface{}{"ids": ids}
+	return c.call(ctx, token, "follow artists", "PUT", "/me/following?type=artist", body, nil, http.StatusNoContent, http.StatusOK)
+}
+
+// Tracks looks up to 50 tracks by ID. Results are in request order, nil for unknown IDs.
+func (c *Client) Tracks(ctx context.Context, token string, ids []string, market string) ([]*Track, error) {
+	path := "/tracks?ids=" + strings.Join(ids, ",")
+	if market != "" {
+		path += "&market=" + market
+	}
+	var result struct {
+		Tracks []*Track `json:"tracks"`
+	}
+	err := c.call(ctx, token, "tracks", "GET", path, nil, &result)
+	return result.Tracks, err
+}
+
+// AudioFeatures looks up the audio features of up to 100 tracks, nil for unknown IDs
+func (c *Client) AudioFeatures(ctx context.Context, token string, ids []string) ([]*AudioFeatures, error) {
+	var result struct {
+		AudioFeatures []*AudioFeatures `json:"audio_features"`
+	}
+	err := c.call(ctx, token, "audio features", "GET", "/audio-features?ids="+url.QueryEscape(strings.Join(ids, ",")), nil, &result)
+	return result.AudioFeatures, err
+}
+
+// CreatePlaylist creates a playlist in a user's library
+func (c *Client) CreatePlaylist(ctx context.Context, token, userID string, req CreatePlaylistRequest) (Playlist, error) {
+	var playlist Playlist
+	err := c.call(ctx, token, "playlist creation", "POST", "/users/"+userID+"/playlists", req, &playlist, http.StatusCreated, http.StatusOK)
+	return playlist, err
+}
+
+// AddTracks appends tracks, given as spotify:track: URIs, to a playlist
+func (c *Client) AddTracks(ctx context.Context, token, playlistID string, uris []string) error {
+	body := map[string]interface{}{"uris": uris}
+	return c.call(ctx, token, "add track", "POST", "/playlists/"+playlistID+"/tracks", body, nil, http.StatusCreated, http.StatusOK)
+}
+
+// ReorderTracks moves the track at rangeStart so it lands before the track
+// currently at insertBefore (len(playlist) to move it to the end)
+func (c *Client) ReorderTracks(ctx context.Context, token, playlistID string, rangeStart, insertBefore int) error {
+	body := map[string]interface{}{"range_start": rangeStart, "insert_before": insertBefore, "range_length": 1}
+	return c.call(ctx, token, "reorder tracks", "PUT", "/playlists/"+playlistID+"/tracks", body, nil)
+}
+
+// RemoveTracks removes every occurrence of the given spotify:track: URIs from a playlist
+func (c *Client) RemoveTracks(ctx context.Context, token, playlistID string, uris []string) error {
+	tracks := make([]map[string]string, len(uris))
+	for i, uri := range uris {
+		tracks[i] = map[string]string{"uri": uri}
+	}
+	body := map[string]interface{}{"tracks": tracks}
+	return c.call(ctx, token, "remove tracks", "DELETE", "/playlists/"+playlistID+"/tracks", body, nil)
+}
+
+// UpdatePlaylistDescription replaces a playlist's description
+func (c *Client) UpdatePlaylistDescription(ctx context.Context, token, playlistID, description string) error {
+	body := map[string]interface{}{"description": description}
+	return c.call(ctx, token, "playlist update", "PUT", "/playlists/"+playlistID, body, nil)
+}
+
+// Play starts playing a context, e.g. a playlist URI, on the user's active device
+func (c *Client) Play(ctx context.Context, token, contextURI string) error {
+	body := map[string]interface{}{"context_uri": contextURI}
+	return c.call(ctx, token, "playback", "PUT", "/me/player/play", body, nil, http.StatusNoContent, http.StatusOK, http.StatusAccepted)
+}
+
+// RevokeToken revokes an access token with the app's client credentials
+func RevokeToken(ctx context.Context, client providers.Doer, clientID, clientSecret, token string) error {
+	data := url.Values{}
+	data.Set("token", token)
+	data.Set("token_type_hint", "access_token")
+
+	req, err := http.NewRequestWithContext(ctx, "POST", RevocationURL, strings.NewReader(data.Encode()))
+	if err != nil {
+		return err
+	}
+	req.SetBasicAuth(clientID, clientSecret)
+	req.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+
+	resp, err := client.Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		return &providers.StatusError{Service: "spotify", Op: "revocation", Status: resp.StatusCode}
+	}
+	return nil
+}
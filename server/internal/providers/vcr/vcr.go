@@ -0,0 +1,202 @@
+// Package vcr records provider API traffic to a cassette file and replays it,
+// so pagination, error handling and matching can be regression tested against
+// real responses without network access or credentials.
+package vcr
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"io"
+	"log"
+	"net/http"
+	"net/url"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+
+	"server/internal/providers"
+)
+
+// Interaction is one recorded request and its response. Credentials are never
+// stored: the Authorization header is dropped and API keys are cut from URLs.
+type Interaction struct {
+	Method       string            `json:"method"`
+	URL          string            `json:"url"`
+	Body         string            `json:"body,omitempty"`
+	Status       int               `json:"status"`
+	Header       map[string]string `json:"header,omitempty"`
+	ResponseBody string            `json:"response_body"`
+}
+
+// Cassette holds interactions and the file they are saved to
+type Cassette struct {
+	mu           sync.Mutex
+	path         string
+	Interactions []Interaction `json:"interactions"`
+	replayed     map[int]bool
+}
+
+// recordedHeaders are the response headers worth keeping for replay
+var recordedHeaders = []string{"Content-Type", "Retry-After"}
+
+// New starts an empty cassette that will be written to path
+func New(path string) *Cassette {
+	return &Cassette{path: path}
+}
+
+// Load reads a recorded cassette
+func Load(path string) (*Cassette, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+	c := &Cassette{path: path}
+	if err := json.Unmarshal(data, c); err != nil {
+		return nil, fmt.Errorf("invalid cassette %s: %w", path, err)
+	}
+	return c, nil
+}
+
+// Record returns a client that sends requests through next and saves every
+// exchange to the cassette
+func (c *Cassette) Record(next providers.Doer) providers.Doer {
+	return doerFunc(func(req *http.Request) (*http.Response, error) {
+		body, err := readBody(&req.Body)
+		if err != nil {
+			return nil, err
+		}
+
+		resp, err := next.Do(req)
+		if err != nil {
+			return nil, err
+		}
+		respBody, err := readBody(&resp.Body)
+		if err != nil {
+			return nil, err
+		}
+
+		interaction := Interaction{
+			Method:       req.Method,
+			URL:          scrubURL(req.URL),
+			Body:         body,
+			Status:       resp.StatusCode,
+			ResponseBody: respBody,
+		}
+		for _, name := range recordedHeaders {
+			if v := resp.Header.Get(name); v != "" {
+				if interaction.Header == nil {
+					interaction.Header = make(map[string]string)
+				}
+				interaction.Header[name] = v
+			}
+		}
+
+		c.mu.Lock()
+		defer c.mu.Unlock()
+		c.Interactions = append(c.Interactions, interaction)
+		if err := c.save(); err != nil {
+			log.Printf("vcr: failed to save cassette %s: %v", c.path, err)
+		}
+		return resp, nil
+	})
+}
+
+// Replay returns a client that answers requests from the cassette. Matching
+// requests get their recorded responses in order, the last one repeating once
+// they run out; a request that was never recorded fails.
+func (c *Cassette) Replay() providers.Doer {
+	return doerFunc(func(req *http.Request) (*http.Response, error) {
+		if err := req.Context().Err(); err != nil {
+			return nil, err
+		}
+		body, err := readBody(&req.Body)
+		if err != nil {
+			return nil, err
+		}
+		key := scrubURL(req.URL)
+
+		c.mu.Lock()
+		defer c.mu.Unlock()
+		if c.replayed == nil {
+			c.replayed = make(map[int]bool)
+		}
+
+		found := -1
+		for i, in := range c.Interactions {
+			if in.Method != req.Method || in.URL != key || in.Body != body {
+				continue
+			}
+			found = i
+			if !c.replayed[i] {
+				break
+			}
+		}
+		if found == -1 {
+			return nil, fmt.Errorf("vcr: no recorded response for %s %s", req.Method, key)
+		}
+		c.replayed[found] = true
+
+		in := c.Interactions[found]
+		header := make(http.Header)
+		for name, v := range in.Header {
+			header.Set(name, v)
+		}
+		return &http.Response{
+			Status:     fmt.Sprintf("%d %s", in.Status, http.StatusText(in.Status)),
+			StatusCode: in.Status,
+			Proto:      "HTTP/1.1",
+			ProtoMajor: 1,
+			ProtoMinor: 1,
+			Header:     header,
+			Body:       io.NopCloser(strings.NewReader(in.ResponseBody)),
+			Request:    req,
+		}, nil
+	})
+}
+
+// save writes the cassette; callers hold c.mu
+func (c *Cassette) save() error {
+	data, err := json.MarshalIndent(c, "", "  ")
+	if err != nil {
+		return err
+	}
+	if dir := filepath.Dir(c.path); dir != "" {
+		if err := os.MkdirAll(dir, 0o755); err != nil {
+			return err
+		}
+	}
+	tmp := c.path + ".tmp"
+	if err := os.WriteFile(tmp, data, 0o600); err != nil {
+		return err
+	}
+	return os.Rename(tmp, c.path)
+}
+
+// scrubURL is the URL an interaction is stored and matched under, without API keys
+func scrubURL(u *url.URL) string {
+	scrubbed := *u
+	q := scrubbed.Query()
+	q.Del("key")
+	scrubbed.RawQuery = q.Encode()
+	return scrubbed.String()
+}
+
+// readBody drains a body and puts an unread copy back
+func readBody(body *io.ReadCloser) (string, error) {
+	if *body == nil || *body == http.NoBody {
+		return "", nil
+	}
+	data, err := io.ReadAll(*body)
+	(*body).Close()
+	if err != nil {
+		return "", err
+	}
+	*body = io.NopCloser(bytes.NewReader(data))
+	return string(data), nil
+}
+
+type doerFunc func(req *http.Request) (*http.Response, error)
+
+func (f doerFunc) Do(req *http.Request) (*http.Response, error) { return f(req) }
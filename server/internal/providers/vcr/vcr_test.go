@@ -0,0 +1,106 @@
+package vcr
+
+import (
+	"context"
+	"errors"
+	"io"
+	"net/http"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"server/internal/providers"
+	"server/internal/providers/spotify"
+	"server/internal/providers/youtube"
+)
+
+func TestReplayCassette(t *testing.T) {
+	cassette, err := Load("testdata/providers.json")
+	if err != nil {
+		t.Fatal(err)
+	}
+	ctx := context.Background()
+	sp := spotify.New(cassette.Replay(), nil)
+
+	playlists, err := sp.MyPlaylists(ctx, "any-token", 2)
+	if err != nil {
+		t.Fatalf("MyPlaylists: %v", err)
+	}
+	var names []string
+	for _, playlist := range playlists {
+		names = append(names, playlist.Name)
+	}
+	if got := strings.Join(names, ", "); got != "Road Trip, Late Night, Focus" {
+		t.Errorf("MyPlaylists replayed %q; want both pages", got)
+	}
+
+	_, err = sp.Playlist(ctx, "any-token", "0000000000000000000000", "")
+	var statusErr *providers.StatusError
+	if !errors.As(err, &statusErr) || statusErr.Status != http.StatusNotFound {
+		t.Errorf("Playlist of a missing playlist returned %v; want a 404 StatusError", err)
+	}
+
+	// The key was scrubbed when recording, so a different one matches too
+	yt := youtube.New(cassette.Replay(), nil)
+	found, err := yt.Playlists(ctx, youtube.APIKeyPrefix+"another-key", "PLx0sYbCqOb8TBPRdmBHs5Iftvv9TPboYG")
+	if err != nil {
+		t.Fatalf("Playlists: %v", err)
+	}
+	if len(found) != 1 || found[0].Snippet.Title != "Summer Mix" {
+		t.Errorf("Playlists replayed %+v; want Summer Mix", found)
+	}
+
+	if _, err := sp.CurrentUser(ctx, "any-token"); err == nil {
+		t.Error("a request that was never recorded succeeded")
+	}
+}
+
+// upstream answers every request and remembers the last one it saw
+type upstream struct {
+	last *http.Request
+}
+
+func (u *upstream) Do(req *http.Request) (*http.Response, error) {
+	u.last = req
+	return &http.Response{
+		StatusCode: http.StatusOK,
+		Header: http.Header{
+			"Content-Type": {"application/json"},
+			"Set-Cookie":   {"session=upstream-session"},
+		},
+		Body: io.NopCloser(strings.NewReader(`{"items":[]}`)),
+	}, nil
+}
+
+func TestRecordScrubsCredentials(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "cassette.json")
+	cassette := New(path)
+	next := &upstream{}
+	ctx := context.Background()
+
+	if _, err := youtube.New(cassette.Record(next), nil).Playlists(ctx, youtube.APIKeyPrefix+"secret-api-key", "PL1"); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := spotify.New(cassette.Record(next), nil).MyPlaylists(ctx, "secret-user-token", 50); err != nil {
+		t.Fatal(err)
+	}
+
+	// The live request still carries its credentials
+	if got := next.last.Header.Get("Authorization"); got != "Bearer secret-user-token" {
+		t.Errorf("upstream saw Authorization %q", got)
+	}
+
+	data, err := os.ReadFile(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, secret := range []string{"secret-api-key", "secret-user-token", "Authorization", "upstream-session", "key="} {
+		if strings.Contains(string(data), secret) {
+			t.Errorf("cassette contains %q:\n%s", secret, data)
+		}
+	}
+	if !strings.Contains(string(data), "Content-Type") {
+		t.Errorf("cassette lost the Content-Type header:\n%s", data)
+	}
+}
@@ -0,0 +1,412 @@
+package youtube
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"net/url"
+	"strings"
+	"time"
+
+	"server/internal/providers"
+)
+
+const (
+	DefaultBaseURL = "https://www.googleapis.com/youtube/v3"
+	RevocationURL  = "https://oauth2.googleapis.com/revoke"
+
+	// APIKeyPrefix marks a "token" that is really an app API key, which the
+	// Data API takes as a query parameter rather than a bearer token
+	APIKeyPrefix = "key:"
+
+	// MusicCategoryID is the video category YouTube files music under
+	MusicCategoryID = "10"
+)
+
+// Client calls the YouTube Data API with a caller-supplied user token or API key
+type Client struct {
+	HTTP    providers.Doer
+	BaseURL string
+	Observe providers.Observer
+}
+
+// New returns a client for the public YouTube Data API sending requests through httpClient
+func New(httpClient providers.Doer, observe providers.Observer) *Client {
+	return &Client{HTTP: httpClient, BaseURL: DefaultBaseURL, Observe: observe}
+}
+
+type Thumbnails struct {
+	Default struct {
+		URL string `json:"url"`
+	} `json:"default"`
+}
+
+type PlaylistSnippet struct {
+	Title        string      `json:"title"`
+	Description  string      `json:"description"`
+	ChannelID    string      `json:"channelId,omitempty"`
+	ChannelTitle string      `json:"channelTitle,omitempty"`
+	Thumbnails   *Thumbnails `json:"thumbnails,omitempty"`
+}
+
+type Playlist struct {
+	ID             string          `json:"id,omitempty"`
+	Snippet        PlaylistSnippet `json:"snippet"`
+	ContentDetails *struct {
+		ItemCount int `json:"itemCount"`
+	} `json:"contentDetails,omitempty"`
+	Status *PlaylistStatus `json:"status,omitempty"`
+}
+
+type PlaylistStatus struct {
+	PrivacyStatus string `json:"privacyStatus"`
+}
+
+type ResourceID struct {
+	Kind    string `json:"kind"`
+	VideoID string `json:"videoId"`
+}
+
+type PlaylistItem struct {
+	ID      string `json:"id"` // identifies this entry, a video may be in a playlist more than once
+	Snippet struct {
+		PlaylistID             string     `json:"playlistId"`
+		Title                  string     `json:"title"`
+		PublishedAt            time.Time  `json:"publishedAt"` // when the video was added to the playlist
+		ChannelID              string     `json:"channelId"`   // channel of the user who added the video
+		VideoOwnerChannelTitle string     `json:"videoOwnerChannelTitle"`
+		ResourceID             ResourceID `json:"resourceId"`
+		Position               int        `json:"position"`
+	} `json:"snippet"`
+}
+
+type SearchResult struct {
+	ID struct {
+		VideoID string `json:"videoId"`
+	} `json:"id"`
+	Snippet struct {
+		Title        string `json:"title"`
+		Description  string `json:"description"`
+		ChannelTitle string `json:"channelTitle"`
+	} `json:"snippet"`
+}
+
+// SearchRequest holds the parameters of a video search
+type SearchRequest struct {
+	Query      string
+	MaxResults int
+	CategoryID string // e.g. MusicCategoryID
+	RegionCode string // ranks results for a region, "" for none
+}
+
+type Video struct {
+	ID      string `json:"id"`
+	Snippet struct {
+		Title        string `json:"title"`
+		CategoryID   string `json:"categoryId"`
+		ChannelID    string `json:"channelId"`
+		ChannelTitle string `json:"channelTitle"`
+	} `json:"snippet"`
+	ContentDetails struct {
+		Duration          string `json:"duration"` // ISO 8601, e.g. "PT4M13S"
+		RegionRestriction struct {
+			Allowed []string `json:"allowed"`
+			Blocked []string `json:"blocked"`
+		} `json:"regionRestriction"`
+		ContentRating struct {
+			YTRating string `json:"ytRating"` // "ytAgeRestricted" for age-restricted videos
+		} `json:"contentRating"`
+	} `json:"contentDetails"`
+}
+
+// DurationMS returns the video's length in milliseconds, 0 if unknown
+func (v Video) DurationMS() int {
+	rest, ok := strings.CutPrefix(v.ContentDetails.Duration, "P")
+	if !ok {
+		return 0
+	}
+	seconds := 0
+	inTime := false
+	n := 0
+	for _, r := range rest {
+		switch {
+		case r >= '0' && r <= '9':
+			n = n*10 + int(r-'0')
+		case r == 'T':
+			inTime = true
+		case r == 'D':
+			seconds += n * 86400
+			n = 0
+		case r == 'H' && inTime:
+			seconds += n * 3600
+			n = 0
+		case r == 'M' && inTime:
+			seconds += n * 60
+			n = 0
+		case r == 'S' && inTime:
+			seconds += n
+			n = 0
+		default:
+			// Weeks, months and years never occur for videos
+			return 0
+		}
+	}
+	return seconds * 1000
+}
+
+type Channel struct {
+	ID      string `json:"id"`
+	Snippet struct {
+		Title string `json:"title"`
+	} `json:"snippet"`
+}
+
+// Subscription is a channel the user subscribes to
+type Subscription struct {
+	ID      string `json:"id"`
+	Snippet struct {
+		Title      string `json:"title"`
+		ResourceID struct {
+			Kind      string `json:"kind"`
+			ChannelID string `json:"channelId"`
+		} `json:"resourceId"`
+	} `json:"snippet"`
+}
+
+// setAuth authorizes a request with either a user token or an app API key
+func setAuth(req *http.Request, token string) {
+	if key, ok := strings.CutPrefix(token, APIKeyPrefix); ok {
+		q := req.URL.Query()
+		q.Set("key", key)
+		req.URL.RawQuery = q.Encode()
+		return
+	}
+	req.Header.Set("Authorization", "Bearer "+token)
+}
+
+func (c *Client) call(ctx context.Context, token, op, method, path string, body, out interface{}, ok ...int) error {
+	return providers.Do(ctx, c.HTTP, c.Observe, providers.Request{
+		Service: "youtube",
+		Op:      op,
+		Method:  method,
+		URL:     c.BaseURL + path,
+		Auth:    func(req *http.Request) { setAuth(req, token) },
+		Body:    body,
+		OK:      ok,
+	}, out)
+}
+
+// MyPlaylists lists every playlist of the token's channel, maxResults per page
+func (c *Client) MyPlaylists(ctx context.Context, token string, maxResults int) ([]Playlist, error) {
+	var playlists []Playlist
+	pageToken := ""
+	for {
+		var page struct {
+			Items         []Playlist `json:"items"`
+			NextPageToken string     `json:"nextPageToken"`
+		}
+		path := fmt.Sprintf("/playlists?part=snippet,contentDetails&mine=true&maxResults=%d", maxResults)
+		if pageToken != "" {
+			path += "&pageToken=" + url.QueryEscape(pageToken)
+		}
+		if err := c.call(ctx, token, "playlists", "GET", path, nil, &page); err != nil {
+			return nil, err
+		}
+		playlists = append(playlists, page.Items...)
+		if page.NextPageToken == "" {
+			return playlists, nil
+		}
+		pageToken = page.NextPageToken
+	}
+}
+
+// Playlists looks playlists up by ID. Private playlists of other channels are omitted.
+func (c *Client) Playlists(ctx context.Context, token string, ids ...string) ([]Playlist, error) {
+	var page struct {
+		Items []Playlist `json:"items"`
+	}
+	err := c.call(ctx, token, "playlists", "GET", "/playlists?part=snippet,status&id="+url.QueryEscape(strings.Join(ids, ",")), nil, &page)
+	return page.Items, err
+}
+
+// PlaylistItems returns all of a playlist's items, fetching maxResults per
+// page, along with the item count YouTube reports for the playlist
+func (c *Client) PlaylistItems(ctx context.Context, token, playlistID string, maxResults int) ([]PlaylistItem, int, error) {
+	var items []PlaylistItem
+	pageToken := ""
+	for {
+		var page struct {
+			Items         []PlaylistItem `json:"items"`
+			NextPageToken string         `json:"nextPageToken"`
+			PageInfo      struct {
+				TotalResults int `json:"totalResults"`
+			} `json:"pageInfo"`
+		}
+		path := fmt.Sprintf("/playlistItems?part=snippet,contentDetails&playlistId=%s&maxResults=%d", url.QueryEscape(playlistID), maxResults)
+		if pageToken != "" {
+			path += "&pageToken=" + url.QueryEscape(pageToken)
+		}
+		if err := c.call(ctx, token, "playlist items", "GET", path, nil, &page); err != nil {
+			return nil, 0, err
+		}
+		items = append(items, page.Items...)
+		if page.NextPageToken == "" {
+			return items, page.PageInfo.TotalResults, nil
+		}
+		pageToken = page.NextPageToken
+	}
+}
+
+// Search finds videos matching a query
+func (c *Client) Search(ctx context.Context, token string, req SearchRequest) ([]SearchResult, error) {
+	path := fmt.Sprintf("/search?part=snippet&q=%s&type=video&maxResults=%d", url.QueryEscape(req.Query), req.MaxResults)
+	if req.CategoryID != "" {
+		path += "&videoCategoryId=" + req.CategoryID
+	}
+	if req.RegionCode != "" {
+		path += "&regionCode=" + req.RegionCode
+	}
+	var page struct {
+		Items []SearchResult `json:"items"`
+	}
+	err := c.call(ctx, token, "search", "GET", path, nil, &page)
+	return page.Items, err
+}
+
+// SearchChannels finds channels matching a query
+func (c *Client) SearchChannels(ctx context.Context, token, query string, maxResults int) ([]Channel, error) {
+	path := fmt.Sprintf("/search?part=snippet&q=%s&type=channel&maxResults=%d", url.QueryEscape(query), maxResults)
+	var page struct {
+		Items []struct {
+			ID struct {
+				ChannelID string `json:"channelId"`
+			} `json:"id"`
+			Snippet struct {
+				Title string `json:"title"`
+			} `json:"snippet"`
+		} `json:"items"`
+	}
+	if err := c.call(ctx, token, "search", "GET", path, nil, &page); err != nil {
+		return nil, err
+	}
+	channels := make([]Channel, 0, len(page.Items))
+	for _, item := range page.Items {
+		var channel Channel
+		channel.ID = item.ID.ChannelID
+		channel.Snippet.Title = item.Snippet.Title
+		channels = append(channels, channel)
+	}
+	return channels, nil
+}
+
+// Videos looks up to 50 videos up by ID with the given parts, e.g. "snippet"
+func (c *Client) Videos(ctx context.Context, token string, ids []string, parts string) ([]Video, error) {
+	var page struct {
+		Items []Video `json:"items"`
+	}
+	err := c.call(ctx, token, "videos", "GET", "/videos?part="+parts+"&id="+url.QueryEscape(strings.Join(ids, ",")), nil, &page)
+	return page.Items, err
+}
+
+// MyChannels lists the channels the token belongs to
+func (c *Client) MyChannels(ctx context.Context, token string) ([]Channel, error) {
+	var page struct {
+		Items []Channel `json:"items"`
+	}
+	err := c.call(ctx, token, "channels", "GET", "/channels?part=snippet&mine=true", nil, &page)
+	return page.Items, err
+}
+
+// MySubscriptions returns all the channels the token's channel subscribes to,
+// fetching maxResults per page
+func (c *Client) MySubscriptions(ctx context.Context, token string, maxResults int) ([]Subscription, error) {
+	var subscriptions []Subscription
+	pageToken := ""
+	for {
+		var page struct {
+			Items         []Subscription `json:"items"`
+			NextPageToken string         `json:"nextPageToken"`
+		}
+		path := fmt.Sprintf("/subscriptions?part=snippet&mine=true&maxResults=%d", maxResults)
+		if pageToken != "" {
+			path += "&pageToken=" + url.QueryEscape(pageToken)
+		}
+		if err := c.call(ctx, token, "subscriptions", "GET", path, nil, &page); err != nil {
+			return nil, err
+		}
+		subscriptions = append(subscriptions, page.Items...)
+		if page.NextPageToken == "" {
+			return subscriptions, nil
+		}
+		pageToken = page.NextPageToken
+	}
+}
+
+// InsertSubscription subscribes the token's channel to a channel
+func (c *Client) InsertSubscription(ctx context.Context, token, channelID string) error {
+	body := map[string]interface{}{
+		"snippet": map[string]interface{}{
+			"resourceId": map[string]string{"kind": "youtube#channel", "channelId": channelID},
+		},
+	}
+	return c.call(ctx, token, "subscribe", "POST", "/subscriptions?part=snippet", body, nil)
+}
+
+// InsertPlaylist creates a playlist on the token's channel
+func (c *Client) InsertPlaylist(ctx context.Context, token string, playlist Playlist) (Playlist, error) {
+	var created Playlist
+	err := c.call(ctx, token, "playlist creation", "POST", "/playlists?part=snippet,status", playlist, &created)
+	return created, err
+}
+
+// UpdatePlaylist replaces a playlist's snippet; the title must always be sent
+func (c *Client) UpdatePlaylist(ctx context.Context, token string, playlist Playlist) error {
+	return c.call(ctx, token, "playlist update", "PUT", "/playlists?part=snippet", playlist, nil)
+}
+
+// InsertPlaylistItem appends a video to a playlist
+func (c *Client) InsertPlaylistItem(ctx context.Context, token, playlistID, videoID string) error {
+	return c.call(ctx, token, "add track", "POST", "/playlistItems?part=snippet", addItemBody(playlistID, videoID), nil)
+}
+
+// MovePlaylistItem moves a playlist entry to position (zero-based)
+func (c *Client) MovePlaylistItem(ctx context.Context, token, itemID, playlistID, videoID string, position int) error {
+	body := addItemBody(playlistID, videoID)
+	body["id"] = itemID
+	body["snippet"].(map[string]interface{})["position"] = position
+	return c.call(ctx, token, "move track", "PUT", "/playlistItems?part=snippet", body, nil)
+}
+
+// DeletePlaylistItem removes one entry from a playlist
+func (c *Client) DeletePlaylistItem(ctx context.Context, token, itemID string) error {
+	return c.call(ctx, token, "remove track", "DELETE", "/playlistItems?id="+url.QueryEscape(itemID), nil, nil, http.StatusNoContent, http.StatusOK)
+}
+
+// addItemBody is the playlistItems.insert body; PlaylistItem would also send read-only fields
+func addItemBody(playlistID, videoID string) map[string]interface{} {
+	return map[string]interface{}{
+		"snippet": map[string]interface{}{
+			"playlistId": playlistID,
+			"resourceId": ResourceID{Kind: "youtube#video", VideoID: videoID},
+		},
+	}
+}
+
+// RevokeToken revokes a Google OAuth token
+func RevokeToken(ctx context.Context, client providers.Doer, token string) error {
+	req, err := http.NewRequestWithContext(ctx, "POST", RevocationURL+"?token="+url.QueryEscape(token), nil)
+	if err != nil {
+		return err
+	}
+
+	resp, err := client.Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		return &providers.StatusError{Service: "google", Op: "revocation", Status: resp.StatusCode}
+	}
+	return nil
+}
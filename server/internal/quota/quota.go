@@ -0,0 +1,100 @@
+package quota
+
+import (
+	"log"
+	"net/http"
+	"strings"
+	"time"
+
+	"server/internal/config"
+	"server/internal/database"
+
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+// YouTube Data API unit costs, see https://developers.google.com/youtube/v3/determine_quota_cost
+const (
+	YouTubeReadCost   = 1
+	YouTubeWriteCost  = 50
+	YouTubeSearchCost = 100
+)
+
+// YouTubeCost returns the quota units a YouTube Data API request consumes
+func YouTubeCost(method, path string) int {
+	resource := path[strings.LastIndex(path, "/")+1:]
+	if resource == "search" {
+		return YouTubeSearchCost
+	}
+	if method != http.MethodGet {
+		return YouTubeWriteCost
+	}
+	return YouTubeReadCost
+}
+
+// DailyLimit returns the daily unit budget for a service, 0 if it has none
+func DailyLimit(service string) int {
+	if service == "youtube" {
+		return config.Int("YOUTUBE_DAILY_QUOTA", 10000)
+	}
+	return 0
+}
+
+// pacific is where YouTube's quota day starts and ends
+var pacific = loadPacific()
+
+func loadPacific() *time.Location {
+	loc, err := time.LoadLocation("America/Los_Angeles")
+	if err != nil {
+		return time.FixedZone("PST", -8*60*60)
+	}
+	return loc
+}
+
+// Day returns the quota day t falls on
+func Day(t time.Time) string {
+	return t.In(pacific).Format("2006-01-02")
+}
+
+// NextReset returns when the quota day after t begins
+func NextReset(t time.Time) time.Time {
+	local := t.In(pacific)
+	return time.Date(local.Year(), local.Month(), local.Day()+1, 0, 0, 0, 0, pacific)
+}
+
+// Record adds units to today's usage of a service
+func Record(service string, units int) {
+	if database.DB == nil || units <= 0 {
+		return
+	}
+	usage := database.QuotaUsage{Service: service, Day: Day(time.Now()), Units: units}
+	err := database.DB.Clauses(clause.OnConflict{
+		Columns:   []clause.Column{{Name: "service"}, {Name: "day"}},
+		DoUpdates: clause.Assignments(map[string]interface{}{"units": gorm.Expr("quota_usages.units + ?", units)}),
+	}).Create(&usage).Error
+	if err != nil {
+		log.Printf("Failed to record %s quota usage: %v", service, err)
+	}
+}
+
+// Used returns the units a service has consumed today
+func Used(service string) int {
+	if database.DB == nil {
+		return 0
+	}
+	var usage database.QuotaUsage
+	database.DB.Where("service = ? AND day = ?", service, Day(time.Now())).Limit(1).Find(&usage)
+	return usage.Units
+}
+
+// Remaining returns the units left today, or -1 if the service has no daily budget
+func Remaining(service string) int {
+	limit := DailyLimit(service)
+	if limit <= 0 {
+		return -1
+	}
+	if remaining := limit - Used(service); remaining > 0 {
+		return remaining
+	}
+	return 0
+}
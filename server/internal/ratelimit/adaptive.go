@@ -0,0 +1,168 @@
+package ratelimit
+
+import (
+	"encoding/json"
+	"log"
+	"net/http"
+	"os"
+	"path/filepath"
+	"strconv"
+	"time"
+)
+
+// Number of consecutive successful responses before a throttled limit is raised again
+const recoveryStreak = 100
+
+// minLimit is the floor adaptive limiting lowers both the rate and the burst to
+const minLimit = 1
+
+// ObserveResponse adapts the service limit to the provider's feedback. Rate
+// limited responses halve the limit, a nearly exhausted quota reported through
+// X-RateLimit-Remaining lowers it gradually, and a long run of successful
+// responses slowly restores it up to the configured ceiling.
+func (rl *RateLimiter) ObserveResponse(service ServiceType, resp *http.Response, retryAfter time.Duration) {
+	rl.mutex.RLock()
+	current, exists := rl.limits[service]
+	ceiling := rl.ceilings[service]
+	rl.mutex.RUnlock()
+
+	if !exists {
+		return
+	}
+
+	if resp.StatusCode == http.StatusTooManyRequests || retryAfter > 0 {
+		log.Printf("Provider %s throttled us (retry after %v), lowering rate limit", service, retryAfter)
+		rl.applyLearnedLimit(service, serviceLimit{
+			RequestsPerSecond: max(minLimit, current.RequestsPerSecond/2),
+			Burst:             max(minLimit, current.Burst/2),
+		})
+		return
+	}
+
+	if remaining, limit, ok := quotaHeaders(resp.Header); ok && limit > 0 && remaining*10 <= limit {
+		log.Printf("Provider %s quota nearly exhausted (%d/%d remaining), lowering rate limit", service, remaining, limit)
+		rl.applyLearnedLimit(service, serviceLimit{
+			RequestsPerSecond: max(minLimit, current.RequestsPerSecond*3/4),
+			Burst:             max(minLimit, current.Burst*3/4),
+		})
+		return
+	}
+
+	if resp.StatusCode < 200 || resp.StatusCode >= 300 {
+		return
+	}
+
+	if current.RequestsPerSecond >= ceiling.RequestsPerSecond && current.Burst >= ceiling.Burst {
+		return
+	}
+
+	rl.mutex.Lock()
+	rl.streaks[service]++
+	recovered := rl.streaks[service] >= recoveryStreak
+	rl.mutex.Unlock()
+
+	if recovered {
+		rl.applyLearnedLimit(service, serviceLimit{
+			RequestsPerSecond: min(ceiling.RequestsPerSecond, current.RequestsPerSecond+1),
+			Burst:             min(ceiling.Burst, current.Burst+max(1, ceiling.Burst/ceiling.RequestsPerSecond)),
+		})
+	}
+}
+
+// applyLearnedLimit updates the limiter and persists the new value if it changed
+func (rl *RateLimiter) applyLearnedLimit(service ServiceType, limits serviceLimit) {
+	rl.mutex.RLock()
+	current := rl.limits[service]
+	rl.mutex.RUnlock()
+
+	if current == limits {
+		rl.mutex.Lock()
+		rl.streaks[service] = 0
+		rl.mutex.Unlock()
+		return
+	}
+
+	rl.SetCustomLimit(service, limits.RequestsPerSecond, limits.Burst)
+	rl.saveState()
+}
+
+// quotaHeaders extracts the remaining and total quota reported by the provider
+func quotaHeaders(header http.Header) (int, int, bool) {
+	remaining, err := strconv.Atoi(header.Get("X-RateLimit-Remaining"))
+	if err != nil {
+		return 0, 0, false
+	}
+
+	limit, err := strconv.Atoi(header.Get("X-RateLimit-Limit"))
+	if err != nil {
+		return remaining, 0, true
+	}
+
+	return remaining, limit, true
+}
+
+// loadState restores learned limits from RATE_LIMIT_STATE_FILE, clamped to the configured ceilings
+func (rl *RateLimiter) loadState() {
+	if rl.statePath == "" {
+		return
+	}
+
+	data, err := os.ReadFile(rl.statePath)
+	if err != nil {
+		if !os.IsNotExist(err) {
+			log.Printf("Failed to read rate limit state: %v", err)
+		}
+		return
+	}
+
+	var state map[ServiceType]serviceLimit
+	if err := json.Unmarshal(data, &state); err != nil {
+		log.Printf("Failed to parse rate limit state: %v", err)
+		return
+	}
+
+	for service, limits := range state {
+		ceiling, exists := rl.ceilings[service]
+		if !exists || limits.RequestsPerSecond <= 0 || limits.Burst <= 0 {
+			continue
+		}
+
+		rl.SetCustomLimit(service,
+			min(limits.RequestsPerSecond, ceiling.RequestsPerSecond),
+			min(limits.Burst, ceiling.Burst),
+		)
+	}
+}
+
+// saveState writes the currently applied limits to RATE_LIMIT_STATE_FILE
+func (rl *RateLimiter) saveState() {
+	if rl.statePath == "" {
+		return
+	}
+
+	rl.mutex.RLock()
+	data, err := json.MarshalIndent(rl.limits, "", "  ")
+	rl.mutex.RUnlock()
+	if err != nil {
+		log.Printf("Failed to encode rate limit state: %v", err)
+		return
+	}
+
+	rl.stateMutex.Lock()
+	defer rl.stateMutex.Unlock()
+
+	if err := os.MkdirAll(filepath.Dir(rl.statePath), 0o755); err != nil {
+		log.Printf("Failed to create rate limit state directory: %v", err)
+		return
+	}
+
+	// Write to a temporary file first so a crash never leaves a truncated state file
+	tmpPath := rl.statePath + ".tmp"
+	if err := os.WriteFile(tmpPath, data, 0o644); err != nil {
+		log.Printf("Failed to write rate limit state: %v", err)
+		return
+	}
+	if err := os.Rename(tmpPath, rl.statePath); err != nil {
+		log.Printf("Failed to save rate limit state: %v", err)
+	}
+}
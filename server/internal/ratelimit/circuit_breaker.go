@@ -0,0 +1,128 @@
+package ratelimit
+
+import (
+	"errors"
+	"fmt"
+	"log"
+	"os"
+	"strconv"
+	"sync"
+	"time"
+)
+
+// ErrProviderUnavailable is returned when a provider's circuit is open
+var ErrProviderUnavailable = errors.New("provider unavailable")
+
+type CircuitState string
+
+const (
+	CircuitClosed   CircuitState = "closed"
+	CircuitOpen     CircuitState = "open"
+	CircuitHalfOpen CircuitState = "half_open"
+)
+
+// CircuitBreaker stops calls to a provider after repeated server errors or
+// timeouts. Once the cooldown has passed a single probe request is let
+// through; its outcome decides whether the circuit closes again.
+type CircuitBreaker struct {
+	service   ServiceType
+	state     CircuitState
+	failures  int
+	openedAt  time.Time
+	probing   bool
+	threshold int
+	cooldown  time.Duration
+	mu        sync.Mutex
+}
+
+func NewCircuitBreaker(service ServiceType) *CircuitBreaker {
+	threshold := 5
+	if v, err := strconv.Atoi(os.Getenv("CIRCUIT_BREAKER_THRESHOLD")); err == nil && v > 0 {
+		threshold = v
+	}
+
+	cooldown := 30 * time.Second
+	if v, err := time.ParseDuration(os.Getenv("CIRCUIT_BREAKER_COOLDOWN")); err == nil && v > 0 {
+		cooldown = v
+	}
+
+	return &CircuitBreaker{
+		service:   service,
+		state:     CircuitClosed,
+		threshold: threshold,
+		cooldown:  cooldown,
+	}
+}
+
+// Allow reports whether a request may be sent to the provider
+func (cb *CircuitBreaker) Allow() error {
+	cb.mu.Lock()
+	defer cb.mu.Unlock()
+
+	switch cb.state {
+	case CircuitOpen:
+		if time.Since(cb.openedAt) < cb.cooldown {
+			return fmt.Errorf("%w: %s circuit is open", ErrProviderUnavailable, cb.service)
+		}
+		log.Printf("Circuit for %s is half-open, sending probe request", cb.service)
+		cb.state = CircuitHalfOpen
+		cb.probing = true
+		return nil
+	case CircuitHalfOpen:
+		if cb.probing {
+			return fmt.Errorf("%w: %s circuit is half-open", ErrProviderUnavailable, cb.service)
+		}
+		cb.probing = true
+		return nil
+	default:
+		return nil
+	}
+}
+
+// RecordSuccess closes the circuit after a healthy response
+func (cb *CircuitBreaker) RecordSuccess() {
+	cb.mu.Lock()
+	defer cb.mu.Unlock()
+
+	if cb.state != CircuitClosed {
+		log.Printf("Circuit for %s closed, provider recovered", cb.service)
+	}
+	cb.state = CircuitClosed
+	cb.failures = 0
+	cb.probing = false
+}
+
+// RecordFailure counts a server error or timeout and opens the circuit when the threshold is reached
+func (cb *CircuitBreaker) RecordFailure() {
+	cb.mu.Lock()
+	defer cb.mu.Unlock()
+
+	cb.failures++
+	cb.probing = false
+
+	if cb.state == CircuitHalfOpen || cb.failures >= cb.threshold {
+		if cb.state != CircuitOpen {
+			log.Printf("Circuit for %s opened after %d consecutive failures", cb.service, cb.failures)
+		}
+		cb.state = CircuitOpen
+		cb.openedAt = time.Now()
+	}
+}
+
+// ReleaseProbe gives up the probe slot of a request that ended without saying
+// anything about the provider's health, e.g. because its caller gave up, so
+// the next request can probe instead
+func (cb *CircuitBreaker) ReleaseProbe() {
+	cb.mu.Lock()
+	defer cb.mu.Unlock()
+
+	cb.probing = false
+}
+
+// State returns the current circuit state
+func (cb *CircuitBreaker) State() CircuitState {
+	cb.mu.Lock()
+	defer cb.mu.Unlock()
+
+	return cb.state
+}
@@ -0,0 +1,45 @@
+package ratelimit
+
+import (
+	"context"
+	"net/http"
+	"testing"
+	"time"
+)
+
+type roundTripFunc func(*http.Request) (*http.Response, error)
+
+func (f roundTripFunc) RoundTrip(req *http.Request) (*http.Response, error) {
+	return f(req)
+}
+
+func TestCancelledProbeReleasesHalfOpenCircuit(t *testing.T) {
+	limiter := NewRateLimiter()
+	breaker := limiter.CircuitBreaker(SpotifyService)
+	breaker.threshold = 1
+	breaker.cooldown = time.Millisecond
+	breaker.RecordFailure()
+	time.Sleep(2 * time.Millisecond)
+
+	// The caller gives up while the probe is in flight
+	ctx, cancel := context.WithCancel(context.Background())
+	client := NewRateLimitedHTTPClient(SpotifyService, limiter)
+	client.client.Transport = roundTripFunc(func(req *http.Request) (*http.Response, error) {
+		cancel()
+		return nil, req.Context().Err()
+	})
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, "https://api.spotify.com/v1/me", nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := client.Do(req); err == nil {
+		t.Fatal("a probe whose context was cancelled succeeded")
+	}
+
+	if state := breaker.State(); state != CircuitHalfOpen {
+		t.Errorf("circuit is %s after a cancelled probe; want %s", state, CircuitHalfOpen)
+	}
+	if err := breaker.Allow(); err != nil {
+		t.Errorf("the request after a cancelled probe was refused: %v", err)
+	}
+}
@@ -1,59 +1,172 @@
 package ratelimit
 
 import (
+	"context"
+	"errors"
 	"fmt"
 	"io"
 	"log"
+	"net"
 	"net/http"
 	"time"
+
+	"server/internal/cache"
 )
 
+// sharedTransport is reused by every provider client so TCP/TLS connections
+// (and HTTP/2 streams) are pooled instead of being re-established per call
+var sharedTransport = &http.Transport{
+	Proxy: http.ProxyFromEnvironment,
+	DialContext: (&net.Dialer{
+		Timeout:   10 * time.Second,
+		KeepAlive: 30 * time.Second,
+	}).DialContext,
+	ForceAttemptHTTP2:     true,
+	MaxIdleConns:          100,
+	MaxIdleConnsPerHost:   20,
+	IdleConnTimeout:       90 * time.Second,
+	TLSHandshakeTimeout:   10 * time.Second,
+	ExpectContinueTimeout: 1 * time.Second,
+}
+
+// ErrRateLimited is returned when a request is still rate limited after all retries
+var ErrRateLimited = errors.New("rate limited")
+
 type RateLimitedHTTPClient struct {
 	client      *http.Client
 	rateLimiter *RateLimiter
 	service     ServiceType
-	maxRetries  int
+	retry       RetryPolicy
+
+	cache        cache.Cache
+	cacheTTL     time.Duration
+	cacheMetrics cacheMetrics
+
+	onResponse []func(req *http.Request)
 }
 
 func NewRateLimitedHTTPClient(service ServiceType, rateLimiter *RateLimiter) *RateLimitedHTTPClient {
 	return &RateLimitedHTTPClient{
 		client: &http.Client{
-			Timeout: 30 * time.Second,
+			Transport: sharedTransport,
+			Timeout:   30 * time.Second,
 		},
 		rateLimiter: rateLimiter,
 		service:     service,
-		maxRetries:  3,
+		retry:       retryPolicyFromEnv(service),
 	}
 }
 
-// Do executes an HTTP request with rate limiting and retry logic
+// RetryPolicy returns the retry policy the client applies
+func (c *RateLimitedHTTPClient) RetryPolicy() RetryPolicy {
+	return c.retry
+}
+
+// SetRetryPolicy replaces the client's retry policy. It must be called
+// before the client is used.
+func (c *RateLimitedHTTPClient) SetRetryPolicy(policy RetryPolicy) {
+	c.retry = policy
+}
+
+// OnResponse registers a hook called for every request that reached the
+// provider, including retries, e.g. to account for quota usage. Hooks must
+// be registered before the client is used.
+func (c *RateLimitedHTTPClient) OnResponse(hook func(req *http.Request)) {
+	c.onResponse = append(c.onResponse, hook)
+}
+
+// Do executes an HTTP request with rate limiting and retry logic. The request's
+// context bounds both the rate limit wait and the retries.
 func (c *RateLimitedHTTPClient) Do(req *http.Request) (*http.Response, error) {
+	if c.isCacheable(req) {
+		return c.cachedDo(req)
+	}
+	return c.do(req)
+}
+
+func (c *RateLimitedHTTPClient) do(req *http.Request) (*http.Response, error) {
 	var resp *http.Response
 	var err error
 
-	for attempt := 0; attempt <= c.maxRetries; attempt++ {
+	breaker := c.rateLimiter.CircuitBreaker(c.service)
+	maxRetries := c.retry.MaxRetries
+	if req.Context().Value(noRetryContextKey{}) != nil {
+		maxRetries = 0
+	}
+	// A write that failed without a clear answer may still have been applied,
+	// so it is only retried when the provider refused it outright (429)
+	retryAmbiguous := idempotent(req)
+
+	for attempt := 0; attempt <= maxRetries; attempt++ {
 		// Wait for rate limit
-		if err := c.rateLimiter.Wait(c.service); err != nil {
-			return nil, fmt.Errorf("rate limit error: %v", err)
+		if err := c.rateLimiter.WaitContext(req.Context(), c.service); err != nil {
+			return nil, fmt.Errorf("rate limit error: %w", err)
+		}
+
+		// Rewind the body for retries of requests that carry one
+		if attempt > 0 && req.GetBody != nil {
+			body, err := req.GetBody()
+			if err != nil {
+				return nil, err
+			}
+			req.Body = body
+		}
+
+		// Fail fast while the provider is considered down
+		if err := breaker.Allow(); err != nil {
+			return nil, err
 		}
 
 		// Execute request
 		resp, err = c.client.Do(req)
 		if err != nil {
-			log.Printf("HTTP request error (attempt %d/%d): %v", attempt+1, c.maxRetries+1, err)
-			if attempt == c.maxRetries {
+			// A caller that gave up says nothing about the provider's health
+			if req.Context().Err() == nil {
+				breaker.RecordFailure()
+			} else {
+				breaker.ReleaseProbe()
+			}
+			log.Printf("HTTP request error (attempt %d/%d): %v", attempt+1, maxRetries+1, err)
+			if attempt == maxRetries || !retryAmbiguous || req.Context().Err() != nil {
+				return nil, err
+			}
+			if err := sleep(req.Context(), c.retry.Backoff(attempt)); err != nil {
 				return nil, err
 			}
-			time.Sleep(time.Duration(attempt+1) * time.Second)
 			continue
 		}
 
+		for _, hook := range c.onResponse {
+			hook(req)
+		}
+
+		if resp.StatusCode >= 500 {
+			if req.Context().Err() == nil {
+				breaker.RecordFailure()
+			} else {
+				breaker.ReleaseProbe()
+			}
+		} else {
+			breaker.RecordSuccess()
+		}
+
+		// Let the limiter learn from the provider's rate limit feedback
+		retryAfter := parseRetryAfter(resp)
+		c.rateLimiter.ObserveResponse(c.service, resp, retryAfter)
+
 		// Check for rate limit headers
 		if c.isRateLimited(resp) {
-			c.handleRateLimitResponse(resp, attempt)
-			if attempt == c.maxRetries {
+			if attempt == maxRetries {
+				resp.Body.Close()
+				return nil, fmt.Errorf("%w after %d retries", ErrRateLimited, maxRetries)
+			}
+			// Waiting longer than the policy allows would only hold up the caller
+			if retryAfter > c.retry.MaxDelay {
 				resp.Body.Close()
-				return nil, fmt.Errorf("rate limited after %d retries", c.maxRetries)
+				return nil, fmt.Errorf("%w for %v", ErrRateLimited, retryAfter.Round(time.Second))
+			}
+			if err := c.handleRateLimitResponse(req.Context(), resp, retryAfter, attempt); err != nil {
+				return nil, err
 			}
 			continue
 		}
@@ -64,15 +177,17 @@ func (c *RateLimitedHTTPClient) Do(req *http.Request) (*http.Response, error) {
 		}
 
 		// Handle other errors
-		if attempt == c.maxRetries {
+		if attempt == maxRetries {
 			return resp, nil // Return the error response
 		}
 
 		// For server errors, retry with backoff
-		if resp.StatusCode >= 500 {
-			log.Printf("Server error %d (attempt %d/%d)", resp.StatusCode, attempt+1, c.maxRetries+1)
+		if resp.StatusCode >= 500 && retryAmbiguous {
+			log.Printf("Server error %d (attempt %d/%d)", resp.StatusCode, attempt+1, maxRetries+1)
 			resp.Body.Close()
-			time.Sleep(time.Duration(attempt+1) * time.Second)
+			if err := sleep(req.Context(), c.retry.Backoff(attempt)); err != nil {
+				return nil, err
+			}
 			continue
 		}
 
@@ -90,47 +205,36 @@ func (c *RateLimitedHTTPClient) isRateLimited(resp *http.Response) bool {
 		resp.Header.Get("X-RateLimit-Remaining") == "0"
 }
 
-// handleRateLimitResponse handles rate limit responses with proper backoff
-func (c *RateLimitedHTTPClient) handleRateLimitResponse(resp *http.Response, attempt int) {
+// handleRateLimitResponse waits out a rate limit response, honouring
+// Retry-After when the provider sent one and backing off with jitter
+// otherwise, giving up early if ctx ends
+func (c *RateLimitedHTTPClient) handleRateLimitResponse(ctx context.Context, resp *http.Response, retryAfter time.Duration, attempt int) error {
 	resp.Body.Close()
 
-	retryAfter := c.getRetryAfter(resp)
-	if retryAfter > 0 {
-		log.Printf("Rate limited for %s. Retrying after %v (attempt %d/%d)",
-			c.service, retryAfter, attempt+1, c.maxRetries+1)
-		time.Sleep(retryAfter)
-	} else {
-		// Exponential backoff
-		backoff := time.Duration(attempt+1) * 5 * time.Second
-		log.Printf("Rate limited for %s. Retrying after %v (attempt %d/%d)",
-			c.service, backoff, attempt+1, c.maxRetries+1)
-		time.Sleep(backoff)
+	wait := retryAfter
+	if wait <= 0 {
+		wait = c.retry.Backoff(attempt)
 	}
+	log.Printf("Rate limited for %s. Retrying after %v (attempt %d/%d)",
+		c.service, wait.Round(time.Millisecond), attempt+1, c.retry.MaxRetries+1)
+	return sleep(ctx, wait)
 }
 
-// getRetryAfter extracts Retry-After header from response
-func (c *RateLimitedHTTPClient) getRetryAfter(resp *http.Response) time.Duration {
-	retryAfter := resp.Header.Get("Retry-After")
-	if retryAfter == "" {
-		return 0
+// sleep waits for d unless ctx ends first
+func sleep(ctx context.Context, d time.Duration) error {
+	timer := time.NewTimer(d)
+	defer timer.Stop()
+	select {
+	case <-timer.C:
+		return nil
+	case <-ctx.Done():
+		return ctx.Err()
 	}
-
-	// Try to parse as seconds
-	if seconds, err := time.ParseDuration(retryAfter + "s"); err == nil {
-		return seconds
-	}
-
-	// Try to parse as RFC1123 date
-	if retryTime, err := time.Parse(time.RFC1123, retryAfter); err == nil {
-		return time.Until(retryTime)
-	}
-
-	return 0
 }
 
 // Get makes a GET request with rate limiting
-func (c *RateLimitedHTTPClient) Get(url string) (*http.Response, error) {
-	req, err := http.NewRequest("GET", url, nil)
+func (c *RateLimitedHTTPClient) Get(ctx context.Context, url string) (*http.Response, error) {
+	req, err := http.NewRequestWithContext(ctx, "GET", url, nil)
 	if err != nil {
 		return nil, err
 	}
@@ -138,8 +242,8 @@ func (c *RateLimitedHTTPClient) Get(url string) (*http.Response, error) {
 }
 
 // Post makes a POST request with rate limiting
-func (c *RateLimitedHTTPClient) Post(url, contentType string, body io.Reader) (*http.Response, error) {
-	req, err := http.NewRequest("POST", url, body)
+func (c *RateLimitedHTTPClient) Post(ctx context.Context, url, contentType string, body io.Reader) (*http.Response, error) {
+	req, err := http.NewRequestWithContext(ctx, "POST", url, body)
 	if err != nil {
 		return nil, err
 	}
@@ -4,6 +4,9 @@ import (
 	"context"
 	"fmt"
 	"log"
+	"os"
+	"strconv"
+	"strings"
 	"sync"
 	"time"
 
@@ -17,38 +20,80 @@ const (
 	YouTubeService ServiceType = "youtube"
 )
 
-// Rate limits based on official API documentation
-var serviceLimits = map[ServiceType]struct {
-	requestsPerSecond int
-	burst             int
-}{
-	SpotifyService: {requestsPerSecond: 10, burst: 20}, // Spotify: 10 req/sec, burst to 20
-	YouTubeService: {requestsPerSecond: 1, burst: 5},   // YouTube: 1 req/sec, burst to 5 (conservative)
+type serviceLimit struct {
+	RequestsPerSecond int `json:"requests_per_second"`
+	Burst             int `json:"burst"`
+}
+
+// Default rate limits based on official API documentation. They act as the
+// ceiling for adaptive limiting and can be overridden per service with
+// <SERVICE>_REQUESTS_PER_SECOND and <SERVICE>_BURST_LIMIT.
+var serviceLimits = map[ServiceType]serviceLimit{
+	SpotifyService: {RequestsPerSecond: 10, Burst: 20}, // Spotify: 10 req/sec, burst to 20
+	YouTubeService: {RequestsPerSecond: 1, Burst: 5},   // YouTube: 1 req/sec, burst to 5 (conservative)
 }
 
 type RateLimiter struct {
 	limiters map[ServiceType]*rate.Limiter
+	limits   map[ServiceType]serviceLimit // currently applied limits
+	ceilings map[ServiceType]serviceLimit // configured maximum limits
+	streaks  map[ServiceType]int          // successful responses since last adjustment
+	breakers map[ServiceType]*CircuitBreaker
 	mutex    sync.RWMutex
+
+	statePath  string
+	stateMutex sync.Mutex
 }
 
 func NewRateLimiter() *RateLimiter {
 	rl := &RateLimiter{
-		limiters: make(map[ServiceType]*rate.Limiter),
+		limiters:  make(map[ServiceType]*rate.Limiter),
+		limits:    make(map[ServiceType]serviceLimit),
+		ceilings:  make(map[ServiceType]serviceLimit),
+		streaks:   make(map[ServiceType]int),
+		breakers:  make(map[ServiceType]*CircuitBreaker),
+		statePath: os.Getenv("RATE_LIMIT_STATE_FILE"),
 	}
 
 	// Initialize limiters for each service
 	for serviceType, limits := range serviceLimits {
+		limits = limitsFromEnv(serviceType, limits)
+		rl.ceilings[serviceType] = limits
+		rl.limits[serviceType] = limits
 		rl.limiters[serviceType] = rate.NewLimiter(
-			rate.Limit(limits.requestsPerSecond),
-			limits.burst,
+			rate.Limit(limits.RequestsPerSecond),
+			limits.Burst,
 		)
+		rl.breakers[serviceType] = NewCircuitBreaker(serviceType)
 	}
 
+	// Restore limits learned before the last restart
+	rl.loadState()
+
 	return rl
 }
 
+// limitsFromEnv overrides the default limits with environment configuration
+func limitsFromEnv(service ServiceType, limits serviceLimit) serviceLimit {
+	prefix := strings.ToUpper(string(service))
+
+	if v, err := strconv.Atoi(os.Getenv(prefix + "_REQUESTS_PER_SECOND")); err == nil && v > 0 {
+		limits.RequestsPerSecond = v
+	}
+	if v, err := strconv.Atoi(os.Getenv(prefix + "_BURST_LIMIT")); err == nil && v > 0 {
+		limits.Burst = v
+	}
+
+	return limits
+}
+
 // Wait blocks until the request is allowed for the service
 func (rl *RateLimiter) Wait(service ServiceType) error {
+	return rl.WaitContext(context.Background(), service)
+}
+
+// WaitContext blocks until the request is allowed for the service or ctx is done
+func (rl *RateLimiter) WaitContext(ctx context.Context, service ServiceType) error {
 	rl.mutex.RLock()
 	limiter, exists := rl.limiters[service]
 	rl.mutex.RUnlock()
@@ -58,7 +103,7 @@ func (rl *RateLimiter) Wait(service ServiceType) error {
 	}
 
 	// Use context with timeout to avoid infinite waiting
-	ctx, cancel := context.WithTimeout(context.Background(), 30*time.Second)
+	ctx, cancel := context.WithTimeout(ctx, 30*time.Second)
 	defer cancel()
 
 	if err := limiter.Wait(ctx); err != nil {
@@ -91,14 +136,83 @@ func (rl *RateLimiter) GetLimiterStats(service ServiceType) map[string]interface
 		return nil
 	}
 
+	rl.mutex.RLock()
+	limits := rl.limits[service]
+	ceiling := rl.ceilings[service]
+	rl.mutex.RUnlock()
+
 	// Note: rate.Limiter doesn't expose internal stats directly
 	// We can track our own metrics
 	return map[string]interface{}{
 		"service":        service,
-		"limit":          serviceLimits[service].requestsPerSecond,
-		"burst":          serviceLimits[service].burst,
+		"limit":          limits.RequestsPerSecond,
+		"burst":          limits.Burst,
+		"max_limit":      ceiling.RequestsPerSecond,
+		"max_burst":      ceiling.Burst,
 		"current_tokens": limiter.Tokens(),
+		"circuit_state":  rl.CircuitBreaker(service).State(),
+	}
+}
+
+// Pressure reports how throttled the providers currently are, from 0 (all
+// limits at their ceiling) to 1 (a circuit is open or a limit is at its floor),
+// see ServicePressure
+func (rl *RateLimiter) Pressure() float64 {
+	rl.mutex.RLock()
+	services := make([]ServiceType, 0, len(rl.ceilings))
+	for service := range rl.ceilings {
+		services = append(services, service)
+	}
+	rl.mutex.RUnlock()
+
+	pressure := 0.0
+	for _, service := range services {
+		pressure = max(pressure, rl.ServicePressure(service))
 	}
+
+	return pressure
+}
+
+// ServicePressure reports how throttled a single provider currently is, from 0
+// (rate and burst at their ceiling) to 1 (either lowered to minLimit). A value
+// whose ceiling is already the floor, like YouTube's 1 request per second,
+// cannot be lowered and so says nothing.
+func (rl *RateLimiter) ServicePressure(service ServiceType) float64 {
+	rl.mutex.RLock()
+	defer rl.mutex.RUnlock()
+
+	if breaker, exists := rl.breakers[service]; exists && breaker.State() != CircuitClosed {
+		return 1
+	}
+
+	ceiling, exists := rl.ceilings[service]
+	if !exists {
+		return 0
+	}
+	current := rl.limits[service]
+	return max(limitPressure(current.RequestsPerSecond, ceiling.RequestsPerSecond), limitPressure(current.Burst, ceiling.Burst))
+}
+
+// limitPressure places current between ceiling (0) and minLimit (1)
+func limitPressure(current, ceiling int) float64 {
+	if ceiling <= minLimit {
+		return 0
+	}
+	return min(1, max(0, float64(ceiling-current)/float64(ceiling-minLimit)))
+}
+
+// CircuitBreaker returns the circuit breaker guarding the service
+func (rl *RateLimiter) CircuitBreaker(service ServiceType) *CircuitBreaker {
+	rl.mutex.Lock()
+	defer rl.mutex.Unlock()
+
+	breaker, exists := rl.breakers[service]
+	if !exists {
+		breaker = NewCircuitBreaker(service)
+		rl.breakers[service] = breaker
+	}
+
+	return breaker
 }
 
 // SetCustomLimit allows dynamic adjustment of rate limits
@@ -106,10 +220,19 @@ func (rl *RateLimiter) SetCustomLimit(service ServiceType, requestsPerSecond int
 	rl.mutex.Lock()
 	defer rl.mutex.Unlock()
 
-	rl.limiters[service] = rate.NewLimiter(
-		rate.Limit(requestsPerSecond),
-		burst,
-	)
+	// Adjust the existing limiter in place so accumulated tokens and
+	// waiting callers are preserved
+	if limiter, exists := rl.limiters[service]; exists {
+		limiter.SetLimit(rate.Limit(requestsPerSecond))
+		limiter.SetBurst(burst)
+	} else {
+		rl.limiters[service] = rate.NewLimiter(
+			rate.Limit(requestsPerSecond),
+			burst,
+		)
+	}
+	rl.limits[service] = serviceLimit{RequestsPerSecond: requestsPerSecond, Burst: burst}
+	rl.streaks[service] = 0
 
 	log.Printf("Updated rate limit for %s: %d req/sec, burst %d",
 		service, requestsPerSecond, burst)
@@ -0,0 +1,31 @@
+package ratelimit
+
+import "testing"
+
+func TestServicePressure(t *testing.T) {
+	limiter := NewRateLimiter()
+	limiter.ceilings[SpotifyService] = serviceLimit{RequestsPerSecond: 10, Burst: 20}
+	limiter.ceilings[YouTubeService] = serviceLimit{RequestsPerSecond: 1, Burst: 5}
+
+	tests := []struct {
+		service           ServiceType
+		requestsPerSecond int
+		burst             int
+		want              float64
+	}{
+		{SpotifyService, 10, 20, 0},
+		{SpotifyService, 1, 20, 1},
+		{SpotifyService, 1, 1, 1},
+		{SpotifyService, 10, 1, 1},
+		// YouTube's rate is already at the floor, so only its burst shows throttling
+		{YouTubeService, 1, 5, 0},
+		{YouTubeService, 1, 3, 0.5},
+		{YouTubeService, 1, 1, 1},
+	}
+	for _, tt := range tests {
+		limiter.SetCustomLimit(tt.service, tt.requestsPerSecond, tt.burst)
+		if got := limiter.ServicePressure(tt.service); got != tt.want {
+			t.Errorf("ServicePressure(%s) at %d req/sec, burst %d = %v; want %v", tt.service, tt.requestsPerSecond, tt.burst, got, tt.want)
+		}
+	}
+}
@@ -0,0 +1,108 @@
+package ratelimit
+
+import (
+	"bytes"
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"io"
+	"net/http"
+	"sync/atomic"
+	"time"
+
+	"server/internal/cache"
+)
+
+type cachedResponse struct {
+	StatusCode int         `json:"status_code"`
+	Header     http.Header `json:"header"`
+	Body       []byte      `json:"body"`
+}
+
+type cacheMetrics struct {
+	hits   atomic.Int64
+	misses atomic.Int64
+}
+
+// SetCache enables caching of successful GET responses for ttl
+func (c *RateLimitedHTTPClient) SetCache(responseCache cache.Cache, ttl time.Duration) {
+	c.cache = responseCache
+	c.cacheTTL = ttl
+}
+
+// CacheStats returns cache hit/miss counters for the client
+func (c *RateLimitedHTTPClient) CacheStats() map[string]interface{} {
+	return map[string]interface{}{
+		"enabled": c.cache != nil,
+		"ttl":     c.cacheTTL.String(),
+		"hits":    c.cacheMetrics.hits.Load(),
+		"misses":  c.cacheMetrics.misses.Load(),
+	}
+}
+
+type noCacheContextKey struct{}
+
+// WithoutCache makes GET calls made with ctx skip the response cache, for
+// reads that must see the provider's current state
+func WithoutCache(ctx context.Context) context.Context {
+	return context.WithValue(ctx, noCacheContextKey{}, true)
+}
+
+// isCacheable reports whether the request may be answered from the cache.
+// Callers can opt out with a "Cache-Control: no-cache" request header or WithoutCache.
+func (c *RateLimitedHTTPClient) isCacheable(req *http.Request) bool {
+	return c.cache != nil &&
+		req.Method == http.MethodGet &&
+		req.Header.Get("Cache-Control") != "no-cache" &&
+		req.Context().Value(noCacheContextKey{}) == nil
+}
+
+// cacheKey identifies a response per service, URL and caller. The access token
+// is hashed into the key so users never see each other's responses.
+func (c *RateLimitedHTTPClient) cacheKey(req *http.Request) string {
+	sum := sha256.Sum256([]byte(req.Header.Get("Authorization") + "\n" + req.URL.String()))
+	return string(c.service) + ":" + hex.EncodeToString(sum[:])
+}
+
+// cachedDo serves GET requests from the cache and stores successful responses
+func (c *RateLimitedHTTPClient) cachedDo(req *http.Request) (*http.Response, error) {
+	key := c.cacheKey(req)
+
+	if data, ok := c.cache.Get(key); ok {
+		var cached cachedResponse
+		if err := json.Unmarshal(data, &cached); err == nil {
+			c.cacheMetrics.hits.Add(1)
+			return &http.Response{
+				Status:     http.StatusText(cached.StatusCode),
+				StatusCode: cached.StatusCode,
+				Header:     cached.Header,
+				Body:       io.NopCloser(bytes.NewReader(cached.Body)),
+				Request:    req,
+			}, nil
+		}
+	}
+	c.cacheMetrics.misses.Add(1)
+
+	resp, err := c.do(req)
+	if err != nil || resp.StatusCode != http.StatusOK {
+		return resp, err
+	}
+
+	body, err := io.ReadAll(resp.Body)
+	resp.Body.Close()
+	if err != nil {
+		return nil, err
+	}
+	resp.Body = io.NopCloser(bytes.NewReader(body))
+
+	if data, err := json.Marshal(cachedResponse{
+		StatusCode: resp.StatusCode,
+		Header:     resp.Header,
+		Body:       body,
+	}); err == nil {
+		c.cache.Set(key, data, c.cacheTTL)
+	}
+
+	return resp, nil
+}
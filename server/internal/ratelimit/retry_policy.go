@@ -0,0 +1,108 @@
+package ratelimit
+
+import (
+	"context"
+	"math"
+	"math/rand/v2"
+	"net/http"
+	"os"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// RetryPolicy decides how often and how long a provider client backs off
+// after transport errors, server errors and rate limit responses
+type RetryPolicy struct {
+	MaxRetries int           `json:"max_retries"`
+	BaseDelay  time.Duration `json:"base_delay"` // backoff ceiling of the first retry, doubled for each further one
+	MaxDelay   time.Duration `json:"max_delay"`  // cap on the backoff and on Retry-After waits
+}
+
+// Default retry policies. They can be overridden per service with
+// <SERVICE>_MAX_RETRIES, <SERVICE>_RETRY_BASE_DELAY and <SERVICE>_RETRY_MAX_DELAY.
+var retryPolicies = map[ServiceType]RetryPolicy{
+	SpotifyService: {MaxRetries: 3, BaseDelay: time.Second, MaxDelay: 30 * time.Second},
+	YouTubeService: {MaxRetries: 3, BaseDelay: 2 * time.Second, MaxDelay: 60 * time.Second},
+}
+
+type noRetryContextKey struct{}
+
+// WithoutRetries makes calls made with ctx go out once, for callers that
+// retry at their own level and would otherwise multiply the attempts
+func WithoutRetries(ctx context.Context) context.Context {
+	return context.WithValue(ctx, noRetryContextKey{}, true)
+}
+
+// idempotent reports whether a request can be sent again after a failure that
+// leaves unknown whether the provider applied it
+func idempotent(req *http.Request) bool {
+	return req.Method != http.MethodPost && req.Method != http.MethodPatch
+}
+
+// retryPolicyFromEnv returns the service's retry policy with environment overrides applied
+func retryPolicyFromEnv(service ServiceType) RetryPolicy {
+	policy, ok := retryPolicies[service]
+	if !ok {
+		policy = RetryPolicy{MaxRetries: 3, BaseDelay: time.Second, MaxDelay: 30 * time.Second}
+	}
+	prefix := strings.ToUpper(string(service))
+
+	if v, err := strconv.Atoi(os.Getenv(prefix + "_MAX_RETRIES")); err == nil && v >= 0 {
+		policy.MaxRetries = v
+	}
+	if v, err := time.ParseDuration(os.Getenv(prefix + "_RETRY_BASE_DELAY")); err == nil && v > 0 {
+		policy.BaseDelay = v
+	}
+	if v, err := time.ParseDuration(os.Getenv(prefix + "_RETRY_MAX_DELAY")); err == nil && v > 0 {
+		policy.MaxDelay = v
+	}
+	if policy.MaxDelay < policy.BaseDelay {
+		policy.MaxDelay = policy.BaseDelay
+	}
+
+	return policy
+}
+
+// Backoff returns the wait before retry attempt+1 using full jitter: a random
+// duration between zero and min(MaxDelay, BaseDelay*2^attempt), so clients
+// that failed together do not retry together
+func (p RetryPolicy) Backoff(attempt int) time.Duration {
+	ceiling := p.MaxDelay
+	if attempt < 32 {
+		if d := p.BaseDelay << attempt; d > 0 && d < ceiling {
+			ceiling = d
+		}
+	}
+	if ceiling <= 0 {
+		return 0
+	}
+	return rand.N(ceiling + 1)
+}
+
+// parseRetryAfter reads a Retry-After header given either as delay seconds or
+// as an HTTP date. It returns 0 when the header is missing, malformed or already past.
+func parseRetryAfter(resp *http.Response) time.Duration {
+	value := strings.TrimSpace(resp.Header.Get("Retry-After"))
+	if value == "" {
+		return 0
+	}
+
+	if seconds, err := strconv.ParseInt(value, 10, 64); err == nil {
+		if seconds <= 0 {
+			return 0
+		}
+		if seconds > int64(math.MaxInt64/time.Second) {
+			seconds = int64(math.MaxInt64 / time.Second)
+		}
+		return time.Duration(seconds) * time.Second
+	}
+
+	if retryTime, err := http.ParseTime(value); err == nil {
+		if wait := time.Until(retryTime); wait > 0 {
+			return wait
+		}
+	}
+
+	return 0
+}
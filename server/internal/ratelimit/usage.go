@@ -0,0 +1,48 @@
+package ratelimit
+
+import (
+	"context"
+	"time"
+)
+
+type userContextKey struct{}
+
+// WithUser marks provider calls made with ctx as made on behalf of a user
+func WithUser(ctx context.Context, userID uint) context.Context {
+	return context.WithValue(ctx, userContextKey{}, userID)
+}
+
+// UserFromContext returns the user provider calls made with ctx are attributed to
+func UserFromContext(ctx context.Context) (uint, bool) {
+	userID, ok := ctx.Value(userContextKey{}).(uint)
+	return userID, ok
+}
+
+type transferContextKey struct{}
+
+// WithTransfer marks provider calls made with ctx as made for a transfer
+func WithTransfer(ctx context.Context, transferID uint) context.Context {
+	return context.WithValue(ctx, transferContextKey{}, transferID)
+}
+
+// TransferFromContext returns the transfer provider calls made with ctx are attributed to
+func TransferFromContext(ctx context.Context) (uint, bool) {
+	transferID, ok := ctx.Value(transferContextKey{}).(uint)
+	return transferID, ok
+}
+
+// WaitTime estimates how long a new request to a service waits for the rate limiter
+func (rl *RateLimiter) WaitTime(service ServiceType) time.Duration {
+	rl.mutex.RLock()
+	limiter, exists := rl.limiters[service]
+	rl.mutex.RUnlock()
+	if !exists {
+		return 0
+	}
+
+	tokens := limiter.Tokens()
+	if tokens >= 1 || limiter.Limit() <= 0 {
+		return 0
+	}
+	return time.Duration((1 - tokens) / float64(limiter.Limit()) * float64(time.Second))
+}
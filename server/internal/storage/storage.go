@@ -0,0 +1,78 @@
+package storage
+
+import (
+	"context"
+	"errors"
+	"io"
+	"log"
+	"path"
+	"strings"
+	"time"
+
+	"server/internal/config"
+)
+
+var (
+	// ErrNotFound is returned by Get for keys that hold no object
+	ErrNotFound = errors.New("storage: object not found")
+	// ErrSignedURLUnsupported is returned by backends that can't hand out direct
+	// download links; callers serve the object themselves instead
+	ErrSignedURLUnsupported = errors.New("storage: signed URLs not supported")
+	errInvalidKey           = errors.New("storage: invalid key")
+)
+
+// Store keeps generated files (artwork, exports, transfer reports) by key.
+// Keys are slash-separated relative paths such as "exports/12-ab34.zip".
+type Store interface {
+	Put(ctx context.Context, key string, body io.Reader, size int64, contentType string) error
+	// Get returns the object's contents, which the caller must close
+	Get(ctx context.Context, key string) (io.ReadCloser, error)
+	// Delete removes an object; deleting a missing key is not an error
+	Delete(ctx context.Context, key string) error
+	// SignedURL returns a link that downloads the object directly until ttl passes
+	SignedURL(key string, ttl time.Duration) (string, error)
+}
+
+// Default is selected by STORAGE_BACKEND: "local" (default) keeps files under
+// STORAGE_DIR, "s3" uses an S3-compatible bucket such as AWS S3 or MinIO
+var Default = newDefault()
+
+func newDefault() Store {
+	local := func() Store {
+		return NewLocalStore(config.String("STORAGE_DIR", "data/storage"))
+	}
+
+	switch backend := config.String("STORAGE_BACKEND", "local"); backend {
+	case "local":
+		return local()
+	case "s3":
+		store, err := NewS3Store(S3Config{
+			Endpoint:        config.String("S3_ENDPOINT", ""),
+			Region:          config.String("S3_REGION", "us-east-1"),
+			Bucket:          config.String("S3_BUCKET", ""),
+			AccessKeyID:     config.String("S3_ACCESS_KEY_ID", ""),
+			SecretAccessKey: config.String("S3_SECRET_ACCESS_KEY", ""),
+			PathStyle:       config.Bool("S3_PATH_STYLE", false),
+		})
+		if err != nil {
+			log.Printf("S3 storage misconfigured (%v), using local storage", err)
+			return local()
+		}
+		return store
+	default:
+		log.Printf("Unknown STORAGE_BACKEND %q, using local", backend)
+		return local()
+	}
+}
+
+// cleanKey rejects keys that could escape the store's root
+func cleanKey(key string) (string, error) {
+	if key == "" || strings.HasPrefix(key, "/") || strings.Contains(key, "\\") {
+		return "", errInvalidKey
+	}
+	cleaned := path.Clean(key)
+	if cleaned != key || cleaned == "." || cleaned == ".." || strings.HasPrefix(cleaned, "../") {
+		return "", errInvalidKey
+	}
+	return cleaned, nil
+}
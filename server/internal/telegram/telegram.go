@@ -0,0 +1,91 @@
+package telegram
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"time"
+)
+
+const apiBaseURL = "https://api.telegram.org/bot"
+
+// Client talks to the Telegram Bot API
+type Client struct {
+	botToken   string
+	httpClient *http.Client
+}
+
+func NewClient(botToken string) *Client {
+	return &Client{
+		botToken:   botToken,
+		httpClient: &http.Client{Timeout: 10 * time.Second},
+	}
+}
+
+// Enabled reports whether a bot token is configured
+func (c *Client) Enabled() bool {
+	return c.botToken != ""
+}
+
+// SendMessage sends a plain text message to a chat
+func (c *Client) SendMessage(ctx context.Context, chatID int64, text string) error {
+	return c.call(ctx, "sendMessage", map[string]interface{}{
+		"chat_id":                  chatID,
+		"text":                     text,
+		"disable_web_page_preview": true,
+	})
+}
+
+// SetWebhook points the bot's updates at url; Telegram echoes secret in the
+// X-Telegram-Bot-Api-Secret-Token header of every update
+func (c *Client) SetWebhook(ctx context.Context, url, secret string) error {
+	return c.call(ctx, "setWebhook", map[string]interface{}{
+		"url":             url,
+		"secret_token":    secret,
+		"allowed_updates": []string{"message"},
+	})
+}
+
+func (c *Client) call(ctx context.Context, method string, payload interface{}) error {
+	body, err := json.Marshal(payload)
+	if err != nil {
+		return err
+	}
+
+	req, err := http.NewRequestWithContext(ctx, "POST", apiBaseURL+c.botToken+"/"+method, bytes.NewReader(body))
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Content-Type", "application/json")
+
+	resp, err := c.httpClient.Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+
+	var result struct {
+		OK          bool   `json:"ok"`
+		Description string `json:"description"`
+	}
+	if err := json.NewDecoder(resp.Body).Decode(&result); err != nil {
+		return err
+	}
+	if !result.OK {
+		return fmt.Errorf("telegram %s failed: %s", method, result.Description)
+	}
+	return nil
+}
+
+// Update is an incoming webhook update; only text messages are used
+type Update struct {
+	UpdateID int64 `json:"update_id"`
+	Message  *struct {
+		Text string `json:"text"`
+		Chat struct {
+			ID int64 `json:"id"`
+		} `json:"chat"`
+	} `json:"message"`
+}
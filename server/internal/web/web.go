@@ -0,0 +1,105 @@
+package web
+
+import (
+	"embed"
+	"io"
+	"io/fs"
+	"log"
+	"net/http"
+	"os"
+	"path"
+	"strings"
+
+	"github.com/gin-gonic/gin"
+)
+
+//go:embed all:dist
+var embedded embed.FS
+
+// Frontend returns the client bundle to serve: FRONTEND_DIR on disk when set,
+// otherwise the bundle embedded at build time. It returns nil when neither
+// contains an index.html.
+func Frontend() fs.FS {
+	var files fs.FS
+	if dir := os.Getenv("FRONTEND_DIR"); dir != "" {
+		files = os.DirFS(dir)
+	} else {
+		sub, err := fs.Sub(embedded, "dist")
+		if err != nil {
+			return nil
+		}
+		files = sub
+	}
+
+	if _, err := fs.Stat(files, "index.html"); err != nil {
+		return nil
+	}
+	return files
+}
+
+// Register serves files from the bundle for every route the API does not
+// handle, falling back to index.html so client-side routes survive reloads
+func Register(r *gin.Engine, files fs.FS) {
+	r.NoRoute(func(c *gin.Context) {
+		if strings.HasPrefix(c.Request.URL.Path, "/api/") || (c.Request.Method != http.MethodGet && c.Request.Method != http.MethodHead) {
+			c.JSON(http.StatusNotFound, gin.H{"error": "Not found"})
+			return
+		}
+
+		name := resolve(files, strings.TrimPrefix(path.Clean(c.Request.URL.Path), "/"))
+		if name == "" {
+			// Missing assets are real 404s; anything else is a client-side route
+			if path.Ext(c.Request.URL.Path) != "" {
+				c.Status(http.StatusNotFound)
+				return
+			}
+			name = "index.html"
+		}
+
+		// Hashed build assets never change; pages must be revalidated
+		if strings.HasPrefix(name, "_next/static/") {
+			c.Header("Cache-Control", "public, max-age=31536000, immutable")
+		} else {
+			c.Header("Cache-Control", "no-cache")
+		}
+
+		serveFile(c, files, name)
+	})
+
+	log.Printf("Serving frontend bundle for non-API routes")
+}
+
+// serveFile writes a bundle file directly; http.FileServer would redirect
+// index.html requests back to "/" and loop with the SPA fallback
+func serveFile(c *gin.Context, files fs.FS, name string) {
+	f, err := files.Open(name)
+	if err != nil {
+		c.Status(http.StatusNotFound)
+		return
+	}
+	defer f.Close()
+
+	info, err := f.Stat()
+	content, ok := f.(io.ReadSeeker)
+	if err != nil || !ok {
+		c.Status(http.StatusInternalServerError)
+		return
+	}
+
+	http.ServeContent(c.Writer, c.Request, name, info.ModTime(), content)
+}
+
+// resolve maps a request path onto a bundle file, trying the exported page
+// variants ("dashboard.html", "dashboard/index.html")
+func resolve(files fs.FS, name string) string {
+	if name == "" || name == "." {
+		return "index.html"
+	}
+
+	for _, candidate := range []string{name, name + ".html", path.Join(name, "index.html")} {
+		if info, err := fs.Stat(files, candidate); err == nil && !info.IsDir() {
+			return candidate
+		}
+	}
+	return ""
+}
@@ -1,13 +1,23 @@
 package main
 
 import (
+	"context"
 	"log"
+	"net/http"
 	"os"
+	"time"
 
 	"server/internal/auth"
+	"server/internal/config"
 	"server/internal/database"
+	"server/internal/discord"
 	"server/internal/handlers"
+	"server/internal/jobs"
+	"server/internal/lock"
 	"server/internal/middleware"
+	"server/internal/ratelimit"
+	"server/internal/telegram"
+	"server/internal/web"
 
 	"github.com/gin-contrib/cors"
 	"github.com/gin-gonic/gin"
@@ -22,15 +32,67 @@ func main() {
 	// Initialize OAuth providers
 	auth.InitOAuthConfigs()
 
+	// Handlers get their dependencies once the database is connected
+	locker := lock.New(database.DB)
+
+	rateLimiter := ratelimit.NewRateLimiter()
+	rateMonitor := ratelimit.NewRateLimitMonitor(rateLimiter)
+	rateMonitor.StartMonitoring()
+
+	// Provider clients are shared so connections are reused across requests
+	spotifyClient := ratelimit.NewRateLimitedHTTPClient(ratelimit.SpotifyService, rateLimiter)
+	youtubeClient := ratelimit.NewRateLimitedHTTPClient(ratelimit.YouTubeService, rateLimiter)
+	providers := handlers.NewProviders(spotifyClient, youtubeClient, rateLimiter, rateMonitor, auth.NewAppTokenManager())
+
+	// Transfers and other background work run on an autoscaling worker pool
+	jobQueue := jobs.NewQueue(jobs.Config{
+		MinWorkers:    config.Int("JOB_MIN_WORKERS", 1),
+		MaxWorkers:    config.Int("JOB_MAX_WORKERS", 4),
+		JobsPerWorker: config.Int("JOB_JOBS_PER_WORKER", 2),
+		ScaleInterval: config.Duration("JOB_SCALE_INTERVAL", 5*time.Second),
+		Pressure:      rateLimiter.Pressure,
+		Lock:          locker.TryLock,
+	})
+	jobQueue.Start(context.Background())
+
+	h := handlers.New(database.DB, auth.NewTokenManager(database.DB, locker), providers, jobQueue, locker,
+		discord.NewClient(os.Getenv("DISCORD_BOT_TOKEN"), os.Getenv("DISCORD_APPLICATION_ID")),
+		telegram.NewClient(os.Getenv("TELEGRAM_BOT_TOKEN")))
+
+	// Optional chat integrations
+	h.InitDiscord(context.Background())
+	h.InitTelegram(context.Background())
+
+	// Keep stored playlists fresh in the background
+	h.StartPlaylistSyncScheduler(context.Background())
+	h.StartPlaylistArchiveScheduler(context.Background())
+	h.StartTransferPlanScheduler(context.Background())
+	h.StartWeeklyDigestScheduler(context.Background())
+	h.StartServiceHealthScheduler(context.Background())
+	h.StartSyncLinkScheduler(context.Background())
+	h.StartAPILedger(context.Background())
+
 	// Set up Gin
 	r := gin.Default()
 
-	// CORS configuration for local development
+	mode := tlsMode()
+	if mode != "" {
+		r.Use(middleware.HSTS(config.Duration("HSTS_MAX_AGE", 365*24*time.Hour), config.Bool("HSTS_INCLUDE_SUBDOMAINS", false)))
+	}
+
+	// Reject oversized request bodies before they reach handlers
+	r.Use(middleware.BodySizeLimit(config.Int64("HTTP_MAX_BODY_BYTES", 1<<20)))
+
+	// Cancel a request's database and provider work when it runs too long
+	r.Use(middleware.RequestTimeout(config.Duration("HTTP_REQUEST_TIMEOUT", 25*time.Second)))
+
+	// CORS defaults suit local development; self-hosters set CORS_* for their domain
 	r.Use(cors.New(cors.Config{
-		AllowOrigins:     []string{"http://localhost:3000", "http://client:3000"},
-		AllowMethods:     []string{"GET", "POST", "PUT", "DELETE", "OPTIONS"},
-		AllowHeaders:     []string{"Origin", "Content-Type", "Authorization"},
-		AllowCredentials: true,
+		AllowOrigins:     config.List("CORS_ALLOWED_ORIGINS", []string{"http://localhost:3000", "http://client:3000"}),
+		AllowMethods:     config.List("CORS_ALLOWED_METHODS", []string{"GET", "POST", "PUT", "DELETE", "OPTIONS"}),
+		AllowHeaders:     config.List("CORS_ALLOWED_HEADERS", []string{"Origin", "Content-Type", "Authorization", "Accept-Language"}),
+		AllowCredentials: config.Bool("CORS_ALLOW_CREDENTIALS", true),
+		MaxAge:           config.Duration("CORS_MAX_AGE", 12*time.Hour),
 	}))
 
 	// API routes
@@ -39,47 +101,170 @@ func main() {
 		// Public auth routes
 		authGroup := api.Group("/auth")
 		{
-			authGroup.GET("/google", handlers.HandleGoogleLogin)
-			authGroup.GET("/google/callback", handlers.HandleGoogleCallback)
-			authGroup.POST("/logout", handlers.HandleLogout)
+			authGroup.GET("/google", h.HandleGoogleLogin)
+			authGroup.GET("/google/callback", h.HandleGoogleCallback)
+			authGroup.POST("/exchange", h.HandleAuthExchange)
+			authGroup.POST("/mobile", h.HandleMobileAuth)
+			authGroup.POST("/logout", h.HandleLogout)
+			authGroup.POST("/2fa/challenge", h.HandleTwoFactorChallenge)
 		}
 
 		// Service connection routes (public for OAuth flow)
 		servicesGroup := api.Group("/services")
 		{
 			// These need to be public because they're called via browser redirects
-			servicesGroup.GET("/connect/:provider", handlers.HandleConnectService)
-			servicesGroup.GET("/callback/:provider", handlers.HandleServiceCallback)
+			servicesGroup.GET("/connect/:provider", h.HandleConnectService)
+			servicesGroup.GET("/callback/:provider", h.HandleServiceCallback)
 		}
 
+		// Activity feed is authenticated by the secret token in its URL
+		api.GET("/feed/:token", h.HandleActivityFeed)
+		api.GET("/stats/global", h.GetGlobalStats)
+
+		// Export downloads and artwork are authorized by the signature in their URL
+		api.GET("/exports/:id/download", h.DownloadExport)
+		api.GET("/artwork/:id", h.GetPlaylistArtwork)
+
+		// Discord interactions are authenticated by request signature
+		api.POST("/integrations/discord/interactions", h.HandleDiscordInteraction)
+		api.POST("/integrations/telegram/webhook", h.HandleTelegramWebhook)
+
 		// Protected routes (require JWT)
 		protected := api.Group("")
 		protected.Use(middleware.AuthMiddleware())
 		{
-			protected.GET("/auth/me", handlers.HandleGetCurrentUser)
-			protected.GET("/rate-limits", handlers.HandleRateLimitStatus)
+			protected.GET("/auth/me", h.HandleGetCurrentUser)
+			protected.GET("/sessions", h.GetSessions)
+			protected.DELETE("/sessions/:id", h.RevokeSession)
+			protected.GET("/auth/2fa", h.GetTwoFactorStatus)
+			protected.POST("/auth/2fa/enroll", h.EnrollTwoFactor)
+			protected.POST("/auth/2fa/verify", h.VerifyTwoFactor)
+			protected.POST("/auth/2fa/backup-codes", h.RegenerateBackupCodes)
+			protected.POST("/auth/2fa/disable", h.DisableTwoFactor)
+			protected.GET("/rate-limits", h.HandleRateLimitStatus)
+			protected.GET("/stats", h.GetUserStats)
+			protected.GET("/usage", h.GetUsage)
+			protected.PUT("/stats/sharing", h.SetStatsSharing)
+			protected.GET("/features", h.GetFeatures)
+			protected.GET("/maintenance", h.GetMaintenance)
+			protected.GET("/settings", h.GetSettings)
+			protected.PUT("/settings", h.UpdateSettings)
+			protected.POST("/feed/token", h.HandleRotateFeedToken)
+			protected.POST("/exports", h.CreateExport)
+			protected.GET("/exports/:id", h.GetExport)
+			protected.GET("/tracks/:service/:id", h.GetTrackIdentity)
+			protected.GET("/rules", h.GetContentRules)
+			protected.POST("/rules", h.CreateContentRule)
+			protected.PUT("/rules/:id", h.UpdateContentRule)
+			protected.DELETE("/rules/:id", h.DeleteContentRule)
 
 			// Services routes (protected)
 			servicesGroup := protected.Group("/services")
 			{
-				servicesGroup.GET("", handlers.HandleGetConnectedServices)
-				servicesGroup.GET("/health", handlers.HandleTokenHealth)
-				servicesGroup.DELETE("/:provider", handlers.HandleDisconnectService)
+				servicesGroup.GET("", h.HandleGetConnectedServices)
+				servicesGroup.GET("/health", h.HandleTokenHealth)
+				servicesGroup.DELETE("/:provider", h.HandleDisconnectService)
 			}
 
 			// Playlists routes
 			playlistsGroup := protected.Group("/playlists")
 			{
-				playlistsGroup.GET("/:service", handlers.GetPlaylists)
-				playlistsGroup.GET("/:service/stored", handlers.GetStoredPlaylists)
-				playlistsGroup.POST("/sync", handlers.SyncAllPlaylists)
+				playlistsGroup.GET("/:service", h.GetPlaylists)
+				playlistsGroup.GET("/:service/stored", h.GetStoredPlaylists)
+				playlistsGroup.GET("/:service/:id/preview", h.GetPlaylistPreview)
+				playlistsGroup.POST("/sync", h.SyncAllPlaylists)
+				playlistsGroup.GET("/tags", h.GetPlaylistTags)
+				playlistsGroup.PUT("/stored/:id/tags", h.SetPlaylistTags)
+				playlistsGroup.PUT("/stored/:id/archive", h.SetPlaylistArchive)
+				playlistsGroup.POST("/stored/:id/tracks/sync", h.SyncStoredPlaylistTracks)
+				playlistsGroup.POST("/smart", h.BuildSmartPlaylist)
+			}
+
+			integrationsGroup := protected.Group("/integrations")
+			{
+				integrationsGroup.POST("/discord/link", h.HandleCreateDiscordLinkCode)
+				integrationsGroup.DELETE("/discord/link", h.HandleDeleteDiscordLink)
+				integrationsGroup.POST("/telegram/link", h.HandleCreateTelegramLinkCode)
+				integrationsGroup.DELETE("/telegram/link", h.HandleDeleteTelegramLink)
+				integrationsGroup.GET("/slack", h.HandleGetSlackIntegration)
+				integrationsGroup.PUT("/slack", h.HandlePutSlackIntegration)
+				integrationsGroup.DELETE("/slack", h.HandleDeleteSlackIntegration)
+				integrationsGroup.POST("/slack/test", h.HandleTestSlackIntegration)
 			}
 
 			transfersGroup := protected.Group("/transfers")
 			{
-				transfersGroup.POST("", handlers.StartTransfer)
-				transfersGroup.GET("", handlers.GetTransfers)
-				transfersGroup.GET("/:id", handlers.GetTransferDetails)
+				transfersGroup.POST("", h.StartTransfer)
+				transfersGroup.POST("/batch", h.StartBatchTransfer)
+				transfersGroup.POST("/library", h.StartLibraryTransfer)
+				transfersGroup.GET("/library", h.GetLibraryTransfers)
+				transfersGroup.GET("/library/:id", h.GetLibraryTransfer)
+				transfersGroup.POST("/estimate", h.EstimateTransfer)
+				transfersGroup.GET("", h.GetTransfers)
+				transfersGroup.GET("/:id", h.GetTransferDetails)
+				transfersGroup.GET("/:id/plan", h.GetTransferPlan)
+				transfersGroup.GET("/:id/report", h.GetTransferReport)
+				transfersGroup.POST("/:id/rerun", h.RerunTransfer)
+				transfersGroup.GET("/:id/drift", h.GetTransferDrift)
+				transfersGroup.POST("/:id/repair", h.RepairTransfer)
+			}
+
+			migrationsGroup := protected.Group("/migrations")
+			{
+				migrationsGroup.POST("", h.StartMigration)
+				migrationsGroup.GET("", h.GetMigrations)
+				migrationsGroup.GET("/:id", h.GetMigration)
+				migrationsGroup.POST("/:id/analyze", h.AnalyzeMigration)
+				migrationsGroup.PUT("/:id/plan", h.PlanMigration)
+				migrationsGroup.POST("/:id/execute", h.ExecuteMigration)
+				migrationsGroup.POST("/:id/verify", h.VerifyMigration)
+				migrationsGroup.DELETE("/:id", h.DeleteMigration)
+			}
+
+			exclusionsGroup := protected.Group("/playlist-exclusions")
+			{
+				exclusionsGroup.GET("", h.GetPlaylistExclusions)
+				exclusionsGroup.POST("", h.CreatePlaylistExclusion)
+				exclusionsGroup.DELETE("/:id", h.DeletePlaylistExclusion)
+			}
+
+			autoSyncGroup := protected.Group("/auto-sync-rules")
+			{
+				autoSyncGroup.POST("", h.CreateAutoSyncRule)
+				autoSyncGroup.GET("", h.GetAutoSyncRules)
+				autoSyncGroup.DELETE("/:id", h.DeleteAutoSyncRule)
+			}
+
+			syncLinksGroup := protected.Group("/sync-links")
+			{
+				syncLinksGroup.POST("", h.CreateSyncLink)
+				syncLinksGroup.GET("", h.GetSyncLinks)
+				syncLinksGroup.PATCH("/:id", h.UpdateSyncLink)
+				syncLinksGroup.DELETE("/:id", h.DeleteSyncLink)
+				syncLinksGroup.GET("/:id/status", h.GetSyncLinkStatus)
+				syncLinksGroup.POST("/:id/sync", h.SyncLinkNow)
+				syncLinksGroup.GET("/:id/conflicts", h.GetSyncConflicts)
+				syncLinksGroup.POST("/:id/conflicts/resolve", h.ResolveSyncConflicts)
+			}
+
+			// Operator routes, restricted to ADMIN_EMAILS
+			adminGroup := protected.Group("/admin")
+			adminGroup.Use(middleware.RequireAdmin())
+			{
+				adminGroup.GET("/flags", h.AdminListFlags)
+				adminGroup.PUT("/flags/:name", h.AdminPutFlag)
+				adminGroup.DELETE("/flags/:name", h.AdminDeleteFlag)
+				adminGroup.GET("/app-credentials", h.AdminAppCredentials)
+				adminGroup.GET("/queue", h.HandleQueueStatus)
+				adminGroup.GET("/jobs", h.AdminListJobs)
+				adminGroup.POST("/jobs/:id/cancel", h.AdminCancelJob)
+				adminGroup.POST("/jobs/:id/retry", h.AdminRetryJob)
+				adminGroup.GET("/ratelimit", h.AdminRateLimitMetrics)
+				adminGroup.GET("/api-calls", h.AdminListAPICalls)
+				adminGroup.GET("/api-calls/rollups", h.AdminAPICallRollups)
+				adminGroup.GET("/maintenance", h.AdminListMaintenance)
+				adminGroup.PUT("/maintenance/:scope", h.AdminPutMaintenance)
+				adminGroup.DELETE("/maintenance/:scope", h.AdminDeleteMaintenance)
 			}
 		}
 
@@ -89,11 +274,34 @@ func main() {
 		})
 	}
 
-	port := os.Getenv("PORT")
-	if port == "" {
-		port = "8080"
+	defaultPort := "8080"
+	if mode != "" {
+		defaultPort = "443"
+	}
+	// Single-binary deployments serve the client bundle themselves
+	if config.Bool("SERVE_FRONTEND", true) {
+		if files := web.Frontend(); files != nil {
+			web.Register(r, files)
+		}
+	}
+
+	port := config.String("PORT", defaultPort)
+
+	server := &http.Server{
+		Addr:              ":" + port,
+		Handler:           r,
+		ReadTimeout:       config.Duration("HTTP_READ_TIMEOUT", 15*time.Second),
+		ReadHeaderTimeout: config.Duration("HTTP_READ_HEADER_TIMEOUT", 5*time.Second),
+		WriteTimeout:      config.Duration("HTTP_WRITE_TIMEOUT", 30*time.Second),
+		IdleTimeout:       config.Duration("HTTP_IDLE_TIMEOUT", 120*time.Second),
+		MaxHeaderBytes:    config.Int("HTTP_MAX_HEADER_BYTES", 1<<20),
+	}
+
+	if mode != "" {
+		log.Printf("Server starting on port %s with TLS (%s)", port, mode)
+		log.Fatal(serveTLS(server, mode))
 	}
 
 	log.Printf("Server starting on port %s", port)
-	log.Fatal(r.Run(":" + port))
+	log.Fatal(server.ListenAndServe())
 }
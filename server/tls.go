@@ -0,0 +1,81 @@
+package main
+
+import (
+	"crypto/tls"
+	"log"
+	"net"
+	"net/http"
+
+	"server/internal/config"
+
+	"golang.org/x/crypto/acme/autocert"
+)
+
+// tlsMode reports how the server terminates TLS: "autocert" when
+// TLS_AUTOCERT_DOMAINS is set, "files" when a cert/key pair is given, or "" for plain HTTP
+func tlsMode() string {
+	if len(config.List("TLS_AUTOCERT_DOMAINS", nil)) > 0 {
+		return "autocert"
+	}
+	if config.String("TLS_CERT_FILE", "") != "" && config.String("TLS_KEY_FILE", "") != "" {
+		return "files"
+	}
+	return ""
+}
+
+// serveTLS serves server over HTTPS and, when HTTP_REDIRECT_PORT is set,
+// redirects plain HTTP to it. Autocert also answers ACME HTTP-01 challenges there.
+func serveTLS(server *http.Server, mode string) error {
+	var redirect http.Handler = http.HandlerFunc(redirectToHTTPS)
+
+	switch mode {
+	case "autocert":
+		manager := &autocert.Manager{
+			Prompt:     autocert.AcceptTOS,
+			HostPolicy: autocert.HostWhitelist(config.List("TLS_AUTOCERT_DOMAINS", nil)...),
+			Cache:      autocert.DirCache(config.String("TLS_AUTOCERT_CACHE_DIR", "certs")),
+			Email:      config.String("TLS_AUTOCERT_EMAIL", ""),
+		}
+		server.TLSConfig = manager.TLSConfig()
+		redirect = manager.HTTPHandler(redirect)
+	default:
+		server.TLSConfig = &tls.Config{MinVersion: tls.VersionTLS12}
+	}
+
+	if port := config.String("HTTP_REDIRECT_PORT", defaultRedirectPort(mode)); port != "" {
+		go func() {
+			log.Printf("Redirecting HTTP on port %s to HTTPS", port)
+			redirectServer := &http.Server{
+				Addr:              ":" + port,
+				Handler:           redirect,
+				ReadHeaderTimeout: server.ReadHeaderTimeout,
+			}
+			if err := redirectServer.ListenAndServe(); err != nil {
+				log.Printf("HTTP redirect server stopped: %v", err)
+			}
+		}()
+	}
+
+	// Certificates come from TLSConfig in autocert mode
+	return server.ListenAndServeTLS(config.String("TLS_CERT_FILE", ""), config.String("TLS_KEY_FILE", ""))
+}
+
+// Autocert needs port 80 for HTTP-01 challenges; with cert files the redirect is opt-in
+func defaultRedirectPort(mode string) string {
+	if mode == "autocert" {
+		return "80"
+	}
+	return ""
+}
+
+func redirectToHTTPS(w http.ResponseWriter, r *http.Request) {
+	host := r.Host
+	if h, _, err := net.SplitHostPort(host); err == nil {
+		host = h
+	}
+	if port := config.String("PORT", "443"); port != "443" {
+		host = net.JoinHostPort(host, port)
+	}
+
+	http.Redirect(w, r, "https://"+host+r.URL.RequestURI(), http.StatusMovedPermanently)
+}